@@ -3,22 +3,41 @@
 pragma solidity ^0.7.0;
 pragma experimental ABIEncoderV2;
 
-import "./perun-eth-contracts/contracts/App.sol";
 import "./perun-eth-contracts/contracts/Channel.sol";
-import "./perun-eth-contracts/vendor/openzeppelin-contracts/contracts/cryptography/ECDSA.sol";
 import "./perun-eth-contracts/contracts/Array.sol";
 import "./Decode.sol";
 
+/**
+ * IERC1271 is the signature validation interface of contract accounts
+ * (EIP-1271). It is declared pure, so that the call is made with STATICCALL
+ * and the account cannot modify state.
+ */
+interface IERC1271 {
+    function isValidSignature(bytes32 hash, bytes calldata signature) external pure returns (bytes4);
+}
+
 /**
  * CredentialSwap is a channel app for swapping a credential against a payment.
+ *
+ * It implements the interface of App, but does not inherit it, as it reads
+ * the block time to check the expiry of credentials issued in a dispute,
+ * which the pure `validTransition` of App cannot. The adjudicator calls it
+ * with STATICCALL, which may read the block time.
  */
-contract CredentialSwap is App {
-    enum Mode{ Default, Offer, Cert }
+contract CredentialSwap {
+    enum Mode{ Default, Offer, Cert, CounterOffer, BatchOffer, BatchCert }
     uint8 constant ASSET_INDEX = 0;
+    // Index of the fee recipient in channels with three participants.
+    uint8 constant FEE_RECIPIENT_INDEX = 2;
     // Indices corresponding to data encoding.
     uint8 constant MODE_INDEX = 0;
     uint8 constant SIG_INDEX = 0;
     uint8 constant SIG_LENGTH = 65;
+    uint8 constant MAX_BATCH_SIZE = 64;
+    bytes4 constant ERC1271_MAGIC_VALUE = 0x1626ba7e;
+    uint256 constant SECP256K1_HALF_N = 0x7FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF5D576E7357A4501DDFE92F46681B20A0;
+    bytes32 constant CREDENTIAL_TYPEHASH =
+        keccak256("Credential(bytes32 document,uint64 expiry,bytes32 metadata)");
 
     struct Frame {
         uint8 mode;
@@ -30,12 +49,28 @@ contract CredentialSwap is App {
         bytes32 h;
         uint256 price;
         uint16 buyer;
+        uint64 expiry;
+        bytes32 meta;
+        address[] cosigners;
+        bytes32 domain;
+        bytes32[] attributes;
+        bytes bbsKey;
+        uint256 fee;
+        uint64 nonce;
     }
 
     struct Cert {
         bytes sig;
     }
 
+    struct BatchOffer {
+        address issuer;
+        bytes32[] hs;
+        uint256 price;
+        uint16 buyer;
+        uint64 nonce;
+    }
+
     /**
      * ValidTransition checks if the transition from `cur` to `next` by
      * participant `actor` is valid.
@@ -49,7 +84,7 @@ contract CredentialSwap is App {
         Channel.State calldata cur,
         Channel.State calldata next,
         uint256 actor
-    ) external pure override {
+    ) external view {
         // We require that we only have a constant single asset.
         requireConstantSingleAsset(cur, next);
 
@@ -58,15 +93,35 @@ contract CredentialSwap is App {
         if (frame.mode == uint8(Mode.Offer)) {
             Offer memory offer = decodeOffer(frame.body);
             validTransitionFromOffer(offer, cur, next, actor);
+        } else if (frame.mode == uint8(Mode.CounterOffer)) {
+            Offer memory counter = decodeOffer(frame.body);
+            validTransitionFromCounterOffer(counter, cur, next, actor);
+        } else if (frame.mode == uint8(Mode.BatchOffer)) {
+            BatchOffer memory offer = abi.decode(frame.body, (BatchOffer));
+            validTransitionFromBatchOffer(offer, cur, next, actor);
         } else {
             // We require that the balances did not change.
             requireBalancesUnchanged(cur, next);
 
-            // If the next state is an offer, check that the potential buyer has
-            // sufficient funds to fulfill the payment.
+            // If the next state is an offer, check that it is made by the
+            // buyer and that the buyer has sufficient funds to fulfill the
+            // payment. Either participant may be the buyer, but only for its
+            // own requests.
             Frame memory nextFrame = decodeFrame(next);
             if (nextFrame.mode == uint8(Mode.Offer)) {
                 Offer memory offer = decodeOffer(nextFrame.body);
+                require(actor == offer.buyer, "invalid actor");
+                requireValidNonce(offer.nonce, next);
+                uint256[][] calldata nextBals = next.outcome.balances;
+                require(nextBals[ASSET_INDEX][offer.buyer] >= offer.price,
+                    "insufficient funds");
+                requireValidFee(offer, next);
+            } else if (nextFrame.mode == uint8(Mode.BatchOffer)) {
+                BatchOffer memory offer = abi.decode(nextFrame.body, (BatchOffer));
+                require(actor == offer.buyer, "invalid actor");
+                require(offer.hs.length > 0 && offer.hs.length <= MAX_BATCH_SIZE,
+                    "invalid batch size");
+                requireValidNonce(offer.nonce, next);
                 uint256[][] calldata nextBals = next.outcome.balances;
                 require(nextBals[ASSET_INDEX][offer.buyer] >= offer.price,
                     "insufficient funds");
@@ -79,22 +134,152 @@ contract CredentialSwap is App {
         Channel.State calldata cur,
         Channel.State calldata next,
         uint256 actor
-    ) internal pure {
+    ) internal view {
+        // The issuer may decline the offer or respond with a counter-offer.
+        // The buyer cannot withdraw the offer, as this would allow it to
+        // revert the state after learning the signature.
+        Frame memory nextFrame = decodeFrame(next);
+        if (nextFrame.mode == uint8(Mode.Default)) {
+            require(actor != offer.buyer, "invalid actor");
+            requireBalancesUnchanged(cur, next);
+            return;
+        } else if (nextFrame.mode == uint8(Mode.CounterOffer)) {
+            require(actor != offer.buyer, "invalid actor");
+            Offer memory counter = decodeOffer(nextFrame.body);
+            require(counter.nonce == offer.nonce, "invalid nonce");
+            requireValidCounterOffer(offer, counter, cur, next);
+            return;
+        }
+
         // Decode next state.
         (Cert memory cert, bool ok) = decodeCert(next);
         require(ok, "invalid next mode");
         uint256 seller = actor;
 
-        // Verify signature.
-        require(verify(offer.h, cert.sig, offer.issuer), "invalid signature");
+        // Credentials with a BBS signature cannot be issued in a dispute, as
+        // BLS12-381 operations are not available here to verify it. They are
+        // only issued off-chain, where the buyer verifies the BBS signature
+        // before accepting the payment.
+        require(offer.bbsKey.length == 0, "BBS offer in dispute");
+
+        // A credential cannot be issued in a dispute once it expired. Off-chain,
+        // the issuer and the buyer check the expiry by their clocks.
+        require(offer.expiry == 0 || block.timestamp < offer.expiry, "offer expired");
+
+        // Verify signature. The signature covers the expiry and metadata.
+        // The issuer's signature is followed by the signatures of the
+        // cosigners.
+        bytes32 h = credentialHash(offer);
+        require(cert.sig.length == (offer.cosigners.length + 1) * SIG_LENGTH,
+            "invalid number of signatures");
+        require(verify(h, Decode.slice(cert.sig, 0, SIG_LENGTH), offer.issuer), "invalid signature");
+        for (uint i = 0; i < offer.cosigners.length; i++) {
+            bytes memory cosig = Decode.slice(cert.sig, (i + 1) * SIG_LENGTH, SIG_LENGTH);
+            require(verify(h, cosig, offer.cosigners[i]), "invalid cosignature");
+        }
+
+        // Verify balances.
+        requirePayment(cur, next, offer.buyer, seller, offer.price, offer.fee);
+    }
+
+    function validTransitionFromBatchOffer(
+        BatchOffer memory offer,
+        Channel.State calldata cur,
+        Channel.State calldata next,
+        uint256 actor
+    ) internal pure {
+        // The issuer may decline the batch or issue all credentials at once.
+        require(actor != offer.buyer, "invalid actor");
+        Frame memory nextFrame = decodeFrame(next);
+        if (nextFrame.mode == uint8(Mode.Default)) {
+            requireBalancesUnchanged(cur, next);
+            return;
+        }
+        require(nextFrame.mode == uint8(Mode.BatchCert), "invalid next mode");
+
+        // Verify signatures.
+        bytes memory sigs = nextFrame.body;
+        require(sigs.length == offer.hs.length * SIG_LENGTH, "invalid number of signatures");
+        for (uint i = 0; i < offer.hs.length; i++) {
+            bytes memory sig = Decode.slice(sigs, i * SIG_LENGTH, SIG_LENGTH);
+            require(verify(offer.hs[i], sig, offer.issuer), "invalid signature");
+        }
 
         // Verify balances.
+        requirePayment(cur, next, offer.buyer, actor, offer.price, 0);
+    }
+
+    function requirePayment(
+        Channel.State calldata cur,
+        Channel.State calldata next,
+        uint256 buyer,
+        uint256 seller,
+        uint256 price,
+        uint256 fee
+    ) internal pure {
         uint256[][] calldata curBals = cur.outcome.balances;
         uint256[][] calldata nextBals = next.outcome.balances;
-        require(nextBals[ASSET_INDEX][offer.buyer] == curBals[ASSET_INDEX][offer.buyer] - offer.price,
+        require(nextBals[ASSET_INDEX][buyer] == curBals[ASSET_INDEX][buyer] - price,
             "invalid amount transferred: buyer");
-        require(nextBals[ASSET_INDEX][seller] == curBals[ASSET_INDEX][seller] + offer.price,
+        // The seller receives the price minus the fee.
+        require(nextBals[ASSET_INDEX][seller] == curBals[ASSET_INDEX][seller] + price - fee,
             "invalid amount transferred: seller");
+        if (curBals[ASSET_INDEX].length > FEE_RECIPIENT_INDEX) {
+            require(nextBals[ASSET_INDEX][FEE_RECIPIENT_INDEX] == curBals[ASSET_INDEX][FEE_RECIPIENT_INDEX] + fee,
+                "invalid amount transferred: fee recipient");
+        }
+    }
+
+    function requireValidFee(Offer memory offer, Channel.State calldata s) internal pure {
+        require(offer.fee <= offer.price, "invalid fee");
+        require(offer.fee == 0 || s.outcome.balances[ASSET_INDEX].length > FEE_RECIPIENT_INDEX,
+            "invalid fee: no fee recipient");
+    }
+
+    function validTransitionFromCounterOffer(
+        Offer memory counter,
+        Channel.State calldata cur,
+        Channel.State calldata next,
+        uint256 actor
+    ) internal pure {
+        // The buyer may accept or counter the counter-offer with a new offer,
+        // or abandon the request.
+        require(actor == counter.buyer, "invalid actor");
+        Frame memory nextFrame = decodeFrame(next);
+        if (nextFrame.mode == uint8(Mode.Default)) {
+            requireBalancesUnchanged(cur, next);
+        } else if (nextFrame.mode == uint8(Mode.Offer)) {
+            Offer memory offer = decodeOffer(nextFrame.body);
+            requireValidNonce(offer.nonce, next);
+            requireValidCounterOffer(counter, offer, cur, next);
+        } else {
+            revert("invalid next mode");
+        }
+    }
+
+    function requireValidCounterOffer(
+        Offer memory cur,
+        Offer memory next,
+        Channel.State calldata curState,
+        Channel.State calldata nextState
+    ) internal pure {
+        require(cur.issuer == next.issuer && cur.h == next.h && cur.buyer == next.buyer
+            && cur.expiry == next.expiry && cur.meta == next.meta
+            && keccak256(abi.encode(cur.cosigners)) == keccak256(abi.encode(next.cosigners))
+            && cur.domain == next.domain
+            && keccak256(abi.encode(cur.attributes, cur.bbsKey)) == keccak256(abi.encode(next.attributes, next.bbsKey)),
+            "counter-offer changes more than the price");
+        requireValidFee(next, nextState);
+        requireBalancesUnchanged(curState, nextState);
+        require(nextState.outcome.balances[ASSET_INDEX][next.buyer] >= next.price,
+            "insufficient funds");
+    }
+
+    /// requireValidNonce requires that an offer in state `s` carries the
+    /// version of `s` as nonce, so that a signed offer cannot be replayed in
+    /// a later state.
+    function requireValidNonce(uint64 nonce, Channel.State calldata s) internal pure {
+        require(nonce == s.version, "invalid nonce");
     }
 
     function decodeFrame(Channel.State calldata s) internal pure returns (Frame memory) {
@@ -119,10 +304,52 @@ contract CredentialSwap is App {
         return (Cert({sig: s.body}), true);
     }
 
-    /// verify verifies that `sig` is a signature on `h` by `signer`.
+    /// credentialHash returns the hash signed by the issuer for `offer`. If
+    /// the offer has an EIP-712 domain separator, this is the typed-data
+    /// digest. The separator is not checked here, as `validTransition` cannot
+    /// access the chain ID and contract address; the issuer checks it before
+    /// signing.
+    function credentialHash(Offer memory offer) internal pure returns (bytes32) {
+        if (offer.domain != bytes32(0)) {
+            bytes32 structHash = keccak256(abi.encode(CREDENTIAL_TYPEHASH, offer.h, offer.expiry, offer.meta));
+            return keccak256(abi.encodePacked("\x19\x01", offer.domain, structHash));
+        }
+        if (offer.expiry == 0 && offer.meta == bytes32(0)) {
+            return offer.h;
+        }
+        return keccak256(abi.encode(offer.h, offer.expiry, offer.meta));
+    }
+
+    /// verify verifies that `sig` is a signature on `h` by `signer`. If it is
+    /// not an ECDSA signature of `signer`, `signer` must be a contract
+    /// account that accepts it according to EIP-1271. Otherwise, the call
+    /// reverts.
     function verify(bytes32 h, bytes memory sig, address signer) internal pure returns (bool) {
-        address recoveredAddr = ECDSA.recover(h, sig);
-        return recoveredAddr == signer;
+        address recovered = tryRecover(h, sig);
+        if (recovered != address(0) && recovered == signer) {
+            return true;
+        }
+        return IERC1271(signer).isValidSignature(h, sig) == ERC1271_MAGIC_VALUE;
+    }
+
+    /// tryRecover returns the signer of ECDSA signature `sig` on `h`, or zero
+    /// if `sig` is not a valid ECDSA signature.
+    function tryRecover(bytes32 h, bytes memory sig) internal pure returns (address) {
+        if (sig.length != SIG_LENGTH) {
+            return address(0);
+        }
+        bytes32 r;
+        bytes32 s;
+        uint8 v;
+        assembly {
+            r := mload(add(sig, 0x20))
+            s := mload(add(sig, 0x40))
+            v := byte(0, mload(add(sig, 0x60)))
+        }
+        if (uint256(s) > SECP256K1_HALF_N || (v != 27 && v != 28)) {
+            return address(0);
+        }
+        return ecrecover(h, v, r, s);
     }
 
     function requireConstantSingleAsset(Channel.State calldata cur, Channel.State calldata next) internal pure {
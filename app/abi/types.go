@@ -6,11 +6,14 @@ import (
 
 var (
 	Address = createType("address")
+	Bool    = createType("bool")
 	Bytes32 = createType("bytes32")
 	Bytes   = createType("bytes")
 	Uint8   = createType("uint8")
 	Uint16  = createType("uint16")
+	Uint64  = createType("uint64")
 	Uint256 = createType("uint256")
+	String  = createType("string")
 )
 
 func createType(name string) abi.Type {
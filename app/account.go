@@ -0,0 +1,23 @@
+package app
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wallet"
+)
+
+// Account is an Ethereum account that signs channel states, messages, and
+// credentials. It is implemented by *simple.Account, which holds its key in
+// memory, and by package pkg/hsm for keys held in a hardware security
+// module.
+type Account interface {
+	wallet.Account
+	// SignHash returns the signature of the already prefixed hash `hash` with
+	// a recovery id of 0 or 1.
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// AccountAddress returns the Ethereum address of `acc`.
+func AccountAddress(acc Account) common.Address {
+	return ethwallet.AsEthAddr(acc.Address())
+}
@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: Apache-2.0
+
+pragma solidity ^0.7.0;
+
+/**
+ * CredentialAnchor records the existence of credentials issued by their
+ * issuers. Only credential IDs are anchored, so the content stays private.
+ */
+contract CredentialAnchor {
+    event Anchored(address indexed issuer, bytes32 indexed id);
+
+    /// anchoredAt holds the time at which a credential was anchored by its
+    /// issuer, or zero if it was not anchored.
+    mapping(address => mapping(bytes32 => uint256)) public anchoredAt;
+
+    /**
+     * anchor anchors the credentials `ids` issued by the sender. Credentials
+     * that are already anchored keep their original time.
+     *
+     * @param ids The credential IDs.
+     */
+    function anchor(bytes32[] calldata ids) external {
+        for (uint256 i = 0; i < ids.length; i++) {
+            if (anchoredAt[msg.sender][ids[i]] != 0) {
+                continue;
+            }
+            anchoredAt[msg.sender][ids[i]] = block.timestamp;
+            emit Anchored(msg.sender, ids[i]);
+        }
+    }
+}
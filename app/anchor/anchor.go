@@ -0,0 +1,48 @@
+// Package anchor provides bindings to the CredentialAnchor contract.
+package anchor
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// CredentialAnchorABI is the ABI of CredentialAnchor.sol.
+const CredentialAnchorABI = `[
+	{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"issuer","type":"address"},{"indexed":true,"internalType":"bytes32","name":"id","type":"bytes32"}],"name":"Anchored","type":"event"},
+	{"inputs":[{"internalType":"bytes32[]","name":"ids","type":"bytes32[]"}],"name":"anchor","outputs":[],"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[{"internalType":"address","name":"","type":"address"},{"internalType":"bytes32","name":"","type":"bytes32"}],"name":"anchoredAt","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}
+]`
+
+// Anchor is a binding to a deployed CredentialAnchor.
+type Anchor struct {
+	contract *bind.BoundContract
+}
+
+func NewAnchor(addr common.Address, backend bind.ContractBackend) (*Anchor, error) {
+	parsed, err := abi.JSON(strings.NewReader(CredentialAnchorABI))
+	if err != nil {
+		return nil, err
+	}
+	return &Anchor{bind.NewBoundContract(addr, parsed, backend, backend, backend)}, nil
+}
+
+// Anchor anchors credentials `ids` of the sender.
+func (a *Anchor) Anchor(opts *bind.TransactOpts, ids [][32]byte) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "anchor", ids)
+}
+
+// AnchoredAt returns the time at which credential `id` was anchored by
+// `issuer`, or zero if it was not anchored.
+func (a *Anchor) AnchoredAt(opts *bind.CallOpts, issuer common.Address, id [32]byte) (*big.Int, error) {
+	var out []interface{}
+	err := a.contract.Call(opts, &out, "anchoredAt", issuer, id)
+	if err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
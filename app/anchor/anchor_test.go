@@ -0,0 +1,38 @@
+package anchor_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/anchor"
+	"github.com/perun-network/perun-credential-payment/app/internal/bindtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnchor(t *testing.T) {
+	b, err := bindtest.NewBackend(anchor.CredentialAnchorABI)
+	require.NoError(t, err)
+	a, err := anchor.NewAnchor(common.Address{1}, b)
+	require.NoError(t, err)
+
+	issuer, id := common.Address{2}, [32]byte{3}
+	b.Handle("anchoredAt", func(args []interface{}) []interface{} {
+		if args[0].(common.Address) == issuer && args[1].([32]byte) == id {
+			return []interface{}{big.NewInt(1000)}
+		}
+		return []interface{}{new(big.Int)}
+	})
+	at, err := a.AnchoredAt(&bind.CallOpts{}, issuer, id)
+	require.NoError(t, err)
+	require.Zero(t, at.Cmp(big.NewInt(1000)))
+	at, err = a.AnchoredAt(&bind.CallOpts{}, common.Address{4}, id)
+	require.NoError(t, err)
+	require.Zero(t, at.Sign(), "anchored by other issuer")
+
+	ids := [][32]byte{{5}, {6}}
+	_, err = a.Anchor(b.TransactOpts(), ids)
+	require.NoError(t, err)
+	require.Equal(t, []bindtest.Call{{Method: "anchor", Args: []interface{}{ids}}}, b.Sent())
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/perun-network/perun-credential-payment/app/data"
@@ -28,6 +29,9 @@ import (
 
 const (
 	AssetIdx = 0
+	// FeeRecipientIdx is the index of the fee recipient in channels with
+	// three participants.
+	FeeRecipientIdx = 2
 )
 
 var (
@@ -37,11 +41,19 @@ var (
 	ErrUnequalAllocation   = errors.New("unequal allocation")
 	ErrInsufficientBalance = errors.New("insufficient balance")
 	ErrInvalidSigner       = errors.New("invalid signer")
+	ErrInvalidActor        = errors.New("invalid actor")
+	ErrCredentialExpired   = errors.New("credential expired")
+	ErrInvalidFee          = errors.New("invalid fee")
+	ErrInvalidBuyer        = errors.New("invalid buyer")
+	ErrInvalidNonce        = errors.New("invalid nonce")
 )
 
 // CredentialSwapApp is a channel app for atomically trading a credential against a payment.
 type CredentialSwapApp struct {
 	Addr wallet.Address
+	// ContractSigs accepts EIP-1271 signatures of contract accounts.
+	// Optional. Without it, only ECDSA signatures are accepted.
+	ContractSigs *ContractSigVerifier
 }
 
 func NewCredentialSwapApp(addr wallet.Address) *CredentialSwapApp {
@@ -80,23 +92,69 @@ func (a *CredentialSwapApp) ValidTransition(params *channel.Params, cur, next *c
 		return err
 	}
 
+	// The buyer of an offer is used as an index into the balances, so we
+	// require that it is a participant.
+	if err := assertValidBuyer(cur); err != nil {
+		return err
+	} else if err := assertValidBuyer(next); err != nil {
+		return err
+	}
+
 	switch cur.Data.(type) {
 	case *data.Offer:
-		err := validTransitionFromOffer(cur, next, actorIdx)
+		err := a.validTransitionFromOffer(cur, next, actorIdx)
 		if err != nil {
 			return fmt.Errorf("validating transition from offer: %w", err)
 		}
 
+	case *data.CounterOffer:
+		err := validTransitionFromCounterOffer(cur, next, actorIdx)
+		if err != nil {
+			return fmt.Errorf("validating transition from counter-offer: %w", err)
+		}
+
+	case *data.BatchOffer:
+		err := a.validTransitionFromBatchOffer(cur, next, actorIdx)
+		if err != nil {
+			return fmt.Errorf("validating transition from batch offer: %w", err)
+		}
+
 	default:
-		// We require that the balances did not change.
-		if !cur.Balances.Equal(next.Balances) {
+		// We require that the balances did not change. Only in a final state,
+		// the actor may give up funds to the other participants, e.g., as a
+		// refund.
+		if next.IsFinal {
+			if err := assertFinalAdjustment(cur, next, actorIdx); err != nil {
+				return err
+			}
+		} else if !cur.Balances.Equal(next.Balances) {
 			return fmt.Errorf("unequal balances")
 		}
 
-		// If the next state is an offer, check that there is sufficient funds
-		// to fulfill the payment.
-		if offer, ok := next.Data.(*data.Offer); ok {
-			if next.Balances[AssetIdx][offer.Buyer].Cmp(offer.Price) < 0 {
+		// If the next state is an offer, check that it is made by the buyer
+		// and that there is sufficient funds to fulfill the payment. Either
+		// participant may be the buyer, but only for its own requests.
+		switch offer := next.Data.(type) {
+		case *data.Offer:
+			if actorIdx != channel.Index(offer.Buyer) {
+				return ErrInvalidActor
+			} else if err := validNonce(offer.Nonce, next); err != nil {
+				return err
+			} else if next.Balances[AssetIdx][offer.Buyer].Cmp(offer.Price) < 0 {
+				return fmt.Errorf("insufficient funds")
+			} else if err := validFee(offer, next); err != nil {
+				return err
+			} else if err := validBBSOffer(offer); err != nil {
+				return err
+			}
+		case *data.BatchOffer:
+			if actorIdx != channel.Index(offer.Buyer) {
+				return ErrInvalidActor
+			} else if n := len(offer.DataHashes); n == 0 || n > data.MaxBatchSize {
+				return fmt.Errorf("invalid batch size: %d", n)
+			} else if err := validNonce(offer.Nonce, next); err != nil {
+				return err
+			} else if next.Balances[AssetIdx][offer.Buyer].Cmp(offer.Price) < 0 {
 				return fmt.Errorf("insufficient funds")
 			}
 		}
@@ -105,9 +163,28 @@ func (a *CredentialSwapApp) ValidTransition(params *channel.Params, cur, next *c
 	return nil
 }
 
-func validTransitionFromOffer(cur *channel.State, next *channel.State, actorIdx channel.Index) error {
+func (a *CredentialSwapApp) validTransitionFromOffer(cur *channel.State, next *channel.State, actorIdx channel.Index) error {
 	offer := cur.Data.(*data.Offer)
 
+	// The issuer may decline the offer or respond with a counter-offer. The
+	// buyer cannot withdraw the offer, as this would allow it to revert the
+	// state after learning the signature.
+	switch nextData := next.Data.(type) {
+	case *data.DefaultData:
+		if actorIdx == channel.Index(offer.Buyer) {
+			return ErrInvalidActor
+		}
+		return assertBalancesUnchanged(cur, next)
+
+	case *data.CounterOffer:
+		if actorIdx == channel.Index(offer.Buyer) {
+			return ErrInvalidActor
+		} else if nextData.Nonce != offer.Nonce {
+			return fmt.Errorf("%w: counter-offer changes the nonce", ErrInvalidNonce)
+		}
+		return validCounterOffer(offer, &nextData.Offer, cur, next)
+	}
+
 	// Verify signature.
 	{
 		// Decode next state.
@@ -116,30 +193,193 @@ func validTransitionFromOffer(cur *channel.State, next *channel.State, actorIdx
 			return ErrInvalidNextData
 		}
 
-		err := VerifySig(cert.Signature, offer.DataHash, offer.Issuer)
+		h := OfferHash(offer)
+		err := verifySig(a.ContractSigs, cert.Signature, h, offer.Issuer)
 		if err != nil {
 			return fmt.Errorf("verifying signature: %w", err)
 		}
+
+		// Verify cosignatures.
+		if len(cert.CoSignatures) != len(offer.Cosigners) {
+			return fmt.Errorf("wrong number of cosignatures")
+		}
+		for i, cosigner := range offer.Cosigners {
+			if err := verifySig(a.ContractSigs, cert.CoSignatures[i], h, cosigner); err != nil {
+				return fmt.Errorf("verifying cosignature %d: %w", i, err)
+			}
+		}
+
+		// Verify the BBS signature on the committed attributes. The contract
+		// cannot verify it, as it has no access to BLS12-381 operations.
+		if err := verifyBBSSignature(offer, cert); err != nil {
+			return fmt.Errorf("verifying BBS signature: %w", err)
+		}
 	}
 
 	// Verify balances.
+	return assertPayment(cur, next, channel.Index(offer.Buyer), actorIdx, offer.Price, offer.FeeAmount())
+}
+
+// validTransitionFromBatchOffer checks the response of the issuer to a batch
+// offer. The issuer may decline it or issue all credentials at once.
+func (a *CredentialSwapApp) validTransitionFromBatchOffer(cur *channel.State, next *channel.State, actorIdx channel.Index) error {
+	offer := cur.Data.(*data.BatchOffer)
+	if actorIdx == channel.Index(offer.Buyer) {
+		return ErrInvalidActor
+	}
+
+	switch nextData := next.Data.(type) {
+	case *data.DefaultData:
+		return assertBalancesUnchanged(cur, next)
 
+	case *data.BatchCert:
+		if len(nextData.Signatures) != len(offer.DataHashes) {
+			return fmt.Errorf("wrong number of signatures")
+		}
+		for i, h := range offer.DataHashes {
+			if err := verifySig(a.ContractSigs, nextData.Signatures[i], h, offer.Issuer); err != nil {
+				return fmt.Errorf("verifying signature %d: %w", i, err)
+			}
+		}
+		return assertPayment(cur, next, channel.Index(offer.Buyer), actorIdx, offer.Price, new(big.Int))
+
+	default:
+		return ErrInvalidNextData
+	}
+}
+
+// assertPayment checks that `price` was transferred from `buyer` to `seller`.
+func assertPayment(cur, next *channel.State, buyer, seller channel.Index, price, fee *big.Int) error {
 	// Verify buyer balance.
 	{
-		expectedBal := new(big.Int).Sub(cur.Balances[AssetIdx][offer.Buyer], offer.Price)
-		if next.Balances[AssetIdx][offer.Buyer].Cmp(expectedBal) != 0 {
+		expectedBal := new(big.Int).Sub(cur.Balances[AssetIdx][buyer], price)
+		if next.Balances[AssetIdx][buyer].Cmp(expectedBal) != 0 {
 			return fmt.Errorf("wrong balance: buyer")
 		}
 	}
 
-	// Verify seller balance.
+	// Verify seller balance. The seller receives the price minus the fee.
 	{
-		expectedBal := new(big.Int).Add(cur.Balances[AssetIdx][actorIdx], offer.Price)
-		if next.Balances[AssetIdx][actorIdx].Cmp(expectedBal) != 0 {
+		expectedBal := new(big.Int).Add(cur.Balances[AssetIdx][seller], price)
+		expectedBal.Sub(expectedBal, fee)
+		if next.Balances[AssetIdx][seller].Cmp(expectedBal) != 0 {
 			return fmt.Errorf("wrong balance: seller")
 		}
 	}
 
+	// Verify fee recipient balance.
+	if len(cur.Balances[AssetIdx]) > FeeRecipientIdx {
+		expectedBal := new(big.Int).Add(cur.Balances[AssetIdx][FeeRecipientIdx], fee)
+		if next.Balances[AssetIdx][FeeRecipientIdx].Cmp(expectedBal) != 0 {
+			return fmt.Errorf("wrong balance: fee recipient")
+		}
+	}
+
+	return nil
+}
+
+// validFee checks that the fee of `offer` does not exceed the price and that
+// the channel has a fee recipient if the fee is non-zero.
+func validFee(offer *data.Offer, s *channel.State) error {
+	fee := offer.FeeAmount()
+	if fee.Sign() < 0 || fee.Cmp(offer.Price) > 0 {
+		return ErrInvalidFee
+	} else if fee.Sign() > 0 && len(s.Balances[AssetIdx]) <= FeeRecipientIdx {
+		return fmt.Errorf("%w: no fee recipient", ErrInvalidFee)
+	}
+	return nil
+}
+
+// validTransitionFromCounterOffer checks the response of the buyer to a
+// counter-offer. The buyer may accept it or counter it with a new offer, or
+// abandon the request.
+func validTransitionFromCounterOffer(cur *channel.State, next *channel.State, actorIdx channel.Index) error {
+	counter := cur.Data.(*data.CounterOffer)
+	if actorIdx != channel.Index(counter.Buyer) {
+		return ErrInvalidActor
+	}
+
+	switch nextData := next.Data.(type) {
+	case *data.DefaultData:
+		return assertBalancesUnchanged(cur, next)
+
+	case *data.Offer:
+		if err := validNonce(nextData.Nonce, next); err != nil {
+			return err
+		}
+		return validCounterOffer(&counter.Offer, nextData, cur, next)
+
+	default:
+		return ErrInvalidNextData
+	}
+}
+
+// validCounterOffer checks that `next` only differs from `cur` in price, fee,
+// and nonce, that the balances did not change, and that the buyer can afford
+// the new price. The nonce is checked by the caller.
+func validCounterOffer(cur, next *data.Offer, curState, nextState *channel.State) error {
+	repriced := next.Clone().(*data.Offer)
+	repriced.Price = cur.Price
+	repriced.Fee = cur.Fee
+	repriced.Nonce = cur.Nonce
+	if !cur.Equal(repriced) {
+		return fmt.Errorf("counter-offer changes more than the price")
+	} else if err := validFee(next, nextState); err != nil {
+		return err
+	} else if err := assertBalancesUnchanged(curState, nextState); err != nil {
+		return err
+	} else if nextState.Balances[AssetIdx][next.Buyer].Cmp(next.Price) < 0 {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// validNonce checks that an offer in state `s` carries the version of `s` as
+// nonce. Versions only increase, so that an offer signed for one state cannot
+// be replayed in a later one.
+func validNonce(nonce uint64, s *channel.State) error {
+	if nonce != s.Version {
+		return fmt.Errorf("%w: %d in version %d", ErrInvalidNonce, nonce, s.Version)
+	}
+	return nil
+}
+
+// assertValidBuyer checks that the buyer of an offer in state `s` is a
+// participant of the channel.
+func assertValidBuyer(s *channel.State) error {
+	var buyer uint16
+	switch offer := s.Data.(type) {
+	case *data.Offer:
+		buyer = offer.Buyer
+	case *data.CounterOffer:
+		buyer = offer.Buyer
+	case *data.BatchOffer:
+		buyer = offer.Buyer
+	default:
+		return nil
+	}
+	if int(buyer) >= len(s.Balances[AssetIdx]) {
+		return fmt.Errorf("%w: %d", ErrInvalidBuyer, buyer)
+	}
+	return nil
+}
+
+func assertBalancesUnchanged(cur, next *channel.State) error {
+	if !cur.Balances.Equal(next.Balances) {
+		return fmt.Errorf("unequal balances")
+	}
+	return nil
+}
+
+// assertFinalAdjustment checks that the balances of all participants other
+// than `actor` did not decrease. The preservation of the total is checked by
+// go-perun.
+func assertFinalAdjustment(cur, next *channel.State, actor channel.Index) error {
+	for i, bal := range cur.Balances[AssetIdx] {
+		if channel.Index(i) != actor && next.Balances[AssetIdx][i].Cmp(bal) < 0 {
+			return fmt.Errorf("final state decreases balance of participant %d", i)
+		}
+	}
 	return nil
 }
 
@@ -167,8 +407,36 @@ func ComputeDocumentHash(doc []byte) Hash {
 type Credential struct {
 	Document  []byte
 	Signature []byte
+	Expiry    uint64    // Unix time. Zero if the credential does not expire.
+	Metadata  *Metadata // Optional.
+	Domain    Hash      // EIP-712 domain separator. Zero if the raw hash is signed.
+
+	CoSignatures [][]byte             // Signatures of the cosigners, in the order of the request.
+	IssuerChain  []*IssuerCertificate // Optional. Certifies the issuer up to a trust anchor.
+	// SuiteSignature is an additional signature of the issuer by a key of a
+	// signature suite, e.g., Ed25519. Optional.
+	SuiteSignature *SuiteSignature
 }
 
 func (c *Credential) String() string {
+	if c.Expiry != 0 {
+		return fmt.Sprintf("Document: \"%s\" Signature: \"%x\" Expiry: %v", c.Document, c.Signature, time.Unix(int64(c.Expiry), 0).UTC())
+	}
 	return fmt.Sprintf("Document: \"%s\" Signature: \"%x\"", c.Document, c.Signature)
 }
+
+// ID returns the ID of the credential, which is the hash signed by the
+// issuer. For EIP-712 signatures, this is the typed-data digest.
+func (c *Credential) ID() Hash {
+	var meta Hash
+	if c.Metadata != nil {
+		meta = c.Metadata.Hash()
+	}
+	return TypedCredentialHash(c.Domain, ComputeDocumentHash(c.Document), c.Expiry, meta)
+}
+
+// Expired returns whether a credential with expiry `expiry` is expired at
+// time `now`.
+func Expired(expiry uint64, now time.Time) bool {
+	return expiry != 0 && expiry <= uint64(now.Unix())
+}
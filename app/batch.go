@@ -0,0 +1,195 @@
+package app
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/perun-network/perun-credential-payment/app/internal/h2c"
+)
+
+// batchScalarBits is the size of the random scalars that combine the
+// signatures of a batch. A forged batch passes with probability 2^-128.
+const batchScalarBits = 128
+
+// BatchSuite is a SignatureSuite that verifies many signatures faster than
+// one by one.
+type BatchSuite interface {
+	SignatureSuite
+	// VerifyBatch verifies signature sigs[i] on message msgs[i] by public
+	// key pubs[i] for all i. It only reports whether all signatures are
+	// valid.
+	VerifyBatch(pubs, msgs, sigs [][]byte) error
+}
+
+// BatchError reports the first invalid entry of a batch.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("entry %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyCredentials verifies credentials at time `now` as VerifyCredential
+// does, where creds[i] must be issued by issuers[i]. The credentials are verified in
+// parallel and the result holds the error of each credential, or nil. It
+// fails if the numbers of credentials and issuers differ.
+func VerifyCredentials(creds []*Credential, issuers []common.Address, now time.Time) ([]error, error) {
+	if len(creds) != len(issuers) {
+		return nil, errors.New("number of credentials and issuers differ")
+	}
+	errs := make([]error, len(creds))
+	parallel(len(creds), func(i int) {
+		errs[i] = VerifyCredential(creds[i], issuers[i], now)
+	})
+	return errs, nil
+}
+
+// VerifySuiteSignatures verifies suite signature sigs[i] on credential
+// creds[i] for all i. Signatures of suites that implement BatchSuite are
+// verified together. If a batch is invalid, its signatures are verified one
+// by one to find the invalid one, which is reported as *BatchError.
+func VerifySuiteSignatures(creds []*Credential, sigs []*SuiteSignature) error {
+	if len(creds) != len(sigs) {
+		return errors.New("number of credentials and signatures differ")
+	}
+
+	bySuite := make(map[string][]int)
+	for i, s := range sigs {
+		bySuite[s.Suite] = append(bySuite[s.Suite], i)
+	}
+	for id, idxs := range bySuite {
+		suite, err := SuiteByID(id)
+		if err != nil {
+			return &BatchError{idxs[0], err}
+		}
+		if bs, ok := suite.(BatchSuite); ok && len(idxs) > 1 {
+			pubs, msgs, ss := make([][]byte, len(idxs)), make([][]byte, len(idxs)), make([][]byte, len(idxs))
+			for j, i := range idxs {
+				id := creds[i].ID()
+				pubs[j], msgs[j], ss[j] = sigs[i].PublicKey, id[:], sigs[i].Signature
+			}
+			if bs.VerifyBatch(pubs, msgs, ss) == nil {
+				continue
+			}
+		}
+		if err := verifyEach(creds, sigs, idxs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyEach verifies the signatures at `idxs` in parallel and returns the
+// first invalid one.
+func verifyEach(creds []*Credential, sigs []*SuiteSignature, idxs []int) error {
+	errs := make([]error, len(idxs))
+	parallel(len(idxs), func(j int) {
+		errs[j] = sigs[idxs[j]].Verify(creds[idxs[j]])
+	})
+	for j, err := range errs {
+		if err != nil {
+			return &BatchError{idxs[j], err}
+		}
+	}
+	return nil
+}
+
+// parallel calls f(i) for all 0 <= i < n on GOMAXPROCS goroutines.
+func parallel(n int, f func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// VerifyBatch verifies the BLS signatures with a random linear combination:
+// prod e(r_i pk_i, H(m_i)) == e(g1, sum r_i sig_i). This takes n + 1
+// pairings and one final exponentiation instead of 2n and n. The points are
+// decoded and hashed in parallel.
+func (BLS12381Suite) VerifyBatch(pubs, msgs, sigs [][]byte) error {
+	if len(pubs) != len(msgs) || len(pubs) != len(sigs) {
+		return errors.New("batch lengths differ")
+	}
+	type entry struct {
+		pk  *bls12381.PointG1 // r_i pk_i
+		h   *bls12381.PointG2
+		sig *bls12381.PointG2 // r_i sig_i
+		err error
+	}
+	entries := make([]entry, len(pubs))
+	bound := new(big.Int).Lsh(big.NewInt(1), batchScalarBits)
+	parallel(len(pubs), func(i int) {
+		g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+		pk, err := g1.FromBytes(pubs[i])
+		if err != nil || g1.IsZero(pk) || !g1.InCorrectSubgroup(pk) {
+			entries[i].err = ErrInvalidSignature
+			return
+		}
+		s, err := g2.FromBytes(sigs[i])
+		if err != nil || !g2.InCorrectSubgroup(s) {
+			entries[i].err = ErrInvalidSignature
+			return
+		}
+		h, err := h2c.HashToG2(msgs[i], blsDST)
+		if err != nil {
+			entries[i].err = err
+			return
+		}
+		r, err := rand.Int(rand.Reader, bound)
+		if err != nil {
+			entries[i].err = err
+			return
+		}
+		r.Add(r, big.NewInt(1)) // Non-zero.
+		entries[i] = entry{
+			pk:  g1.MulScalar(g1.New(), pk, r),
+			h:   h,
+			sig: g2.MulScalar(g2.New(), s, r),
+		}
+	})
+
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	e := bls12381.NewPairingEngine()
+	aggSig := g2.Zero()
+	for _, en := range entries {
+		if en.err != nil {
+			return en.err
+		}
+		e.AddPair(en.pk, en.h)
+		g2.Add(aggSig, aggSig, en.sig)
+	}
+	e.AddPairInv(g1.One(), aggSig)
+	if !e.Check() {
+		return ErrInvalidSignature
+	}
+	return nil
+}
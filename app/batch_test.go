@@ -0,0 +1,144 @@
+package app
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// batchSize is the number of credentials verified by the batch tests and
+// benchmarks.
+const batchSize = 16
+
+func newCredentials(n int) []*Credential {
+	creds := make([]*Credential, n)
+	for i := range creds {
+		creds[i] = &Credential{Document: []byte(fmt.Sprintf("document %d", i))}
+	}
+	return creds
+}
+
+// blsBatch returns credentials signed by distinct BLS keys and their suite
+// signatures.
+func blsBatch(t testing.TB, n int) ([]*Credential, []*SuiteSignature) {
+	creds := newCredentials(n)
+	sigs := make([]*SuiteSignature, n)
+	for i, c := range creds {
+		s, err := GenerateBLS12381Key(nil)
+		require.NoError(t, err)
+		sigs[i], err = SignCredential(s, c)
+		require.NoError(t, err)
+	}
+	return creds, sigs
+}
+
+func newEd25519Signer(t testing.TB) Ed25519Signer {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return Ed25519Signer{Key: key}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	now := time.Now()
+	acc := newAccount(t)
+	issuer := AccountAddress(acc)
+	creds := newCredentials(batchSize)
+	issuers := make([]common.Address, len(creds))
+	for i, c := range creds {
+		sig, err := SignHash(acc, c.ID())
+		require.NoError(t, err)
+		c.Signature = sig[:]
+		issuers[i] = issuer
+	}
+
+	errs, err := VerifyCredentials(creds, issuers, now)
+	require.NoError(t, err)
+	for i, err := range errs {
+		require.NoError(t, err, "credential %d", i)
+	}
+
+	// Only the credential with the invalid signature is reported.
+	creds[3].Signature[0] ^= 1
+	errs, err = VerifyCredentials(creds, issuers, now)
+	require.NoError(t, err)
+	for i, err := range errs {
+		if i == 3 {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err, "credential %d", i)
+		}
+	}
+
+	_, err = VerifyCredentials(creds, issuers[1:], now)
+	require.Error(t, err, "fewer issuers than credentials")
+}
+
+func TestVerifySuiteSignatures(t *testing.T) {
+	creds, sigs := blsBatch(t, batchSize)
+	require.NoError(t, VerifySuiteSignatures(creds, sigs))
+	require.NoError(t, BLS12381Suite{}.VerifyBatch(batchArgs(creds, sigs)))
+
+	// A single invalid signature fails the batch and is found.
+	sigs[5] = sigs[6]
+	require.ErrorIs(t, BLS12381Suite{}.VerifyBatch(batchArgs(creds, sigs)), ErrInvalidSignature)
+	err := VerifySuiteSignatures(creds, sigs)
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, 5, batchErr.Index)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	require.Error(t, VerifySuiteSignatures(creds[1:], sigs), "fewer credentials than signatures")
+}
+
+func TestVerifySuiteSignaturesMixed(t *testing.T) {
+	creds, sigs := blsBatch(t, 4)
+	ed := newEd25519Signer(t)
+	for i := 0; i < 2; i++ {
+		c := &Credential{Document: []byte(fmt.Sprintf("ed25519 document %d", i))}
+		s, err := SignCredential(ed, c)
+		require.NoError(t, err)
+		creds, sigs = append(creds, c), append(sigs, s)
+	}
+	require.NoError(t, VerifySuiteSignatures(creds, sigs))
+
+	sigs[len(sigs)-1].Signature[0] ^= 1
+	var batchErr *BatchError
+	require.ErrorAs(t, VerifySuiteSignatures(creds, sigs), &batchErr)
+	require.Equal(t, len(sigs)-1, batchErr.Index)
+}
+
+func batchArgs(creds []*Credential, sigs []*SuiteSignature) (pubs, msgs, ss [][]byte) {
+	for i, c := range creds {
+		id := c.ID()
+		pubs = append(pubs, sigs[i].PublicKey)
+		msgs = append(msgs, id[:])
+		ss = append(ss, sigs[i].Signature)
+	}
+	return pubs, msgs, ss
+}
+
+func BenchmarkVerifyBLS(b *testing.B) {
+	creds, sigs := blsBatch(b, batchSize)
+	pubs, msgs, ss := batchArgs(creds, sigs)
+
+	b.Run("Sequential", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for i := range pubs {
+				if err := (BLS12381Suite{}).Verify(pubs[i], msgs[i], ss[i]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if err := (BLS12381Suite{}).VerifyBatch(pubs, msgs, ss); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,489 @@
+// Package bbs implements BBS+ signatures on BLS12-381 with proofs of knowledge
+// for selective disclosure, with public keys in G2 and signatures in G1. The
+// construction is modeled on the IETF BBS signature draft, but its signatures
+// are not interoperable with implementations of the draft: points are encoded
+// uncompressed, and the generators are derived differently.
+//
+// Messages are given as scalars, see MessageScalar, so that a signature can be
+// verified from the scalars alone without knowing the messages.
+package bbs
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/perun-network/perun-credential-payment/app/internal/h2c"
+)
+
+const (
+	// ScalarLen is the length of an encoded scalar.
+	ScalarLen = 32
+	// PublicKeyLen is the length of an encoded public key.
+	PublicKeyLen = 192
+	// SignatureLen is the length of an encoded signature.
+	SignatureLen = 96 + ScalarLen
+
+	// MaxMessages is the maximum number of messages per signature.
+	MaxMessages = 256
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid BBS signature")
+	ErrInvalidProof     = errors.New("invalid BBS proof")
+)
+
+var (
+	dstGenerator = []byte("BBS_BLS12381G1_XMD:SHA-256_SSWU_RO_H2G_")
+	dstScalar    = []byte("BBS_BLS12381G1_XMD:SHA-256_SSWU_RO_H2S_")
+	dstMessage   = []byte("BBS_BLS12381G1_XMD:SHA-256_SSWU_RO_MAP_MSG_TO_SCALAR_AS_HASH_")
+)
+
+// Scalar is a big-endian encoded scalar.
+type Scalar [ScalarLen]byte
+
+// MessageScalar maps message `msg` to a scalar.
+func MessageScalar(msg []byte) Scalar {
+	s, err := h2c.HashToScalar(msg, dstMessage)
+	if err != nil {
+		panic(err) // Cannot happen for fixed output length and DST.
+	}
+	return encodeScalar(s)
+}
+
+// SecretKey is a BBS secret key.
+type SecretKey struct {
+	x *big.Int
+}
+
+// GenerateKey generates a secret key using randomness from `r`. If `r` is nil,
+// crypto/rand is used.
+func GenerateKey(r io.Reader) (*SecretKey, error) {
+	x, err := randomScalar(r)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretKey{x: x}, nil
+}
+
+// PublicKey returns the encoded public key.
+func (sk *SecretKey) PublicKey() []byte {
+	g2 := bls12381.NewG2()
+	return g2.ToBytes(g2.MulScalar(g2.New(), g2.One(), sk.x))
+}
+
+// Sign signs the messages `msgs` under header `header`.
+func (sk *SecretKey) Sign(header []byte, msgs []Scalar) ([]byte, error) {
+	if len(msgs) > MaxMessages {
+		return nil, fmt.Errorf("too many messages: %d", len(msgs))
+	}
+	pk := sk.PublicKey()
+	gens, err := generators(len(msgs))
+	if err != nil {
+		return nil, err
+	}
+	domain, err := calculateDomain(pk, gens, header)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := decodeScalars(msgs)
+	in := append(encodeScalar(sk.x).bytes(), encodeScalar(domain).bytes()...)
+	for _, m := range msgs {
+		in = append(in, m[:]...)
+	}
+	e, err := h2c.HashToScalar(in, dstScalar)
+	if err != nil {
+		return nil, err
+	}
+
+	g1 := bls12381.NewG1()
+	b := commitment(gens, domain, ms, nil)
+	exp := new(big.Int).Add(sk.x, e)
+	if exp.Mod(exp, h2c.R).Sign() == 0 {
+		return nil, errors.New("invalid signing key")
+	}
+	a := g1.MulScalar(g1.New(), b, exp.ModInverse(exp, h2c.R))
+	return append(g1.ToBytes(a), encodeScalar(e).bytes()...), nil
+}
+
+// Verify verifies signature `sig` on messages `msgs` under header `header` by
+// public key `pk`.
+func Verify(pk, header []byte, msgs []Scalar, sig []byte) error {
+	if len(msgs) > MaxMessages {
+		return ErrInvalidSignature
+	}
+	w, err := decodePublicKey(pk)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	a, e, err := decodeSignature(sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	gens, err := generators(len(msgs))
+	if err != nil {
+		return err
+	}
+	domain, err := calculateDomain(pk, gens, header)
+	if err != nil {
+		return err
+	}
+	b := commitment(gens, domain, decodeScalars(msgs), nil)
+
+	// e(A, W + P2 * e) == e(B, P2)
+	g2 := bls12381.NewG2()
+	we := g2.Add(g2.New(), w, g2.MulScalar(g2.New(), g2.One(), e))
+	p := bls12381.NewPairingEngine()
+	p.AddPair(a, we)
+	p.AddPairInv(b, g2.One())
+	if !p.Check() {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// DeriveProof derives a proof of knowledge of signature `sig` on messages
+// `msgs` that only discloses the messages at indices `disclosed`. The proof is
+// bound to presentation header `ph`.
+func DeriveProof(pk, header, ph []byte, msgs []Scalar, sig []byte, disclosed []int) ([]byte, error) {
+	if err := Verify(pk, header, msgs, sig); err != nil {
+		return nil, err
+	}
+	a, e, err := decodeSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	revealed, err := indexSet(disclosed, len(msgs))
+	if err != nil {
+		return nil, err
+	}
+	gens, err := generators(len(msgs))
+	if err != nil {
+		return nil, err
+	}
+	domain, err := calculateDomain(pk, gens, header)
+	if err != nil {
+		return nil, err
+	}
+	ms := decodeScalars(msgs)
+
+	var undisclosed []int
+	for i := range msgs {
+		if !revealed[i] {
+			undisclosed = append(undisclosed, i)
+		}
+	}
+	rs, err := randomScalars(nil, 5+len(undisclosed))
+	if err != nil {
+		return nil, err
+	}
+	r1, r2, eT, r1T, r3T, mT := rs[0], rs[1], rs[2], rs[3], rs[4], rs[5:]
+
+	g1 := bls12381.NewG1()
+	b := commitment(gens, domain, ms, nil)
+	d := g1.MulScalar(g1.New(), b, r2)
+	aBar := g1.MulScalar(g1.New(), a, mulMod(r1, r2))
+	bBar := g1.Sub(g1.New(), g1.MulScalar(g1.New(), d, r1), g1.MulScalar(g1.New(), aBar, e))
+	t1 := g1.Add(g1.New(), g1.MulScalar(g1.New(), aBar, eT), g1.MulScalar(g1.New(), d, r1T))
+	t2 := g1.MulScalar(g1.New(), d, r3T)
+	for k, j := range undisclosed {
+		g1.Add(t2, t2, g1.MulScalar(g1.New(), gens.h[j], mT[k]))
+	}
+
+	c, err := challenge(aBar, bBar, d, t1, t2, disclosed, ms, domain, ph)
+	if err != nil {
+		return nil, err
+	}
+
+	r3 := new(big.Int).ModInverse(r2, h2c.R)
+	proof := append(g1.ToBytes(aBar), g1.ToBytes(bBar)...)
+	proof = append(proof, g1.ToBytes(d)...)
+	proof = append(proof, encodeScalar(addMod(eT, mulMod(e, c))).bytes()...)
+	proof = append(proof, encodeScalar(subMod(r1T, mulMod(r1, c))).bytes()...)
+	proof = append(proof, encodeScalar(subMod(r3T, mulMod(r3, c))).bytes()...)
+	for k, j := range undisclosed {
+		proof = append(proof, encodeScalar(addMod(mT[k], mulMod(ms[j], c))).bytes()...)
+	}
+	return append(proof, encodeScalar(c).bytes()...), nil
+}
+
+// VerifyProof verifies proof `proof` by public key `pk` for a signature on `n`
+// messages, of which the messages `disclosed` are disclosed.
+func VerifyProof(pk, header, ph []byte, n int, disclosed map[int]Scalar, proof []byte) error {
+	if n > MaxMessages || len(disclosed) > n {
+		return ErrInvalidProof
+	}
+	w, err := decodePublicKey(pk)
+	if err != nil {
+		return ErrInvalidProof
+	}
+	indices := make([]int, 0, len(disclosed))
+	for i := range disclosed {
+		if i < 0 || i >= n {
+			return ErrInvalidProof
+		}
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	u := n - len(indices)
+	if len(proof) != 3*96+(4+u)*ScalarLen {
+		return ErrInvalidProof
+	}
+
+	g1 := bls12381.NewG1()
+	var pts [3]*bls12381.PointG1
+	for i := range pts {
+		if pts[i], err = decodeG1(proof[i*96 : (i+1)*96]); err != nil {
+			return ErrInvalidProof
+		}
+	}
+	aBar, bBar, d := pts[0], pts[1], pts[2]
+	if g1.IsZero(aBar) {
+		return ErrInvalidProof
+	}
+	scalars := make([]*big.Int, 4+u)
+	for i := range scalars {
+		off := 3*96 + i*ScalarLen
+		if scalars[i], err = decodeScalar(proof[off : off+ScalarLen]); err != nil {
+			return ErrInvalidProof
+		}
+	}
+	eH, r1H, r3H, mH, c := scalars[0], scalars[1], scalars[2], scalars[3:3+u], scalars[3+u]
+
+	gens, err := generators(n)
+	if err != nil {
+		return err
+	}
+	domain, err := calculateDomain(pk, gens, header)
+	if err != nil {
+		return err
+	}
+	ms := make([]*big.Int, n)
+	for i, m := range disclosed {
+		if ms[i], err = decodeScalar(m[:]); err != nil {
+			return ErrInvalidProof
+		}
+	}
+
+	t1 := g1.MulScalar(g1.New(), bBar, c)
+	g1.Add(t1, t1, g1.MulScalar(g1.New(), aBar, eH))
+	g1.Add(t1, t1, g1.MulScalar(g1.New(), d, r1H))
+
+	bv := commitment(gens, domain, ms, indices)
+	t2 := g1.MulScalar(g1.New(), bv, c)
+	g1.Add(t2, t2, g1.MulScalar(g1.New(), d, r3H))
+	k := 0
+	for j := 0; j < n; j++ {
+		if _, ok := disclosed[j]; ok {
+			continue
+		}
+		g1.Add(t2, t2, g1.MulScalar(g1.New(), gens.h[j], mH[k]))
+		k++
+	}
+
+	cv, err := challenge(aBar, bBar, d, t1, t2, indices, ms, domain, ph)
+	if err != nil {
+		return err
+	}
+	if cv.Cmp(c) != 0 {
+		return ErrInvalidProof
+	}
+
+	// e(Abar, W) == e(Bbar, P2)
+	p := bls12381.NewPairingEngine()
+	p.AddPair(aBar, w)
+	p.AddPairInv(bBar, bls12381.NewG2().One())
+	if !p.Check() {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+type generatorSet struct {
+	q1 *bls12381.PointG1
+	h  []*bls12381.PointG1
+}
+
+// generators returns the base point Q1 and `n` message generators.
+func generators(n int) (*generatorSet, error) {
+	gens := &generatorSet{h: make([]*bls12381.PointG1, n)}
+	var err error
+	if gens.q1, err = h2c.HashToG1([]byte("Q1"), dstGenerator); err != nil {
+		return nil, err
+	}
+	for i := range gens.h {
+		var idx [8]byte
+		binary.BigEndian.PutUint64(idx[:], uint64(i+1))
+		if gens.h[i], err = h2c.HashToG1(append([]byte("H"), idx[:]...), dstGenerator); err != nil {
+			return nil, err
+		}
+	}
+	return gens, nil
+}
+
+// calculateDomain binds signatures to the public key, the generators and the
+// header.
+func calculateDomain(pk []byte, gens *generatorSet, header []byte) (*big.Int, error) {
+	g1 := bls12381.NewG1()
+	in := append([]byte(nil), pk...)
+	in = appendUint64(in, uint64(len(gens.h)))
+	in = append(in, g1.ToBytes(gens.q1)...)
+	for _, h := range gens.h {
+		in = append(in, g1.ToBytes(h)...)
+	}
+	in = appendUint64(in, uint64(len(header)))
+	in = append(in, header...)
+	return h2c.HashToScalar(in, dstScalar)
+}
+
+// commitment computes P1 + Q1 * domain + sum(H_i * m_i). If `indices` is not
+// nil, only the messages at these indices are included.
+func commitment(gens *generatorSet, domain *big.Int, ms []*big.Int, indices []int) *bls12381.PointG1 {
+	g1 := bls12381.NewG1()
+	b := g1.Add(g1.New(), g1.One(), g1.MulScalar(g1.New(), gens.q1, domain))
+	if indices == nil {
+		for i, m := range ms {
+			g1.Add(b, b, g1.MulScalar(g1.New(), gens.h[i], m))
+		}
+	} else {
+		for _, i := range indices {
+			g1.Add(b, b, g1.MulScalar(g1.New(), gens.h[i], ms[i]))
+		}
+	}
+	return g1.Affine(b)
+}
+
+// challenge computes the Fiat-Shamir challenge of a proof.
+func challenge(aBar, bBar, d, t1, t2 *bls12381.PointG1, disclosed []int, ms []*big.Int, domain *big.Int, ph []byte) (*big.Int, error) {
+	g1 := bls12381.NewG1()
+	var in []byte
+	for _, p := range []*bls12381.PointG1{aBar, bBar, d, t1, t2} {
+		in = append(in, g1.ToBytes(p)...)
+	}
+	in = appendUint64(in, uint64(len(disclosed)))
+	for _, i := range disclosed {
+		in = appendUint64(in, uint64(i))
+		in = append(in, encodeScalar(ms[i]).bytes()...)
+	}
+	in = append(in, encodeScalar(domain).bytes()...)
+	in = appendUint64(in, uint64(len(ph)))
+	in = append(in, ph...)
+	return h2c.HashToScalar(in, dstScalar)
+}
+
+// indexSet checks that `indices` are distinct, sorted, and smaller than `n`.
+func indexSet(indices []int, n int) (map[int]bool, error) {
+	set := make(map[int]bool, len(indices))
+	for k, i := range indices {
+		if i < 0 || i >= n || (k > 0 && i <= indices[k-1]) {
+			return nil, fmt.Errorf("invalid disclosed indices: %v", indices)
+		}
+		set[i] = true
+	}
+	return set, nil
+}
+
+func decodePublicKey(b []byte) (*bls12381.PointG2, error) {
+	g2 := bls12381.NewG2()
+	w, err := g2.FromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	if g2.IsZero(w) || !g2.InCorrectSubgroup(w) {
+		return nil, errors.New("invalid public key")
+	}
+	return w, nil
+}
+
+func decodeSignature(b []byte) (*bls12381.PointG1, *big.Int, error) {
+	if len(b) != SignatureLen {
+		return nil, nil, ErrInvalidSignature
+	}
+	a, err := decodeG1(b[:96])
+	if err != nil || bls12381.NewG1().IsZero(a) {
+		return nil, nil, ErrInvalidSignature
+	}
+	e, err := decodeScalar(b[96:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, e, nil
+}
+
+func decodeG1(b []byte) (*bls12381.PointG1, error) {
+	g1 := bls12381.NewG1()
+	p, err := g1.FromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	if !g1.InCorrectSubgroup(p) {
+		return nil, errors.New("point not in subgroup")
+	}
+	return p, nil
+}
+
+func decodeScalar(b []byte) (*big.Int, error) {
+	s := new(big.Int).SetBytes(b)
+	if s.Cmp(h2c.R) >= 0 {
+		return nil, errors.New("scalar out of range")
+	}
+	return s, nil
+}
+
+func decodeScalars(msgs []Scalar) []*big.Int {
+	ms := make([]*big.Int, len(msgs))
+	for i, m := range msgs {
+		ms[i] = new(big.Int).Mod(new(big.Int).SetBytes(m[:]), h2c.R)
+	}
+	return ms
+}
+
+func encodeScalar(s *big.Int) Scalar {
+	var b Scalar
+	s.FillBytes(b[:])
+	return b
+}
+
+func (s Scalar) bytes() []byte { return s[:] }
+
+func randomScalar(r io.Reader) (*big.Int, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	for {
+		k, err := rand.Int(r, h2c.R)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() != 0 {
+			return k, nil
+		}
+	}
+}
+
+func randomScalars(r io.Reader, n int) ([]*big.Int, error) {
+	s := make([]*big.Int, n)
+	for i := range s {
+		var err error
+		if s[i], err = randomScalar(r); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func addMod(a, b *big.Int) *big.Int { return new(big.Int).Mod(new(big.Int).Add(a, b), h2c.R) }
+func subMod(a, b *big.Int) *big.Int { return new(big.Int).Mod(new(big.Int).Sub(a, b), h2c.R) }
+func mulMod(a, b *big.Int) *big.Int { return new(big.Int).Mod(new(big.Int).Mul(a, b), h2c.R) }
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,121 @@
+package bbs_test
+
+import (
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/app/bbs"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	header = []byte("header")
+	ph     = []byte("presentation header")
+)
+
+func setup(t *testing.T, n int) (*bbs.SecretKey, []bbs.Scalar, []byte) {
+	sk, err := bbs.GenerateKey(nil)
+	require.NoError(t, err)
+	msgs := make([]bbs.Scalar, n)
+	for i := range msgs {
+		msgs[i] = bbs.MessageScalar([]byte{byte(i)})
+	}
+	sig, err := sk.Sign(header, msgs)
+	require.NoError(t, err)
+	require.Len(t, sig, bbs.SignatureLen)
+	return sk, msgs, sig
+}
+
+func TestSignVerify(t *testing.T) {
+	sk, msgs, sig := setup(t, 4)
+	pk := sk.PublicKey()
+	require.Len(t, pk, bbs.PublicKeyLen)
+	require.NoError(t, bbs.Verify(pk, header, msgs, sig))
+
+	other, err := bbs.GenerateKey(nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, bbs.Verify(other.PublicKey(), header, msgs, sig), bbs.ErrInvalidSignature, "wrong key")
+	require.ErrorIs(t, bbs.Verify(pk, []byte("other"), msgs, sig), bbs.ErrInvalidSignature, "wrong header")
+	require.ErrorIs(t, bbs.Verify(pk, header, msgs[:3], sig), bbs.ErrInvalidSignature, "dropped message")
+
+	forged := append([]bbs.Scalar(nil), msgs...)
+	forged[2] = bbs.MessageScalar([]byte("forged"))
+	require.ErrorIs(t, bbs.Verify(pk, header, forged, sig), bbs.ErrInvalidSignature, "changed message")
+	forged = append([]bbs.Scalar(nil), msgs...)
+	forged[0], forged[1] = forged[1], forged[0]
+	require.ErrorIs(t, bbs.Verify(pk, header, forged, sig), bbs.ErrInvalidSignature, "reordered messages")
+
+	for _, i := range []int{0, 95, bbs.SignatureLen - 1} {
+		tampered := append([]byte(nil), sig...)
+		tampered[i] ^= 1
+		require.Error(t, bbs.Verify(pk, header, msgs, tampered), "tampered byte %d", i)
+	}
+	require.Error(t, bbs.Verify(pk, header, msgs, sig[:bbs.SignatureLen-1]), "truncated")
+}
+
+func TestProof(t *testing.T) {
+	sk, msgs, sig := setup(t, 5)
+	pk := sk.PublicKey()
+	proof, err := bbs.DeriveProof(pk, header, ph, msgs, sig, []int{1, 3})
+	require.NoError(t, err)
+	disclosed := map[int]bbs.Scalar{1: msgs[1], 3: msgs[3]}
+	require.NoError(t, bbs.VerifyProof(pk, header, ph, len(msgs), disclosed, proof))
+
+	// Proofs are unlinkable.
+	again, err := bbs.DeriveProof(pk, header, ph, msgs, sig, []int{1, 3})
+	require.NoError(t, err)
+	require.NotEqual(t, proof, again)
+
+	// Nothing or everything may be disclosed.
+	for _, idx := range [][]int{nil, {0, 1, 2, 3, 4}} {
+		proof, err := bbs.DeriveProof(pk, header, ph, msgs, sig, idx)
+		require.NoError(t, err)
+		disclosed := make(map[int]bbs.Scalar)
+		for _, i := range idx {
+			disclosed[i] = msgs[i]
+		}
+		require.NoError(t, bbs.VerifyProof(pk, header, ph, len(msgs), disclosed, proof))
+	}
+}
+
+func TestProofForgery(t *testing.T) {
+	sk, msgs, sig := setup(t, 5)
+	pk := sk.PublicKey()
+	proof, err := bbs.DeriveProof(pk, header, ph, msgs, sig, []int{1, 3})
+	require.NoError(t, err)
+	disclosed := map[int]bbs.Scalar{1: msgs[1], 3: msgs[3]}
+
+	other, err := bbs.GenerateKey(nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, bbs.VerifyProof(other.PublicKey(), header, ph, len(msgs), disclosed, proof), bbs.ErrInvalidProof, "wrong key")
+	require.ErrorIs(t, bbs.VerifyProof(pk, []byte("other"), ph, len(msgs), disclosed, proof), bbs.ErrInvalidProof, "wrong header")
+	require.ErrorIs(t, bbs.VerifyProof(pk, header, []byte("replayed"), len(msgs), disclosed, proof), bbs.ErrInvalidProof, "wrong presentation header")
+	require.ErrorIs(t, bbs.VerifyProof(pk, header, ph, len(msgs)+1, disclosed, proof), bbs.ErrInvalidProof, "wrong message count")
+
+	forged := map[int]bbs.Scalar{1: msgs[1], 3: bbs.MessageScalar([]byte("forged"))}
+	require.ErrorIs(t, bbs.VerifyProof(pk, header, ph, len(msgs), forged, proof), bbs.ErrInvalidProof, "changed message")
+	moved := map[int]bbs.Scalar{1: msgs[1], 2: msgs[3]}
+	require.ErrorIs(t, bbs.VerifyProof(pk, header, ph, len(msgs), moved, proof), bbs.ErrInvalidProof, "moved message")
+	require.ErrorIs(t, bbs.VerifyProof(pk, header, ph, len(msgs), map[int]bbs.Scalar{1: msgs[1]}, proof), bbs.ErrInvalidProof, "hidden message")
+	outOfRange := map[int]bbs.Scalar{1: msgs[1], 3: msgs[3], 5: msgs[0]}
+	require.ErrorIs(t, bbs.VerifyProof(pk, header, ph, len(msgs), outOfRange, proof), bbs.ErrInvalidProof, "index out of range")
+
+	for _, i := range []int{0, 100, 200, len(proof) - 1} {
+		tampered := append([]byte(nil), proof...)
+		tampered[i] ^= 1
+		require.ErrorIs(t, bbs.VerifyProof(pk, header, ph, len(msgs), disclosed, tampered), bbs.ErrInvalidProof, "tampered byte %d", i)
+	}
+	require.ErrorIs(t, bbs.VerifyProof(pk, header, ph, len(msgs), disclosed, proof[:len(proof)-1]), bbs.ErrInvalidProof, "truncated")
+}
+
+func TestDeriveProofInvalid(t *testing.T) {
+	sk, msgs, sig := setup(t, 3)
+	pk := sk.PublicKey()
+	forged := append([]bbs.Scalar(nil), msgs...)
+	forged[0] = bbs.MessageScalar([]byte("forged"))
+	_, err := bbs.DeriveProof(pk, header, ph, forged, sig, []int{0})
+	require.ErrorIs(t, err, bbs.ErrInvalidSignature)
+	_, err = bbs.DeriveProof(pk, header, ph, msgs, sig, []int{3})
+	require.Error(t, err, "index out of range")
+	_, err = bbs.DeriveProof(pk, header, ph, msgs, sig, []int{1, 1})
+	require.Error(t, err, "duplicate index")
+}
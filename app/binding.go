@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+)
+
+var (
+	ErrNotBound    = errors.New("credential not bound to a holder")
+	ErrWrongHolder = errors.New("wrong holder")
+)
+
+// holderProofHash returns the hash that a holder signs to prove possession
+// of credential `c` for `challenge`.
+func holderProofHash(c *Credential, challenge []byte) Hash {
+	id := c.ID()
+	return crypto.Keccak256Hash(id[:], challenge)
+}
+
+// VerifyHolder checks that credential `c` is bound to the holder DID in its
+// metadata and that this DID controls address `holder`.
+func (c *Credential) VerifyHolder(ctx context.Context, r did.Resolver, holder common.Address) error {
+	if c.Metadata == nil || c.Metadata.Holder == "" {
+		return ErrNotBound
+	}
+	doc, err := r.Resolve(ctx, c.Metadata.Holder)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", c.Metadata.Holder, err)
+	} else if !doc.Controls(holder) {
+		return fmt.Errorf("%w: %s does not control %v", ErrWrongHolder, c.Metadata.Holder, holder)
+	}
+	return nil
+}
+
+// ProveHolder signs `challenge` for credential `c` with the holder account
+// `acc`, so that a verifier can check that the presenter is the holder the
+// credential is bound to.
+func ProveHolder(acc Account, c *Credential, challenge []byte) ([]byte, error) {
+	sig, err := SignHash(acc, holderProofHash(c, challenge))
+	if err != nil {
+		return nil, err
+	}
+	return sig[:], nil
+}
+
+// VerifyHolderProof checks that `proof` was made by ProveHolder for
+// `challenge` with a key that the holder DID of credential `c` controls.
+// Verifiers should pick a fresh challenge for every presentation.
+func VerifyHolderProof(ctx context.Context, r did.Resolver, c *Credential, challenge, proof []byte) error {
+	if len(proof) != data.SigLen {
+		return ErrInvalidSignature
+	}
+	var sig [data.SigLen]byte
+	copy(sig[:], proof)
+	signer, err := RecoverSigner(sig, holderProofHash(c, challenge))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	return c.VerifyHolder(ctx, r, signer)
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app/abi"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// certificateTag separates certificate signatures from other signatures.
+const certificateTag = "perun-credential-payment/issuer-certificate"
+
+var ErrUntrustedIssuer = errors.New("untrusted issuer")
+
+// IssuerCertificate certifies that Subject may issue credentials. It is
+// signed by Authority, which is either a trust anchor or certified itself.
+type IssuerCertificate struct {
+	Subject    common.Address `json:"subject"`
+	Authority  common.Address `json:"authority"`
+	CA         bool           `json:"ca"` // Whether Subject may certify other issuers.
+	ValidFrom  uint64         `json:"validFrom"`
+	ValidUntil uint64         `json:"validUntil"`
+	Signature  []byte         `json:"signature"`
+}
+
+var certificateArgs = abi.Arguments{
+	{Type: abi.String},
+	{Type: abi.Address},
+	{Type: abi.Address},
+	{Type: abi.Bool},
+	{Type: abi.Uint64},
+	{Type: abi.Uint64},
+}
+
+// Hash returns the hash of the certificate fields covered by the signature.
+func (c *IssuerCertificate) Hash() (Hash, error) {
+	b, err := certificateArgs.Pack(certificateTag, c.Subject, c.Authority, c.CA, c.ValidFrom, c.ValidUntil)
+	if err != nil {
+		return Hash{}, fmt.Errorf("encoding certificate: %w", err)
+	}
+	return crypto.Keccak256Hash(b), nil
+}
+
+// Sign signs the certificate with `acc`, which must be the account of the
+// authority.
+func (c *IssuerCertificate) Sign(acc Account) error {
+	if AccountAddress(acc) != c.Authority {
+		return ErrInvalidSigner
+	}
+	h, err := c.Hash()
+	if err != nil {
+		return err
+	}
+	sig, err := SignHash(acc, h)
+	if err != nil {
+		return err
+	}
+	c.Signature = sig[:]
+	return nil
+}
+
+// Verify checks that the certificate is signed by the authority and valid at
+// time `now`.
+func (c *IssuerCertificate) Verify(now time.Time) error {
+	if len(c.Signature) != data.SigLen {
+		return ErrInvalidSignature
+	}
+	h, err := c.Hash()
+	if err != nil {
+		return err
+	}
+	var sig [data.SigLen]byte
+	copy(sig[:], c.Signature)
+	if err := VerifySig(sig, h, c.Authority); err != nil {
+		return err
+	}
+
+	t := uint64(now.Unix())
+	if t < c.ValidFrom || t > c.ValidUntil {
+		return fmt.Errorf("certificate of %v not valid at %v", c.Subject, now)
+	}
+	return nil
+}
+
+// VerifyChain checks that certificate chain `chain` certifies `issuer` up to
+// one of the trust anchors at time `now`. The first certificate must certify
+// the issuer and every further certificate the authority of the previous
+// one. An issuer that is a trust anchor needs no chain.
+func VerifyChain(chain []*IssuerCertificate, issuer common.Address, anchors []common.Address, now time.Time) error {
+	subject := issuer
+	for i := 0; ; i++ {
+		for _, a := range anchors {
+			if a == subject {
+				return nil
+			}
+		}
+		if i == len(chain) {
+			return fmt.Errorf("%w: chain of %v does not end at a trust anchor", ErrUntrustedIssuer, issuer)
+		}
+
+		c := chain[i]
+		if c.Subject != subject {
+			return fmt.Errorf("%w: certificate %d is for %v, expected %v", ErrUntrustedIssuer, i, c.Subject, subject)
+		} else if i > 0 && !c.CA {
+			return fmt.Errorf("%w: %v may not certify issuers", ErrUntrustedIssuer, c.Subject)
+		} else if err := c.Verify(now); err != nil {
+			return fmt.Errorf("%w: certificate %d: %v", ErrUntrustedIssuer, i, err)
+		}
+		subject = c.Authority
+	}
+}
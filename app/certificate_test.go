@@ -0,0 +1,50 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// certify returns a certificate of `subject` signed by `authority`, valid
+// from 1000 to 2000.
+func certify(t *testing.T, authority Account, subject common.Address, ca bool) *IssuerCertificate {
+	t.Helper()
+	c := &IssuerCertificate{Subject: subject, Authority: AccountAddress(authority), CA: ca, ValidFrom: 1000, ValidUntil: 2000}
+	require.NoError(t, c.Sign(authority))
+	return c
+}
+
+func TestVerifyChain(t *testing.T) {
+	root, ca, issuer := newAccount(t), newAccount(t), newAccount(t)
+	rootAddr, caAddr, issuerAddr := AccountAddress(root), AccountAddress(ca), AccountAddress(issuer)
+	anchors := []common.Address{rootAddr}
+	now := time.Unix(1500, 0)
+
+	toIssuer := certify(t, ca, issuerAddr, false)
+	toCA := certify(t, root, caAddr, true)
+	require.NoError(t, VerifyChain([]*IssuerCertificate{toIssuer, toCA}, issuerAddr, anchors, now))
+	require.NoError(t, VerifyChain(nil, rootAddr, anchors, now), "trust anchor")
+	require.NoError(t, VerifyChain([]*IssuerCertificate{certify(t, root, issuerAddr, false)}, issuerAddr, anchors, now), "certified by anchor")
+
+	for name, chain := range map[string][]*IssuerCertificate{
+		"no chain":        nil,
+		"incomplete":      {toIssuer},
+		"wrong order":     {toCA, toIssuer},
+		"not a CA":        {toIssuer, certify(t, root, caAddr, false)},
+		"other authority": {certify(t, issuer, issuerAddr, false), toCA},
+	} {
+		require.ErrorIs(t, VerifyChain(chain, issuerAddr, anchors, now), ErrUntrustedIssuer, name)
+	}
+	for _, at := range []int64{999, 2001} {
+		require.ErrorIs(t, VerifyChain([]*IssuerCertificate{toIssuer, toCA}, issuerAddr, anchors, time.Unix(at, 0)), ErrUntrustedIssuer, "at %d", at)
+	}
+
+	// The fields are covered by the signature.
+	promoted := *toIssuer
+	promoted.CA = true
+	require.Error(t, promoted.Verify(now))
+	require.ErrorIs(t, (&IssuerCertificate{Authority: rootAddr}).Sign(ca), ErrInvalidSigner)
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app/abi"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// chunkTag separates chunk signatures from other signatures.
+const chunkTag = "perun-credential-payment/chunk"
+
+var chunkArgs = abi.Arguments{
+	{Type: abi.String},
+	{Type: abi.Bytes32},
+	{Type: abi.Uint64},
+	{Type: abi.Uint64},
+	{Type: abi.Bytes},
+}
+
+// SplitDocument splits `doc` into chunks of at most `size` bytes. The size
+// must be positive.
+func SplitDocument(doc []byte, size int) [][]byte {
+	chunks := make([][]byte, 0, (len(doc)+size-1)/size)
+	for len(doc) > size {
+		chunks = append(chunks, doc[:size])
+		doc = doc[size:]
+	}
+	return append(chunks, doc)
+}
+
+// SplitPrice splits `price` into `n` partial payments. The remainder is added
+// to the last payment.
+func SplitPrice(price *big.Int, n int) []*big.Int {
+	div, rem := new(big.Int).QuoRem(price, big.NewInt(int64(n)), new(big.Int))
+	prices := make([]*big.Int, n)
+	for i := range prices {
+		prices[i] = new(big.Int).Set(div)
+	}
+	prices[n-1].Add(prices[n-1], rem)
+	return prices
+}
+
+// ChunkDocument returns the document that is signed for chunk `i` of `n`
+// chunks of the document with hash `root`. It binds the chunk to its
+// document and position, so that chunk signatures cannot be reordered,
+// spliced into other documents, or presented as credentials on the chunk
+// alone.
+func ChunkDocument(root Hash, i, n int, chunk []byte) []byte {
+	enc, err := chunkArgs.Pack(chunkTag, root, uint64(i), uint64(n), chunk)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// ChunkedCredential is a credential on a document that was issued in chunks.
+// Each chunk is signed individually, as document ChunkDocument.
+type ChunkedCredential struct {
+	Document   []byte
+	ChunkSize  int
+	Signatures [][data.SigLen]byte
+	Domain     Hash // EIP-712 domain separator. Zero if the raw hashes are signed.
+}
+
+// Verify verifies that every chunk of the document is signed by `issuer`.
+func (c *ChunkedCredential) Verify(issuer common.Address) error {
+	chunks := SplitDocument(c.Document, c.ChunkSize)
+	if len(chunks) != len(c.Signatures) {
+		return errors.New("wrong number of signatures")
+	}
+	root := ComputeDocumentHash(c.Document)
+	for i, chunk := range chunks {
+		h := TypedCredentialHash(c.Domain, crypto.Keccak256Hash(ChunkDocument(root, i, len(chunks), chunk)), 0, Hash{})
+		if err := VerifySig(c.Signatures[i], h, issuer); err != nil {
+			return fmt.Errorf("verifying chunk %d: %w", i, err)
+		}
+	}
+	return nil
+}
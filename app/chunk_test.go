@@ -0,0 +1,75 @@
+package app
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/stretchr/testify/require"
+)
+
+// signChunks signs the chunks of `doc` like the issuer does in chunked
+// issuance.
+func signChunks(t *testing.T, acc Account, doc []byte, size int) *ChunkedCredential {
+	t.Helper()
+	chunks := SplitDocument(doc, size)
+	cred := &ChunkedCredential{Document: doc, ChunkSize: size, Signatures: make([][data.SigLen]byte, len(chunks))}
+	for i, chunk := range chunks {
+		sig, err := SignHash(acc, crypto.Keccak256Hash(ChunkDocument(ComputeDocumentHash(doc), i, len(chunks), chunk)))
+		require.NoError(t, err)
+		cred.Signatures[i] = sig
+	}
+	return cred
+}
+
+func TestChunkedCredential(t *testing.T) {
+	acc := newAccount(t)
+	issuer := AccountAddress(acc)
+	cred := signChunks(t, acc, []byte("abcabcxyz"), 3)
+	require.NoError(t, cred.Verify(issuer))
+
+	t.Run("reordered", func(t *testing.T) {
+		// Chunks 0 and 1 are equal, so only their positions differ.
+		sigs := append([][data.SigLen]byte{}, cred.Signatures...)
+		sigs[0], sigs[1] = sigs[1], sigs[0]
+		require.Error(t, (&ChunkedCredential{Document: cred.Document, ChunkSize: 3, Signatures: sigs}).Verify(issuer))
+	})
+
+	t.Run("spliced", func(t *testing.T) {
+		// The first chunk of another document is spliced into the credential.
+		other := signChunks(t, acc, []byte("abcdefxyz"), 3)
+		sigs := append([][data.SigLen]byte{other.Signatures[0]}, cred.Signatures[1:]...)
+		require.Error(t, (&ChunkedCredential{Document: cred.Document, ChunkSize: 3, Signatures: sigs}).Verify(issuer))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		// A prefix of the chunks is not a credential on the prefix.
+		require.Error(t, (&ChunkedCredential{Document: []byte("abcabc"), ChunkSize: 3, Signatures: cred.Signatures[:2]}).Verify(issuer))
+	})
+
+	t.Run("single chunk", func(t *testing.T) {
+		// A chunk signature is not a credential on the chunk.
+		c := &Credential{Document: []byte("xyz"), Signature: cred.Signatures[2][:]}
+		require.Error(t, VerifyCredential(c, issuer, time.Now()))
+	})
+}
+
+func TestSplitDocument(t *testing.T) {
+	require.Equal(t, [][]byte{[]byte("abc"), []byte("abc"), []byte("xy")}, SplitDocument([]byte("abcabcxy"), 3))
+	require.Equal(t, [][]byte{[]byte("abc")}, SplitDocument([]byte("abc"), 3))
+	require.Len(t, SplitDocument(nil, 3), 1, "empty document")
+}
+
+func TestSplitPrice(t *testing.T) {
+	prices := SplitPrice(big.NewInt(11), 3)
+	require.Equal(t, []*big.Int{big.NewInt(3), big.NewInt(3), big.NewInt(5)}, prices)
+
+	// The partial payments add up to the price.
+	sum := new(big.Int)
+	for _, p := range SplitPrice(big.NewInt(1000003), 7) {
+		sum.Add(sum, p)
+	}
+	require.Zero(t, sum.Cmp(big.NewInt(1000003)))
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	appabi "github.com/perun-network/perun-credential-payment/app/abi"
+	"perun.network/go-perun/channel"
+)
+
+// MaxBatchSize is the maximum number of documents in a batch.
+const MaxBatchSize = 64
+
+// BatchOffer represents an offer for credentials on multiple documents at an
+// aggregate price.
+type BatchOffer struct {
+	Issuer     common.Address
+	DataHashes [][HashLen]byte
+	Price      *big.Int
+	Buyer      uint16
+	Nonce      uint64 // Version of the state that makes the offer, see Offer.Nonce.
+}
+
+func (a BatchOffer) Equal(b *BatchOffer) bool {
+	if len(a.DataHashes) != len(b.DataHashes) {
+		return false
+	}
+	for i := range a.DataHashes {
+		if a.DataHashes[i] != b.DataHashes[i] {
+			return false
+		}
+	}
+	return a.Issuer == b.Issuer &&
+		a.Price.Cmp(b.Price) == 0 &&
+		a.Buyer == b.Buyer &&
+		a.Nonce == b.Nonce
+}
+
+var batchOfferType = func() abi.Type {
+	t, err := abi.NewType(
+		"tuple",
+		"batchOffer",
+		[]abi.ArgumentMarshaling{
+			{Type: "address", Name: "issuer"},
+			{Type: "bytes32[]", Name: "dataHashes"},
+			{Type: "uint256", Name: "price"},
+			{Type: "uint16", Name: "buyer"},
+			{Type: "uint64", Name: "nonce"},
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}()
+
+var batchOfferArgs = appabi.Arguments{
+	{Name: "batchOffer", Type: batchOfferType},
+}
+
+// Encode encodes app data onto an io.Writer.
+func (d *BatchOffer) Encode(w io.Writer) error {
+	body, err := batchOfferArgs.Pack(d)
+	if err != nil {
+		return err
+	}
+
+	f := &dataFrame{
+		Mode: batchOfferMode,
+		Data: body,
+	}
+	return f.Encode(w)
+}
+
+func (d *BatchOffer) Unmarshal(b []byte) error {
+	return appabi.Unpack(b, d, batchOfferArgs)
+}
+
+// Clone returns a deep copy of the app data.
+func (d *BatchOffer) Clone() channel.Data {
+	_d := *d
+	_d.DataHashes = append([][HashLen]byte(nil), d.DataHashes...)
+	_d.Price = new(big.Int).Set(d.Price)
+	return &_d
+}
+
+// BatchCert represents the response to a batch offer. It holds one signature
+// per requested document.
+type BatchCert struct {
+	Signatures [][SigLen]byte
+}
+
+// Encode encodes the data onto an io.Writer. The signatures are
+// concatenated.
+func (d *BatchCert) Encode(w io.Writer) error {
+	body := make([]byte, 0, len(d.Signatures)*SigLen)
+	for _, sig := range d.Signatures {
+		body = append(body, sig[:]...)
+	}
+
+	f := &dataFrame{
+		Mode: batchCertMode,
+		Data: body,
+	}
+	return f.Encode(w)
+}
+
+// Clone returns a deep copy of the app data.
+func (d *BatchCert) Clone() channel.Data {
+	return &BatchCert{append([][SigLen]byte(nil), d.Signatures...)}
+}
+
+func (d *BatchCert) Unmarshal(b []byte) error {
+	if len(b)%SigLen != 0 {
+		return fmt.Errorf("invalid signatures length")
+	}
+	d.Signatures = make([][SigLen]byte, len(b)/SigLen)
+	for i := range d.Signatures {
+		copy(d.Signatures[i][:], b[i*SigLen:])
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"math/big"
@@ -8,6 +9,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	appabi "github.com/perun-network/perun-credential-payment/app/abi"
+	"github.com/perun-network/perun-credential-payment/app/bbs"
 	"perun.network/go-perun/channel"
 )
 
@@ -22,16 +24,24 @@ const (
 	defaultMode Mode = iota
 	offerMode
 	certMode
+	counterOfferMode
+	batchOfferMode
+	batchCertMode
 )
 
 // DefaultData represents the default state.
-type DefaultData struct{}
+type DefaultData struct {
+	// Tenant identifies the issuer hosted by the peer with which the channel
+	// is opened. It is only set in the initial state of a channel and is
+	// ignored by the contract.
+	Tenant string
+}
 
 // Encode encodes the data onto an io.Writer.
 func (d *DefaultData) Encode(w io.Writer) error {
 	f := &dataFrame{
 		Mode: defaultMode,
-		Data: []byte{},
+		Data: []byte(d.Tenant),
 	}
 	return f.Encode(w)
 }
@@ -44,17 +54,76 @@ func (d *DefaultData) Clone() channel.Data {
 
 // Offer represents an offer.
 type Offer struct {
-	Issuer   common.Address
-	DataHash [HashLen]byte
-	Price    *big.Int
-	Buyer    uint16
+	Issuer    common.Address
+	DataHash  [HashLen]byte
+	Price     *big.Int
+	Buyer     uint16
+	Expiry    uint64           // Unix time at which the credential expires. Zero if it does not expire.
+	MetaHash  [HashLen]byte    // Hash of the credential metadata. Zero if there is none.
+	Cosigners []common.Address // Additional required signers of the credential.
+	Domain    [HashLen]byte    // EIP-712 domain separator. Zero if the raw credential hash is signed.
+
+	// Attributes are the BBS message scalars of the attributes of the
+	// credential. BBSKey is the BBS public key of the issuer. Both are empty
+	// if the credential has no BBS signature.
+	Attributes [][HashLen]byte
+	BBSKey     []byte
+
+	// Fee is the part of the price that goes to the fee recipient, the third
+	// participant of the channel. Nil is treated as zero.
+	Fee *big.Int
+
+	// Nonce is the version of the state that makes the offer. It binds the
+	// offer to its place in the channel, so that a signed offer cannot be
+	// replayed in a later state.
+	Nonce uint64
+}
+
+// FeeAmount returns the fee of the offer, which is zero if unset.
+func (a *Offer) FeeAmount() *big.Int {
+	if a.Fee == nil {
+		return new(big.Int)
+	}
+	return a.Fee
 }
 
 func (a Offer) Equal(b *Offer) bool {
 	return a.Issuer == b.Issuer &&
 		a.DataHash == b.DataHash &&
 		a.Price.Cmp(b.Price) == 0 &&
-		a.Buyer == b.Buyer
+		a.Buyer == b.Buyer &&
+		a.Expiry == b.Expiry &&
+		a.MetaHash == b.MetaHash &&
+		equalAddresses(a.Cosigners, b.Cosigners) &&
+		a.Domain == b.Domain &&
+		equalHashes(a.Attributes, b.Attributes) &&
+		bytes.Equal(a.BBSKey, b.BBSKey) &&
+		a.FeeAmount().Cmp(b.FeeAmount()) == 0 &&
+		a.Nonce == b.Nonce
+}
+
+func equalAddresses(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalHashes(a, b [][HashLen]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 var offerType = func() abi.Type {
@@ -66,6 +135,14 @@ var offerType = func() abi.Type {
 			{Type: "bytes32", Name: "dataHash"},
 			{Type: "uint256", Name: "price"},
 			{Type: "uint16", Name: "buyer"},
+			{Type: "uint64", Name: "expiry"},
+			{Type: "bytes32", Name: "metaHash"},
+			{Type: "address[]", Name: "cosigners"},
+			{Type: "bytes32", Name: "domain"},
+			{Type: "bytes32[]", Name: "attributes"},
+			{Type: "bytes", Name: "bBSKey"},
+			{Type: "uint256", Name: "fee"},
+			{Type: "uint64", Name: "nonce"},
 		},
 	)
 	if err != nil {
@@ -80,7 +157,7 @@ var offerArgs = appabi.Arguments{
 
 // Encode encodes app data onto an io.Writer.
 func (d *Offer) Encode(w io.Writer) error {
-	body, err := offerArgs.Pack(d)
+	body, err := d.pack()
 	if err != nil {
 		return err
 	}
@@ -92,6 +169,13 @@ func (d *Offer) Encode(w io.Writer) error {
 	return f.Encode(w)
 }
 
+// pack returns the ABI encoding of the offer.
+func (d *Offer) pack() ([]byte, error) {
+	o := *d
+	o.Fee = d.FeeAmount()
+	return offerArgs.Pack(&o)
+}
+
 func (d *Offer) Unmarshal(b []byte) error {
 	return appabi.Unpack(b, d, offerArgs)
 }
@@ -100,19 +184,61 @@ func (d *Offer) Unmarshal(b []byte) error {
 func (d *Offer) Clone() channel.Data {
 	_d := *d
 	_d.Price = new(big.Int).Set(d.Price)
+	_d.Cosigners = append([]common.Address(nil), d.Cosigners...)
+	_d.Attributes = append([][HashLen]byte(nil), d.Attributes...)
+	_d.BBSKey = append([]byte(nil), d.BBSKey...)
+	if d.Fee != nil {
+		_d.Fee = new(big.Int).Set(d.Fee)
+	}
 	return &_d
 }
 
-// Cert represents an offer response.
+// CounterOffer represents an offer made by the issuer in response to an
+// offer by the buyer.
+type CounterOffer struct {
+	Offer
+}
+
+// Encode encodes app data onto an io.Writer.
+func (d *CounterOffer) Encode(w io.Writer) error {
+	body, err := d.Offer.pack()
+	if err != nil {
+		return err
+	}
+
+	f := &dataFrame{
+		Mode: counterOfferMode,
+		Data: body,
+	}
+	return f.Encode(w)
+}
+
+// Clone returns a deep copy of the app data.
+func (d *CounterOffer) Clone() channel.Data {
+	return &CounterOffer{*d.Offer.Clone().(*Offer)}
+}
+
+// Cert represents an offer response. It holds the signature of the issuer,
+// followed by the signatures of the cosigners and, if requested, the BBS
+// signature of the issuer.
 type Cert struct {
-	Signature [SigLen]byte
+	Signature    [SigLen]byte
+	CoSignatures [][SigLen]byte
+	BBSSignature []byte
 }
 
-// Encode encodes the data onto an io.Writer.
+// Encode encodes the data onto an io.Writer. The signatures are
+// concatenated.
 func (d *Cert) Encode(w io.Writer) error {
+	body := append([]byte(nil), d.Signature[:]...)
+	for _, sig := range d.CoSignatures {
+		body = append(body, sig[:]...)
+	}
+	body = append(body, d.BBSSignature...)
+
 	f := &dataFrame{
 		Mode: certMode,
-		Data: d.Signature[:],
+		Data: body,
 	}
 	return f.Encode(w)
 }
@@ -120,14 +246,28 @@ func (d *Cert) Encode(w io.Writer) error {
 // Clone returns a deep copy of the app data.
 func (d *Cert) Clone() channel.Data {
 	_d := *d
+	_d.CoSignatures = append([][SigLen]byte(nil), d.CoSignatures...)
+	_d.BBSSignature = append([]byte(nil), d.BBSSignature...)
 	return &_d
 }
 
+// Unmarshal decodes the signatures. A trailing BBS signature is detected by
+// the length, which is not a multiple of SigLen.
 func (d *Cert) Unmarshal(b []byte) error {
-	if len(b) != len(d.Signature) {
+	if n := len(b) - bbs.SignatureLen; n >= SigLen && n%SigLen == 0 {
+		d.BBSSignature = append([]byte(nil), b[n:]...)
+		b = b[:n]
+	}
+	if len(b) < SigLen || len(b)%SigLen != 0 {
 		return fmt.Errorf("invalid signature length")
 	}
 	copy(d.Signature[:], b)
+	if n := len(b)/SigLen - 1; n > 0 {
+		d.CoSignatures = make([][SigLen]byte, n)
+		for i := range d.CoSignatures {
+			copy(d.CoSignatures[i][:], b[(i+1)*SigLen:])
+		}
+	}
 	return nil
 }
 
@@ -140,13 +280,22 @@ func Decode(r io.Reader) (channel.Data, error) {
 
 	switch f.Mode {
 	case defaultMode:
-		return &DefaultData{}, nil
+		return &DefaultData{Tenant: string(f.Data)}, nil
 	case offerMode:
 		var offer Offer
 		return &offer, offer.Unmarshal(f.Data)
 	case certMode:
 		var cert Cert
 		return &cert, cert.Unmarshal(f.Data)
+	case counterOfferMode:
+		var counter CounterOffer
+		return &counter, counter.Unmarshal(f.Data)
+	case batchOfferMode:
+		var offer BatchOffer
+		return &offer, offer.Unmarshal(f.Data)
+	case batchCertMode:
+		var cert BatchCert
+		return &cert, cert.Unmarshal(f.Data)
 	default:
 		return nil, fmt.Errorf("unknown mode")
 	}
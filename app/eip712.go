@@ -0,0 +1,82 @@
+package app
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	appabi "github.com/perun-network/perun-credential-payment/app/abi"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+const (
+	// DomainName is the EIP-712 domain name of credential signatures.
+	DomainName = "CredentialSwap"
+	// DomainVersion is the EIP-712 domain version of credential signatures.
+	DomainVersion = "1"
+)
+
+var (
+	domainTypeHash     = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	credentialTypeHash = crypto.Keccak256Hash([]byte("Credential(bytes32 document,uint64 expiry,bytes32 metadata)"))
+)
+
+// Domain is the EIP-712 domain of credential signatures. The verifying
+// contract is the app contract.
+type Domain struct {
+	ChainID           *big.Int
+	VerifyingContract common.Address
+}
+
+var domainArgs = abi.Arguments{
+	{Type: appabi.Bytes32},
+	{Type: appabi.Bytes32},
+	{Type: appabi.Bytes32},
+	{Type: appabi.Uint256},
+	{Type: appabi.Address},
+}
+
+// Separator returns the EIP-712 domain separator.
+func (d Domain) Separator() Hash {
+	enc, err := domainArgs.Pack(
+		domainTypeHash,
+		crypto.Keccak256Hash([]byte(DomainName)),
+		crypto.Keccak256Hash([]byte(DomainVersion)),
+		d.ChainID,
+		d.VerifyingContract,
+	)
+	if err != nil {
+		panic(err)
+	}
+	return crypto.Keccak256Hash(enc)
+}
+
+var credentialStructArgs = abi.Arguments{
+	{Type: appabi.Bytes32},
+	{Type: appabi.Bytes32},
+	{Type: appabi.Uint64},
+	{Type: appabi.Bytes32},
+}
+
+// TypedCredentialHash returns the EIP-712 digest of a credential on the
+// document with hash `h`, in the domain with separator `domain`. If the
+// domain is zero, the raw CredentialHash is returned instead.
+func TypedCredentialHash(domain Hash, h Hash, expiry uint64, meta Hash) Hash {
+	if domain == (Hash{}) {
+		return CredentialHash(h, expiry, meta)
+	}
+
+	enc, err := credentialStructArgs.Pack(credentialTypeHash, h, expiry, meta)
+	if err != nil {
+		panic(err)
+	}
+	structHash := crypto.Keccak256Hash(enc)
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domain[:], structHash[:])
+}
+
+// OfferHash returns the hash signed by the issuer and the cosigners for
+// `offer`.
+func OfferHash(offer *data.Offer) Hash {
+	return TypedCredentialHash(offer.Domain, offer.DataHash, offer.Expiry, offer.MetaHash)
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// ERC1271ABI is the ABI of the signature validation interface of contract
+// accounts (EIP-1271).
+const ERC1271ABI = `[
+	{"inputs":[{"internalType":"bytes32","name":"hash","type":"bytes32"},{"internalType":"bytes","name":"signature","type":"bytes"}],"name":"isValidSignature","outputs":[{"internalType":"bytes4","name":"","type":"bytes4"}],"stateMutability":"view","type":"function"}
+]`
+
+// erc1271MagicValue is returned by contract accounts for valid signatures.
+var erc1271MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+// contractSigTimeout bounds the contract calls made while validating
+// channel transitions, which have no context.
+const contractSigTimeout = 10 * time.Second
+
+// ContractSigVerifier verifies signatures of contract accounts, e.g., Gnosis
+// Safes, according to EIP-1271.
+type ContractSigVerifier struct {
+	abi    abi.ABI
+	caller bind.ContractCaller
+}
+
+func NewContractSigVerifier(caller bind.ContractCaller) (*ContractSigVerifier, error) {
+	parsed, err := abi.JSON(strings.NewReader(ERC1271ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &ContractSigVerifier{parsed, caller}, nil
+}
+
+// Verify checks that contract account `signer` accepts signature `sig` on
+// `h`.
+func (v *ContractSigVerifier) Verify(ctx context.Context, sig []byte, h Hash, signer common.Address) error {
+	contract := bind.NewBoundContract(signer, v.abi, v.caller, nil, nil)
+	var out []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx}, &out, "isValidSignature", h, sig); err != nil {
+		return fmt.Errorf("%w: calling contract account: %v", ErrInvalidSigner, err)
+	}
+	if *abi.ConvertType(out[0], new([4]byte)).(*[4]byte) != erc1271MagicValue {
+		return ErrInvalidSigner
+	}
+	return nil
+}
+
+// verifySig verifies that `sig` is an ECDSA signature on `h` by `signer`. If
+// it is not and `v` is not nil, `signer` may also be a contract account that
+// accepts the signature.
+func verifySig(v *ContractSigVerifier, sig [data.SigLen]byte, h Hash, signer common.Address) error {
+	err := VerifySig(sig, h, signer)
+	if err == nil || v == nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), contractSigTimeout)
+	defer cancel()
+	if v.Verify(ctx, sig[:], h, signer) != nil {
+		return err
+	}
+	return nil
+}
+
+// VerifyCredential checks credential `c` as the package-level
+// VerifyCredential does, but also accepts signatures of contract account
+// `issuer`.
+func (v *ContractSigVerifier) VerifyCredential(ctx context.Context, c *Credential, issuer common.Address, now time.Time) error {
+	err := VerifyCredential(c, issuer, now)
+	if err == nil || errors.Is(err, ErrCredentialExpired) {
+		return err
+	}
+	if v.Verify(ctx, c.Signature, c.ID(), issuer) != nil {
+		return err
+	}
+	if Expired(c.Expiry, now) {
+		return ErrCredentialExpired
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+//go:build go1.18
+// +build go1.18
+
+package app_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+	"perun.network/go-perun/channel"
+)
+
+// fuzzKey is the key of the issuer whose signatures the fuzzer may request.
+var fuzzKey, _ = crypto.HexToECDSA("f63d7d8e930bccd74e93cf5662fde2c28fd8be95edb70c73f1bdd863d07f412e")
+
+func FuzzDecodeData(f *testing.F) {
+	for _, d := range seedData() {
+		f.Add(encode(f, d))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		d, err := data.Decode(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+
+		// Decoded data must survive a round trip.
+		enc := encode(t, d)
+		d2, err := data.Decode(bytes.NewReader(enc))
+		require.NoError(t, err, "decoding encoded data")
+		require.Equal(t, enc, encode(t, d2), "round trip")
+	})
+}
+
+// FuzzValidTransition checks that ValidTransition does not panic and that
+// only the buyer of an issued credential loses funds in transitions that it
+// accepts, apart from the actor giving up funds in a final state. The
+// preservation of the total is checked by go-perun.
+func FuzzValidTransition(f *testing.F) {
+	seeds := seedData()
+	for _, cur := range seeds {
+		for _, next := range seeds {
+			f.Add(encode(f, cur), encode(f, next), uint64(100), uint64(100), uint64(0), uint64(99), uint64(101), uint64(0), false, uint8(1), false, true)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, curData, nextData []byte, cur0, cur1, cur2, next0, next1, next2 uint64, withFeeRecipient bool, actor uint8, final, sign bool) {
+		cd, err := data.Decode(bytes.NewReader(curData))
+		if err != nil {
+			return
+		}
+		nd, err := data.Decode(bytes.NewReader(nextData))
+		if err != nil {
+			return
+		}
+		if sign {
+			signCert(t, cd, nd)
+		}
+
+		curBals, nextBals := []uint64{cur0, cur1}, []uint64{next0, next1}
+		if withFeeRecipient {
+			curBals, nextBals = append(curBals, cur2), append(nextBals, next2)
+		}
+		cur, next := newState(cd, curBals, false), newState(nd, nextBals, final)
+		actorIdx := channel.Index(int(actor) % len(curBals)) // Checked by go-perun.
+		swapApp := app.NewCredentialSwapApp(ethwallet.AsWalletAddr(common.Address{}))
+
+		if err := swapApp.ValidTransition(nil, cur, next, actorIdx); err != nil {
+			return
+		}
+
+		buyer, price, issued := payment(cd, nd)
+		for i := range curBals {
+			idx := channel.Index(i)
+			curBal, nextBal := cur.Balances[app.AssetIdx][i], next.Balances[app.AssetIdx][i]
+			switch {
+			case issued && idx == buyer:
+				require.Zero(t, new(big.Int).Sub(curBal, price).Cmp(nextBal), "buyer pays the price")
+			case idx == actorIdx && final:
+			case issued || final:
+				require.True(t, nextBal.Cmp(curBal) >= 0, "participant %d loses funds", i)
+			default:
+				require.Zero(t, nextBal.Cmp(curBal), "balance of participant %d changes", i)
+			}
+		}
+	})
+}
+
+// seedData returns data of every type.
+func seedData() []channel.Data {
+	offer := data.Offer{Issuer: crypto.PubkeyToAddress(fuzzKey.PublicKey), Price: big.NewInt(1), Buyer: 0}
+	foreign := offer
+	foreign.Buyer = 7
+	return []channel.Data{
+		&data.DefaultData{},
+		&data.DefaultData{Tenant: "tenant"},
+		&offer,
+		&foreign,
+		&data.CounterOffer{Offer: offer},
+		&data.Cert{},
+		&data.BatchOffer{Issuer: offer.Issuer, DataHashes: [][data.HashLen]byte{{1}}, Price: big.NewInt(1)},
+		&data.BatchCert{Signatures: make([][data.SigLen]byte, 1)},
+	}
+}
+
+// signCert replaces the signatures in `next` with valid signatures of
+// fuzzKey if it issues the credentials of `cur`.
+func signCert(t *testing.T, cur, next channel.Data) {
+	acc := fuzzAccount(t)
+	switch offer := cur.(type) {
+	case *data.Offer:
+		cert, ok := next.(*data.Cert)
+		if !ok {
+			return
+		}
+		offer.Issuer, offer.Cosigners, offer.Attributes, offer.BBSKey = acc.Account.Address, nil, nil, nil
+		cert.CoSignatures, cert.BBSSignature = nil, nil
+		sig, err := app.SignHash(acc, app.OfferHash(offer))
+		require.NoError(t, err)
+		cert.Signature = sig
+	case *data.BatchOffer:
+		cert, ok := next.(*data.BatchCert)
+		if !ok {
+			return
+		}
+		offer.Issuer = acc.Account.Address
+		cert.Signatures = make([][data.SigLen]byte, len(offer.DataHashes))
+		for i, h := range offer.DataHashes {
+			sig, err := app.SignHash(acc, h)
+			require.NoError(t, err)
+			cert.Signatures[i] = sig
+		}
+	}
+}
+
+func fuzzAccount(t *testing.T) *simple.Account {
+	w := simple.NewWallet(fuzzKey)
+	acc, err := w.Unlock(ethwallet.AsWalletAddr(crypto.PubkeyToAddress(fuzzKey.PublicKey)))
+	require.NoError(t, err)
+	return acc.(*simple.Account)
+}
+
+// payment returns the buyer and the price of the credentials issued by the
+// transition from `cur` to `next`, if any.
+func payment(cur, next channel.Data) (buyer channel.Index, price *big.Int, issued bool) {
+	switch offer := cur.(type) {
+	case *data.Offer:
+		if _, ok := next.(*data.Cert); ok {
+			return channel.Index(offer.Buyer), offer.Price, true
+		}
+	case *data.BatchOffer:
+		if _, ok := next.(*data.BatchCert); ok {
+			return channel.Index(offer.Buyer), offer.Price, true
+		}
+	}
+	return 0, nil, false
+}
+
+func newState(d channel.Data, bals []uint64, final bool) *channel.State {
+	balances := make([]channel.Bal, len(bals))
+	for i, b := range bals {
+		balances[i] = new(big.Int).SetUint64(b)
+	}
+	return &channel.State{
+		Allocation: channel.Allocation{
+			Assets:   []channel.Asset{ethwallet.AsWalletAddr(common.Address{1})},
+			Balances: [][]channel.Bal{balances},
+		},
+		Data:    d,
+		IsFinal: final,
+	}
+}
+
+func encode(t testing.TB, d channel.Data) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, d.Encode(&buf))
+	return buf.Bytes()
+}
@@ -0,0 +1,116 @@
+// Package h2c implements parts of the hash-to-curve specification for
+// BLS12-381.
+package h2c
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+var (
+	// P is the modulus of the base field.
+	P, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+	// R is the order of the groups.
+	R, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+)
+
+// fieldElementLen is the number of uniform bytes per field element.
+const fieldElementLen = 64
+
+// ExpandMessageXMD implements expand_message_xmd with SHA-256.
+func ExpandMessageXMD(msg, dst []byte, n int) ([]byte, error) {
+	const bLen, rLen = sha256.Size, sha256.BlockSize
+	ell := (n + bLen - 1) / bLen
+	if ell > 255 || len(dst) > 255 {
+		return nil, errors.New("expand_message_xmd: invalid length")
+	}
+	dstPrime := append(append([]byte(nil), dst...), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, rLen))
+	h.Write(msg)
+	h.Write([]byte{byte(n >> 8), byte(n), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+
+	out := append([]byte(nil), bi...)
+	for i := 2; i <= ell; i++ {
+		x := make([]byte, bLen)
+		for j := range x {
+			x[j] = b0[j] ^ bi[j]
+		}
+		h.Reset()
+		h.Write(x)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:n], nil
+}
+
+// HashToScalar hashes `msg` to a scalar modulo R.
+func HashToScalar(msg, dst []byte) (*big.Int, error) {
+	uniform, err := ExpandMessageXMD(msg, dst, 48)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Mod(new(big.Int).SetBytes(uniform), R), nil
+}
+
+// HashToG1 hashes `msg` to G1 following the random-oracle construction.
+func HashToG1(msg, dst []byte) (*bls12381.PointG1, error) {
+	uniform, err := ExpandMessageXMD(msg, dst, 2*fieldElementLen)
+	if err != nil {
+		return nil, err
+	}
+
+	g1 := bls12381.NewG1()
+	q := g1.Zero()
+	for i := 0; i < 2; i++ {
+		p, err := g1.MapToCurve(fieldElement(uniform[i*fieldElementLen : (i+1)*fieldElementLen]))
+		if err != nil {
+			return nil, fmt.Errorf("mapping to curve: %w", err)
+		}
+		g1.Add(q, q, p)
+	}
+	return g1.Affine(q), nil
+}
+
+// HashToG2 hashes `msg` to G2 following the random-oracle construction.
+func HashToG2(msg, dst []byte) (*bls12381.PointG2, error) {
+	uniform, err := ExpandMessageXMD(msg, dst, 4*fieldElementLen)
+	if err != nil {
+		return nil, err
+	}
+
+	g2 := bls12381.NewG2()
+	q := g2.Zero()
+	for i := 0; i < 2; i++ {
+		// An element of Fp2 is encoded as c1 || c0.
+		c0 := fieldElement(uniform[(2*i)*fieldElementLen : (2*i+1)*fieldElementLen])
+		c1 := fieldElement(uniform[(2*i+1)*fieldElementLen : (2*i+2)*fieldElementLen])
+		p, err := g2.MapToCurve(append(c1, c0...))
+		if err != nil {
+			return nil, fmt.Errorf("mapping to curve: %w", err)
+		}
+		g2.Add(q, q, p)
+	}
+	return g2.Affine(q), nil
+}
+
+// fieldElement reduces `b` modulo P and encodes it in 48 bytes.
+func fieldElement(b []byte) []byte {
+	e := new(big.Int).Mod(new(big.Int).SetBytes(b), P)
+	return e.FillBytes(make([]byte, 48))
+}
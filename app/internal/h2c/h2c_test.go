@@ -0,0 +1,58 @@
+package h2c
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors from RFC 9380, appendices K.1 and J.9.1.
+
+func TestExpandMessageXMD(t *testing.T) {
+	dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+	tests := []struct {
+		msg, uniform string
+	}{
+		{"", "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235"},
+		{"abc", "d8ccab23b5985ccea865c6c97b6e5b8350e794e603b4b97902f53a8a0d605615"},
+	}
+	for _, tt := range tests {
+		uniform, err := ExpandMessageXMD([]byte(tt.msg), dst, 0x20)
+		require.NoError(t, err)
+		require.Equal(t, tt.uniform, hex.EncodeToString(uniform), "msg %q", tt.msg)
+	}
+}
+
+func TestHashToG1(t *testing.T) {
+	dst := []byte("QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_RO_")
+	tests := []struct {
+		msg, x, y string
+	}{
+		{
+			"",
+			"052926add2207b76ca4fa57a8734416c8dc95e24501772c814278700eed6d1e4e8cf62d9c09db0fac349612b759e79a1",
+			"08ba738453bfed09cb546dbb0783dbb3a5f1f566ed67bb6be0e8c67e2e81a4cc68ee29813bb7994998f3eae0c9c6a265",
+		},
+		{
+			"abc",
+			"03567bc5ef9c690c2ab2ecdf6a96ef1c139cc0b2f284dca0a9a7943388a49a3aee664ba5379a7655d3c68900be2f6903",
+			"0b9c15f3fe6e5cf4211f346271d7b01c8f3b28be689c8429c85b67af215533311f0b8dfaaa154fa6b88176c229f2885d",
+		},
+	}
+	for _, tt := range tests {
+		p, err := HashToG1([]byte(tt.msg), dst)
+		require.NoError(t, err)
+		enc := bls12381.NewG1().ToBytes(p)
+		require.Equal(t, tt.x, hex.EncodeToString(enc[:48]), "msg %q", tt.msg)
+		require.Equal(t, tt.y, hex.EncodeToString(enc[48:]), "msg %q", tt.msg)
+	}
+}
+
+func TestExpandMessageXMDLength(t *testing.T) {
+	_, err := ExpandMessageXMD(nil, []byte("DST"), 255*32+1)
+	require.Error(t, err)
+	_, err = ExpandMessageXMD(nil, make([]byte, 256), 32)
+	require.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app/abi"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/pkg/qr"
+)
+
+// invoiceTag separates invoice signatures from other signatures.
+const invoiceTag = "perun-credential-payment/invoice"
+
+// InvoiceScheme is the URI scheme of invoices.
+const InvoiceScheme = "perun-invoice"
+
+var (
+	ErrInvoiceExpired = errors.New("invoice expired")
+	ErrInvalidInvoice = errors.New("invalid invoice URI")
+)
+
+// Invoice is a payment request for a credential of a type, signed by the
+// issuer. It tells the holder where to reach the issuer, so that the holder
+// can open a channel and request the credential from the invoice alone.
+type Invoice struct {
+	Issuer    common.Address `json:"issuer"`
+	Endpoint  string         `json:"endpoint"` // Host and port of the issuer's client.
+	Type      string         `json:"type"`     // Credential type URI.
+	Price     *big.Int       `json:"price"`
+	Expiry    uint64         `json:"expiry"` // Unix time.
+	Signature []byte         `json:"signature"`
+}
+
+var invoiceArgs = abi.Arguments{
+	{Type: abi.String},
+	{Type: abi.Address},
+	{Type: abi.String},
+	{Type: abi.String},
+	{Type: abi.Uint256},
+	{Type: abi.Uint64},
+}
+
+// Hash returns the hash of the invoice fields covered by the signature.
+func (inv *Invoice) Hash() (Hash, error) {
+	b, err := invoiceArgs.Pack(invoiceTag, inv.Issuer, inv.Endpoint, inv.Type, inv.Price, inv.Expiry)
+	if err != nil {
+		return Hash{}, fmt.Errorf("encoding invoice: %w", err)
+	}
+	return crypto.Keccak256Hash(b), nil
+}
+
+// Sign signs the invoice with `acc`, which must be the account of the
+// issuer.
+func (inv *Invoice) Sign(acc Account) error {
+	if AccountAddress(acc) != inv.Issuer {
+		return ErrInvalidSigner
+	}
+	h, err := inv.Hash()
+	if err != nil {
+		return err
+	}
+	sig, err := SignHash(acc, h)
+	if err != nil {
+		return err
+	}
+	inv.Signature = sig[:]
+	return nil
+}
+
+// Verify checks that the invoice is signed by the issuer and not expired at
+// time `now`.
+func (inv *Invoice) Verify(now time.Time) error {
+	if len(inv.Signature) != data.SigLen {
+		return fmt.Errorf("invalid signature length")
+	} else if inv.Price == nil || inv.Price.Sign() < 0 {
+		return fmt.Errorf("invalid price")
+	}
+	h, err := inv.Hash()
+	if err != nil {
+		return err
+	}
+	var sig [data.SigLen]byte
+	copy(sig[:], inv.Signature)
+	if err := VerifySig(sig, h, inv.Issuer); err != nil {
+		return err
+	}
+
+	if uint64(now.Unix()) > inv.Expiry {
+		return ErrInvoiceExpired
+	}
+	return nil
+}
+
+// URI returns the invoice as payment request link.
+func (inv *Invoice) URI() string {
+	enc, err := json.Marshal(inv)
+	if err != nil {
+		panic(err)
+	}
+	return InvoiceScheme + "://?invoice=" + url.QueryEscape(string(enc))
+}
+
+// QRCode returns the URI of the invoice as QR code.
+func (inv *Invoice) QRCode() (*qr.Code, error) {
+	return qr.Encode([]byte(inv.URI()), qr.M)
+}
+
+// ParseInvoiceURI decodes an invoice from a URI returned by Invoice.URI. The
+// invoice is not verified.
+func ParseInvoiceURI(uri string) (*Invoice, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	} else if !strings.EqualFold(u.Scheme, InvoiceScheme) {
+		return nil, fmt.Errorf("%w: scheme %q", ErrInvalidInvoice, u.Scheme)
+	}
+	enc := u.Query().Get("invoice")
+	if enc == "" {
+		return nil, fmt.Errorf("%w: no invoice", ErrInvalidInvoice)
+	}
+	var inv Invoice
+	if err := json.Unmarshal([]byte(enc), &inv); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	}
+	return &inv, nil
+}
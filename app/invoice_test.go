@@ -0,0 +1,51 @@
+package app
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoice(t *testing.T) {
+	acc, other := newAccount(t), newAccount(t)
+	now := time.Unix(1000, 0)
+	inv := &Invoice{Issuer: AccountAddress(acc), Endpoint: "localhost:5750", Type: "Diploma", Price: big.NewInt(3), Expiry: 2000}
+	require.ErrorIs(t, inv.Sign(other), ErrInvalidSigner)
+	require.NoError(t, inv.Sign(acc))
+	require.NoError(t, inv.Verify(now))
+	require.ErrorIs(t, inv.Verify(time.Unix(2001, 0)), ErrInvoiceExpired)
+
+	tampered := *inv
+	tampered.Price = big.NewInt(1)
+	require.Error(t, tampered.Verify(now), "tampered price")
+	tampered = *inv
+	tampered.Endpoint = "attacker:5750"
+	require.Error(t, tampered.Verify(now), "tampered endpoint")
+	tampered = *inv
+	tampered.Signature = inv.Signature[1:]
+	require.Error(t, tampered.Verify(now), "short signature")
+}
+
+func TestInvoiceURI(t *testing.T) {
+	acc := newAccount(t)
+	inv := &Invoice{Issuer: AccountAddress(acc), Endpoint: "localhost:5750", Type: "Diploma", Price: big.NewInt(3), Expiry: 2000}
+	require.NoError(t, inv.Sign(acc))
+
+	parsed, err := ParseInvoiceURI(inv.URI())
+	require.NoError(t, err)
+	require.Equal(t, inv, parsed)
+	require.NoError(t, parsed.Verify(time.Unix(1000, 0)))
+	_, err = inv.QRCode()
+	require.NoError(t, err)
+
+	for _, uri := range []string{
+		"https://?invoice={}",
+		InvoiceScheme + "://?other=1",
+		InvoiceScheme + "://?invoice=nojson",
+	} {
+		_, err := ParseInvoiceURI(uri)
+		require.ErrorIs(t, err, ErrInvalidInvoice, uri)
+	}
+}
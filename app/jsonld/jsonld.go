@@ -0,0 +1,176 @@
+// Package jsonld emits issued credentials as JSON-LD verifiable credentials
+// secured by a Data Integrity proof of the eddsa-jcs-2022 cryptosuite.
+//
+// The cryptosuite canonicalizes with the JSON Canonicalization Scheme, so
+// proofs can be created and verified without resolving JSON-LD contexts.
+package jsonld
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/jcs"
+	"github.com/perun-network/perun-credential-payment/pkg/multibase"
+)
+
+const (
+	ContextV2   = "https://www.w3.org/ns/credentials/v2"
+	ProofType   = "DataIntegrityProof"
+	Cryptosuite = "eddsa-jcs-2022"
+
+	baseType       = "VerifiableCredential"
+	proofPurpose   = "assertionMethod"
+	didKeyPrefix   = "did:key:"
+	dateTimeLayout = time.RFC3339
+)
+
+var ErrInvalidProof = errors.New("invalid proof")
+
+// Credential is a JSON-LD verifiable credential.
+type Credential struct {
+	Context           []string        `json:"@context"`
+	Type              []string        `json:"type"`
+	Issuer            string          `json:"issuer"`
+	ValidFrom         string          `json:"validFrom,omitempty"`
+	ValidUntil        string          `json:"validUntil,omitempty"`
+	CredentialSubject json.RawMessage `json:"credentialSubject"`
+	Proof             *Proof          `json:"proof,omitempty"`
+}
+
+// Proof is a Data Integrity proof.
+type Proof struct {
+	Context            []string `json:"@context,omitempty"`
+	Type               string   `json:"type"`
+	Cryptosuite        string   `json:"cryptosuite"`
+	Created            string   `json:"created"`
+	VerificationMethod string   `json:"verificationMethod"`
+	ProofPurpose       string   `json:"proofPurpose"`
+	ProofValue         string   `json:"proofValue,omitempty"`
+}
+
+// Issue emits credential `c` as JSON-LD verifiable credential with a proof by
+// signer `s`, created at `created`. The issuer is the did:key DID of the
+// signer. The document of the credential must be a JSON object and becomes
+// the credential subject.
+func Issue(c *app.Credential, s app.Ed25519Signer, created time.Time) ([]byte, error) {
+	var subject map[string]json.RawMessage
+	if err := json.Unmarshal(c.Document, &subject); err != nil {
+		return nil, fmt.Errorf("decoding document: %w", err)
+	}
+
+	issuer := did.KeyEd25519(s.PublicKey())
+	vc := &Credential{
+		Context:           []string{ContextV2},
+		Type:              []string{baseType},
+		Issuer:            issuer,
+		CredentialSubject: c.Document,
+	}
+	if c.Metadata != nil {
+		vc.ValidFrom = formatTime(c.Metadata.IssuedAt)
+		if c.Metadata.Type != "" {
+			vc.Type = append(vc.Type, c.Metadata.Type)
+		}
+	}
+	if c.Expiry != 0 {
+		vc.ValidUntil = formatTime(c.Expiry)
+	}
+
+	proof := &Proof{
+		Context:            vc.Context,
+		Type:               ProofType,
+		Cryptosuite:        Cryptosuite,
+		Created:            created.UTC().Format(dateTimeLayout),
+		VerificationMethod: issuer + "#" + strings.TrimPrefix(issuer, didKeyPrefix),
+		ProofPurpose:       proofPurpose,
+	}
+	h, err := hashData(vc, proof)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.Sign(h)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	proof.ProofValue = multibase.Encode(sig)
+	vc.Proof = proof
+
+	return json.Marshal(vc)
+}
+
+// Verify verifies the proof of JSON-LD verifiable credential `doc` against
+// the did:key DID of its verification method, which must be the issuer.
+func Verify(doc []byte) (*Credential, error) {
+	var vc Credential
+	if err := json.Unmarshal(doc, &vc); err != nil {
+		return nil, fmt.Errorf("decoding credential: %w", err)
+	}
+	proof := vc.Proof
+	if proof == nil || proof.Type != ProofType || proof.Cryptosuite != Cryptosuite || proof.ProofPurpose != proofPurpose {
+		return nil, fmt.Errorf("unsupported proof")
+	}
+	pub, err := verificationKey(proof.VerificationMethod, vc.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := multibase.Decode(proof.ProofValue)
+	if err != nil {
+		return nil, ErrInvalidProof
+	}
+
+	// The proof is computed over the document without the proof, and the
+	// proof without its value.
+	unsecured := vc
+	unsecured.Proof = nil
+	config := *proof
+	config.ProofValue = ""
+	h, err := hashData(&unsecured, &config)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(pub, h, sig) {
+		return nil, ErrInvalidProof
+	}
+	return &vc, nil
+}
+
+// hashData returns the data signed by the proof, which is the hash of the
+// canonical proof configuration followed by the hash of the canonical
+// document.
+func hashData(vc *Credential, proof *Proof) ([]byte, error) {
+	doc, err := jcs.Marshal(vc)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalizing document: %w", err)
+	}
+	config, err := jcs.Marshal(proof)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalizing proof: %w", err)
+	}
+	hc, hd := sha256.Sum256(config), sha256.Sum256(doc)
+	return append(hc[:], hd[:]...), nil
+}
+
+// verificationKey returns the public key of verification method `vm`, which
+// must be a key of did:key DID `issuer`.
+func verificationKey(vm, issuer string) (ed25519.PublicKey, error) {
+	doc, err := did.KeyResolver{}.Resolve(context.Background(), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("resolving issuer: %w", err)
+	}
+	m, err := doc.Method(vm)
+	if err != nil {
+		return nil, err
+	}
+	return m.Ed25519Key()
+}
+
+func formatTime(t uint64) string {
+	return time.Unix(int64(t), 0).UTC().Format(dateTimeLayout)
+}
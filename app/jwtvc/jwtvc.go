@@ -0,0 +1,125 @@
+// Package jwtvc encodes issued credentials as JWT verifiable credentials
+// signed with ES256K, following the JWT encoding of the W3C Verifiable
+// Credentials Data Model.
+package jwtvc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+)
+
+const (
+	// Type is the JWT type of verifiable credentials.
+	Type = "JWT"
+
+	contextV1 = "https://www.w3.org/2018/credentials/v1"
+	baseType  = "VerifiableCredential"
+)
+
+// Claims are the claims of a JWT-VC.
+type Claims struct {
+	Issuer    string     `json:"iss"`
+	Subject   string     `json:"sub,omitempty"`
+	ID        string     `json:"jti"`
+	NotBefore uint64     `json:"nbf,omitempty"`
+	Expiry    uint64     `json:"exp,omitempty"`
+	VC        Credential `json:"vc"`
+}
+
+// Credential is the verifiable credential embedded in the claims.
+type Credential struct {
+	Context           []string        `json:"@context"`
+	Type              []string        `json:"type"`
+	CredentialSubject json.RawMessage `json:"credentialSubject"`
+}
+
+// Encode encodes credential `c` as JWT-VC signed by issuer account `acc`.
+// The document of the credential must be a JSON object and becomes the
+// credential subject. If the credential has metadata, its type is added to
+// the credential types and its issuance date becomes the `nbf` claim. The
+// DIDs of the metadata become the `iss` and `sub` claims. Without issuer DID,
+// the did:ethr DID of the account is used.
+func Encode(c *app.Credential, acc app.Account) (string, error) {
+	var subject map[string]json.RawMessage
+	if err := json.Unmarshal(c.Document, &subject); err != nil {
+		return "", fmt.Errorf("decoding document: %w", err)
+	}
+
+	id := c.ID()
+	iss := did.Ethr(app.AccountAddress(acc))
+	kid := iss + "#controller"
+	if c.Metadata != nil && c.Metadata.Issuer != "" {
+		iss, kid = c.Metadata.Issuer, ""
+	}
+	claims := Claims{
+		Issuer: iss,
+		ID:     "urn:credential:" + common.Bytes2Hex(id[:]),
+		Expiry: c.Expiry,
+		VC: Credential{
+			Context:           []string{contextV1},
+			Type:              []string{baseType},
+			CredentialSubject: c.Document,
+		},
+	}
+	if c.Metadata != nil {
+		claims.Subject = c.Metadata.Holder
+		claims.NotBefore = c.Metadata.IssuedAt
+		if c.Metadata.Type != "" {
+			claims.VC.Type = append(claims.VC.Type, c.Metadata.Type)
+		}
+	}
+	enc, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return jws.Sign(jws.ES256K{Key: acc}, jws.Header{Typ: Type, Kid: kid}, enc)
+}
+
+// Verify verifies JWT-VC `token` against the keys of its issuer DID, which is
+// resolved with `r`, and returns its claims. The caller must check that the
+// issuer is trusted and that the credential has not expired.
+func Verify(ctx context.Context, token string, r did.Resolver) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWT")
+	}
+	enc, err := jws.Decode(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(enc, &claims); err != nil {
+		return nil, fmt.Errorf("decoding claims: %w", err)
+	}
+	doc, err := r.Resolve(ctx, claims.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("resolving issuer: %w", err)
+	}
+
+	// The token is verified against every key of the issuer, as the key ID
+	// is optional.
+	for i := range doc.VerificationMethod {
+		addr, err := doc.VerificationMethod[i].EthereumAddress()
+		if err != nil {
+			continue
+		}
+		// The payload is decoded again after verification, so that only
+		// signed claims are returned.
+		if _, enc, err = jws.Verify(jws.ES256KVerifier{Address: addr}, token); err != nil {
+			continue
+		}
+		claims = Claims{}
+		if err := json.Unmarshal(enc, &claims); err != nil {
+			return nil, fmt.Errorf("decoding claims: %w", err)
+		}
+		return &claims, nil
+	}
+	return nil, jws.ErrInvalidSignature
+}
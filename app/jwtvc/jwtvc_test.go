@@ -0,0 +1,85 @@
+package jwtvc_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/jwtvc"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+func newAccount(t *testing.T) app.Account {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(t, err)
+	return acc.(*simple.Account)
+}
+
+func TestEncodeVerify(t *testing.T) {
+	ctx := context.Background()
+	acc := newAccount(t)
+	c := &app.Credential{
+		Document: []byte(`{"degree":"MSc"}`),
+		Expiry:   2000,
+		Metadata: &app.Metadata{Type: "Diploma", IssuedAt: 1000, Holder: did.Ethr(common.Address{1})},
+	}
+	token, err := jwtvc.Encode(c, acc)
+	require.NoError(t, err)
+
+	claims, err := jwtvc.Verify(ctx, token, did.EthrResolver{})
+	require.NoError(t, err)
+	id := c.ID()
+	require.Equal(t, did.Ethr(app.AccountAddress(acc)), claims.Issuer)
+	require.Equal(t, c.Metadata.Holder, claims.Subject)
+	require.Equal(t, "urn:credential:"+common.Bytes2Hex(id[:]), claims.ID)
+	require.Equal(t, uint64(1000), claims.NotBefore)
+	require.Equal(t, uint64(2000), claims.Expiry)
+	require.Equal(t, []string{"VerifiableCredential", "Diploma"}, claims.VC.Type)
+	require.JSONEq(t, string(c.Document), string(claims.VC.CredentialSubject))
+
+	_, err = jwtvc.Encode(&app.Credential{Document: []byte("not JSON")}, acc)
+	require.Error(t, err, "document not a JSON object")
+}
+
+func TestVerifyTampered(t *testing.T) {
+	ctx := context.Background()
+	acc := newAccount(t)
+	token, err := jwtvc.Encode(&app.Credential{Document: []byte(`{"degree":"MSc"}`)}, acc)
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+
+	// The claims are replaced by claims of another subject.
+	payload, err := jws.Decode(parts[1])
+	require.NoError(t, err)
+	var claims jwtvc.Claims
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	claims.VC.CredentialSubject = json.RawMessage(`{"degree":"PhD"}`)
+	payload, err = json.Marshal(claims)
+	require.NoError(t, err)
+	tampered := parts[0] + "." + jws.Encode(payload) + "." + parts[2]
+	_, err = jwtvc.Verify(ctx, tampered, did.EthrResolver{})
+	require.ErrorIs(t, err, jws.ErrInvalidSignature, "tampered claims")
+
+	// The issuer is replaced by another one.
+	claims.VC.CredentialSubject = json.RawMessage(`{"degree":"MSc"}`)
+	claims.Issuer = did.Ethr(common.Address{1})
+	payload, err = json.Marshal(claims)
+	require.NoError(t, err)
+	forged := parts[0] + "." + jws.Encode(payload) + "." + parts[2]
+	_, err = jwtvc.Verify(ctx, forged, did.EthrResolver{})
+	require.ErrorIs(t, err, jws.ErrInvalidSignature, "other issuer")
+
+	_, err = jwtvc.Verify(ctx, "a.b", did.EthrResolver{})
+	require.Error(t, err, "malformed")
+}
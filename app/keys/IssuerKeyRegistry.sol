@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+
+pragma solidity ^0.7.0;
+
+/**
+ * IssuerKeyRegistry records the keys with which issuers sign credentials.
+ * Every issuer has at most one active key. Publishing a new key rotates the
+ * active one, and the full history is kept, so that credentials signed with
+ * retired keys remain verifiable.
+ */
+contract IssuerKeyRegistry {
+    event KeyPublished(address indexed issuer, address indexed key);
+    event KeyRevoked(address indexed issuer, address indexed key);
+
+    struct Key {
+        address key;
+        uint64 activeFrom;
+        uint64 revokedAt;
+    }
+
+    mapping(address => Key[]) internal keys;
+
+    /**
+     * publish makes `key` the active key of the sender. The previously active
+     * key is revoked.
+     *
+     * @param key The signing key.
+     */
+    function publish(address key) external {
+        require(key != address(0), "zero key");
+        _revoke();
+        keys[msg.sender].push(Key(key, uint64(block.timestamp), 0));
+        emit KeyPublished(msg.sender, key);
+    }
+
+    /**
+     * revoke revokes the active key of the sender without replacing it.
+     */
+    function revoke() external {
+        require(_revoke(), "no active key");
+    }
+
+    /// keyCount returns the number of keys ever published by `issuer`.
+    function keyCount(address issuer) external view returns (uint256) {
+        return keys[issuer].length;
+    }
+
+    /// keyAt returns the `i`-th key published by `issuer`.
+    function keyAt(address issuer, uint256 i) external view returns (address key, uint64 activeFrom, uint64 revokedAt) {
+        Key storage k = keys[issuer][i];
+        return (k.key, k.activeFrom, k.revokedAt);
+    }
+
+    /// activeKey returns the active key of `issuer`, or zero if it has none.
+    function activeKey(address issuer) external view returns (address) {
+        Key[] storage ks = keys[issuer];
+        if (ks.length == 0 || ks[ks.length - 1].revokedAt != 0) {
+            return address(0);
+        }
+        return ks[ks.length - 1].key;
+    }
+
+    function _revoke() internal returns (bool) {
+        Key[] storage ks = keys[msg.sender];
+        if (ks.length == 0 || ks[ks.length - 1].revokedAt != 0) {
+            return false;
+        }
+        ks[ks.length - 1].revokedAt = uint64(block.timestamp);
+        emit KeyRevoked(msg.sender, ks[ks.length - 1].key);
+        return true;
+    }
+}
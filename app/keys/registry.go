@@ -0,0 +1,114 @@
+// Package keys provides bindings to the IssuerKeyRegistry contract.
+package keys
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// IssuerKeyRegistryABI is the ABI of IssuerKeyRegistry.sol.
+const IssuerKeyRegistryABI = `[
+	{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"issuer","type":"address"},{"indexed":true,"internalType":"address","name":"key","type":"address"}],"name":"KeyPublished","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"issuer","type":"address"},{"indexed":true,"internalType":"address","name":"key","type":"address"}],"name":"KeyRevoked","type":"event"},
+	{"inputs":[{"internalType":"address","name":"issuer","type":"address"}],"name":"activeKey","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"internalType":"address","name":"issuer","type":"address"},{"internalType":"uint256","name":"i","type":"uint256"}],"name":"keyAt","outputs":[{"internalType":"address","name":"key","type":"address"},{"internalType":"uint64","name":"activeFrom","type":"uint64"},{"internalType":"uint64","name":"revokedAt","type":"uint64"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"internalType":"address","name":"issuer","type":"address"}],"name":"keyCount","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"internalType":"address","name":"key","type":"address"}],"name":"publish","outputs":[],"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[],"name":"revoke","outputs":[],"stateMutability":"nonpayable","type":"function"}
+]`
+
+var ErrNoKey = errors.New("no active issuer key")
+
+// Key is a key published by an issuer.
+type Key struct {
+	Key        common.Address
+	ActiveFrom time.Time
+	RevokedAt  time.Time // Zero while the key is active.
+}
+
+// ActiveAt returns whether the key was active at time `t`.
+func (k Key) ActiveAt(t time.Time) bool {
+	return !t.Before(k.ActiveFrom) && (k.RevokedAt.IsZero() || t.Before(k.RevokedAt))
+}
+
+// ActiveWithin returns whether the key was active at time `t` or revoked at
+// most `overlap` before, e.g., while requests addressed to a rotated key were
+// still being signed.
+func (k Key) ActiveWithin(t time.Time, overlap time.Duration) bool {
+	return !t.Before(k.ActiveFrom) && (k.RevokedAt.IsZero() || t.Before(k.RevokedAt.Add(overlap)))
+}
+
+// Registry is a binding to a deployed IssuerKeyRegistry.
+type Registry struct {
+	contract *bind.BoundContract
+}
+
+func NewRegistry(addr common.Address, backend bind.ContractBackend) (*Registry, error) {
+	parsed, err := abi.JSON(strings.NewReader(IssuerKeyRegistryABI))
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{bind.NewBoundContract(addr, parsed, backend, backend, backend)}, nil
+}
+
+// Publish makes `key` the active key of the sender, revoking the previous
+// one.
+func (r *Registry) Publish(opts *bind.TransactOpts, key common.Address) (*types.Transaction, error) {
+	return r.contract.Transact(opts, "publish", key)
+}
+
+// Revoke revokes the active key of the sender without replacing it.
+func (r *Registry) Revoke(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return r.contract.Transact(opts, "revoke")
+}
+
+// ActiveKey returns the active key of `issuer`. It fails with ErrNoKey if
+// the issuer has none.
+func (r *Registry) ActiveKey(opts *bind.CallOpts, issuer common.Address) (common.Address, error) {
+	var out []interface{}
+	err := r.contract.Call(opts, &out, "activeKey", issuer)
+	if err != nil {
+		return common.Address{}, err
+	}
+	key := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+	if key == (common.Address{}) {
+		return common.Address{}, ErrNoKey
+	}
+	return key, nil
+}
+
+// Keys returns all keys ever published by `issuer`, oldest first.
+func (r *Registry) Keys(opts *bind.CallOpts, issuer common.Address) ([]Key, error) {
+	var out []interface{}
+	if err := r.contract.Call(opts, &out, "keyCount", issuer); err != nil {
+		return nil, err
+	}
+	n := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	keys := make([]Key, n.Int64())
+	for i := range keys {
+		out = nil
+		if err := r.contract.Call(opts, &out, "keyAt", issuer, big.NewInt(int64(i))); err != nil {
+			return nil, err
+		}
+		keys[i].Key = *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+		keys[i].ActiveFrom = unixTime(*abi.ConvertType(out[1], new(uint64)).(*uint64))
+		keys[i].RevokedAt = unixTime(*abi.ConvertType(out[2], new(uint64)).(*uint64))
+	}
+	return keys, nil
+}
+
+// unixTime converts a block timestamp, mapping zero to the zero time.
+func unixTime(t uint64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(t), 0)
+}
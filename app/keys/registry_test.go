@@ -0,0 +1,87 @@
+package keys_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/internal/bindtest"
+	"github.com/perun-network/perun-credential-payment/app/keys"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyActive(t *testing.T) {
+	k := keys.Key{ActiveFrom: time.Unix(1000, 0)}
+	require.False(t, k.ActiveAt(time.Unix(999, 0)))
+	require.True(t, k.ActiveAt(time.Unix(1000, 0)))
+	require.True(t, k.ActiveAt(time.Unix(5000, 0)), "not revoked")
+
+	k.RevokedAt = time.Unix(2000, 0)
+	require.True(t, k.ActiveAt(time.Unix(1999, 0)))
+	require.False(t, k.ActiveAt(time.Unix(2000, 0)))
+	require.True(t, k.ActiveWithin(time.Unix(2000, 0), time.Minute))
+	require.False(t, k.ActiveWithin(time.Unix(2060, 0), time.Minute))
+	require.False(t, k.ActiveWithin(time.Unix(999, 0), time.Minute), "before activation")
+}
+
+func TestRegistry(t *testing.T) {
+	b, err := bindtest.NewBackend(keys.IssuerKeyRegistryABI)
+	require.NoError(t, err)
+	r, err := keys.NewRegistry(common.Address{1}, b)
+	require.NoError(t, err)
+
+	issuer := common.Address{2}
+	history := []keys.Key{
+		{Key: common.Address{3}, ActiveFrom: time.Unix(1000, 0), RevokedAt: time.Unix(2000, 0)},
+		{Key: common.Address{4}, ActiveFrom: time.Unix(2000, 0)},
+	}
+	b.Handle("activeKey", func(args []interface{}) []interface{} {
+		if args[0].(common.Address) == issuer {
+			return []interface{}{history[1].Key}
+		}
+		return []interface{}{common.Address{}}
+	})
+	b.Handle("keyCount", func(args []interface{}) []interface{} {
+		if args[0].(common.Address) == issuer {
+			return []interface{}{big.NewInt(int64(len(history)))}
+		}
+		return []interface{}{new(big.Int)}
+	})
+	b.Handle("keyAt", func(args []interface{}) []interface{} {
+		k := history[args[1].(*big.Int).Int64()]
+		var revokedAt uint64
+		if !k.RevokedAt.IsZero() {
+			revokedAt = uint64(k.RevokedAt.Unix())
+		}
+		return []interface{}{k.Key, uint64(k.ActiveFrom.Unix()), revokedAt}
+	})
+
+	key, err := r.ActiveKey(&bind.CallOpts{}, issuer)
+	require.NoError(t, err)
+	require.Equal(t, history[1].Key, key)
+	_, err = r.ActiveKey(&bind.CallOpts{}, common.Address{5})
+	require.ErrorIs(t, err, keys.ErrNoKey)
+
+	got, err := r.Keys(&bind.CallOpts{}, issuer)
+	require.NoError(t, err)
+	require.Len(t, got, len(history))
+	for i := range history {
+		require.Equal(t, history[i].Key, got[i].Key)
+		require.True(t, history[i].ActiveFrom.Equal(got[i].ActiveFrom))
+		require.True(t, history[i].RevokedAt.Equal(got[i].RevokedAt))
+	}
+	got, err = r.Keys(&bind.CallOpts{}, common.Address{5})
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	_, err = r.Publish(b.TransactOpts(), common.Address{6})
+	require.NoError(t, err)
+	_, err = r.Revoke(b.TransactOpts())
+	require.NoError(t, err)
+	require.Equal(t, []bindtest.Call{
+		{Method: "publish", Args: []interface{}{common.Address{6}}},
+		{Method: "revoke", Args: []interface{}{}},
+	}, b.Sent())
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	appabi "github.com/perun-network/perun-credential-payment/app/abi"
+)
+
+// Metadata describes a credential. It is covered by the issuer's signature
+// through its hash.
+type Metadata struct {
+	Type     string `json:"type"`     // Credential type URI.
+	Schema   string `json:"schema"`   // Schema ID.
+	IssuedAt uint64 `json:"issuedAt"` // Unix time.
+	Issuer   string `json:"issuer"`   // DID of the issuer. Optional.
+	Holder   string `json:"holder"`   // DID of the holder. Optional.
+}
+
+var metadataArgs = abi.Arguments{
+	{Type: appabi.String},
+	{Type: appabi.String},
+	{Type: appabi.Uint64},
+	{Type: appabi.String},
+	{Type: appabi.String},
+}
+
+// Encode returns the ABI encoding of the metadata.
+func (m *Metadata) Encode() []byte {
+	enc, err := metadataArgs.Pack(m.Type, m.Schema, m.IssuedAt, m.Issuer, m.Holder)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// Hash returns the hash of the encoded metadata.
+func (m *Metadata) Hash() Hash {
+	return ComputeDocumentHash(m.Encode())
+}
+
+// DecodeMetadata decodes metadata encoded with Metadata.Encode.
+func DecodeMetadata(b []byte) (*Metadata, error) {
+	vals, err := metadataArgs.Unpack(b)
+	if err != nil {
+		return nil, fmt.Errorf("unpacking metadata: %w", err)
+	}
+	return &Metadata{
+		Type:     vals[0].(string),
+		Schema:   vals[1].(string),
+		IssuedAt: vals[2].(uint64),
+		Issuer:   vals[3].(string),
+		Holder:   vals[4].(string),
+	}, nil
+}
@@ -0,0 +1,119 @@
+// Package oracle provides bindings to Chainlink-style price feeds and
+// converts USD prices into token amounts.
+package oracle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+)
+
+// AggregatorV3ABI is the part of the ABI of Chainlink's AggregatorV3Interface
+// that is used.
+const AggregatorV3ABI = `[
+	{"inputs":[],"name":"decimals","outputs":[{"internalType":"uint8","name":"","type":"uint8"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"latestRoundData","outputs":[{"internalType":"uint80","name":"roundId","type":"uint80"},{"internalType":"int256","name":"answer","type":"int256"},{"internalType":"uint256","name":"startedAt","type":"uint256"},{"internalType":"uint256","name":"updatedAt","type":"uint256"},{"internalType":"uint80","name":"answeredInRound","type":"uint80"}],"stateMutability":"view","type":"function"}
+]`
+
+// DefaultMaxAge is the default maximum age of a rate.
+const DefaultMaxAge = time.Hour
+
+var (
+	ErrStaleRate   = errors.New("stale rate")
+	ErrInvalidRate = errors.New("invalid rate")
+)
+
+// Feed is a binding to a deployed price feed.
+type Feed struct {
+	contract *bind.BoundContract
+}
+
+func NewFeed(addr common.Address, caller bind.ContractCaller) (*Feed, error) {
+	parsed, err := abi.JSON(strings.NewReader(AggregatorV3ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &Feed{bind.NewBoundContract(addr, parsed, caller, nil, nil)}, nil
+}
+
+// Rate is the USD price of one token, scaled by 10^Decimals.
+type Rate struct {
+	Answer    *big.Int
+	Decimals  uint8
+	UpdatedAt time.Time
+}
+
+// Rate returns the latest rate of the feed.
+func (f *Feed) Rate(opts *bind.CallOpts) (*Rate, error) {
+	var out []interface{}
+	if err := f.contract.Call(opts, &out, "decimals"); err != nil {
+		return nil, err
+	}
+	decimals := *abi.ConvertType(out[0], new(uint8)).(*uint8)
+
+	out = nil
+	if err := f.contract.Call(opts, &out, "latestRoundData"); err != nil {
+		return nil, err
+	}
+	answer := *abi.ConvertType(out[1], new(*big.Int)).(**big.Int)
+	updatedAt := *abi.ConvertType(out[3], new(*big.Int)).(**big.Int)
+	return &Rate{
+		Answer:    answer,
+		Decimals:  decimals,
+		UpdatedAt: time.Unix(updatedAt.Int64(), 0),
+	}, nil
+}
+
+// Converter converts USD prices into token amounts at the rate of a feed.
+type Converter struct {
+	Feed          *Feed
+	TokenDecimals uint8         // 18 for ETH.
+	MaxAge        time.Duration // Rates older than this are rejected. Defaults to DefaultMaxAge.
+	Clock         clock.Clock   // The time source for the age of rates. Defaults to the system clock.
+}
+
+// Convert returns the amount of token base units that are worth `cents` USD
+// cents at the current rate.
+func (c *Converter) Convert(ctx context.Context, cents *big.Int) (*big.Int, error) {
+	rate, err := c.Feed.Rate(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("querying price feed: %w", err)
+	}
+	maxAge := c.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	clk := c.Clock
+	if clk == nil {
+		clk = clock.System()
+	}
+	if clk.Now().Sub(rate.UpdatedAt) > maxAge {
+		return nil, fmt.Errorf("%w: updated at %v", ErrStaleRate, rate.UpdatedAt)
+	}
+	return rate.Convert(cents, c.TokenDecimals)
+}
+
+// Convert returns the amount of token base units that are worth `cents` USD
+// cents, for a token with `tokenDecimals` decimals. The amount is rounded
+// down.
+func (r *Rate) Convert(cents *big.Int, tokenDecimals uint8) (*big.Int, error) {
+	if r.Answer.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+	// amount = cents / 100 * 10^tokenDecimals / (answer / 10^decimals)
+	amount := new(big.Int).Mul(cents, pow10(tokenDecimals))
+	amount.Mul(amount, pow10(r.Decimals))
+	return amount.Div(amount, new(big.Int).Mul(r.Answer, big.NewInt(100))), nil
+}
+
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
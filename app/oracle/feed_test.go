@@ -0,0 +1,60 @@
+package oracle_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/internal/bindtest"
+	"github.com/perun-network/perun-credential-payment/app/oracle"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter(t *testing.T) {
+	ctx := context.Background()
+	b, err := bindtest.NewBackend(oracle.AggregatorV3ABI)
+	require.NoError(t, err)
+	feed, err := oracle.NewFeed(common.Address{1}, b)
+	require.NoError(t, err)
+
+	// 2000 USD per token, with 8 decimals.
+	updatedAt := time.Unix(1000, 0)
+	answer := big.NewInt(2000_00000000)
+	b.Handle("decimals", func([]interface{}) []interface{} {
+		return []interface{}{uint8(8)}
+	})
+	b.Handle("latestRoundData", func([]interface{}) []interface{} {
+		return []interface{}{big.NewInt(1), answer, big.NewInt(updatedAt.Unix()), big.NewInt(updatedAt.Unix()), big.NewInt(1)}
+	})
+
+	clk := clock.NewFake(updatedAt.Add(time.Minute))
+	c := &oracle.Converter{Feed: feed, TokenDecimals: 18, Clock: clk}
+	amount, err := c.Convert(ctx, big.NewInt(1000))
+	require.NoError(t, err)
+	require.Zero(t, amount.Cmp(big.NewInt(5_000_000_000_000_000)), "10 USD")
+
+	clk.Advance(oracle.DefaultMaxAge)
+	_, err = c.Convert(ctx, big.NewInt(1000))
+	require.ErrorIs(t, err, oracle.ErrStaleRate)
+	c.MaxAge = 2 * oracle.DefaultMaxAge
+	_, err = c.Convert(ctx, big.NewInt(1000))
+	require.NoError(t, err, "custom max age")
+
+	answer = new(big.Int)
+	_, err = c.Convert(ctx, big.NewInt(1000))
+	require.ErrorIs(t, err, oracle.ErrInvalidRate)
+}
+
+func TestRateConvert(t *testing.T) {
+	// Amounts are rounded down.
+	r := &oracle.Rate{Answer: big.NewInt(3), Decimals: 0}
+	amount, err := r.Convert(big.NewInt(100), 0)
+	require.NoError(t, err)
+	require.Zero(t, amount.Sign())
+	amount, err = r.Convert(big.NewInt(1000), 2)
+	require.NoError(t, err)
+	require.Zero(t, amount.Cmp(big.NewInt(333)))
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app/abi"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// quoteTag separates quote signatures from other signatures.
+const quoteTag = "perun-credential-payment/quote"
+
+var ErrQuoteNotValid = errors.New("quote not valid")
+
+// Quote is a price for a credential, signed by the issuer and valid within a
+// time window.
+type Quote struct {
+	Issuer     common.Address `json:"issuer"`
+	Type       string         `json:"type"`
+	DocHash    Hash           `json:"docHash"`
+	Price      *big.Int       `json:"price"`
+	ValidFrom  uint64         `json:"validFrom"`
+	ValidUntil uint64         `json:"validUntil"`
+	Signature  []byte         `json:"signature"`
+}
+
+var quoteArgs = abi.Arguments{
+	{Type: abi.String},
+	{Type: abi.Address},
+	{Type: abi.String},
+	{Type: abi.Bytes32},
+	{Type: abi.Uint256},
+	{Type: abi.Uint64},
+	{Type: abi.Uint64},
+}
+
+// Hash returns the hash of the quote fields covered by the signature.
+func (q *Quote) Hash() (Hash, error) {
+	b, err := quoteArgs.Pack(quoteTag, q.Issuer, q.Type, q.DocHash, q.Price, q.ValidFrom, q.ValidUntil)
+	if err != nil {
+		return Hash{}, fmt.Errorf("encoding quote: %w", err)
+	}
+	return crypto.Keccak256Hash(b), nil
+}
+
+// Sign signs the quote with `acc`, which must be the account of the issuer.
+func (q *Quote) Sign(acc Account) error {
+	if AccountAddress(acc) != q.Issuer {
+		return ErrInvalidSigner
+	}
+	h, err := q.Hash()
+	if err != nil {
+		return err
+	}
+	sig, err := SignHash(acc, h)
+	if err != nil {
+		return err
+	}
+	q.Signature = sig[:]
+	return nil
+}
+
+// Verify checks that the quote is signed by the issuer and valid at time
+// `now`.
+func (q *Quote) Verify(now time.Time) error {
+	if len(q.Signature) != data.SigLen {
+		return fmt.Errorf("invalid signature length")
+	}
+	h, err := q.Hash()
+	if err != nil {
+		return err
+	}
+	var sig [data.SigLen]byte
+	copy(sig[:], q.Signature)
+	if err := VerifySig(sig, h, q.Issuer); err != nil {
+		return err
+	}
+
+	t := uint64(now.Unix())
+	if t < q.ValidFrom || t > q.ValidUntil {
+		return ErrQuoteNotValid
+	}
+	return nil
+}
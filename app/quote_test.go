@@ -0,0 +1,52 @@
+package app
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+func newAccount(t *testing.T) Account {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(t, err)
+	return acc.(*simple.Account)
+}
+
+// TestSignatureTags checks that the signatures of the issuer on quotes,
+// invoices and sessions are not valid credentials on the encodings of their
+// fields, which the holder chooses.
+func TestSignatureTags(t *testing.T) {
+	acc := newAccount(t)
+	issuer := AccountAddress(acc)
+	now := time.Now()
+	until := uint64(now.Add(time.Hour).Unix())
+
+	quote := &Quote{Issuer: issuer, Type: "type", DocHash: Hash{1}, Price: big.NewInt(1), ValidUntil: until}
+	require.NoError(t, quote.Sign(acc))
+	require.NoError(t, quote.Verify(now))
+	untagged, err := quoteArgs[1:].Pack(quote.Issuer, quote.Type, quote.DocHash, quote.Price, quote.ValidFrom, quote.ValidUntil)
+	require.NoError(t, err)
+	require.Error(t, VerifyCredential(&Credential{Document: untagged, Signature: quote.Signature}, issuer, now), "quote")
+
+	inv := &Invoice{Issuer: issuer, Endpoint: "localhost:5750", Type: "type", Price: big.NewInt(1), Expiry: until}
+	require.NoError(t, inv.Sign(acc))
+	require.NoError(t, inv.Verify(now))
+	untagged, err = invoiceArgs[1:].Pack(inv.Issuer, inv.Endpoint, inv.Type, inv.Price, inv.Expiry)
+	require.NoError(t, err)
+	require.Error(t, VerifyCredential(&Credential{Document: untagged, Signature: inv.Signature}, issuer, now), "invoice")
+
+	_, session, err := NewSessionKey(acc, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, session.Verify(now))
+	untagged, err = sessionArgs[1:].Pack(session.Account, session.Key, session.Expiry)
+	require.NoError(t, err)
+	require.Error(t, VerifyCredential(&Credential{Document: untagged, Signature: session.Signature}, issuer, now), "session")
+}
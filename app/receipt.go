@@ -0,0 +1,102 @@
+package app
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app/abi"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// receiptTag separates receipt signatures from other signatures.
+const receiptTag = "perun-credential-payment/receipt"
+
+// Receipt is a proof of purchase of a credential, signed by the issuer and
+// the holder.
+type Receipt struct {
+	ChannelID       Hash           `json:"channelId"`
+	CredentialID    Hash           `json:"credentialId"`
+	Issuer          common.Address `json:"issuer"`
+	Holder          common.Address `json:"holder"`
+	Price           *big.Int       `json:"price"`
+	RequestedAt     uint64         `json:"requestedAt"`
+	IssuedAt        uint64         `json:"issuedAt"`
+	IssuerSignature []byte         `json:"issuerSignature"`
+	HolderSignature []byte         `json:"holderSignature"`
+}
+
+var receiptArgs = abi.Arguments{
+	{Type: abi.String},
+	{Type: abi.Bytes32},
+	{Type: abi.Bytes32},
+	{Type: abi.Address},
+	{Type: abi.Address},
+	{Type: abi.Uint256},
+	{Type: abi.Uint64},
+	{Type: abi.Uint64},
+}
+
+// Hash returns the hash of the receipt fields covered by the signatures.
+func (r *Receipt) Hash() (Hash, error) {
+	b, err := receiptArgs.Pack(receiptTag, r.ChannelID, r.CredentialID, r.Issuer, r.Holder, r.Price, r.RequestedAt, r.IssuedAt)
+	if err != nil {
+		return Hash{}, fmt.Errorf("encoding receipt: %w", err)
+	}
+	return crypto.Keccak256Hash(b), nil
+}
+
+// Sign signs the receipt with `acc`, which must be the account of the issuer
+// or the holder.
+func (r *Receipt) Sign(acc Account) error {
+	var sig *[]byte
+	switch AccountAddress(acc) {
+	case r.Issuer:
+		sig = &r.IssuerSignature
+	case r.Holder:
+		sig = &r.HolderSignature
+	default:
+		return ErrInvalidSigner
+	}
+
+	h, err := r.Hash()
+	if err != nil {
+		return err
+	}
+	s, err := SignHash(acc, h)
+	if err != nil {
+		return err
+	}
+	*sig = s[:]
+	return nil
+}
+
+// VerifyIssuer checks the signature of the issuer.
+func (r *Receipt) VerifyIssuer() error {
+	return r.verify(r.IssuerSignature, r.Issuer)
+}
+
+// Verify checks the signatures of both parties.
+func (r *Receipt) Verify() error {
+	if err := r.VerifyIssuer(); err != nil {
+		return fmt.Errorf("issuer: %w", err)
+	}
+	if err := r.verify(r.HolderSignature, r.Holder); err != nil {
+		return fmt.Errorf("holder: %w", err)
+	}
+	return nil
+}
+
+func (r *Receipt) verify(sig []byte, signer common.Address) error {
+	if len(sig) != data.SigLen {
+		return ErrInvalidSignature
+	}
+	h, err := r.Hash()
+	if err != nil {
+		return err
+	}
+	var fixed [data.SigLen]byte
+	copy(fixed[:], sig)
+	return VerifySig(fixed, h, signer)
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceipt(t *testing.T) {
+	issuer, holder := newAccount(t), newAccount(t)
+	rc := &Receipt{
+		ChannelID:    Hash{1},
+		CredentialID: Hash{2},
+		Issuer:       AccountAddress(issuer),
+		Holder:       AccountAddress(holder),
+		Price:        big.NewInt(10),
+		RequestedAt:  1000,
+		IssuedAt:     1001,
+	}
+
+	require.ErrorIs(t, rc.Sign(newAccount(t)), ErrInvalidSigner, "other party")
+	require.NoError(t, rc.Sign(issuer))
+	require.NoError(t, rc.VerifyIssuer())
+	require.Error(t, rc.Verify(), "missing holder signature")
+	require.NoError(t, rc.Sign(holder))
+	require.NoError(t, rc.Verify())
+
+	// All fields are covered by the signatures.
+	for name, tamper := range map[string]func(r *Receipt){
+		"channel":    func(r *Receipt) { r.ChannelID = Hash{3} },
+		"credential": func(r *Receipt) { r.CredentialID = Hash{3} },
+		"price":      func(r *Receipt) { r.Price = big.NewInt(1) },
+		"requested":  func(r *Receipt) { r.RequestedAt = 999 },
+		"issued":     func(r *Receipt) { r.IssuedAt = 1002 },
+	} {
+		tampered := *rc
+		tamper(&tampered)
+		require.Error(t, tampered.VerifyIssuer(), name)
+	}
+
+	// Signatures are not interchangeable.
+	swapped := *rc
+	swapped.IssuerSignature, swapped.HolderSignature = rc.HolderSignature, rc.IssuerSignature
+	require.Error(t, swapped.Verify())
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"sync"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+var registry = struct {
+	mu   sync.Mutex
+	apps map[wallet.AddrKey]*CredentialSwapApp
+}{apps: make(map[wallet.AddrKey]*CredentialSwapApp)}
+
+// Register registers the app contract at `addr` in the app registry of
+// go-perun, from which channels proposed by peers resolve their app. The
+// registry is global to the process, so the app is registered once per
+// address and shared by all clients of the process. Registering it again
+// with a contract signature verifier enables contract signatures for the
+// channels proposed from then on; they are never disabled again.
+func Register(addr wallet.Address, contractSigs *ContractSigVerifier) *CredentialSwapApp {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	key := wallet.Key(addr)
+	if a, ok := registry.apps[key]; ok && (a.ContractSigs != nil || contractSigs == nil) {
+		return a
+	}
+
+	// Registered apps are not modified, as channels may be using them.
+	a := NewCredentialSwapApp(addr)
+	a.ContractSigs = contractSigs
+	channel.RegisterApp(a)
+	registry.apps[key] = a
+	return a
+}
@@ -0,0 +1,32 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+)
+
+func TestRegister(t *testing.T) {
+	addr := ethwallet.AsWalletAddr(common.HexToAddress("0x5eb3bc0a489c5a8288765d2336659ebca68fcd00"))
+
+	plain := app.Register(addr, nil)
+	require.Same(t, plain, app.Register(addr, nil), "registered twice")
+	resolved, err := channel.Resolve(addr)
+	require.NoError(t, err)
+	require.Same(t, plain, resolved)
+
+	sigs, err := app.NewContractSigVerifier(nil)
+	require.NoError(t, err)
+	withSigs := app.Register(addr, sigs)
+	require.NotSame(t, plain, withSigs)
+	require.Nil(t, plain.ContractSigs, "registered app modified")
+	require.Same(t, sigs, withSigs.ContractSigs)
+	require.Same(t, withSigs, app.Register(addr, nil), "contract signatures disabled")
+	resolved, err = channel.Resolve(addr)
+	require.NoError(t, err)
+	require.Same(t, withSigs, resolved)
+}
@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: Apache-2.0
+
+pragma solidity ^0.7.0;
+
+/**
+ * RevocationRegistry records the revocation of credentials by their issuers.
+ */
+contract RevocationRegistry {
+    event Revoked(address indexed issuer, bytes32 indexed id);
+
+    /// revokedAt holds the time at which a credential was revoked by its
+    /// issuer, or zero if it was not revoked.
+    mapping(address => mapping(bytes32 => uint256)) public revokedAt;
+
+    /**
+     * revoke revokes the credential `id` issued by the sender.
+     *
+     * @param id The credential ID.
+     */
+    function revoke(bytes32 id) external {
+        require(revokedAt[msg.sender][id] == 0, "already revoked");
+        revokedAt[msg.sender][id] = block.timestamp;
+        emit Revoked(msg.sender, id);
+    }
+}
@@ -0,0 +1,48 @@
+// Package revocation provides bindings to the RevocationRegistry contract.
+package revocation
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RevocationRegistryABI is the ABI of RevocationRegistry.sol.
+const RevocationRegistryABI = `[
+	{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"issuer","type":"address"},{"indexed":true,"internalType":"bytes32","name":"id","type":"bytes32"}],"name":"Revoked","type":"event"},
+	{"inputs":[{"internalType":"bytes32","name":"id","type":"bytes32"}],"name":"revoke","outputs":[],"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[{"internalType":"address","name":"","type":"address"},{"internalType":"bytes32","name":"","type":"bytes32"}],"name":"revokedAt","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}
+]`
+
+// Registry is a binding to a deployed RevocationRegistry.
+type Registry struct {
+	contract *bind.BoundContract
+}
+
+func NewRegistry(addr common.Address, backend bind.ContractBackend) (*Registry, error) {
+	parsed, err := abi.JSON(strings.NewReader(RevocationRegistryABI))
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{bind.NewBoundContract(addr, parsed, backend, backend, backend)}, nil
+}
+
+// Revoke revokes credential `id` of the sender.
+func (r *Registry) Revoke(opts *bind.TransactOpts, id [32]byte) (*types.Transaction, error) {
+	return r.contract.Transact(opts, "revoke", id)
+}
+
+// RevokedAt returns the time at which credential `id` was revoked by
+// `issuer`, or zero if it was not revoked.
+func (r *Registry) RevokedAt(opts *bind.CallOpts, issuer common.Address, id [32]byte) (*big.Int, error) {
+	var out []interface{}
+	err := r.contract.Call(opts, &out, "revokedAt", issuer, id)
+	if err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
@@ -0,0 +1,37 @@
+package revocation_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/internal/bindtest"
+	"github.com/perun-network/perun-credential-payment/app/revocation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	b, err := bindtest.NewBackend(revocation.RevocationRegistryABI)
+	require.NoError(t, err)
+	r, err := revocation.NewRegistry(common.Address{1}, b)
+	require.NoError(t, err)
+
+	issuer, id := common.Address{2}, [32]byte{3}
+	b.Handle("revokedAt", func(args []interface{}) []interface{} {
+		if args[0].(common.Address) == issuer && args[1].([32]byte) == id {
+			return []interface{}{big.NewInt(1000)}
+		}
+		return []interface{}{new(big.Int)}
+	})
+	at, err := r.RevokedAt(&bind.CallOpts{}, issuer, id)
+	require.NoError(t, err)
+	require.Zero(t, at.Cmp(big.NewInt(1000)))
+	at, err = r.RevokedAt(&bind.CallOpts{}, issuer, [32]byte{4})
+	require.NoError(t, err)
+	require.Zero(t, at.Sign(), "not revoked")
+
+	_, err = r.Revoke(b.TransactOpts(), id)
+	require.NoError(t, err)
+	require.Equal(t, []bindtest.Call{{Method: "revoke", Args: []interface{}{id}}}, b.Sent())
+}
@@ -0,0 +1,203 @@
+// Package sdjwt exports issued credentials as SD-JWT verifiable credentials,
+// in which every claim of the document can be disclosed selectively.
+package sdjwt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+)
+
+const (
+	// Type is the media type of SD-JWT VCs.
+	Type = "vc+sd-jwt"
+
+	hashAlg  = "sha-256"
+	saltSize = 16
+)
+
+var ErrMissingType = errors.New("credential has no type")
+
+// Disclosure discloses a single claim.
+type Disclosure struct {
+	Salt  string
+	Name  string
+	Value json.RawMessage
+}
+
+// Encode returns the encoded disclosure.
+func (d *Disclosure) Encode() string {
+	enc, err := json.Marshal([]interface{}{d.Salt, d.Name, d.Value})
+	if err != nil {
+		panic(err) // Cannot happen, the value is valid JSON.
+	}
+	return jws.Encode(enc)
+}
+
+// Digest returns the digest of the encoded disclosure, which is included in
+// the signed JWT.
+func (d *Disclosure) Digest() string {
+	return digest(d.Encode())
+}
+
+func digest(encoded string) string {
+	h := sha256.Sum256([]byte(encoded))
+	return jws.Encode(h[:])
+}
+
+// SDJWT is an issuer-signed JWT together with the disclosures of its claims.
+type SDJWT struct {
+	JWT         string
+	Disclosures []Disclosure
+}
+
+// Issue wraps credential `c` into an SD-JWT VC with issuer `iss`, signed by
+// `s`. The document of the credential must be a JSON object, each member of
+// which becomes a selectively disclosable claim. The type of the credential
+// is taken from its metadata.
+//
+// The SD-JWT is signed separately from the credential signature exchanged in
+// the channel, so it must be created by the issuer.
+func Issue(c *app.Credential, iss string, s jws.Signer) (*SDJWT, error) {
+	if c.Metadata == nil || c.Metadata.Type == "" {
+		return nil, ErrMissingType
+	}
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(c.Document, &claims); err != nil {
+		return nil, fmt.Errorf("decoding document: %w", err)
+	}
+
+	sd := &SDJWT{Disclosures: make([]Disclosure, 0, len(claims))}
+	digests := make([]string, 0, len(claims))
+	for name, value := range claims {
+		if name == "_sd" || name == "..." {
+			return nil, fmt.Errorf("reserved claim name: %s", name)
+		}
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		d := Disclosure{Salt: jws.Encode(salt), Name: name, Value: value}
+		sd.Disclosures = append(sd.Disclosures, d)
+		digests = append(digests, d.Digest())
+	}
+	// Sort the digests so that their order does not reveal the claim names.
+	sort.Strings(digests)
+
+	payload := map[string]interface{}{
+		"iss":     iss,
+		"vct":     c.Metadata.Type,
+		"iat":     c.Metadata.IssuedAt,
+		"_sd":     digests,
+		"_sd_alg": hashAlg,
+	}
+	if c.Expiry != 0 {
+		payload["exp"] = c.Expiry
+	}
+	enc, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if sd.JWT, err = jws.Sign(s, jws.Header{Typ: Type}, enc); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// String returns the serialization of the SD-JWT with all disclosures.
+func (sd *SDJWT) String() string {
+	names := make([]string, len(sd.Disclosures))
+	for i, d := range sd.Disclosures {
+		names[i] = d.Name
+	}
+	return sd.Present(names...)
+}
+
+// Present returns the serialization of the SD-JWT that only includes the
+// disclosures of the claims `names`.
+func (sd *SDJWT) Present(names ...string) string {
+	var b strings.Builder
+	b.WriteString(sd.JWT)
+	b.WriteByte('~')
+	for _, d := range sd.Disclosures {
+		for _, n := range names {
+			if d.Name == n {
+				b.WriteString(d.Encode())
+				b.WriteByte('~')
+				break
+			}
+		}
+	}
+	return b.String()
+}
+
+// Verify verifies the serialized SD-JWT `s` with verifier `v` and returns the
+// payload of the JWT together with the disclosed claims.
+func Verify(s string, v jws.Verifier) (map[string]json.RawMessage, error) {
+	parts := strings.Split(s, "~")
+	if len(parts) < 2 || parts[len(parts)-1] != "" {
+		return nil, fmt.Errorf("malformed SD-JWT")
+	}
+	h, enc, err := jws.Verify(v, parts[0])
+	if err != nil {
+		return nil, err
+	} else if h.Typ != Type {
+		return nil, fmt.Errorf("unexpected type: %s", h.Typ)
+	}
+
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(enc, &claims); err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+	var digests []string
+	if err := json.Unmarshal(claims["_sd"], &digests); err != nil {
+		return nil, fmt.Errorf("decoding digests: %w", err)
+	}
+	known := make(map[string]bool, len(digests))
+	for _, d := range digests {
+		known[d] = true
+	}
+	delete(claims, "_sd")
+	delete(claims, "_sd_alg")
+
+	for _, p := range parts[1 : len(parts)-1] {
+		if !known[digest(p)] {
+			return nil, fmt.Errorf("unknown disclosure")
+		}
+		delete(known, digest(p))
+		d, err := decodeDisclosure(p)
+		if err != nil {
+			return nil, err
+		} else if _, ok := claims[d.Name]; ok {
+			return nil, fmt.Errorf("duplicate claim: %s", d.Name)
+		}
+		claims[d.Name] = d.Value
+	}
+	return claims, nil
+}
+
+func decodeDisclosure(s string) (*Disclosure, error) {
+	enc, err := jws.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding disclosure: %w", err)
+	}
+	var fields []json.RawMessage
+	if err := json.Unmarshal(enc, &fields); err != nil || len(fields) != 3 {
+		return nil, fmt.Errorf("malformed disclosure")
+	}
+	d := &Disclosure{Value: fields[2]}
+	if err := json.Unmarshal(fields[0], &d.Salt); err != nil {
+		return nil, fmt.Errorf("malformed disclosure salt")
+	} else if err := json.Unmarshal(fields[1], &d.Name); err != nil {
+		return nil, fmt.Errorf("malformed disclosure name")
+	}
+	return d, nil
+}
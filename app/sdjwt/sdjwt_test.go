@@ -0,0 +1,111 @@
+package sdjwt
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDisclosureDigest checks disclosures from the examples of the SD-JWT
+// specification.
+func TestDisclosureDigest(t *testing.T) {
+	tests := []struct {
+		encoded, digest string
+		salt, name      string
+		value           string
+	}{
+		{
+			"WyIyR0xDNDJzS1F2ZUNmR2ZyeU5STjl3IiwgImdpdmVuX25hbWUiLCAiSm9obiJd",
+			"jsu9yVulwQQlhFlM_3JlzMaSFzglhQG0DpfayQwLUK4",
+			"2GLC42sKQveCfGfryNRN9w", "given_name", `"John"`,
+		},
+		{
+			"WyJfMjZiYzRMVC1hYzZxMktJNmNCVzVlcyIsICJmYW1pbHlfbmFtZSIsICJNw7ZiaXVzIl0",
+			"X9yH0Ajrdm1Oij4tWso9UzzKJvPoDxwmuEcO3XAdRC0",
+			"_26bc4LT-ac6q2KI6cBW5es", "family_name", `"Möbius"`,
+		},
+	}
+	for _, tt := range tests {
+		// The digest is taken over the disclosure as encoded by the issuer.
+		require.Equal(t, tt.digest, digest(tt.encoded))
+		d, err := decodeDisclosure(tt.encoded)
+		require.NoError(t, err)
+		require.Equal(t, tt.salt, d.Salt)
+		require.Equal(t, tt.name, d.Name)
+		require.JSONEq(t, tt.value, string(d.Value))
+	}
+}
+
+type keySigner struct{ key *ecdsa.PrivateKey }
+
+func (s keySigner) SignHash(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+func issue(t *testing.T) (*SDJWT, jws.Verifier) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	c := &app.Credential{
+		Document: []byte(`{"given_name": "John", "family_name": "Doe", "address": {"country": "DE"}}`),
+		Metadata: &app.Metadata{Type: "IdentityCredential", IssuedAt: 1683000000},
+		Expiry:   1883000000,
+	}
+	sd, err := Issue(c, "did:example:issuer", jws.ES256K{Key: keySigner{key}})
+	require.NoError(t, err)
+	return sd, jws.ES256KVerifier{Address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func TestIssueVerify(t *testing.T) {
+	sd, v := issue(t)
+	require.Len(t, sd.Disclosures, 3)
+
+	claims, err := Verify(sd.String(), v)
+	require.NoError(t, err)
+	require.JSONEq(t, `"John"`, string(claims["given_name"]))
+	require.JSONEq(t, `{"country": "DE"}`, string(claims["address"]))
+	require.JSONEq(t, `"IdentityCredential"`, string(claims["vct"]))
+	require.JSONEq(t, `1883000000`, string(claims["exp"]))
+	require.NotContains(t, claims, "_sd")
+
+	claims, err = Verify(sd.Present("family_name"), v)
+	require.NoError(t, err)
+	require.JSONEq(t, `"Doe"`, string(claims["family_name"]))
+	require.NotContains(t, claims, "given_name")
+	require.NotContains(t, claims, "address")
+}
+
+func TestVerifyRejects(t *testing.T) {
+	sd, v := issue(t)
+
+	forged := Disclosure{Salt: sd.Disclosures[0].Salt, Name: "given_name", Value: json.RawMessage(`"Mallory"`)}
+	_, err := Verify(sd.JWT+"~"+forged.Encode()+"~", v)
+	require.Error(t, err, "forged disclosure")
+
+	one := sd.Disclosures[0].Encode()
+	_, err = Verify(sd.JWT+"~"+one+"~"+one+"~", v)
+	require.Error(t, err, "repeated disclosure")
+
+	_, err = Verify(sd.JWT+"~"+one, v)
+	require.Error(t, err, "missing trailing separator")
+
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	_, err = Verify(sd.String(), jws.ES256KVerifier{Address: crypto.PubkeyToAddress(other.PublicKey)})
+	require.Error(t, err, "wrong issuer key")
+
+	parts := strings.Split(sd.JWT, ".")
+	parts[1] = jws.Encode([]byte(`{"_sd":[],"iss":"did:example:mallory"}`))
+	_, err = Verify(strings.Join(parts, ".")+"~", v)
+	require.Error(t, err, "tampered payload")
+}
+
+func TestIssueRequiresType(t *testing.T) {
+	_, err := Issue(&app.Credential{Document: []byte(`{}`)}, "iss", jws.ES256K{})
+	require.ErrorIs(t, err, ErrMissingType)
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app/abi"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// sessionTag separates session signatures from other signatures.
+const sessionTag = "perun-credential-payment/session"
+
+var ErrSessionExpired = errors.New("session expired")
+
+// Session authorizes an ephemeral session key to open and update channels on
+// behalf of a funding account. Funds withdrawn from the channels of the
+// session key are sent to the funding account, so that the process using the
+// session key does not need to hold the key of the funding account.
+type Session struct {
+	Account   common.Address `json:"account"` // The funding account.
+	Key       common.Address `json:"key"`     // The address of the session key.
+	Expiry    uint64         `json:"expiry"`  // Unix time.
+	Signature []byte         `json:"signature"`
+}
+
+var sessionArgs = abi.Arguments{
+	{Type: abi.String},
+	{Type: abi.Address},
+	{Type: abi.Address},
+	{Type: abi.Uint64},
+}
+
+// NewSessionKey generates a session key and authorizes it on behalf of
+// funding account `acc` for `validity`. The key and the session are handed
+// to the process that opens channels, while `acc` stays offline.
+func NewSessionKey(acc Account, validity time.Duration) (*ecdsa.PrivateKey, *Session, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating session key: %w", err)
+	}
+	s := &Session{
+		Account: AccountAddress(acc),
+		Key:     crypto.PubkeyToAddress(key.PublicKey),
+		Expiry:  uint64(time.Now().Add(validity).Unix()),
+	}
+	if err := s.Sign(acc); err != nil {
+		return nil, nil, err
+	}
+	return key, s, nil
+}
+
+// Hash returns the hash of the session fields covered by the signature.
+func (s *Session) Hash() (Hash, error) {
+	b, err := sessionArgs.Pack(sessionTag, s.Account, s.Key, s.Expiry)
+	if err != nil {
+		return Hash{}, fmt.Errorf("encoding session: %w", err)
+	}
+	return crypto.Keccak256Hash(b), nil
+}
+
+// Sign signs the session with `acc`, which must be the funding account.
+func (s *Session) Sign(acc Account) error {
+	if AccountAddress(acc) != s.Account {
+		return ErrInvalidSigner
+	}
+	h, err := s.Hash()
+	if err != nil {
+		return err
+	}
+	sig, err := SignHash(acc, h)
+	if err != nil {
+		return err
+	}
+	s.Signature = sig[:]
+	return nil
+}
+
+// Verify checks that the session is signed by the funding account and not
+// expired at time `now`.
+func (s *Session) Verify(now time.Time) error {
+	if len(s.Signature) != data.SigLen {
+		return fmt.Errorf("invalid signature length")
+	}
+	h, err := s.Hash()
+	if err != nil {
+		return err
+	}
+	var sig [data.SigLen]byte
+	copy(sig[:], s.Signature)
+	if err := VerifySig(sig, h, s.Account); err != nil {
+		return err
+	}
+
+	if uint64(now.Unix()) > s.Expiry {
+		return ErrSessionExpired
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	acc := newAccount(t)
+	now := time.Now()
+	key, s, err := NewSessionKey(acc, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, AccountAddress(acc), s.Account)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), s.Key)
+	require.NoError(t, s.Verify(now))
+	require.ErrorIs(t, s.Verify(now.Add(2*time.Hour)), ErrSessionExpired)
+
+	// Only the funding account can sign the session.
+	require.ErrorIs(t, s.Sign(newAccount(t)), ErrInvalidSigner)
+
+	tampered := *s
+	tampered.Key = common.Address{1}
+	require.Error(t, tampered.Verify(now), "tampered key")
+	tampered = *s
+	tampered.Expiry++
+	require.Error(t, tampered.Verify(now), "tampered expiry")
+	tampered = *s
+	tampered.Signature = s.Signature[1:]
+	require.Error(t, tampered.Verify(now), "truncated signature")
+}
@@ -4,10 +4,11 @@ import (
 	"bytes"
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	appabi "github.com/perun-network/perun-credential-payment/app/abi"
 	"github.com/perun-network/perun-credential-payment/app/data"
-	"perun.network/go-perun/backend/ethereum/wallet/simple"
 )
 
 const (
@@ -15,7 +16,7 @@ const (
 	sigVMagicNum = 27
 )
 
-func SignHash(acc *simple.Account, h [data.HashLen]byte) ([data.SigLen]byte, error) {
+func SignHash(acc Account, h [data.HashLen]byte) ([data.SigLen]byte, error) {
 	sig, err := acc.SignHash(h[:])
 	if err != nil {
 		return [data.SigLen]byte{}, fmt.Errorf("signing hash: %w", err)
@@ -28,21 +29,49 @@ func SignHash(acc *simple.Account, h [data.HashLen]byte) ([data.SigLen]byte, err
 }
 
 func VerifySig(sig [data.SigLen]byte, h [data.HashLen]byte, addr common.Address) error {
+	signerAddr, err := RecoverSigner(sig, h)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(signerAddr[:], addr[:]) {
+		return ErrInvalidSigner
+	}
+
+	return nil
+}
+
+// RecoverSigner returns the address that made signature `sig` on hash `h`.
+func RecoverSigner(sig [data.SigLen]byte, h [data.HashLen]byte) (common.Address, error) {
 	sig[sigVIndex] -= sigVMagicNum
 	realSigner, err := crypto.Ecrecover(h[:], sig[:])
 	if err != nil {
-		return fmt.Errorf("failed to recover signer: %w", err)
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
 	}
 
 	signerPubKey, err := crypto.UnmarshalPubkey(realSigner)
 	if err != nil {
-		return fmt.Errorf("unmarshalling public key: %w", err)
+		return common.Address{}, fmt.Errorf("unmarshalling public key: %w", err)
 	}
+	return crypto.PubkeyToAddress(*signerPubKey), nil
+}
 
-	signerAddr := crypto.PubkeyToAddress(*signerPubKey)
-	if !bytes.Equal(signerAddr[:], addr[:]) {
-		return ErrInvalidSigner
+// CredentialHash returns the hash signed by the issuer for a credential on
+// the document with hash `h` that expires at `expiry` and is described by the
+// metadata with hash `meta`. Credentials without expiry and metadata are
+// signed on the document hash itself.
+func CredentialHash(h [data.HashLen]byte, expiry uint64, meta [data.HashLen]byte) [data.HashLen]byte {
+	if expiry == 0 && meta == ([data.HashLen]byte{}) {
+		return h
 	}
+	enc, err := credentialHashArgs.Pack(h, expiry, meta)
+	if err != nil {
+		panic(err)
+	}
+	return crypto.Keccak256Hash(enc)
+}
 
-	return nil
+var credentialHashArgs = abi.Arguments{
+	{Type: appabi.Bytes32},
+	{Type: appabi.Uint64},
+	{Type: appabi.Bytes32},
 }
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignatureSuite is a signature scheme for credentials. Credential signatures
+// are independent of the Ethereum account used for payments.
+type SignatureSuite interface {
+	// ID returns the identifier of the suite.
+	ID() string
+	// Verify verifies signature `sig` on message `msg` by public key `pub`.
+	Verify(pub, msg, sig []byte) error
+}
+
+// CredentialSigner signs credentials with a key of a signature suite.
+type CredentialSigner interface {
+	Suite() SignatureSuite
+	PublicKey() []byte
+	Sign(msg []byte) ([]byte, error)
+}
+
+var suites = struct {
+	sync.RWMutex
+	m map[string]SignatureSuite
+}{m: make(map[string]SignatureSuite)}
+
+// RegisterSuite registers signature suite `s` under its ID.
+func RegisterSuite(s SignatureSuite) {
+	suites.Lock()
+	defer suites.Unlock()
+	suites.m[s.ID()] = s
+}
+
+// SuiteByID returns the signature suite with ID `id`.
+func SuiteByID(id string) (SignatureSuite, error) {
+	suites.RLock()
+	defer suites.RUnlock()
+	s, ok := suites.m[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown signature suite: %s", id)
+	}
+	return s, nil
+}
+
+func init() {
+	RegisterSuite(Secp256k1Suite{})
+	RegisterSuite(Ed25519Suite{})
+	RegisterSuite(BLS12381Suite{})
+	RegisterSuite(BBSSuite{})
+}
+
+// Secp256k1Suite is the Ethereum signature scheme. Public keys are given as
+// addresses and messages must be 32-byte hashes. It is the only suite that
+// the app contract can verify.
+type Secp256k1Suite struct{}
+
+func (Secp256k1Suite) ID() string { return "secp256k1" }
+
+func (Secp256k1Suite) Verify(pub, msg, sig []byte) error {
+	var (
+		h [data.HashLen]byte
+		s [data.SigLen]byte
+	)
+	if len(pub) != common.AddressLength || len(msg) != len(h) || len(sig) != len(s) {
+		return ErrInvalidSignature
+	}
+	copy(h[:], msg)
+	copy(s[:], sig)
+	return VerifySig(s, h, common.BytesToAddress(pub))
+}
+
+// Secp256k1Signer signs with an Ethereum account.
+type Secp256k1Signer struct {
+	Account Account
+}
+
+func (s Secp256k1Signer) Suite() SignatureSuite { return Secp256k1Suite{} }
+
+func (s Secp256k1Signer) PublicKey() []byte { return AccountAddress(s.Account).Bytes() }
+
+func (s Secp256k1Signer) Sign(msg []byte) ([]byte, error) {
+	var h [data.HashLen]byte
+	if len(msg) != len(h) {
+		return nil, fmt.Errorf("message must be a %d-byte hash", len(h))
+	}
+	copy(h[:], msg)
+	sig, err := SignHash(s.Account, h)
+	return sig[:], err
+}
+
+// SuiteSignature is a signature on a credential by a key of a signature
+// suite. It complements the Ethereum signature that is exchanged against the
+// payment.
+type SuiteSignature struct {
+	Suite     string `json:"suite"`
+	PublicKey []byte `json:"publicKey"`
+	Signature []byte `json:"signature"`
+}
+
+// SignCredential signs the ID of credential `c` with signer `s`.
+func SignCredential(s CredentialSigner, c *Credential) (*SuiteSignature, error) {
+	return SignCredentialID(s, c.ID())
+}
+
+// SignCredentialID signs credential ID `id` with signer `s`, e.g., if only
+// the hash of the document is known.
+func SignCredentialID(s CredentialSigner, id Hash) (*SuiteSignature, error) {
+	sig, err := s.Sign(id[:])
+	if err != nil {
+		return nil, fmt.Errorf("signing credential: %w", err)
+	}
+	return &SuiteSignature{
+		Suite:     s.Suite().ID(),
+		PublicKey: s.PublicKey(),
+		Signature: sig,
+	}, nil
+}
+
+// Verify verifies the signature on credential `c`.
+func (s *SuiteSignature) Verify(c *Credential) error {
+	suite, err := SuiteByID(s.Suite)
+	if err != nil {
+		return err
+	}
+	id := c.ID()
+	return suite.Verify(s.PublicKey, id[:], s.Signature)
+}
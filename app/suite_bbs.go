@@ -0,0 +1,181 @@
+package app
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/perun-network/perun-credential-payment/app/bbs"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// BBSSuite is the BBS+ signature scheme on BLS12-381. As a suite, it signs a
+// message as a single attribute. Credentials with selectively disclosable
+// attributes are requested with their attributes committed in the offer,
+// see BBSCredential.
+type BBSSuite struct{}
+
+func (BBSSuite) ID() string { return "bbs-bls12381" }
+
+func (BBSSuite) Verify(pub, msg, sig []byte) error {
+	if bbs.Verify(pub, nil, []bbs.Scalar{bbs.MessageScalar(msg)}, sig) != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// BBSSigner signs with a BBS secret key.
+type BBSSigner struct {
+	Key *bbs.SecretKey
+}
+
+func (s BBSSigner) Suite() SignatureSuite { return BBSSuite{} }
+
+func (s BBSSigner) PublicKey() []byte { return s.Key.PublicKey() }
+
+func (s BBSSigner) Sign(msg []byte) ([]byte, error) {
+	return s.Key.Sign(nil, []bbs.Scalar{bbs.MessageScalar(msg)})
+}
+
+// EncodeAttributes encodes the attributes of a BBS credential as its
+// document.
+func EncodeAttributes(attrs []string) []byte {
+	enc, err := json.Marshal(attrs)
+	if err != nil {
+		panic(err) // Cannot happen for a string slice.
+	}
+	return enc
+}
+
+// DecodeAttributes decodes the attributes of a BBS credential from its
+// document.
+func DecodeAttributes(doc []byte) ([]string, error) {
+	var attrs []string
+	if err := json.Unmarshal(doc, &attrs); err != nil {
+		return nil, fmt.Errorf("decoding attributes: %w", err)
+	} else if len(attrs) == 0 || len(attrs) > bbs.MaxMessages {
+		return nil, fmt.Errorf("invalid number of attributes: %d", len(attrs))
+	}
+	return attrs, nil
+}
+
+// AttributeScalars returns the BBS message scalars of `attrs`, which are
+// committed to in the offer.
+func AttributeScalars(attrs []string) []Hash {
+	s := make([]Hash, len(attrs))
+	for i, a := range attrs {
+		s[i] = bbs.MessageScalar([]byte(a))
+	}
+	return s
+}
+
+// BBSHeader returns the header of the BBS signature requested by `offer`. It
+// binds the signature to the expiry and the signature domain, but not to the
+// document hash, which would allow verifiers to guess undisclosed
+// attributes.
+func BBSHeader(offer *data.Offer) []byte {
+	return bbsHeader(offer.Domain, offer.Expiry)
+}
+
+func bbsHeader(domain Hash, expiry uint64) []byte {
+	h := append([]byte(nil), domain[:]...)
+	var e [8]byte
+	binary.BigEndian.PutUint64(e[:], expiry)
+	return append(h, e[:]...)
+}
+
+// verifyBBSSignature verifies the BBS signature of `cert` on the attributes
+// committed to in `offer`.
+func verifyBBSSignature(offer *data.Offer, cert *data.Cert) error {
+	if len(offer.BBSKey) == 0 {
+		if len(cert.BBSSignature) != 0 {
+			return fmt.Errorf("unexpected BBS signature")
+		}
+		return nil
+	}
+	return bbs.Verify(offer.BBSKey, BBSHeader(offer), bbsScalars(offer.Attributes), cert.BBSSignature)
+}
+
+// validBBSOffer checks that `offer` either requests no BBS signature or a BBS
+// signature on a valid number of attributes.
+func validBBSOffer(offer *data.Offer) error {
+	if len(offer.BBSKey) == 0 && len(offer.Attributes) == 0 {
+		return nil
+	} else if len(offer.BBSKey) != bbs.PublicKeyLen {
+		return fmt.Errorf("invalid BBS key")
+	} else if n := len(offer.Attributes); n == 0 || n > bbs.MaxMessages {
+		return fmt.Errorf("invalid number of attributes: %d", n)
+	}
+	return nil
+}
+
+func bbsScalars(hs []Hash) []bbs.Scalar {
+	s := make([]bbs.Scalar, len(hs))
+	for i, h := range hs {
+		s[i] = h
+	}
+	return s
+}
+
+// BBSCredential is a credential with a BBS signature of the issuer on its
+// attributes. The holder can derive presentations from it that only disclose
+// some of the attributes.
+type BBSCredential struct {
+	Attributes []string
+	Expiry     uint64 // Unix time. Zero if the credential does not expire.
+	Domain     Hash   // Signature domain of the issuing offer.
+	PublicKey  []byte // BBS public key of the issuer.
+	Signature  []byte
+}
+
+// Verify verifies the BBS signature of the issuer.
+func (c *BBSCredential) Verify() error {
+	return bbs.Verify(c.PublicKey, bbsHeader(c.Domain, c.Expiry), c.scalars(), c.Signature)
+}
+
+// Present derives a presentation that only discloses the attributes at
+// indices `disclosed`, which must be sorted. The presentation is bound to
+// `nonce`, which is chosen by the verifier.
+func (c *BBSCredential) Present(disclosed []int, nonce []byte) (*BBSPresentation, error) {
+	header := bbsHeader(c.Domain, c.Expiry)
+	proof, err := bbs.DeriveProof(c.PublicKey, header, nonce, c.scalars(), c.Signature, disclosed)
+	if err != nil {
+		return nil, fmt.Errorf("deriving proof: %w", err)
+	}
+	p := &BBSPresentation{
+		Attributes: len(c.Attributes),
+		Disclosed:  make(map[int]string, len(disclosed)),
+		Expiry:     c.Expiry,
+		Domain:     c.Domain,
+		PublicKey:  c.PublicKey,
+		Proof:      proof,
+	}
+	for _, i := range disclosed {
+		p.Disclosed[i] = c.Attributes[i]
+	}
+	return p, nil
+}
+
+func (c *BBSCredential) scalars() []bbs.Scalar {
+	return bbsScalars(AttributeScalars(c.Attributes))
+}
+
+// BBSPresentation is a selective disclosure of a BBS credential.
+type BBSPresentation struct {
+	Attributes int            `json:"attributes"` // Total number of attributes.
+	Disclosed  map[int]string `json:"disclosed"`
+	Expiry     uint64         `json:"expiry"`
+	Domain     Hash           `json:"domain"`
+	PublicKey  []byte         `json:"publicKey"`
+	Proof      []byte         `json:"proof"`
+}
+
+// Verify verifies the presentation for nonce `nonce`. The caller must check
+// that the public key belongs to a trusted issuer.
+func (p *BBSPresentation) Verify(nonce []byte) error {
+	disclosed := make(map[int]bbs.Scalar, len(p.Disclosed))
+	for i, a := range p.Disclosed {
+		disclosed[i] = bbs.MessageScalar([]byte(a))
+	}
+	return bbs.VerifyProof(p.PublicKey, bbsHeader(p.Domain, p.Expiry), nonce, p.Attributes, disclosed, p.Proof)
+}
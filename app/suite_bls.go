@@ -0,0 +1,82 @@
+package app
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/perun-network/perun-credential-payment/app/internal/h2c"
+)
+
+// blsDST is the domain separation tag for hashing messages to G2.
+var blsDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_")
+
+// BLS12381Suite is the BLS signature scheme on BLS12-381 with public keys in
+// G1 and signatures in G2. Points are encoded uncompressed.
+type BLS12381Suite struct{}
+
+func (BLS12381Suite) ID() string { return "bls12381" }
+
+func (BLS12381Suite) Verify(pub, msg, sig []byte) error {
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	pk, err := g1.FromBytes(pub)
+	if err != nil || g1.IsZero(pk) || !g1.InCorrectSubgroup(pk) {
+		return ErrInvalidSignature
+	}
+	s, err := g2.FromBytes(sig)
+	if err != nil || !g2.InCorrectSubgroup(s) {
+		return ErrInvalidSignature
+	}
+	h, err := h2c.HashToG2(msg, blsDST)
+	if err != nil {
+		return err
+	}
+
+	// e(pk, H(m)) == e(g1, sig)
+	e := bls12381.NewPairingEngine()
+	e.AddPair(pk, h)
+	e.AddPairInv(g1.One(), s)
+	if !e.Check() {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// BLS12381Signer signs with a BLS secret key.
+type BLS12381Signer struct {
+	key *big.Int
+}
+
+// GenerateBLS12381Key generates a BLS secret key using randomness from `r`.
+// If `r` is nil, crypto/rand is used.
+func GenerateBLS12381Key(r io.Reader) (*BLS12381Signer, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	for {
+		k, err := rand.Int(r, h2c.R)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() != 0 {
+			return &BLS12381Signer{key: k}, nil
+		}
+	}
+}
+
+func (s *BLS12381Signer) Suite() SignatureSuite { return BLS12381Suite{} }
+
+func (s *BLS12381Signer) PublicKey() []byte {
+	g1 := bls12381.NewG1()
+	return g1.ToBytes(g1.MulScalar(g1.New(), g1.One(), s.key))
+}
+
+func (s *BLS12381Signer) Sign(msg []byte) ([]byte, error) {
+	h, err := h2c.HashToG2(msg, blsDST)
+	if err != nil {
+		return nil, err
+	}
+	g2 := bls12381.NewG2()
+	return g2.ToBytes(g2.MulScalar(g2.New(), h, s.key)), nil
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"crypto/ed25519"
+)
+
+// Ed25519Suite is the Ed25519 signature scheme.
+type Ed25519Suite struct{}
+
+func (Ed25519Suite) ID() string { return "ed25519" }
+
+func (Ed25519Suite) Verify(pub, msg, sig []byte) error {
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, msg, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Ed25519Signer signs with an Ed25519 key.
+type Ed25519Signer struct {
+	Key ed25519.PrivateKey
+}
+
+func (s Ed25519Signer) Suite() SignatureSuite { return Ed25519Suite{} }
+
+func (s Ed25519Signer) PublicKey() []byte { return s.Key.Public().(ed25519.PublicKey) }
+
+func (s Ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(s.Key, msg), nil
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureSuites(t *testing.T) {
+	bls, err := GenerateBLS12381Key(nil)
+	require.NoError(t, err)
+	for _, s := range []CredentialSigner{
+		Secp256k1Signer{Account: newAccount(t)},
+		newEd25519Signer(t),
+		bls,
+	} {
+		s := s
+		t.Run(s.Suite().ID(), func(t *testing.T) {
+			c := &Credential{Document: []byte("Perun/Bosch: SSI Credential Payment"), Expiry: 1}
+			sig, err := SignCredential(s, c)
+			require.NoError(t, err)
+			require.Equal(t, s.Suite().ID(), sig.Suite)
+			require.NoError(t, sig.Verify(c))
+
+			byID, err := SignCredentialID(s, c.ID())
+			require.NoError(t, err)
+			require.NoError(t, byID.Verify(c), "signature of ID")
+
+			// The signature covers the document and the properties of the
+			// credential.
+			other := *c
+			other.Document = []byte("other")
+			require.ErrorIs(t, sig.Verify(&other), ErrInvalidSignature, "other document")
+			other = *c
+			other.Expiry = 2
+			require.ErrorIs(t, sig.Verify(&other), ErrInvalidSignature, "other expiry")
+
+			tampered := *sig
+			tampered.Signature = append([]byte(nil), sig.Signature...)
+			tampered.Signature[len(tampered.Signature)-1] ^= 1
+			require.Error(t, tampered.Verify(c), "tampered signature")
+
+			unknown := *sig
+			unknown.Suite = "unknown"
+			require.Error(t, unknown.Verify(c), "unknown suite")
+		})
+	}
+}
+
+func TestSuiteMismatch(t *testing.T) {
+	c := &Credential{Document: []byte("Perun/Bosch: SSI Credential Payment")}
+	sig, err := SignCredential(newEd25519Signer(t), c)
+	require.NoError(t, err)
+
+	// An Ed25519 signature does not verify as BLS signature.
+	sig.Suite = BLS12381Suite{}.ID()
+	require.Error(t, sig.Verify(c))
+}
+
+func TestVerifyCredentialSuite(t *testing.T) {
+	acc := newAccount(t)
+	c := &Credential{Document: []byte("Perun/Bosch: SSI Credential Payment")}
+	sig, err := SignHash(acc, c.ID())
+	require.NoError(t, err)
+	c.Signature = sig[:]
+	c.SuiteSignature, err = SignCredential(newEd25519Signer(t), c)
+	require.NoError(t, err)
+	require.NoError(t, VerifyCredential(c, AccountAddress(acc), time.Now()))
+
+	c.SuiteSignature.Signature[0] ^= 1
+	require.ErrorIs(t, VerifyCredential(c, AccountAddress(acc), time.Now()), ErrInvalidSignature)
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+)
+
+// transition returns the states of a transition from `cur` to `next`, in
+// which the balances change from `curBals` to `nextBals`.
+func transition(cur, next channel.Data, curBals, nextBals []int64) (*channel.State, *channel.State) {
+	return testState(cur, curBals, 1), testState(next, nextBals, 2)
+}
+
+func testState(d channel.Data, bals []int64, version uint64) *channel.State {
+	balances := make([]channel.Bal, len(bals))
+	for i, b := range bals {
+		balances[i] = big.NewInt(b)
+	}
+	return &channel.State{
+		Version: version,
+		Allocation: channel.Allocation{
+			Assets:   []channel.Asset{ethwallet.AsWalletAddr(common.Address{1})},
+			Balances: [][]channel.Bal{balances},
+		},
+		Data: d,
+	}
+}
+
+func signOffer(t *testing.T, acc Account, offer *data.Offer) [data.SigLen]byte {
+	t.Helper()
+	sig, err := SignHash(acc, OfferHash(offer))
+	require.NoError(t, err)
+	return sig
+}
+
+func TestCoSignedTransition(t *testing.T) {
+	issuer, cosigner := newAccount(t), newAccount(t)
+	offer := &data.Offer{
+		Issuer:    AccountAddress(issuer),
+		DataHash:  ComputeDocumentHash([]byte("Perun/Bosch: SSI Credential Payment")),
+		Price:     big.NewInt(1),
+		Cosigners: []common.Address{AccountAddress(cosigner)},
+		Nonce:     1,
+	}
+	swapApp := NewCredentialSwapApp(ethwallet.AsWalletAddr(common.Address{}))
+	validate := func(cert *data.Cert) error {
+		cur, next := transition(offer, cert, []int64{5, 5}, []int64{4, 6})
+		return swapApp.ValidTransition(nil, cur, next, 1)
+	}
+
+	sig, cosig := signOffer(t, issuer, offer), signOffer(t, cosigner, offer)
+	require.NoError(t, validate(&data.Cert{Signature: sig, CoSignatures: [][data.SigLen]byte{cosig}}))
+	require.Error(t, validate(&data.Cert{Signature: sig}), "missing cosignature")
+	require.Error(t, validate(&data.Cert{Signature: sig, CoSignatures: [][data.SigLen]byte{sig}}), "cosignature of issuer")
+	require.Error(t, validate(&data.Cert{Signature: cosig, CoSignatures: [][data.SigLen]byte{cosig}}), "signature of cosigner")
+}
+
+func TestOfferByBuyer(t *testing.T) {
+	swapApp := NewCredentialSwapApp(ethwallet.AsWalletAddr(common.Address{}))
+	request := func(buyer uint16, actor channel.Index) error {
+		offer := &data.Offer{DataHash: Hash{1}, Price: big.NewInt(3), Buyer: buyer, Nonce: 2}
+		cur, next := transition(&data.DefaultData{}, offer, []int64{5, 4}, []int64{5, 4})
+		return swapApp.ValidTransition(nil, cur, next, actor)
+	}
+
+	// Either participant can request credentials, paying from its own
+	// balance.
+	require.NoError(t, request(0, 0))
+	require.NoError(t, request(1, 1))
+	require.ErrorIs(t, request(1, 0), ErrInvalidActor, "request for other buyer")
+	require.ErrorIs(t, request(0, 1), ErrInvalidActor, "request for other buyer")
+}
+
+func TestFee(t *testing.T) {
+	issuer := newAccount(t)
+	swapApp := NewCredentialSwapApp(ethwallet.AsWalletAddr(common.Address{}))
+	newOffer := func(price, fee int64) *data.Offer {
+		return &data.Offer{
+			Issuer:   AccountAddress(issuer),
+			DataHash: ComputeDocumentHash([]byte("Perun/Bosch: SSI Credential Payment")),
+			Price:    big.NewInt(price),
+			Fee:      big.NewInt(fee),
+			Nonce:    2,
+		}
+	}
+	request := func(offer *data.Offer, bals []int64) error {
+		cur, next := transition(&data.DefaultData{}, offer, bals, bals)
+		return swapApp.ValidTransition(nil, cur, next, 0)
+	}
+
+	require.NoError(t, request(newOffer(3, 1), []int64{5, 5, 0}))
+	require.NoError(t, request(newOffer(3, 0), []int64{5, 5}), "no fee without fee recipient")
+	require.ErrorIs(t, request(newOffer(3, 1), []int64{5, 5}), ErrInvalidFee, "fee without fee recipient")
+	require.ErrorIs(t, request(newOffer(3, 4), []int64{5, 5, 0}), ErrInvalidFee, "fee exceeds price")
+	require.ErrorIs(t, request(newOffer(3, -1), []int64{5, 5, 0}), ErrInvalidFee, "negative fee")
+
+	// On issuance, the fee goes to the fee recipient instead of the issuer.
+	offer := newOffer(3, 1)
+	cert := &data.Cert{Signature: signOffer(t, issuer, offer)}
+	issue := func(nextBals []int64) error {
+		cur, next := transition(offer, cert, []int64{5, 5, 0}, nextBals)
+		return swapApp.ValidTransition(nil, cur, next, 1)
+	}
+	require.NoError(t, issue([]int64{2, 7, 1}))
+	require.Error(t, issue([]int64{2, 8, 0}), "fee kept by issuer")
+	require.Error(t, issue([]int64{2, 6, 2}), "fee too high")
+}
+
+func TestFinalAdjustment(t *testing.T) {
+	swapApp := NewCredentialSwapApp(ethwallet.AsWalletAddr(common.Address{}))
+	finalize := func(nextBals []int64, final bool, actor channel.Index) error {
+		cur, next := transition(&data.DefaultData{}, &data.DefaultData{}, []int64{4, 6}, nextBals)
+		next.IsFinal = final
+		return swapApp.ValidTransition(nil, cur, next, actor)
+	}
+
+	require.NoError(t, finalize([]int64{4, 6}, true, 0))
+	// The actor may give up funds in a final state, e.g., as a refund.
+	require.NoError(t, finalize([]int64{5, 5}, true, 1))
+	require.NoError(t, finalize([]int64{3, 7}, true, 0))
+	require.Error(t, finalize([]int64{5, 5}, true, 0), "balance of peer decreased")
+	require.Error(t, finalize([]int64{5, 5}, false, 1), "not final")
+}
+
+// TestNonce checks that offers carry the version of their state as nonce,
+// so that signed offers cannot be replayed in later states.
+func TestNonce(t *testing.T) {
+	swapApp := NewCredentialSwapApp(ethwallet.AsWalletAddr(common.Address{}))
+	request := func(offer channel.Data) error {
+		cur, next := transition(&data.DefaultData{}, offer, []int64{5, 5}, []int64{5, 5})
+		return swapApp.ValidTransition(nil, cur, next, 0)
+	}
+	newOffer := func(nonce uint64) *data.Offer {
+		return &data.Offer{DataHash: Hash{1}, Price: big.NewInt(3), Nonce: nonce}
+	}
+	require.NoError(t, request(newOffer(2)))
+	require.ErrorIs(t, request(newOffer(1)), ErrInvalidNonce, "replayed offer")
+	require.ErrorIs(t, request(newOffer(3)), ErrInvalidNonce, "future offer")
+	batch := &data.BatchOffer{DataHashes: [][data.HashLen]byte{{1}}, Price: big.NewInt(3), Nonce: 2}
+	require.NoError(t, request(batch))
+	batch.Nonce = 1
+	require.ErrorIs(t, request(batch), ErrInvalidNonce, "replayed batch offer")
+
+	// Counter-offers keep the nonce of the offer, and the buyer's acceptance
+	// carries the version of its own state.
+	offer := newOffer(1)
+	counter := func(nonce uint64) error {
+		c := &data.CounterOffer{Offer: *newOffer(nonce)}
+		c.Price = big.NewInt(2)
+		cur, next := transition(offer, c, []int64{5, 5}, []int64{5, 5})
+		return swapApp.ValidTransition(nil, cur, next, 1)
+	}
+	require.NoError(t, counter(1))
+	require.ErrorIs(t, counter(2), ErrInvalidNonce, "counter-offer changes nonce")
+	accept := func(nonce uint64) error {
+		c := &data.CounterOffer{Offer: *newOffer(1)}
+		c.Price = big.NewInt(2)
+		accepted := newOffer(nonce)
+		accepted.Price = big.NewInt(2)
+		return swapApp.ValidTransition(nil, testState(c, []int64{5, 5}, 2), testState(accepted, []int64{5, 5}, 3), 0)
+	}
+	require.NoError(t, accept(3))
+	require.ErrorIs(t, accept(1), ErrInvalidNonce, "acceptance with nonce of offer")
+}
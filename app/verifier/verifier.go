@@ -0,0 +1,196 @@
+// Package verifier verifies purchased credentials for relying parties,
+// without running a client.
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/keys"
+	"github.com/perun-network/perun-credential-payment/app/revocation"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+)
+
+var (
+	ErrRevoked = errors.New("credential revoked")
+	// ErrKeyNotActive is returned by VerifyRegistered for credentials that
+	// are not signed by a key that the issuer published and that was active
+	// when the credential was issued.
+	ErrKeyNotActive = errors.New("signing key not active at issuance")
+)
+
+// Config configures a Verifier.
+type Config struct {
+	Revocations *revocation.Registry // Optional. Enables revocation checks.
+	DIDs        did.Resolver         // Optional. Defaults to resolving did:ethr, did:key, and did:web.
+	// RequireHolderBinding rejects credentials that are not bound to a
+	// holder DID.
+	RequireHolderBinding bool
+	// TrustAnchors are the authorities that certify issuers. If set, every
+	// credential must carry a certificate chain from its issuer up to one of
+	// them, see app.VerifyChain.
+	TrustAnchors []common.Address
+	// ContractSigs accepts credentials signed by contract accounts, see
+	// EIP-1271. Optional. Without it, only ECDSA signatures are accepted.
+	ContractSigs *app.ContractSigVerifier
+	// IssuerKeys is the registry of issuer keys used by VerifyRegistered.
+	// Optional.
+	IssuerKeys *keys.Registry
+	// KeyOverlap is how long after its rotation a key is still accepted by
+	// VerifyRegistered, see client.RotateIssuerKey.
+	KeyOverlap time.Duration
+	// Clock is the time source for expiry checks. Optional. Defaults to the
+	// system clock.
+	Clock clock.Clock
+}
+
+// Verifier verifies credentials.
+type Verifier struct {
+	cfg Config
+}
+
+func New(cfg Config) *Verifier {
+	if cfg.DIDs == nil {
+		cfg.DIDs = did.NewResolver(nil)
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.System()
+	}
+	return &Verifier{cfg}
+}
+
+// Verify checks that credential `c` is signed by `issuer`, not expired, and
+// not revoked. If the metadata names an issuer DID, the DID must control
+// `issuer`. If trust anchors are configured, the issuer must be certified by
+// one of them.
+func (v *Verifier) Verify(ctx context.Context, c *app.Credential, issuer common.Address) error {
+	if err := v.verifySig(ctx, c, issuer); err != nil {
+		return err
+	}
+	return v.check(ctx, c, issuer, v.cfg.DIDs)
+}
+
+func (v *Verifier) verifySig(ctx context.Context, c *app.Credential, issuer common.Address) error {
+	if v.cfg.ContractSigs != nil {
+		return v.cfg.ContractSigs.VerifyCredential(ctx, c, issuer, v.cfg.Clock.Now())
+	}
+	return app.VerifyCredential(c, issuer, v.cfg.Clock.Now())
+}
+
+// VerifyRegistered checks credential `c` like Verify, but for the issuer
+// account `issuer`, whose signing keys are looked up in the key registry. The
+// credential must be signed by a key published by the issuer that was active
+// at the issuance date of the metadata, or at the current time if there is
+// none, allowing for KeyOverlap after the key was rotated.
+func (v *Verifier) VerifyRegistered(ctx context.Context, c *app.Credential, issuer common.Address) error {
+	if v.cfg.IssuerKeys == nil {
+		return fmt.Errorf("no issuer key registry configured")
+	}
+	history, err := v.cfg.IssuerKeys.Keys(&bind.CallOpts{Context: ctx}, issuer)
+	if err != nil {
+		return fmt.Errorf("querying issuer key registry: %w", err)
+	}
+
+	issuedAt := v.cfg.Clock.Now()
+	if c.Metadata != nil && c.Metadata.IssuedAt != 0 {
+		issuedAt = time.Unix(int64(c.Metadata.IssuedAt), 0)
+	}
+	for _, k := range history {
+		if err := v.verifySig(ctx, c, k.Key); errors.Is(err, app.ErrCredentialExpired) {
+			return err
+		} else if err != nil {
+			continue
+		} else if !k.ActiveWithin(issuedAt, v.cfg.KeyOverlap) {
+			return fmt.Errorf("%w: %v", ErrKeyNotActive, k.Key)
+		}
+		return v.check(ctx, c, issuer, v.cfg.DIDs)
+	}
+	return fmt.Errorf("%w: not signed by a key of %v", ErrKeyNotActive, issuer)
+}
+
+// VerifyBatch verifies credentials as Verify does, where creds[i] must be
+// issued by issuers[i]. The signatures are verified in parallel and every
+// DID is resolved only once. Signatures of contract accounts are checked
+// one by one. The result holds the error of each credential,
+// or nil. It fails if the numbers of credentials and issuers differ.
+func (v *Verifier) VerifyBatch(ctx context.Context, creds []*app.Credential, issuers []common.Address) ([]error, error) {
+	now := v.cfg.Clock.Now()
+	errs, err := app.VerifyCredentials(creds, issuers, now)
+	if err != nil {
+		return nil, err
+	}
+	dids := &cachingResolver{r: v.cfg.DIDs, docs: make(map[string]*did.Document)}
+	for i, c := range creds {
+		if errs[i] != nil && v.cfg.ContractSigs != nil && !errors.Is(errs[i], app.ErrCredentialExpired) {
+			errs[i] = v.cfg.ContractSigs.VerifyCredential(ctx, c, issuers[i], now)
+		}
+		if errs[i] == nil {
+			errs[i] = v.check(ctx, c, issuers[i], dids)
+		}
+	}
+	return errs, nil
+}
+
+// check performs the checks of Verify after the signature check.
+func (v *Verifier) check(ctx context.Context, c *app.Credential, issuer common.Address, dids did.Resolver) error {
+	if c.Metadata != nil && c.Metadata.Issuer != "" {
+		doc, err := dids.Resolve(ctx, c.Metadata.Issuer)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", c.Metadata.Issuer, err)
+		} else if !doc.Controls(issuer) {
+			return fmt.Errorf("%w: %s does not control %v", app.ErrInvalidSigner, c.Metadata.Issuer, issuer)
+		}
+	}
+	if len(v.cfg.TrustAnchors) > 0 {
+		if err := app.VerifyChain(c.IssuerChain, issuer, v.cfg.TrustAnchors, v.cfg.Clock.Now()); err != nil {
+			return err
+		}
+	}
+	if v.cfg.RequireHolderBinding && (c.Metadata == nil || c.Metadata.Holder == "") {
+		return app.ErrNotBound
+	}
+
+	if v.cfg.Revocations != nil {
+		t, err := v.cfg.Revocations.RevokedAt(&bind.CallOpts{Context: ctx}, issuer, c.ID())
+		if err != nil {
+			return fmt.Errorf("querying revocation registry: %w", err)
+		} else if t.Sign() != 0 {
+			return ErrRevoked
+		}
+	}
+	return nil
+}
+
+// cachingResolver resolves every DID only once.
+type cachingResolver struct {
+	r    did.Resolver
+	docs map[string]*did.Document
+}
+
+func (c *cachingResolver) Resolve(ctx context.Context, id string) (*did.Document, error) {
+	if doc, ok := c.docs[id]; ok {
+		return doc, nil
+	}
+	doc, err := c.r.Resolve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.docs[id] = doc
+	return doc, nil
+}
+
+// VerifyPresentation verifies credential `c` as Verify does and checks that
+// the presenter proved to be the holder the credential is bound to by
+// signing `challenge`, see app.ProveHolder.
+func (v *Verifier) VerifyPresentation(ctx context.Context, c *app.Credential, issuer common.Address, challenge, proof []byte) error {
+	if err := v.Verify(ctx, c, issuer); err != nil {
+		return err
+	}
+	return app.VerifyHolderProof(ctx, v.cfg.DIDs, c, challenge, proof)
+}
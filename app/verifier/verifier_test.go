@@ -0,0 +1,167 @@
+package verifier_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/internal/bindtest"
+	"github.com/perun-network/perun-credential-payment/app/keys"
+	"github.com/perun-network/perun-credential-payment/app/revocation"
+	"github.com/perun-network/perun-credential-payment/app/verifier"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+func newAccount(t *testing.T) app.Account {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(t, err)
+	return acc.(*simple.Account)
+}
+
+// newCredential returns a credential with metadata `meta` that expires at
+// `expiry`, signed by `acc`.
+func newCredential(t *testing.T, acc app.Account, meta *app.Metadata, expiry uint64) *app.Credential {
+	t.Helper()
+	c := &app.Credential{Document: []byte("Perun/Bosch: SSI Credential Payment"), Expiry: expiry, Metadata: meta}
+	sig, err := app.SignHash(acc, c.ID())
+	require.NoError(t, err)
+	c.Signature = sig[:]
+	return c
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	issuer, other := newAccount(t), newAccount(t)
+	issuerAddr := app.AccountAddress(issuer)
+	clk := clock.NewFake(time.Unix(1000, 0))
+	v := verifier.New(verifier.Config{Clock: clk, DIDs: did.EthrResolver{}})
+
+	c := newCredential(t, issuer, nil, 2000)
+	require.NoError(t, v.Verify(ctx, c, issuerAddr))
+	require.Error(t, v.Verify(ctx, c, app.AccountAddress(other)), "other issuer")
+	require.Error(t, v.Verify(ctx, newCredential(t, other, nil, 2000), issuerAddr), "signed by other")
+
+	// The issuer DID must control the issuer account.
+	require.NoError(t, v.Verify(ctx, newCredential(t, issuer, &app.Metadata{Issuer: did.Ethr(issuerAddr)}, 0), issuerAddr))
+	require.ErrorIs(t, v.Verify(ctx, newCredential(t, issuer, &app.Metadata{Issuer: did.Ethr(common.Address{1})}, 0), issuerAddr), app.ErrInvalidSigner)
+
+	clk.Advance(time.Hour)
+	require.ErrorIs(t, v.Verify(ctx, c, issuerAddr), app.ErrCredentialExpired)
+}
+
+func TestVerifyRevoked(t *testing.T) {
+	ctx := context.Background()
+	issuer := newAccount(t)
+	issuerAddr := app.AccountAddress(issuer)
+	revoked := newCredential(t, issuer, nil, 0)
+	valid := newCredential(t, issuer, &app.Metadata{Type: "Diploma"}, 0)
+
+	b, err := bindtest.NewBackend(revocation.RevocationRegistryABI)
+	require.NoError(t, err)
+	b.Handle("revokedAt", func(args []interface{}) []interface{} {
+		if args[0].(common.Address) == issuerAddr && args[1].([32]byte) == revoked.ID() {
+			return []interface{}{big.NewInt(1000)}
+		}
+		return []interface{}{new(big.Int)}
+	})
+	r, err := revocation.NewRegistry(common.Address{1}, b)
+	require.NoError(t, err)
+	v := verifier.New(verifier.Config{Revocations: r})
+
+	require.ErrorIs(t, v.Verify(ctx, revoked, issuerAddr), verifier.ErrRevoked)
+	require.NoError(t, v.Verify(ctx, valid, issuerAddr))
+
+	errs, err := v.VerifyBatch(ctx, []*app.Credential{valid, revoked, valid}, []common.Address{issuerAddr, issuerAddr, {1}})
+	require.NoError(t, err)
+	require.NoError(t, errs[0])
+	require.ErrorIs(t, errs[1], verifier.ErrRevoked)
+	require.Error(t, errs[2], "other issuer")
+	_, err = v.VerifyBatch(ctx, []*app.Credential{valid}, nil)
+	require.Error(t, err, "missing issuer")
+}
+
+func TestVerifyPresentation(t *testing.T) {
+	ctx := context.Background()
+	issuer, holder := newAccount(t), newAccount(t)
+	issuerAddr := app.AccountAddress(issuer)
+	v := verifier.New(verifier.Config{DIDs: did.EthrResolver{}, RequireHolderBinding: true})
+
+	require.ErrorIs(t, v.Verify(ctx, newCredential(t, issuer, nil, 0), issuerAddr), app.ErrNotBound)
+	c := newCredential(t, issuer, &app.Metadata{Holder: did.Ethr(app.AccountAddress(holder))}, 0)
+	require.NoError(t, v.Verify(ctx, c, issuerAddr))
+
+	challenge := []byte("challenge")
+	proof, err := app.ProveHolder(holder, c, challenge)
+	require.NoError(t, err)
+	require.NoError(t, v.VerifyPresentation(ctx, c, issuerAddr, challenge, proof))
+	require.Error(t, v.VerifyPresentation(ctx, c, issuerAddr, []byte("replayed"), proof), "other challenge")
+	proof, err = app.ProveHolder(issuer, c, challenge)
+	require.NoError(t, err)
+	require.ErrorIs(t, v.VerifyPresentation(ctx, c, issuerAddr, challenge, proof), app.ErrWrongHolder, "presented by issuer")
+}
+
+func TestVerifyTrustAnchors(t *testing.T) {
+	ctx := context.Background()
+	anchor, issuer := newAccount(t), newAccount(t)
+	issuerAddr := app.AccountAddress(issuer)
+	clk := clock.NewFake(time.Unix(1500, 0))
+	v := verifier.New(verifier.Config{Clock: clk, TrustAnchors: []common.Address{app.AccountAddress(anchor)}})
+
+	c := newCredential(t, issuer, nil, 0)
+	require.ErrorIs(t, v.Verify(ctx, c, issuerAddr), app.ErrUntrustedIssuer, "no chain")
+
+	cert := &app.IssuerCertificate{Subject: issuerAddr, Authority: app.AccountAddress(anchor), ValidFrom: 1000, ValidUntil: 2000}
+	require.NoError(t, cert.Sign(anchor))
+	c.IssuerChain = []*app.IssuerCertificate{cert}
+	require.NoError(t, v.Verify(ctx, c, issuerAddr))
+
+	clk.Advance(time.Hour)
+	require.ErrorIs(t, v.Verify(ctx, c, issuerAddr), app.ErrUntrustedIssuer, "certificate expired")
+}
+
+func TestVerifyRegistered(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey, other := newAccount(t), newAccount(t), newAccount(t)
+	issuer := common.Address{2}
+	b, err := bindtest.NewBackend(keys.IssuerKeyRegistryABI)
+	require.NoError(t, err)
+	// The old key was active from 1000 to 2000, the new key since.
+	b.Handle("keyCount", func(args []interface{}) []interface{} {
+		if args[0].(common.Address) == issuer {
+			return []interface{}{big.NewInt(2)}
+		}
+		return []interface{}{new(big.Int)}
+	})
+	b.Handle("keyAt", func(args []interface{}) []interface{} {
+		if args[1].(*big.Int).Int64() == 0 {
+			return []interface{}{app.AccountAddress(oldKey), uint64(1000), uint64(2000)}
+		}
+		return []interface{}{app.AccountAddress(newKey), uint64(2000), uint64(0)}
+	})
+	r, err := keys.NewRegistry(common.Address{1}, b)
+	require.NoError(t, err)
+	clk := clock.NewFake(time.Unix(3000, 0))
+	v := verifier.New(verifier.Config{Clock: clk, IssuerKeys: r, KeyOverlap: time.Minute})
+
+	require.NoError(t, v.VerifyRegistered(ctx, newCredential(t, newKey, nil, 0), issuer))
+	require.ErrorIs(t, v.VerifyRegistered(ctx, newCredential(t, oldKey, nil, 0), issuer), verifier.ErrKeyNotActive, "rotated")
+	require.NoError(t, v.VerifyRegistered(ctx, newCredential(t, oldKey, &app.Metadata{IssuedAt: 1500}, 0), issuer), "issued before rotation")
+	require.NoError(t, v.VerifyRegistered(ctx, newCredential(t, oldKey, &app.Metadata{IssuedAt: 2030}, 0), issuer), "within overlap")
+	require.ErrorIs(t, v.VerifyRegistered(ctx, newCredential(t, oldKey, &app.Metadata{IssuedAt: 2100}, 0), issuer), verifier.ErrKeyNotActive)
+	require.ErrorIs(t, v.VerifyRegistered(ctx, newCredential(t, other, nil, 0), issuer), verifier.ErrKeyNotActive, "unpublished key")
+	require.ErrorIs(t, v.VerifyRegistered(ctx, newCredential(t, newKey, nil, 0), common.Address{3}), verifier.ErrKeyNotActive, "other issuer")
+	require.ErrorIs(t, v.VerifyRegistered(ctx, newCredential(t, newKey, nil, 2000), issuer), app.ErrCredentialExpired)
+
+	require.Error(t, verifier.New(verifier.Config{}).VerifyRegistered(ctx, newCredential(t, newKey, nil, 0), issuer), "no registry")
+}
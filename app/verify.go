@@ -0,0 +1,33 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// VerifyCredential checks that credential `c` is signed by `issuer` and not
+// expired at time `now`. Its suite signature, if any, must be valid, but is not
+// checked to be made by a key of `issuer`. It does not check revocation or holder binding, see package
+// verifier for that.
+func VerifyCredential(c *Credential, issuer common.Address, now time.Time) error {
+	if len(c.Signature) != data.SigLen {
+		return ErrInvalidSignature
+	}
+	var sig [data.SigLen]byte
+	copy(sig[:], c.Signature)
+	if err := VerifySig(sig, c.ID(), issuer); err != nil {
+		return err
+	}
+	if c.SuiteSignature != nil {
+		if err := c.SuiteSignature.Verify(c); err != nil {
+			return fmt.Errorf("verifying suite signature: %w", err)
+		}
+	}
+	if Expired(c.Expiry, now) {
+		return ErrCredentialExpired
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// signCredential sets the issuer signature of `c`, made by `acc`.
+func signCredential(t *testing.T, acc Account, c *Credential) {
+	t.Helper()
+	sig, err := SignHash(acc, c.ID())
+	require.NoError(t, err)
+	c.Signature = sig[:]
+}
+
+func TestCredentialExpiry(t *testing.T) {
+	acc := newAccount(t)
+	issuer := AccountAddress(acc)
+	now := time.Unix(1000, 0)
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+
+	// Credentials without expiry are signed on the document hash.
+	require.Equal(t, ComputeDocumentHash(doc), (&Credential{Document: doc}).ID())
+
+	c := &Credential{Document: doc, Expiry: 1001}
+	signCredential(t, acc, c)
+	require.NoError(t, VerifyCredential(c, issuer, now))
+	require.ErrorIs(t, VerifyCredential(c, issuer, now.Add(time.Second)), ErrCredentialExpired)
+
+	// The expiry is covered by the signature.
+	extended := *c
+	extended.Expiry = 2000
+	require.Error(t, VerifyCredential(&extended, issuer, now), "extended expiry")
+	removed := *c
+	removed.Expiry = 0
+	require.Error(t, VerifyCredential(&removed, issuer, now.Add(time.Hour)), "removed expiry")
+}
+
+func TestMetadata(t *testing.T) {
+	meta := &Metadata{Type: "Diploma", Schema: "schema", IssuedAt: 1000, Issuer: "did:ethr:0x01", Holder: "did:ethr:0x02"}
+	dec, err := DecodeMetadata(meta.Encode())
+	require.NoError(t, err)
+	require.Equal(t, meta, dec)
+	_, err = DecodeMetadata([]byte("invalid"))
+	require.Error(t, err)
+
+	// The metadata is covered by the signature.
+	acc := newAccount(t)
+	c := &Credential{Document: []byte("Perun/Bosch: SSI Credential Payment"), Metadata: meta}
+	signCredential(t, acc, c)
+	require.NoError(t, VerifyCredential(c, AccountAddress(acc), time.Now()))
+	other := *c
+	other.Metadata = &Metadata{Type: "Certificate", Schema: meta.Schema, IssuedAt: meta.IssuedAt}
+	require.Error(t, VerifyCredential(&other, AccountAddress(acc), time.Now()), "other metadata")
+	other.Metadata = nil
+	require.Error(t, VerifyCredential(&other, AccountAddress(acc), time.Now()), "removed metadata")
+}
+
+func TestTypedCredential(t *testing.T) {
+	acc := newAccount(t)
+	issuer := AccountAddress(acc)
+	domain := Domain{ChainID: big.NewInt(1337), VerifyingContract: common.Address{1}}.Separator()
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+
+	c := &Credential{Document: doc, Expiry: 2000, Domain: domain}
+	require.NotEqual(t, CredentialHash(ComputeDocumentHash(doc), c.Expiry, Hash{}), c.ID(), "typed hash")
+	require.Equal(t, CredentialHash(ComputeDocumentHash(doc), c.Expiry, Hash{}), TypedCredentialHash(Hash{}, ComputeDocumentHash(doc), c.Expiry, Hash{}), "zero domain")
+	signCredential(t, acc, c)
+	require.NoError(t, VerifyCredential(c, issuer, time.Unix(1000, 0)))
+
+	// Signatures are bound to the chain and the app contract.
+	for _, d := range []Domain{
+		{ChainID: big.NewInt(1), VerifyingContract: common.Address{1}},
+		{ChainID: big.NewInt(1337), VerifyingContract: common.Address{2}},
+	} {
+		other := *c
+		other.Domain = d.Separator()
+		require.Error(t, VerifyCredential(&other, issuer, time.Unix(1000, 0)), "domain %v", d)
+	}
+	raw := *c
+	raw.Domain = Hash{}
+	require.Error(t, VerifyCredential(&raw, issuer, time.Unix(1000, 0)), "raw hash")
+}
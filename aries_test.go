@@ -0,0 +1,89 @@
+package main_test
+
+import (
+	"context"
+	"encoding/base64"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/aries"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAriesBridges checks that a credential requested by an Aries holder
+// agent is issued once the Aries issuer agent approves it, and rejected
+// otherwise.
+func TestAriesBridges(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// The holder agent verifies the signature in the EIP-712 domain of the
+	// clients.
+	var domain app.Hash
+	env := testutil.Setup(t, func(holder, _ *client.ClientConfig) {
+		domain = app.Domain{ChainID: holder.ChainID, VerifyingContract: holder.AppAddress}.Separator()
+	})
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	price := env.Amount(1)
+
+	// The issuer agent approves the first request and rejects the others.
+	var approved int32
+	var issuerBridge *aries.IssuerBridge
+	issuerAgent := func(_ context.Context, m *aries.Message) error {
+		resp := &aries.Message{Type: aries.TypeProblemReport, ID: "resp", Thread: &aries.Thread{ThID: m.ID}}
+		if atomic.AddInt32(&approved, 1) == 1 {
+			resp.Type = aries.TypeIssueCredential
+		}
+		return issuerBridge.HandleResponse(resp)
+	}
+	go func() {
+		req, err := issuer.NextConnectionRequest(ctx)
+		if err != nil {
+			return
+		}
+		conn, err := req.Accept(ctx)
+		if err != nil {
+			return
+		}
+		issuerBridge = aries.NewIssuerBridge(conn, issuer.Account(), issuerAgent, log.None{})
+		_ = issuerBridge.Serve(ctx)
+	}()
+
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	toHolderAgent := make(chan *aries.Message, 1)
+	holderBridge := aries.NewHolderBridge(conn, func(_ context.Context, m *aries.Message) error {
+		toHolderAgent <- m
+		return nil
+	})
+	request := func(id string) *aries.Message {
+		return &aries.Message{
+			Type:     aries.TypeRequestCredential,
+			ID:       id,
+			Payment:  &aries.Payment{Price: price.String(), Issuer: issuer.Address()},
+			Formats:  []aries.Format{{AttachID: "doc", Format: aries.FormatDocument}},
+			Requests: []aries.Attachment{{ID: "doc", Data: aries.AttachmentData{Base64: base64.StdEncoding.EncodeToString(doc)}}},
+		}
+	}
+
+	require.NoError(holderBridge.HandleRequest(ctx, request("req-1")))
+	resp := <-toHolderAgent
+	require.Equal(aries.TypeIssueCredential, resp.Type)
+	require.Equal("req-1", resp.Thread.ThID)
+	require.Len(resp.Credentials, 1)
+	sig, err := base64.StdEncoding.DecodeString(resp.Credentials[0].Data.Base64)
+	require.NoError(err)
+	require.NoError(app.VerifyCredential(&app.Credential{Document: doc, Signature: sig, Domain: domain}, issuer.Address(), time.Now()))
+
+	require.Error(holderBridge.HandleRequest(ctx, request("req-2")), "rejected request")
+	resp = <-toHolderAgent
+	require.Equal(aries.TypeProblemReport, resp.Type)
+	require.Equal("req-2", resp.Thread.ThID)
+}
@@ -0,0 +1,76 @@
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatchCredentials checks that a batch of credentials is issued and paid
+// for in a single update.
+func TestBatchCredentials(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	docs := make([][]byte, 3)
+	for i := range docs {
+		docs[i] = []byte(fmt.Sprintf("Perun/Bosch: SSI Credential Payment %d", i))
+	}
+	balance, price := env.Amount(5), env.Amount(3)
+
+	issuerErr := make(chan error, 1)
+	go func() {
+		issuerErr <- func() error {
+			req, err := issuer.NextConnectionRequest(ctx)
+			if err != nil {
+				return err
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return err
+			}
+			batchReq, err := conn.NextBatchCredentialRequest(ctx)
+			if err != nil {
+				return err
+			}
+			if err := batchReq.CheckDocs(docs); err != nil {
+				return err
+			} else if err := batchReq.CheckDocs(docs[:2]); err == nil {
+				return fmt.Errorf("partial batch accepted")
+			} else if err := batchReq.CheckPrice(price); err != nil {
+				return err
+			}
+			return batchReq.IssueCredentials(ctx, issuer.Account())
+		}()
+	}()
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), balance)
+	require.NoError(err, "proposing connection")
+	version := conn.State().Version
+	asyncCreds, err := conn.RequestCredentials(ctx, docs, price, issuer.Address())
+	require.NoError(err, "requesting credentials")
+	resp, err := asyncCreds.Await(ctx)
+	require.NoError(err, "awaiting credentials")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+
+	require.Len(resp.Signatures, len(docs))
+	for i, doc := range docs {
+		cred := &app.Credential{Document: doc, Signature: resp.Signatures[i]}
+		require.NoError(app.VerifyCredential(cred, issuer.Address(), time.Now()), "credential %d", i)
+	}
+	require.Equal(version+2, conn.State().Version, "state version")
+	rest := new(big.Int).Sub(balance, price)
+	require.Zero(rest.Cmp(conn.State().Balances[app.AssetIdx][conn.Idx()]), "holder balance")
+
+	_, err = conn.RequestCredentials(ctx, nil, price, issuer.Address())
+	require.Error(err, "empty batch")
+}
@@ -0,0 +1,243 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// The benchmarks measure the protocol on the chain of the test environment.
+// Compare runs with benchstat to catch regressions, see README.
+
+var benchDoc = []byte("Perun/Bosch: SSI Credential Payment Benchmark")
+
+// BenchmarkChannelOpen measures the time until a channel is opened and
+// funded by both parties.
+func BenchmarkChannelOpen(b *testing.B) {
+	env := testutil.Setup(b)
+	ctx := benchContext(b)
+	price := benchPrice(env)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		openChannel(ctx, b, env, price)
+	}
+}
+
+// BenchmarkIssuance measures the round-trip latency of issuing a credential
+// in an open channel, from the request of the holder until it accepted the
+// payment. It also reports the issuances per second per channel.
+func BenchmarkIssuance(b *testing.B) {
+	env := testutil.Setup(b)
+	ctx := benchContext(b)
+	price := benchPrice(env)
+	holderConn, issuerConn := openChannel(ctx, b, env, new(big.Int).Mul(price, big.NewInt(int64(b.N))))
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		require.NoError(b, issue(ctx, env, holderConn, issuerConn, price, true))
+	}
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "issuances/s")
+}
+
+// BenchmarkDisputeResolution measures the time from the holder refusing to
+// pay for an issued credential until the dispute is resolved on-chain.
+func BenchmarkDisputeResolution(b *testing.B) {
+	env := testutil.Setup(b)
+	ctx := benchContext(b)
+	price := benchPrice(env)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		holderConn, issuerConn := openChannel(ctx, b, env, price)
+		b.StartTimer()
+
+		require.NoError(b, issue(ctx, env, holderConn, issuerConn, price, false))
+		errs := make(chan error, 2)
+		go func() { errs <- holderConn.WaitConcludadable(ctx) }()
+		go func() { errs <- issuerConn.WaitConcludadable(ctx) }()
+		require.NoError(b, <-errs, "waiting for dispute resolution")
+		require.NoError(b, <-errs, "waiting for dispute resolution")
+	}
+}
+
+// BenchmarkCompression measures the bytes on the wire of requests carrying
+// multi-KB documents, without and with compression of messages. It runs
+// without chain.
+func BenchmarkCompression(b *testing.B) {
+	for _, size := range []int{4 << 10, 16 << 10, 64 << 10} {
+		doc := benchDocument(size)
+		for _, codec := range []message.Codec{nil, message.Gzip} {
+			name := "none"
+			if codec != nil {
+				name = codec.Name()
+			}
+			b.Run(fmt.Sprintf("%dKiB/%s", size>>10, name), func(b *testing.B) {
+				benchCompression(b, codec, doc)
+			})
+		}
+	}
+}
+
+func benchCompression(b *testing.B, codec message.Codec, doc []byte) {
+	ctx := benchContext(b)
+	var cfg message.CompressionConfig
+	if codec != nil {
+		cfg.Codecs = []message.Codec{codec}
+	}
+	bus := &encodingBus{LocalBus: wire.NewLocalBus()}
+	alice, bob := ethwallet.AsWalletAddr(common.Address{1}), ethwallet.AsWalletAddr(common.Address{2})
+	var sender *message.Messenger
+	for _, addr := range []wire.Address{alice, bob} {
+		cbus := message.NewCompressingBus(bus, cfg)
+		m := message.NewMessenger(cbus, addr)
+		cbus.Negotiate(m)
+		m.Handle("document", func(context.Context, wire.Address, json.RawMessage) (interface{}, error) {
+			return nil, nil
+		})
+		require.NoError(b, message.NewBus(cbus, m).SubscribeClient(wire.NewReceiver(), addr))
+		if addr == alice {
+			sender = m
+		}
+	}
+	// The first request starts the negotiation of the codec, which completes
+	// in the background.
+	require.NoError(b, sender.Request(ctx, bob, "document", doc, nil))
+	time.Sleep(10 * time.Millisecond)
+
+	b.ResetTimer()
+	atomic.StoreInt64(&bus.n, 0)
+	for i := 0; i < b.N; i++ {
+		require.NoError(b, sender.Request(ctx, bob, "document", doc, nil))
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&bus.n))/float64(b.N), "wire-B/op")
+}
+
+// benchDocument returns a JSON document of about `size` bytes.
+func benchDocument(size int) []byte {
+	type entry struct {
+		Name    string `json:"name"`
+		Degree  string `json:"degree"`
+		Issued  string `json:"issued"`
+		Courses []int  `json:"courses"`
+	}
+	var entries []entry
+	for n := 0; n < size; {
+		e := entry{
+			Name:    fmt.Sprintf("Holder %d", len(entries)),
+			Degree:  "Master of Science in Computer Science",
+			Issued:  time.Date(2021, 1, 1+len(entries)%28, 0, 0, 0, 0, time.UTC).Format(time.RFC3339),
+			Courses: []int{len(entries) % 7, len(entries) % 11, len(entries) % 13},
+		}
+		enc, _ := json.Marshal(e)
+		n += len(enc)
+		entries = append(entries, e)
+	}
+	doc, _ := json.Marshal(entries)
+	return doc
+}
+
+// encodingBus passes messages through their wire encoding and counts the
+// encoded bytes.
+type encodingBus struct {
+	*wire.LocalBus
+	n int64
+}
+
+func (b *encodingBus) Publish(ctx context.Context, e *wire.Envelope) error {
+	var buf bytes.Buffer
+	if err := wire.Encode(e.Msg, &buf); err != nil {
+		return err
+	}
+	atomic.AddInt64(&b.n, int64(buf.Len()))
+	msg, err := wire.Decode(&buf)
+	if err != nil {
+		return err
+	}
+	return b.LocalBus.Publish(ctx, &wire.Envelope{Sender: e.Sender, Recipient: e.Recipient, Msg: msg})
+}
+
+func benchContext(b *testing.B) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(cancel)
+	return ctx
+}
+
+// benchPrice returns the price of a credential, which is small so that the
+// accounts can fund many channels.
+func benchPrice(env *testutil.Environment) *big.Int {
+	return new(big.Int).Div(env.Amount(1), big.NewInt(1000))
+}
+
+// openChannel opens a channel in which the holder deposits `balance`.
+func openChannel(ctx context.Context, b *testing.B, env *testutil.Environment, balance *big.Int) (holderConn, issuerConn *connection.Connection) {
+	errs := make(chan error, 1)
+	go func() {
+		req, err := env.Issuer.NextConnectionRequest(ctx)
+		if err != nil {
+			errs <- fmt.Errorf("awaiting connection request: %w", err)
+			return
+		}
+		issuerConn, err = req.Accept(ctx)
+		errs <- err
+	}()
+
+	holderConn, err := env.Holder.Connect(ctx, env.Issuer.PerunAddress(), balance)
+	require.NoError(b, err, "proposing connection")
+	require.NoError(b, <-errs, "accepting connection")
+	return holderConn, issuerConn
+}
+
+// issue issues a credential at `price`, which the holder pays if `pay` is
+// true and rejects otherwise.
+func issue(
+	ctx context.Context,
+	env *testutil.Environment,
+	holderConn, issuerConn *connection.Connection,
+	price *big.Int,
+	pay bool,
+) error {
+	errs := make(chan error, 1)
+	go func() {
+		req, err := issuerConn.NextCredentialRequest(ctx)
+		if err != nil {
+			errs <- fmt.Errorf("awaiting credential request: %w", err)
+			return
+		}
+		errs <- req.IssueCredential(ctx, env.Issuer.Account())
+	}()
+
+	asyncCred, err := holderConn.RequestCredential(ctx, benchDoc, price, env.Issuer.Address())
+	if err != nil {
+		return fmt.Errorf("requesting credential: %w", err)
+	}
+	resp, err := asyncCred.Await(ctx)
+	if err != nil {
+		return fmt.Errorf("awaiting credential: %w", err)
+	}
+	if pay {
+		err = resp.Accept(ctx)
+	} else {
+		err = resp.Reject(ctx, "Won't pay!")
+	}
+	if err != nil {
+		return fmt.Errorf("responding to issuance: %w", err)
+	}
+	// If the holder refused to pay, the issuer enforces the payment on-chain.
+	return <-errs
+}
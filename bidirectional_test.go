@@ -0,0 +1,85 @@
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBidirectionalIssuance checks that both participants of a channel can
+// request credentials from each other, each paying from its own balance.
+func TestBidirectionalIssuance(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	alice, bob := env.Holder, env.Issuer
+	aliceDoc := []byte("Perun/Bosch: SSI Credential Payment")
+	bobDoc := []byte("Perun/Bosch: SSI Credential Payment, the other way round")
+
+	// Bob deposits, so that he can buy from Alice, and issues one credential
+	// to her.
+	bobConns := make(chan *connection.Connection, 1)
+	bobErr := make(chan error, 1)
+	go func() {
+		bobErr <- func() error {
+			req, err := bob.NextConnectionRequest(ctx)
+			if err != nil {
+				return err
+			} else if req.Balance().Cmp(env.Amount(3)) != 0 {
+				return fmt.Errorf("wrong balance: %v", req.Balance())
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return err
+			}
+			bobConns <- conn
+			credReq, err := conn.NextCredentialRequest(ctx)
+			if err != nil {
+				return err
+			}
+			return credReq.IssueCredential(ctx, bob.Account())
+		}()
+	}()
+	aliceConn, err := alice.ConnectWithPeerBalance(ctx, bob.PerunAddress(), env.Amount(5), env.Amount(3))
+	require.NoError(err, "proposing connection")
+	bobConn := <-bobConns
+
+	asyncCred, err := aliceConn.RequestCredential(ctx, aliceDoc, env.Amount(1), bob.Address())
+	require.NoError(err, "requesting credential from Bob")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential from Bob")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-bobErr, "issuing to Alice")
+	require.NoError(app.VerifyCredential(&app.Credential{Document: aliceDoc, Signature: resp.Signature, Domain: resp.Domain()}, bob.Address(), time.Now()))
+
+	aliceErr := make(chan error, 1)
+	go func() {
+		credReq, err := aliceConn.NextCredentialRequest(ctx)
+		if err != nil {
+			aliceErr <- err
+			return
+		}
+		aliceErr <- credReq.IssueCredential(ctx, alice.Account())
+	}()
+	asyncCred, err = bobConn.RequestCredential(ctx, bobDoc, env.Amount(2), alice.Address())
+	require.NoError(err, "requesting credential from Alice")
+	resp, err = asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential from Alice")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-aliceErr, "issuing to Bob")
+	require.NoError(app.VerifyCredential(&app.Credential{Document: bobDoc, Signature: resp.Signature, Domain: resp.Domain()}, alice.Address(), time.Now()))
+
+	// Alice paid 1 of her 5 and received 2, Bob paid 2 of his 3 and
+	// received 1.
+	bals := bobConn.State().Balances[app.AssetIdx]
+	require.Zero(env.Amount(6).Cmp(bals[aliceConn.Idx()]), "Alice's balance")
+	require.Zero(env.Amount(2).Cmp(bals[bobConn.Idx()]), "Bob's balance")
+}
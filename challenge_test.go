@@ -0,0 +1,37 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChallengeDuration checks that channels are opened with the proposed
+// challenge duration if it is within the bounds of the peer.
+func TestChallengeDuration(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t, func(_, issuer *client.ClientConfig) {
+		issuer.MinChallengeDuration = time.Second
+		issuer.MaxChallengeDuration = time.Hour
+	})
+	holder, issuer := env.Holder, env.Issuer
+
+	_, err := holder.ConnectWithChallengeDuration(ctx, issuer.PerunAddress(), env.Amount(5), env.Amount(0), 2*time.Hour)
+	var rejected *connection.PeerRejectedError
+	require.True(errors.As(err, &rejected), "above maximum: %v", err)
+
+	issuerErr := runIssuer(ctx, issuer, 0, nil)
+	conn, err := holder.ConnectWithChallengeDuration(ctx, issuer.PerunAddress(), env.Amount(5), env.Amount(0), 30*time.Minute)
+	require.NoError(err, "proposing connection")
+	require.NoError(<-issuerErr, "running issuer")
+	require.Equal(uint64((30 * time.Minute).Seconds()), conn.Params().ChallengeDuration)
+}
@@ -0,0 +1,60 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+)
+
+// TestChannels checks that the open channels of a client are listed in the
+// order in which they were opened, and that concluded channels are dropped.
+func TestChannels(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	events := holder.Events(ctx)
+	ids := func(conns []*connection.Connection) []channel.ID {
+		ids := make([]channel.ID, len(conns))
+		for i, conn := range conns {
+			ids[i] = conn.ID()
+		}
+		return ids
+	}
+
+	// The holder opens two channels to the issuer.
+	var conns []*connection.Connection
+	for i := 0; i < 2; i++ {
+		issuerErr := runIssuer(ctx, issuer, 0, nil)
+		conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+		require.NoError(err, "proposing connection %d", i)
+		require.NoError(<-issuerErr, "running issuer")
+		conns = append(conns, conn)
+	}
+	require.Equal(ids(conns), ids(holder.Channels()))
+	require.Equal(ids(conns), ids(holder.ChannelsWith(issuer.PerunAddress())))
+	require.Empty(holder.ChannelsWith(ethwallet.AsWalletAddr(common.Address{1})), "unknown peer")
+	require.Equal(ids(conns), ids(issuer.ChannelsWith(holder.PerunAddress())))
+
+	require.NoError(conns[0].Close(ctx), "closing")
+	for {
+		select {
+		case ev := <-events:
+			if concluded, ok := ev.(*connection.ChannelConcluded); ok && concluded.Channel == conns[0].ID() {
+				require.Equal(ids(conns[1:]), ids(holder.Channels()))
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("channel not concluded:", ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChunkedCredential checks that every chunk of a chunked credential is
+// paid for in its own update.
+func TestChunkedCredential(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	const chunkSize = 16
+	n := len(app.SplitDocument(doc, chunkSize))
+	balance, price := env.Amount(5), env.Amount(3)
+
+	issuerErr := make(chan error, 1)
+	go func() {
+		issuerErr <- func() error {
+			req, err := issuer.NextConnectionRequest(ctx)
+			if err != nil {
+				return err
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return err
+			}
+			for i := 0; i < n; i++ {
+				credReq, err := conn.NextCredentialRequest(ctx)
+				if err != nil {
+					return err
+				}
+				if err := credReq.IssueCredential(ctx, issuer.Account()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+	}()
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), balance)
+	require.NoError(err, "proposing connection")
+	version := conn.State().Version
+	cred, err := conn.RequestChunkedCredential(ctx, doc, chunkSize, price, issuer.Address())
+	require.NoError(err, "requesting chunked credential")
+	require.NoError(<-issuerErr, "running issuer")
+
+	require.Len(cred.Signatures, n)
+	require.NoError(cred.Verify(issuer.Address()))
+	// Every chunk is requested and paid for in two updates.
+	require.Equal(version+uint64(2*n), conn.State().Version, "state version")
+	rest := new(big.Int).Sub(balance, price)
+	require.Zero(rest.Cmp(conn.State().Balances[app.AssetIdx][conn.Idx()]), "holder balance")
+}
@@ -0,0 +1,116 @@
+// Package access decides which peers may open channels with the client.
+// Proposals of denied peers are rejected before any funds are deposited.
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrDenied = errors.New("access denied")
+
+// DeniedError is returned when a peer is denied access.
+type DeniedError struct {
+	Peer   common.Address
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%v: %v: %s", ErrDenied, e.Peer, e.Reason)
+}
+
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+// Control decides on the access of peers.
+type Control interface {
+	// Check returns nil if `peer` may open a channel and an error describing
+	// the reason otherwise.
+	Check(ctx context.Context, peer common.Address) error
+}
+
+// Func is a Control implemented by a function, e.g., a lookup in an external
+// registry.
+type Func func(ctx context.Context, peer common.Address) error
+
+func (f Func) Check(ctx context.Context, peer common.Address) error {
+	return f(ctx, peer)
+}
+
+// All grants access if all controls grant it. Controls are checked in order
+// and checking stops at the first denial.
+func All(controls ...Control) Control {
+	return Func(func(ctx context.Context, peer common.Address) error {
+		for _, c := range controls {
+			if err := c.Check(ctx, peer); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List is an allowlist or a denylist of peers, which can be changed while it
+// is in use.
+type List struct {
+	allow bool
+	mu    sync.RWMutex
+	peers map[common.Address]struct{}
+}
+
+// Allowlist returns a list that grants access to the given peers only.
+func Allowlist(peers ...common.Address) *List {
+	return newList(true, peers)
+}
+
+// Denylist returns a list that denies access to the given peers.
+func Denylist(peers ...common.Address) *List {
+	return newList(false, peers)
+}
+
+func newList(allow bool, peers []common.Address) *List {
+	l := &List{allow: allow, peers: make(map[common.Address]struct{}, len(peers))}
+	l.Add(peers...)
+	return l
+}
+
+// Add adds peers to the list.
+func (l *List) Add(peers ...common.Address) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range peers {
+		l.peers[p] = struct{}{}
+	}
+}
+
+// Remove removes peers from the list.
+func (l *List) Remove(peers ...common.Address) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range peers {
+		delete(l.peers, p)
+	}
+}
+
+// Contains returns whether `peer` is on the list.
+func (l *List) Contains(peer common.Address) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.peers[peer]
+	return ok
+}
+
+func (l *List) Check(_ context.Context, peer common.Address) error {
+	switch listed := l.Contains(peer); {
+	case l.allow && !listed:
+		return &DeniedError{Peer: peer, Reason: "not on allowlist"}
+	case !l.allow && listed:
+		return &DeniedError{Peer: peer, Reason: "on denylist"}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"errors"
+	"io"
+
+	"github.com/perun-network/perun-credential-payment/client/connection"
+)
+
+var ErrNoAccounting = errors.New("accounting disabled")
+
+// ExportChannels writes the accounting records of the channels of the client
+// to `w` in format `f`, e.g., connection.FormatCSV. It fails with
+// ErrNoAccounting if ClientConfig.Accounting is not set.
+func (c *Client) ExportChannels(w io.Writer, f connection.ExportFormat) error {
+	if c.ledger == nil {
+		return ErrNoAccounting
+	}
+	return c.ledger.ExportChannels(w, f)
+}
+
+// ExportPayments writes the records of the payments for credentials made in
+// the channels of the client to `w` in format `f`. It fails with
+// ErrNoAccounting if ClientConfig.Accounting is not set.
+func (c *Client) ExportPayments(w io.Writer, f connection.ExportFormat) error {
+	if c.ledger == nil {
+		return ErrNoAccounting
+	}
+	return c.ledger.ExportPayments(w, f)
+}
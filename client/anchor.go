@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	pkgapp "github.com/perun-network/perun-credential-payment/app"
+)
+
+const (
+	anchorBaseGas  = 30000
+	anchorGasPerID = 25000
+)
+
+var ErrNoAnchorContract = errors.New("no anchor contract configured")
+
+// anchorCredentials anchors the credentials `ids` issued by the client.
+func (c *Client) anchorCredentials(ctx context.Context, ids []pkgapp.Hash) error {
+	cb := &c.perunClient.ContractBackend
+	acc := c.txAccount()
+	opts, err := cb.NewTransactor(ctx, anchorBaseGas+anchorGasPerID*uint64(len(ids)), acc)
+	if err != nil {
+		return fmt.Errorf("creating transactor: %w", err)
+	}
+
+	raw := make([][32]byte, len(ids))
+	for i, id := range ids {
+		raw[i] = id
+	}
+	tx, err := c.anchor.Anchor(opts, raw)
+	if err != nil {
+		return fmt.Errorf("sending anchoring: %w", err)
+	}
+	if _, err := cb.ConfirmTransaction(ctx, tx, acc); err != nil {
+		return fmt.Errorf("confirming anchoring: %w", err)
+	}
+	return nil
+}
+
+// CheckAnchor returns the time at which credential `id` was anchored by
+// `issuer`. The time is zero if the credential was not anchored.
+func (c *Client) CheckAnchor(ctx context.Context, issuer common.Address, id pkgapp.Hash) (time.Time, error) {
+	if c.anchor == nil {
+		return time.Time{}, ErrNoAnchorContract
+	}
+
+	t, err := c.anchor.AnchoredAt(&bind.CallOpts{Context: ctx}, issuer, id)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("querying anchor contract: %w", err)
+	}
+	if t.Sign() == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(t.Int64(), 0), nil
+}
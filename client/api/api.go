@@ -0,0 +1,83 @@
+// Package api defines interfaces of the client and its channels, so that
+// applications built on top can substitute them, e.g., by the in-memory mocks
+// of package mock in unit tests. Wrap adapts a client.Client to them.
+package api
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+type (
+	// Client opens channels to peers and accepts the channels opened by
+	// peers.
+	Client interface {
+		Address() common.Address
+		PerunAddress() wallet.Address
+		// Connect opens a channel to `peer`, in which we deposit `balance`.
+		Connect(ctx context.Context, peer wire.Address, balance channel.Bal) (Channel, error)
+		NextConnectionRequest(ctx context.Context) (ConnectionRequest, error)
+		// Channels returns the open channels in the order in which they were
+		// opened.
+		Channels() []Channel
+		// Shutdown shuts the client down immediately, leaving the channels
+		// open.
+		Shutdown()
+		// GracefulShutdown rejects new channels and requests, waits until the
+		// requests in progress are answered, closes the channels, and then
+		// shuts the client down.
+		GracefulShutdown(ctx context.Context) error
+	}
+
+	// ConnectionRequest is a channel proposed by a peer.
+	ConnectionRequest interface {
+		Peer() wallet.Address
+		// Balance returns the balance that accepting the request deposits.
+		Balance() *big.Int
+		Accept(ctx context.Context) (Channel, error)
+		Reject(ctx context.Context, reason string) error
+	}
+
+	// Channel is a channel in which credentials are traded.
+	Channel interface {
+		ID() channel.ID
+		Idx() channel.Index
+		// Balances returns the current balances by participant index.
+		Balances() []channel.Bal
+		RequestCredential(ctx context.Context, doc []byte, price channel.Bal, issuer common.Address) (AsyncCredential, error)
+		NextCredentialRequest(ctx context.Context) (CredentialRequest, error)
+		// WaitConcludadable waits until the channel is final or a dispute
+		// timed out.
+		WaitConcludadable(ctx context.Context) error
+		Close(ctx context.Context) error
+	}
+
+	// AsyncCredential is a requested credential.
+	AsyncCredential interface {
+		Await(ctx context.Context) (CredentialProposal, error)
+	}
+
+	// CredentialProposal is an issued credential, which is paid by accepting
+	// it.
+	CredentialProposal interface {
+		Signature() []byte
+		Accept(ctx context.Context) error
+		Reject(ctx context.Context, reason string) error
+	}
+
+	// CredentialRequest is a credential requested by the peer.
+	CredentialRequest interface {
+		Offer() *data.Offer
+		CheckDoc(doc []byte) error
+		CheckPrice(p *big.Int) error
+		IssueCredential(ctx context.Context, acc app.Account) error
+		Reject(ctx context.Context, reason string) error
+	}
+)
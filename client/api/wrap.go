@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire"
+)
+
+type (
+	clientAdapter struct {
+		*client.Client
+	}
+
+	connectionRequestAdapter struct {
+		*connection.ConnectionRequest
+	}
+
+	channelAdapter struct {
+		*connection.Connection
+	}
+
+	asyncCredentialAdapter struct {
+		*connection.AsyncCredential
+	}
+
+	credentialProposalAdapter struct {
+		*connection.CredentialProposal
+	}
+)
+
+// Wrap returns `c` as a Client.
+func Wrap(c *client.Client) Client {
+	return &clientAdapter{c}
+}
+
+// WrapConnection returns `conn` as a Channel.
+func WrapConnection(conn *connection.Connection) Channel {
+	return &channelAdapter{conn}
+}
+
+func (c *clientAdapter) Connect(ctx context.Context, peer wire.Address, balance channel.Bal) (Channel, error) {
+	conn, err := c.Client.Connect(ctx, peer, balance)
+	if err != nil {
+		return nil, err
+	}
+	return WrapConnection(conn), nil
+}
+
+func (c *clientAdapter) Channels() []Channel {
+	conns := c.Client.Channels()
+	chs := make([]Channel, len(conns))
+	for i, conn := range conns {
+		chs[i] = WrapConnection(conn)
+	}
+	return chs
+}
+
+func (c *clientAdapter) NextConnectionRequest(ctx context.Context) (ConnectionRequest, error) {
+	req, err := c.Client.NextConnectionRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &connectionRequestAdapter{req}, nil
+}
+
+func (r *connectionRequestAdapter) Accept(ctx context.Context) (Channel, error) {
+	conn, err := r.ConnectionRequest.Accept(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return WrapConnection(conn), nil
+}
+
+func (c *channelAdapter) Balances() []channel.Bal {
+	return c.State().Balances[app.AssetIdx]
+}
+
+func (c *channelAdapter) RequestCredential(ctx context.Context, doc []byte, price channel.Bal, issuer common.Address) (AsyncCredential, error) {
+	asyncCred, err := c.Connection.RequestCredential(ctx, doc, price, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return &asyncCredentialAdapter{asyncCred}, nil
+}
+
+func (c *channelAdapter) NextCredentialRequest(ctx context.Context) (CredentialRequest, error) {
+	req, err := c.Connection.NextCredentialRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (c *asyncCredentialAdapter) Await(ctx context.Context) (CredentialProposal, error) {
+	prop, err := c.AsyncCredential.Await(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &credentialProposalAdapter{prop}, nil
+}
+
+func (p *credentialProposalAdapter) Signature() []byte {
+	return p.CredentialProposal.Signature
+}
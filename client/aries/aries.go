@@ -0,0 +1,155 @@
+// Package aries bridges the Aries issue-credential protocol (version 2.0) to
+// credential swaps in Perun channels. Prices and issuer addresses are carried
+// in the `~payment` decorator; documents and signatures are attached in the
+// formats FormatDocument and FormatSignature.
+package aries
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/pkg/didcomm"
+)
+
+// Message types of the issue-credential protocol.
+const (
+	TypeRequestCredential = "https://didcomm.org/issue-credential/2.0/request-credential"
+	TypeIssueCredential   = "https://didcomm.org/issue-credential/2.0/issue-credential"
+	TypeProblemReport     = "https://didcomm.org/issue-credential/2.0/problem-report"
+)
+
+// Attachment formats.
+const (
+	// FormatDocument is the format of the requested document.
+	FormatDocument = "perun/document@v1.0"
+	// FormatSignature is the format of the issuer's signature on the
+	// document.
+	FormatSignature = "perun/signature@v1.0"
+)
+
+// Message is an issue-credential message.
+type Message struct {
+	Type        string       `json:"@type"`
+	ID          string       `json:"@id"`
+	Thread      *Thread      `json:"~thread,omitempty"`
+	Payment     *Payment     `json:"~payment,omitempty"`
+	Formats     []Format     `json:"formats,omitempty"`
+	Requests    []Attachment `json:"requests~attach,omitempty"`
+	Credentials []Attachment `json:"credentials~attach,omitempty"`
+	Description *Description `json:"description,omitempty"`
+}
+
+// Thread identifies the thread of a message.
+type Thread struct {
+	ThID string `json:"thid"`
+}
+
+// Payment is the price of the credential and the address of the issuer.
+type Payment struct {
+	Price  string         `json:"price"` // Decimal amount in Wei.
+	Issuer common.Address `json:"issuer"`
+}
+
+// Format declares the format of an attachment.
+type Format struct {
+	AttachID string `json:"attach_id"`
+	Format   string `json:"format"`
+}
+
+// Attachment is an attachment with inline data.
+type Attachment struct {
+	ID       string         `json:"@id"`
+	MimeType string         `json:"mime-type,omitempty"`
+	Data     AttachmentData `json:"data"`
+}
+
+// AttachmentData holds the data of an attachment.
+type AttachmentData struct {
+	Base64 string `json:"base64"`
+}
+
+// Description describes a problem.
+type Description struct {
+	Code string `json:"code"`
+	En   string `json:"en,omitempty"`
+}
+
+// threadID returns the ID of the thread of the message.
+func (m *Message) threadID() string {
+	if m.Thread != nil && m.Thread.ThID != "" {
+		return m.Thread.ThID
+	}
+	return m.ID
+}
+
+// price returns the price of the payment decorator.
+func (m *Message) price() (*big.Int, error) {
+	if m.Payment == nil {
+		return nil, fmt.Errorf("missing payment decorator")
+	}
+	p, ok := new(big.Int).SetString(m.Payment.Price, 10)
+	if !ok || p.Sign() < 0 {
+		return nil, fmt.Errorf("invalid price: %s", m.Payment.Price)
+	}
+	return p, nil
+}
+
+// attachment returns the data of the attachment of format `format`.
+func (m *Message) attachment(format string, attachments []Attachment) ([]byte, error) {
+	for _, f := range m.Formats {
+		if f.Format != format {
+			continue
+		}
+		for _, a := range attachments {
+			if a.ID == f.AttachID {
+				return base64.StdEncoding.DecodeString(a.Data.Base64)
+			}
+		}
+	}
+	return nil, fmt.Errorf("missing attachment: %s", format)
+}
+
+// newMessage creates a message of type `typ` in thread `thid` that carries
+// `data` in format `format`.
+func newMessage(typ, thid, format string, data []byte) *Message {
+	id := didcomm.NewID()
+	att := []Attachment{{
+		ID:       id,
+		MimeType: "application/octet-stream",
+		Data:     AttachmentData{Base64: base64.StdEncoding.EncodeToString(data)},
+	}}
+	m := &Message{
+		Type:    typ,
+		ID:      didcomm.NewID(),
+		Thread:  &Thread{ThID: thid},
+		Formats: []Format{{AttachID: id, Format: format}},
+	}
+	if typ == TypeRequestCredential {
+		m.Requests = att
+	} else {
+		m.Credentials = att
+	}
+	return m
+}
+
+// problemReport creates a problem report in thread `thid`.
+func problemReport(thid string, err error) *Message {
+	return &Message{
+		Type:        TypeProblemReport,
+		ID:          didcomm.NewID(),
+		Thread:      &Thread{ThID: thid},
+		Description: &Description{Code: "issuance-abandoned", En: err.Error()},
+	}
+}
+
+// Decode decodes an issue-credential message.
+func Decode(b []byte) (*Message, error) {
+	var m Message
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("decoding message: %w", err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,42 @@
+package aries
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage(t *testing.T) {
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	m := newMessage(TypeRequestCredential, "thread", FormatDocument, doc)
+	m.Payment = &Payment{Price: "100", Issuer: common.Address{1}}
+	enc, err := json.Marshal(m)
+	require.NoError(t, err)
+	dec, err := Decode(enc)
+	require.NoError(t, err)
+	require.Equal(t, m, dec)
+
+	got, err := dec.attachment(FormatDocument, dec.Requests)
+	require.NoError(t, err)
+	require.Equal(t, doc, got)
+	_, err = dec.attachment(FormatSignature, dec.Requests)
+	require.Error(t, err, "missing format")
+	require.Equal(t, "thread", dec.threadID())
+	price, err := dec.price()
+	require.NoError(t, err)
+	require.Equal(t, int64(100), price.Int64())
+
+	for _, p := range []string{"", "-1", "1.5", "0x10"} {
+		dec.Payment.Price = p
+		_, err := dec.price()
+		require.Error(t, err, "price %q", p)
+	}
+	dec.Payment = nil
+	_, err = dec.price()
+	require.Error(t, err, "missing payment")
+
+	// Messages without thread start a thread.
+	require.Equal(t, "id", (&Message{ID: "id"}).threadID())
+}
@@ -0,0 +1,64 @@
+package aries
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/perun-network/perun-credential-payment/client/connection"
+)
+
+// SendFunc delivers a message to the Aries agent.
+type SendFunc func(ctx context.Context, m *Message) error
+
+// HolderBridge lets an Aries holder agent buy credentials over a connection.
+// Credential requests of the agent are sent as channel requests, and the
+// issued signatures are returned as issue-credential messages.
+type HolderBridge struct {
+	conn *connection.Connection
+	send SendFunc
+}
+
+func NewHolderBridge(conn *connection.Connection, send SendFunc) *HolderBridge {
+	return &HolderBridge{conn: conn, send: send}
+}
+
+// HandleRequest requests the credential described by request-credential
+// message `m`, pays for it, and sends the issued signature to the agent. If
+// the request fails, a problem report is sent instead.
+func (b *HolderBridge) HandleRequest(ctx context.Context, m *Message) error {
+	if m.Type != TypeRequestCredential {
+		return fmt.Errorf("unexpected message type: %s", m.Type)
+	}
+	sig, err := b.request(ctx, m)
+	if err != nil {
+		if sendErr := b.send(ctx, problemReport(m.threadID(), err)); sendErr != nil {
+			return fmt.Errorf("sending problem report: %w", sendErr)
+		}
+		return err
+	}
+	return b.send(ctx, newMessage(TypeIssueCredential, m.threadID(), FormatSignature, sig))
+}
+
+func (b *HolderBridge) request(ctx context.Context, m *Message) ([]byte, error) {
+	doc, err := m.attachment(FormatDocument, m.Requests)
+	if err != nil {
+		return nil, err
+	}
+	price, err := m.price()
+	if err != nil {
+		return nil, err
+	}
+
+	async, err := b.conn.RequestCredential(ctx, doc, price, m.Payment.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("requesting credential: %w", err)
+	}
+	prop, err := async.Await(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("awaiting credential: %w", err)
+	}
+	if err := prop.Accept(ctx); err != nil {
+		return nil, fmt.Errorf("accepting credential: %w", err)
+	}
+	return prop.Signature, nil
+}
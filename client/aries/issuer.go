@@ -0,0 +1,126 @@
+package aries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+)
+
+var ErrRejected = errors.New("rejected by issuer agent")
+
+// IssuerBridge lets an Aries issuer agent decide on the credential requests
+// of a connection. Each request is forwarded to the agent as
+// request-credential message. If the agent responds with issue-credential,
+// the credential is issued in the channel, and if it responds with a problem
+// report, the request is rejected.
+type IssuerBridge struct {
+	conn *connection.Connection
+	acc  app.Account
+	send SendFunc
+	log  log.Logger
+
+	mu      sync.Mutex
+	pending map[string]chan *Message
+}
+
+// NewIssuerBridge creates a bridge issuing with account `acc`. Failures to
+// issue are logged to `log`.
+func NewIssuerBridge(conn *connection.Connection, acc app.Account, send SendFunc, log log.Logger) *IssuerBridge {
+	return &IssuerBridge{
+		conn:    conn,
+		acc:     acc,
+		send:    send,
+		log:     log,
+		pending: make(map[string]chan *Message),
+	}
+}
+
+// Serve forwards credential requests to the agent until `ctx` is done.
+func (b *IssuerBridge) Serve(ctx context.Context) error {
+	for {
+		r, err := b.conn.NextCredentialRequest(ctx)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := b.forward(ctx, r); err != nil {
+				b.log.Warnf("Forwarding credential request: %v", err)
+			}
+		}()
+	}
+}
+
+// HandleResponse handles a response of the agent to a forwarded request.
+func (b *IssuerBridge) HandleResponse(m *Message) error {
+	b.mu.Lock()
+	ch, ok := b.pending[m.threadID()]
+	delete(b.pending, m.threadID())
+	b.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown thread: %s", m.threadID())
+	}
+	ch <- m
+	return nil
+}
+
+// forward forwards request `r` to the agent and issues the credential if the
+// agent approves it. Otherwise, the request is rejected.
+func (b *IssuerBridge) forward(ctx context.Context, r *connection.CredentialRequest) error {
+	resp, err := b.ask(ctx, r)
+	if err != nil {
+		return r.Reject(ctx, err.Error())
+	}
+
+	switch resp.Type {
+	case TypeIssueCredential:
+		return r.IssueCredential(ctx, b.acc)
+	case TypeProblemReport:
+		reason := ErrRejected.Error()
+		if resp.Description != nil && resp.Description.En != "" {
+			reason = fmt.Sprintf("%s: %s", reason, resp.Description.En)
+		}
+		return r.RejectWithCode(ctx, connection.RejectPolicyDenied, reason)
+	default:
+		return r.Reject(ctx, fmt.Sprintf("unexpected message type: %s", resp.Type))
+	}
+}
+
+// ask sends request `r` to the agent and waits for the response.
+func (b *IssuerBridge) ask(ctx context.Context, r *connection.CredentialRequest) (*Message, error) {
+	doc, err := r.Document()
+	if err != nil {
+		return nil, err
+	} else if err := r.CheckDoc(doc); err != nil {
+		return nil, err
+	}
+
+	offer := r.Offer()
+	req := newMessage(TypeRequestCredential, "", FormatDocument, doc)
+	req.Thread = nil
+	req.Payment = &Payment{Price: offer.Price.String(), Issuer: offer.Issuer}
+
+	resp := make(chan *Message, 1)
+	b.mu.Lock()
+	b.pending[req.ID] = resp
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, req.ID)
+		b.mu.Unlock()
+	}()
+
+	if err := b.send(ctx, req); err != nil {
+		return nil, fmt.Errorf("forwarding request: %w", err)
+	}
+	select {
+	case m := <-resp:
+		return m, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
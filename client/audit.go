@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	pkgapp "github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/pkg/audit"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wire"
+)
+
+// auditor records channel operations in the audit log. A nil auditor
+// records nothing.
+type auditor struct {
+	log    *audit.Log
+	logger log.Logger
+}
+
+// record appends an entry to the audit log. Failures are logged.
+func (a *auditor) record(kind, subject string, details interface{}) {
+	if a == nil {
+		return
+	}
+	if err := a.log.Append(kind, subject, details); err != nil {
+		a.logger.Warnf("Recording %s in audit log: %v", kind, err)
+	}
+}
+
+// recordReceipt records a mined transaction of the client.
+func (a *auditor) recordReceipt(r *types.Receipt) {
+	a.record("transaction", r.TxHash.Hex(), struct {
+		Block   *big.Int `json:"block"`
+		Status  uint64   `json:"status"`
+		GasUsed uint64   `json:"gasUsed"`
+	}{r.BlockNumber, r.Status, r.GasUsed})
+}
+
+// AuditLog returns the audit log of the client, or nil if auditing is
+// disabled.
+func (c *Client) AuditLog() *audit.Log {
+	if c.audit == nil {
+		return nil
+	}
+	return c.audit.log
+}
+
+func channelSubject(id channel.ID) string {
+	return hexutil.Encode(id[:])
+}
+
+// proposalDetails are the details of audited channel proposals.
+type proposalDetails struct {
+	Peer              string     `json:"peer"`
+	App               string     `json:"app,omitempty"`
+	ChallengeDuration uint64     `json:"challengeDuration"`
+	Balances          []*big.Int `json:"balances"`
+}
+
+func newProposalDetails(peer wire.Address, p *client.LedgerChannelProposal) proposalDetails {
+	d := proposalDetails{
+		Peer:              peer.String(),
+		ChallengeDuration: p.ChallengeDuration,
+	}
+	if !channel.IsNoApp(p.App) {
+		d.App = p.App.Def().String()
+	}
+	if len(p.InitBals.Balances) > pkgapp.AssetIdx {
+		d.Balances = p.InitBals.Balances[pkgapp.AssetIdx]
+	}
+	return d
+}
+
+// stateDetails are the details of audited channel states.
+type stateDetails struct {
+	Version  uint64     `json:"version"`
+	Data     string     `json:"data"`
+	Balances []*big.Int `json:"balances"`
+	IsFinal  bool       `json:"isFinal"`
+	Phase    string     `json:"phase,omitempty"`
+}
+
+func newStateDetails(s *channel.State) stateDetails {
+	var bals []*big.Int
+	if len(s.Balances) > pkgapp.AssetIdx {
+		bals = s.Balances[pkgapp.AssetIdx]
+	}
+	return stateDetails{
+		Version:  s.Version,
+		Data:     fmt.Sprintf("%T", s.Data),
+		Balances: bals,
+		IsFinal:  s.IsFinal,
+	}
+}
+
+// auditPersister records the states of the channels in the audit log and
+// forwards them to the embedded PersistRestorer. A state that cannot be
+// recorded fails the channel operation.
+type auditPersister struct {
+	persistence.PersistRestorer
+	log *audit.Log
+}
+
+func (p *auditPersister) recordState(kind string, s channel.Source) error {
+	tx := s.CurrentTX()
+	if kind == "update_staged" {
+		tx = s.StagingTX()
+	}
+	if tx.State == nil {
+		return nil
+	}
+	d := newStateDetails(tx.State)
+	d.Phase = s.Phase().String()
+	if err := p.log.Append(kind, channelSubject(s.ID()), d); err != nil {
+		return fmt.Errorf("recording %s in audit log: %w", kind, err)
+	}
+	return nil
+}
+
+func (p *auditPersister) ChannelCreated(ctx context.Context, s channel.Source, peers []wire.Address, parent *channel.ID) error {
+	if err := p.recordState("channel_created", s); err != nil {
+		return err
+	}
+	return p.PersistRestorer.ChannelCreated(ctx, s, peers, parent)
+}
+
+func (p *auditPersister) Staged(ctx context.Context, s channel.Source) error {
+	if err := p.recordState("update_staged", s); err != nil {
+		return err
+	}
+	return p.PersistRestorer.Staged(ctx, s)
+}
+
+func (p *auditPersister) Enabled(ctx context.Context, s channel.Source) error {
+	if err := p.recordState("update_enabled", s); err != nil {
+		return err
+	}
+	return p.PersistRestorer.Enabled(ctx, s)
+}
+
+func (p *auditPersister) PhaseChanged(ctx context.Context, s channel.Source) error {
+	if err := p.recordState("phase_changed", s); err != nil {
+		return err
+	}
+	return p.PersistRestorer.PhaseChanged(ctx, s)
+}
+
+func (p *auditPersister) ChannelRemoved(ctx context.Context, id channel.ID) error {
+	if err := p.log.Append("channel_removed", channelSubject(id), nil); err != nil {
+		return fmt.Errorf("recording channel_removed in audit log: %w", err)
+	}
+	return p.PersistRestorer.ChannelRemoved(ctx, id)
+}
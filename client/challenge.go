@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/client/access"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// Defaults of ClientConfig.ProposalTimeout.
+const (
+	// rejectTimeout bounds rejecting a channel proposal automatically.
+	rejectTimeout = 30 * time.Second
+	// accessTimeout bounds checking the access of a proposing peer.
+	accessTimeout = 10 * time.Second
+)
+
+// challengeBounds are the challenge durations accepted in channels proposed
+// by peers. A zero bound is not enforced.
+type challengeBounds struct {
+	min, max time.Duration
+}
+
+// check checks the challenge duration `seconds` against the bounds.
+func (b challengeBounds) check(seconds uint64) error {
+	d := time.Duration(seconds) * time.Second
+	if b.min != 0 && d < b.min {
+		return fmt.Errorf("challenge duration %v below minimum %v", d, b.min)
+	} else if b.max != 0 && d > b.max {
+		return fmt.Errorf("challenge duration %v above maximum %v", d, b.max)
+	}
+	return nil
+}
+
+// ConnectWithChallengeDuration opens a channel like ConnectWithPeerBalance,
+// but with challenge duration `d` instead of the configured one, e.g., a
+// longer one for a long-lived channel holding high balances. The peer
+// rejects the channel if `d` is outside of its configured bounds.
+func (c *Client) ConnectWithChallengeDuration(ctx context.Context, peer wire.Address, balance, peerBalance channel.Bal, d time.Duration) (*connection.Connection, error) {
+	return c.connect(ctx, c.appAddress, peer, "", balance, peerBalance, d)
+}
+
+// rejectProposal rejects channel proposal `req` with `code` and `reason`.
+func (c *Client) rejectProposal(req *connection.ConnectionRequest, code connection.RejectionCode, reason string) {
+	c.log.WithField("peer", req.Peer()).Warnf("Rejecting channel proposal: %s", reason)
+	ctx, cancel := c.proposalContext(rejectTimeout)
+	defer cancel()
+	if err := req.RejectWithCode(ctx, code, reason); err != nil {
+		c.log.Warnf("Failed to reject channel proposal: %v", err)
+	}
+}
+
+// proposalContext returns a context for handling a channel proposal
+// automatically, bounded by the configured proposal timeout or else by
+// `def`.
+func (c *Client) proposalContext(def time.Duration) (context.Context, context.CancelFunc) {
+	if c.proposalTimeout != 0 {
+		def = c.proposalTimeout
+	}
+	return context.WithTimeout(context.Background(), def)
+}
+
+// checkAccess checks whether `peer` may open a channel.
+func (c *Client) checkAccess(peer wallet.Address) error {
+	if c.access == nil {
+		return nil
+	}
+	ctx, cancel := c.proposalContext(accessTimeout)
+	defer cancel()
+	if err := c.access.Check(ctx, ethwallet.AsEthAddr(peer)); err != nil {
+		if !errors.Is(err, access.ErrDenied) {
+			// Failed lookups deny access, as funds are at stake.
+			return &access.DeniedError{Peer: ethwallet.AsEthAddr(peer), Reason: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChallengeBounds(t *testing.T) {
+	b := challengeBounds{min: time.Minute, max: time.Hour}
+	require.NoError(t, b.check(60))
+	require.NoError(t, b.check(3600))
+	require.Error(t, b.check(59), "below minimum")
+	require.Error(t, b.check(3601), "above maximum")
+
+	require.NoError(t, challengeBounds{}.check(0), "no bounds")
+	require.NoError(t, challengeBounds{max: time.Hour}.check(1), "no minimum")
+	require.NoError(t, challengeBounds{min: time.Minute}.check(1<<32), "no maximum")
+}
+
+func TestProposalContext(t *testing.T) {
+	deadline := func(c *Client) time.Duration {
+		ctx, cancel := c.proposalContext(time.Minute)
+		defer cancel()
+		d, ok := ctx.Deadline()
+		require.True(t, ok)
+		return time.Until(d)
+	}
+	require.InDelta(t, time.Minute, deadline(&Client{}), float64(time.Second), "default")
+	require.InDelta(t, time.Hour, deadline(&Client{proposalTimeout: time.Hour}), float64(time.Second), "configured")
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"perun.network/go-perun/wire"
+)
+
+// Channels returns the open channels of the client in the order in which they
+// were opened. A client may hold any number of channels, to the same or to
+// different peers, and the requests in each channel are independent of the
+// others.
+func (c *Client) Channels() []*connection.Connection {
+	var open []*connection.Connection
+	for _, conn := range c.connections.All() {
+		if !conn.Inspect().Concluded {
+			open = append(open, conn)
+		}
+	}
+	return open
+}
+
+// ChannelsWith returns the open channels of the client to `peer`.
+func (c *Client) ChannelsWith(peer wire.Address) []*connection.Connection {
+	var with []*connection.Connection
+	for _, conn := range c.Channels() {
+		if conn.Peer().Equals(peer) {
+			with = append(with, conn)
+		}
+	}
+	return with
+}
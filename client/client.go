@@ -4,26 +4,92 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"net"
+	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	pkgapp "github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/anchor"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/app/keys"
+	"github.com/perun-network/perun-credential-payment/app/oracle"
+	"github.com/perun-network/perun-credential-payment/app/revocation"
+	"github.com/perun-network/perun-credential-payment/client/access"
 	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/client/message"
 	"github.com/perun-network/perun-credential-payment/client/perun"
+	"github.com/perun-network/perun-credential-payment/client/store"
+	"github.com/perun-network/perun-credential-payment/deploy"
+	patomic "github.com/perun-network/perun-credential-payment/pkg/atomic"
+	"github.com/perun-network/perun-credential-payment/pkg/audit"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"github.com/perun-network/perun-credential-payment/pkg/metrics"
+	"github.com/perun-network/perun-credential-payment/pkg/ratelimit"
+	"github.com/perun-network/perun-credential-payment/pkg/supervisor"
+	"github.com/perun-network/perun-credential-payment/pkg/trace"
+	"github.com/perun-network/perun-credential-payment/pkg/webhook"
 	"github.com/pkg/errors"
+	"perun.network/go-perun/backend/ethereum/bindings/adjudicator"
 	"perun.network/go-perun/backend/ethereum/bindings/assetholdereth"
-	ethchannel "perun.network/go-perun/backend/ethereum/channel"
 	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
-	"perun.network/go-perun/backend/ethereum/wallet/simple"
 	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
 	"perun.network/go-perun/client"
 	"perun.network/go-perun/wire"
 )
 
 type ClientConfig struct {
 	perun.ClientConfig
-	ChallengeDuration time.Duration
-	AppAddress        common.Address
+	ChallengeDuration    time.Duration
+	AppAddress           common.Address
+	Logger               log.Logger                  // Optional. Defaults to the standard logger.
+	Metrics              *metrics.Registry           // Optional. Enables metrics collection.
+	HTTPAddress          string                      // Optional. Serves /metrics, /healthz and /readyz at this address.
+	Tracer               trace.Tracer                // Optional. Enables tracing.
+	Webhooks             []string                    // Optional. URLs notified about channel and credential events.
+	WebhookSecret        []byte                      // Optional. Signs webhook requests with HMAC-SHA256, see webhook.Verify.
+	Quoter               connection.Quoter           // Optional. Enables answering quote requests.
+	DescriptorMapper     connection.DescriptorMapper // Optional. Enables answering quote requests by input descriptor.
+	ContentStore         connection.ContentStore     // Optional. Large documents are transferred via the store, e.g., an ipfs.Client.
+	RevocationRegistry   common.Address              // Optional. Enables Revoke and CheckRevocation.
+	DIDResolver          did.Resolver                // Optional. Defaults to resolving did:ethr, did:key, and did:web.
+	DIDComm              bool                        // Optional. Serves DIDComm requests at /didcomm on HTTPAddress.
+	RequireHolderBinding bool                        // Optional. Rejects requests for credentials not bound to the requester's DID.
+	ProofOfPossession    bool                        // Optional. Challenges requesters to prove possession of the bound key before issuing.
+	FeeRecipient         wire.Address                // Optional. Joins opened channels as third participant and receives a fee on every issuance.
+	FeeRate              uint16                      // Optional. The fee in basis points of the price.
+	PriceFeed            common.Address              // Optional. A Chainlink-style ETH/USD feed. Enables pricing in USD.
+	PriceFeedMaxAge      time.Duration               // Optional. Rejects older rates. Defaults to oracle.DefaultMaxAge.
+	PriceTolerance       uint16                      // Optional. The accepted deviation from the oracle rate in basis points.
+	AnchorContract       common.Address              // Optional. Anchors the IDs of issued credentials when channels are settled.
+	RecordEvidence       bool                        // Optional. Records the evidence exported by Connection.ExportDisputeEvidence.
+	IssuerChain          []*pkgapp.IssuerCertificate // Optional. Certifies the client as issuer. Served to holders on request.
+	CredentialSigner     pkgapp.CredentialSigner     // Optional. Additionally signs issued credentials with a key of a signature suite, e.g., pkgapp.Ed25519Signer. Served to holders on request.
+	ContractSignatures   bool                        // Optional. Accepts EIP-1271 signatures of contract issuers, e.g., Gnosis Safes.
+	IssuerKeyRegistry    common.Address              // Optional. Enables PublishIssuerKey and ResolveIssuerKey.
+	MinChallengeDuration time.Duration               // Optional. Rejects proposed channels with a shorter challenge duration.
+	MaxChallengeDuration time.Duration               // Optional. Rejects proposed channels with a longer challenge duration.
+	FundingTimeout       time.Duration               // Optional. Bounds opening channels. Deposits of channels not funded in time are reclaimed.
+	ReorgWindow          time.Duration               // Optional. Checks observed adjudicator events against the chain for this long and re-evaluates channels whose events were dropped by a reorg.
+	Store                *store.Store                // Optional. Persists channel states, pending requests, and issued credentials, e.g., in store.OpenLevelDB. Not closed by Shutdown.
+	Accounting           bool                        // Optional. Records deposits and payments for ExportChannels and ExportPayments.
+	AuditLog             *audit.Log                  // Optional. Records proposals, updates, responses, and transactions in a hash-chained log. Not closed by Shutdown.
+	Endpoint             string                      // Optional. The public host and port of the client, advertised in invoices. Defaults to Host.
+	Tenants              []TenantConfig              // Optional. Hosts logical issuers, to which proposals naming them are routed.
+	ProposalLimits       ratelimit.Limits            // Optional. Rejects channel proposals exceeding the rates per peer or in total.
+	RequestLimits        ratelimit.Limits            // Optional. Rejects credential requests exceeding the rates per peer or in total.
+	AccessControl        access.Control              // Optional. Rejects channel proposals of denied peers, e.g., by access.Allowlist.
+	IssuerKey            connection.Signer           // Optional. Signs credentials in IssueCredential until rotated, e.g., a threshold-ECDSA signer. Defaults to the client's account.
+	ProposalTimeout      time.Duration               // Optional. Bounds handling channel proposals automatically, i.e., checking access and rejecting them. Defaults to 10s for access checks and 30s for rejections.
+	ResponseTimeout      time.Duration               // Optional. Bounds responding to channel updates of peers automatically. Defaults to connection.DefaultResponseTimeout.
+	IdleTimeout          time.Duration               // Optional. Closes channels without state updates for this long, force-closing them if the peer does not respond. Disabled if zero.
 }
 
 type PaymentAcceptancePolicy = func(
@@ -42,22 +108,165 @@ type Client struct {
 	appAddress        common.Address
 	channelProposals  chan *connection.ChannelProposal
 	connections       *connection.Registry
+	log               log.Logger
+	metrics           *metrics.Registry
+	connCfg           *connection.Config
+	httpServer        *http.Server
+	tracer            trace.Tracer
+	listening         *patomic.Bool
+	pendingProposals  int32
+	webhooks          *webhook.Notifier
+	events            *eventSubs
+	revocations       *revocation.Registry
+	anchor            *anchor.Anchor
+	didComm           *message.DIDComm
+	feeRecipient      wire.Address
+	contractSigs      *pkgapp.ContractSigVerifier
+	issuerKeys        *keys.Registry
+	keyPins           keyPins
+	signingKeys       signingKeys
+	migrations        migrations
+	challengeBounds   challengeBounds
+	supervisor        *supervisor.Supervisor
+	audit             *auditor
+	ledger            *connection.Ledger
+	endpoint          string
+	tenants           map[string]*Tenant
+	proposalLimiter   *ratelimit.Limiter
+	access            access.Control
+	shuttingDown      *patomic.Bool
+	proposalTimeout   time.Duration
 }
 
 func StartClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.NewStdLogger(nil)
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.System()
+		cfg.Clock = clk
+	}
+	tenants, err := newTenants(cfg.Tenants)
+	if err != nil {
+		return nil, fmt.Errorf("configuring tenants: %w", err)
+	}
+	observeReceipts := func(observe func(*types.Receipt)) {
+		onReceipt := cfg.OnReceipt
+		cfg.OnReceipt = func(r *types.Receipt) {
+			if onReceipt != nil {
+				onReceipt(r)
+			}
+			observe(r)
+		}
+	}
+
+	if cfg.Metrics == nil && cfg.HTTPAddress != "" {
+		cfg.Metrics = metrics.NewRegistry()
+	}
+	var m *connection.Metrics
+	if cfg.Metrics != nil {
+		m = connection.NewMetrics(cfg.Metrics)
+		observeReceipts(func(r *types.Receipt) {
+			m.ObserveGas(r.GasUsed)
+		})
+	}
+	var pr persistence.PersistRestorer
+	if cfg.Store != nil {
+		pr = cfg.Store
+	}
+	var evidence *connection.EvidenceRecorder
+	if cfg.RecordEvidence {
+		evidence = connection.NewEvidenceRecorder(pr, clk)
+		pr = evidence
+		observeReceipts(evidence.ObserveReceipt)
+	}
+	var ledger *connection.Ledger
+	if cfg.Accounting {
+		ledger = connection.NewLedger(pr, clk)
+		pr = ledger
+		observeReceipts(ledger.ObserveReceipt)
+	}
+	var aud *auditor
+	if cfg.AuditLog != nil {
+		if pr == nil {
+			pr = persistence.NonPersistRestorer
+		}
+		aud = &auditor{log: cfg.AuditLog, logger: logger}
+		pr = &auditPersister{PersistRestorer: pr, log: cfg.AuditLog}
+		observeReceipts(aud.recordReceipt)
+	}
+
 	perunClient, err := perun.SetupClient(ctx, cfg.ClientConfig)
 	if err != nil {
 		return nil, errors.WithMessage(err, "creating perun client")
 	}
+	if pr != nil {
+		perunClient.PerunClient.EnablePersistence(pr)
+	}
 
-	if err := ethchannel.ValidateAssetHolderETH(ctx, perunClient.ContractBackend, cfg.AssetHolder, cfg.Adjudicator); err != nil {
-		return nil, fmt.Errorf("validating adjudicator: %w", err)
+	contracts := deploy.ContractAddresses{Adjudicator: cfg.Adjudicator, AssetHolder: cfg.AssetHolder, App: cfg.AppAddress}
+	if err := deploy.Validate(ctx, perunClient.ContractBackend, contracts); err != nil {
+		return nil, fmt.Errorf("validating contracts: %w", err)
 	}
 	ah, err := assetholdereth.NewAssetHolderETH(cfg.AssetHolder, perunClient.ContractBackend)
 	if err != nil {
 		return nil, errors.WithMessage(err, "loading asset holder")
 	}
 
+	var revocations *revocation.Registry
+	if cfg.RevocationRegistry != (common.Address{}) {
+		revocations, err = revocation.NewRegistry(cfg.RevocationRegistry, perunClient.ContractBackend)
+		if err != nil {
+			return nil, fmt.Errorf("loading revocation registry: %w", err)
+		}
+	}
+
+	var anc *anchor.Anchor
+	if cfg.AnchorContract != (common.Address{}) {
+		anc, err = anchor.NewAnchor(cfg.AnchorContract, perunClient.ContractBackend)
+		if err != nil {
+			return nil, fmt.Errorf("loading anchor contract: %w", err)
+		}
+	}
+
+	var priceOracle connection.PriceOracle
+	if cfg.PriceFeed != (common.Address{}) {
+		feed, err := oracle.NewFeed(cfg.PriceFeed, perunClient.ContractBackend)
+		if err != nil {
+			return nil, fmt.Errorf("loading price feed: %w", err)
+		}
+		priceOracle = &oracle.Converter{Feed: feed, TokenDecimals: 18, MaxAge: cfg.PriceFeedMaxAge, Clock: clk}
+	}
+
+	var issuerKeys *keys.Registry
+	if cfg.IssuerKeyRegistry != (common.Address{}) {
+		issuerKeys, err = keys.NewRegistry(cfg.IssuerKeyRegistry, perunClient.ContractBackend)
+		if err != nil {
+			return nil, fmt.Errorf("loading issuer key registry: %w", err)
+		}
+	}
+
+	var disputes connection.DisputeReader
+	if cfg.ReorgWindow > 0 {
+		caller, err := adjudicator.NewAdjudicatorCaller(cfg.Adjudicator, perunClient.ContractBackend)
+		if err != nil {
+			return nil, fmt.Errorf("loading adjudicator: %w", err)
+		}
+		disputes = &adjudicatorDisputes{caller}
+	}
+
+	var contractSigs *pkgapp.ContractSigVerifier
+	if cfg.ContractSignatures {
+		contractSigs, err = pkgapp.NewContractSigVerifier(perunClient.ContractBackend)
+		if err != nil {
+			return nil, fmt.Errorf("creating contract signature verifier: %w", err)
+		}
+		// Channels proposed by peers resolve the app from the registry.
+		pkgapp.Register(ethwallet.AsWalletAddr(cfg.AppAddress), contractSigs)
+	}
+
 	c := &Client{
 		perunClient:       perunClient,
 		assetHolderAddr:   cfg.AssetHolder,
@@ -66,29 +275,179 @@ func StartClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
 		appAddress:        cfg.AppAddress,
 		channelProposals:  make(chan *connection.ChannelProposal),
 		connections:       connection.NewRegistry(),
+		metrics:           cfg.Metrics,
+		listening:         patomic.NewBool(false),
+		events:            newEventSubs(),
+		revocations:       revocations,
+		anchor:            anc,
+		feeRecipient:      cfg.FeeRecipient,
+		contractSigs:      contractSigs,
+		issuerKeys:        issuerKeys,
+		keyPins:           keyPins{pins: make(map[common.Address]common.Address)},
+		signingKeys:       signingKeys{current: cfg.IssuerKey, retired: make(map[common.Address]retiredKey)},
+		migrations:        migrations{m: make(map[string]*migration)},
+		challengeBounds:   challengeBounds{min: cfg.MinChallengeDuration, max: cfg.MaxChallengeDuration},
+		audit:             aud,
+		ledger:            ledger,
+		endpoint:          cfg.Endpoint,
+		tenants:           tenants,
+		access:            cfg.AccessControl,
+		shuttingDown:      patomic.NewBool(false),
+		proposalTimeout:   cfg.ProposalTimeout,
+	}
+	if c.signingKeys.current == nil {
+		c.signingKeys.current = connection.AccountSigner(perunClient.Account)
+	}
+	if !cfg.ProposalLimits.IsZero() {
+		c.proposalLimiter = ratelimit.New(cfg.ProposalLimits, clk)
+	}
+	for _, t := range tenants {
+		t.client = c
+	}
+	if c.endpoint == "" {
+		c.endpoint = cfg.Host
+	}
+	c.log = logger.WithField("client", c.Address())
+	c.tracer = cfg.Tracer
+	if c.tracer == nil {
+		c.tracer = trace.Noop{}
+	}
+	if len(cfg.Webhooks) > 0 {
+		c.webhooks = webhook.NewNotifier(webhook.Config{URLs: cfg.Webhooks, Secret: cfg.WebhookSecret, Clock: clk}, c.log)
+	}
+	c.connCfg = &connection.Config{
+		Log:       c.log,
+		Metrics:   m,
+		Tracer:    c.tracer,
+		Notify:    c.notify,
+		Messenger: perunClient.Messenger,
+		Documents: connection.NewDocumentStore(connection.DefaultDocumentStoreSize),
+		Content:   cfg.ContentStore,
+		Schemas:   connection.NewSchemaRegistry(),
+		Domain:    pkgapp.Domain{ChainID: cfg.ChainID, VerifyingContract: cfg.AppAddress}.Separator(),
+		DIDs:      cfg.DIDResolver,
+
+		RequireHolderBinding: cfg.RequireHolderBinding,
+		ProofOfPossession:    cfg.ProofOfPossession,
+		FeeRate:              cfg.FeeRate,
+		PriceOracle:          priceOracle,
+		PriceTolerance:       cfg.PriceTolerance,
+		Evidence:             evidence,
+		SuiteSigner:          cfg.CredentialSigner,
+		ContractSigs:         contractSigs,
+		FundingTimeout:       cfg.FundingTimeout,
+		ResponseTimeout:      cfg.ResponseTimeout,
+		IdleTimeout:          cfg.IdleTimeout,
+		Disputes:             disputes,
+		ReorgWindow:          cfg.ReorgWindow,
+		Clock:                clk,
+	}
+	if anc != nil {
+		c.connCfg.Anchor = c.anchorCredentials
+	}
+	if !cfg.RequestLimits.IsZero() {
+		c.connCfg.RequestLimiter = ratelimit.New(cfg.RequestLimits, clk)
+	}
+	if c.connCfg.DIDs == nil {
+		c.connCfg.DIDs = did.NewResolver(nil)
+	}
+	c.supervisor = supervisor.New(c.log, c.connCfg.Clock)
+	c.connCfg.Supervisor = c.supervisor
+	c.connCfg.Versions = connection.NewVersions(connection.CurrentVersion())
+	if aud != nil {
+		c.connCfg.Audit = aud.record
+	}
+	// Documents are decrypted with the private key, which a key held in a
+	// hardware security module does not reveal.
+	if cfg.PrivateKey != nil && cfg.Account == nil {
+		connection.HandlePublicKeyRequests(perunClient.Messenger, cfg.PrivateKey)
+		connection.HandleDocuments(perunClient.Messenger, c.connCfg.Documents, c.connections, cfg.PrivateKey, cfg.ContentStore)
+	} else {
+		connection.RejectDocuments(perunClient.Messenger)
+	}
+	connection.HandlePossessionChallenges(perunClient.Messenger, perunClient.Account)
+	connection.HandleReceipts(perunClient.Messenger, c.connections, perunClient.Account)
+	connection.HandleCancellations(perunClient.Messenger, c.connections)
+	connection.HandleIssuerChainRequests(perunClient.Messenger, cfg.IssuerChain)
+	connection.HandleSuiteSignatureRequests(perunClient.Messenger, c.connections)
+	connection.HandleSessionRequests(perunClient.Messenger, cfg.Session)
+	connection.HandleVersionRequests(perunClient.Messenger, c.connCfg.Versions)
+	connection.HandleMigrations(perunClient.Messenger, c.connections, c.acceptMigration)
+	if cfg.Quoter != nil {
+		connection.HandleQuoteRequests(perunClient.Messenger, cfg.Quoter, perunClient.Account, clk)
+	}
+	if cfg.DescriptorMapper != nil {
+		connection.HandleDescriptorQuoteRequests(perunClient.Messenger, cfg.DescriptorMapper, perunClient.Account, clk)
+	}
+
+	if cfg.DIDComm {
+		self := did.Ethr(pkgapp.AccountAddress(perunClient.Account))
+		signer := jws.ES256K{Key: perunClient.Account}
+		c.didComm = message.NewDIDComm(perunClient.Messenger, self, self+"#controller", signer, c.connCfg.DIDs, clk)
+	}
+
+	if cfg.HTTPAddress != "" {
+		if err := c.serveHTTP(cfg.HTTPAddress); err != nil {
+			c.Shutdown()
+			return nil, fmt.Errorf("serving http: %w", err)
+		}
 	}
 
 	h := &handler{Client: c}
 
-	go c.perunClient.PerunClient.Handle(h, h)
-	go c.perunClient.Bus.Listen(c.perunClient.Listener)
+	c.supervisor.Go("handler", supervisor.OnPanic, func() error {
+		c.perunClient.PerunClient.Handle(h, h)
+		return nil
+	})
+	c.listening.SetValue(true)
+	c.supervisor.Go("listener", supervisor.OnPanic, func() error {
+		c.listening.SetValue(true)
+		defer c.listening.SetValue(false)
+		c.perunClient.Bus.Listen(c.perunClient.Listener)
+		return nil
+	})
 
 	return c, nil
 }
 
 func (c *Client) Connect(ctx context.Context, peer wire.Address, balance channel.Bal) (*connection.Connection, error) {
-	app := pkgapp.NewCredentialSwapApp(ethwallet.AsWalletAddr(c.appAddress))
+	return c.ConnectWithPeerBalance(ctx, peer, balance, big.NewInt(0))
+}
+
+// ConnectWithPeerBalance opens a channel in which the peer also deposits
+// `peerBalance`. Both participants can then request credentials from each
+// other, each paying from its own balance.
+func (c *Client) ConnectWithPeerBalance(ctx context.Context, peer wire.Address, balance, peerBalance channel.Bal) (*connection.Connection, error) {
+	return c.connect(ctx, c.appAddress, peer, "", balance, peerBalance, c.challengeDuration)
+}
+
+// connect opens a channel with the app contract at `appAddr` and challenge
+// duration `challengeDuration` for tenant `tenant` of the peer, if non-empty.
+func (c *Client) connect(ctx context.Context, appAddr common.Address, peer wire.Address, tenant string, balance, peerBalance channel.Bal, challengeDuration time.Duration) (_ *connection.Connection, err error) {
+	// Incompatible peers are detected before any funds are deposited.
+	if _, err := connection.Handshake(ctx, c.perunClient.Messenger, peer, c.connCfg.Versions); err != nil {
+		return nil, err
+	}
+
+	app := pkgapp.NewCredentialSwapApp(ethwallet.AsWalletAddr(appAddr))
+	app.ContractSigs = c.contractSigs
 	peers := []wire.Address{c.perunClient.Account.Address(), peer}
-	withApp := client.WithApp(app, app.InitData())
+	if c.feeRecipient != nil {
+		peers = append(peers, c.feeRecipient)
+	}
+	withApp := client.WithApp(app, &data.DefaultData{Tenant: tenant})
 
 	asset := ethwallet.AsWalletAddr(c.assetHolderAddr)
-	alloc := channel.NewAllocation(2, asset)
+	alloc := channel.NewAllocation(len(peers), asset)
 	ourIndex, peerIndex := channel.Index(0), channel.Index(1)
 	alloc.SetBalance(ourIndex, asset, balance)
-	alloc.SetBalance(peerIndex, asset, big.NewInt(0))
+	alloc.SetBalance(peerIndex, asset, peerBalance)
+	if c.feeRecipient != nil {
+		alloc.SetBalance(pkgapp.FeeRecipientIdx, asset, big.NewInt(0))
+	}
 
 	prop, err := client.NewLedgerChannelProposal(
-		c.challengeDurationInSeconds(),
+		uint64(challengeDuration.Seconds()),
 		c.PerunAddress(),
 		alloc,
 		peers,
@@ -98,37 +457,152 @@ func (c *Client) Connect(ctx context.Context, peer wire.Address, balance channel
 		return nil, fmt.Errorf("creating channel proposal: %w", err)
 	}
 
-	ch, err := c.perunClient.PerunClient.ProposeChannel(ctx, prop)
+	propID := prop.ProposalID()
+	c.audit.record("proposal_sent", hexutil.Encode(propID[:]), newProposalDetails(peer, prop))
+	ctx, span := c.tracer.Start(trace.WithTraceID(ctx, trace.DeriveTraceID(propID[:])), "OpenChannel")
+	defer func() { trace.EndWithError(span, err) }()
+
+	openCtx, cancel := connection.OpenContext(ctx, c.connCfg.FundingTimeout)
+	defer cancel()
+	ch, err := c.perunClient.PerunClient.ProposeChannel(openCtx, prop)
 	if err != nil {
-		return nil, fmt.Errorf("proposing channel: %w", err)
+		return nil, fmt.Errorf("proposing channel: %w", connection.HandleOpenError(ctx, ch, err, c.connCfg))
 	}
-	conn := connection.NewConnection(ch)
+	conn := connection.NewConnection(ch, c.connCfg)
 	c.connections.Add(conn)
 
-	h := connection.NewEventHandler(conn)
-	go func() {
-		err := conn.Watch(h)
-		if err != nil {
-			c.Logf("Watching failed: %v", err)
-		}
-	}()
+	conn.StartWatching()
+	conn.StartIdleTimer()
 
 	return conn, nil
 }
 
+// ConnectDID opens a channel with the party identified by DID `id`. The DID
+// is resolved to the Ethereum address of the peer.
+func (c *Client) ConnectDID(ctx context.Context, id string, balance channel.Bal) (*connection.Connection, error) {
+	addr, err := c.ResolveAddress(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(ctx, ethwallet.AsWalletAddr(addr), balance)
+}
+
+// ResolveAddress resolves DID `id` to the Ethereum address of its
+// verification key.
+func (c *Client) ResolveAddress(ctx context.Context, id string) (common.Address, error) {
+	return did.ResolveAddress(ctx, c.connCfg.DIDs, id)
+}
+
+// DIDComm returns the DIDComm endpoint of the client, which sends requests
+// from the did:ethr DID of its account, or nil if DIDComm is disabled.
+func (c *Client) DIDComm() *message.DIDComm {
+	return c.didComm
+}
+
+// RequestAccount returns the funding account of `peer`, which differs from
+// the peer's address if the peer uses a session key.
+func (c *Client) RequestAccount(ctx context.Context, peer wire.Address) (common.Address, error) {
+	return connection.RequestAccount(ctx, c.perunClient.Messenger, peer, c.connCfg.Clock)
+}
+
+// RequestQuote requests a quote from `peer` before a channel is opened.
+func (c *Client) RequestQuote(ctx context.Context, peer wire.Address, req connection.QuoteRequest) (*pkgapp.Quote, error) {
+	return connection.RequestQuote(ctx, c.perunClient.Messenger, peer, req, c.connCfg.Clock)
+}
+
+// NextConnectionRequest returns the next channel proposal of a peer. It
+// fails with the error of `ctx` if it is done before.
 func (c *Client) NextConnectionRequest(ctx context.Context) (*connection.ConnectionRequest, error) {
-	p, ok := <-c.channelProposals
-	if !ok {
-		return nil, fmt.Errorf("channel closed")
+	select {
+	case p, ok := <-c.channelProposals:
+		if !ok {
+			return nil, connection.ErrChannelClosed
+		}
+		atomic.AddInt32(&c.pendingProposals, -1)
+		return connection.NewConnectionRequest(p, c.PerunAddress(), c.connections, c.connCfg), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	return connection.NewConnectionRequest(p, c.PerunAddress(), c.connections), nil
 }
 
+// Shutdown releases the listeners, chain subscriptions, and connections of
+// the client immediately. Open channels are left as they are. Use
+// GracefulShutdown to finish the exchanges in progress and close the channels
+// before.
 func (c *Client) Shutdown() {
+	c.supervisor.Stop()
+	if c.httpServer != nil {
+		c.httpServer.Close()
+	}
+	c.perunClient.Messenger.Close()
 	c.perunClient.PerunClient.Close()
 	c.perunClient.Bus.Close()
+	c.perunClient.Close()
+	c.events.close()
+	if c.webhooks != nil {
+		c.webhooks.Close()
+	}
+}
+
+// Err returns the failures of the internal goroutines of the client, e.g.,
+// recovered panics of the proposal and update handlers, as
+// supervisor.Failures, or nil if none failed.
+func (c *Client) Err() error {
+	return c.supervisor.Err()
+}
+
+// notify forwards an event to the event subscribers and the registered
+// webhooks.
+func (c *Client) notify(e connection.Event) {
+	c.audit.record(string(e.Type()), channelSubject(e.Header().Channel), e)
+	if dropped := c.events.publish(e); dropped > 0 {
+		c.log.Warnf("Dropped %s event for %d slow subscribers", e.Type(), dropped)
+	}
+	if c.webhooks != nil {
+		c.webhooks.Notify(string(e.Type()), e)
+	}
+}
+
+// Metrics returns the metrics registry of the client, or nil if metrics are
+// disabled.
+func (c *Client) Metrics() *metrics.Registry {
+	return c.metrics
 }
 
-func (c *Client) Account() *simple.Account {
+func (c *Client) serveHTTP(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", c.metrics.Handler())
+	mux.HandleFunc("/healthz", c.serveHealth(false))
+	mux.HandleFunc("/readyz", c.serveHealth(true))
+	if c.didComm != nil {
+		mux.Handle("/didcomm", c.didComm)
+	}
+	c.httpServer = &http.Server{Handler: mux}
+	go func() {
+		err := c.httpServer.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			c.log.Warnf("Serving http failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Log returns the logger of the client.
+func (c *Client) Log() log.Logger {
+	return c.log
+}
+
+func (c *Client) Account() pkgapp.Account {
 	return c.perunClient.Account
 }
+
+// RegisterSchema registers JSON schema `schema` for credential type `typ`.
+// Requested documents of this type are validated against the schema.
+func (c *Client) RegisterSchema(typ string, schema []byte) error {
+	return c.connCfg.Schemas.Register(typ, schema)
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/client/perun"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+func TestNextConnectionRequest(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(t, err)
+	c := &Client{
+		perunClient:      &perun.Client{Account: acc.(*simple.Account)},
+		channelProposals: make(chan *connection.ChannelProposal, 1),
+		connections:      connection.NewRegistry(),
+		connCfg:          &connection.Config{},
+	}
+
+	// A received proposal is no longer pending.
+	c.pendingProposals = 1
+	c.channelProposals <- &connection.ChannelProposal{}
+	req, err := c.NextConnectionRequest(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, req)
+	require.Zero(t, c.pendingRequests())
+
+	// Giving up does not change the pending proposals.
+	c.pendingProposals = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.NextConnectionRequest(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, c.pendingRequests())
+
+	close(c.channelProposals)
+	_, err = c.NextConnectionRequest(context.Background())
+	require.ErrorIs(t, err, connection.ErrChannelClosed)
+	require.Equal(t, 1, c.pendingRequests())
+}
@@ -0,0 +1,61 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/app"
+)
+
+var ErrNoAnchor = errors.New("no anchoring configured")
+
+// anchors collects the IDs of the credentials issued in a channel, which are
+// anchored when the channel is settled.
+type anchors struct {
+	mu  sync.Mutex
+	ids []app.Hash
+}
+
+func (a *anchors) add(id app.Hash) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ids = append(a.ids, id)
+}
+
+func (a *anchors) take() []app.Hash {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ids := a.ids
+	a.ids = nil
+	return ids
+}
+
+// recordIssued records credential `id` for anchoring, if anchoring is
+// enabled.
+func (c *Connection) recordIssued(id app.Hash) {
+	if c.cfg.Anchor != nil {
+		c.anchors.add(id)
+	}
+}
+
+// AnchorIssued anchors the credentials issued in the channel that are not
+// anchored yet. It is called when the channel is closed, and may be called
+// again if anchoring failed then.
+func (c *Connection) AnchorIssued(ctx context.Context) error {
+	if c.cfg.Anchor == nil {
+		return ErrNoAnchor
+	}
+	ids := c.anchors.take()
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := c.cfg.Anchor(ctx, ids); err != nil {
+		for _, id := range ids {
+			c.anchors.add(id)
+		}
+		return fmt.Errorf("anchoring %d credentials: %w", len(ids), err)
+	}
+	return nil
+}
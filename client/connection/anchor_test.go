@@ -0,0 +1,37 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnchorIssued(t *testing.T) {
+	ctx := context.Background()
+	var anchored []app.Hash
+	fail := true
+	c := &Connection{cfg: &Config{Anchor: func(_ context.Context, ids []app.Hash) error {
+		if fail {
+			return errors.New("failure")
+		}
+		anchored = append(anchored, ids...)
+		return nil
+	}}}
+
+	c.recordIssued(app.Hash{1})
+	c.recordIssued(app.Hash{2})
+	require.Error(t, c.AnchorIssued(ctx))
+	require.Empty(t, anchored)
+
+	// Failed anchorings are retried.
+	fail = false
+	require.NoError(t, c.AnchorIssued(ctx))
+	require.Equal(t, []app.Hash{{1}, {2}}, anchored)
+	require.NoError(t, c.AnchorIssued(ctx))
+	require.Len(t, anchored, 2, "anchored twice")
+
+	require.ErrorIs(t, (&Connection{cfg: &Config{}}).AnchorIssued(ctx), ErrNoAnchor)
+}
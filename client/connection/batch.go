@@ -0,0 +1,334 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/pkg/trace"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+)
+
+// batchHash identifies a batch of documents.
+func batchHash(hs [][data.HashLen]byte) app.Hash {
+	b := make([]byte, 0, len(hs)*data.HashLen)
+	for _, h := range hs {
+		b = append(b, h[:]...)
+	}
+	return crypto.Keccak256Hash(b)
+}
+
+// RequestCredentials requests credentials on documents `docs` from `issuer`
+// at the aggregate price `price`. All credentials are issued in a single
+// channel update.
+func (c *Connection) RequestCredentials(
+	ctx context.Context,
+	docs [][]byte,
+	price channel.Bal,
+	issuer common.Address,
+) (_ *AsyncBatchCredential, err error) {
+	if n := len(docs); n == 0 || n > data.MaxBatchSize {
+		return nil, fmt.Errorf("invalid batch size: %d", n)
+	}
+
+	offer := &data.BatchOffer{
+		Issuer:     issuer,
+		DataHashes: make([][data.HashLen]byte, len(docs)),
+		Price:      price,
+		Buyer:      uint16(c.Idx()),
+	}
+	for i, doc := range docs {
+		offer.DataHashes[i] = app.ComputeDocumentHash(doc)
+	}
+	h := batchHash(offer.DataHashes)
+
+	ctx, span := c.startSpan(ctx, "RequestCredentials", h, c.State().Version+1)
+	defer func() { trace.EndWithError(span, err) }()
+
+	if c.Disputed() {
+		return nil, ErrDisputeRegistered
+	} else if err := c.acceptsRequests(c.State()); err != nil {
+		return nil, err
+	}
+
+	// Transfer the documents out-of-band, the channel only holds their
+	// hashes.
+	for _, doc := range docs {
+		if err := c.SendDocument(ctx, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	callback, err := c.sigs.RegisterCallback(h, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.UpdateBy(ctx, func(s *channel.State) error {
+		o := offer.Clone().(*data.BatchOffer)
+		o.Nonce = s.Version + 1
+		s.Data = o
+		return nil
+	})
+	if err != nil {
+		c.sigs.Unregister(h, issuer)
+		if ctx.Err() != nil {
+			c.cancelExpired(h)
+		}
+		err = WrapPerunError(err)
+		c.notifyIfRejected(err)
+		return nil, fmt.Errorf("updating channel: %w", err)
+	}
+
+	return &AsyncBatchCredential{callback}, nil
+}
+
+type AsyncBatchCredential struct {
+	sigRegCallback
+}
+
+// Await waits for the credentials.
+func (c *AsyncBatchCredential) Await(ctx context.Context) (*BatchCredentialProposal, error) {
+	select {
+	case resp := <-c.sigRegCallback:
+		return resp.(*BatchCredentialProposal), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// BatchCredentialProposal holds the signatures issued on a batch of
+// documents, in the order of the request.
+type BatchCredentialProposal struct {
+	*client.UpdateResponder
+	Signatures [][]byte
+	conn       *Connection
+	offer      *data.BatchOffer
+}
+
+func (*BatchCredentialProposal) isCredentialResponse() {}
+
+// OnChain returns whether the credentials were issued on-chain, in which
+// case the payment was already enforced by the adjudicator.
+func (p *BatchCredentialProposal) OnChain() bool {
+	return p.UpdateResponder == nil
+}
+
+// Accept accepts the channel update issuing the credentials, thereby
+// completing the payment.
+func (p *BatchCredentialProposal) Accept(ctx context.Context) (err error) {
+	ctx, span := p.conn.startSpan(ctx, "AcceptCredentials", batchHash(p.offer.DataHashes), p.offer.Nonce)
+	defer func() { trace.EndWithError(span, err) }()
+
+	if p.OnChain() {
+		return nil
+	}
+	return p.UpdateResponder.Accept(ctx)
+}
+
+// Reject rejects the channel update issuing the credentials.
+func (p *BatchCredentialProposal) Reject(ctx context.Context, reason string) error {
+	return p.RejectWithCode(ctx, RejectUnspecified, reason)
+}
+
+// RejectWithCode rejects the channel update issuing the credentials with
+// `code`, which is carried to the issuer along with `reason`.
+func (p *BatchCredentialProposal) RejectWithCode(ctx context.Context, code RejectionCode, reason string) (err error) {
+	ctx, span := p.conn.startSpan(ctx, "RejectCredentials", batchHash(p.offer.DataHashes), p.offer.Nonce)
+	defer func() { trace.EndWithError(span, err) }()
+
+	if p.OnChain() {
+		return ErrIssuedOnChain
+	}
+
+	err = p.UpdateResponder.Reject(ctx, encodeReason(code, reason))
+	if err != nil {
+		return err
+	}
+	p.conn.notify(&UpdateRejected{EventHeader: p.conn.header(), Code: code, Reason: reason})
+	return nil
+}
+
+func (c *Connection) addBatchSignatures(cert *data.BatchCert, offer *data.BatchOffer, responder *client.UpdateResponder) {
+	sigs := make([][]byte, len(cert.Signatures))
+	for i := range cert.Signatures {
+		sigs[i] = append([]byte(nil), cert.Signatures[i][:]...)
+	}
+	c.sigs.Push(batchHash(offer.DataHashes), offer.Issuer, &BatchCredentialProposal{
+		UpdateResponder: responder,
+		Signatures:      sigs,
+		conn:            c,
+		offer:           offer,
+	})
+}
+
+// BatchCredentialRequest is a request for credentials on multiple documents.
+type BatchCredentialRequest struct {
+	resp     chan CredentialRequestResponse
+	offer    *data.BatchOffer
+	conn     *Connection
+	decision *decision
+}
+
+// addBatchCredentialRequest forwards the request to the application, unless
+// the peer cancels it before it is picked up.
+func (c *Connection) addBatchCredentialRequest(offer *data.BatchOffer, d *decision) chan CredentialRequestResponse {
+	atomic.AddInt32(&c.pending, 1)
+	defer atomic.AddInt32(&c.pending, -1)
+
+	response := make(chan CredentialRequestResponse)
+	req := &BatchCredentialRequest{
+		resp:     response,
+		offer:    offer,
+		conn:     c,
+		decision: d,
+	}
+	select {
+	case c.batchRequests <- req:
+	case <-d.cancelled:
+	}
+	return response
+}
+
+// NextBatchCredentialRequest returns the next request for a batch of
+// credentials.
+func (c *Connection) NextBatchCredentialRequest(ctx context.Context) (*BatchCredentialRequest, error) {
+	select {
+	case r := <-c.batchRequests:
+		return r, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Offer returns the requested offer.
+func (r *BatchCredentialRequest) Offer() *data.BatchOffer {
+	return r.offer
+}
+
+// Peer returns the address of the requesting peer.
+func (r *BatchCredentialRequest) Peer() wallet.Address {
+	return r.conn.Params().Parts[r.offer.Buyer]
+}
+
+// CheckDocs checks that `docs` are the requested documents.
+func (r *BatchCredentialRequest) CheckDocs(docs [][]byte) error {
+	if len(docs) != len(r.offer.DataHashes) {
+		return ErrWrongDocument
+	}
+	for i, doc := range docs {
+		if app.ComputeDocumentHash(doc) != r.offer.DataHashes[i] {
+			return ErrWrongDocument
+		}
+	}
+	return nil
+}
+
+func (r *BatchCredentialRequest) CheckPrice(p *big.Int) error {
+	if r.offer.Price.Cmp(p) != 0 {
+		return ErrWrongPrice
+	}
+	return nil
+}
+
+// Documents returns the requested documents if they have been received.
+func (r *BatchCredentialRequest) Documents() ([][]byte, error) {
+	docs := make([][]byte, len(r.offer.DataHashes))
+	for i, h := range r.offer.DataHashes {
+		doc, ok := r.conn.docs.Get(h)
+		if !ok {
+			return nil, ErrDocumentUnknown
+		}
+		docs[i] = doc
+	}
+	return docs, nil
+}
+
+// Reject rejects the request.
+func (r *BatchCredentialRequest) Reject(ctx context.Context, reason string) error {
+	return r.RejectWithCode(ctx, RejectUnspecified, reason)
+}
+
+// RejectWithCode rejects the request with `code`, which is carried to the
+// requester along with `reason`.
+func (r *BatchCredentialRequest) RejectWithCode(ctx context.Context, code RejectionCode, reason string) error {
+	if err := r.decision.make(); err != nil {
+		return fmt.Errorf("rejecting credential request: %w", err)
+	}
+	errs := make(chan error)
+	r.resp <- &CredentialRequestResponseReject{ctx, encodeReason(code, reason), errs}
+	err := <-errs
+	if err != nil {
+		return fmt.Errorf("rejecting credential request: %w", err)
+	}
+	r.conn.notify(&UpdateRejected{EventHeader: r.conn.header(), Code: code, Reason: reason})
+	return nil
+}
+
+// IssueCredentials issues all requested credentials in a single channel
+// update.
+func (r *BatchCredentialRequest) IssueCredentials(ctx context.Context, acc app.Account) (err error) {
+	ctx, span := r.conn.startSpan(ctx, "IssueCredentials", batchHash(r.offer.DataHashes), r.offer.Nonce)
+	defer func() { trace.EndWithError(span, err) }()
+
+	start := r.conn.cfg.Clock.Now()
+	if err := r.decision.make(); err != nil {
+		return fmt.Errorf("accepting credential request: %w", err)
+	}
+	errs := make(chan error)
+	r.resp <- &CredentialRequestResponseAccept{ctx, errs}
+	err = <-errs
+	if err != nil {
+		return fmt.Errorf("accepting credential request: %w", err)
+	}
+
+	err = r.conn.issueCredentials(ctx, r.offer, acc)
+	if err != nil {
+		return fmt.Errorf("issueing credentials: %w", err)
+	}
+	for range r.offer.DataHashes {
+		r.conn.cfg.Metrics.credentialIssued(r.conn.cfg.Clock.Now().Sub(start))
+	}
+	return nil
+}
+
+func (c *Connection) issueCredentials(ctx context.Context, offer *data.BatchOffer, acc app.Account) error {
+	up := func(s *channel.State) error {
+		// Check inputs against current state.
+		curOffer, ok := s.Data.(*data.BatchOffer)
+		if !ok {
+			return fmt.Errorf("data has wrong type: %T", s.Data)
+		} else if !curOffer.Equal(offer) {
+			return fmt.Errorf("unequal offers: got %v, expected %v", curOffer, offer)
+		} else if addr := app.AccountAddress(acc); offer.Issuer != addr {
+			return fmt.Errorf("unequal addresses: got %v, expected %v", addr, offer.Issuer)
+		}
+
+		// Sign.
+		cert := &data.BatchCert{Signatures: make([][data.SigLen]byte, len(offer.DataHashes))}
+		for i, h := range offer.DataHashes {
+			sig, err := app.SignHash(acc, h)
+			if err != nil {
+				return fmt.Errorf("signing hash: %w", err)
+			}
+			cert.Signatures[i] = sig
+		}
+		s.Data = cert
+
+		// Update balances.
+		asset := s.Allocation.Assets[app.AssetIdx]
+		s.Allocation.SubFromBalance(channel.Index(offer.Buyer), asset, offer.Price)
+		s.Allocation.AddToBalance(c.Idx(), asset, offer.Price)
+
+		return nil
+	}
+
+	return c.updateOrForce(ctx, up, nil)
+}
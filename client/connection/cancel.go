@@ -0,0 +1,170 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindCancel is the message kind of request cancellations.
+const MsgKindCancel = "cancel"
+
+// Cancellation asks the issuer to reject the undecided request for the
+// document with hash Hash, or for the batch of documents with hash Hash, in
+// channel Channel.
+type Cancellation struct {
+	Channel channel.ID `json:"channel"`
+	Hash    app.Hash   `json:"hash"`
+}
+
+// decision makes the decision of the application on a request of the peer
+// and the cancellation of the request by the peer mutually exclusive.
+type decision struct {
+	mu        sync.Mutex
+	made      bool
+	cancelled chan struct{}
+}
+
+func newDecision() *decision {
+	return &decision{cancelled: make(chan struct{})}
+}
+
+// make marks the decision as made. It fails if the request was cancelled.
+func (d *decision) make() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.cancelled:
+		return ErrRequestCancelled
+	default:
+	}
+	d.made = true
+	return nil
+}
+
+// cancel cancels the request. It fails if the decision was made.
+func (d *decision) cancel() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.made {
+		return ErrRequestDecided
+	}
+	select {
+	case <-d.cancelled:
+	default:
+		close(d.cancelled)
+	}
+	return nil
+}
+
+// incoming holds the decisions on the requests of the peer that are in
+// progress, by document or batch hash.
+type incoming struct {
+	mu        sync.Mutex
+	decisions map[app.Hash]*decision
+}
+
+func (in *incoming) add(h app.Hash) *decision {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.decisions == nil {
+		in.decisions = make(map[app.Hash]*decision)
+	}
+	d := newDecision()
+	in.decisions[h] = d
+	return d
+}
+
+func (in *incoming) remove(h app.Hash) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	delete(in.decisions, h)
+}
+
+func (in *incoming) cancel(h app.Hash) error {
+	in.mu.Lock()
+	d, ok := in.decisions[h]
+	in.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown request: %x", h)
+	}
+	return d.cancel()
+}
+
+// CancelRequest withdraws the own request for the credential on the
+// document with hash `h`, or for the credentials on a batch of documents
+// with hash `h`, e.g., after the user gave up waiting. A request that awaits
+// the decision of the issuer is rejected by the issuer, which rolls the
+// channel back to the state before the request. A counter-offer is
+// abandoned. Once the issuer accepted the request, ErrRequestDecided is
+// returned, as the issuer is committed to issuing the credential.
+func (c *Connection) CancelRequest(ctx context.Context, h app.Hash) error {
+	switch d := c.State().Data.(type) {
+	case *data.CounterOffer:
+		if d.DataHash == h {
+			return c.abandonRequest(ctx)
+		}
+	case *data.Offer:
+		if d.DataHash == h && channel.Index(d.Buyer) == c.Idx() {
+			return ErrRequestDecided
+		}
+	case *data.BatchOffer:
+		if batchHash(d.DataHashes) == h && channel.Index(d.Buyer) == c.Idx() {
+			return ErrRequestDecided
+		}
+	}
+
+	req := Cancellation{Channel: c.ID(), Hash: h}
+	if err := c.cfg.Messenger.Request(ctx, c.peer(), MsgKindCancel, req, nil); err != nil {
+		return fmt.Errorf("cancelling request: %w", err)
+	}
+	return nil
+}
+
+// cancelExpired cancels the request with hash `h` after the context of the
+// request expired. go-perun discards the update on our side, so the issuer
+// must not accept it anymore.
+func (c *Connection) cancelExpired(h app.Hash) {
+	ctx, cancel := c.responseContext()
+	defer cancel()
+	if err := c.CancelRequest(ctx, h); err != nil {
+		c.log.Warnf("Error cancelling expired request: %v", err)
+	}
+}
+
+// HandleCancellations answers request cancellations for the connections in
+// `reg`. A cancellation is refused if the request was already decided.
+func HandleCancellations(m *message.Messenger, reg *Registry) {
+	m.Handle(MsgKindCancel, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var req Cancellation
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("decoding cancellation: %w", err)
+		}
+		c, ok := reg.ForID(req.Channel)
+		if !ok || !c.peer().Equals(peer) {
+			return nil, fmt.Errorf("unknown channel: %x", req.Channel)
+		}
+		return nil, c.incoming.cancel(req.Hash)
+	})
+}
+
+// rejectCancelled rejects a channel update whose request was cancelled by
+// the peer.
+func (conn *Connection) rejectCancelled(responder *client.UpdateResponder) {
+	const reason = "cancelled by requester"
+	ctx, cancel := conn.responseContext()
+	defer cancel()
+	if err := responder.Reject(ctx, encodeReason(RejectCancelled, reason)); err != nil {
+		conn.log.Warnf("Error rejecting cancelled request: %v", err)
+		return
+	}
+	conn.notify(&UpdateRejected{EventHeader: conn.header(), Code: RejectCancelled, Reason: reason})
+}
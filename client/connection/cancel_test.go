@@ -0,0 +1,65 @@
+package connection
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecision(t *testing.T) {
+	d := newDecision()
+	require.NoError(t, d.make())
+	require.ErrorIs(t, d.cancel(), ErrRequestDecided)
+	select {
+	case <-d.cancelled:
+		t.Fatal("decided request cancelled")
+	default:
+	}
+
+	d = newDecision()
+	require.NoError(t, d.cancel())
+	require.NoError(t, d.cancel(), "cancelling twice")
+	<-d.cancelled
+	require.ErrorIs(t, d.make(), ErrRequestCancelled)
+}
+
+func TestDecisionRace(t *testing.T) {
+	// Exactly one of the decision and the cancellation succeeds.
+	for i := 0; i < 100; i++ {
+		d := newDecision()
+		var wg sync.WaitGroup
+		var madeErr, cancelErr error
+		wg.Add(2)
+		go func() { defer wg.Done(); madeErr = d.make() }()
+		go func() { defer wg.Done(); cancelErr = d.cancel() }()
+		wg.Wait()
+		if madeErr == nil {
+			require.ErrorIs(t, cancelErr, ErrRequestDecided)
+		} else {
+			require.ErrorIs(t, madeErr, ErrRequestCancelled)
+			require.NoError(t, cancelErr)
+		}
+	}
+}
+
+func TestIncoming(t *testing.T) {
+	var in incoming
+	h1, h2 := app.Hash{1}, app.Hash{2}
+	require.Error(t, in.cancel(h1), "unknown request")
+
+	d1 := in.add(h1)
+	d2 := in.add(h2)
+	require.NoError(t, d2.make())
+	require.NoError(t, in.cancel(h1))
+	require.ErrorIs(t, d1.make(), ErrRequestCancelled)
+	require.ErrorIs(t, in.cancel(h2), ErrRequestDecided)
+
+	// A new request for the same document gets a new decision.
+	d1 = in.add(h1)
+	require.NoError(t, d1.make())
+
+	in.remove(h1)
+	require.Error(t, in.cancel(h1), "removed request")
+}
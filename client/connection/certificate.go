@@ -0,0 +1,38 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindIssuerChain is the message kind of issuer certificate chain
+// requests.
+const MsgKindIssuerChain = "issuer-chain"
+
+// HandleIssuerChainRequests answers issuer certificate chain requests with
+// `chain`, which certifies us as issuer.
+func HandleIssuerChainRequests(m *message.Messenger, chain []*app.IssuerCertificate) {
+	m.Handle(MsgKindIssuerChain, func(context.Context, wire.Address, json.RawMessage) (interface{}, error) {
+		return chain, nil
+	})
+}
+
+// RequestIssuerChain requests the certificate chain of the peer, which
+// holders attach to the credentials issued by the peer. The chain is not
+// verified against trust anchors, which is up to the verifiers.
+func (c *Connection) RequestIssuerChain(ctx context.Context) ([]*app.IssuerCertificate, error) {
+	var chain []*app.IssuerCertificate
+	if err := c.cfg.Messenger.Request(ctx, c.peer(), MsgKindIssuerChain, nil, &chain); err != nil {
+		return nil, fmt.Errorf("requesting issuer chain: %w", err)
+	}
+	if len(chain) > 0 && chain[0].Subject != ethwallet.AsEthAddr(c.peer()) {
+		return nil, fmt.Errorf("%w: chain is for %v", app.ErrUntrustedIssuer, chain[0].Subject)
+	}
+	return chain, nil
+}
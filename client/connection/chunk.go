@@ -0,0 +1,68 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// RequestChunkedCredential requests a credential on document `doc` in chunks
+// of `chunkSize` bytes. Each chunk is requested and paid for in a separate
+// channel update at its share of `price`, so neither party is exposed for
+// more than the value of a single chunk. Each chunk is signed as
+// app.ChunkDocument, and its signature is verified before it is paid for.
+func (c *Connection) RequestChunkedCredential(
+	ctx context.Context,
+	doc []byte,
+	chunkSize int,
+	price *big.Int,
+	issuer common.Address,
+) (*app.ChunkedCredential, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size: %d", chunkSize)
+	}
+
+	chunks := app.SplitDocument(doc, chunkSize)
+	prices := app.SplitPrice(price, len(chunks))
+	cred := &app.ChunkedCredential{
+		Document:   doc,
+		ChunkSize:  chunkSize,
+		Signatures: make([][data.SigLen]byte, len(chunks)),
+		Domain:     c.cfg.Domain,
+	}
+
+	root := app.ComputeDocumentHash(doc)
+	for i, chunk := range chunks {
+		sig, err := c.requestChunk(ctx, app.ChunkDocument(root, i, len(chunks), chunk), prices[i], issuer)
+		if err != nil {
+			return nil, fmt.Errorf("requesting chunk %d: %w", i, err)
+		}
+		cred.Signatures[i] = sig
+	}
+	return cred, nil
+}
+
+// requestChunk requests a signature on chunk document `doc`.
+func (c *Connection) requestChunk(ctx context.Context, doc []byte, price *big.Int, issuer common.Address) (sig [data.SigLen]byte, err error) {
+	asyncCred, err := c.RequestCredential(ctx, doc, price, issuer)
+	if err != nil {
+		return sig, err
+	}
+
+	prop, err := asyncCred.Await(ctx)
+	if err != nil {
+		return sig, fmt.Errorf("awaiting signature: %w", err)
+	}
+
+	copy(sig[:], prop.Signature)
+	h := app.TypedCredentialHash(c.cfg.Domain, app.ComputeDocumentHash(doc), 0, app.Hash{})
+	if err := app.VerifySig(sig, h, issuer); err != nil {
+		_ = prop.Reject(ctx, "invalid signature")
+		return sig, fmt.Errorf("verifying signature: %w", err)
+	}
+	return sig, prop.Accept(ctx)
+}
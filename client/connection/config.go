@@ -0,0 +1,92 @@
+package connection
+
+import (
+	"context"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"github.com/perun-network/perun-credential-payment/pkg/ratelimit"
+	"github.com/perun-network/perun-credential-payment/pkg/supervisor"
+	"github.com/perun-network/perun-credential-payment/pkg/trace"
+)
+
+// Config holds the dependencies that a client shares with its connections.
+type Config struct {
+	Log       log.Logger
+	Metrics   *Metrics
+	Tracer    trace.Tracer
+	Notify    func(Event) // Optional. Called for every event.
+	Messenger *message.Messenger
+	Documents *DocumentStore // Documents received out-of-band.
+	Content   ContentStore   // Optional. Used for transferring large documents.
+	Schemas   *SchemaRegistry
+	Domain    app.Hash     // EIP-712 domain separator of credential signatures.
+	DIDs      did.Resolver // Resolves the DIDs in credential metadata.
+	// RequireHolderBinding rejects credential requests whose metadata does
+	// not bind the credential to the requester's DID.
+	RequireHolderBinding bool
+	// ProofOfPossession makes the issuer challenge the requester to prove
+	// possession of the bound holder key before issuing.
+	ProofOfPossession bool
+	// FeeRate is the share of the price in basis points that goes to the fee
+	// recipient in channels with three participants.
+	FeeRate uint16
+	// PriceOracle converts USD prices into asset amounts. Optional. Enables
+	// RequestCredentialUSD and CheckPriceUSD.
+	PriceOracle PriceOracle
+	// PriceTolerance is the deviation in basis points from the oracle rate
+	// that CheckPriceUSD accepts.
+	PriceTolerance uint16
+	// Anchor writes the IDs of the credentials issued by us to an anchoring
+	// contract. Optional. Called when the channel is settled. Credentials
+	// issued in batches are not anchored.
+	Anchor func(ctx context.Context, ids []app.Hash) error
+	// SuiteSigner additionally signs the credentials issued by us with a
+	// key of a signature suite. Optional. The signatures are served to the
+	// holders on request, see RequestSuiteSignature.
+	SuiteSigner app.CredentialSigner
+	// Evidence records the evidence exported by ExportDisputeEvidence.
+	// Optional.
+	Evidence *EvidenceRecorder
+	// ContractSigs verifies EIP-1271 signatures of contract issuers.
+	// Optional. Enables IssueContractSignedCredential.
+	ContractSigs *app.ContractSigVerifier
+	// FundingTimeout bounds opening a channel, including its funding.
+	// Optional. Deposits of channels not funded in time are reclaimed.
+	FundingTimeout time.Duration
+	// Disputes reads the disputes recorded by the adjudicator. Optional.
+	// Together with ReorgWindow, enables re-evaluating the channel state
+	// when a reorg drops an observed adjudicator event.
+	Disputes DisputeReader
+	// ReorgWindow is the time for which observed adjudicator events are
+	// checked against the chain.
+	ReorgWindow time.Duration
+	// Clock times the waits for challenge durations, reorg checks, and
+	// subscription renewals.
+	Clock clock.Clock
+	// Supervisor runs the watchers of the adjudicator subscriptions and
+	// restarts them when they fail.
+	Supervisor *supervisor.Supervisor
+	// Audit records channel operations in the audit log. Optional.
+	Audit func(kind, subject string, details interface{})
+	// RequestLimiter rejects credential requests exceeding its rates.
+	// Optional.
+	RequestLimiter *ratelimit.Limiter
+	// Versions records the protocol versions agreed with peers. Optional.
+	// Peers are assumed to speak the legacy version without it.
+	Versions *Versions
+	// ResponseTimeout bounds responding to channel updates of the peer that
+	// are decided automatically. Optional. Defaults to
+	// DefaultResponseTimeout.
+	ResponseTimeout time.Duration
+	// IdleTimeout is the time without state updates after which a channel
+	// is closed. Optional. Channels are not closed for idleness if zero.
+	IdleTimeout time.Duration
+}
+
+// DefaultResponseTimeout is the default of Config.ResponseTimeout.
+const DefaultResponseTimeout = 30 * time.Second
@@ -2,17 +2,26 @@ package connection
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math/big"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/perun-network/perun-credential-payment/app"
 	"github.com/perun-network/perun-credential-payment/app/data"
-	"github.com/perun-network/perun-credential-payment/pkg/atomic"
-	ewallet "perun.network/go-perun/backend/ethereum/wallet/simple"
+	patomic "github.com/perun-network/perun-credential-payment/pkg/atomic"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"github.com/perun-network/perun-credential-payment/pkg/trace"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/client"
 	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
 )
 
 type ChannelProposal struct {
@@ -34,17 +43,20 @@ type ConnectionRequest struct {
 	p        *ChannelProposal
 	acc      wallet.Address
 	registry *Registry
+	cfg      *Config
 }
 
 func NewConnectionRequest(
 	p *ChannelProposal,
 	acc wallet.Address,
 	registry *Registry,
+	cfg *Config,
 ) *ConnectionRequest {
 	return &ConnectionRequest{
 		p:        p,
 		acc:      acc,
 		registry: registry,
+		cfg:      cfg,
 	}
 }
 
@@ -52,58 +64,295 @@ func (r *ConnectionRequest) Peer() wallet.Address {
 	return r.p.p.Participant
 }
 
-func (r *ConnectionRequest) Accept(ctx context.Context) (*Connection, error) {
+// ChallengeDuration returns the challenge duration of the proposed channel.
+func (r *ConnectionRequest) ChallengeDuration() time.Duration {
+	return time.Duration(r.p.p.ChallengeDuration) * time.Second
+}
+
+// Tenant returns the tenant for which the channel is proposed, or the empty
+// string if the proposal does not name one.
+func (r *ConnectionRequest) Tenant() string {
+	if d, ok := r.p.p.InitData.(*data.DefaultData); ok {
+		return d.Tenant
+	}
+	return ""
+}
+
+// Balance returns the balance that accepting the request deposits. It is
+// non-zero if the peer wants to request credentials in both directions.
+func (r *ConnectionRequest) Balance() *big.Int {
+	for i, p := range r.p.p.Peers {
+		if p.Equals(r.acc) {
+			return new(big.Int).Set(r.p.p.InitBals.Balances[0][i])
+		}
+	}
+	return new(big.Int)
+}
+
+func (r *ConnectionRequest) Accept(ctx context.Context) (_ *Connection, err error) {
+	propID := r.p.p.ProposalID()
+	ctx, span := r.cfg.Tracer.Start(trace.WithTraceID(ctx, trace.DeriveTraceID(propID[:])), "AcceptChannel")
+	defer func() { trace.EndWithError(span, err) }()
+
+	openCtx, cancel := OpenContext(ctx, r.cfg.FundingTimeout)
+	defer cancel()
 	msg := r.p.p.Accept(r.acc, client.WithRandomNonce())
-	ch, err := r.p.r.Accept(ctx, msg)
+	ch, err := r.p.r.Accept(openCtx, msg)
 	if err != nil {
-		return nil, fmt.Errorf("accepting channel: %w", err)
+		return nil, fmt.Errorf("accepting channel: %w", HandleOpenError(ctx, ch, err, r.cfg))
 	}
-	conn := NewConnection(ch)
+	conn := NewConnection(ch, r.cfg)
 	r.registry.Add(conn)
 
-	h := NewEventHandler(conn)
-	go func() {
-		err := conn.Watch(h)
-		if err != nil {
-			conn.Log().Warnf("Watching failed: %v", err)
-		}
-	}()
+	conn.StartWatching()
+	conn.StartIdleTimer()
 
 	return conn, nil
 }
 
+// Reject rejects the request with `reason`.
+func (r *ConnectionRequest) Reject(ctx context.Context, reason string) error {
+	return r.RejectWithCode(ctx, RejectUnspecified, reason)
+}
+
+// RejectWithCode rejects the request with `code`, which is carried to the
+// proposer along with `reason`.
+func (r *ConnectionRequest) RejectWithCode(ctx context.Context, code RejectionCode, reason string) error {
+	if err := r.p.r.Reject(ctx, encodeReason(code, reason)); err != nil {
+		return fmt.Errorf("rejecting channel: %w", err)
+	}
+	if r.cfg.Audit != nil {
+		propID := r.p.p.ProposalID()
+		r.cfg.Audit("proposal_rejected", hexutil.Encode(propID[:]), struct {
+			Reason string `json:"reason"`
+		}{reason})
+	}
+	return nil
+}
+
 type Connection struct {
 	*client.Channel
-	sigs         *sigReg
-	credRequests chan *CredentialRequest
-	disputed     *atomic.Bool
-	concludable  *atomic.Bool
-	concluded    *atomic.Bool
+	sigs          *sigReg
+	requests      requestQueue
+	batchRequests chan *BatchCredentialRequest
+	pending       int32
+	disputed      *patomic.Bool
+	concludable   *patomic.Bool
+	concluded     *patomic.Bool
+	migrating     *patomic.Bool
+	draining      *patomic.Bool
+	onChain       onChainState
+	peerKey       peerKeyCache
+	subs          subscriptions
+	anchors       anchors
+	receipts      receipts
+	suiteSigs     suiteSigs
+	incoming      incoming
+	docs          *PeerDocuments // Documents received from the peer.
+	log           log.Logger
+	cfg           *Config
 }
 
-func NewConnection(ch *client.Channel) *Connection {
-	return &Connection{
-		Channel:      ch,
-		sigs:         newSigReg(),
-		credRequests: make(chan *CredentialRequest),
-		disputed:     atomic.NewBool(false),
-		concludable:  atomic.NewBool(false),
-		concluded:    atomic.NewBool(false),
+// NewConnection wraps `ch` into a connection. The logger is annotated with
+// the channel ID and the peer address.
+func NewConnection(ch *client.Channel, cfg *Config) *Connection {
+	cfg.Metrics.channelOpened()
+	c := &Connection{
+		Channel:       ch,
+		sigs:          newSigReg(),
+		batchRequests: make(chan *BatchCredentialRequest),
+		disputed:      patomic.NewBool(false),
+		concludable:   patomic.NewBool(false),
+		concluded:     patomic.NewBool(false),
+		migrating:     patomic.NewBool(false),
+		draining:      patomic.NewBool(false),
+		cfg:           cfg,
 	}
+	c.log = cfg.Log.WithField("channel", formatID(ch.ID())).WithField("peer", c.peer())
+	if cfg.Documents != nil {
+		c.docs = cfg.Documents.Peer(c.peer())
+	}
+	c.notify(&ChannelOpened{EventHeader: c.header(), Peer: c.peer().String()})
+	return c
+}
+
+// formatID formats channel ID `id` as hex string for logs and traces.
+func formatID(id channel.ID) string {
+	return hexutil.Encode(id[:])
 }
 
 func (c *Connection) Disputed() bool {
 	return c.disputed.Value()
 }
 
+// self returns the own address in the channel.
+func (c *Connection) self() common.Address {
+	return ethwallet.AsEthAddr(c.Peers()[c.Idx()])
+}
+
+// bindHolder returns a copy of `meta` whose holder DID is the own did:ethr,
+// unless it already names a holder.
+func (c *Connection) bindHolder(meta *app.Metadata) *app.Metadata {
+	if meta == nil {
+		meta = &app.Metadata{IssuedAt: uint64(c.cfg.Clock.Now().Unix())}
+	} else {
+		m := *meta
+		meta = &m
+	}
+	if meta.Holder == "" {
+		meta.Holder = did.Ethr(c.self())
+	}
+	return meta
+}
+
+// peer returns the address of the channel peer. For the fee recipient, this
+// is the first participant.
+func (c *Connection) peer() wire.Address {
+	if c.isFeeRecipient() {
+		return c.Peers()[0]
+	}
+	return c.Peers()[1-c.Idx()]
+}
+
+// Peer returns the address of the channel peer.
+func (c *Connection) Peer() wire.Address {
+	return c.peer()
+}
+
+func (c *Connection) setDisputed() {
+	if !c.disputed.Swap(true) {
+		c.cfg.Metrics.disputeRaised()
+	}
+}
+
+// startSpan starts a span that is part of the trace of the request for the
+// document with hash `h` that is made in the state with version `nonce`, see
+// data.Offer.Nonce. Repeated requests for a document are traced separately.
+func (c *Connection) startSpan(ctx context.Context, name string, h app.Hash, nonce uint64) (context.Context, trace.Span) {
+	id := c.ID()
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], nonce)
+	ctx = trace.WithTraceID(ctx, trace.DeriveTraceID(id[:], h[:], n[:]))
+	ctx, span := c.cfg.Tracer.Start(ctx, name)
+	span.SetAttribute("channel", formatID(id))
+	return ctx, span
+}
+
 func (c *Connection) RequestCredential(
 	ctx context.Context,
 	doc []byte,
 	price channel.Bal,
 	issuer common.Address,
 ) (*AsyncCredential, error) {
-	// Compute hash.
-	h := app.ComputeDocumentHash(doc)
+	return c.RequestCredentialWithOptions(ctx, doc, price, issuer, CredentialOptions{})
+}
+
+// RequestExpiringCredential requests a credential that expires at `expiry`.
+// The expiry is covered by the issuer's signature. A zero expiry requests a
+// credential that does not expire.
+func (c *Connection) RequestExpiringCredential(
+	ctx context.Context,
+	doc []byte,
+	price channel.Bal,
+	issuer common.Address,
+	expiry time.Time,
+) (*AsyncCredential, error) {
+	return c.RequestCredentialWithOptions(ctx, doc, price, issuer, CredentialOptions{Expiry: expiry})
+}
+
+// CredentialOptions holds optional properties of a requested credential. They
+// are covered by the issuer's signature.
+type CredentialOptions struct {
+	Expiry   time.Time     // Zero if the credential does not expire.
+	Metadata *app.Metadata // Optional.
+	// Cosigners are the addresses that must cosign the credential in
+	// addition to the issuer.
+	Cosigners []common.Address
+	// BBSKey is the BBS public key of the issuer. If set, the document must
+	// hold attributes encoded by app.EncodeAttributes, and the issuer also
+	// signs them with BBS, so that they can be disclosed selectively.
+	BBSKey []byte
+	// BindHolder binds the credential to the requester by setting the holder
+	// DID of the metadata to the requester's did:ethr, if no holder DID is
+	// set. Metadata is created if there is none.
+	BindHolder bool
+}
+
+// RequestCredentialWithOptions requests a credential with the properties
+// given by `opts`.
+func (c *Connection) RequestCredentialWithOptions(
+	ctx context.Context,
+	doc []byte,
+	price channel.Bal,
+	issuer common.Address,
+	opts CredentialOptions,
+) (*AsyncCredential, error) {
+	offer := &data.Offer{
+		Issuer:    issuer,
+		DataHash:  app.ComputeDocumentHash(doc),
+		Price:     price,
+		Cosigners: opts.Cosigners,
+		Domain:    c.cfg.Domain,
+		Fee:       c.fee(price),
+	}
+	if !opts.Expiry.IsZero() {
+		if !opts.Expiry.After(c.cfg.Clock.Now()) {
+			return nil, app.ErrCredentialExpired
+		}
+		offer.Expiry = uint64(opts.Expiry.Unix())
+	}
+	if len(opts.BBSKey) != 0 {
+		attrs, err := app.DecodeAttributes(doc)
+		if err != nil {
+			return nil, err
+		}
+		offer.Attributes = app.AttributeScalars(attrs)
+		offer.BBSKey = opts.BBSKey
+	}
+
+	// Transfer the document out-of-band, the channel only holds its hash.
+	if err := c.SendDocument(ctx, doc); err != nil {
+		return nil, err
+	}
+	// The metadata is transferred the same way, as it is also addressed by
+	// its hash.
+	if opts.BindHolder {
+		opts.Metadata = c.bindHolder(opts.Metadata)
+	}
+	if opts.Metadata != nil {
+		if opts.Metadata.Issuer != "" {
+			if err := c.checkDID(ctx, opts.Metadata.Issuer, issuer); err != nil {
+				return nil, fmt.Errorf("checking issuer DID: %w", err)
+			}
+		}
+		if opts.Metadata.Holder != "" {
+			if err := c.checkDID(ctx, opts.Metadata.Holder, c.self()); err != nil {
+				return nil, fmt.Errorf("checking holder DID: %w", err)
+			}
+		}
+		if err := c.SendDocument(ctx, opts.Metadata.Encode()); err != nil {
+			return nil, fmt.Errorf("sending metadata: %w", err)
+		}
+		offer.MetaHash = opts.Metadata.Hash()
+	}
+
+	return c.requestCredential(ctx, offer)
+}
+
+// requestCredential requests the credential described by `offer`. The buyer
+// is set to the own channel index.
+func (c *Connection) requestCredential(ctx context.Context, offer *data.Offer) (_ *AsyncCredential, err error) {
+	h, issuer, price := offer.DataHash, offer.Issuer, offer.Price
+	// The nonce is the version of the updated state, see below. If another
+	// update comes first, the trace of the issuer differs.
+	ctx, span := c.startSpan(ctx, "RequestCredential", h, c.State().Version+1)
+	defer func() { trace.EndWithError(span, err) }()
+
+	if c.Disputed() {
+		return nil, ErrDisputeRegistered
+	} else if err := c.acceptsRequests(c.State()); err != nil {
+		return nil, err
+	}
 
 	callback, err := c.sigs.RegisterCallback(h, issuer)
 	if err != nil {
@@ -111,46 +360,103 @@ func (c *Connection) RequestCredential(
 	}
 
 	// Perform request.
+	// The nonce is the version of the updated state, which is only known
+	// once the update is made.
 	err = c.UpdateBy(ctx, func(s *channel.State) error {
-		s.Data = &data.Offer{
-			Issuer:   issuer,
-			DataHash: h,
-			Price:    price,
-			Buyer:    uint16(c.Idx()),
-		}
+		o := offer.Clone().(*data.Offer)
+		o.Buyer = uint16(c.Idx())
+		o.Nonce = s.Version + 1
+		s.Data = o
 		return nil
 	})
 	if err != nil {
+		c.sigs.Unregister(h, issuer)
+		if ctx.Err() != nil {
+			c.cancelExpired(h)
+		}
+		err = WrapPerunError(err)
+		c.notifyIfRejected(err)
 		return nil, fmt.Errorf("updating channel: %w", err)
 	}
+	c.notify(&CredentialRequested{
+		EventHeader: c.header(),
+		Issuer:      issuer,
+		DocHash:     h,
+		Price:       price,
+	})
+
+	return &AsyncCredential{callback, c, h}, nil
+}
 
-	return &AsyncCredential{callback}, nil
+// PendingRequests returns the number of credential requests that have not
+// been picked up by NextCredentialRequest yet.
+func (c *Connection) PendingRequests() int {
+	return int(atomic.LoadInt32(&c.pending)) + c.requests.len()
 }
 
-func (c *Connection) addCredentialRequest(offer *data.Offer) chan CredentialRequestResponse {
-	response := make(chan CredentialRequestResponse)
-	c.credRequests <- &CredentialRequest{
-		resp:  response,
-		offer: offer,
-		conn:  c,
+// addCredentialRequest queues the request for the application.
+func (c *Connection) addCredentialRequest(offer *data.Offer, d *decision) *CredentialRequest {
+	req := &CredentialRequest{
+		resp:     make(chan CredentialRequestResponse),
+		offer:    offer,
+		conn:     c,
+		decision: d,
+		received: c.cfg.Clock.Now(),
 	}
-	return response
+	c.requests.push(req)
+	return req
 }
 
 func (c *Connection) NextCredentialRequest(ctx context.Context) (*CredentialRequest, error) {
-	select {
-	case r := <-c.credRequests:
-		return r, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	return c.requests.next(ctx, nil)
+}
+
+func (c *Connection) addCounterOffer(counter *data.CounterOffer) {
+	c.sigs.Push(counter.DataHash, counter.Issuer, &CounterOfferProposal{
+		conn:    c,
+		counter: counter,
+	})
+}
+
+func (c *Connection) addSignature(cert *data.Cert, offer *data.Offer, responder *client.UpdateResponder) {
+	cosigs := make([][]byte, len(cert.CoSignatures))
+	for i := range cert.CoSignatures {
+		cosigs[i] = append([]byte(nil), cert.CoSignatures[i][:]...)
 	}
+	c.sigs.Push(offer.DataHash, offer.Issuer, &CredentialProposal{
+		UpdateResponder: responder,
+		Signature:       append([]byte(nil), cert.Signature[:]...),
+		CoSignatures:    cosigs,
+		BBSSignature:    append([]byte(nil), cert.BBSSignature...),
+		conn:            c,
+		offer:           offer,
+	})
 }
 
-func (c *Connection) addSignature(sig app.Signature, h app.Hash, issuer common.Address, responder *client.UpdateResponder) {
-	c.sigs.Push(sig, h, issuer, responder)
+// credentialSigner makes the issuer signature on the credential requested by
+// an offer.
+type credentialSigner func(offer *data.Offer) ([data.SigLen]byte, error)
+
+// accountSigner signs credentials with `acc`, which must be the account of
+// the issuer.
+func accountSigner(acc app.Account) credentialSigner {
+	return func(offer *data.Offer) ([data.SigLen]byte, error) {
+		if addr := app.AccountAddress(acc); offer.Issuer != addr {
+			return [data.SigLen]byte{}, fmt.Errorf("unequal addresses: got %v, expected %v", addr, offer.Issuer)
+		}
+		return app.SignHash(acc, app.OfferHash(offer))
+	}
 }
 
-func (c *Connection) issueCredential(ctx context.Context, offer *data.Offer, acc *ewallet.Account) error {
+// issueCredential issues the credential requested by `offer`, signed by `sign`
+// and cosigned by the cosigners of the offer with signatures `cosigs`. If the
+// offer requests a BBS signature, it must be given as `bbsSig`.
+func (c *Connection) issueCredential(ctx context.Context, offer *data.Offer, sign credentialSigner, cosigs [][]byte, bbsSig []byte) error {
+	suiteSig, err := c.signSuite(offer)
+	if err != nil {
+		return err
+	}
+
 	up := func(s *channel.State) error {
 		// Check inputs against current state.
 		curOffer, ok := s.Data.(*data.Offer)
@@ -158,46 +464,94 @@ func (c *Connection) issueCredential(ctx context.Context, offer *data.Offer, acc
 			return fmt.Errorf("data has wrong type: %T", s.Data)
 		} else if !curOffer.Equal(offer) {
 			return fmt.Errorf("unequal offers: got %v, expected %v", curOffer, offer)
-		} else if addr := acc.Account.Address; offer.Issuer != addr {
-			return fmt.Errorf("unequal addresses: got %v, expected %v", addr, offer.Issuer)
 		}
 
 		// Sign.
-		sig, err := app.SignHash(acc, offer.DataHash)
+		sig, err := sign(offer)
 		if err != nil {
 			return fmt.Errorf("signing hash: %w", err)
 		}
 
 		// Update state data.
-		var cert data.Cert
-		copy(cert.Signature[:], sig[:])
+		cert := data.Cert{
+			Signature:    sig,
+			CoSignatures: make([][data.SigLen]byte, len(cosigs)),
+			BBSSignature: bbsSig,
+		}
+		for i, cosig := range cosigs {
+			copy(cert.CoSignatures[i][:], cosig)
+		}
 		s.Data = &cert
 
 		// Update balances.
-		asset := s.Allocation.Assets[app.AssetIdx]
-		s.Allocation.SubFromBalance(channel.Index(offer.Buyer), asset, offer.Price)
-		s.Allocation.AddToBalance(c.Idx(), asset, offer.Price)
+		c.transferPrice(s, offer)
 
 		return nil
 	}
 
+	if err := c.updateOrForce(ctx, up, func() error { return forcible(offer, c.cfg.Clock.Now()) }); err != nil {
+		return err
+	}
+	c.recordIssued(app.OfferHash(offer))
+	c.suiteSigs.add(app.OfferHash(offer), suiteSig)
+	c.notifyIssued(offer)
+
+	return nil
+}
+
+// transferPrice transfers the price of `offer` in state `s` from the buyer to
+// us, minus the fee, which goes to the fee recipient.
+func (c *Connection) transferPrice(s *channel.State, offer *data.Offer) {
+	asset := s.Allocation.Assets[app.AssetIdx]
+	fee := offer.FeeAmount()
+	s.Allocation.SubFromBalance(channel.Index(offer.Buyer), asset, offer.Price)
+	s.Allocation.AddToBalance(c.Idx(), asset, new(big.Int).Sub(offer.Price, fee))
+	if fee.Sign() > 0 {
+		s.Allocation.AddToBalance(app.FeeRecipientIdx, asset, fee)
+	}
+}
+
+// forcible returns an error if the credential requested by `offer` cannot be
+// issued on-chain at time `now`.
+func forcible(offer *data.Offer, now time.Time) error {
+	if len(offer.BBSKey) != 0 {
+		return ErrBBSDispute
+	} else if app.Expired(offer.Expiry, now) {
+		return app.ErrCredentialExpired
+	}
+	return nil
+}
+
+// updateOrForce performs the issuing update `up`. If the peer does not
+// accept the update, it is enforced on-chain, unless `forcible` is not nil
+// and returns an error.
+func (c *Connection) updateOrForce(ctx context.Context, up func(*channel.State) error, forcible func() error) error {
+	log := c.log.WithField("phase", "issue")
 	err := c.UpdateBy(ctx, up)
 	if err != nil {
-		c.Log().Warnf("Failed to update channel off-ledger: %v", err)
-		c.Log().Warnf("Forcing update on-ledger")
+		c.notifyIfRejected(WrapPerunError(err))
+		log.Warnf("Failed to update channel off-ledger: %v", err)
+		if forcible != nil {
+			if ferr := forcible(); ferr != nil {
+				return fmt.Errorf("updating channel: %v; not forcing: %w", err, ferr)
+			}
+		}
+		log.Warnf("Forcing update on-ledger")
 
-		c.disputed.SetValue(true)
+		c.setDisputed()
+		ctx, span := c.cfg.Tracer.Start(ctx, "ForceUpdate")
+		span.SetAttribute("channel", formatID(c.ID()))
 		err := c.ForceUpdate(ctx, func(s *channel.State) {
 			err := up(s)
 			if err != nil {
-				c.Log().Warnf("Updating channel state: %v", err)
+				log.Warnf("Updating channel state: %v", err)
 			}
 		})
+		trace.EndWithError(span, err)
 		if err != nil {
 			return fmt.Errorf("forcing update: %w", err)
 		}
 	}
-
 	return nil
 }
 
@@ -207,13 +561,18 @@ func (c *Connection) TryClose(ctx context.Context, attempts int) error {
 		if err == nil {
 			return nil
 		} else {
-			c.Log().Warnf("Failed to close channel (attempt %d): %v", i, err)
+			c.log.WithField("phase", "close").Warnf("Failed to close channel (attempt %d): %v", i, err)
 		}
 	}
 	return fmt.Errorf("Failed to close channel in %d attempts", attempts)
 }
 
-func (c *Connection) Close(ctx context.Context) error {
+func (c *Connection) Close(ctx context.Context) (err error) {
+	ctx, span := c.cfg.Tracer.Start(ctx, "CloseChannel")
+	span.SetAttribute("channel", formatID(c.ID()))
+	span.SetAttribute("disputed", c.Disputed())
+	defer func() { trace.EndWithError(span, err) }()
+
 	if c.Disputed() {
 		// If there is a dispute, we wait until the channel is concludable.
 		err := c.WaitConcludadable(ctx)
@@ -228,38 +587,155 @@ func (c *Connection) Close(ctx context.Context) error {
 			return nil
 		})
 		if err != nil {
-			c.Log().Warnf("Failed to finalize channel off-ledger: %v", err)
+			c.log.WithField("phase", "close").Warnf("Failed to finalize channel off-ledger: %v", err)
 		}
 	}
 
-	err := c.Settle(ctx, false)
+	err = c.Settle(ctx, false)
 	if err != nil {
 		return fmt.Errorf("settling: %w", err)
 	}
+	c.settled(ctx)
+	return nil
+}
+
+// ForceClose closes the channel without the cooperation of the peer, e.g.,
+// if the peer vanished. The latest state is registered on the adjudicator
+// and withdrawn once the dispute timed out.
+func (c *Connection) ForceClose(ctx context.Context) (err error) {
+	ctx, span := c.cfg.Tracer.Start(ctx, "ForceCloseChannel")
+	span.SetAttribute("channel", formatID(c.ID()))
+	defer func() { trace.EndWithError(span, err) }()
+
+	c.setDisputed()
+	if err := forceSettle(ctx, c.Channel, c.cfg.Clock, c.log.WithField("phase", "close")); err != nil {
+		return fmt.Errorf("settling: %w", err)
+	}
+	c.settled(ctx)
+	return nil
+}
+
+// settled notifies that the channel was closed and anchors the credentials
+// issued in it.
+func (c *Connection) settled(ctx context.Context) {
+	c.notify(&ChannelClosed{EventHeader: c.header()})
+
+	if c.cfg.Anchor != nil {
+		if err := c.AnchorIssued(ctx); err != nil {
+			c.log.WithField("phase", "close").Warnf("Failed to anchor issued credentials: %v", err)
+		}
+	}
+}
+
+// CloseWith finalizes the channel with the final allocation `alloc`, given
+// by participant index, and settles it. The own participant may give up
+// funds to the others, e.g., as a goodwill refund, but no other balance may
+// decrease. Unlike Close, it fails if the channel cannot be finalized
+// off-ledger.
+func (c *Connection) CloseWith(ctx context.Context, alloc []channel.Bal) error {
+	if c.Disputed() {
+		return ErrDisputeRegistered
+	} else if c.State().IsFinal {
+		return fmt.Errorf("channel already final")
+	}
 
+	err := c.UpdateBy(ctx, func(s *channel.State) error {
+		bals := s.Balances[app.AssetIdx]
+		if len(alloc) != len(bals) {
+			return fmt.Errorf("wrong number of balances: got %d, expected %d", len(alloc), len(bals))
+		}
+		for i, bal := range alloc {
+			bals[i] = new(big.Int).Set(bal)
+		}
+		s.Data = &data.DefaultData{}
+		s.IsFinal = true
+		return nil
+	})
+	if err != nil {
+		err = WrapPerunError(err)
+		c.notifyIfRejected(err)
+		return fmt.Errorf("finalizing channel: %w", err)
+	}
+	return c.Close(ctx)
+}
+
+// counterOffer responds to `offer` with a counter-offer at `price`.
+func (c *Connection) counterOffer(ctx context.Context, offer *data.Offer, price channel.Bal) error {
+	err := c.UpdateBy(ctx, func(s *channel.State) error {
+		curOffer, ok := s.Data.(*data.Offer)
+		if !ok {
+			return fmt.Errorf("data has wrong type: %T", s.Data)
+		} else if !curOffer.Equal(offer) {
+			return fmt.Errorf("unequal offers: got %v, expected %v", curOffer, offer)
+		}
+
+		counter := &data.CounterOffer{Offer: *offer.Clone().(*data.Offer)}
+		counter.Price = price
+		counter.Fee = c.fee(price)
+		s.Data = counter
+		return nil
+	})
+	if err != nil {
+		err = WrapPerunError(err)
+		c.notifyIfRejected(err)
+		return fmt.Errorf("updating channel: %w", err)
+	}
+	return nil
+}
+
+// abandonRequest moves the channel from a counter-offer back to the default
+// state.
+func (c *Connection) abandonRequest(ctx context.Context) error {
+	err := c.UpdateBy(ctx, func(s *channel.State) error {
+		if _, ok := s.Data.(*data.CounterOffer); !ok {
+			return fmt.Errorf("data has wrong type: %T", s.Data)
+		}
+		s.Data = &data.DefaultData{}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("updating channel: %w", WrapPerunError(err))
+	}
 	return nil
 }
 
+func (c *Connection) notifyIssued(offer *data.Offer) {
+	c.notify(&CredentialIssued{
+		EventHeader: c.header(),
+		Issuer:      offer.Issuer,
+		DocHash:     offer.DataHash,
+		Price:       offer.Price,
+	})
+}
+
+func (c *Connection) notifyIfRejected(err error) {
+	var rejected *PeerRejectedError
+	if errors.As(err, &rejected) {
+		c.notify(&UpdateRejected{EventHeader: c.header(), Code: rejected.Code, Reason: rejected.Reason})
+	}
+}
+
 func (c *Connection) WaitConcludadable(ctx context.Context) error {
 	return waitCondition(ctx, func() bool {
 		return c.State().IsFinal || c.concludable.Value()
 	})
 }
 
+// waitCondition polls `cond` until it holds or `ctx` is done. The polling
+// interval is not a duration of the protocol, so it is not taken from the
+// clock of the connection. Otherwise, a fake clock that is not advanced
+// would stop the polling.
 func waitCondition(ctx context.Context, cond func() bool) error {
 	const tick = 500 * time.Millisecond
 
-loop:
-	for {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for !cond() {
 		select {
-		case <-time.After(tick):
-			if cond() {
-				break loop
-			}
+		case <-ticker.C:
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
-
 	return nil
 }
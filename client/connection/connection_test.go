@@ -0,0 +1,21 @@
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForcible(t *testing.T) {
+	now := time.Unix(1000, 0)
+	require.NoError(t, forcible(&data.Offer{}, now))
+	require.NoError(t, forcible(&data.Offer{Expiry: 1001}, now))
+
+	// The app cannot verify BBS signatures in a dispute, and rejects expired
+	// credentials.
+	require.ErrorIs(t, forcible(&data.Offer{BBSKey: []byte{1}}, now), ErrBBSDispute)
+	require.ErrorIs(t, forcible(&data.Offer{Expiry: 1000}, now), app.ErrCredentialExpired)
+}
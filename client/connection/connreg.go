@@ -7,8 +7,9 @@ import (
 )
 
 type Registry struct {
-	mu sync.RWMutex
-	r  map[channel.ID]*Connection
+	mu  sync.RWMutex
+	r   map[channel.ID]*Connection
+	ids []channel.ID // In the order in which the connections were added.
 }
 
 func NewRegistry() *Registry {
@@ -20,6 +21,9 @@ func NewRegistry() *Registry {
 
 func (r *Registry) Add(conn *Connection) {
 	r.mu.Lock()
+	if _, ok := r.r[conn.ID()]; !ok {
+		r.ids = append(r.ids, conn.ID())
+	}
 	r.r[conn.ID()] = conn
 	r.mu.Unlock()
 }
@@ -30,3 +34,16 @@ func (r *Registry) ForID(id channel.ID) (*Connection, bool) {
 	r.mu.RUnlock()
 	return c, ok
 }
+
+// All returns all registered connections in the order in which they were
+// added.
+func (r *Registry) All() []*Connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conns := make([]*Connection, 0, len(r.ids))
+	for _, id := range r.ids {
+		conns = append(conns, r.r[id])
+	}
+	return conns
+}
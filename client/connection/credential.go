@@ -5,48 +5,339 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/bbs"
 	"github.com/perun-network/perun-credential-payment/app/data"
-	"perun.network/go-perun/backend/ethereum/wallet/simple"
+	"github.com/perun-network/perun-credential-payment/client/policy"
+	"github.com/perun-network/perun-credential-payment/pkg/trace"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
 	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
 )
 
+// MaxIssuanceDateDeviation is the maximum deviation of the issuance date of a
+// requested credential from the current time.
+const MaxIssuanceDateDeviation = 5 * time.Minute
+
 type CredentialRequest struct {
-	resp  chan CredentialRequestResponse
-	offer *data.Offer
-	conn  *Connection
+	resp     chan CredentialRequestResponse
+	offer    *data.Offer
+	conn     *Connection
+	decision *decision
+	received time.Time
 }
 
 func (r *CredentialRequest) CheckDoc(doc []byte) error {
 	docHash := app.ComputeDocumentHash(doc)
 	if !bytes.Equal(docHash[:], r.offer.DataHash[:]) {
-		return fmt.Errorf("wrong document")
+		return ErrWrongDocument
+	} else if app.Expired(r.offer.Expiry, r.conn.cfg.Clock.Now()) {
+		return app.ErrCredentialExpired
+	} else if d := r.offer.Domain; d != (app.Hash{}) && d != r.conn.cfg.Domain {
+		return ErrWrongDomain
+	} else if err := r.checkAttributes(doc); err != nil {
+		return err
+	} else if err := r.conn.checkFee(r.offer); err != nil {
+		return err
+	}
+	return r.checkMetadata(doc)
+}
+
+// checkAttributes checks that the attributes committed to in the request are
+// the attributes of the document.
+func (r *CredentialRequest) checkAttributes(doc []byte) error {
+	if len(r.offer.BBSKey) == 0 {
+		return nil
+	}
+	attrs, err := app.DecodeAttributes(doc)
+	if err != nil {
+		return err
+	}
+	scalars := app.AttributeScalars(attrs)
+	if len(scalars) != len(r.offer.Attributes) {
+		return ErrWrongDocument
+	}
+	for i := range scalars {
+		if scalars[i] != r.offer.Attributes[i] {
+			return ErrWrongDocument
+		}
+	}
+	return nil
+}
+
+// checkMetadata checks that the metadata of the request is known, that its
+// issuance date does not deviate from the current time by more than
+// MaxIssuanceDateDeviation, that its DIDs belong to the parties, and that the
+// document matches the schema registered for the credential type. Schema
+// violations are returned as *SchemaError.
+func (r *CredentialRequest) checkMetadata(doc []byte) error {
+	meta, err := r.Metadata()
+	if err != nil {
+		return err
+	} else if meta == nil || meta.Holder == "" {
+		if r.conn.cfg.RequireHolderBinding {
+			return app.ErrNotBound
+		} else if meta == nil {
+			return nil
+		}
+	}
+
+	issuedAt := time.Unix(int64(meta.IssuedAt), 0)
+	if d := r.conn.cfg.Clock.Now().Sub(issuedAt); d > MaxIssuanceDateDeviation || d < -MaxIssuanceDateDeviation {
+		return fmt.Errorf("issuance date %v deviates from current time", issuedAt)
+	}
+	if err := r.checkDIDs(meta); err != nil {
+		return err
+	}
+	return r.conn.cfg.Schemas.Validate(meta.Type, doc)
+}
+
+// checkDIDs checks that the DIDs of the issuer and the holder in `meta`
+// control the addresses of the issuer and the requesting peer.
+func (r *CredentialRequest) checkDIDs(meta *app.Metadata) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DIDResolveTimeout)
+	defer cancel()
+	if meta.Issuer != "" {
+		if err := r.conn.checkDID(ctx, meta.Issuer, r.offer.Issuer); err != nil {
+			return fmt.Errorf("checking issuer DID: %w", err)
+		}
+	}
+	if meta.Holder != "" {
+		if err := r.conn.checkDID(ctx, meta.Holder, ethwallet.AsEthAddr(r.Peer())); err != nil {
+			return fmt.Errorf("checking holder DID: %w", err)
+		}
 	}
 	return nil
 }
 
 func (r *CredentialRequest) CheckPrice(p *big.Int) error {
 	if r.offer.Price.Cmp(p) != 0 {
-		return fmt.Errorf("wrong price")
+		return ErrWrongPrice
+	}
+	return nil
+}
+
+// Peer returns the address of the requesting peer.
+func (r *CredentialRequest) Peer() wallet.Address {
+	return r.conn.Params().Parts[r.offer.Buyer]
+}
+
+// Offer returns the requested offer.
+func (r *CredentialRequest) Offer() *data.Offer {
+	return r.offer
+}
+
+// Reject rejects the credential request.
+func (r *CredentialRequest) Reject(ctx context.Context, reason string) error {
+	return r.RejectWithCode(ctx, RejectUnspecified, reason)
+}
+
+// RejectWithCode rejects the credential request with `code`, which is
+// carried to the requester along with `reason`.
+func (r *CredentialRequest) RejectWithCode(ctx context.Context, code RejectionCode, reason string) error {
+	if err := r.decision.make(); err != nil {
+		return fmt.Errorf("rejecting credential request: %w", err)
 	}
+	errs := make(chan error)
+	r.resp <- &CredentialRequestResponseReject{ctx, encodeReason(code, reason), errs}
+	err := <-errs
+	if err != nil {
+		return fmt.Errorf("rejecting credential request: %w", err)
+	}
+	r.conn.notify(&UpdateRejected{EventHeader: r.conn.header(), Code: code, Reason: reason})
 	return nil
 }
 
-func (r *CredentialRequest) IssueCredential(ctx context.Context, acc *simple.Account) error {
+// CounterOffer responds to the request with a counter-offer at price `p`.
+// If the requester accepts or counters, the resulting offer is received as a
+// new credential request.
+func (r *CredentialRequest) CounterOffer(ctx context.Context, p *big.Int) error {
+	if err := r.decision.make(); err != nil {
+		return fmt.Errorf("accepting credential request: %w", err)
+	}
 	errs := make(chan error)
-	r.resp <- &CredentialRequestResponseAccept{ctx, errs}
+	r.resp <- &CredentialRequestResponseCounter{ctx, errs}
 	err := <-errs
 	if err != nil {
 		return fmt.Errorf("accepting credential request: %w", err)
 	}
 
+	err = r.conn.counterOffer(ctx, r.offer, p)
+	if err != nil {
+		return fmt.Errorf("making counter-offer: %w", err)
+	}
+	return nil
+}
+
+// Evaluate evaluates the request against policy `p`. The document `doc` must
+// be the requested document, it is checked against the requested hash. The
+// credential type is taken from the metadata of the request, if any.
+func (r *CredentialRequest) Evaluate(p policy.Policy, doc []byte) error {
+	if err := r.CheckDoc(doc); err != nil {
+		return err
+	}
+	req, err := r.policyRequest(r.Peer(), doc)
+	if err != nil {
+		return err
+	}
+	return p.Evaluate(req)
+}
+
+// policyRequest describes the request by `peer` for document `doc` to
+// policies.
+func (r *CredentialRequest) policyRequest(peer wallet.Address, doc []byte) (*policy.Request, error) {
+	meta, err := r.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	req := &policy.Request{
+		Peer:     peer,
+		Document: doc,
+		DocHash:  r.offer.DataHash,
+		Price:    r.offer.Price,
+		Time:     r.conn.cfg.Clock.Now(),
+	}
+	if meta != nil {
+		req.Type = meta.Type
+	}
+	return req, nil
+}
+
+func (r *CredentialRequest) IssueCredential(ctx context.Context, acc app.Account) error {
+	return r.IssueCoSignedCredential(ctx, acc, nil)
+}
+
+// IssueBBSCredential issues a credential that requests a BBS signature, which
+// is made with `key`. The signatures of the cosigners are given as in
+// IssueCoSignedCredential.
+func (r *CredentialRequest) IssueBBSCredential(ctx context.Context, acc app.Account, key *bbs.SecretKey, cosigs [][]byte) error {
+	if !bytes.Equal(key.PublicKey(), r.offer.BBSKey) {
+		return fmt.Errorf("request is not for BBS key")
+	}
+	sig, err := key.Sign(app.BBSHeader(r.offer), r.attributes())
+	if err != nil {
+		return fmt.Errorf("signing attributes: %w", err)
+	}
+	return r.issue(ctx, acc, accountSigner(acc), cosigs, sig)
+}
+
+func (r *CredentialRequest) attributes() []bbs.Scalar {
+	s := make([]bbs.Scalar, len(r.offer.Attributes))
+	for i, a := range r.offer.Attributes {
+		s[i] = a
+	}
+	return s
+}
+
+// checkCoSignatures checks that `cosigs` are valid signatures of the
+// cosigners. It is called before accepting the request, as the request cannot
+// be declined afterwards.
+func (r *CredentialRequest) checkCoSignatures(cosigs [][]byte) error {
+	if len(cosigs) != len(r.offer.Cosigners) {
+		return fmt.Errorf("expected %d cosignatures, got %d", len(r.offer.Cosigners), len(cosigs))
+	}
+	h := r.SigningHash()
+	for i, cosigner := range r.offer.Cosigners {
+		var sig [data.SigLen]byte
+		if len(cosigs[i]) != len(sig) {
+			return fmt.Errorf("cosignature %d: invalid length", i)
+		}
+		copy(sig[:], cosigs[i])
+		if err := app.VerifySig(sig, h, cosigner); err != nil {
+			return fmt.Errorf("verifying cosignature %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// SigningHash returns the hash that the issuer and the cosigners sign.
+func (r *CredentialRequest) SigningHash() app.Hash {
+	return app.OfferHash(r.offer)
+}
+
+// IssueCoSignedCredential issues a credential that requires cosignatures.
+// The signatures of the cosigners on SigningHash must be given in the order of
+// Offer().Cosigners.
+func (r *CredentialRequest) IssueCoSignedCredential(ctx context.Context, acc app.Account, cosigs [][]byte) error {
+	if len(r.offer.BBSKey) != 0 {
+		return fmt.Errorf("request requires a BBS signature")
+	}
+	return r.issue(ctx, acc, accountSigner(acc), cosigs, nil)
+}
+
+// IssueContractSignedCredential issues a credential whose issuer is a
+// contract account, e.g., a Gnosis Safe. Signature `sig` on SigningHash must
+// be accepted by the account according to EIP-1271. The cosignatures are
+// given as in IssueCoSignedCredential, and `acc` is the channel account,
+// which signs the receipt.
+func (r *CredentialRequest) IssueContractSignedCredential(ctx context.Context, acc app.Account, sig []byte, cosigs [][]byte) error {
+	if len(r.offer.BBSKey) != 0 {
+		return fmt.Errorf("request requires a BBS signature")
+	} else if r.conn.cfg.ContractSigs == nil {
+		return ErrNoContractSigs
+	} else if len(sig) != data.SigLen {
+		return app.ErrInvalidSignature
+	}
+	if err := r.conn.cfg.ContractSigs.Verify(ctx, sig, r.SigningHash(), r.offer.Issuer); err != nil {
+		return fmt.Errorf("verifying contract signature: %w", err)
+	}
+
+	var fixed [data.SigLen]byte
+	copy(fixed[:], sig)
+	sign := func(*data.Offer) ([data.SigLen]byte, error) { return fixed, nil }
+	return r.issue(ctx, acc, sign, cosigs, nil)
+}
+
+func (r *CredentialRequest) issue(ctx context.Context, acc app.Account, sign credentialSigner, cosigs [][]byte, bbsSig []byte) (err error) {
+	ctx, span := r.conn.startSpan(ctx, "IssueCredential", r.offer.DataHash, r.offer.Nonce)
+	defer func() { trace.EndWithError(span, err) }()
+
+	// The app of the clients does not check the expiry, as the validity of a
+	// transition must not depend on the time at which it is checked. Only
+	// the contract checks it in disputes.
+	if app.Expired(r.offer.Expiry, r.conn.cfg.Clock.Now()) {
+		return app.ErrCredentialExpired
+	}
+	if err := r.checkCoSignatures(cosigs); err != nil {
+		return err
+	}
+	if r.conn.cfg.ProofOfPossession {
+		if err := r.ChallengePossession(ctx); err != nil {
+			return fmt.Errorf("checking proof of possession: %w", err)
+		}
+	}
+
+	start := r.conn.cfg.Clock.Now()
+	if err := r.accept(ctx); err != nil {
+		return err
+	}
+
 	// Issue credential.
-	err = r.conn.issueCredential(ctx, r.offer, acc)
+	err = r.conn.issueCredential(ctx, r.offer, sign, cosigs, bbsSig)
 	if err != nil {
 		return fmt.Errorf("issueing credential: %w", err)
 	}
+	r.conn.cfg.Metrics.credentialIssued(r.conn.cfg.Clock.Now().Sub(start))
+
+	if err := r.conn.exchangeReceipt(ctx, r.offer, acc, r.received); err != nil {
+		r.conn.log.WithField("phase", "issue").Warnf("Exchanging receipt: %v", err)
+	}
+	return nil
+}
 
+// accept accepts the offer into the channel state, which commits us to
+// issuing the credential.
+func (r *CredentialRequest) accept(ctx context.Context) error {
+	if err := r.decision.make(); err != nil {
+		return fmt.Errorf("accepting credential request: %w", err)
+	}
+	errs := make(chan error)
+	r.resp <- &CredentialRequestResponseAccept{ctx, errs}
+	if err := <-errs; err != nil {
+		return fmt.Errorf("accepting credential request: %w", err)
+	}
 	return nil
 }
 
@@ -60,6 +351,17 @@ type (
 		ctx  context.Context
 		errs chan error
 	}
+
+	CredentialRequestResponseCounter struct {
+		ctx  context.Context
+		errs chan error
+	}
+
+	CredentialRequestResponseReject struct {
+		ctx    context.Context
+		reason string
+		errs   chan error
+	}
 )
 
 func (r *CredentialRequestResponseAccept) Context() context.Context {
@@ -70,20 +372,209 @@ func (r *CredentialRequestResponseAccept) Result() chan error {
 	return r.errs
 }
 
+func (r *CredentialRequestResponseCounter) Context() context.Context {
+	return r.ctx
+}
+
+func (r *CredentialRequestResponseCounter) Result() chan error {
+	return r.errs
+}
+
+func (r *CredentialRequestResponseReject) Context() context.Context {
+	return r.ctx
+}
+
+func (r *CredentialRequestResponseReject) Result() chan error {
+	return r.errs
+}
+
 type AsyncCredential struct {
 	sigRegCallback
+	conn *Connection
+	hash app.Hash
 }
 
+// Cancel withdraws the request if the issuer responded with a counter-offer
+// that was not answered yet. Otherwise, the issuer already accepted the
+// request and ErrRequestDecided is returned.
+func (c *AsyncCredential) Cancel(ctx context.Context) error {
+	return c.conn.CancelRequest(ctx, c.hash)
+}
+
+// Await waits for the credential. If the issuer responds with a
+// counter-offer, ErrCounterOffered is returned and the counter-offer must be
+// obtained via AwaitResponse.
 func (c *AsyncCredential) Await(ctx context.Context) (*CredentialProposal, error) {
+	resp, err := c.AwaitResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prop, ok := resp.(*CredentialProposal)
+	if !ok {
+		return nil, ErrCounterOffered
+	}
+	return prop, nil
+}
+
+// AwaitResponse waits for the response of the issuer, which is either a
+// *CredentialProposal or a *CounterOfferProposal.
+func (c *AsyncCredential) AwaitResponse(ctx context.Context) (CredentialResponse, error) {
 	select {
-	case prop := <-c.sigRegCallback:
-		return prop, nil
+	case resp := <-c.sigRegCallback:
+		return resp, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 }
 
+// CredentialResponse is the response of the issuer to a credential request.
+type CredentialResponse interface {
+	isCredentialResponse()
+}
+
+func (*CredentialProposal) isCredentialResponse()   {}
+func (*CounterOfferProposal) isCredentialResponse() {}
+
+// CounterOfferProposal is a counter-offer made by the issuer. The requester
+// can accept it, counter it, or abandon the request.
+type CounterOfferProposal struct {
+	conn    *Connection
+	counter *data.CounterOffer
+}
+
+// Price returns the price demanded by the issuer.
+func (p *CounterOfferProposal) Price() *big.Int {
+	return new(big.Int).Set(p.counter.Price)
+}
+
+// Accept accepts the counter-offer by requesting the credential at the
+// demanded price.
+func (p *CounterOfferProposal) Accept(ctx context.Context) (*AsyncCredential, error) {
+	return p.Counter(ctx, p.counter.Price)
+}
+
+// Counter requests the credential at price `price` instead.
+func (p *CounterOfferProposal) Counter(ctx context.Context, price *big.Int) (*AsyncCredential, error) {
+	offer := p.counter.Offer.Clone().(*data.Offer)
+	offer.Price = price
+	offer.Fee = p.conn.fee(price)
+	return p.conn.requestCredential(ctx, offer)
+}
+
+// Abandon abandons the request.
+func (p *CounterOfferProposal) Abandon(ctx context.Context) error {
+	return p.conn.abandonRequest(ctx)
+}
+
 type CredentialProposal struct {
 	*client.UpdateResponder
-	Signature []byte
+	Signature    []byte
+	CoSignatures [][]byte
+	BBSSignature []byte // Empty if no BBS signature was requested.
+	conn         *Connection
+	offer        *data.Offer
+}
+
+// Metadata returns the metadata of the requested credential, or nil if there
+// is none. The metadata is checked against the requested hash.
+func (r *CredentialRequest) Metadata() (*app.Metadata, error) {
+	if r.offer.MetaHash == (app.Hash{}) {
+		return nil, nil
+	}
+	enc, ok := r.conn.docs.Get(r.offer.MetaHash)
+	if !ok {
+		return nil, ErrMetadataUnknown
+	}
+	return app.DecodeMetadata(enc)
+}
+
+// Expiry returns the expiry of the credential as Unix time, or zero if it does
+// not expire.
+func (p *CredentialProposal) Expiry() uint64 {
+	return p.offer.Expiry
+}
+
+// Domain returns the EIP-712 domain separator of the credential signature, or
+// zero if the raw credential hash is signed.
+func (p *CredentialProposal) Domain() app.Hash {
+	return p.offer.Domain
+}
+
+// BBSCredential returns the BBS credential on the attributes encoded in `doc`,
+// which must be the requested document.
+func (p *CredentialProposal) BBSCredential(doc []byte) (*app.BBSCredential, error) {
+	if len(p.offer.BBSKey) == 0 {
+		return nil, fmt.Errorf("no BBS signature requested")
+	} else if app.ComputeDocumentHash(doc) != p.offer.DataHash {
+		return nil, ErrWrongDocument
+	}
+	attrs, err := app.DecodeAttributes(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &app.BBSCredential{
+		Attributes: attrs,
+		Expiry:     p.offer.Expiry,
+		Domain:     p.offer.Domain,
+		PublicKey:  p.offer.BBSKey,
+		Signature:  p.BBSSignature,
+	}, nil
+}
+
+// OnChain returns whether the credential was issued on-chain, in which case
+// the payment was already enforced by the adjudicator.
+func (p *CredentialProposal) OnChain() bool {
+	return p.UpdateResponder == nil
+}
+
+// Accept accepts the channel update issuing the credential, thereby
+// completing the payment. It fails with app.ErrCredentialExpired if the
+// credential expired in the meantime, in which case the update should be
+// rejected.
+func (p *CredentialProposal) Accept(ctx context.Context) (err error) {
+	ctx, span := p.conn.startSpan(ctx, "AcceptCredential", p.offer.DataHash, p.offer.Nonce)
+	defer func() { trace.EndWithError(span, err) }()
+
+	if p.OnChain() {
+		p.conn.notifyIssued(p.offer)
+		return nil
+	} else if app.Expired(p.offer.Expiry, p.conn.cfg.Clock.Now()) {
+		return app.ErrCredentialExpired
+	}
+
+	// Expect the receipt before accepting, as the issuer sends it right
+	// after.
+	id := app.OfferHash(p.offer)
+	p.conn.receipts.expect(id, p.offer)
+	err = p.UpdateResponder.Accept(ctx)
+	if err != nil {
+		p.conn.receipts.take(id)
+		return err
+	}
+	p.conn.notifyIssued(p.offer)
+	return nil
+}
+
+// Reject rejects the channel update issuing the credential.
+func (p *CredentialProposal) Reject(ctx context.Context, reason string) error {
+	return p.RejectWithCode(ctx, RejectUnspecified, reason)
+}
+
+// RejectWithCode rejects the channel update issuing the credential with
+// `code`, which is carried to the issuer along with `reason`.
+func (p *CredentialProposal) RejectWithCode(ctx context.Context, code RejectionCode, reason string) (err error) {
+	ctx, span := p.conn.startSpan(ctx, "RejectCredential", p.offer.DataHash, p.offer.Nonce)
+	defer func() { trace.EndWithError(span, err) }()
+
+	if p.OnChain() {
+		return ErrIssuedOnChain
+	}
+
+	err = p.UpdateResponder.Reject(ctx, encodeReason(code, reason))
+	if err != nil {
+		return err
+	}
+	p.conn.notify(&UpdateRejected{EventHeader: p.conn.header(), Code: code, Reason: reason})
+	return nil
 }
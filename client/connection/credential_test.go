@@ -0,0 +1,44 @@
+package connection
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client/policy"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+func TestPolicyRequest(t *testing.T) {
+	docs := newPeerDocuments()
+	now := time.Unix(1000, 0)
+	peer := ethwallet.AsWalletAddr(common.Address{1})
+	minPrice := policy.MinPrice(map[string]*big.Int{"Diploma": big.NewInt(10)}, nil)
+
+	// The type of the credential is taken from the metadata, so that per-type
+	// rules apply, and the time from the clock of the connection.
+	r := newRequest(docs, "Diploma", 10)
+	r.conn.cfg.Clock = clock.NewFake(now)
+	req, err := r.policyRequest(peer, []byte("doc"))
+	require.NoError(t, err)
+	require.Equal(t, "Diploma", req.Type)
+	require.Equal(t, now, req.Time)
+	require.Same(t, peer, req.Peer)
+	require.NoError(t, minPrice.Evaluate(req))
+
+	r = newRequest(docs, "Diploma", 9)
+	r.conn.cfg.Clock = clock.NewFake(now)
+	req, err = r.policyRequest(peer, []byte("doc"))
+	require.NoError(t, err)
+	require.ErrorIs(t, minPrice.Evaluate(req), policy.ErrDenied)
+
+	// Requests with unknown metadata are not evaluated.
+	r = newRequest(newPeerDocuments(), "Diploma", 10)
+	r.offer.MetaHash[0]++
+	r.conn.cfg.Clock = clock.NewFake(now)
+	_, err = r.policyRequest(peer, []byte("doc"))
+	require.ErrorIs(t, err, ErrMetadataUnknown)
+}
@@ -0,0 +1,30 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DIDResolveTimeout bounds the resolution of the DIDs of a credential
+// request.
+const DIDResolveTimeout = 10 * time.Second
+
+var ErrWrongDID = errors.New("DID does not control address")
+
+// checkDID checks that `did` resolves to a document that controls `addr`.
+func (c *Connection) checkDID(ctx context.Context, did string, addr common.Address) error {
+	if c.cfg.DIDs == nil {
+		return fmt.Errorf("no DID resolver")
+	}
+	doc, err := c.cfg.DIDs.Resolve(ctx, did)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", did, err)
+	} else if !doc.Controls(addr) {
+		return fmt.Errorf("%w: %s", ErrWrongDID, did)
+	}
+	return nil
+}
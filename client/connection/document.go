@@ -0,0 +1,221 @@
+package connection
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+const (
+	// MsgKindDocument is the message kind of out-of-band document transfers.
+	MsgKindDocument = "document"
+	// MsgKindDocumentRef is the message kind of document references into a
+	// ContentStore.
+	MsgKindDocumentRef = "document_ref"
+)
+
+// ContentStoreThreshold is the document size starting from which documents
+// are transferred via the content store, if one is configured.
+const ContentStoreThreshold = 4 << 10
+
+// ContentStore is a content-addressed store, e.g., IPFS.
+type ContentStore interface {
+	// Put stores `data` and returns a reference to it.
+	Put(ctx context.Context, data []byte) (ref string, err error)
+	// Get returns the data referenced by `ref`.
+	Get(ctx context.Context, ref string) ([]byte, error)
+}
+
+// DefaultDocumentStoreSize is the default number of documents kept per peer
+// by a DocumentStore. It suffices for the documents of a batch request and
+// the metadata of as many requests.
+const DefaultDocumentStoreSize = 2 * data.MaxBatchSize
+
+// DocumentStore holds documents received out-of-band, per peer and indexed
+// by their hash. If the documents of a peer exceed the size of the store, the
+// oldest document of the peer is evicted, so that a peer cannot evict the
+// documents of others.
+type DocumentStore struct {
+	mu    sync.Mutex
+	peers map[common.Address]*PeerDocuments
+	size  int
+}
+
+// NewDocumentStore creates a store holding up to `size` documents per peer.
+func NewDocumentStore(size int) *DocumentStore {
+	return &DocumentStore{
+		peers: make(map[common.Address]*PeerDocuments),
+		size:  size,
+	}
+}
+
+// Peer returns the documents received from `peer`.
+func (s *DocumentStore) Peer(peer wire.Address) *PeerDocuments {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addr := ethwallet.AsEthAddr(peer)
+	d, ok := s.peers[addr]
+	if !ok {
+		d = &PeerDocuments{docs: make(map[app.Hash][]byte), size: s.size}
+		s.peers[addr] = d
+	}
+	return d
+}
+
+// PeerDocuments are the documents received from a peer.
+type PeerDocuments struct {
+	mu    sync.Mutex
+	docs  map[app.Hash][]byte
+	order []app.Hash
+	size  int
+}
+
+// Put adds document `doc` to the store and returns its hash.
+func (s *PeerDocuments) Put(doc []byte) app.Hash {
+	h := app.ComputeDocumentHash(doc)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.docs[h]; ok {
+		return h
+	}
+	if len(s.order) >= s.size {
+		delete(s.docs, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.docs[h] = doc
+	s.order = append(s.order, h)
+	return h
+}
+
+// Get returns the document with hash `h`, if it is known. It can be used as
+// a DocumentResolver.
+func (s *PeerDocuments) Get(h app.Hash) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[h]
+	return doc, ok
+}
+
+// Delete removes the document with hash `h`.
+func (s *PeerDocuments) Delete(h app.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.docs[h]; !ok {
+		return
+	}
+	delete(s.docs, h)
+	for i, _h := range s.order {
+		if _h == h {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// HandleDocuments stores documents received via `m` in store `s`. Documents
+// are encrypted to `key`. If `content` is not nil, documents referenced by
+// peers are fetched from it.
+//
+// Documents are sent ahead of the credential request that refers to them.
+// They are only accepted from peers that have a channel in `reg` in which
+// they may request credentials, and are only resolved for the requests of
+// the peer that sent them.
+func HandleDocuments(m *message.Messenger, s *DocumentStore, reg *Registry, key *ecdsa.PrivateKey, content ContentStore) {
+	m.Handle(MsgKindDocument, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		if err := reg.acceptsDocuments(peer); err != nil {
+			return nil, err
+		}
+		var ct []byte
+		if err := json.Unmarshal(body, &ct); err != nil {
+			return nil, fmt.Errorf("decoding document: %w", err)
+		}
+		doc, err := decrypt(key, ct)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting document: %w", err)
+		}
+		return s.Peer(peer).Put(doc), nil
+	})
+
+	if content == nil {
+		return
+	}
+	m.Handle(MsgKindDocumentRef, func(ctx context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		if err := reg.acceptsDocuments(peer); err != nil {
+			return nil, err
+		}
+		var ref string
+		if err := json.Unmarshal(body, &ref); err != nil {
+			return nil, fmt.Errorf("decoding document reference: %w", err)
+		}
+		ct, err := content.Get(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("fetching document %s: %w", ref, err)
+		}
+		doc, err := decrypt(key, ct)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting document: %w", err)
+		}
+		return s.Peer(peer).Put(doc), nil
+	})
+}
+
+// acceptsDocuments returns nil if `peer` has a channel in which it may
+// request credentials, and ErrUnexpectedDocument otherwise.
+func (r *Registry) acceptsDocuments(peer wire.Address) error {
+	for _, c := range r.All() {
+		if c.peer().Equals(peer) && !c.Disputed() && !c.concluded.Value() && c.acceptsRequests(c.State()) == nil {
+			return nil
+		}
+	}
+	return ErrUnexpectedDocument
+}
+
+// SendDocument transfers document `doc` to the peer of the connection. The
+// document is encrypted to the peer's key. Large documents are put into the
+// content store, if one is configured, and only referenced in the message.
+// The channel state only commits to the hash of the document.
+func (c *Connection) SendDocument(ctx context.Context, doc []byte) error {
+	ct, err := c.encryptToPeer(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("encrypting document: %w", err)
+	}
+
+	kind, body := MsgKindDocument, interface{}(ct)
+	if c.cfg.Content != nil && len(doc) >= ContentStoreThreshold {
+		ref, err := c.cfg.Content.Put(ctx, ct)
+		if err != nil {
+			return fmt.Errorf("storing document: %w", err)
+		}
+		kind, body = MsgKindDocumentRef, ref
+	}
+
+	var h app.Hash
+	err = c.cfg.Messenger.Request(ctx, c.peer(), kind, body, &h)
+	if err != nil {
+		return fmt.Errorf("sending document: %w", err)
+	} else if h != app.ComputeDocumentHash(doc) {
+		return errors.New("sending document: hash mismatch")
+	}
+	return nil
+}
+
+// Document returns the requested document if it has been received from the
+// requesting peer.
+func (r *CredentialRequest) Document() ([]byte, error) {
+	doc, ok := r.conn.docs.Get(r.offer.DataHash)
+	if !ok {
+		return nil, ErrDocumentUnknown
+	}
+	return doc, nil
+}
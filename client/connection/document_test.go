@@ -0,0 +1,44 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+func TestDocumentStore(t *testing.T) {
+	alice := ethwallet.AsWalletAddr(common.Address{1})
+	bob := ethwallet.AsWalletAddr(common.Address{2})
+	s := NewDocumentStore(2)
+	require.Same(t, s.Peer(alice), s.Peer(ethwallet.AsWalletAddr(common.Address{1})), "same peer")
+
+	h := s.Peer(alice).Put([]byte("alice"))
+	_, ok := s.Peer(bob).Get(h)
+	require.False(t, ok, "document of other peer")
+
+	// A peer flooding the store only evicts its own documents.
+	var last app.Hash
+	for _, doc := range []string{"a", "b", "c"} {
+		last = s.Peer(bob).Put([]byte(doc))
+	}
+	doc, ok := s.Peer(alice).Get(h)
+	require.True(t, ok)
+	require.Equal(t, []byte("alice"), doc)
+	_, ok = s.Peer(bob).Get(app.ComputeDocumentHash([]byte("a")))
+	require.False(t, ok, "oldest document evicted")
+	_, ok = s.Peer(bob).Get(last)
+	require.True(t, ok)
+
+	s.Peer(alice).Delete(h)
+	_, ok = s.Peer(alice).Get(h)
+	require.False(t, ok)
+}
+
+func TestAcceptsDocuments(t *testing.T) {
+	// Peers without a channel cannot push documents.
+	err := NewRegistry().acceptsDocuments(ethwallet.AsWalletAddr(common.Address{1}))
+	require.ErrorIs(t, err, ErrUnexpectedDocument)
+}
@@ -0,0 +1,40 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"perun.network/go-perun/channel"
+)
+
+// Drain stops the channel from taking new requests and waits until the
+// requests in progress are answered, e.g., before the client shuts down. New
+// requests of either participant are rejected with ErrShuttingDown, while
+// counter-offers can still be answered. The channel keeps taking no new
+// requests if `ctx` is done before.
+func (c *Connection) Drain(ctx context.Context) error {
+	c.draining.SetValue(true)
+	if err := waitCondition(ctx, c.idle); err != nil {
+		return fmt.Errorf("waiting for requests in progress: %w", err)
+	}
+	return nil
+}
+
+// Draining returns whether the channel is being drained.
+func (c *Connection) Draining() bool {
+	return c.draining.Value()
+}
+
+// acceptsRequests returns an error if the channel takes no new requests
+// following state `cur`.
+func (c *Connection) acceptsRequests(cur *channel.State) error {
+	if c.Migrating() {
+		return ErrMigrating
+	}
+	// A request answering a counter-offer finishes the exchange in progress.
+	if _, ok := cur.Data.(*data.CounterOffer); c.Draining() && !ok {
+		return ErrShuttingDown
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package connection
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindPublicKey is the message kind of public key requests.
+const MsgKindPublicKey = "pubkey"
+
+// HandlePublicKeyRequests answers public key requests with the public key of
+// `key`. Peers use it to encrypt messages to us.
+func HandlePublicKeyRequests(m *message.Messenger, key *ecdsa.PrivateKey) {
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+	m.Handle(MsgKindPublicKey, func(context.Context, wire.Address, json.RawMessage) (interface{}, error) {
+		return pub, nil
+	})
+}
+
+// RejectDocuments answers public key requests and documents with
+// ErrNoDecryption, e.g., if our key is held in a hardware security module,
+// which does not decrypt. Peers then fail to send documents to us right
+// away.
+func RejectDocuments(m *message.Messenger) {
+	reject := func(context.Context, wire.Address, json.RawMessage) (interface{}, error) {
+		return nil, ErrNoDecryption
+	}
+	for _, kind := range []string{MsgKindPublicKey, MsgKindDocument, MsgKindDocumentRef} {
+		m.Handle(kind, reject)
+	}
+}
+
+// peerKeyCache caches the public key of the peer of a connection.
+type peerKeyCache struct {
+	mu  sync.Mutex
+	key *ecdsa.PublicKey
+}
+
+// peerPublicKey returns the public key of the channel peer. The key is
+// requested from the peer and checked against the peer's address, which is
+// derived from it.
+func (c *Connection) peerPublicKey(ctx context.Context) (*ecdsa.PublicKey, error) {
+	c.peerKey.mu.Lock()
+	defer c.peerKey.mu.Unlock()
+	if c.peerKey.key != nil {
+		return c.peerKey.key, nil
+	}
+
+	var b []byte
+	if err := c.cfg.Messenger.Request(ctx, c.peer(), MsgKindPublicKey, nil, &b); err != nil {
+		return nil, fmt.Errorf("requesting public key: %w", err)
+	}
+	pub, err := crypto.UnmarshalPubkey(b)
+	if err != nil {
+		return nil, fmt.Errorf("decoding public key: %w", err)
+	} else if crypto.PubkeyToAddress(*pub) != ethwallet.AsEthAddr(c.peer()) {
+		return nil, errors.New("public key does not match peer address")
+	}
+	c.peerKey.key = pub
+	return pub, nil
+}
+
+// encryptToPeer encrypts `msg` to the public key of the channel peer.
+func (c *Connection) encryptToPeer(ctx context.Context, msg []byte) ([]byte, error) {
+	pub, err := c.peerPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(pub), msg, nil, nil)
+}
+
+// decrypt decrypts a message that was encrypted to `key`.
+func decrypt(key *ecdsa.PrivateKey, ct []byte) ([]byte, error) {
+	return ecies.ImportECDSA(key).Decrypt(ct, nil, nil)
+}
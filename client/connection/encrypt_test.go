@@ -0,0 +1,33 @@
+package connection
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecrypt(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	ct, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(&key.PublicKey), doc, nil, nil)
+	require.NoError(t, err)
+	require.NotContains(t, string(ct), string(doc), "plaintext in ciphertext")
+
+	got, err := decrypt(key, ct)
+	require.NoError(t, err)
+	require.Equal(t, doc, got)
+
+	// Only the key the document is encrypted to decrypts it.
+	_, err = decrypt(other, ct)
+	require.Error(t, err, "other key")
+	ct[len(ct)-1] ^= 1
+	_, err = decrypt(key, ct)
+	require.Error(t, err, "tampered ciphertext")
+}
@@ -0,0 +1,74 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+)
+
+var (
+	ErrWrongDocument      = errors.New("wrong document")
+	ErrWrongPrice         = errors.New("wrong price")
+	ErrDisputeRegistered  = errors.New("dispute registered")
+	ErrChannelClosed      = errors.New("channel closed")
+	ErrCounterOffered     = errors.New("counter-offered")
+	ErrDocumentUnknown    = errors.New("document unknown")
+	ErrMetadataUnknown    = errors.New("metadata unknown")
+	ErrUnexpectedDocument = errors.New("unexpected document")
+	ErrWrongDomain        = errors.New("wrong signature domain")
+	ErrNoContractSigs     = errors.New("contract signatures not enabled")
+	ErrMigrating          = errors.New("channel is being migrated")
+	ErrShuttingDown       = errors.New("client is shutting down")
+	ErrIssuedOnChain      = errors.New("credential issued on-chain")
+	ErrRequestCancelled   = errors.New("request cancelled")
+	ErrRequestDecided     = errors.New("request already decided")
+	ErrNoDecryption       = errors.New("documents cannot be decrypted")
+	ErrNoSuiteSignature   = errors.New("no suite signature")
+	ErrBBSDispute         = errors.New("BBS credentials cannot be issued on-chain")
+)
+
+type (
+	// PeerRejectedError indicates that the peer rejected a channel proposal or
+	// a channel update.
+	PeerRejectedError struct {
+		Code   RejectionCode // RejectUnspecified if the peer gave none.
+		Reason string
+	}
+
+	// FundingTimeoutError indicates that the channel was not funded in time.
+	// Reclaimed reports whether the own deposit was withdrawn again.
+	FundingTimeoutError struct {
+		Err       error
+		Reclaimed bool
+	}
+)
+
+func (e *PeerRejectedError) Error() string {
+	if e.Code != RejectUnspecified {
+		return fmt.Sprintf("peer rejected (%s): %s", e.Code, e.Reason)
+	}
+	return fmt.Sprintf("peer rejected: %s", e.Reason)
+}
+
+func (e *FundingTimeoutError) Error() string {
+	return fmt.Sprintf("funding timeout: %v", e.Err)
+}
+
+func (e *FundingTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// WrapPerunError translates errors returned by go-perun into the error types
+// of this package. Errors that have no counterpart are returned unchanged.
+func WrapPerunError(err error) error {
+	var rejected client.PeerRejectedError
+	if errors.As(err, &rejected) {
+		code, reason := decodeReason(rejected.Reason)
+		return &PeerRejectedError{Code: code, Reason: reason}
+	} else if channel.IsFundingTimeoutError(err) {
+		return &FundingTimeoutError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package connection
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/channel"
+)
+
+type EventType string
+
+const (
+	EventChannelOpened       EventType = "channel_opened"
+	EventCredentialRequested EventType = "credential_requested"
+	EventCredentialIssued    EventType = "credential_issued"
+	EventUpdateRejected      EventType = "update_rejected"
+	EventDisputeRegistered   EventType = "dispute_registered"
+	EventChannelClosed       EventType = "channel_closed"
+	EventSubscriptionLapsed  EventType = "subscription_lapsed"
+	EventChannelMigrated     EventType = "channel_migrated"
+	EventDisputeProgressed   EventType = "dispute_progressed"
+	EventChannelConcluded    EventType = "channel_concluded"
+	EventDisputeReverted     EventType = "dispute_reverted"
+)
+
+// Event is emitted when a channel makes progress.
+type Event interface {
+	Type() EventType
+	Header() EventHeader
+}
+
+// EventHeader holds the fields common to all events.
+type EventHeader struct {
+	Channel channel.ID `json:"channel"`
+	Time    time.Time  `json:"time"`
+}
+
+type (
+	ChannelOpened struct {
+		EventHeader
+		Peer string `json:"peer"`
+	}
+
+	CredentialRequested struct {
+		EventHeader
+		Issuer  common.Address `json:"issuer"`
+		DocHash app.Hash       `json:"docHash"`
+		Price   *big.Int       `json:"price"`
+	}
+
+	CredentialIssued struct {
+		EventHeader
+		Issuer  common.Address `json:"issuer"`
+		DocHash app.Hash       `json:"docHash"`
+		Price   *big.Int       `json:"price"`
+	}
+
+	UpdateRejected struct {
+		EventHeader
+		Code   RejectionCode `json:"code,omitempty"`
+		Reason string        `json:"reason"`
+	}
+
+	// DisputeRegistered is emitted when a state of the channel was
+	// registered on the adjudicator, by either participant. Until Timeout,
+	// it can be refuted with a newer state.
+	DisputeRegistered struct {
+		EventHeader
+		Version uint64    `json:"version"`
+		Timeout time.Time `json:"timeout"`
+	}
+
+	// DisputeProgressed is emitted when the registered state was progressed
+	// on-chain. Until Timeout, it can be progressed further.
+	DisputeProgressed struct {
+		EventHeader
+		Version uint64    `json:"version"`
+		Timeout time.Time `json:"timeout"`
+	}
+
+	// ChannelConcluded is emitted when the channel was concluded on the
+	// adjudicator with the state of version Version.
+	ChannelConcluded struct {
+		EventHeader
+		Version uint64 `json:"version"`
+	}
+
+	// DisputeReverted is emitted when a reorg dropped an observed
+	// registration, progression, or conclusion of the channel. It holds the
+	// dispute recorded on-chain after the reorg.
+	DisputeReverted struct {
+		EventHeader
+		Registered bool   `json:"registered"`
+		Version    uint64 `json:"version"`
+		Concluded  bool   `json:"concluded"`
+	}
+
+	ChannelClosed struct {
+		EventHeader
+	}
+
+	// SubscriptionLapsed is emitted by the issuer when a subscriber missed a
+	// payment.
+	SubscriptionLapsed struct {
+		EventHeader
+		DocHash app.Hash `json:"docHash"`
+	}
+
+	// ChannelMigrated is emitted when the channel was closed and reopened
+	// as channel Successor with the app contract at App.
+	ChannelMigrated struct {
+		EventHeader
+		Successor channel.ID     `json:"successor"`
+		App       common.Address `json:"app"`
+	}
+)
+
+func (e EventHeader) Header() EventHeader { return e }
+
+func (*ChannelOpened) Type() EventType       { return EventChannelOpened }
+func (*CredentialRequested) Type() EventType { return EventCredentialRequested }
+func (*CredentialIssued) Type() EventType    { return EventCredentialIssued }
+func (*UpdateRejected) Type() EventType      { return EventUpdateRejected }
+func (*DisputeRegistered) Type() EventType   { return EventDisputeRegistered }
+func (*ChannelClosed) Type() EventType       { return EventChannelClosed }
+func (*SubscriptionLapsed) Type() EventType  { return EventSubscriptionLapsed }
+func (*ChannelMigrated) Type() EventType     { return EventChannelMigrated }
+func (*DisputeProgressed) Type() EventType   { return EventDisputeProgressed }
+func (*ChannelConcluded) Type() EventType    { return EventChannelConcluded }
+func (*DisputeReverted) Type() EventType     { return EventDisputeReverted }
+
+func (c *Connection) header() EventHeader {
+	return EventHeader{
+		Channel: c.ID(),
+		Time:    c.cfg.Clock.Now(),
+	}
+}
+
+// timeoutTime returns the time at which `t` elapses, or zero if it is not a
+// block timeout.
+func timeoutTime(t channel.Timeout) time.Time {
+	if bt, ok := t.(*ethchannel.BlockTimeout); ok {
+		return time.Unix(int64(bt.Time), 0)
+	}
+	return time.Time{}
+}
+
+func (c *Connection) notify(e Event) {
+	if c.cfg.Notify != nil {
+		c.cfg.Notify(e)
+	}
+}
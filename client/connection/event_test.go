@@ -0,0 +1,27 @@
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/channel"
+)
+
+func TestTimeoutTime(t *testing.T) {
+	require.True(t, time.Unix(1000, 0).Equal(timeoutTime(ethchannel.NewBlockTimeout(nil, 1000))))
+	require.True(t, timeoutTime(&channel.ElapsedTimeout{}).IsZero(), "no block timeout")
+}
+
+func TestResponseContext(t *testing.T) {
+	deadline := func(cfg *Config) time.Duration {
+		ctx, cancel := (&Connection{cfg: cfg}).responseContext()
+		defer cancel()
+		d, ok := ctx.Deadline()
+		require.True(t, ok)
+		return time.Until(d)
+	}
+	require.InDelta(t, DefaultResponseTimeout, deadline(&Config{}), float64(time.Second), "default")
+	require.InDelta(t, time.Hour, deadline(&Config{ResponseTimeout: time.Hour}), float64(time.Second), "configured")
+}
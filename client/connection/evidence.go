@@ -0,0 +1,233 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/wire"
+)
+
+var ErrNoEvidence = errors.New("no evidence recorded")
+
+// EvidenceRecorder records the evidence needed to settle disputes about
+// channels: every fully signed state, the adjudicator events, and the
+// transactions sent by the client concerning the channels. It is installed
+// as the persister of the Perun client and forwards to the embedded
+// PersistRestorer, which restores channels.
+type EvidenceRecorder struct {
+	persistence.PersistRestorer
+	clock    clock.Clock
+	mu       sync.Mutex
+	channels map[channel.ID]*evidence
+}
+
+type evidence struct {
+	params *channel.Params
+	states []channel.Transaction
+	events []EvidenceEvent
+	txs    []common.Hash
+}
+
+// NewEvidenceRecorder returns an evidence recorder forwarding to `pr`, which
+// timestamps the evidence with `clk`. If `pr` is nil, channels are not
+// persisted.
+func NewEvidenceRecorder(pr persistence.PersistRestorer, clk clock.Clock) *EvidenceRecorder {
+	if pr == nil {
+		pr = persistence.NonPersistRestorer
+	}
+	return &EvidenceRecorder{
+		PersistRestorer: pr,
+		clock:           clk,
+		channels:        make(map[channel.ID]*evidence),
+	}
+}
+
+// ChannelCreated records the initial state of a channel.
+func (r *EvidenceRecorder) ChannelCreated(ctx context.Context, s channel.Source, peers []wire.Address, parent *channel.ID) error {
+	if err := r.PersistRestorer.ChannelCreated(ctx, s, peers, parent); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels[s.ID()] = &evidence{
+		params: s.Params().Clone(),
+		states: []channel.Transaction{s.CurrentTX().Clone()},
+	}
+	return nil
+}
+
+// Enabled records a new fully signed state of a channel.
+func (r *EvidenceRecorder) Enabled(ctx context.Context, s channel.Source) error {
+	if err := r.PersistRestorer.Enabled(ctx, s); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.channels[s.ID()]; ok {
+		e.states = append(e.states, s.CurrentTX().Clone())
+	}
+	return nil
+}
+
+// ChannelRemoved keeps the evidence, as it may still be needed after the
+// channel is settled.
+func (r *EvidenceRecorder) ChannelRemoved(ctx context.Context, id channel.ID) error {
+	return r.PersistRestorer.ChannelRemoved(ctx, id)
+}
+
+// ObserveReceipt records the transaction of receipt `rec` for the channels
+// that its logs refer to.
+func (r *EvidenceRecorder) ObserveReceipt(rec *types.Receipt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range receiptChannels(rec) {
+		if e, ok := r.channels[id]; ok {
+			e.txs = append(e.txs, rec.TxHash)
+		}
+	}
+}
+
+// receiptChannels returns the IDs of the channels that the logs of receipt
+// `rec` refer to, without duplicates. The contracts index all events by
+// channel ID.
+func receiptChannels(rec *types.Receipt) []channel.ID {
+	var ids []channel.ID
+	seen := make(map[channel.ID]bool)
+	for _, l := range rec.Logs {
+		if len(l.Topics) < 2 {
+			continue
+		}
+		id := channel.ID(l.Topics[1])
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (r *EvidenceRecorder) recordEvent(e channel.AdjudicatorEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ev, ok := r.channels[e.ID()]
+	if !ok {
+		return
+	}
+	ev.events = append(ev.events, EvidenceEvent{
+		Type:       fmt.Sprintf("%T", e),
+		Version:    e.Version(),
+		Timeout:    fmt.Sprint(e.Timeout()),
+		ObservedAt: r.clock.Now(),
+	})
+}
+
+type (
+	// DisputeEvidence is a self-contained bundle of the evidence about a
+	// channel, to be handed to third parties when a dispute occurs.
+	DisputeEvidence struct {
+		ChannelID    common.Hash     `json:"channelId"`
+		Params       EvidenceParams  `json:"params"`
+		States       []EvidenceState `json:"states"`
+		Events       []EvidenceEvent `json:"events"`
+		Transactions []common.Hash   `json:"transactions"`
+		ExportedAt   time.Time       `json:"exportedAt"`
+	}
+
+	// EvidenceParams are the parameters of a channel.
+	EvidenceParams struct {
+		ChallengeDuration uint64           `json:"challengeDuration"`
+		Participants      []common.Address `json:"participants"`
+		App               common.Address   `json:"app"`
+		Nonce             *big.Int         `json:"nonce"`
+	}
+
+	// EvidenceState is a fully signed channel state. Encoded is the encoding
+	// of the state that the participants signed and the adjudicator verifies.
+	EvidenceState struct {
+		Version    uint64          `json:"version"`
+		Encoded    hexutil.Bytes   `json:"encoded"`
+		DataType   string          `json:"dataType"`
+		Data       channel.Data    `json:"data"`
+		Balances   [][]*big.Int    `json:"balances"`
+		IsFinal    bool            `json:"isFinal"`
+		Signatures []hexutil.Bytes `json:"signatures"`
+	}
+
+	// EvidenceEvent is an adjudicator event observed for a channel.
+	EvidenceEvent struct {
+		Type       string    `json:"type"`
+		Version    uint64    `json:"version"`
+		Timeout    string    `json:"timeout"`
+		ObservedAt time.Time `json:"observedAt"`
+	}
+)
+
+// export returns the evidence about channel `id`.
+func (r *EvidenceRecorder) export(id channel.ID) (*DisputeEvidence, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.channels[id]
+	if !ok {
+		return nil, ErrNoEvidence
+	}
+
+	d := &DisputeEvidence{
+		ChannelID: common.Hash(id),
+		Params: EvidenceParams{
+			ChallengeDuration: e.params.ChallengeDuration,
+			Nonce:             e.params.Nonce,
+		},
+		States:       make([]EvidenceState, len(e.states)),
+		Events:       append([]EvidenceEvent(nil), e.events...),
+		Transactions: append([]common.Hash(nil), e.txs...),
+		ExportedAt:   r.clock.Now(),
+	}
+	for _, p := range e.params.Parts {
+		d.Params.Participants = append(d.Params.Participants, ethwallet.AsEthAddr(p))
+	}
+	if !channel.IsNoApp(e.params.App) {
+		d.Params.App = ethwallet.AsEthAddr(e.params.App.Def())
+	}
+
+	for i, tx := range e.states {
+		ethState := ethchannel.ToEthState(tx.State)
+		enc, err := ethchannel.EncodeState(&ethState)
+		if err != nil {
+			return nil, fmt.Errorf("encoding state %d: %w", tx.Version, err)
+		}
+		s := EvidenceState{
+			Version:  tx.Version,
+			Encoded:  enc,
+			DataType: fmt.Sprintf("%T", tx.Data),
+			Data:     tx.Data,
+			Balances: tx.Balances,
+			IsFinal:  tx.IsFinal,
+		}
+		for _, sig := range tx.Sigs {
+			s.Signatures = append(s.Signatures, hexutil.Bytes(sig))
+		}
+		d.States[i] = s
+	}
+	return d, nil
+}
+
+// ExportDisputeEvidence returns the evidence recorded about the channel. It
+// fails with ErrNoEvidence if evidence recording is disabled.
+func (c *Connection) ExportDisputeEvidence() (*DisputeEvidence, error) {
+	if c.cfg.Evidence == nil {
+		return nil, ErrNoEvidence
+	}
+	return c.cfg.Evidence.export(c.ID())
+}
@@ -0,0 +1,22 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+)
+
+func TestReceiptChannels(t *testing.T) {
+	event := common.Hash{0xee}
+	rec := &types.Receipt{Logs: []*types.Log{
+		{Topics: []common.Hash{event, {1}}},
+		{Topics: []common.Hash{event}}, // Not indexed by channel.
+		{Topics: []common.Hash{event, {2}, {3}}},
+		{Topics: []common.Hash{event, {1}}},
+	}}
+	require.Equal(t, []channel.ID{{1}, {2}}, receiptChannels(rec))
+	require.Empty(t, receiptChannels(&types.Receipt{}))
+}
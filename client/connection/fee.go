@@ -0,0 +1,73 @@
+package connection
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"perun.network/go-perun/client"
+)
+
+// feeRateDenominator is the denominator of fee rates, which are given in
+// basis points.
+const feeRateDenominator = 10000
+
+// hasFeeRecipient returns whether the channel has a fee recipient as third
+// participant.
+func (c *Connection) hasFeeRecipient() bool {
+	return len(c.Peers()) > app.FeeRecipientIdx
+}
+
+// fee returns the fee that the fee recipient of the channel receives from a
+// payment of `price`. It is zero if the channel has no fee recipient.
+func (c *Connection) fee(price *big.Int) *big.Int {
+	if !c.hasFeeRecipient() {
+		return new(big.Int)
+	}
+	fee := new(big.Int).Mul(price, big.NewInt(int64(c.cfg.FeeRate)))
+	return fee.Div(fee, big.NewInt(feeRateDenominator))
+}
+
+// checkFee checks that `offer` routes the configured fee to the fee
+// recipient.
+func (c *Connection) checkFee(offer *data.Offer) error {
+	if offer.FeeAmount().Cmp(c.fee(offer.Price)) != 0 {
+		return fmt.Errorf("%w: got %v, expected %v", app.ErrInvalidFee, offer.FeeAmount(), c.fee(offer.Price))
+	}
+	return nil
+}
+
+// isFeeRecipient returns whether we are the fee recipient of the channel.
+func (c *Connection) isFeeRecipient() bool {
+	return c.Idx() == app.FeeRecipientIdx
+}
+
+// handleUpdateAsFeeRecipient accepts all updates that charge the configured
+// fee. The app ensures that the fee is paid out on issuance. Batch
+// issuance is declined, as it carries no fee.
+func (c *Connection) handleUpdateAsFeeRecipient(update client.ChannelUpdate, responder *client.UpdateResponder) {
+	ctx, cancel := c.responseContext()
+	defer cancel()
+	var err error
+	switch d := update.State.Data.(type) {
+	case *data.Offer:
+		err = c.checkFee(d)
+	case *data.CounterOffer:
+		err = c.checkFee(&d.Offer)
+	case *data.BatchOffer:
+		err = fmt.Errorf("%w: batch requests carry no fee", app.ErrInvalidFee)
+	}
+
+	if err != nil {
+		if err := responder.Reject(ctx, encodeReason(RejectPolicyDenied, err.Error())); err != nil {
+			c.log.Warnf("Error rejecting update: %v", err)
+			return
+		}
+		c.notify(&UpdateRejected{EventHeader: c.header(), Code: RejectPolicyDenied, Reason: err.Error()})
+		return
+	}
+	if err := responder.Accept(ctx); err != nil {
+		c.log.Warnf("Error accepting update: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package connection
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"perun.network/go-perun/wallet"
+)
+
+// RequestFilter selects credential requests. It must not block, as requests
+// are matched against it while they are queued.
+type RequestFilter func(r *CredentialRequest) bool
+
+// ForType selects the requests for credentials of type `typ`, as given by
+// the metadata of the request. Requests without metadata are not selected.
+func ForType(typ string) RequestFilter {
+	return func(r *CredentialRequest) bool {
+		meta, err := r.Metadata()
+		return err == nil && meta != nil && meta.Type == typ
+	}
+}
+
+// PriceBetween selects the requests with a price from `lo` to `hi`,
+// inclusive. A nil bound is not checked.
+func PriceBetween(lo, hi *big.Int) RequestFilter {
+	return func(r *CredentialRequest) bool {
+		p := r.offer.Price
+		return (lo == nil || p.Cmp(lo) >= 0) && (hi == nil || p.Cmp(hi) <= 0)
+	}
+}
+
+// FromPeer selects the requests of `peer`.
+func FromPeer(peer wallet.Address) RequestFilter {
+	return func(r *CredentialRequest) bool {
+		return r.Peer().Equals(peer)
+	}
+}
+
+// AllOf selects the requests that are selected by all of `filters`.
+func AllOf(filters ...RequestFilter) RequestFilter {
+	return func(r *CredentialRequest) bool {
+		for _, f := range filters {
+			if !f(r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// NextMatchingCredentialRequest returns the next credential request that is
+// selected by `f`, e.g., so that each request type is handled by its own pool
+// of workers. Requests that are not selected stay queued for other callers.
+func (c *Connection) NextMatchingCredentialRequest(ctx context.Context, f RequestFilter) (*CredentialRequest, error) {
+	return c.requests.next(ctx, f)
+}
+
+// requestQueue holds the credential requests of the peer that were not
+// picked up yet, and the callers waiting for one.
+type requestQueue struct {
+	mu      sync.Mutex
+	queued  []*CredentialRequest
+	waiters []*requestWaiter
+}
+
+type requestWaiter struct {
+	filter RequestFilter // nil selects all requests.
+	req    chan *CredentialRequest
+}
+
+func (w *requestWaiter) selects(r *CredentialRequest) bool {
+	return w.filter == nil || w.filter(r)
+}
+
+// push hands `r` to the first waiter that selects it, or queues it.
+func (q *requestQueue) push(r *CredentialRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, w := range q.waiters {
+		if w.selects(r) {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			w.req <- r
+			return
+		}
+	}
+	q.queued = append(q.queued, r)
+}
+
+// remove removes `r` if it is still queued.
+func (q *requestQueue) remove(r *CredentialRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, qr := range q.queued {
+		if qr == r {
+			q.queued = append(q.queued[:i], q.queued[i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *requestQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+// next returns the first queued request that is selected by `f`, or waits
+// for one.
+func (q *requestQueue) next(ctx context.Context, f RequestFilter) (*CredentialRequest, error) {
+	w := &requestWaiter{filter: f, req: make(chan *CredentialRequest, 1)}
+	q.mu.Lock()
+	for i, r := range q.queued {
+		if w.selects(r) {
+			q.queued = append(q.queued[:i], q.queued[i+1:]...)
+			q.mu.Unlock()
+			return r, nil
+		}
+	}
+	q.waiters = append(q.waiters, w)
+	q.mu.Unlock()
+
+	select {
+	case r := <-w.req:
+		return r, nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, qw := range q.waiters {
+		if qw == w {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			return nil, ctx.Err()
+		}
+	}
+	// The request was handed to us in the meantime.
+	return <-w.req, nil
+}
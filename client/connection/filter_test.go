@@ -0,0 +1,119 @@
+package connection
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+// newRequest returns a request for a credential of type `typ` at price
+// `price` in a connection that knows the documents in `docs`. An empty type
+// gives a request without metadata.
+func newRequest(docs *PeerDocuments, typ string, price int64) *CredentialRequest {
+	offer := &data.Offer{Price: big.NewInt(price)}
+	if typ != "" {
+		offer.MetaHash = docs.Put((&app.Metadata{Type: typ}).Encode())
+	}
+	return &CredentialRequest{offer: offer, conn: &Connection{cfg: &Config{}, docs: docs}}
+}
+
+// newPeerDocuments returns an empty store of the documents of a peer.
+func newPeerDocuments() *PeerDocuments {
+	return NewDocumentStore(10).Peer(ethwallet.AsWalletAddr(common.Address{1}))
+}
+
+func TestRequestFilters(t *testing.T) {
+	docs := newPeerDocuments()
+	diploma := newRequest(docs, "Diploma", 10)
+	license := newRequest(docs, "License", 20)
+	noMeta := newRequest(docs, "", 30)
+	unknownMeta := newRequest(newPeerDocuments(), "", 40)
+	unknownMeta.offer.MetaHash = app.Hash{1}
+
+	tests := []struct {
+		name string
+		f    RequestFilter
+		want []*CredentialRequest
+	}{
+		{"type", ForType("Diploma"), []*CredentialRequest{diploma}},
+		{"price range", PriceBetween(big.NewInt(20), big.NewInt(30)), []*CredentialRequest{license, noMeta}},
+		{"min price", PriceBetween(big.NewInt(30), nil), []*CredentialRequest{noMeta, unknownMeta}},
+		{"max price", PriceBetween(nil, big.NewInt(10)), []*CredentialRequest{diploma}},
+		{"all of", AllOf(ForType("License"), PriceBetween(nil, big.NewInt(20))), []*CredentialRequest{license}},
+		{"all of none", AllOf(), []*CredentialRequest{diploma, license, noMeta, unknownMeta}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []*CredentialRequest
+			for _, r := range []*CredentialRequest{diploma, license, noMeta, unknownMeta} {
+				if tt.f(r) {
+					got = append(got, r)
+				}
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRequestQueue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	docs := newPeerDocuments()
+	diploma := newRequest(docs, "Diploma", 10)
+	license := newRequest(docs, "License", 20)
+	var q requestQueue
+
+	// Queued requests are handed out in order, skipping unselected ones.
+	q.push(diploma)
+	q.push(license)
+	r, err := q.next(ctx, ForType("License"))
+	require.NoError(t, err)
+	require.Same(t, license, r)
+	require.Equal(t, 1, q.len())
+	r, err = q.next(ctx, nil)
+	require.NoError(t, err)
+	require.Same(t, diploma, r)
+
+	// A pushed request goes to the first waiter that selects it.
+	got := make(chan *CredentialRequest, 2)
+	for _, typ := range []string{"License", "Diploma"} {
+		f := ForType(typ)
+		go func() {
+			r, _ := q.next(ctx, f) // Nil on error.
+			got <- r
+		}()
+	}
+	require.Eventually(t, func() bool { return waiters(&q) == 2 }, time.Second, time.Millisecond)
+	q.push(diploma)
+	require.Same(t, diploma, <-got)
+	require.Equal(t, 0, q.len())
+	q.push(license)
+	require.Same(t, license, <-got)
+
+	// Removed requests are not handed out.
+	q.push(diploma)
+	q.remove(diploma)
+	require.Equal(t, 0, q.len())
+
+	// Waiting ends with the context, and the waiter is removed.
+	short, cancelShort := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelShort()
+	_, err = q.next(short, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 0, waiters(&q))
+	q.push(license)
+	require.Equal(t, 1, q.len())
+}
+
+func waiters(q *requestQueue) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters)
+}
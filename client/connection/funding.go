@@ -0,0 +1,89 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"perun.network/go-perun/client"
+)
+
+// settleAttempts bounds the attempts to settle a disputed channel.
+const settleAttempts = 3
+
+// OpenContext returns the context for opening a channel within `timeout`,
+// which includes funding it. A zero timeout does not bound opening.
+func OpenContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// HandleOpenError handles error `err` of opening channel `ch`. If the
+// channel was created but funding failed, e.g., because the peer did not
+// deposit in time, the own deposit is reclaimed by registering the initial
+// state and withdrawing once the dispute timed out. The asset holder then
+// refunds the deposits of the underfunded channel. Reclaiming takes twice
+// the challenge duration and is bounded by `ctx`, or by reclaimTimeout if
+// `ctx` is already done. If funding timed out, a *FundingTimeoutError is
+// returned.
+func HandleOpenError(ctx context.Context, ch *client.Channel, err error, cfg *Config) error {
+	err = WrapPerunError(err)
+	if ch == nil {
+		return err
+	}
+
+	var timeout *FundingTimeoutError
+	if !errors.As(err, &timeout) && errors.Is(err, context.DeadlineExceeded) {
+		timeout = &FundingTimeoutError{Err: err}
+		err = timeout
+	}
+
+	log := cfg.Log.WithField("channel", formatID(ch.ID())).WithField("phase", "fund")
+	log.Warnf("Funding failed, reclaiming deposit: %v", err)
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), reclaimTimeout(ch))
+		defer cancel()
+	}
+	if rerr := forceSettle(ctx, ch, cfg.Clock, log); rerr != nil {
+		log.Warnf("Failed to reclaim deposit: %v", rerr)
+	} else if timeout != nil {
+		timeout.Reclaimed = true
+	}
+	return err
+}
+
+// reclaimTimeout returns the time needed to reclaim the deposit of `ch`,
+// which covers all attempts of forceSettle.
+func reclaimTimeout(ch *client.Channel) time.Duration {
+	return 2 * settleAttempts * time.Duration(ch.Params().ChallengeDuration) * time.Second
+}
+
+// forceSettle settles channel `ch` without the cooperation of the peer. The
+// latest state is registered on the adjudicator and withdrawn once the
+// dispute timed out. As the app state may be progressed after the
+// registration timeout, concluding may fail until the challenge duration
+// passed again, in which case settling is retried.
+func forceSettle(ctx context.Context, ch *client.Channel, clk clock.Clock, log log.Logger) error {
+	challengeDuration := time.Duration(ch.Params().ChallengeDuration) * time.Second
+	for attempt := 1; ; attempt++ {
+		err := ch.Settle(ctx, false)
+		if err == nil {
+			return nil
+		} else if attempt == settleAttempts {
+			return err
+		}
+		log.Warnf("Failed to settle channel (attempt %d): %v", attempt, err)
+
+		select {
+		case <-clk.After(challengeDuration):
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for challenge duration: %w", ctx.Err())
+		}
+	}
+}
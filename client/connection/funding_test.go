@@ -0,0 +1,32 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/client"
+)
+
+func TestOpenContext(t *testing.T) {
+	ctx, cancel := OpenContext(context.Background(), 0)
+	defer cancel()
+	_, ok := ctx.Deadline()
+	require.False(t, ok, "unbounded")
+
+	ctx, cancel = OpenContext(context.Background(), time.Minute)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
+
+func TestHandleOpenError(t *testing.T) {
+	// Without a channel, there is no deposit to reclaim.
+	err := HandleOpenError(context.Background(), nil, client.PeerRejectedError{Reason: "no"}, &Config{})
+	var rejected *PeerRejectedError
+	require.True(t, errors.As(err, &rejected))
+	require.Equal(t, "no", rejected.Reason)
+}
@@ -5,11 +5,32 @@ import (
 	"fmt"
 
 	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/pkg/supervisor"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/client"
 )
 
 func (conn *Connection) HandleUpdate(cur *channel.State, update client.ChannelUpdate, responder *client.UpdateResponder) {
+	if conn.isFeeRecipient() {
+		conn.handleUpdateAsFeeRecipient(update, responder)
+		return
+	}
+	ctx, cancel := conn.responseContext()
+	defer cancel()
+
+	switch update.State.Data.(type) {
+	case *data.Offer, *data.BatchOffer:
+		if err := conn.acceptsRequests(cur); err != nil {
+			code := RejectionCodeOf(err)
+			if err := responder.Reject(ctx, encodeReason(code, err.Error())); err != nil {
+				conn.log.Warnf("Error rejecting request: %v", err)
+				return
+			}
+			conn.notify(&UpdateRejected{EventHeader: conn.header(), Code: code, Reason: err.Error()})
+			return
+		}
+	}
+
 	switch nextData := update.State.Data.(type) {
 	case *data.Offer:
 		conn.handleOffer(nextData, responder)
@@ -18,28 +39,116 @@ func (conn *Connection) HandleUpdate(cur *channel.State, update client.ChannelUp
 		curData := cur.Data.(*data.Offer)
 		conn.handleCert(curData, nextData, responder)
 
+	case *data.BatchOffer:
+		conn.handleBatchOffer(nextData, responder)
+
+	case *data.BatchCert:
+		// The app logic ensures that the signatures are valid.
+		curData := cur.Data.(*data.BatchOffer)
+		conn.addBatchSignatures(nextData, curData, responder)
+
+	case *data.CounterOffer:
+		// Accepting the counter-offer into the channel state does not commit
+		// us to anything. The decision is made by the requester afterwards.
+		err := responder.Accept(ctx)
+		if err != nil {
+			conn.log.Warnf("Error accepting counter-offer: %v", err)
+			return
+		}
+		conn.addCounterOffer(nextData)
+
 	case *data.DefaultData:
 		// Always accept update. The app logic ensures that the balances do not
-		// change.
-		err := responder.Accept(context.TODO())
+		// change, except for final states in which the peer gives up funds.
+		err := responder.Accept(ctx)
 		if err != nil {
-			conn.Log().Warnf("Error accepting update: %v", err)
+			conn.log.Warnf("Error accepting update: %v", err)
 			return
 		}
 
 	default:
-		conn.Log().Warnf("Unexpected data type: %T", nextData)
+		conn.log.Warnf("Unexpected data type: %T", nextData)
+
+	}
+}
+
+// responseContext returns a context for responding to a channel update
+// automatically.
+func (conn *Connection) responseContext() (context.Context, context.CancelFunc) {
+	d := conn.cfg.ResponseTimeout
+	if d == 0 {
+		d = DefaultResponseTimeout
+	}
+	return context.WithTimeout(context.Background(), d)
+}
 
+// checkRate rejects the update if the credential requests of the peer
+// exceed the configured rates. It returns whether the update was rejected.
+func (conn *Connection) checkRate(responder *client.UpdateResponder) bool {
+	if conn.cfg.RequestLimiter == nil {
+		return false
+	}
+	err := conn.cfg.RequestLimiter.Allow(conn.peer().String())
+	if err == nil {
+		return false
+	}
+	ctx, cancel := conn.responseContext()
+	defer cancel()
+	if err := responder.Reject(ctx, encodeReason(RejectRateLimited, err.Error())); err != nil {
+		conn.log.Warnf("Error rejecting request: %v", err)
+		return true
 	}
+	conn.notify(&UpdateRejected{EventHeader: conn.header(), Code: RejectRateLimited, Reason: err.Error()})
+	return true
 }
 
 func (conn *Connection) handleOffer(offer *data.Offer, responder *client.UpdateResponder) {
-	// Forward the request and get response.
-	response := conn.addCredentialRequest(offer)
-	r := <-response
+	if conn.checkRate(responder) {
+		return
+	}
+	conn.notify(&CredentialRequested{
+		EventHeader: conn.header(),
+		Issuer:      offer.Issuer,
+		DocHash:     offer.DataHash,
+		Price:       offer.Price,
+	})
 
-	// Send response.
-	switch r.(type) {
+	// Forward the request and get response, unless the peer cancels the
+	// request before it is decided.
+	d := conn.incoming.add(offer.DataHash)
+	defer conn.incoming.remove(offer.DataHash)
+	req := conn.addCredentialRequest(offer, d)
+	select {
+	case r := <-req.resp:
+		conn.respond(responder, r)
+	case <-d.cancelled:
+		conn.requests.remove(req)
+		conn.rejectCancelled(responder)
+	}
+}
+
+func (conn *Connection) handleBatchOffer(offer *data.BatchOffer, responder *client.UpdateResponder) {
+	if conn.checkRate(responder) {
+		return
+	}
+	// Forward the request and get response, unless the peer cancels the
+	// request before it is decided.
+	h := batchHash(offer.DataHashes)
+	d := conn.incoming.add(h)
+	defer conn.incoming.remove(h)
+	response := conn.addBatchCredentialRequest(offer, d)
+	select {
+	case r := <-response:
+		conn.respond(responder, r)
+	case <-d.cancelled:
+		conn.rejectCancelled(responder)
+	}
+}
+
+// respond responds to a channel update according to the decision on a
+// credential request.
+func (conn *Connection) respond(responder *client.UpdateResponder, r CredentialRequestResponse) {
+	switch r := r.(type) {
 	case *CredentialRequestResponseAccept:
 		err := responder.Accept(r.Context())
 		if err != nil {
@@ -49,6 +158,24 @@ func (conn *Connection) handleOffer(offer *data.Offer, responder *client.UpdateR
 
 		r.Result() <- nil
 
+	case *CredentialRequestResponseCounter:
+		err := responder.Accept(r.Context())
+		if err != nil {
+			r.Result() <- fmt.Errorf("accepting update: %w", err)
+			return
+		}
+
+		r.Result() <- nil
+
+	case *CredentialRequestResponseReject:
+		err := responder.Reject(r.Context(), r.reason)
+		if err != nil {
+			r.Result() <- fmt.Errorf("rejecting update: %w", err)
+			return
+		}
+
+		r.Result() <- nil
+
 	default:
 		panic(fmt.Sprintf("unsupported type: %T", r))
 	}
@@ -56,7 +183,7 @@ func (conn *Connection) handleOffer(offer *data.Offer, responder *client.UpdateR
 
 func (conn *Connection) handleCert(curData *data.Offer, nextData *data.Cert, responder *client.UpdateResponder) {
 	// The app logic ensures that the signature is valid.
-	conn.addSignature(nextData.Signature[:], curData.DataHash, curData.Issuer, responder)
+	conn.addSignature(nextData, curData, responder)
 }
 
 type EventHandler struct {
@@ -67,20 +194,47 @@ func NewEventHandler(conn *Connection) *EventHandler {
 	return &EventHandler{Connection: conn}
 }
 
+// StartWatching watches the adjudicator for events on the channel. The
+// watcher is restarted by the supervisor if it fails before the channel is
+// closed.
+func (c *Connection) StartWatching() {
+	h := NewEventHandler(c)
+	c.cfg.Supervisor.Go(fmt.Sprintf("watcher %x", c.ID()), supervisor.OnFailure, func() error {
+		err := c.Watch(h)
+		if c.Ctx().Err() != nil {
+			return nil
+		}
+		return err
+	})
+}
+
 func (h *EventHandler) HandleAdjudicatorEvent(e channel.AdjudicatorEvent) {
+	if h.cfg.Evidence != nil {
+		h.cfg.Evidence.recordEvent(e)
+	}
 	switch e := e.(type) {
 	case *channel.RegisteredEvent:
-		h.disputed.SetValue(true)
+		h.setDisputed()
+		h.onChain.swap(e.State)
+		h.notify(&DisputeRegistered{EventHeader: h.header(), Version: e.Version(), Timeout: timeoutTime(e.Timeout())})
+		go h.trackReorg(e.Version(), false)
 	case *channel.ProgressedEvent:
+		if prev := h.onChain.swap(e.State); prev != nil {
+			h.handleProgressed(prev, e.State)
+		}
+		h.notify(&DisputeProgressed{EventHeader: h.header(), Version: e.Version(), Timeout: timeoutTime(e.Timeout())})
+		go h.trackReorg(e.Version(), false)
 		go func() {
 			err := e.TimeoutV.Wait(context.TODO())
 			if err != nil {
-				h.Log().Warnf("waiting for timeout: %v", err)
+				h.log.WithField("phase", "dispute").Warnf("waiting for timeout: %v", err)
 				return
 			}
 			h.concludable.SetValue(true)
 		}()
 	case *channel.ConcludedEvent:
 		h.concluded.SetValue(true)
+		h.notify(&ChannelConcluded{EventHeader: h.header(), Version: e.Version()})
+		go h.trackReorg(e.Version(), true)
 	}
 }
@@ -0,0 +1,76 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/pkg/supervisor"
+	"perun.network/go-perun/channel"
+)
+
+// StartIdleTimer closes the channel once its state was not updated for
+// Config.IdleTimeout, so that the deposits are not locked in an abandoned
+// channel. The channel is finalized cooperatively if the peer responds in
+// time and force-closed otherwise. It does nothing if IdleTimeout is zero.
+func (c *Connection) StartIdleTimer() {
+	if c.cfg.IdleTimeout <= 0 {
+		return
+	}
+	c.cfg.Supervisor.Go(fmt.Sprintf("idle timer %x", c.ID()), supervisor.OnPanic, func() error {
+		c.closeWhenIdle(c.cfg.IdleTimeout)
+		return nil
+	})
+}
+
+// closeWhenIdle waits until the state version did not change for `timeout`
+// and closes the channel. It returns early if the channel is closed or
+// disputed in the meantime.
+func (c *Connection) closeWhenIdle(timeout time.Duration) {
+	clk := c.cfg.Clock
+	version, since := c.State().Version, clk.Now()
+	for {
+		select {
+		case <-clk.After(since.Add(timeout).Sub(clk.Now())):
+		case <-c.Ctx().Done():
+			return
+		}
+
+		s := c.State()
+		if s.IsFinal || c.Disputed() || c.concluded.Value() {
+			return
+		} else if s.Version != version {
+			version, since = s.Version, clk.Now()
+			continue
+		}
+		c.closeIdle()
+		return
+	}
+}
+
+// closeIdle proposes to finalize the channel and settles it. If the peer does
+// not accept within the response timeout, the channel is force-closed.
+func (c *Connection) closeIdle() {
+	log := c.log.WithField("phase", "idle")
+	log.Infof("Closing channel after %v without updates", c.cfg.IdleTimeout)
+
+	ctx, cancel := c.responseContext()
+	err := c.UpdateBy(ctx, func(s *channel.State) error {
+		s.Data = &data.DefaultData{}
+		s.IsFinal = true
+		return nil
+	})
+	cancel()
+	if err != nil {
+		log.Warnf("Failed to finalize channel off-ledger, force-closing: %v", WrapPerunError(err))
+		if err := c.ForceClose(context.Background()); err != nil {
+			log.Warnf("Failed to force-close channel: %v", err)
+		}
+		return
+	}
+
+	if err := c.Close(context.Background()); err != nil {
+		log.Warnf("Failed to close channel: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+)
+
+// RequestCredentialWithInvoice requests the credential for document `doc` of
+// the type and at the price of invoice `inv`.
+func (c *Connection) RequestCredentialWithInvoice(ctx context.Context, doc []byte, inv *app.Invoice) (*AsyncCredential, error) {
+	now := c.cfg.Clock.Now()
+	if err := inv.Verify(now); err != nil {
+		return nil, fmt.Errorf("verifying invoice: %w", err)
+	}
+	meta := &app.Metadata{Type: inv.Type, IssuedAt: uint64(now.Unix())}
+	return c.RequestCredentialWithOptions(ctx, doc, inv.Price, inv.Issuer, CredentialOptions{Metadata: meta})
+}
+
+// CheckInvoice checks that the request matches invoice `inv` issued by
+// `issuer`.
+func (r *CredentialRequest) CheckInvoice(inv *app.Invoice, issuer common.Address) error {
+	if inv.Issuer != issuer {
+		return fmt.Errorf("invoice issued by %v", inv.Issuer)
+	} else if err := inv.Verify(r.conn.cfg.Clock.Now()); err != nil {
+		return fmt.Errorf("verifying invoice: %w", err)
+	}
+	meta, err := r.Metadata()
+	if err != nil {
+		return err
+	} else if meta == nil || meta.Type != inv.Type {
+		return fmt.Errorf("credential type does not match invoice")
+	}
+	return r.CheckPrice(inv.Price)
+}
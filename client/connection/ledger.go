@@ -0,0 +1,366 @@
+package connection
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// Directions of payments.
+const (
+	PaymentOut = "out" // We paid for a credential.
+	PaymentIn  = "in"  // We were paid for a credential.
+	PaymentFee = "fee" // We received the fee as fee recipient.
+)
+
+// ExportFormat is the format of accounting exports.
+type ExportFormat string
+
+const (
+	FormatCSV  ExportFormat = "csv"
+	FormatJSON ExportFormat = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+type (
+	// ChannelRecord is the accounting record of a channel. The amounts are
+	// in the smallest unit of the asset. Balance and PeerBalance are the
+	// balances of the latest state, which are final once ClosedAt is set.
+	ChannelRecord struct {
+		Channel      common.Hash    `json:"channel"`
+		Peer         common.Address `json:"peer"`
+		Asset        common.Address `json:"asset"`
+		Deposit      *big.Int       `json:"deposit"`
+		PeerDeposit  *big.Int       `json:"peerDeposit"`
+		Balance      *big.Int       `json:"balance"`
+		PeerBalance  *big.Int       `json:"peerBalance"`
+		OpenedAt     time.Time      `json:"openedAt"`
+		ClosedAt     *time.Time     `json:"closedAt,omitempty"`
+		Transactions []common.Hash  `json:"transactions"`
+		Tenant       string         `json:"tenant,omitempty"`
+	}
+
+	// PaymentRecord is the accounting record of a payment for a credential,
+	// or for a batch of credentials. Amount is the amount that changed hands
+	// for us, that is, the price minus the fee for the issuer, the price for
+	// the buyer, and the fee for the fee recipient.
+	PaymentRecord struct {
+		Channel   common.Hash    `json:"channel"`
+		Version   uint64         `json:"version"`
+		Peer      common.Address `json:"peer"`
+		Direction string         `json:"direction"`
+		Amount    *big.Int       `json:"amount"`
+		Fee       *big.Int       `json:"fee"`
+		Asset     common.Address `json:"asset"`
+		Issuer    common.Address `json:"issuer"`
+		DocHashes []common.Hash  `json:"docHashes"`
+		Time      time.Time      `json:"time"`
+		Tenant    string         `json:"tenant,omitempty"`
+	}
+)
+
+// Ledger records the payments made in the channels of a client for
+// accounting. Like the EvidenceRecorder, it is installed as the persister of
+// the Perun client and forwards to the embedded PersistRestorer.
+type Ledger struct {
+	persistence.PersistRestorer
+	clock    clock.Clock
+	mu       sync.Mutex
+	channels map[channel.ID]*ledgerChannel
+	order    []channel.ID
+	payments []PaymentRecord
+}
+
+type ledgerChannel struct {
+	record       *ChannelRecord
+	idx, peerIdx channel.Index
+	data         channel.Data // Data of the latest state.
+}
+
+// NewLedger returns a ledger forwarding to `pr`, which times the records with
+// `clk`. If `pr` is nil, channels are not persisted.
+func NewLedger(pr persistence.PersistRestorer, clk clock.Clock) *Ledger {
+	if pr == nil {
+		pr = persistence.NonPersistRestorer
+	}
+	return &Ledger{
+		PersistRestorer: pr,
+		clock:           clk,
+		channels:        make(map[channel.ID]*ledgerChannel),
+	}
+}
+
+// ChannelCreated records the deposits of a channel.
+func (l *Ledger) ChannelCreated(ctx context.Context, s channel.Source, peers []wire.Address, parent *channel.ID) error {
+	if err := l.PersistRestorer.ChannelCreated(ctx, s, peers, parent); err != nil {
+		return err
+	}
+	tx := s.CurrentTX()
+	idx := s.Idx()
+	peerIdx := 1 - idx
+	if idx == app.FeeRecipientIdx {
+		peerIdx = 0
+	}
+	r := &ChannelRecord{
+		Channel:      common.Hash(s.ID()),
+		Peer:         ethwallet.AsEthAddr(s.Params().Parts[peerIdx]),
+		Asset:        assetAddress(tx.Assets[app.AssetIdx]),
+		Deposit:      new(big.Int).Set(tx.Balances[app.AssetIdx][idx]),
+		PeerDeposit:  new(big.Int).Set(tx.Balances[app.AssetIdx][peerIdx]),
+		Balance:      new(big.Int).Set(tx.Balances[app.AssetIdx][idx]),
+		PeerBalance:  new(big.Int).Set(tx.Balances[app.AssetIdx][peerIdx]),
+		OpenedAt:     l.clock.Now(),
+		Transactions: []common.Hash{},
+	}
+	if d, ok := tx.Data.(*data.DefaultData); ok {
+		r.Tenant = d.Tenant
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.channels[s.ID()] = &ledgerChannel{record: r, idx: idx, peerIdx: peerIdx, data: tx.Data}
+	l.order = append(l.order, s.ID())
+	return nil
+}
+
+// Enabled records the payment made by a new fully signed state, if any.
+func (l *Ledger) Enabled(ctx context.Context, s channel.Source) error {
+	if err := l.PersistRestorer.Enabled(ctx, s); err != nil {
+		return err
+	}
+	tx := s.CurrentTX()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch, ok := l.channels[s.ID()]
+	if !ok {
+		return nil
+	}
+	r := ch.record
+	r.Balance = new(big.Int).Set(tx.Balances[app.AssetIdx][ch.idx])
+	r.PeerBalance = new(big.Int).Set(tx.Balances[app.AssetIdx][ch.peerIdx])
+	if p, ok := ch.payment(tx.Data); ok {
+		p.Channel, p.Version, p.Asset, p.Time = r.Channel, tx.Version, r.Asset, l.clock.Now()
+		p.Tenant = r.Tenant
+		l.payments = append(l.payments, p)
+	}
+	ch.data = tx.Data
+	return nil
+}
+
+// payment returns the payment made by the transition of the channel to
+// `next`, if any. The channel, version, asset, and time are not set.
+func (ch *ledgerChannel) payment(next channel.Data) (PaymentRecord, bool) {
+	var (
+		p      PaymentRecord
+		price  *big.Int
+		buyer  uint16
+		fee    = new(big.Int)
+		hashes []common.Hash
+	)
+	switch cur := ch.data.(type) {
+	case *data.Offer:
+		if _, ok := next.(*data.Cert); !ok {
+			return p, false
+		}
+		price, buyer, fee = cur.Price, cur.Buyer, cur.FeeAmount()
+		p.Issuer, hashes = cur.Issuer, []common.Hash{cur.DataHash}
+	case *data.BatchOffer:
+		if _, ok := next.(*data.BatchCert); !ok {
+			return p, false
+		}
+		price, buyer = cur.Price, cur.Buyer
+		p.Issuer = cur.Issuer
+		for _, h := range cur.DataHashes {
+			hashes = append(hashes, common.Hash(h))
+		}
+	default:
+		return p, false
+	}
+
+	p.Peer, p.Fee, p.DocHashes = ch.record.Peer, new(big.Int).Set(fee), hashes
+	switch {
+	case ch.idx == app.FeeRecipientIdx:
+		p.Direction, p.Amount = PaymentFee, new(big.Int).Set(fee)
+	case channel.Index(buyer) == ch.idx:
+		p.Direction, p.Amount = PaymentOut, new(big.Int).Set(price)
+	default:
+		p.Direction, p.Amount = PaymentIn, new(big.Int).Sub(price, fee)
+	}
+	return p, true
+}
+
+// ChannelRemoved records the closing time of a settled channel.
+func (l *Ledger) ChannelRemoved(ctx context.Context, id channel.ID) error {
+	if err := l.PersistRestorer.ChannelRemoved(ctx, id); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ch, ok := l.channels[id]; ok {
+		t := l.clock.Now()
+		ch.record.ClosedAt = &t
+	}
+	return nil
+}
+
+// ObserveReceipt records the transaction of receipt `rec` for the channels
+// that its logs refer to.
+func (l *Ledger) ObserveReceipt(rec *types.Receipt) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range receiptChannels(rec) {
+		if ch, ok := l.channels[id]; ok {
+			ch.record.Transactions = append(ch.record.Transactions, rec.TxHash)
+		}
+	}
+}
+
+// Channels returns the records of all channels in the order of opening.
+func (l *Ledger) Channels() []ChannelRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rs := make([]ChannelRecord, len(l.order))
+	for i, id := range l.order {
+		r := *l.channels[id].record
+		r.Transactions = append([]common.Hash(nil), r.Transactions...)
+		rs[i] = r
+	}
+	return rs
+}
+
+// Payments returns the records of all payments in the order of payment.
+func (l *Ledger) Payments() []PaymentRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]PaymentRecord(nil), l.payments...)
+}
+
+// ExportChannels writes the channel records to `w` in format `f`.
+func (l *Ledger) ExportChannels(w io.Writer, f ExportFormat) error {
+	return writeChannels(w, l.Channels(), f)
+}
+
+// ExportTenantChannels writes the records of the channels opened for tenant
+// `tenant` to `w` in format `f`.
+func (l *Ledger) ExportTenantChannels(w io.Writer, tenant string, f ExportFormat) error {
+	var rs []ChannelRecord
+	for _, r := range l.Channels() {
+		if r.Tenant == tenant {
+			rs = append(rs, r)
+		}
+	}
+	return writeChannels(w, rs, f)
+}
+
+func writeChannels(w io.Writer, rs []ChannelRecord, f ExportFormat) error {
+	if rs == nil {
+		rs = []ChannelRecord{}
+	}
+	switch f {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(rs)
+	case FormatCSV:
+		rows := [][]string{{"channel", "peer", "asset", "deposit", "peer_deposit", "balance", "peer_balance", "opened_at", "closed_at", "transactions", "tenant"}}
+		for _, r := range rs {
+			closed := ""
+			if r.ClosedAt != nil {
+				closed = formatTime(*r.ClosedAt)
+			}
+			txs := make([]string, len(r.Transactions))
+			for i, h := range r.Transactions {
+				txs[i] = h.Hex()
+			}
+			rows = append(rows, []string{
+				r.Channel.Hex(), r.Peer.Hex(), r.Asset.Hex(),
+				r.Deposit.String(), r.PeerDeposit.String(), r.Balance.String(), r.PeerBalance.String(),
+				formatTime(r.OpenedAt), closed, strings.Join(txs, " "), r.Tenant,
+			})
+		}
+		return writeCSV(w, rows)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, f)
+	}
+}
+
+// ExportPayments writes the payment records to `w` in format `f`.
+func (l *Ledger) ExportPayments(w io.Writer, f ExportFormat) error {
+	return writePayments(w, l.Payments(), f)
+}
+
+// ExportTenantPayments writes the records of the payments made in channels
+// of tenant `tenant` to `w` in format `f`.
+func (l *Ledger) ExportTenantPayments(w io.Writer, tenant string, f ExportFormat) error {
+	var ps []PaymentRecord
+	for _, p := range l.Payments() {
+		if p.Tenant == tenant {
+			ps = append(ps, p)
+		}
+	}
+	return writePayments(w, ps, f)
+}
+
+func writePayments(w io.Writer, ps []PaymentRecord, f ExportFormat) error {
+	if ps == nil {
+		ps = []PaymentRecord{}
+	}
+	switch f {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(ps)
+	case FormatCSV:
+		rows := [][]string{{"channel", "version", "peer", "direction", "amount", "fee", "asset", "issuer", "doc_hashes", "time", "tenant"}}
+		for _, p := range ps {
+			hashes := make([]string, len(p.DocHashes))
+			for i, h := range p.DocHashes {
+				hashes[i] = h.Hex()
+			}
+			rows = append(rows, []string{
+				p.Channel.Hex(), fmt.Sprint(p.Version), p.Peer.Hex(), p.Direction,
+				p.Amount.String(), p.Fee.String(), p.Asset.Hex(), p.Issuer.Hex(),
+				strings.Join(hashes, " "), formatTime(p.Time), p.Tenant,
+			})
+		}
+		return writeCSV(w, rows)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, f)
+	}
+}
+
+func writeCSV(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("writing csv: %w", err)
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// assetAddress returns the address of the asset holder of `a`.
+func assetAddress(a channel.Asset) common.Address {
+	if w, ok := a.(wallet.Address); ok {
+		return ethwallet.AsEthAddr(w)
+	}
+	return common.Address{}
+}
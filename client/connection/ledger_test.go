@@ -0,0 +1,250 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+var (
+	holder    = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	issuer    = common.HexToAddress("0x2000000000000000000000000000000000000002")
+	feeRecip  = common.HexToAddress("0x3000000000000000000000000000000000000003")
+	assetAddr = common.HexToAddress("0x4000000000000000000000000000000000000004")
+)
+
+// source is a channel source that only has parameters and a current state.
+type source struct {
+	channel.Source
+	idx    channel.Index
+	params *channel.Params
+	state  *channel.State
+}
+
+func (s *source) ID() channel.ID                 { return s.state.ID }
+func (s *source) Idx() channel.Index             { return s.idx }
+func (s *source) Params() *channel.Params        { return s.params }
+func (s *source) CurrentTX() channel.Transaction { return channel.Transaction{State: s.state} }
+
+// newSource returns the source of a channel between the holder and the
+// issuer, and the fee recipient if `bals` has three entries, with tenant
+// `tenant`. The own index is `idx`.
+func newSource(id channel.ID, idx channel.Index, tenant string, bals ...int64) *source {
+	parts := []wallet.Address{ethwallet.AsWalletAddr(holder), ethwallet.AsWalletAddr(issuer), ethwallet.AsWalletAddr(feeRecip)}[:len(bals)]
+	return &source{
+		idx:    idx,
+		params: &channel.Params{Parts: parts},
+		state: &channel.State{
+			ID: id,
+			Allocation: channel.Allocation{
+				Assets:   []channel.Asset{ethwallet.AsWalletAddr(assetAddr)},
+				Balances: channel.Balances{bigs(bals...)},
+			},
+			Data: &data.DefaultData{Tenant: tenant},
+		},
+	}
+}
+
+// update moves the state of `s` to the next version, with data `d` and
+// balances `bals`, and enables it in `l`.
+func (s *source) update(t *testing.T, l *Ledger, d channel.Data, bals ...int64) {
+	t.Helper()
+	st := s.state.Clone()
+	st.Version++
+	st.Data = d
+	st.Balances = channel.Balances{bigs(bals...)}
+	s.state = st
+	require.NoError(t, l.Enabled(context.Background(), s))
+}
+
+func bigs(xs ...int64) []*big.Int {
+	bs := make([]*big.Int, len(xs))
+	for i, x := range xs {
+		bs[i] = big.NewInt(x)
+	}
+	return bs
+}
+
+func newTestLedger(t *testing.T, srcs ...*source) (*Ledger, *clock.Fake) {
+	t.Helper()
+	clk := clock.NewFake(time.Unix(1000, 0))
+	l := NewLedger(nil, clk)
+	for _, s := range srcs {
+		require.NoError(t, l.ChannelCreated(context.Background(), s, nil, nil))
+	}
+	return l, clk
+}
+
+func TestLedgerDirections(t *testing.T) {
+	offer := &data.Offer{Issuer: issuer, DataHash: [32]byte{1}, Price: big.NewInt(10), Fee: big.NewInt(2), Nonce: 1}
+	tests := []struct {
+		name   string
+		idx    channel.Index
+		peer   common.Address
+		dir    string
+		amount int64
+	}{
+		{"holder", 0, issuer, PaymentOut, 10},
+		{"issuer", 1, holder, PaymentIn, 8},
+		{"fee recipient", 2, holder, PaymentFee, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSource(channel.ID{1}, tt.idx, "", 100, 0, 0)
+			l, clk := newTestLedger(t, s)
+			s.update(t, l, offer, 100, 0, 0)
+			clk.Advance(time.Minute)
+			s.update(t, l, &data.Cert{}, 90, 8, 2)
+
+			ps := l.Payments()
+			require.Len(t, ps, 1)
+			p := ps[0]
+			require.Equal(t, common.Hash{1}, p.Channel)
+			require.Equal(t, uint64(2), p.Version)
+			require.Equal(t, tt.peer, p.Peer)
+			require.Equal(t, tt.dir, p.Direction)
+			require.Equal(t, big.NewInt(tt.amount), p.Amount)
+			require.Equal(t, big.NewInt(2), p.Fee)
+			require.Equal(t, assetAddr, p.Asset)
+			require.Equal(t, issuer, p.Issuer)
+			require.Equal(t, []common.Hash{{1}}, p.DocHashes)
+			require.Equal(t, clk.Now(), p.Time)
+
+			r := l.Channels()[0]
+			require.Equal(t, tt.peer, r.Peer)
+			require.Equal(t, bigs(90, 8, 2)[tt.idx], r.Balance)
+		})
+	}
+}
+
+func TestLedgerBatch(t *testing.T) {
+	s := newSource(channel.ID{1}, 1, "", 100, 0)
+	l, _ := newTestLedger(t, s)
+	batch := &data.BatchOffer{Issuer: issuer, DataHashes: [][32]byte{{2}, {3}}, Price: big.NewInt(20), Nonce: 1}
+	s.update(t, l, batch, 100, 0)
+	s.update(t, l, &data.BatchCert{}, 80, 20)
+
+	ps := l.Payments()
+	require.Len(t, ps, 1)
+	require.Equal(t, PaymentIn, ps[0].Direction)
+	require.Equal(t, big.NewInt(20), ps[0].Amount)
+	require.Equal(t, big.NewInt(0), ps[0].Fee)
+	require.Equal(t, []common.Hash{{2}, {3}}, ps[0].DocHashes)
+}
+
+func TestLedgerNoPayment(t *testing.T) {
+	s := newSource(channel.ID{1}, 0, "", 100, 0)
+	l, _ := newTestLedger(t, s)
+	offer := &data.Offer{Issuer: issuer, DataHash: [32]byte{1}, Price: big.NewInt(10), Nonce: 1}
+
+	// A canceled offer is not paid.
+	s.update(t, l, offer, 100, 0)
+	s.update(t, l, &data.DefaultData{}, 100, 0)
+	// A certificate without a preceding offer is not paid.
+	s.update(t, l, &data.Cert{}, 100, 0)
+	require.Empty(t, l.Payments())
+
+	// Channels that the ledger did not see being created are ignored.
+	other := newSource(channel.ID{2}, 0, "", 100, 0)
+	other.update(t, l, offer, 100, 0)
+	other.update(t, l, &data.Cert{}, 90, 10)
+	require.Empty(t, l.Payments())
+	require.Len(t, l.Channels(), 1)
+}
+
+func TestLedgerChannels(t *testing.T) {
+	s1 := newSource(channel.ID{1}, 0, "", 100, 5)
+	s2 := newSource(channel.ID{2}, 1, "", 50, 0)
+	l, clk := newTestLedger(t, s1, s2)
+
+	rs := l.Channels()
+	require.Len(t, rs, 2)
+	require.Equal(t, common.Hash{1}, rs[0].Channel)
+	require.Equal(t, common.Hash{2}, rs[1].Channel)
+	require.Equal(t, big.NewInt(100), rs[0].Deposit)
+	require.Equal(t, big.NewInt(5), rs[0].PeerDeposit)
+	require.Equal(t, big.NewInt(0), rs[1].Deposit)
+	require.Equal(t, big.NewInt(50), rs[1].PeerDeposit)
+	require.Equal(t, clk.Now(), rs[0].OpenedAt)
+
+	// Only logs that refer to a known channel are recorded.
+	l.ObserveReceipt(&types.Receipt{
+		TxHash: common.Hash{7},
+		Logs: []*types.Log{
+			{Topics: []common.Hash{{}, {1}}},
+			{Topics: []common.Hash{{}, {1}}},
+			{Topics: []common.Hash{{}, {3}}},
+			{Topics: []common.Hash{{}}},
+		},
+	})
+	clk.Advance(time.Hour)
+	require.NoError(t, l.ChannelRemoved(context.Background(), channel.ID{1}))
+	require.NoError(t, l.ChannelRemoved(context.Background(), channel.ID{3}))
+
+	rs = l.Channels()
+	require.Equal(t, []common.Hash{{7}}, rs[0].Transactions)
+	require.Empty(t, rs[1].Transactions)
+	require.NotNil(t, rs[0].ClosedAt)
+	require.Equal(t, clk.Now(), *rs[0].ClosedAt)
+	require.Nil(t, rs[1].ClosedAt)
+
+	// The returned records are copies.
+	rs[0].Transactions[0] = common.Hash{8}
+	require.Equal(t, []common.Hash{{7}}, l.Channels()[0].Transactions)
+}
+
+func TestLedgerExport(t *testing.T) {
+	s1 := newSource(channel.ID{1}, 1, "alice", 100, 0)
+	s2 := newSource(channel.ID{2}, 1, "bob", 100, 0)
+	l, _ := newTestLedger(t, s1, s2)
+	offer := &data.Offer{Issuer: issuer, DataHash: [32]byte{1}, Price: big.NewInt(10), Nonce: 1}
+	for _, s := range []*source{s1, s2} {
+		s.update(t, l, offer, 100, 0)
+		s.update(t, l, &data.Cert{}, 90, 10)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, l.ExportPayments(&buf, FormatJSON))
+	var ps []PaymentRecord
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &ps))
+	require.Len(t, ps, 2)
+	require.Equal(t, "alice", ps[0].Tenant)
+	require.Equal(t, big.NewInt(10), ps[0].Amount)
+
+	buf.Reset()
+	require.NoError(t, l.ExportTenantPayments(&buf, "bob", FormatCSV))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "channel", rows[0][0])
+	require.Equal(t, common.Hash{2}.Hex(), rows[1][0])
+	require.Equal(t, "bob", rows[1][10])
+
+	buf.Reset()
+	require.NoError(t, l.ExportTenantChannels(&buf, "carol", FormatJSON))
+	require.JSONEq(t, "[]", buf.String())
+
+	buf.Reset()
+	require.NoError(t, l.ExportChannels(&buf, FormatCSV))
+	rows, err = csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, "10", rows[1][5])
+	require.Equal(t, "alice", rows[1][10])
+
+	require.ErrorIs(t, l.ExportChannels(&buf, "xml"), ErrUnknownFormat)
+	require.ErrorIs(t, l.ExportPayments(&buf, "xml"), ErrUnknownFormat)
+}
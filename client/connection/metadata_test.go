@@ -0,0 +1,47 @@
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMetadata(t *testing.T) {
+	now := time.Unix(100000, 0)
+	docs := newPeerDocuments()
+	newMetaRequest := func(meta *app.Metadata) *CredentialRequest {
+		r := newRequest(docs, "", 1)
+		r.offer.MetaHash = docs.Put(meta.Encode())
+		r.conn.cfg.Clock = clock.NewFake(now)
+		r.conn.cfg.Schemas = NewSchemaRegistry()
+		return r
+	}
+
+	r := newMetaRequest(&app.Metadata{Type: "Diploma", IssuedAt: uint64(now.Unix())})
+	require.NoError(t, r.checkMetadata([]byte("{}")))
+	meta, err := r.Metadata()
+	require.NoError(t, err)
+	require.Equal(t, "Diploma", meta.Type)
+
+	// The issuance date must be close to the current time.
+	late := now.Add(MaxIssuanceDateDeviation + time.Second)
+	r = newMetaRequest(&app.Metadata{Type: "Diploma", IssuedAt: uint64(late.Unix())})
+	require.Error(t, r.checkMetadata([]byte("{}")), "future issuance date")
+	early := now.Add(-MaxIssuanceDateDeviation - time.Second)
+	r = newMetaRequest(&app.Metadata{Type: "Diploma", IssuedAt: uint64(early.Unix())})
+	require.Error(t, r.checkMetadata([]byte("{}")), "past issuance date")
+
+	// The metadata must have been received.
+	r = newMetaRequest(&app.Metadata{Type: "Diploma", IssuedAt: uint64(now.Unix())})
+	r.offer.MetaHash[0]++
+	require.ErrorIs(t, r.checkMetadata([]byte("{}")), ErrMetadataUnknown)
+
+	// Requests without metadata pass, unless holder binding is required.
+	r = newRequest(docs, "", 1)
+	require.NoError(t, r.checkMetadata([]byte("{}")))
+	r.conn.cfg.RequireHolderBinding = true
+	require.ErrorIs(t, r.checkMetadata([]byte("{}")), app.ErrNotBound)
+}
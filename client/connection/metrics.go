@@ -0,0 +1,59 @@
+package connection
+
+import (
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/metrics"
+)
+
+// Metrics holds the metrics collected by the client and its connections. A
+// nil Metrics disables collection.
+type Metrics struct {
+	ChannelsOpened    *metrics.Counter
+	CredentialsIssued *metrics.Counter
+	IssuanceLatency   *metrics.Histogram
+	DisputesRaised    *metrics.Counter
+	GasUsed           *metrics.Counter
+}
+
+// NewMetrics registers the client metrics with `r`.
+func NewMetrics(r *metrics.Registry) *Metrics {
+	return &Metrics{
+		ChannelsOpened:    r.Counter("credential_payment_channels_opened_total", "Number of channels opened."),
+		CredentialsIssued: r.Counter("credential_payment_credentials_issued_total", "Number of credentials issued."),
+		IssuanceLatency:   r.Histogram("credential_payment_issuance_latency_seconds", "Duration of credential issuance.", metrics.DefBuckets),
+		DisputesRaised:    r.Counter("credential_payment_disputes_raised_total", "Number of disputes registered on-chain."),
+		GasUsed:           r.Counter("credential_payment_gas_used_total", "Gas used by on-chain transactions."),
+	}
+}
+
+func (m *Metrics) channelOpened() {
+	if m == nil {
+		return
+	}
+	m.ChannelsOpened.Inc()
+}
+
+// credentialIssued records the issuance of a credential that took `latency`.
+func (m *Metrics) credentialIssued(latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.CredentialsIssued.Inc()
+	m.IssuanceLatency.Observe(latency.Seconds())
+}
+
+func (m *Metrics) disputeRaised() {
+	if m == nil {
+		return
+	}
+	m.DisputesRaised.Inc()
+}
+
+// ObserveGas records gas used by an on-chain transaction.
+func (m *Metrics) ObserveGas(gas uint64) {
+	if m == nil {
+		return
+	}
+	m.GasUsed.Add(float64(gas))
+}
@@ -0,0 +1,128 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindMigrate is the message kind of migration announcements.
+const MsgKindMigrate = "migrate"
+
+// MigrationRequest announces that the sender closes channel Channel and
+// reopens it with the app contract at App.
+type MigrationRequest struct {
+	Channel channel.ID     `json:"channel"`
+	App     common.Address `json:"app"`
+}
+
+// Reopener opens the channel that replaces a migrated one. The balances are
+// those of the own participant and of the peer in the final state of the
+// migrated channel.
+type Reopener func(ctx context.Context, peer wire.Address, balance, peerBalance channel.Bal) (*Connection, error)
+
+// Migrate moves the channel to the app contract at `newApp`. It waits until
+// all requests in progress are answered, announces the migration to the
+// peer, closes the channel cooperatively, and reopens it with `reopen`. New
+// requests are rejected with ErrMigrating in the meantime.
+func (c *Connection) Migrate(ctx context.Context, newApp common.Address, reopen Reopener) (*Connection, error) {
+	if c.Disputed() {
+		return nil, ErrDisputeRegistered
+	} else if c.isFeeRecipient() {
+		return nil, fmt.Errorf("fee recipient cannot migrate channels")
+	} else if c.migrating.Swap(true) {
+		return nil, ErrMigrating
+	}
+
+	if err := waitCondition(ctx, c.idle); err != nil {
+		c.migrating.SetValue(false)
+		return nil, fmt.Errorf("waiting for requests in progress: %w", err)
+	}
+	req := MigrationRequest{Channel: c.ID(), App: newApp}
+	if err := c.cfg.Messenger.Request(ctx, c.peer(), MsgKindMigrate, req, nil); err != nil {
+		c.migrating.SetValue(false)
+		return nil, fmt.Errorf("announcing migration: %w", err)
+	}
+
+	if err := c.Close(ctx); err != nil {
+		return nil, fmt.Errorf("closing channel: %w", err)
+	}
+	balance, peerBalance := c.Balances()
+	next, err := reopen(ctx, c.peer(), balance, peerBalance)
+	if err != nil {
+		return nil, fmt.Errorf("reopening channel: %w", err)
+	}
+	c.notify(&ChannelMigrated{EventHeader: c.header(), Successor: next.ID(), App: newApp})
+	return next, nil
+}
+
+// HandleMigrations answers migration announcements for the connections in
+// `reg`. The announcement is refused if a request is in progress or if
+// `accept` returns an error. Otherwise, the connection stops taking new
+// requests.
+func HandleMigrations(m *message.Messenger, reg *Registry, accept func(c *Connection, peer wire.Address, app common.Address) error) {
+	m.Handle(MsgKindMigrate, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var req MigrationRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("decoding migration request: %w", err)
+		}
+		c, ok := reg.ForID(req.Channel)
+		if !ok || c.isFeeRecipient() || !c.peer().Equals(peer) {
+			return nil, fmt.Errorf("unknown channel: %x", req.Channel)
+		} else if c.Disputed() {
+			return nil, ErrDisputeRegistered
+		} else if c.migrating.Swap(true) {
+			return nil, ErrMigrating
+		}
+
+		if !c.idle() {
+			c.migrating.SetValue(false)
+			return nil, fmt.Errorf("request in progress")
+		}
+		if err := accept(c, peer, req.App); err != nil {
+			c.migrating.SetValue(false)
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+// Migrating returns whether the channel is being migrated.
+func (c *Connection) Migrating() bool {
+	return c.migrating.Value()
+}
+
+// Balances returns the balances of the own participant and of the peer in
+// the current state.
+func (c *Connection) Balances() (balance, peerBalance channel.Bal) {
+	bals := c.State().Balances[app.AssetIdx]
+	return new(big.Int).Set(bals[c.Idx()]), new(big.Int).Set(bals[c.peerIdx()])
+}
+
+// idle returns whether no request is in progress, neither in the channel
+// state nor waiting for a decision.
+func (c *Connection) idle() bool {
+	switch c.State().Data.(type) {
+	case *data.Offer, *data.BatchOffer, *data.CounterOffer:
+		return false
+	}
+	return c.PendingRequests() == 0
+}
+
+// peerIdx returns the index of the channel peer.
+func (c *Connection) peerIdx() channel.Index {
+	for i, p := range c.Peers() {
+		if p.Equals(c.peer()) {
+			return channel.Index(i)
+		}
+	}
+	panic("peer not in channel")
+}
@@ -0,0 +1,81 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"perun.network/go-perun/channel"
+)
+
+// Misbehavior is a deviation of the issuer from the protocol. It is used for
+// testing that requesters recover their funds from dishonest issuers.
+type Misbehavior int
+
+const (
+	// SignWrongDocument issues a credential on another document than the
+	// requested one.
+	SignWrongDocument Misbehavior = iota + 1
+	// DemandPayment takes the price without issuing the credential.
+	DemandPayment
+	// Stall never issues the credential.
+	Stall
+)
+
+func (m Misbehavior) String() string {
+	switch m {
+	case SignWrongDocument:
+		return "sign wrong document"
+	case DemandPayment:
+		return "demand payment"
+	case Stall:
+		return "stall"
+	default:
+		return fmt.Sprintf("misbehavior(%d)", int(m))
+	}
+}
+
+// Misbehave accepts the request and then deviates from the protocol
+// according to `m` instead of issuing the credential. The update of a
+// misbehaving issuer is not forced on-chain.
+//
+// go-perun checks updates against the app before proposing them, so the
+// updates of SignWrongDocument and DemandPayment fail and their error is
+// returned. To the requester, the issuer then looks like it stalls.
+func (r *CredentialRequest) Misbehave(ctx context.Context, acc app.Account, m Misbehavior) error {
+	if err := r.accept(ctx); err != nil {
+		return err
+	}
+
+	var up func(*channel.State) error
+	switch m {
+	case SignWrongDocument:
+		up = func(s *channel.State) error {
+			wrong := r.offer.Clone().(*data.Offer)
+			wrong.DataHash[0] ^= 0xff
+			sig, err := app.SignHash(acc, app.OfferHash(wrong))
+			if err != nil {
+				return fmt.Errorf("signing hash: %w", err)
+			}
+			s.Data = &data.Cert{Signature: sig}
+			r.conn.transferPrice(s, r.offer)
+			return nil
+		}
+	case DemandPayment:
+		up = func(s *channel.State) error {
+			s.Data = &data.Cert{}
+			r.conn.transferPrice(s, r.offer)
+			return nil
+		}
+	case Stall:
+		return nil
+	default:
+		return fmt.Errorf("unknown misbehavior: %v", m)
+	}
+
+	if err := r.conn.UpdateBy(ctx, up); err != nil {
+		return fmt.Errorf("updating channel: %w", WrapPerunError(err))
+	}
+	return nil
+}
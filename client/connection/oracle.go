@@ -0,0 +1,53 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrNoPriceOracle = errors.New("no price oracle configured")
+
+// PriceOracle converts USD prices into amounts of the channel asset, e.g.,
+// an oracle.Converter.
+type PriceOracle interface {
+	Convert(ctx context.Context, cents *big.Int) (*big.Int, error)
+}
+
+// RequestCredentialUSD requests the credential for document `doc` at the
+// current asset amount of `cents` USD cents.
+func (c *Connection) RequestCredentialUSD(ctx context.Context, doc []byte, cents *big.Int, issuer common.Address) (*AsyncCredential, error) {
+	if c.cfg.PriceOracle == nil {
+		return nil, ErrNoPriceOracle
+	}
+	price, err := c.cfg.PriceOracle.Convert(ctx, cents)
+	if err != nil {
+		return nil, fmt.Errorf("converting price: %w", err)
+	}
+	return c.RequestCredential(ctx, doc, price, issuer)
+}
+
+// CheckPriceUSD checks that the requested price is worth `cents` USD cents
+// at the current rate. The price may deviate by PriceTolerance basis points,
+// as the parties may have converted at different rates.
+func (r *CredentialRequest) CheckPriceUSD(ctx context.Context, cents *big.Int) error {
+	oracle := r.conn.cfg.PriceOracle
+	if oracle == nil {
+		return ErrNoPriceOracle
+	}
+	expected, err := oracle.Convert(ctx, cents)
+	if err != nil {
+		return fmt.Errorf("converting price: %w", err)
+	}
+
+	tolerance := new(big.Int).Mul(expected, big.NewInt(int64(r.conn.cfg.PriceTolerance)))
+	tolerance.Div(tolerance, big.NewInt(feeRateDenominator))
+	diff := new(big.Int).Sub(r.offer.Price, expected)
+	if diff.CmpAbs(tolerance) > 0 {
+		return fmt.Errorf("%w: %v deviates from %v", ErrWrongPrice, r.offer.Price, expected)
+	}
+	return nil
+}
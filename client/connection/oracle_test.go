@@ -0,0 +1,35 @@
+package connection
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fixedOracle converts one cent into `rate` asset units.
+type fixedOracle struct{ rate int64 }
+
+func (o fixedOracle) Convert(_ context.Context, cents *big.Int) (*big.Int, error) {
+	return new(big.Int).Mul(cents, big.NewInt(o.rate)), nil
+}
+
+func TestCheckPriceUSD(t *testing.T) {
+	ctx := context.Background()
+	check := func(price int64) error {
+		r := newRequest(newPeerDocuments(), "", price)
+		r.conn.cfg.PriceOracle = fixedOracle{rate: 100}
+		r.conn.cfg.PriceTolerance = 100 // 1%
+		return r.CheckPriceUSD(ctx, big.NewInt(100))
+	}
+
+	require.NoError(t, check(10000))
+	require.NoError(t, check(10100), "within tolerance")
+	require.NoError(t, check(9900), "within tolerance")
+	require.ErrorIs(t, check(10101), ErrWrongPrice)
+	require.ErrorIs(t, check(9899), ErrWrongPrice)
+
+	r := newRequest(newPeerDocuments(), "", 10000)
+	require.ErrorIs(t, r.CheckPriceUSD(ctx, big.NewInt(100)), ErrNoPriceOracle)
+}
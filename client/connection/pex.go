@@ -0,0 +1,111 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/pex"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindDescriptorQuote is the message kind of quote requests by input
+// descriptor.
+const MsgKindDescriptorQuote = "descriptor-quote"
+
+// DescriptorQuoteRequest describes the needed credential by a Presentation
+// Exchange input descriptor instead of a credential type.
+type DescriptorQuoteRequest struct {
+	Descriptor pex.InputDescriptor `json:"inputDescriptor"`
+	DocHash    app.Hash            `json:"docHash"`
+}
+
+// DescriptorMapper maps the input descriptor requested by `peer` to a
+// credential type and prices it. It also returns for how long the quote is
+// valid.
+type DescriptorMapper func(peer wire.Address, d *pex.InputDescriptor) (typ string, price *big.Int, validity time.Duration, err error)
+
+// RequestDescriptorQuote requests a quote for document `doc` described by
+// input descriptor `d` from `peer`. The document must satisfy the
+// descriptor. The quote names the credential type chosen by the peer.
+func RequestDescriptorQuote(ctx context.Context, m *message.Messenger, peer wire.Address, d *pex.InputDescriptor, doc []byte, clk clock.Clock) (*app.Quote, error) {
+	if err := d.Match(doc); err != nil {
+		return nil, err
+	}
+
+	req := DescriptorQuoteRequest{Descriptor: *d, DocHash: app.ComputeDocumentHash(doc)}
+	var q app.Quote
+	err := m.Request(ctx, peer, MsgKindDescriptorQuote, req, &q)
+	if err != nil {
+		return nil, fmt.Errorf("requesting quote: %w", err)
+	}
+
+	if q.Issuer != ethwallet.AsEthAddr(peer) {
+		return nil, fmt.Errorf("quote issued by %v, expected %v", q.Issuer, peer)
+	} else if q.DocHash != req.DocHash {
+		return nil, fmt.Errorf("quote does not match request")
+	} else if err := q.Verify(clk.Now()); err != nil {
+		return nil, fmt.Errorf("verifying quote: %w", err)
+	}
+	return &q, nil
+}
+
+// HandleDescriptorQuoteRequests answers quote requests by input descriptor
+// using `mapper` and signs the quotes with `acc`.
+func HandleDescriptorQuoteRequests(m *message.Messenger, mapper DescriptorMapper, acc app.Account, clk clock.Clock) {
+	m.Handle(MsgKindDescriptorQuote, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var req DescriptorQuoteRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("decoding quote request: %w", err)
+		}
+
+		typ, price, validity, err := mapper(peer, &req.Descriptor)
+		if err != nil {
+			return nil, err
+		}
+
+		now := clk.Now()
+		q := &app.Quote{
+			Issuer:     app.AccountAddress(acc),
+			Type:       typ,
+			DocHash:    req.DocHash,
+			Price:      price,
+			ValidFrom:  uint64(now.Unix()),
+			ValidUntil: uint64(now.Add(validity).Unix()),
+		}
+		if err := q.Sign(acc); err != nil {
+			return nil, fmt.Errorf("signing quote: %w", err)
+		}
+		return q, nil
+	})
+}
+
+// RequestDescriptorQuote requests a quote by input descriptor from the peer
+// of the connection.
+func (c *Connection) RequestDescriptorQuote(ctx context.Context, d *pex.InputDescriptor, doc []byte) (*app.Quote, error) {
+	return RequestDescriptorQuote(ctx, c.cfg.Messenger, c.peer(), d, doc, c.cfg.Clock)
+}
+
+// TypeMapper returns a DescriptorMapper that maps descriptors to the first
+// credential type in `prices` that they require at `$.type`, priced
+// accordingly.
+func TypeMapper(prices map[string]*big.Int, validity time.Duration) DescriptorMapper {
+	return func(_ wire.Address, d *pex.InputDescriptor) (string, *big.Int, time.Duration, error) {
+		for _, v := range d.Values("$.type") {
+			typ, ok := v.(string)
+			if !ok {
+				continue
+			}
+			if price, ok := prices[typ]; ok {
+				return typ, new(big.Int).Set(price), validity, nil
+			}
+		}
+		return "", nil, 0, fmt.Errorf("no credential type offered for descriptor %q", d.ID)
+	}
+}
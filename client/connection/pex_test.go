@@ -0,0 +1,29 @@
+package connection
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/pex"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeMapper(t *testing.T) {
+	mapper := TypeMapper(map[string]*big.Int{"Diploma": big.NewInt(10), "License": big.NewInt(20)}, time.Minute)
+	descriptor := func(filter string) *pex.InputDescriptor {
+		return &pex.InputDescriptor{ID: "id", Constraints: pex.Constraints{Fields: []pex.Field{
+			{Path: []string{"$.type"}, Filter: json.RawMessage(filter)},
+		}}}
+	}
+
+	typ, price, validity, err := mapper(nil, descriptor(`{"enum":["Badge","License","Diploma"]}`))
+	require.NoError(t, err)
+	require.Equal(t, "License", typ, "first offered type")
+	require.Equal(t, big.NewInt(20), price)
+	require.Equal(t, time.Minute, validity)
+
+	_, _, _, err = mapper(nil, descriptor(`{"contains":{"const":"Badge"}}`))
+	require.Error(t, err, "type not offered")
+}
@@ -0,0 +1,83 @@
+package connection
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindPossession is the message kind of proof-of-possession challenges.
+const MsgKindPossession = "possession"
+
+// possessionTag separates possession proofs from other signatures of the
+// holder.
+const possessionTag = "perun-credential-payment/possession"
+
+// PossessionChallenge asks the holder to prove possession of the key that is
+// bound into the credential with hash DocHash.
+type PossessionChallenge struct {
+	Nonce   []byte   `json:"nonce"`
+	DocHash app.Hash `json:"docHash"`
+}
+
+func (c *PossessionChallenge) hash() app.Hash {
+	return crypto.Keccak256Hash([]byte(possessionTag), c.Nonce, c.DocHash[:])
+}
+
+// HandlePossessionChallenges answers proof-of-possession challenges by
+// signing them with `acc`.
+func HandlePossessionChallenges(m *message.Messenger, acc app.Account) {
+	m.Handle(MsgKindPossession, func(_ context.Context, _ wire.Address, body json.RawMessage) (interface{}, error) {
+		var c PossessionChallenge
+		if err := json.Unmarshal(body, &c); err != nil {
+			return nil, fmt.Errorf("decoding challenge: %w", err)
+		} else if len(c.Nonce) == 0 {
+			return nil, fmt.Errorf("empty nonce")
+		}
+		sig, err := app.SignHash(acc, c.hash())
+		if err != nil {
+			return nil, err
+		}
+		return sig[:], nil
+	})
+}
+
+// ChallengePossession sends the requester a nonce, which it must sign with a
+// key that the holder DID of the requested credential controls. It fails
+// with app.ErrNotBound if the request does not bind the credential to a
+// holder.
+func (r *CredentialRequest) ChallengePossession(ctx context.Context) error {
+	meta, err := r.Metadata()
+	if err != nil {
+		return err
+	} else if meta == nil || meta.Holder == "" {
+		return app.ErrNotBound
+	}
+
+	c := PossessionChallenge{Nonce: make([]byte, 32), DocHash: r.offer.DataHash}
+	if _, err := rand.Read(c.Nonce); err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+	var sig []byte
+	if err := r.conn.cfg.Messenger.Request(ctx, r.Peer(), MsgKindPossession, c, &sig); err != nil {
+		return fmt.Errorf("requesting proof of possession: %w", err)
+	} else if len(sig) != data.SigLen {
+		return app.ErrInvalidSignature
+	}
+
+	// The signer must be controlled by the holder DID.
+	var fixed [data.SigLen]byte
+	copy(fixed[:], sig)
+	signer, err := app.RecoverSigner(fixed, c.hash())
+	if err != nil {
+		return app.ErrInvalidSignature
+	}
+	return r.conn.checkDID(ctx, meta.Holder, signer)
+}
@@ -0,0 +1,49 @@
+package connection
+
+import (
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"perun.network/go-perun/channel"
+)
+
+// onChainState holds the latest channel state registered or progressed on
+// the adjudicator.
+type onChainState struct {
+	mu    sync.Mutex
+	state *channel.State
+}
+
+// swap sets the on-chain state to `s` and returns the previous one, or nil
+// if there is none.
+func (o *onChainState) swap(s *channel.State) *channel.State {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	prev := o.state
+	o.state = s
+	return prev
+}
+
+// handleProgressed handles the progression of the on-chain state from `prev`
+// to `next`. If the peer issued a credential requested by us on-chain, it
+// is delivered to the waiting request. The payment was enforced by the
+// adjudicator, so the resulting proposal cannot be rejected.
+func (c *Connection) handleProgressed(prev, next *channel.State) {
+	switch cert := next.Data.(type) {
+	case *data.Cert:
+		offer, ok := prev.Data.(*data.Offer)
+		if !ok || channel.Index(offer.Buyer) != c.Idx() {
+			return
+		}
+		c.log.WithField("phase", "dispute").Infof("Credential issued on-chain: %x", offer.DataHash)
+		c.addSignature(cert, offer, nil)
+
+	case *data.BatchCert:
+		offer, ok := prev.Data.(*data.BatchOffer)
+		if !ok || channel.Index(offer.Buyer) != c.Idx() {
+			return
+		}
+		c.log.WithField("phase", "dispute").Infof("Credentials issued on-chain: %x", batchHash(offer.DataHashes))
+		c.addBatchSignatures(cert, offer, nil)
+	}
+}
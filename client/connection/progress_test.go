@@ -0,0 +1,40 @@
+package connection
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+)
+
+func TestOnChainState(t *testing.T) {
+	var o onChainState
+	registered, progressed := &channel.State{Version: 1}, &channel.State{Version: 2}
+	require.Nil(t, o.swap(registered))
+	require.Same(t, registered, o.swap(progressed))
+	require.Same(t, progressed, o.swap(progressed))
+}
+
+func TestOnChainDelivery(t *testing.T) {
+	c := &Connection{sigs: newSigReg()}
+	offer := &data.Offer{Issuer: common.Address{1}, DataHash: [32]byte{2}, Price: big.NewInt(1)}
+	callback, err := c.sigs.RegisterCallback(offer.DataHash, offer.Issuer)
+	require.NoError(t, err)
+
+	// Credentials issued on-chain are delivered without an update to accept.
+	cert := &data.Cert{Signature: [data.SigLen]byte{3}}
+	c.addSignature(cert, offer, nil)
+	resp, err := callback.Await(context.Background())
+	require.NoError(t, err)
+	prop, ok := resp.(*CredentialProposal)
+	require.True(t, ok, "credential proposal expected, got %T", resp)
+	require.True(t, prop.OnChain())
+	require.Equal(t, cert.Signature[:], prop.Signature)
+
+	batch := &BatchCredentialProposal{}
+	require.True(t, batch.OnChain())
+}
@@ -0,0 +1,106 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindQuote is the message kind of quote requests.
+const MsgKindQuote = "quote"
+
+// QuoteRequest describes the credential for which a quote is requested.
+type QuoteRequest struct {
+	Type    string   `json:"type"`
+	DocHash app.Hash `json:"docHash"`
+}
+
+// Quoter prices the credential requested by `peer`. It returns the price and
+// for how long the quote is valid.
+type Quoter func(peer wire.Address, req QuoteRequest) (price *big.Int, validity time.Duration, err error)
+
+// RequestQuote requests a quote from `peer` and verifies that it is signed
+// by the peer and matches the request.
+func RequestQuote(ctx context.Context, m *message.Messenger, peer wire.Address, req QuoteRequest, clk clock.Clock) (*app.Quote, error) {
+	var q app.Quote
+	err := m.Request(ctx, peer, MsgKindQuote, req, &q)
+	if err != nil {
+		return nil, fmt.Errorf("requesting quote: %w", err)
+	}
+
+	if q.Issuer != ethwallet.AsEthAddr(peer) {
+		return nil, fmt.Errorf("quote issued by %v, expected %v", q.Issuer, peer)
+	} else if q.Type != req.Type || q.DocHash != req.DocHash {
+		return nil, fmt.Errorf("quote does not match request")
+	} else if err := q.Verify(clk.Now()); err != nil {
+		return nil, fmt.Errorf("verifying quote: %w", err)
+	}
+	return &q, nil
+}
+
+// HandleQuoteRequests answers quote requests using `quoter` and signs the
+// quotes with `acc`.
+func HandleQuoteRequests(m *message.Messenger, quoter Quoter, acc app.Account, clk clock.Clock) {
+	m.Handle(MsgKindQuote, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var req QuoteRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("decoding quote request: %w", err)
+		}
+
+		price, validity, err := quoter(peer, req)
+		if err != nil {
+			return nil, err
+		}
+
+		now := clk.Now()
+		q := &app.Quote{
+			Issuer:     app.AccountAddress(acc),
+			Type:       req.Type,
+			DocHash:    req.DocHash,
+			Price:      price,
+			ValidFrom:  uint64(now.Unix()),
+			ValidUntil: uint64(now.Add(validity).Unix()),
+		}
+		if err := q.Sign(acc); err != nil {
+			return nil, fmt.Errorf("signing quote: %w", err)
+		}
+		return q, nil
+	})
+}
+
+// RequestQuote requests a quote from the peer of the connection.
+func (c *Connection) RequestQuote(ctx context.Context, req QuoteRequest) (*app.Quote, error) {
+	return RequestQuote(ctx, c.cfg.Messenger, c.peer(), req, c.cfg.Clock)
+}
+
+// RequestCredentialWithQuote requests the credential for document `doc` at
+// the price of quote `q`.
+func (c *Connection) RequestCredentialWithQuote(ctx context.Context, doc []byte, q *app.Quote) (*AsyncCredential, error) {
+	if err := q.Verify(c.cfg.Clock.Now()); err != nil {
+		return nil, fmt.Errorf("verifying quote: %w", err)
+	} else if app.ComputeDocumentHash(doc) != q.DocHash {
+		return nil, ErrWrongDocument
+	}
+	return c.RequestCredential(ctx, doc, q.Price, q.Issuer)
+}
+
+// CheckQuote checks that the request matches quote `q` issued by `issuer`.
+func (r *CredentialRequest) CheckQuote(q *app.Quote, issuer common.Address) error {
+	if q.Issuer != issuer {
+		return fmt.Errorf("quote issued by %v", q.Issuer)
+	} else if err := q.Verify(r.conn.cfg.Clock.Now()); err != nil {
+		return fmt.Errorf("verifying quote: %w", err)
+	} else if q.DocHash != r.offer.DataHash {
+		return ErrWrongDocument
+	}
+	return r.CheckPrice(q.Price)
+}
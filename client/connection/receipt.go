@@ -0,0 +1,137 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindReceipt is the message kind of receipt countersignature requests.
+const MsgKindReceipt = "receipt"
+
+var ErrUnexpectedReceipt = errors.New("unexpected receipt")
+
+// receipts holds the receipts of a connection and the credentials accepted
+// by the holder that await a receipt, by credential ID.
+type receipts struct {
+	mu       sync.Mutex
+	list     []*app.Receipt
+	accepted map[app.Hash]*data.Offer
+}
+
+func (r *receipts) add(rc *app.Receipt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.list = append(r.list, rc)
+}
+
+func (r *receipts) expect(id app.Hash, offer *data.Offer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.accepted == nil {
+		r.accepted = make(map[app.Hash]*data.Offer)
+	}
+	r.accepted[id] = offer
+}
+
+func (r *receipts) take(id app.Hash) (*data.Offer, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	offer, ok := r.accepted[id]
+	delete(r.accepted, id)
+	return offer, ok
+}
+
+// Receipts returns the receipts of the credentials issued in the channel.
+// Credentials issued in batches have no receipts.
+func (c *Connection) Receipts() []*app.Receipt {
+	c.receipts.mu.Lock()
+	defer c.receipts.mu.Unlock()
+	return append([]*app.Receipt(nil), c.receipts.list...)
+}
+
+// exchangeReceipt creates a receipt for the credential issued on `offer`,
+// signs it with the channel account `acc`, and has the holder countersign
+// it. The channel account may differ from the issuer of contract-signed
+// credentials.
+func (c *Connection) exchangeReceipt(ctx context.Context, offer *data.Offer, acc app.Account, requestedAt time.Time) error {
+	rc := &app.Receipt{
+		ChannelID:    c.ID(),
+		CredentialID: app.OfferHash(offer),
+		Issuer:       app.AccountAddress(acc),
+		Holder:       ethwallet.AsEthAddr(c.Peers()[offer.Buyer]),
+		Price:        new(big.Int).Set(offer.Price),
+		RequestedAt:  uint64(requestedAt.Unix()),
+		IssuedAt:     uint64(c.cfg.Clock.Now().Unix()),
+	}
+	if err := rc.Sign(acc); err != nil {
+		return fmt.Errorf("signing receipt: %w", err)
+	}
+
+	var sig []byte
+	if err := c.cfg.Messenger.Request(ctx, c.Peers()[offer.Buyer], MsgKindReceipt, rc, &sig); err != nil {
+		return fmt.Errorf("requesting countersignature: %w", err)
+	}
+	rc.HolderSignature = sig
+	if err := rc.Verify(); err != nil {
+		return fmt.Errorf("verifying receipt: %w", err)
+	}
+	c.receipts.add(rc)
+	return nil
+}
+
+// HandleReceipts countersigns the receipts of credentials accepted in the
+// connections of `reg` with `acc`.
+func HandleReceipts(m *message.Messenger, reg *Registry, acc app.Account) {
+	m.Handle(MsgKindReceipt, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var rc app.Receipt
+		if err := json.Unmarshal(body, &rc); err != nil {
+			return nil, fmt.Errorf("decoding receipt: %w", err)
+		}
+		c, ok := reg.ForID(channel.ID(rc.ChannelID))
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown channel", ErrUnexpectedReceipt)
+		} else if !c.peer().Equals(peer) {
+			return nil, fmt.Errorf("%w: sent by %v", ErrUnexpectedReceipt, peer)
+		}
+		return c.countersignReceipt(&rc, acc)
+	})
+}
+
+// countersignReceipt checks that `rc` describes a credential that we
+// accepted and signs it.
+func (c *Connection) countersignReceipt(rc *app.Receipt, acc app.Account) ([]byte, error) {
+	offer, ok := c.receipts.take(rc.CredentialID)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown credential", ErrUnexpectedReceipt)
+	}
+
+	deviation := c.cfg.Clock.Now().Sub(time.Unix(int64(rc.IssuedAt), 0))
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	if rc.Issuer != ethwallet.AsEthAddr(c.peer()) || rc.Holder != c.self() || rc.Price == nil || rc.Price.Cmp(offer.Price) != 0 {
+		return nil, fmt.Errorf("%w: does not match credential", ErrUnexpectedReceipt)
+	} else if rc.RequestedAt > rc.IssuedAt || deviation > MaxIssuanceDateDeviation {
+		return nil, fmt.Errorf("%w: invalid timestamps", ErrUnexpectedReceipt)
+	} else if err := rc.VerifyIssuer(); err != nil {
+		return nil, fmt.Errorf("verifying receipt: %w", err)
+	}
+
+	if err := rc.Sign(acc); err != nil {
+		return nil, fmt.Errorf("signing receipt: %w", err)
+	}
+	c.receipts.add(rc)
+	return rc.HolderSignature, nil
+}
@@ -0,0 +1,73 @@
+package connection
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/access"
+	"github.com/perun-network/perun-credential-payment/client/policy"
+	"github.com/perun-network/perun-credential-payment/pkg/ratelimit"
+)
+
+// RejectionCode is a machine-readable reason for rejecting a request or a
+// channel update. It is carried to the peer in front of the rejection
+// reason, as go-perun only transfers a free-text reason.
+type RejectionCode string
+
+const (
+	RejectUnspecified         RejectionCode = ""
+	RejectPriceTooHigh        RejectionCode = "price_too_high"
+	RejectDocMismatch         RejectionCode = "doc_mismatch"
+	RejectPolicyDenied        RejectionCode = "policy_denied"
+	RejectExpired             RejectionCode = "expired"
+	RejectRateLimited         RejectionCode = "rate_limited"
+	RejectAccessDenied        RejectionCode = "access_denied"
+	RejectIncompatibleVersion RejectionCode = "incompatible_version"
+	RejectShuttingDown        RejectionCode = "shutting_down"
+	RejectCancelled           RejectionCode = "cancelled"
+)
+
+// RejectionCodeOf returns the code for rejecting a request because of
+// `err`, or RejectUnspecified if there is none.
+func RejectionCodeOf(err error) RejectionCode {
+	var schemaErr *SchemaError
+	switch {
+	case errors.Is(err, ErrWrongDocument), errors.Is(err, ErrWrongDomain),
+		errors.Is(err, ErrMetadataUnknown), errors.As(err, &schemaErr):
+		return RejectDocMismatch
+	case errors.Is(err, app.ErrCredentialExpired):
+		return RejectExpired
+	case errors.Is(err, policy.ErrDenied):
+		return RejectPolicyDenied
+	case errors.Is(err, ratelimit.ErrRateLimited):
+		return RejectRateLimited
+	case errors.Is(err, access.ErrDenied):
+		return RejectAccessDenied
+	case errors.Is(err, ErrIncompatibleVersion):
+		return RejectIncompatibleVersion
+	case errors.Is(err, ErrShuttingDown):
+		return RejectShuttingDown
+	case errors.Is(err, ErrRequestCancelled):
+		return RejectCancelled
+	}
+	return RejectUnspecified
+}
+
+// encodeReason prefixes `reason` with `code`.
+func encodeReason(code RejectionCode, reason string) string {
+	if code == RejectUnspecified {
+		return reason
+	}
+	return "[" + string(code) + "] " + reason
+}
+
+// decodeReason splits a rejection reason received from the peer into its
+// code and the remaining reason.
+func decodeReason(s string) (RejectionCode, string) {
+	end := strings.Index(s, "] ")
+	if !strings.HasPrefix(s, "[") || end < 0 {
+		return RejectUnspecified, s
+	}
+	return RejectionCode(s[1:end]), s[end+2:]
+}
@@ -0,0 +1,55 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/access"
+	"github.com/perun-network/perun-credential-payment/client/policy"
+	"github.com/perun-network/perun-credential-payment/pkg/ratelimit"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/client"
+)
+
+func TestRejectionCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want RejectionCode
+	}{
+		{ErrWrongDocument, RejectDocMismatch},
+		{fmt.Errorf("checking: %w", ErrMetadataUnknown), RejectDocMismatch},
+		{&SchemaError{}, RejectDocMismatch},
+		{app.ErrCredentialExpired, RejectExpired},
+		{policy.ErrDenied, RejectPolicyDenied},
+		{ratelimit.ErrRateLimited, RejectRateLimited},
+		{access.ErrDenied, RejectAccessDenied},
+		{ErrIncompatibleVersion, RejectIncompatibleVersion},
+		{ErrShuttingDown, RejectShuttingDown},
+		{ErrRequestCancelled, RejectCancelled},
+		{errors.New("other"), RejectUnspecified},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, RejectionCodeOf(tt.err), tt.err.Error())
+	}
+}
+
+func TestReason(t *testing.T) {
+	for _, code := range []RejectionCode{RejectUnspecified, RejectPriceTooHigh, RejectCancelled} {
+		got, reason := decodeReason(encodeReason(code, "too expensive"))
+		require.Equal(t, code, got)
+		require.Equal(t, "too expensive", reason)
+	}
+	code, reason := decodeReason("[not closed")
+	require.Equal(t, RejectUnspecified, code)
+	require.Equal(t, "[not closed", reason)
+
+	err := WrapPerunError(pkgerrors.WithStack(client.PeerRejectedError{ItemType: "channel update", Reason: encodeReason(RejectExpired, "expired")}))
+	var rejected *PeerRejectedError
+	require.True(t, errors.As(err, &rejected))
+	require.Equal(t, &PeerRejectedError{Code: RejectExpired, Reason: "expired"}, rejected)
+	other := errors.New("other")
+	require.Same(t, other, WrapPerunError(other))
+}
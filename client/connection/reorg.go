@@ -0,0 +1,91 @@
+package connection
+
+import (
+	"context"
+	"time"
+
+	"perun.network/go-perun/channel"
+)
+
+// reorgCheckInterval is the interval at which observed adjudicator events
+// are checked against the chain during the reorg window.
+const reorgCheckInterval = 15 * time.Second
+
+type (
+	// DisputeReader reads the dispute of a channel recorded by the
+	// adjudicator in the latest block.
+	DisputeReader interface {
+		Dispute(ctx context.Context, id channel.ID) (OnChainDispute, error)
+	}
+
+	// OnChainDispute is the dispute of a channel recorded by the adjudicator.
+	OnChainDispute struct {
+		Registered bool // False if no state of the channel was registered.
+		Version    uint64
+		Concluded  bool
+	}
+)
+
+// covers reports whether the dispute still contains an event of kind
+// `concluded` for the state of version `version`.
+func (d OnChainDispute) covers(version uint64, concluded bool) bool {
+	if concluded {
+		return d.Concluded
+	}
+	return d.Registered && d.Version >= version
+}
+
+// trackReorg checks the dispute recorded by the adjudicator until the reorg
+// window passed since the event for the state of version `version` was
+// observed. If the event was dropped by a reorg, the channel state is
+// re-evaluated from the recorded dispute.
+func (c *Connection) trackReorg(version uint64, concluded bool) {
+	if c.cfg.Disputes == nil || c.cfg.ReorgWindow <= 0 {
+		return
+	}
+	log := c.log.WithField("phase", "dispute")
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.ReorgWindow)
+	defer cancel()
+
+	ticker := c.cfg.Clock.NewTicker(reorgCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		d, err := c.cfg.Disputes.Dispute(ctx, c.ID())
+		if err != nil {
+			log.Warnf("Reading dispute: %v", err)
+			continue
+		} else if d.covers(version, concluded) {
+			continue
+		}
+
+		log.Warnf("Event for version %d dropped by reorg", version)
+		c.revertDispute(d)
+		return
+	}
+}
+
+// revertDispute resets the dispute state of the connection to the dispute
+// `d` recorded on-chain. go-perun does not revert the phase of the channel
+// machine, so the channel can still not be updated off-chain.
+func (c *Connection) revertDispute(d OnChainDispute) {
+	if !d.Concluded {
+		c.concluded.SetValue(false)
+	}
+	if !d.Registered {
+		c.disputed.SetValue(false)
+		c.concludable.SetValue(false)
+		c.onChain.swap(nil)
+	}
+	c.notify(&DisputeReverted{
+		EventHeader: c.header(),
+		Registered:  d.Registered,
+		Version:     d.Version,
+		Concluded:   d.Concluded,
+	})
+}
@@ -0,0 +1,87 @@
+package connection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/pkg/jsonschema"
+)
+
+// schemaErrorPrefix prefixes the rejection reason of requests that failed
+// schema validation. It is followed by the JSON-encoded validation errors.
+const schemaErrorPrefix = "schema validation failed: "
+
+// SchemaError indicates that a document does not match the schema of the
+// requested credential type.
+type SchemaError struct {
+	Errors []jsonschema.Error
+}
+
+func (e *SchemaError) Error() string {
+	b, err := json.Marshal(e.Errors)
+	if err != nil {
+		return schemaErrorPrefix + err.Error()
+	}
+	return schemaErrorPrefix + string(b)
+}
+
+// AsSchemaError returns the validation errors if the issuer rejected a
+// request because the document does not match the schema.
+func AsSchemaError(err error) (*SchemaError, bool) {
+	var serr *SchemaError
+	if errors.As(err, &serr) {
+		return serr, true
+	}
+
+	var rejected *PeerRejectedError
+	if !errors.As(err, &rejected) || !strings.HasPrefix(rejected.Reason, schemaErrorPrefix) {
+		return nil, false
+	}
+	serr = &SchemaError{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(rejected.Reason, schemaErrorPrefix)), &serr.Errors); err != nil {
+		return nil, false
+	}
+	return serr, true
+}
+
+// SchemaRegistry holds the JSON schemas of credential types.
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas map[string]*jsonschema.Schema
+}
+
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string]*jsonschema.Schema)}
+}
+
+// Register registers JSON schema `schema` for credential type `typ`.
+func (r *SchemaRegistry) Register(typ string, schema []byte) error {
+	s, err := jsonschema.Compile(schema)
+	if err != nil {
+		return fmt.Errorf("compiling schema for %s: %w", typ, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[typ] = s
+	return nil
+}
+
+// Validate validates document `doc` against the schema of credential type
+// `typ`. Documents of types without schema are always valid.
+func (r *SchemaRegistry) Validate(typ string, doc []byte) error {
+	r.mu.RLock()
+	s, ok := r.schemas[typ]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	if errs := s.Validate(doc); len(errs) > 0 {
+		return &SchemaError{Errors: errs}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/policy"
+)
+
+// DocumentResolver returns the document with hash `h`, if it is known.
+type DocumentResolver func(h app.Hash) (doc []byte, ok bool)
+
+// ServeCredentialRequests decides on incoming credential requests according
+// to policy `p` until the context is done. Requested documents are obtained
+// from `docs`, or from the documents received out-of-band from the peer if
+// `docs` is nil.
+// Accepted requests are issued using account `acc`. Requests that offer less
+// than the price asked by a policy.Priced rule are countered with the asked
+// price, all others are rejected with the reason given by the policy.
+func (c *Connection) ServeCredentialRequests(
+	ctx context.Context,
+	p policy.Policy,
+	docs DocumentResolver,
+	acc app.Account,
+) error {
+	if docs == nil {
+		docs = c.docs.Get
+	}
+	for {
+		req, err := c.NextCredentialRequest(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = c.serveCredentialRequest(ctx, req, p, docs, acc)
+		if err != nil {
+			c.log.WithField("phase", "serve").Warnf("Serving credential request: %v", err)
+		}
+	}
+}
+
+func (c *Connection) serveCredentialRequest(
+	ctx context.Context,
+	req *CredentialRequest,
+	p policy.Policy,
+	docs DocumentResolver,
+	acc app.Account,
+) error {
+	var decision error
+	doc, ok := docs(req.offer.DataHash)
+	if !ok {
+		decision = ErrWrongDocument
+	} else {
+		decision = req.Evaluate(p, doc)
+	}
+
+	var counter *policy.CounterError
+	if errors.As(decision, &counter) {
+		return req.CounterOffer(ctx, counter.Price)
+	} else if decision != nil {
+		if err := req.RejectWithCode(ctx, RejectionCodeOf(decision), decision.Error()); err != nil {
+			return err
+		}
+		return fmt.Errorf("rejected request: %w", decision)
+	}
+
+	return req.IssueCredential(ctx, acc)
+}
@@ -0,0 +1,49 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindSession is the message kind of session requests.
+const MsgKindSession = "session"
+
+// HandleSessionRequests answers session requests with `s`, which authorizes
+// our key on behalf of a funding account, or with nil if there is none.
+func HandleSessionRequests(m *message.Messenger, s *app.Session) {
+	m.Handle(MsgKindSession, func(context.Context, wire.Address, json.RawMessage) (interface{}, error) {
+		return s, nil
+	})
+}
+
+// RequestAccount requests the session of `peer` and returns the funding
+// account that authorized the peer's key. It returns the address of the peer
+// if the peer does not use a session key.
+func RequestAccount(ctx context.Context, m *message.Messenger, peer wire.Address, clk clock.Clock) (common.Address, error) {
+	var s *app.Session
+	if err := m.Request(ctx, peer, MsgKindSession, nil, &s); err != nil {
+		return common.Address{}, fmt.Errorf("requesting session: %w", err)
+	}
+	addr := ethwallet.AsEthAddr(peer)
+	if s == nil {
+		return addr, nil
+	} else if s.Key != addr {
+		return common.Address{}, fmt.Errorf("session authorizes %v, expected %v", s.Key, addr)
+	} else if err := s.Verify(clk.Now()); err != nil {
+		return common.Address{}, fmt.Errorf("verifying session: %w", err)
+	}
+	return s.Account, nil
+}
+
+// RequestAccount returns the funding account of the peer of the connection.
+func (c *Connection) RequestAccount(ctx context.Context) (common.Address, error) {
+	return RequestAccount(ctx, c.cfg.Messenger, c.peer(), c.cfg.Clock)
+}
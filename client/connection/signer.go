@@ -0,0 +1,69 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+)
+
+// Signer makes issuer signatures on credentials. It abstracts from where the
+// signing key is held, e.g., by a threshold-ECDSA protocol whose t-of-n
+// shares are held by different operators, so that no single machine holds
+// the issuer key.
+type Signer interface {
+	// Address returns the address of the signing key.
+	Address() common.Address
+	// SignHash returns the Ethereum signature of `h` in the format of
+	// app.SignHash. It may block until enough parties took part.
+	SignHash(ctx context.Context, h app.Hash) ([]byte, error)
+}
+
+// AccountSigner returns a Signer that signs with `acc`.
+func AccountSigner(acc app.Account) Signer {
+	return accountKey{acc}
+}
+
+type accountKey struct {
+	acc app.Account
+}
+
+func (k accountKey) Address() common.Address {
+	return app.AccountAddress(k.acc)
+}
+
+func (k accountKey) SignHash(_ context.Context, h app.Hash) ([]byte, error) {
+	sig, err := app.SignHash(k.acc, h)
+	return sig[:], err
+}
+
+// IssueSignedBy issues the credential with the signature of `s`, which must
+// be the signer of the issuer requested by the offer. The signature is made
+// and checked before the request is accepted, as a signer that involves
+// other parties may fail. The cosignatures are given as in
+// IssueCoSignedCredential, and `acc` is the channel account, which signs the
+// receipt.
+func (r *CredentialRequest) IssueSignedBy(ctx context.Context, acc app.Account, s Signer, cosigs [][]byte) error {
+	if len(r.offer.BBSKey) != 0 {
+		return fmt.Errorf("request requires a BBS signature")
+	} else if s.Address() != r.offer.Issuer {
+		return fmt.Errorf("unequal addresses: got %v, expected %v", s.Address(), r.offer.Issuer)
+	}
+	h := r.SigningHash()
+	sig, err := s.SignHash(ctx, h)
+	if err != nil {
+		return fmt.Errorf("signing hash: %w", err)
+	} else if len(sig) != data.SigLen {
+		return app.ErrInvalidSignature
+	}
+
+	var fixed [data.SigLen]byte
+	copy(fixed[:], sig)
+	if err := app.VerifySig(fixed, h, r.offer.Issuer); err != nil {
+		return fmt.Errorf("verifying signature: %w", err)
+	}
+	sign := func(*data.Offer) ([data.SigLen]byte, error) { return fixed, nil }
+	return r.issue(ctx, acc, sign, cosigs, nil)
+}
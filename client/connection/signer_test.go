@@ -0,0 +1,78 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+func newAccount(t *testing.T) *simple.Account {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(t, err)
+	return acc.(*simple.Account)
+}
+
+// fakeSigner claims `addr` and returns `sig` or `err` if set, or the
+// signature of `signer` otherwise.
+type fakeSigner struct {
+	addr   common.Address
+	signer Signer
+	sig    []byte
+	err    error
+}
+
+func (s fakeSigner) Address() common.Address { return s.addr }
+
+func (s fakeSigner) SignHash(ctx context.Context, h app.Hash) ([]byte, error) {
+	if s.err != nil || s.sig != nil {
+		return s.sig, s.err
+	}
+	return s.signer.SignHash(ctx, h)
+}
+
+func TestAccountSigner(t *testing.T) {
+	acc := newAccount(t)
+	s := AccountSigner(acc)
+	require.Equal(t, acc.Account.Address, s.Address())
+
+	h := app.Hash{1}
+	sig, err := s.SignHash(context.Background(), h)
+	require.NoError(t, err)
+	require.Len(t, sig, data.SigLen)
+	var fixed [data.SigLen]byte
+	copy(fixed[:], sig)
+	require.NoError(t, app.VerifySig(fixed, h, s.Address()))
+}
+
+// TestIssueSignedBy checks that signatures of a signer are checked before
+// the request is accepted.
+func TestIssueSignedBy(t *testing.T) {
+	ctx := context.Background()
+	issuer, other := AccountSigner(newAccount(t)), AccountSigner(newAccount(t))
+	r := &CredentialRequest{offer: &data.Offer{Issuer: issuer.Address(), DataHash: [32]byte{1}, Price: big.NewInt(1)}}
+	acc := newAccount(t)
+
+	require.Error(t, r.IssueSignedBy(ctx, acc, other, nil), "signer of other issuer")
+	errSign := errors.New("too few parties")
+	err := r.IssueSignedBy(ctx, acc, fakeSigner{addr: issuer.Address(), err: errSign}, nil)
+	require.ErrorIs(t, err, errSign)
+	err = r.IssueSignedBy(ctx, acc, fakeSigner{addr: issuer.Address(), sig: []byte{1}}, nil)
+	require.ErrorIs(t, err, app.ErrInvalidSignature)
+	err = r.IssueSignedBy(ctx, acc, fakeSigner{addr: issuer.Address(), signer: other}, nil)
+	require.Error(t, err, "signature of other key")
+
+	bbs := &CredentialRequest{offer: &data.Offer{Issuer: issuer.Address(), BBSKey: []byte{1}}}
+	require.Error(t, bbs.IssueSignedBy(ctx, acc, issuer, nil), "BBS request")
+}
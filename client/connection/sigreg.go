@@ -7,7 +7,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/perun-network/perun-credential-payment/app"
-	"perun.network/go-perun/client"
 )
 
 type (
@@ -16,7 +15,7 @@ type (
 		DocHash app.Hash
 	}
 
-	sigRegReturnVal = *CredentialProposal
+	sigRegReturnVal = CredentialResponse
 
 	sigReg struct {
 		sync.RWMutex
@@ -46,7 +45,7 @@ func (r *sigReg) RegisterCallback(h app.Hash, issuer common.Address) (sigRegCall
 	return sigRegCallback(callback), nil
 }
 
-func (r *sigReg) Push(sig app.Signature, h app.Hash, issuer common.Address, responder *client.UpdateResponder) {
+func (r *sigReg) Push(h app.Hash, issuer common.Address, prop sigRegReturnVal) {
 	r.Lock()
 	defer r.Unlock()
 
@@ -56,13 +55,17 @@ func (r *sigReg) Push(sig app.Signature, h app.Hash, issuer common.Address, resp
 		return
 	}
 
-	cb <- &CredentialProposal{
-		UpdateResponder: responder,
-		Signature:       sig,
-	}
+	cb <- prop
 	delete(r.callbacks, k)
 }
 
+func (r *sigReg) Unregister(h app.Hash, issuer common.Address) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.callbacks, sigRegKey{Issuer: issuer, DocHash: h})
+}
+
 type sigRegCallback chan sigRegReturnVal
 
 func (cb sigRegCallback) Await(ctx context.Context) (sigRegReturnVal, error) {
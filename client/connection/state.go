@@ -0,0 +1,116 @@
+package connection
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+)
+
+// Phase is the phase of the credential swap in a channel.
+type Phase string
+
+const (
+	PhaseIdle           Phase = "idle"            // No request in progress.
+	PhaseRequested      Phase = "requested"       // A credential was requested.
+	PhaseCounterOffered Phase = "counter_offered" // The issuer countered the request.
+	PhaseIssued         Phase = "issued"          // The requested credential was issued.
+	PhaseBatchRequested Phase = "batch_requested" // A batch of credentials was requested.
+	PhaseBatchIssued    Phase = "batch_issued"    // The requested batch was issued.
+)
+
+type (
+	// ChannelState describes the current state of a channel.
+	ChannelState struct {
+		ID        channel.ID           `json:"id"`
+		Version   uint64               `json:"version"`
+		Phase     Phase                `json:"phase"`
+		Idx       channel.Index        `json:"idx"` // The own index.
+		Balances  []ParticipantBalance `json:"balances"`
+		Pending   *PendingRequest      `json:"pending,omitempty"` // Set in the requested phases.
+		IsFinal   bool                 `json:"isFinal"`
+		Disputed  bool                 `json:"disputed"`
+		Concluded bool                 `json:"concluded"`
+		Migrating bool                 `json:"migrating"`
+		Draining  bool                 `json:"draining"`
+	}
+
+	// ParticipantBalance is the balance of a channel participant.
+	ParticipantBalance struct {
+		Address common.Address `json:"address"`
+		Balance *big.Int       `json:"balance"`
+	}
+
+	// PendingRequest describes the request in progress in a channel.
+	PendingRequest struct {
+		Issuer    common.Address `json:"issuer"`
+		Buyer     channel.Index  `json:"buyer"`
+		DocHashes []app.Hash     `json:"docHashes"`
+		Price     *big.Int       `json:"price"`
+		Fee       *big.Int       `json:"fee,omitempty"`
+		Expiry    uint64         `json:"expiry,omitempty"`
+	}
+)
+
+// Inspect returns the current state of the channel. Unlike the go-perun
+// state returned by State, it describes the app data.
+func (c *Connection) Inspect() *ChannelState {
+	s := c.State()
+	cs := &ChannelState{
+		ID:        c.ID(),
+		Version:   s.Version,
+		Idx:       c.Idx(),
+		IsFinal:   s.IsFinal,
+		Disputed:  c.Disputed(),
+		Concluded: c.concluded.Value(),
+		Migrating: c.Migrating(),
+		Draining:  c.Draining(),
+	}
+	for i, p := range c.Peers() {
+		cs.Balances = append(cs.Balances, ParticipantBalance{
+			Address: ethwallet.AsEthAddr(p),
+			Balance: new(big.Int).Set(s.Balances[app.AssetIdx][i]),
+		})
+	}
+
+	switch d := s.Data.(type) {
+	case *data.Offer:
+		cs.Phase, cs.Pending = PhaseRequested, pendingOffer(d)
+	case *data.CounterOffer:
+		cs.Phase, cs.Pending = PhaseCounterOffered, pendingOffer(&d.Offer)
+	case *data.Cert:
+		cs.Phase = PhaseIssued
+	case *data.BatchOffer:
+		cs.Phase = PhaseBatchRequested
+		cs.Pending = &PendingRequest{
+			Issuer: d.Issuer,
+			Buyer:  channel.Index(d.Buyer),
+			Price:  new(big.Int).Set(d.Price),
+		}
+		for _, h := range d.DataHashes {
+			cs.Pending.DocHashes = append(cs.Pending.DocHashes, h)
+		}
+	case *data.BatchCert:
+		cs.Phase = PhaseBatchIssued
+	default:
+		cs.Phase = PhaseIdle
+	}
+	return cs
+}
+
+func pendingOffer(o *data.Offer) *PendingRequest {
+	p := &PendingRequest{
+		Issuer:    o.Issuer,
+		Buyer:     channel.Index(o.Buyer),
+		DocHashes: []app.Hash{o.DataHash},
+		Price:     new(big.Int).Set(o.Price),
+		Expiry:    o.Expiry,
+	}
+	if o.Fee != nil {
+		p.Fee = new(big.Int).Set(o.Fee)
+	}
+	return p
+}
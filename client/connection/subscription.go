@@ -0,0 +1,201 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+)
+
+var ErrExpiryExceedsPeriod = errors.New("expiry exceeds subscription period")
+
+// SubscriptionTerms are the terms of a credential subscription. The holder
+// pays Price every Period and receives a fresh credential that expires
+// Period + Grace after the payment, so that it lapses if a payment is
+// missed.
+type SubscriptionTerms struct {
+	Price    *big.Int
+	Period   time.Duration
+	Grace    time.Duration // Tolerated delay of a payment.
+	Metadata *app.Metadata // Optional. The issuance date is set for every renewal.
+}
+
+func (t *SubscriptionTerms) validity() time.Duration {
+	return t.Period + t.Grace
+}
+
+// Subscription is a running credential subscription of a holder.
+type Subscription struct {
+	creds  chan *app.Credential
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Subscribe subscribes to the credential for document `doc` from `issuer`.
+// It pays for the first credential before returning and renews it every
+// period until `ctx` is done, Cancel is called, or a renewal fails.
+func (c *Connection) Subscribe(ctx context.Context, doc []byte, issuer common.Address, terms SubscriptionTerms) (*Subscription, error) {
+	if terms.Period <= 0 {
+		return nil, fmt.Errorf("invalid subscription period: %v", terms.Period)
+	}
+
+	cred, err := c.renew(ctx, doc, issuer, &terms)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Subscription{
+		creds:  make(chan *app.Credential, 1),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.creds <- cred
+	go s.run(ctx, c, doc, issuer, &terms)
+	return s, nil
+}
+
+func (s *Subscription) run(ctx context.Context, c *Connection, doc []byte, issuer common.Address, terms *SubscriptionTerms) {
+	defer close(s.done)
+	defer close(s.creds)
+
+	t := c.cfg.Clock.NewTicker(terms.Period)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			return
+		}
+
+		cred, err := c.renew(ctx, doc, issuer, terms)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.err = err
+				c.log.Warnf("Renewing subscription: %v", err)
+			}
+			return
+		}
+		select {
+		case s.creds <- cred:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// renew pays for and obtains one credential of a subscription.
+func (c *Connection) renew(ctx context.Context, doc []byte, issuer common.Address, terms *SubscriptionTerms) (*app.Credential, error) {
+	now := c.cfg.Clock.Now()
+	opts := CredentialOptions{Expiry: now.Add(terms.validity())}
+	if terms.Metadata != nil {
+		meta := *terms.Metadata
+		meta.IssuedAt = uint64(now.Unix())
+		opts.Metadata = &meta
+	}
+
+	async, err := c.RequestCredentialWithOptions(ctx, doc, terms.Price, issuer, opts)
+	if err != nil {
+		return nil, fmt.Errorf("requesting credential: %w", err)
+	}
+	p, err := async.Await(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("awaiting credential: %w", err)
+	}
+	if err := p.Accept(ctx); err != nil {
+		return nil, fmt.Errorf("accepting credential: %w", err)
+	}
+	return &app.Credential{
+		Document:     doc,
+		Signature:    p.Signature,
+		Expiry:       p.Expiry(),
+		Metadata:     opts.Metadata,
+		Domain:       p.Domain(),
+		CoSignatures: p.CoSignatures,
+	}, nil
+}
+
+// Credentials returns the credentials of the subscription, one per period.
+// The channel is closed when the subscription ends.
+func (s *Subscription) Credentials() <-chan *app.Credential {
+	return s.creds
+}
+
+// Cancel ends the subscription. The last credential stays valid until it
+// expires.
+func (s *Subscription) Cancel() {
+	s.cancel()
+	<-s.done
+}
+
+// Err returns the error that ended the subscription, if any. It must only be
+// called after the credentials channel is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// IssueSubscription issues a renewal of a subscription with `terms`. The
+// request must pay the subscription price for a credential that expires
+// within one period and the grace time. If the subscriber does not renew in
+// that time, the subscription lapses and a SubscriptionLapsed event is
+// emitted.
+func (r *CredentialRequest) IssueSubscription(ctx context.Context, acc app.Account, terms SubscriptionTerms) error {
+	if err := r.CheckPrice(terms.Price); err != nil {
+		return err
+	}
+	maxExpiry := r.conn.cfg.Clock.Now().Add(terms.validity() + MaxIssuanceDateDeviation)
+	if r.offer.Expiry == 0 || time.Unix(int64(r.offer.Expiry), 0).After(maxExpiry) {
+		return ErrExpiryExceedsPeriod
+	}
+
+	if err := r.IssueCredential(ctx, acc); err != nil {
+		return err
+	}
+	r.conn.subs.renewed(r.conn, r.offer.DataHash, terms.validity())
+	return nil
+}
+
+// subscriptions tracks the subscriptions served by the issuer of a
+// connection, by document hash.
+type subscriptions struct {
+	mu     sync.Mutex
+	lapses map[app.Hash]chan struct{} // Closed when the lapse is rescheduled.
+}
+
+// renewed schedules the lapse of the subscription for `docHash` after `d` on
+// the clock of `c`, replacing the previous schedule.
+func (s *subscriptions) renewed(c *Connection, docHash app.Hash, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lapses == nil {
+		s.lapses = make(map[app.Hash]chan struct{})
+	}
+	if stop, ok := s.lapses[docHash]; ok {
+		close(stop)
+	}
+
+	stop := make(chan struct{})
+	s.lapses[docHash] = stop
+	lapse := c.cfg.Clock.After(d)
+	go func() {
+		select {
+		case <-lapse:
+		case <-stop:
+			return
+		}
+		s.mu.Lock()
+		if s.lapses[docHash] != stop {
+			s.mu.Unlock()
+			return // Renewed concurrently.
+		}
+		delete(s.lapses, docHash)
+		s.mu.Unlock()
+		c.notify(&SubscriptionLapsed{EventHeader: c.header(), DocHash: docHash})
+	}()
+}
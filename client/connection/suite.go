@@ -0,0 +1,86 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindSuiteSignature is the message kind of suite signature requests.
+const MsgKindSuiteSignature = "suite-signature"
+
+// suiteSigs holds the suite signatures of the credentials issued by us in a
+// connection, by credential ID.
+type suiteSigs struct {
+	mu   sync.Mutex
+	sigs map[app.Hash]*app.SuiteSignature
+}
+
+func (s *suiteSigs) add(id app.Hash, sig *app.SuiteSignature) {
+	if sig == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sigs == nil {
+		s.sigs = make(map[app.Hash]*app.SuiteSignature)
+	}
+	s.sigs[id] = sig
+}
+
+func (s *suiteSigs) get(id app.Hash) (*app.SuiteSignature, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sig, ok := s.sigs[id]
+	return sig, ok
+}
+
+// signSuite signs the credential requested by `offer` with the suite signer
+// of the config. It returns nil if none is configured.
+func (c *Connection) signSuite(offer *data.Offer) (*app.SuiteSignature, error) {
+	if c.cfg.SuiteSigner == nil {
+		return nil, nil
+	}
+	return app.SignCredentialID(c.cfg.SuiteSigner, app.OfferHash(offer))
+}
+
+// HandleSuiteSignatureRequests answers the requests of holders for the suite
+// signatures of the credentials issued to them in the connections of `reg`.
+func HandleSuiteSignatureRequests(m *message.Messenger, reg *Registry) {
+	m.Handle(MsgKindSuiteSignature, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var id app.Hash
+		if err := json.Unmarshal(body, &id); err != nil {
+			return nil, fmt.Errorf("decoding credential ID: %w", err)
+		}
+		for _, c := range reg.All() {
+			if !c.peer().Equals(peer) {
+				continue
+			}
+			if sig, ok := c.suiteSigs.get(id); ok {
+				return sig, nil
+			}
+		}
+		return nil, ErrNoSuiteSignature
+	})
+}
+
+// RequestSuiteSignature requests the suite signature of credential `cred`,
+// which the peer issued to us, and verifies it. Whether the public key of
+// the signature belongs to the issuer is up to the verifiers, e.g., by the
+// DID document of the issuer.
+func (c *Connection) RequestSuiteSignature(ctx context.Context, cred *app.Credential) (*app.SuiteSignature, error) {
+	var sig app.SuiteSignature
+	if err := c.cfg.Messenger.Request(ctx, c.peer(), MsgKindSuiteSignature, cred.ID(), &sig); err != nil {
+		return nil, fmt.Errorf("requesting suite signature: %w", err)
+	}
+	if err := sig.Verify(cred); err != nil {
+		return nil, fmt.Errorf("verifying suite signature: %w", err)
+	}
+	return &sig, nil
+}
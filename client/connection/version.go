@@ -0,0 +1,180 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindVersion is the message kind of version handshakes.
+const MsgKindVersion = "version"
+
+// Versions of the protocol, which covers the app data schema and the
+// exchanges between peers. Peers that predate the version handshake speak
+// LegacyProtocol.
+const (
+	LegacyProtocol  uint16 = 1
+	CurrentProtocol uint16 = 2
+)
+
+// Features are optional extensions of the protocol.
+const (
+	FeatureBatch        = "batch"
+	FeatureBBS          = "bbs"
+	FeatureCounterOffer = "counter-offer"
+	FeatureFee          = "fee"
+	FeatureMigration    = "migration"
+)
+
+var ErrIncompatibleVersion = errors.New("incompatible protocol version")
+
+// Version is the protocol version spoken by a client, the oldest version it
+// still speaks, and the features it supports.
+type Version struct {
+	Protocol    uint16   `json:"protocol"`
+	MinProtocol uint16   `json:"minProtocol"`
+	Features    []string `json:"features,omitempty"`
+}
+
+// CurrentVersion returns the version of this implementation.
+func CurrentVersion() Version {
+	return Version{
+		Protocol:    CurrentProtocol,
+		MinProtocol: LegacyProtocol,
+		Features:    []string{FeatureBatch, FeatureBBS, FeatureCounterOffer, FeatureFee, FeatureMigration},
+	}
+}
+
+// legacyVersion is the version assumed for peers that do not answer the
+// version handshake.
+func legacyVersion() Version {
+	return Version{Protocol: LegacyProtocol, MinProtocol: LegacyProtocol}
+}
+
+// Supports returns whether feature `f` is in the version.
+func (v Version) Supports(f string) bool {
+	for _, g := range v.Features {
+		if g == f {
+			return true
+		}
+	}
+	return false
+}
+
+// IncompatibleVersionError is returned if the protocol versions spoken by us
+// and the peer do not overlap.
+type IncompatibleVersionError struct {
+	Local, Remote Version
+}
+
+func (e *IncompatibleVersionError) Error() string {
+	return fmt.Sprintf("%v: we speak %d to %d, peer speaks %d to %d", ErrIncompatibleVersion,
+		e.Local.MinProtocol, e.Local.Protocol, e.Remote.MinProtocol, e.Remote.Protocol)
+}
+
+func (e *IncompatibleVersionError) Is(target error) bool {
+	return target == ErrIncompatibleVersion
+}
+
+// agree returns the highest protocol version spoken by both `local` and
+// `remote` and the features supported by both.
+func agree(local, remote Version) (Version, error) {
+	if remote.Protocol < local.MinProtocol || local.Protocol < remote.MinProtocol {
+		return Version{}, &IncompatibleVersionError{Local: local, Remote: remote}
+	}
+	v := Version{Protocol: local.Protocol, MinProtocol: local.MinProtocol}
+	if remote.Protocol < v.Protocol {
+		v.Protocol = remote.Protocol
+	}
+	if remote.MinProtocol > v.MinProtocol {
+		v.MinProtocol = remote.MinProtocol
+	}
+	for _, f := range local.Features {
+		if remote.Supports(f) {
+			v.Features = append(v.Features, f)
+		}
+	}
+	return v, nil
+}
+
+// Versions records the versions announced by peers in handshakes.
+type Versions struct {
+	local Version
+
+	mu    sync.Mutex
+	peers map[common.Address]Version
+}
+
+// NewVersions returns an empty record for a client speaking `local`.
+func NewVersions(local Version) *Versions {
+	return &Versions{local: local, peers: make(map[common.Address]Version)}
+}
+
+// Local returns the version of the client.
+func (v *Versions) Local() Version {
+	return v.local
+}
+
+// Agreed returns the version agreed with `peer`, or an
+// *IncompatibleVersionError. Peers without handshake speak the legacy
+// version.
+func (v *Versions) Agreed(peer wire.Address) (Version, error) {
+	v.mu.Lock()
+	remote, ok := v.peers[ethwallet.AsEthAddr(peer)]
+	v.mu.Unlock()
+	if !ok {
+		remote = legacyVersion()
+	}
+	return agree(v.local, remote)
+}
+
+func (v *Versions) set(peer wire.Address, remote Version) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.peers[ethwallet.AsEthAddr(peer)] = remote
+}
+
+// HandleVersionRequests answers version handshakes with the version of `v`
+// and records the version of the requester.
+func HandleVersionRequests(m *message.Messenger, v *Versions) {
+	m.Handle(MsgKindVersion, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var remote Version
+		if err := json.Unmarshal(body, &remote); err != nil {
+			return nil, fmt.Errorf("decoding version: %w", err)
+		}
+		v.set(peer, remote)
+		return v.local, nil
+	})
+}
+
+// Handshake exchanges versions with `peer` and returns the agreed version,
+// or an *IncompatibleVersionError. Peers that do not know the handshake are
+// recorded with the legacy version.
+func Handshake(ctx context.Context, m *message.Messenger, peer wire.Address, v *Versions) (Version, error) {
+	var remote Version
+	err := m.Request(ctx, peer, MsgKindVersion, v.local, &remote)
+	var remoteErr *message.RemoteError
+	if errors.As(err, &remoteErr) && remoteErr.Reason == message.ErrUnknownKind.Error() {
+		remote = legacyVersion()
+	} else if err != nil {
+		return Version{}, fmt.Errorf("exchanging versions: %w", err)
+	}
+	v.set(peer, remote)
+	return agree(v.local, remote)
+}
+
+// Version returns the version agreed with the peer of the connection.
+func (c *Connection) Version() Version {
+	if c.cfg.Versions == nil {
+		return legacyVersion()
+	}
+	v, _ := c.cfg.Versions.Agreed(c.peer())
+	return v
+}
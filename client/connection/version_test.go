@@ -0,0 +1,89 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+func TestAgree(t *testing.T) {
+	local := Version{Protocol: 3, MinProtocol: 2, Features: []string{FeatureBatch, FeatureFee}}
+
+	v, err := agree(local, Version{Protocol: 4, MinProtocol: 1, Features: []string{FeatureFee, FeatureBBS}})
+	require.NoError(t, err)
+	require.Equal(t, Version{Protocol: 3, MinProtocol: 2, Features: []string{FeatureFee}}, v)
+	require.True(t, v.Supports(FeatureFee))
+	require.False(t, v.Supports(FeatureBatch), "not supported by remote")
+
+	v, err = agree(local, Version{Protocol: 2, MinProtocol: 2})
+	require.NoError(t, err)
+	require.Equal(t, uint16(2), v.Protocol)
+	require.Empty(t, v.Features)
+
+	for _, remote := range []Version{legacyVersion(), {Protocol: 5, MinProtocol: 4}} {
+		_, err = agree(local, remote)
+		var incompatible *IncompatibleVersionError
+		require.True(t, errors.As(err, &incompatible), "incompatible %v", remote)
+		require.Equal(t, remote, incompatible.Remote)
+		require.ErrorIs(t, err, ErrIncompatibleVersion)
+	}
+}
+
+func TestVersionsAgreed(t *testing.T) {
+	vs := NewVersions(CurrentVersion())
+	peer := ethwallet.AsWalletAddr(common.Address{1})
+
+	// Peers without handshake speak the legacy version.
+	v, err := vs.Agreed(peer)
+	require.NoError(t, err)
+	require.Equal(t, LegacyProtocol, v.Protocol)
+	require.Empty(t, v.Features)
+
+	vs.set(peer, CurrentVersion())
+	v, err = vs.Agreed(peer)
+	require.NoError(t, err)
+	require.Equal(t, CurrentProtocol, v.Protocol)
+	require.True(t, v.Supports(FeatureMigration))
+
+	require.Equal(t, LegacyProtocol, (&Connection{cfg: &Config{}}).Version().Protocol, "without versions")
+}
+
+// newVersionPeer returns the messenger of a peer at `addr` on `bus`.
+func newVersionPeer(t *testing.T, bus wire.Bus, addr wire.Address) *message.Messenger {
+	t.Helper()
+	m := message.NewMessenger(bus, addr)
+	t.Cleanup(m.Close)
+	require.NoError(t, message.NewBus(bus, m).SubscribeClient(wire.NewReceiver(), addr))
+	return m
+}
+
+func TestHandshake(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	bus := wire.NewLocalBus()
+	alice, bob, carol := ethwallet.AsWalletAddr(common.Address{1}), ethwallet.AsWalletAddr(common.Address{2}), ethwallet.AsWalletAddr(common.Address{3})
+	am := newVersionPeer(t, bus, alice)
+	bm := newVersionPeer(t, bus, bob)
+	newVersionPeer(t, bus, carol)
+	av, bv := NewVersions(CurrentVersion()), NewVersions(Version{Protocol: 9, MinProtocol: 9})
+	HandleVersionRequests(am, av)
+	HandleVersionRequests(bm, bv)
+
+	// Both peers record the version of the other.
+	_, err := Handshake(ctx, am, bob, av)
+	require.ErrorIs(t, err, ErrIncompatibleVersion)
+	_, err = bv.Agreed(alice)
+	require.ErrorIs(t, err, ErrIncompatibleVersion)
+
+	// Peers that do not know the handshake speak the legacy version.
+	v, err := Handshake(ctx, am, carol, av)
+	require.NoError(t, err)
+	require.Equal(t, LegacyProtocol, v.Protocol)
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/client/connection"
+)
+
+// eventBufferSize is the number of events buffered per subscriber. Events
+// are dropped for subscribers that do not keep up.
+const eventBufferSize = 64
+
+type eventSubs struct {
+	mu     sync.Mutex
+	subs   map[chan connection.Event]struct{}
+	closed bool
+	done   chan struct{} // Closed by close.
+}
+
+func newEventSubs() *eventSubs {
+	return &eventSubs{
+		subs: make(map[chan connection.Event]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Events returns a stream of the events of all connections of the client.
+// The events are of type *connection.ChannelOpened,
+// *connection.CredentialRequested, *connection.CredentialIssued,
+// *connection.UpdateRejected, *connection.DisputeRegistered,
+// *connection.DisputeProgressed, *connection.ChannelConcluded,
+// *connection.DisputeReverted, *connection.ChannelClosed,
+// *connection.SubscriptionLapsed, and *connection.ChannelMigrated. The stream
+// is closed when `ctx` is done or the client shuts down.
+func (c *Client) Events(ctx context.Context) <-chan connection.Event {
+	events := make(chan connection.Event, eventBufferSize)
+
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+	if c.events.closed {
+		close(events)
+		return events
+	}
+	c.events.subs[events] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.events.remove(events)
+		case <-c.events.done:
+		}
+	}()
+	return events
+}
+
+func (s *eventSubs) publish(e connection.Event) (dropped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subs {
+		select {
+		case sub <- e:
+		default:
+			dropped++
+		}
+	}
+	return
+}
+
+func (s *eventSubs) remove(sub chan connection.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub)
+	}
+}
+
+func (s *eventSubs) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	close(s.done)
+	for sub := range s.subs {
+		close(sub)
+	}
+	s.subs = nil
+	s.closed = true
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsClose(t *testing.T) {
+	c := &Client{events: newEventSubs()}
+	before := runtime.NumGoroutine()
+
+	// Subscriptions whose context is never done end when the client closes.
+	events := c.Events(context.Background())
+	require.Zero(t, c.events.publish(&connection.ChannelOpened{}))
+	c.events.close()
+	require.IsType(t, &connection.ChannelOpened{}, <-events)
+	_, ok := <-events
+	require.False(t, ok, "stream closed")
+	require.Eventually(t, func() bool { return runtime.NumGoroutine() <= before }, time.Second, 10*time.Millisecond)
+
+	// Subscribing after the client closed returns a closed stream.
+	_, ok = <-c.Events(context.Background())
+	require.False(t, ok)
+	c.events.close()
+}
+
+func TestEventsCancel(t *testing.T) {
+	c := &Client{events: newEventSubs()}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := c.Events(ctx)
+	cancel()
+	for range events {
+	}
+	require.Zero(t, c.events.publish(&connection.ChannelOpened{}))
+	c.events.close()
+}
@@ -1,28 +1,89 @@
 package client
 
 import (
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/perun-network/perun-credential-payment/app/data"
 	"github.com/perun-network/perun-credential-payment/client/connection"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/client"
 )
 
+// proposerIdx is the index of the proposer in the peers of a two-party
+// channel proposal.
+const proposerIdx = 0
+
 type handler struct {
 	*Client
 }
 
 func (h *handler) HandleProposal(p client.ChannelProposal, r *client.ProposalResponder) {
+	defer h.supervisor.Recover("proposal handler")
 	lp, ok := p.(*client.LedgerChannelProposal)
 	if !ok {
-		h.Logf("invalid proposal type: %T", p)
+		h.log.Warnf("invalid proposal type: %T", p)
+		return
+	}
+	propID := lp.ProposalID()
+	h.audit.record("proposal_received", hexutil.Encode(propID[:]), newProposalDetails(lp.Participant, lp))
+	prop := connection.NewChannelProposal(lp, r)
+	req := connection.NewConnectionRequest(prop, h.PerunAddress(), h.connections, h.connCfg)
+	if code, err := h.checkProposal(lp); err != nil {
+		go h.rejectProposal(req, code, err.Error())
 		return
 	}
-	h.channelProposals <- connection.NewChannelProposal(lp, r)
+	if mig, ok := h.takeMigration(lp); ok {
+		go h.reopen(mig, req)
+		return
+	}
+	proposals := h.channelProposals
+	if d, ok := lp.InitData.(*data.DefaultData); ok && d.Tenant != "" {
+		t, ok := h.tenants[d.Tenant]
+		if !ok {
+			go h.rejectProposal(req, connection.RejectUnspecified, "unknown tenant")
+			return
+		}
+		proposals = t.proposals
+	}
+	atomic.AddInt32(&h.pendingProposals, 1)
+	proposals <- prop
+}
+
+// checkProposal checks whether proposal `lp` may be accepted. If not, it
+// returns the code with which to reject it.
+//
+// Peers are identified by their wire address, which go-perun authenticates,
+// and not by the participant address they declare in the proposal.
+func (h *handler) checkProposal(lp *client.LedgerChannelProposal) (connection.RejectionCode, error) {
+	proposer := lp.Peers[proposerIdx]
+	if h.shuttingDown.Value() {
+		return connection.RejectShuttingDown, connection.ErrShuttingDown
+	}
+	if h.proposalLimiter != nil {
+		if err := h.proposalLimiter.Allow(proposer.String()); err != nil {
+			return connection.RejectRateLimited, err
+		}
+	}
+	if err := h.checkAccess(proposer); err != nil {
+		return connection.RejectAccessDenied, err
+	}
+	if _, err := h.connCfg.Versions.Agreed(proposer); err != nil {
+		return connection.RejectIncompatibleVersion, err
+	}
+	if err := h.challengeBounds.check(lp.ChallengeDuration); err != nil {
+		return connection.RejectUnspecified, err
+	}
+	return "", nil
 }
 
 func (h *handler) HandleUpdate(cur *channel.State, update client.ChannelUpdate, responder *client.UpdateResponder) {
+	defer h.supervisor.Recover("update handler")
+	h.audit.record("update_received", channelSubject(update.State.ID), newStateDetails(update.State))
 	conn, ok := h.connections.ForID(update.State.ID)
 	if !ok {
-		h.Logf("Update on unknown channel: %x", update.State.ID)
+		h.log.Warnf("Update on unknown channel: %x", update.State.ID)
+		return
 	}
 
 	conn.HandleUpdate(cur, update, responder)
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client/access"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	patomic "github.com/perun-network/perun-credential-payment/pkg/atomic"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/ratelimit"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wire"
+)
+
+// newProposal returns a proposal sent by wire peer `proposer`, declaring
+// `participant` as its participant address.
+func newProposal(proposer, participant common.Address) *client.LedgerChannelProposal {
+	return &client.LedgerChannelProposal{
+		Participant: wallet.AsWalletAddr(participant),
+		Peers:       []wire.Address{wallet.AsWalletAddr(proposer), wallet.AsWalletAddr(common.Address{0xff})},
+	}
+}
+
+func TestCheckAccessWirePeer(t *testing.T) {
+	denied, fresh := common.Address{1}, common.Address{2}
+	h := &handler{&Client{
+		shuttingDown: patomic.NewBool(false),
+		access:       access.Denylist(denied),
+		connCfg:      &connection.Config{Versions: connection.NewVersions(connection.CurrentVersion())},
+	}}
+
+	// A denied peer is rejected even if it declares a fresh participant.
+	code, err := h.checkProposal(newProposal(denied, fresh))
+	require.ErrorIs(t, err, access.ErrDenied)
+	require.Equal(t, connection.RejectAccessDenied, code)
+
+	// A peer declaring the denied participant is not mistaken for it.
+	_, err = h.checkProposal(newProposal(fresh, denied))
+	require.NoError(t, err)
+}
+
+func TestProposalLimitWirePeer(t *testing.T) {
+	peer := common.Address{1}
+	limits := ratelimit.Limits{PerKey: ratelimit.Rate{Events: 1, Interval: time.Hour}}
+	h := &handler{&Client{
+		shuttingDown:    patomic.NewBool(false),
+		proposalLimiter: ratelimit.New(limits, clock.NewFake(time.Unix(0, 0))),
+		connCfg:         &connection.Config{Versions: connection.NewVersions(connection.CurrentVersion())},
+	}}
+
+	_, err := h.checkProposal(newProposal(peer, common.Address{2}))
+	require.NoError(t, err)
+	// A fresh participant address does not reset the limit of the peer.
+	code, err := h.checkProposal(newProposal(peer, common.Address{3}))
+	require.ErrorIs(t, err, ratelimit.ErrRateLimited)
+	require.Equal(t, connection.RejectRateLimited, code)
+	// Other peers have their own limit.
+	_, err = h.checkProposal(newProposal(common.Address{4}, common.Address{3}))
+	require.NoError(t, err)
+}
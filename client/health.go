@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// healthCheckTimeout bounds the on-chain queries of a health check.
+const healthCheckTimeout = 5 * time.Second
+
+// Health is a report on the state of the client.
+type Health struct {
+	ChainConnected  bool     `json:"chainConnected"`
+	ChainError      string   `json:"chainError,omitempty"`
+	BlockNumber     uint64   `json:"blockNumber"`
+	Listening       bool     `json:"listening"`
+	ShuttingDown    bool     `json:"shuttingDown"`
+	PendingRequests int      `json:"pendingRequests"`
+	Balance         *big.Int `json:"balance,omitempty"`
+	FailedTasks     []string `json:"failedTasks,omitempty"` // Internal goroutines that were given up after failing repeatedly.
+}
+
+// Ready returns whether the client is able to serve requests.
+func (h Health) Ready() bool {
+	return h.ChainConnected && h.Listening && !h.ShuttingDown && len(h.FailedTasks) == 0
+}
+
+// Health checks the chain connection and reports the state of the client.
+func (c *Client) Health(ctx context.Context) Health {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	h := Health{
+		Listening:       c.listening.Value(),
+		ShuttingDown:    c.shuttingDown.Value(),
+		PendingRequests: c.pendingRequests(),
+		FailedTasks:     c.supervisor.Failed(),
+	}
+
+	head, err := c.perunClient.EthClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		h.ChainError = err.Error()
+		return h
+	}
+	h.BlockNumber = head.Number.Uint64()
+
+	balance, err := c.perunClient.EthClient.BalanceAt(ctx, c.Address(), nil)
+	if err != nil {
+		h.ChainError = err.Error()
+		return h
+	}
+	h.Balance = balance
+	h.ChainConnected = true
+
+	return h
+}
+
+// pendingRequests returns the number of connection and credential requests
+// that are waiting to be picked up.
+func (c *Client) pendingRequests() int {
+	n := int(atomic.LoadInt32(&c.pendingProposals))
+	for _, conn := range c.connections.All() {
+		n += conn.PendingRequests()
+	}
+	return n
+}
+
+// serveHealth returns an http handler reporting the health of the client. If
+// `readiness` is set, the handler responds with an error status if the client
+// is not ready.
+func (c *Client) serveHealth(readiness bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := c.Health(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if readiness && !h.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(h); err != nil {
+			c.log.Warnf("Encoding health report: %v", err)
+		}
+	}
+}
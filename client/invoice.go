@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	pkgapp "github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+)
+
+// CreateInvoice creates an invoice for a credential of type `typ` at price
+// `price`, which expires after `validity`. The invoice is signed by the
+// client's account and directs holders to the client's endpoint.
+func (c *Client) CreateInvoice(typ string, price *big.Int, validity time.Duration) (*pkgapp.Invoice, error) {
+	inv := &pkgapp.Invoice{
+		Issuer:   c.Address(),
+		Endpoint: c.endpoint,
+		Type:     typ,
+		Price:    new(big.Int).Set(price),
+		Expiry:   uint64(c.connCfg.Clock.Now().Add(validity).Unix()),
+	}
+	if err := inv.Sign(c.Account()); err != nil {
+		return nil, fmt.Errorf("signing invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// PayInvoice opens a channel with the issuer of invoice `inv`, depositing
+// `balance`, and requests the credential for document `doc` as invoiced.
+// The issuer is reached at the endpoint of the invoice.
+func (c *Client) PayInvoice(ctx context.Context, inv *pkgapp.Invoice, doc []byte, balance channel.Bal) (*connection.Connection, *connection.AsyncCredential, error) {
+	if err := inv.Verify(c.connCfg.Clock.Now()); err != nil {
+		return nil, nil, fmt.Errorf("verifying invoice: %w", err)
+	} else if balance.Cmp(inv.Price) < 0 {
+		return nil, nil, fmt.Errorf("balance %v below invoiced price %v", balance, inv.Price)
+	}
+
+	peer := ethwallet.AsWalletAddr(inv.Issuer)
+	if inv.Endpoint != "" {
+		c.perunClient.Dialer.Register(peer, inv.Endpoint)
+	}
+	conn, err := c.Connect(ctx, peer, balance)
+	if err != nil {
+		return nil, nil, err
+	}
+	cred, err := conn.RequestCredentialWithInvoice(ctx, doc, inv)
+	if err != nil {
+		return conn, nil, err
+	}
+	return conn, cred, nil
+}
+
+// PayInvoiceURI is like PayInvoice for an invoice encoded as URI.
+func (c *Client) PayInvoiceURI(ctx context.Context, uri string, doc []byte, balance channel.Bal) (*connection.Connection, *connection.AsyncCredential, error) {
+	inv, err := pkgapp.ParseInvoiceURI(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.PayInvoice(ctx, inv, doc, balance)
+}
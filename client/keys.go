@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/perun-network/perun-credential-payment/app/keys"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+)
+
+const issuerKeyGasLimit = 120000
+
+var (
+	ErrNoKeyRegistry = errors.New("no issuer key registry configured")
+	// ErrUnknownIssuerKey is returned for requests addressed to a key that
+	// the client does not sign with, e.g., a key rotated out of its overlap
+	// window.
+	ErrUnknownIssuerKey = errors.New("unknown issuer key")
+	// ErrKeyMismatch is returned if the registry contradicts a pinned key,
+	// i.e., the active key changed without the pinned key being revoked.
+	ErrKeyMismatch = errors.New("issuer key does not match pinned key")
+)
+
+// keyPins holds the issuer keys pinned by the client.
+type keyPins struct {
+	mu   sync.Mutex
+	pins map[common.Address]common.Address
+}
+
+// signingKeys are the credential signing keys of the client. Retired keys
+// still sign the requests addressed to them until their overlap ends.
+type signingKeys struct {
+	mu      sync.Mutex
+	current connection.Signer
+	retired map[common.Address]retiredKey
+}
+
+type retiredKey struct {
+	signer connection.Signer
+	until  time.Time
+}
+
+// lookup returns the key with address `addr` at time `now`, if it is the
+// current key or a retired key within its overlap. Retired keys whose overlap
+// ended are dropped.
+func (s *signingKeys) lookup(addr common.Address, now time.Time) (connection.Signer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for a, k := range s.retired {
+		if !now.Before(k.until) {
+			delete(s.retired, a)
+		}
+	}
+	if s.current.Address() == addr {
+		return s.current, true
+	} else if k, ok := s.retired[addr]; ok {
+		return k.signer, true
+	}
+	return nil, false
+}
+
+// IssuerKey returns the address of the current credential signing key, which
+// holders pass as issuer when requesting credentials.
+func (c *Client) IssuerKey() common.Address {
+	c.signingKeys.mu.Lock()
+	defer c.signingKeys.mu.Unlock()
+	return c.signingKeys.current.Address()
+}
+
+// RotateIssuerKey replaces the credential signing key with `next`, e.g.,
+// connection.AccountSigner of a new account. If a key
+// registry is configured, `next` is published first, which revokes the
+// current key. Requests addressed to the current key are still signed for
+// `overlap`, so that requests in flight during the rotation are honored.
+// Verifiers should accept the same overlap, see verifier.Config.KeyOverlap.
+func (c *Client) RotateIssuerKey(ctx context.Context, next connection.Signer, overlap time.Duration) error {
+	if c.issuerKeys != nil {
+		if err := c.PublishIssuerKey(ctx, next.Address()); err != nil {
+			return err
+		}
+	}
+
+	c.signingKeys.mu.Lock()
+	defer c.signingKeys.mu.Unlock()
+	prev := c.signingKeys.current
+	c.signingKeys.current = next
+	if overlap > 0 && prev.Address() != next.Address() {
+		c.signingKeys.retired[prev.Address()] = retiredKey{signer: prev, until: c.connCfg.Clock.Now().Add(overlap)}
+	}
+	delete(c.signingKeys.retired, next.Address())
+	c.log.Infof("Rotated issuer key from %v to %v", prev.Address(), next.Address())
+	return nil
+}
+
+// IssueCredential issues the credential of request `r` with the signing key
+// to which the request is addressed, which is the current key or a key
+// rotated out less than its overlap ago. The document must have been checked
+// before, e.g., by CredentialRequest.CheckDoc.
+func (c *Client) IssueCredential(ctx context.Context, r *connection.CredentialRequest) error {
+	issuer := r.Offer().Issuer
+	key, ok := c.signingKeys.lookup(issuer, c.connCfg.Clock.Now())
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownIssuerKey, issuer)
+	}
+	return r.IssueSignedBy(ctx, c.Account(), key, nil)
+}
+
+// PublishIssuerKey publishes `key` as the client's credential signing key.
+// The previously published key is revoked.
+func (c *Client) PublishIssuerKey(ctx context.Context, key common.Address) error {
+	return c.transactIssuerKey(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.issuerKeys.Publish(opts, key)
+	})
+}
+
+// RevokeIssuerKey revokes the client's credential signing key without
+// replacing it.
+func (c *Client) RevokeIssuerKey(ctx context.Context) error {
+	return c.transactIssuerKey(ctx, c.issuerKeys.Revoke)
+}
+
+func (c *Client) transactIssuerKey(ctx context.Context, send func(*bind.TransactOpts) (*types.Transaction, error)) error {
+	if c.issuerKeys == nil {
+		return ErrNoKeyRegistry
+	}
+
+	cb := &c.perunClient.ContractBackend
+	acc := c.txAccount()
+	opts, err := cb.NewTransactor(ctx, issuerKeyGasLimit, acc)
+	if err != nil {
+		return fmt.Errorf("creating transactor: %w", err)
+	}
+
+	tx, err := send(opts)
+	if err != nil {
+		return fmt.Errorf("sending key update: %w", err)
+	}
+	if _, err := cb.ConfirmTransaction(ctx, tx, acc); err != nil {
+		return fmt.Errorf("confirming key update: %w", err)
+	}
+	return nil
+}
+
+// ResolveIssuerKey returns the active signing key of `issuer`, to be passed
+// as issuer when requesting credentials from it. The first resolved key is
+// pinned. A different key is only accepted if the registry records that the
+// pinned key was revoked, and is then pinned instead.
+func (c *Client) ResolveIssuerKey(ctx context.Context, issuer common.Address) (common.Address, error) {
+	if c.issuerKeys == nil {
+		return common.Address{}, ErrNoKeyRegistry
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+	for {
+		key, err := c.issuerKeys.ActiveKey(opts, issuer)
+		if err != nil {
+			return common.Address{}, fmt.Errorf("querying issuer key registry: %w", err)
+		} else if key == (common.Address{}) {
+			return common.Address{}, keys.ErrNoKey
+		}
+
+		c.keyPins.mu.Lock()
+		pinned, ok := c.keyPins.pins[issuer]
+		c.keyPins.mu.Unlock()
+		if ok && pinned != key {
+			history, err := c.issuerKeys.Keys(opts, issuer)
+			if err != nil {
+				return common.Address{}, fmt.Errorf("querying issuer key registry: %w", err)
+			}
+			if !revoked(history, pinned) {
+				return common.Address{}, fmt.Errorf("%w: pinned %v, registry %v", ErrKeyMismatch, pinned, key)
+			}
+		}
+
+		// The registry is queried without holding the lock, so the pin is
+		// only replaced if no concurrent resolution replaced it meanwhile.
+		// Otherwise, the new pin is checked again.
+		if c.keyPins.swap(issuer, pinned, ok, key) {
+			return key, nil
+		}
+	}
+}
+
+// swap pins `key` for `issuer` if the pin of `issuer` is still `old`, or
+// still unset if `!pinned`. It returns whether the pin was replaced.
+func (p *keyPins) swap(issuer, old common.Address, pinned bool, key common.Address) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cur, ok := p.pins[issuer]; ok != pinned || cur != old {
+		return false
+	}
+	p.pins[issuer] = key
+	return true
+}
+
+// revoked returns whether `key` was published and revoked in `history`.
+func revoked(history []keys.Key, key common.Address) bool {
+	for _, k := range history {
+		if k.Key == key && !k.RevokedAt.IsZero() {
+			return true
+		}
+	}
+	return false
+}
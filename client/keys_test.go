@@ -0,0 +1,66 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+func newAccount(t *testing.T) *simple.Account {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(t, err)
+	return acc.(*simple.Account)
+}
+
+func TestKeyPinsSwap(t *testing.T) {
+	var (
+		issuer     = common.Address{1}
+		key1, key2 = common.Address{2}, common.Address{3}
+		p          = keyPins{pins: make(map[common.Address]common.Address)}
+	)
+
+	// The first key is only pinned if none is pinned yet.
+	require.False(t, p.swap(issuer, common.Address{}, true, key1))
+	require.True(t, p.swap(issuer, common.Address{}, false, key1))
+	require.False(t, p.swap(issuer, common.Address{}, false, key2))
+	require.Equal(t, key1, p.pins[issuer])
+
+	// A pin is only replaced if it was not replaced concurrently.
+	require.False(t, p.swap(issuer, key2, true, key1))
+	require.True(t, p.swap(issuer, key1, true, key2))
+	require.False(t, p.swap(issuer, key1, true, key1))
+	require.Equal(t, key2, p.pins[issuer])
+}
+
+func TestSigningKeysLookup(t *testing.T) {
+	prev, cur := connection.AccountSigner(newAccount(t)), connection.AccountSigner(newAccount(t))
+	now := time.Unix(1000, 0)
+	s := signingKeys{current: cur, retired: map[common.Address]retiredKey{
+		prev.Address(): {signer: prev, until: now.Add(time.Minute)},
+	}}
+
+	key, ok := s.lookup(cur.Address(), now)
+	require.True(t, ok)
+	require.Equal(t, cur.Address(), key.Address())
+	key, ok = s.lookup(prev.Address(), now.Add(time.Minute-time.Second))
+	require.True(t, ok, "retired key within overlap")
+	require.Equal(t, prev.Address(), key.Address())
+	_, ok = s.lookup(common.Address{1}, now)
+	require.False(t, ok, "unknown key")
+
+	// Retired keys are dropped once their overlap ended.
+	_, ok = s.lookup(prev.Address(), now.Add(time.Minute))
+	require.False(t, ok, "retired key after overlap")
+	require.Empty(t, s.retired)
+	_, ok = s.lookup(cur.Address(), now.Add(time.Hour))
+	require.True(t, ok, "current key")
+}
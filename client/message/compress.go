@@ -0,0 +1,323 @@
+package message
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+const (
+	// CompressedMsgType is the wire type of compressed messages.
+	CompressedMsgType = wire.LastType + 65
+
+	// MsgKindCompression is the kind of requests announcing the codecs with
+	// which a client decompresses messages.
+	MsgKindCompression = "compression"
+
+	// DefaultMinCompressSize is the size of encoded messages in bytes from
+	// which on they are compressed.
+	DefaultMinCompressSize = 1024
+
+	// negotiationTimeout bounds the exchange of supported codecs.
+	negotiationTimeout = 10 * time.Second
+)
+
+func init() {
+	wire.RegisterExternalDecoder(CompressedMsgType, decodeCompressedMsg, "CompressedMsg")
+}
+
+// Codec compresses messages with an algorithm.
+type Codec interface {
+	// Name identifies the algorithm in the negotiation with peers.
+	Name() string
+	// Compress compresses `b`.
+	Compress(b []byte) ([]byte, error)
+	// Decompress decompresses `b` and fails if the result exceeds `limit`
+	// bytes.
+	Decompress(b []byte, limit int) ([]byte, error)
+}
+
+// Gzip is the gzip codec of the standard library.
+var Gzip Codec = gzipCodec{}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) Compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(b []byte, limit int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	} else if len(out) > limit {
+		return nil, fmt.Errorf("decompressed message exceeds %d bytes", limit)
+	}
+	return out, nil
+}
+
+// CompressionConfig configures the compression of messages.
+type CompressionConfig struct {
+	Codecs  []Codec // Codecs in order of preference. Messages are not compressed if there are none.
+	MinSize int     // Optional. Encoded messages below MinSize bytes are sent uncompressed. Defaults to DefaultMinCompressSize.
+}
+
+// compressedMsg is a message compressed by a codec.
+type compressedMsg struct {
+	Codec   string
+	Payload []byte
+}
+
+func (m *compressedMsg) Type() wire.Type {
+	return CompressedMsgType
+}
+
+func (m *compressedMsg) Encode(w io.Writer) error {
+	if len(m.Codec) > 255 {
+		return fmt.Errorf("codec name too long: %d", len(m.Codec))
+	}
+	if _, err := w.Write(append([]byte{byte(len(m.Codec))}, m.Codec...)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(m.Payload))); err != nil {
+		return err
+	}
+	_, err := w.Write(m.Payload)
+	return err
+}
+
+func decodeCompressedMsg(r io.Reader) (wire.Msg, error) {
+	var n [1]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, fmt.Errorf("reading codec: %w", err)
+	}
+	codec := make([]byte, n[0])
+	if _, err := io.ReadFull(r, codec); err != nil {
+		return nil, fmt.Errorf("reading codec: %w", err)
+	}
+
+	var l uint32
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return nil, fmt.Errorf("reading length: %w", err)
+	} else if l > maxMsgLen {
+		return nil, fmt.Errorf("message too long: %d", l)
+	}
+	payload := make([]byte, l)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+	return &compressedMsg{Codec: string(codec), Payload: payload}, nil
+}
+
+// CompressingBus wraps a wire.Bus and compresses large messages to peers
+// that announced a common codec. The codecs are announced with the first
+// message to a peer, which is sent uncompressed, so that peers without
+// compression support are not affected.
+type CompressingBus struct {
+	wire.Bus
+	cfg    CompressionConfig
+	codecs map[string]Codec
+
+	mu    sync.Mutex
+	m     *Messenger
+	peers map[common.Address]Codec // Nil while negotiating or without common codec.
+}
+
+// NewCompressingBus wraps `bus`, compressing messages as configured by
+// `cfg`. Compression starts once Negotiate is called.
+func NewCompressingBus(bus wire.Bus, cfg CompressionConfig) *CompressingBus {
+	if cfg.MinSize == 0 {
+		cfg.MinSize = DefaultMinCompressSize
+	}
+	codecs := make(map[string]Codec, len(cfg.Codecs))
+	for _, c := range cfg.Codecs {
+		codecs[c.Name()] = c
+	}
+	return &CompressingBus{
+		Bus:    bus,
+		cfg:    cfg,
+		codecs: codecs,
+		peers:  make(map[common.Address]Codec),
+	}
+}
+
+// Negotiate announces the codecs to peers via `m` and answers their
+// announcements.
+func (b *CompressingBus) Negotiate(m *Messenger) {
+	if len(b.cfg.Codecs) == 0 {
+		return
+	}
+	b.mu.Lock()
+	b.m = m
+	b.mu.Unlock()
+	m.Handle(MsgKindCompression, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var names []string
+		if err := json.Unmarshal(body, &names); err != nil {
+			return nil, fmt.Errorf("decoding codecs: %w", err)
+		}
+		b.setCodec(peer, names)
+		return b.names(), nil
+	})
+}
+
+// Publish publishes `e`, compressed if the codec of the recipient is known.
+func (b *CompressingBus) Publish(ctx context.Context, e *wire.Envelope) error {
+	if codec := b.codec(e.Recipient); codec != nil {
+		msg, err := b.compress(codec, e.Msg)
+		if err != nil {
+			return fmt.Errorf("compressing message: %w", err)
+		} else if msg != nil {
+			e = &wire.Envelope{Sender: e.Sender, Recipient: e.Recipient, Msg: msg}
+		}
+	}
+	return b.Bus.Publish(ctx, e)
+}
+
+// SubscribeClient subscribes the client consumer `c` for address `addr`.
+// Compressed messages are decompressed before they are passed on.
+func (b *CompressingBus) SubscribeClient(c wire.Consumer, addr wire.Address) error {
+	return b.Bus.SubscribeClient(&decompressor{Consumer: c, bus: b}, addr)
+}
+
+// codec returns the codec for messages to `peer`. If it is not known yet,
+// the negotiation with the peer is started.
+func (b *CompressingBus) codec(peer wire.Address) Codec {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.m == nil {
+		return nil
+	}
+	addr := ethwallet.AsEthAddr(peer)
+	codec, ok := b.peers[addr]
+	if !ok {
+		b.peers[addr] = nil
+		go b.announce(peer)
+	}
+	return codec
+}
+
+// announce sends the codecs to `peer` and records the common codec. Peers
+// without compression support answer with an error, and messages to them
+// stay uncompressed. After other errors, the negotiation is repeated.
+func (b *CompressingBus) announce(peer wire.Address) {
+	ctx, cancel := context.WithTimeout(context.Background(), negotiationTimeout)
+	defer cancel()
+
+	var names []string
+	err := b.m.Request(ctx, peer, MsgKindCompression, b.names(), &names)
+	var remoteErr *RemoteError
+	if errors.As(err, &remoteErr) {
+		return
+	} else if err != nil {
+		b.mu.Lock()
+		delete(b.peers, ethwallet.AsEthAddr(peer))
+		b.mu.Unlock()
+		return
+	}
+	b.setCodec(peer, names)
+}
+
+// setCodec records the most preferred codec that is in `names` for `peer`.
+func (b *CompressingBus) setCodec(peer wire.Address, names []string) {
+	supported := make(map[string]bool, len(names))
+	for _, n := range names {
+		supported[n] = true
+	}
+	var codec Codec
+	for _, c := range b.cfg.Codecs {
+		if supported[c.Name()] {
+			codec = c
+			break
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.peers[ethwallet.AsEthAddr(peer)] = codec
+}
+
+func (b *CompressingBus) names() []string {
+	names := make([]string, len(b.cfg.Codecs))
+	for i, c := range b.cfg.Codecs {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// compress returns `msg` compressed with `codec`, or nil if it is too small
+// or does not shrink.
+func (b *CompressingBus) compress(codec Codec, msg wire.Msg) (wire.Msg, error) {
+	var buf bytes.Buffer
+	if err := wire.Encode(msg, &buf); err != nil {
+		return nil, err
+	} else if buf.Len() < b.cfg.MinSize {
+		return nil, nil
+	}
+	payload, err := codec.Compress(buf.Bytes())
+	if err != nil || len(payload) >= buf.Len() {
+		return nil, err
+	}
+	return &compressedMsg{Codec: codec.Name(), Payload: payload}, nil
+}
+
+// decompress returns the message compressed in `msg`.
+func (b *CompressingBus) decompress(msg *compressedMsg) (wire.Msg, error) {
+	codec, ok := b.codecs[msg.Codec]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec: %s", msg.Codec)
+	}
+	enc, err := codec.Decompress(msg.Payload, maxMsgLen)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := wire.Decode(bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	} else if _, ok := inner.(*compressedMsg); ok {
+		return nil, errors.New("nested compressed message")
+	}
+	return inner, nil
+}
+
+type decompressor struct {
+	wire.Consumer
+	bus *CompressingBus
+}
+
+// Put passes on `e`, decompressing its message. Messages that cannot be
+// decompressed are dropped, like messages that cannot be decoded.
+func (d *decompressor) Put(e *wire.Envelope) {
+	if msg, ok := e.Msg.(*compressedMsg); ok {
+		inner, err := d.bus.decompress(msg)
+		if err != nil {
+			return
+		}
+		e = &wire.Envelope{Sender: e.Sender, Recipient: e.Recipient, Msg: inner}
+	}
+	d.Consumer.Put(e)
+}
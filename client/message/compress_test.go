@@ -0,0 +1,258 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+var (
+	alice = ethwallet.AsWalletAddr(common.Address{1})
+	bob   = ethwallet.AsWalletAddr(common.Address{2})
+)
+
+// doc is a request body that is larger than DefaultMinCompressSize and
+// compresses well.
+var doc = strings.Repeat("perun credential payment ", 100)
+
+func TestGzip(t *testing.T) {
+	in := []byte(doc)
+	enc, err := Gzip.Compress(in)
+	require.NoError(t, err)
+	require.Less(t, len(enc), len(in))
+
+	out, err := Gzip.Decompress(enc, len(in))
+	require.NoError(t, err)
+	require.Equal(t, in, out)
+
+	_, err = Gzip.Decompress(enc, len(in)-1)
+	require.Error(t, err, "limit exceeded")
+	_, err = Gzip.Decompress(in, len(in))
+	require.Error(t, err, "not gzip")
+}
+
+func TestCompressedMsgEncoding(t *testing.T) {
+	msg := &compressedMsg{Codec: "gzip", Payload: []byte{1, 2, 3}}
+	var buf bytes.Buffer
+	require.NoError(t, wire.Encode(msg, &buf))
+	dec, err := wire.Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, msg, dec)
+
+	require.Error(t, (&compressedMsg{Codec: strings.Repeat("x", 256)}).Encode(&buf))
+}
+
+func TestCompressingBus(t *testing.T) {
+	bus := newRecordingBus()
+	a, am, _ := newPeer(t, bus, alice, Gzip)
+	_, _, docs := newPeer(t, bus, bob, Gzip)
+
+	// The first message starts the negotiation and is sent uncompressed.
+	require.NoError(t, am.Request(context.Background(), bob, "doc", doc, nil))
+	require.Equal(t, doc, <-docs)
+	require.Eventually(t, func() bool { return a.codec(bob) == Gzip }, time.Second, time.Millisecond)
+
+	// Large messages are compressed from then on, small ones are not.
+	bus.reset()
+	require.NoError(t, am.Request(context.Background(), bob, "doc", doc, nil))
+	require.Equal(t, doc, <-docs)
+	require.NoError(t, am.Request(context.Background(), bob, "doc", "small", nil))
+	require.Equal(t, "small", <-docs)
+	require.Equal(t, []string{"gzip", ""}, bus.codecs(alice))
+}
+
+func TestCompressingBusNegotiation(t *testing.T) {
+	other := namedCodec{Codec: Gzip, name: "other"}
+	tests := []struct {
+		name   string
+		codecs []Codec // Codecs of bob.
+		want   Codec
+	}{
+		{"preferred codec", []Codec{other, Gzip}, Gzip},
+		{"common codec", []Codec{namedCodec{Codec: Gzip, name: "third"}, other}, other},
+		{"no common codec", []Codec{namedCodec{Codec: Gzip, name: "third"}}, nil},
+		{"no compression", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newRecordingBus()
+			a, _, _ := newPeer(t, bus, alice, Gzip, other)
+			newPeer(t, bus, bob, tt.codecs...)
+
+			// Peers without compression support answer with an error, after
+			// which the negotiation is not repeated.
+			negotiate(a, bob)
+			codec, ok := peerCodec(a, bob)
+			require.True(t, ok)
+			require.Equal(t, tt.want, codec)
+		})
+	}
+}
+
+func TestCompressingBusRenegotiation(t *testing.T) {
+	bus := newRecordingBus()
+	a, _, _ := newPeer(t, bus, alice, Gzip)
+	newPeer(t, bus, bob, Gzip)
+
+	// After a failed negotiation, the next message negotiates again.
+	bus.fail(bob, true)
+	negotiate(a, bob)
+	_, ok := peerCodec(a, bob)
+	require.False(t, ok)
+
+	bus.fail(bob, false)
+	require.Nil(t, a.codec(bob))
+	require.Eventually(t, func() bool { return a.codec(bob) == Gzip }, time.Second, time.Millisecond)
+}
+
+func TestDecompress(t *testing.T) {
+	b := NewCompressingBus(wire.NewLocalBus(), CompressionConfig{Codecs: []Codec{Gzip}})
+	compress := func(msg wire.Msg) *compressedMsg {
+		var buf bytes.Buffer
+		require.NoError(t, wire.Encode(msg, &buf))
+		payload, err := Gzip.Compress(buf.Bytes())
+		require.NoError(t, err)
+		return &compressedMsg{Codec: "gzip", Payload: payload}
+	}
+
+	msg := &Msg{ID: 1, Kind: "doc", Body: json.RawMessage(`"doc"`)}
+	dec, err := b.decompress(compress(msg))
+	require.NoError(t, err)
+	require.Equal(t, msg, dec)
+
+	_, err = b.decompress(&compressedMsg{Codec: "other", Payload: compress(msg).Payload})
+	require.Error(t, err, "unknown codec")
+	_, err = b.decompress(&compressedMsg{Codec: "gzip", Payload: []byte("garbage")})
+	require.Error(t, err, "invalid payload")
+	_, err = b.decompress(compress(compress(msg)))
+	require.Error(t, err, "nested compression")
+
+	// Messages that cannot be decompressed are dropped.
+	recv := wire.NewReceiver()
+	d := &decompressor{Consumer: recv, bus: b}
+	d.Put(&wire.Envelope{Sender: alice, Recipient: bob, Msg: &compressedMsg{Codec: "other"}})
+	d.Put(&wire.Envelope{Sender: alice, Recipient: bob, Msg: compress(msg)})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, err := recv.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, msg, e.Msg)
+}
+
+// newPeer returns the compressing bus and messenger of a peer at `addr` with
+// codecs `codecs`, and the channel on which it receives the bodies of "doc"
+// requests.
+func newPeer(t *testing.T, bus wire.Bus, addr wire.Address, codecs ...Codec) (*CompressingBus, *Messenger, <-chan string) {
+	t.Helper()
+	b := NewCompressingBus(bus, CompressionConfig{Codecs: codecs})
+	m := NewMessenger(b, addr)
+	t.Cleanup(m.Close)
+	b.Negotiate(m)
+
+	docs := make(chan string, 1)
+	m.Handle("doc", func(_ context.Context, _ wire.Address, body json.RawMessage) (interface{}, error) {
+		var d string
+		if err := json.Unmarshal(body, &d); err != nil {
+			return nil, err
+		}
+		docs <- d
+		return nil, nil
+	})
+	require.NoError(t, NewBus(b, m).SubscribeClient(wire.NewReceiver(), addr))
+	return b, m, docs
+}
+
+// negotiate negotiates the codec of `b` for `peer` like the first message to
+// `peer` does, but synchronously.
+func negotiate(b *CompressingBus, peer wire.Address) {
+	b.mu.Lock()
+	b.peers[ethwallet.AsEthAddr(peer)] = nil
+	b.mu.Unlock()
+	b.announce(peer)
+}
+
+// peerCodec returns the codec recorded by `b` for `peer`, and whether it is
+// recorded.
+func peerCodec(b *CompressingBus, peer wire.Address) (Codec, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	codec, ok := b.peers[ethwallet.AsEthAddr(peer)]
+	return codec, ok
+}
+
+// namedCodec renames a codec.
+type namedCodec struct {
+	Codec
+	name string
+}
+
+func (c namedCodec) Name() string { return c.name }
+
+// recordingBus is a local bus that records the codecs of the published
+// messages and fails to publish to selected recipients.
+type recordingBus struct {
+	*wire.LocalBus
+	mu      sync.Mutex
+	msgs    []*wire.Envelope
+	failing map[common.Address]bool
+}
+
+func newRecordingBus() *recordingBus {
+	return &recordingBus{LocalBus: wire.NewLocalBus(), failing: make(map[common.Address]bool)}
+}
+
+func (b *recordingBus) Publish(ctx context.Context, e *wire.Envelope) error {
+	b.mu.Lock()
+	if b.failing[ethwallet.AsEthAddr(e.Recipient)] {
+		b.mu.Unlock()
+		return errors.New("recipient unreachable")
+	}
+	b.msgs = append(b.msgs, e)
+	b.mu.Unlock()
+	return b.LocalBus.Publish(ctx, e)
+}
+
+func (b *recordingBus) fail(recipient wire.Address, fail bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failing[ethwallet.AsEthAddr(recipient)] = fail
+}
+
+func (b *recordingBus) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.msgs = nil
+}
+
+// codecs returns the codecs of the messages sent by `sender`, where
+// uncompressed messages have the empty codec. The messages of the
+// negotiation are skipped.
+func (b *recordingBus) codecs(sender wire.Address) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var codecs []string
+	for _, e := range b.msgs {
+		if ethwallet.AsEthAddr(e.Sender) != ethwallet.AsEthAddr(sender) {
+			continue
+		}
+		switch msg := e.Msg.(type) {
+		case *compressedMsg:
+			codecs = append(codecs, msg.Codec)
+		case *Msg:
+			if msg.Kind != MsgKindCompression {
+				codecs = append(codecs, "")
+			}
+		}
+	}
+	return codecs
+}
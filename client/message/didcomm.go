@@ -0,0 +1,187 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/didcomm"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+const (
+	// DIDCommTypePrefix is the prefix of the DIDComm message types of
+	// requests. The type of a request is the prefix followed by its kind.
+	DIDCommTypePrefix = "https://perun.network/credential-payment/1.0/"
+	// didCommResponseSuffix is appended to the type of responses.
+	didCommResponseSuffix = "-response"
+
+	// maxDIDCommAge bounds the age of accepted requests, which limits
+	// replays.
+	maxDIDCommAge = 5 * time.Minute
+
+	problemUnknownKind = "e.p.msg.unsupported"
+	problemFailed      = "e.p.msg.failed"
+)
+
+var ErrUnexpectedResponse = errors.New("unexpected DIDComm response")
+
+// DIDComm exchanges the requests of a Messenger as signed DIDComm v2
+// messages over HTTP, so that SSI agents can, e.g., request quotes and
+// transfer documents. The payment itself stays in the channel. The peer of a
+// request is the Ethereum address of the key that signed it.
+type DIDComm struct {
+	m        *Messenger
+	did      string
+	kid      string
+	signer   jws.Signer
+	resolver did.Resolver
+	clock    clock.Clock
+	client   *http.Client
+}
+
+// NewDIDComm creates a DIDComm endpoint for the handlers of `m`. Messages are
+// sent from DID `self` and signed by `s` with key `kid`, a DID URL of `self`.
+// The DIDs of peers are resolved with `r`. The creation times of messages are
+// taken from and checked against `clk`.
+func NewDIDComm(m *Messenger, self, kid string, s jws.Signer, r did.Resolver, clk clock.Clock) *DIDComm {
+	return &DIDComm{
+		m:        m,
+		did:      self,
+		kid:      kid,
+		signer:   s,
+		resolver: r,
+		clock:    clk,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ServeHTTP handles a signed DIDComm request and writes the signed response
+// to the response body.
+func (d *DIDComm) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	packed, err := io.ReadAll(io.LimitReader(r.Body, maxMsgLen))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, addr, err := didcomm.Verify(r.Context(), packed, d.resolver)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	} else if age := d.clock.Now().Sub(time.Unix(req.CreatedTime, 0)); age > maxDIDCommAge || age < -maxDIDCommAge {
+		http.Error(w, "message expired", http.StatusBadRequest)
+		return
+	}
+
+	resp := d.handle(r.Context(), req, ethwallet.AsWalletAddr(addr))
+	enc, err := didcomm.Sign(resp, d.signer, d.kid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", didcomm.MediaTypeSigned)
+	_, _ = w.Write(enc)
+}
+
+// handle dispatches request `req` of `peer` and returns the response.
+func (d *DIDComm) handle(ctx context.Context, req *didcomm.Message, peer wire.Address) *didcomm.Message {
+	resp := &didcomm.Message{
+		ID:          didcomm.NewID(),
+		From:        d.did,
+		To:          []string{req.From},
+		ThID:        req.ID,
+		CreatedTime: d.clock.Now().Unix(),
+	}
+	problem := func(code string, err error) *didcomm.Message {
+		resp.Type = didcomm.TypeProblemReport
+		resp.Body, _ = json.Marshal(didcomm.ProblemReport{Code: code, Comment: err.Error()})
+		return resp
+	}
+
+	kind := strings.TrimPrefix(req.Type, DIDCommTypePrefix)
+	h, ok := d.m.handler(kind)
+	if kind == req.Type || !ok {
+		return problem(problemUnknownKind, ErrUnknownKind)
+	}
+	body, err := call(ctx, h, peer, req.Body)
+	if err != nil {
+		return problem(problemFailed, err)
+	}
+	resp.Type = req.Type + didCommResponseSuffix
+	resp.Body = body
+	return resp
+}
+
+// Request sends `req` to the DIDComm endpoint `endpoint` of DID `to` and
+// decodes the response into `resp`.
+func (d *DIDComm) Request(ctx context.Context, endpoint, to, kind string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+	msg := &didcomm.Message{
+		ID:          didcomm.NewID(),
+		Type:        DIDCommTypePrefix + kind,
+		From:        d.did,
+		To:          []string{to},
+		CreatedTime: d.clock.Now().Unix(),
+		Body:        body,
+	}
+	packed, err := didcomm.Sign(msg, d.signer, d.kid)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", didcomm.MediaTypeSigned)
+	httpResp, err := d.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer httpResp.Body.Close()
+	enc, err := io.ReadAll(io.LimitReader(httpResp.Body, maxMsgLen))
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	} else if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sending request: %s: %s", httpResp.Status, bytes.TrimSpace(enc))
+	}
+
+	r, _, err := didcomm.Verify(ctx, enc, d.resolver)
+	if err != nil {
+		return fmt.Errorf("verifying response: %w", err)
+	} else if r.From != to || r.ThID != msg.ID {
+		return ErrUnexpectedResponse
+	}
+	switch r.Type {
+	case didcomm.TypeProblemReport:
+		var p didcomm.ProblemReport
+		if err := json.Unmarshal(r.Body, &p); err != nil {
+			return fmt.Errorf("decoding problem report: %w", err)
+		}
+		return &RemoteError{Kind: kind, Reason: p.Comment}
+	case msg.Type + didCommResponseSuffix:
+		if resp == nil {
+			return nil
+		}
+		return json.Unmarshal(r.Body, resp)
+	default:
+		return ErrUnexpectedResponse
+	}
+}
@@ -0,0 +1,71 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+	"perun.network/go-perun/wire"
+)
+
+// newDIDComm returns a DIDComm endpoint for the handlers of `m` with a new
+// did:ethr identity.
+func newDIDComm(t *testing.T, m *Messenger) (*DIDComm, string) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	acc, err := simple.NewWallet(key).Unlock(ethwallet.AsWalletAddr(addr))
+	require.NoError(t, err)
+	self := did.Ethr(addr)
+	return NewDIDComm(m, self, self+"#controller", jws.ES256K{Key: acc.(*simple.Account)}, did.EthrResolver{}, clock.System()), self
+}
+
+func TestDIDComm(t *testing.T) {
+	ctx := context.Background()
+	server := NewMessenger(make(publisher), alice)
+	t.Cleanup(server.Close)
+	client := NewMessenger(make(publisher), bob)
+	t.Cleanup(client.Close)
+
+	// The peer of a request is the address of the key that signed it.
+	peers := make(chan wire.Address, 1)
+	server.Handle("echo", func(_ context.Context, p wire.Address, body json.RawMessage) (interface{}, error) {
+		select {
+		case peers <- p:
+		default:
+		}
+		var s string
+		err := json.Unmarshal(body, &s)
+		return s, err
+	})
+	server.Handle("fail", func(context.Context, wire.Address, json.RawMessage) (interface{}, error) {
+		return nil, errors.New("failure")
+	})
+	serverComm, serverDID := newDIDComm(t, server)
+	srv := httptest.NewServer(serverComm)
+	t.Cleanup(srv.Close)
+	clientComm, clientDID := newDIDComm(t, client)
+
+	var resp string
+	require.NoError(t, clientComm.Request(ctx, srv.URL, serverDID, "echo", "hello", &resp))
+	require.Equal(t, "hello", resp)
+	require.Equal(t, clientDID, did.Ethr(ethwallet.AsEthAddr(<-peers)))
+
+	var remote *RemoteError
+	require.ErrorAs(t, clientComm.Request(ctx, srv.URL, serverDID, "fail", nil, nil), &remote)
+	require.Equal(t, "failure", remote.Reason)
+	require.ErrorAs(t, clientComm.Request(ctx, srv.URL, serverDID, "unknown", nil, nil), &remote)
+
+	// Responses must come from the addressed DID.
+	require.ErrorIs(t, clientComm.Request(ctx, srv.URL, clientDID, "echo", "hello", &resp), ErrUnexpectedResponse)
+}
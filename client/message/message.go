@@ -0,0 +1,68 @@
+// Package message implements request-response messaging between clients on
+// top of the Perun wire bus. It is used for exchanges that happen outside of
+// channel updates, such as quotes.
+package message
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"perun.network/go-perun/wire"
+)
+
+// MsgType is the wire type of app messages. It lies outside the range of the
+// Perun wire protocol.
+const MsgType = wire.LastType + 64
+
+// maxMsgLen bounds the size of a decoded message.
+const maxMsgLen = 1 << 24
+
+func init() {
+	wire.RegisterExternalDecoder(MsgType, decodeMsg, "CredentialPaymentMsg")
+}
+
+// Msg is an app message. Requests and responses are correlated by ID.
+type Msg struct {
+	ID       uint64          `json:"id"`
+	Kind     string          `json:"kind"`
+	Response bool            `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+// Type returns the wire type of the message.
+func (m *Msg) Type() wire.Type {
+	return MsgType
+}
+
+// Encode encodes the message onto an io.Writer.
+func (m *Msg) Encode(w io.Writer) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func decodeMsg(r io.Reader) (wire.Msg, error) {
+	var l uint32
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return nil, fmt.Errorf("reading length: %w", err)
+	} else if l > maxMsgLen {
+		return nil, fmt.Errorf("message too long: %d", l)
+	}
+
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+
+	var m Msg
+	return &m, json.Unmarshal(b, &m)
+}
@@ -0,0 +1,225 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/retry"
+	"perun.network/go-perun/wire"
+)
+
+// Handler handles a request of a specific kind. The returned value is sent
+// back to the peer as the response body.
+type Handler func(ctx context.Context, peer wire.Address, body json.RawMessage) (interface{}, error)
+
+// RemoteError is returned by Request if the peer failed to handle the
+// request.
+type RemoteError struct {
+	Kind   string
+	Reason string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("peer failed to handle %s: %s", e.Kind, e.Reason)
+}
+
+var ErrUnknownKind = errors.New("unknown message kind")
+
+// Messenger sends requests to peers and dispatches incoming requests to the
+// registered handlers.
+type Messenger struct {
+	pub    wire.Publisher
+	self   wire.Address
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	pending  map[uint64]*pendingRequest
+	nextID   uint64
+	retry    retry.Policy
+}
+
+// pendingRequest is a request awaiting the response of peer.
+type pendingRequest struct {
+	peer wire.Address
+	resp chan *Msg
+}
+
+// NewMessenger creates a messenger publishing on `pub` on behalf of `self`.
+func NewMessenger(pub wire.Publisher, self wire.Address) *Messenger {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Messenger{
+		pub:      pub,
+		self:     self,
+		ctx:      ctx,
+		cancel:   cancel,
+		handlers: make(map[string]Handler),
+		pending:  make(map[uint64]*pendingRequest),
+	}
+}
+
+// SetRetry sets the policy with which requests are sent again if they
+// cannot be delivered, e.g., because the peer cannot be dialed.
+func (m *Messenger) SetRetry(p retry.Policy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retry = p
+}
+
+// Close cancels the context of all running handlers.
+func (m *Messenger) Close() {
+	m.cancel()
+}
+
+// Handle registers the handler for requests of kind `kind`, replacing any
+// previously registered handler.
+func (m *Messenger) Handle(kind string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[kind] = h
+}
+
+// Request sends `req` to `peer` and decodes the response into `resp`.
+func (m *Messenger) Request(ctx context.Context, peer wire.Address, kind string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	m.mu.Lock()
+	m.nextID++
+	id := m.nextID
+	respChan := make(chan *Msg, 1)
+	m.pending[id] = &pendingRequest{peer: peer, resp: respChan}
+	policy := m.retry
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.pending, id)
+		m.mu.Unlock()
+	}()
+
+	msg := &Msg{ID: id, Kind: kind, Body: body}
+	err = policy.Do(ctx, clock.System(), func(ctx context.Context) error {
+		return m.publish(ctx, peer, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+
+	select {
+	case r := <-respChan:
+		if r.Error != "" {
+			return &RemoteError{Kind: kind, Reason: r.Error}
+		}
+		if resp == nil {
+			return nil
+		}
+		return json.Unmarshal(r.Body, resp)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Messenger) publish(ctx context.Context, peer wire.Address, msg *Msg) error {
+	return m.pub.Publish(ctx, &wire.Envelope{
+		Sender:    m.self,
+		Recipient: peer,
+		Msg:       msg,
+	})
+}
+
+// dispatch routes an incoming message. Requests are handled in the
+// background, so that the bus is not blocked. Responses are only delivered
+// once and only if they are sent by the requested peer, others are dropped.
+func (m *Messenger) dispatch(sender wire.Address, msg *Msg) {
+	m.mu.Lock()
+	if msg.Response {
+		p, ok := m.pending[msg.ID]
+		if ok && p.peer.Equals(sender) {
+			delete(m.pending, msg.ID)
+		} else {
+			ok = false
+		}
+		m.mu.Unlock()
+		if ok {
+			select {
+			case p.resp <- msg:
+			default: // Never blocks the bus.
+			}
+		}
+		return
+	}
+	h, ok := m.handlers[msg.Kind]
+	m.mu.Unlock()
+
+	go func() {
+		resp := &Msg{ID: msg.ID, Kind: msg.Kind, Response: true}
+		if !ok {
+			resp.Error = ErrUnknownKind.Error()
+		} else if body, err := call(m.ctx, h, sender, msg.Body); err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Body = body
+		}
+
+		// Responses are best effort, the requester times out otherwise.
+		_ = m.publish(m.ctx, sender, resp)
+	}()
+}
+
+// handler returns the handler registered for `kind`.
+func (m *Messenger) handler(kind string) (Handler, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.handlers[kind]
+	return h, ok
+}
+
+// call calls handler `h` and encodes its response.
+func call(ctx context.Context, h Handler, peer wire.Address, body json.RawMessage) (json.RawMessage, error) {
+	resp, err := h(ctx, peer, body)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := json.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("encoding response: %v", err)
+	}
+	return enc, nil
+}
+
+// Bus wraps a wire.Bus and diverts app messages to a Messenger, while all
+// other messages are passed on to the subscribed client.
+type Bus struct {
+	wire.Bus
+	m *Messenger
+}
+
+// NewBus wraps `bus`, diverting app messages to `m`.
+func NewBus(bus wire.Bus, m *Messenger) *Bus {
+	return &Bus{Bus: bus, m: m}
+}
+
+// SubscribeClient subscribes the client consumer `c` for address `addr`.
+func (b *Bus) SubscribeClient(c wire.Consumer, addr wire.Address) error {
+	return b.Bus.SubscribeClient(&consumer{Consumer: c, bus: b}, addr)
+}
+
+type consumer struct {
+	wire.Consumer
+	bus *Bus
+}
+
+func (c *consumer) Put(e *wire.Envelope) {
+	if msg, ok := e.Msg.(*Msg); ok {
+		c.bus.m.dispatch(e.Sender, msg)
+		return
+	}
+	c.Consumer.Put(e)
+}
@@ -0,0 +1,88 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/pkg/retry"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// publisher passes published envelopes on to the test.
+type publisher chan *wire.Envelope
+
+func (p publisher) Publish(ctx context.Context, e *wire.Envelope) error {
+	select {
+	case p <- e:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestResponseDispatch(t *testing.T) {
+	pub := make(publisher, 1)
+	m := NewMessenger(pub, alice)
+	t.Cleanup(m.Close)
+
+	var resp string
+	errc := make(chan error, 1)
+	go func() { errc <- m.Request(context.Background(), bob, "ping", nil, &resp) }()
+	id := (<-pub).Msg.(*Msg).ID
+	response := func(body string) *Msg {
+		return &Msg{ID: id, Kind: "ping", Response: true, Body: []byte(body)}
+	}
+
+	// Responses of other peers are dropped.
+	m.dispatch(ethwallet.AsWalletAddr(common.Address{3}), response(`"spoofed"`))
+	m.mu.Lock()
+	require.Contains(t, m.pending, id)
+	m.mu.Unlock()
+
+	// Only the first response of the peer is delivered, and duplicates do
+	// not block the dispatch.
+	m.dispatch(bob, response(`"pong"`))
+	m.dispatch(bob, response(`"duplicate"`))
+	require.NoError(t, <-errc)
+	require.Equal(t, "pong", resp)
+	require.Empty(t, m.pending)
+}
+
+// flakyPublisher fails the first `failures` publications and passes the
+// others on to the test.
+type flakyPublisher struct {
+	publisher
+	failures int
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, e *wire.Envelope) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("dialing peer")
+	}
+	return p.publisher.Publish(ctx, e)
+}
+
+func TestRequestRetry(t *testing.T) {
+	pub := &flakyPublisher{publisher: make(publisher, 1), failures: 2}
+	m := NewMessenger(pub, alice)
+	t.Cleanup(m.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Without retries, the first failure is returned.
+	require.Error(t, m.Request(ctx, bob, "ping", nil, nil))
+
+	m.SetRetry(retry.Policy{Attempts: 3, Backoff: time.Millisecond})
+	pub.failures = 2
+	errc := make(chan error, 1)
+	go func() { errc <- m.Request(ctx, bob, "ping", nil, nil) }()
+	id := (<-pub.publisher).Msg.(*Msg).ID
+	m.dispatch(bob, &Msg{ID: id, Kind: "ping", Response: true, Body: []byte("null")})
+	require.NoError(t, <-errc)
+}
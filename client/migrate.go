@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wire"
+)
+
+// migrationTimeout bounds settling a migrated channel and accepting its
+// successor on the side of the peer.
+const migrationTimeout = 10 * time.Minute
+
+// migration is a migration announced by a peer.
+type migration struct {
+	old     *connection.Connection
+	app     common.Address
+	settled chan struct{}
+	err     error // Set before settled is closed.
+}
+
+// migrations holds the migrations announced by peers, by peer address.
+type migrations struct {
+	mu sync.Mutex
+	m  map[string]*migration
+}
+
+// Migrate moves channel `conn` to the app contract at `app`, e.g., after the
+// contract was upgraded. The channel is closed cooperatively and reopened
+// with the same peer and balances. Requests in progress are answered before
+// the channel is closed. The peer must be configured with the new app.
+func (c *Client) Migrate(ctx context.Context, conn *connection.Connection, app common.Address) (*connection.Connection, error) {
+	challengeDuration := time.Duration(conn.Params().ChallengeDuration) * time.Second
+	return conn.Migrate(ctx, app, func(ctx context.Context, peer wire.Address, balance, peerBalance channel.Bal) (*connection.Connection, error) {
+		return c.connect(ctx, app, peer, "", balance, peerBalance, challengeDuration)
+	})
+}
+
+// acceptMigration accepts the migration of `conn` to `app` announced by the
+// peer. The channel is settled once the peer finalized it, after which the
+// successor proposed by the peer is accepted.
+func (c *Client) acceptMigration(conn *connection.Connection, peer wire.Address, app common.Address) error {
+	if app != c.appAddress {
+		return fmt.Errorf("unsupported app: %v", app)
+	}
+
+	mig := &migration{old: conn, app: app, settled: make(chan struct{})}
+	c.migrations.mu.Lock()
+	c.migrations.m[peer.String()] = mig
+	c.migrations.mu.Unlock()
+
+	go func() {
+		defer close(mig.settled)
+		ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+		defer cancel()
+		if err := conn.WaitConcludadable(ctx); err != nil {
+			mig.err = fmt.Errorf("waiting for final state: %w", err)
+		} else if err := conn.Close(ctx); err != nil {
+			mig.err = fmt.Errorf("settling: %w", err)
+		}
+	}()
+	return nil
+}
+
+// takeMigration returns the migration that proposal `p` reopens, if any.
+func (c *Client) takeMigration(p *client.LedgerChannelProposal) (*migration, bool) {
+	c.migrations.mu.Lock()
+	defer c.migrations.mu.Unlock()
+	mig, ok := c.migrations.m[p.Participant.String()]
+	if !ok || channel.IsNoApp(p.App) || ethwallet.AsEthAddr(p.App.Def()) != mig.app {
+		return nil, false
+	}
+	delete(c.migrations.m, p.Participant.String())
+	return mig, true
+}
+
+// reopen accepts request `req` to open the successor of a migrated channel.
+// The request must deposit the final balance of the migrated channel.
+func (c *Client) reopen(mig *migration, req *connection.ConnectionRequest) {
+	log := c.log.WithField("channel", channelSubject(mig.old.ID())).WithField("phase", "migrate")
+	<-mig.settled // Bounded by migrationTimeout.
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	balance, _ := mig.old.Balances()
+	if mig.err != nil {
+		log.Warnf("Failed to settle migrated channel: %v", mig.err)
+		reject(ctx, log, req, "migrated channel not settled")
+		return
+	} else if req.Balance().Cmp(balance) != 0 {
+		log.Warnf("Wrong deposit for migrated channel: got %v, expected %v", req.Balance(), balance)
+		reject(ctx, log, req, "wrong deposit")
+		return
+	}
+
+	conn, err := req.Accept(ctx)
+	if err != nil {
+		log.Warnf("Failed to accept successor: %v", err)
+		return
+	}
+	c.notify(&connection.ChannelMigrated{
+		EventHeader: connection.EventHeader{Channel: mig.old.ID(), Time: c.connCfg.Clock.Now()},
+		Successor:   conn.ID(),
+		App:         mig.app,
+	})
+}
+
+func reject(ctx context.Context, log log.Logger, req *connection.ConnectionRequest, reason string) {
+	if err := req.Reject(ctx, reason); err != nil {
+		log.Warnf("Failed to reject channel proposal: %v", err)
+	}
+}
+
+// Connection returns the connection of channel `id`.
+func (c *Client) Connection(id channel.ID) (*connection.Connection, bool) {
+	return c.connections.ForID(id)
+}
@@ -0,0 +1,263 @@
+package mock
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client/api"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"perun.network/go-perun/channel"
+)
+
+// state is the state of a channel shared by both participants.
+type state struct {
+	mu       sync.Mutex
+	id       channel.ID
+	balances [2]*big.Int
+	pending  bool                       // Whether a credential request is pending.
+	requests [2]chan *CredentialRequest // Requests to the participants by index.
+	final    chan struct{}              // Closed when the channel is final.
+	once     sync.Once
+}
+
+func newState(balance channel.Bal) *state {
+	s := &state{
+		balances: [2]*big.Int{new(big.Int).Set(balance), new(big.Int)},
+		requests: [2]chan *CredentialRequest{make(chan *CredentialRequest), make(chan *CredentialRequest)},
+		final:    make(chan struct{}),
+	}
+	if _, err := rand.Read(s.id[:]); err != nil {
+		panic(fmt.Sprintf("generating channel ID: %v", err))
+	}
+	return s
+}
+
+func (s *state) finalize() {
+	s.once.Do(func() { close(s.final) })
+}
+
+// pay transfers the price of `offer` from the buyer to the issuer.
+func (s *state) pay(offer *data.Offer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balances[offer.Buyer].Sub(s.balances[offer.Buyer], offer.Price)
+	s.balances[1-offer.Buyer].Add(s.balances[1-offer.Buyer], offer.Price)
+	s.pending = false
+}
+
+func (s *state) clearPending() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = false
+}
+
+// Channel is an in-memory api.Channel.
+type Channel struct {
+	state *state
+	idx   channel.Index
+}
+
+var _ api.Channel = (*Channel)(nil)
+
+func newChannel(s *state, idx channel.Index) *Channel {
+	return &Channel{state: s, idx: idx}
+}
+
+func (c *Channel) ID() channel.ID {
+	return c.state.id
+}
+
+func (c *Channel) Idx() channel.Index {
+	return c.idx
+}
+
+func (c *Channel) Balances() []channel.Bal {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return []channel.Bal{
+		new(big.Int).Set(c.state.balances[0]),
+		new(big.Int).Set(c.state.balances[1]),
+	}
+}
+
+// RequestCredential requests the credential on `doc` from the peer. Like in
+// a real channel, only one request may be pending at a time.
+func (c *Channel) RequestCredential(ctx context.Context, doc []byte, price channel.Bal, issuer common.Address) (api.AsyncCredential, error) {
+	s := c.state
+	s.mu.Lock()
+	select {
+	case <-s.final:
+		s.mu.Unlock()
+		return nil, connection.ErrChannelClosed
+	default:
+	}
+	if s.pending {
+		s.mu.Unlock()
+		return nil, ErrPendingRequest
+	} else if s.balances[c.idx].Cmp(price) < 0 {
+		s.mu.Unlock()
+		return nil, app.ErrInsufficientBalance
+	}
+	s.pending = true
+	s.mu.Unlock()
+
+	req := &CredentialRequest{
+		state: s,
+		offer: &data.Offer{
+			Issuer:   issuer,
+			DataHash: app.ComputeDocumentHash(doc),
+			Price:    new(big.Int).Set(price),
+			Fee:      new(big.Int),
+			Buyer:    uint16(c.idx),
+		},
+		resp: make(chan credentialResponse, 1),
+	}
+	select {
+	case s.requests[1-c.idx] <- req:
+	case <-ctx.Done():
+		s.clearPending()
+		return nil, ctx.Err()
+	}
+	return &AsyncCredential{resp: req.resp}, nil
+}
+
+func (c *Channel) NextCredentialRequest(ctx context.Context) (api.CredentialRequest, error) {
+	select {
+	case r := <-c.state.requests[c.idx]:
+		return r, nil
+	case <-c.state.final:
+		return nil, connection.ErrChannelClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Channel) WaitConcludadable(ctx context.Context) error {
+	select {
+	case <-c.state.final:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close finalizes the channel. The balances are kept for inspection.
+func (c *Channel) Close(ctx context.Context) error {
+	c.state.finalize()
+	return nil
+}
+
+type credentialResponse struct {
+	prop *CredentialProposal
+	err  error
+}
+
+// AsyncCredential is an in-memory api.AsyncCredential.
+type AsyncCredential struct {
+	resp chan credentialResponse
+}
+
+var _ api.AsyncCredential = (*AsyncCredential)(nil)
+
+func (c *AsyncCredential) Await(ctx context.Context) (api.CredentialProposal, error) {
+	select {
+	case r := <-c.resp:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return r.prop, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// CredentialRequest is an in-memory api.CredentialRequest.
+type CredentialRequest struct {
+	state *state
+	offer *data.Offer
+	resp  chan credentialResponse
+}
+
+var _ api.CredentialRequest = (*CredentialRequest)(nil)
+
+func (r *CredentialRequest) Offer() *data.Offer {
+	return r.offer.Clone().(*data.Offer)
+}
+
+func (r *CredentialRequest) CheckDoc(doc []byte) error {
+	if app.ComputeDocumentHash(doc) != r.offer.DataHash {
+		return connection.ErrWrongDocument
+	}
+	return nil
+}
+
+func (r *CredentialRequest) CheckPrice(p *big.Int) error {
+	if r.offer.Price.Cmp(p) != 0 {
+		return connection.ErrWrongPrice
+	}
+	return nil
+}
+
+// IssueCredential signs the credential with `acc` and waits until the
+// requester accepts or rejects it. The price is paid in either case: if the
+// requester rejects, the payment is enforced as if the dispute was resolved,
+// and the channel becomes final.
+func (r *CredentialRequest) IssueCredential(ctx context.Context, acc app.Account) error {
+	if addr := app.AccountAddress(acc); addr != r.offer.Issuer {
+		return fmt.Errorf("unequal addresses: got %v, expected %v", addr, r.offer.Issuer)
+	}
+	sig, err := app.SignHash(acc, app.OfferHash(r.offer))
+	if err != nil {
+		return fmt.Errorf("signing hash: %w", err)
+	}
+
+	prop := &CredentialProposal{
+		signature: sig[:],
+		decision:  make(chan bool, 1),
+	}
+	r.resp <- credentialResponse{prop: prop}
+	select {
+	case accepted := <-prop.decision:
+		r.state.pay(r.offer)
+		if !accepted {
+			r.state.finalize()
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *CredentialRequest) Reject(ctx context.Context, reason string) error {
+	r.state.clearPending()
+	r.resp <- credentialResponse{err: fmt.Errorf("%w: %s", ErrRejected, reason)}
+	return nil
+}
+
+// CredentialProposal is an in-memory api.CredentialProposal.
+type CredentialProposal struct {
+	signature []byte
+	decision  chan bool
+}
+
+var _ api.CredentialProposal = (*CredentialProposal)(nil)
+
+func (p *CredentialProposal) Signature() []byte {
+	return append([]byte(nil), p.signature...)
+}
+
+func (p *CredentialProposal) Accept(ctx context.Context) error {
+	p.decision <- true
+	return nil
+}
+
+func (p *CredentialProposal) Reject(ctx context.Context, reason string) error {
+	p.decision <- false
+	return nil
+}
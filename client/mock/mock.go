@@ -0,0 +1,190 @@
+// Package mock provides in-memory implementations of the interfaces of
+// package api. Clients of a Network open channels to each other without a
+// chain or a network, so that applications can unit test their business
+// logic. Credentials are signed as by the real client, and balances are
+// tracked, but disputes are resolved immediately.
+package mock
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/client/api"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+var (
+	ErrUnknownPeer    = errors.New("unknown peer")
+	ErrClientShutdown = errors.New("client shut down")
+	ErrRejected       = errors.New("rejected by peer")
+	ErrPendingRequest = errors.New("credential request pending")
+)
+
+// Network connects the clients created by it.
+type Network struct {
+	mu      sync.Mutex
+	clients map[common.Address]*Client
+}
+
+func NewNetwork() *Network {
+	return &Network{clients: make(map[common.Address]*Client)}
+}
+
+// NewClient returns a client with the account of `key`.
+func (n *Network) NewClient(key *ecdsa.PrivateKey) *Client {
+	c := &Client{
+		network:  n,
+		addr:     crypto.PubkeyToAddress(key.PublicKey),
+		requests: make(chan *ConnectionRequest),
+		done:     make(chan struct{}),
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.clients[c.addr] = c
+	return c
+}
+
+func (n *Network) client(addr common.Address) (*Client, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	c, ok := n.clients[addr]
+	return c, ok
+}
+
+// Client is an in-memory api.Client.
+type Client struct {
+	network  *Network
+	addr     common.Address
+	requests chan *ConnectionRequest
+	done     chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+	channels []*Channel
+}
+
+var _ api.Client = (*Client)(nil)
+
+func (c *Client) Address() common.Address {
+	return c.addr
+}
+
+func (c *Client) PerunAddress() wallet.Address {
+	return ethwallet.AsWalletAddr(c.addr)
+}
+
+// Connect proposes a channel to `peer`, which must be a client of the same
+// network, and waits until the peer responds.
+func (c *Client) Connect(ctx context.Context, peer wire.Address, balance channel.Bal) (api.Channel, error) {
+	p, ok := c.network.client(ethwallet.AsEthAddr(peer))
+	if !ok {
+		return nil, ErrUnknownPeer
+	}
+
+	st := newState(balance)
+	req := &ConnectionRequest{
+		client: p,
+		peer:   c.PerunAddress(),
+		state:  st,
+		resp:   make(chan error, 1),
+	}
+	select {
+	case p.requests <- req:
+	case <-p.done:
+		return nil, ErrClientShutdown
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case err := <-req.resp:
+		if err != nil {
+			return nil, err
+		}
+		return c.add(newChannel(st, 0)), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) NextConnectionRequest(ctx context.Context) (api.ConnectionRequest, error) {
+	select {
+	case r := <-c.requests:
+		return r, nil
+	case <-c.done:
+		return nil, ErrClientShutdown
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Channels returns the channels of the client that are not final.
+func (c *Client) Channels() []api.Channel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var open []api.Channel
+	for _, ch := range c.channels {
+		select {
+		case <-ch.state.final:
+		default:
+			open = append(open, ch)
+		}
+	}
+	return open
+}
+
+func (c *Client) add(ch *Channel) *Channel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.channels = append(c.channels, ch)
+	return ch
+}
+
+// GracefulShutdown shuts the client down like Shutdown. The in-memory
+// channels need not be settled.
+func (c *Client) GracefulShutdown(context.Context) error {
+	c.Shutdown()
+	return nil
+}
+
+func (c *Client) Shutdown() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// ConnectionRequest is an in-memory api.ConnectionRequest. The peer deposits
+// nothing.
+type ConnectionRequest struct {
+	client *Client // The accepting client.
+	peer   wallet.Address
+	state  *state
+	resp   chan error
+}
+
+var _ api.ConnectionRequest = (*ConnectionRequest)(nil)
+
+func (r *ConnectionRequest) Peer() wallet.Address {
+	return r.peer
+}
+
+func (r *ConnectionRequest) Balance() *big.Int {
+	return new(big.Int)
+}
+
+func (r *ConnectionRequest) Accept(ctx context.Context) (api.Channel, error) {
+	ch := r.client.add(newChannel(r.state, 1))
+	r.resp <- nil
+	return ch, nil
+}
+
+func (r *ConnectionRequest) Reject(ctx context.Context, reason string) error {
+	r.resp <- fmt.Errorf("%w: %s", ErrRejected, reason)
+	return nil
+}
@@ -0,0 +1,35 @@
+package oid4vci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"perun.network/go-perun/wire"
+)
+
+// MsgKindOffer is the message kind of credential offers sent after issuance.
+const MsgKindOffer = "oid4vci-offer"
+
+// SendOffer sends credential offer `o` to `peer`, e.g., the offer returned
+// by Issuer.Issue to the holder of the credential.
+func SendOffer(ctx context.Context, m *message.Messenger, peer wire.Address, o *Offer) error {
+	if err := m.Request(ctx, peer, MsgKindOffer, o, nil); err != nil {
+		return fmt.Errorf("sending credential offer: %w", err)
+	}
+	return nil
+}
+
+// HandleOffers passes the credential offers received from peers to
+// `handle`, which may display them as QR code for a mobile wallet.
+func HandleOffers(m *message.Messenger, handle func(peer wire.Address, o *Offer)) {
+	m.Handle(MsgKindOffer, func(_ context.Context, peer wire.Address, body json.RawMessage) (interface{}, error) {
+		var o Offer
+		if err := json.Unmarshal(body, &o); err != nil {
+			return nil, fmt.Errorf("decoding credential offer: %w", err)
+		}
+		handle(peer, &o)
+		return nil, nil
+	})
+}
@@ -0,0 +1,300 @@
+// Package oid4vci implements the issuer side of OpenID for Verifiable
+// Credential Issuance with the pre-authorized code flow. Instead of a
+// pre-authorization by other means, a code is authorized once the credential
+// has been paid for and issued in a channel. The credential endpoint then
+// returns the issued credential as JWT-VC.
+package oid4vci
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/jwtvc"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/pkg/qr"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+const (
+	// GrantPreAuthorizedCode is the grant type of the pre-authorized code
+	// flow.
+	GrantPreAuthorizedCode = "urn:ietf:params:oauth:grant-type:pre-authorized_code"
+	// ConfigurationID is the ID of the supported credential configuration.
+	ConfigurationID = "PerunCredential"
+	// FormatJWTVC is the format of issued credentials.
+	FormatJWTVC = "jwt_vc_json"
+
+	// TokenLifetime is the lifetime of access tokens.
+	TokenLifetime = 5 * time.Minute
+)
+
+// Token error codes.
+const (
+	errInvalidGrant         = "invalid_grant"
+	errAuthorizationPending = "authorization_pending"
+	errUnsupportedGrantType = "unsupported_grant_type"
+	errInvalidToken         = "invalid_token"
+	errInvalidRequest       = "invalid_credential_request"
+)
+
+type (
+	// Offer is a credential offer.
+	Offer struct {
+		CredentialIssuer           string                        `json:"credential_issuer"`
+		CredentialConfigurationIDs []string                      `json:"credential_configuration_ids"`
+		Grants                     map[string]PreAuthorizedGrant `json:"grants"`
+	}
+
+	// PreAuthorizedGrant is the grant of a credential offer.
+	PreAuthorizedGrant struct {
+		Code string `json:"pre-authorized_code"`
+	}
+
+	// grant is the state of a pre-authorized code.
+	grant struct {
+		holder  common.Address
+		docHash app.Hash
+		cred    *app.Credential // Set once the credential is issued.
+	}
+
+	// token is an access token.
+	token struct {
+		cred    *app.Credential
+		expires time.Time
+	}
+)
+
+// URI returns the credential offer as URI for wallets.
+func (o *Offer) URI() string {
+	enc, err := json.Marshal(o)
+	if err != nil {
+		panic(err)
+	}
+	return "openid-credential-offer://?credential_offer=" + url.QueryEscape(string(enc))
+}
+
+// QRCode returns the URI of the credential offer as QR code, which mobile
+// wallets can scan.
+func (o *Offer) QRCode() (*qr.Code, error) {
+	return qr.Encode([]byte(o.URI()), qr.M)
+}
+
+// Issuer serves the endpoints of a credential issuer.
+type Issuer struct {
+	url string
+	acc app.Account
+
+	mu     sync.Mutex
+	grants map[string]*grant
+	tokens map[string]*token
+}
+
+// NewIssuer creates an issuer that is reachable at `url` and signs with
+// account `acc`.
+func NewIssuer(url string, acc app.Account) *Issuer {
+	return &Issuer{
+		url:    strings.TrimSuffix(url, "/"),
+		acc:    acc,
+		grants: make(map[string]*grant),
+		tokens: make(map[string]*token),
+	}
+}
+
+// NewOffer creates a credential offer for the document with hash `docHash`,
+// to be bought by `holder`. The offer is authorized once the credential has
+// been issued by Issue.
+func (i *Issuer) NewOffer(holder common.Address, docHash app.Hash) *Offer {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.newOffer(&grant{holder: holder, docHash: docHash})
+}
+
+// newOffer creates a credential offer for grant `g`. The mutex must be held.
+func (i *Issuer) newOffer(g *grant) *Offer {
+	code := randomToken()
+	i.grants[code] = g
+	return &Offer{
+		CredentialIssuer:           i.url,
+		CredentialConfigurationIDs: []string{ConfigurationID},
+		Grants:                     map[string]PreAuthorizedGrant{GrantPreAuthorizedCode: {Code: code}},
+	}
+}
+
+// Issue issues the credential requested by `r` in the channel and authorizes
+// the offers for the requested document that were made to the requester. It
+// returns an authorized offer, with which the requester can retrieve the
+// credential into a wallet.
+func (i *Issuer) Issue(ctx context.Context, r *connection.CredentialRequest) (*Offer, error) {
+	doc, err := r.Document()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := r.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	offer := r.Offer()
+	sig, err := app.SignHash(i.acc, r.SigningHash())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.IssueCredential(ctx, i.acc); err != nil {
+		return nil, err
+	}
+
+	cred := &app.Credential{
+		Document:  doc,
+		Signature: sig[:],
+		Expiry:    offer.Expiry,
+		Metadata:  meta,
+		Domain:    offer.Domain,
+	}
+	holder := ethwallet.AsEthAddr(r.Peer())
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, g := range i.grants {
+		if g.holder == holder && g.docHash == offer.DataHash {
+			g.cred = cred
+		}
+	}
+	return i.newOffer(&grant{holder: holder, docHash: offer.DataHash, cred: cred}), nil
+}
+
+// Handler returns the HTTP handler of the issuer endpoints.
+func (i *Issuer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-credential-issuer", i.serveMetadata)
+	mux.HandleFunc("/.well-known/oauth-authorization-server", i.serveAuthorizationServerMetadata)
+	mux.HandleFunc("/token", i.serveToken)
+	mux.HandleFunc("/credential", i.serveCredential)
+	return mux
+}
+
+func (i *Issuer) serveMetadata(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"credential_issuer":   i.url,
+		"credential_endpoint": i.url + "/credential",
+		"credential_configurations_supported": map[string]interface{}{
+			ConfigurationID: map[string]interface{}{
+				"format": FormatJWTVC,
+				"cryptographic_binding_methods_supported": []string{"did:ethr"},
+				"credential_signing_alg_values_supported": []string{"ES256K"},
+				"credential_definition": map[string]interface{}{
+					"type": []string{"VerifiableCredential"},
+				},
+			},
+		},
+	})
+}
+
+func (i *Issuer) serveAuthorizationServerMetadata(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                i.url,
+		"token_endpoint":        i.url + "/token",
+		"grant_types_supported": []string{GrantPreAuthorizedCode},
+		"pre-authorized_grant_anonymous_access_supported": true,
+	})
+}
+
+func (i *Issuer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	} else if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request")
+		return
+	} else if r.PostForm.Get("grant_type") != GrantPreAuthorizedCode {
+		writeError(w, http.StatusBadRequest, errUnsupportedGrantType)
+		return
+	}
+
+	code := r.PostForm.Get("pre-authorized_code")
+	i.mu.Lock()
+	g, ok := i.grants[code]
+	if !ok {
+		i.mu.Unlock()
+		writeError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	} else if g.cred == nil {
+		// The credential has not been paid for yet.
+		i.mu.Unlock()
+		writeError(w, http.StatusBadRequest, errAuthorizationPending)
+		return
+	}
+	delete(i.grants, code)
+	access := randomToken()
+	i.tokens[access] = &token{cred: g.cred, expires: time.Now().Add(TokenLifetime)}
+	i.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": access,
+		"token_type":   "Bearer",
+		"expires_in":   int(TokenLifetime.Seconds()),
+	})
+}
+
+func (i *Issuer) serveCredential(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	access := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	i.mu.Lock()
+	t, ok := i.tokens[access]
+	if ok {
+		delete(i.tokens, access)
+	}
+	i.mu.Unlock()
+	if !ok || time.Now().After(t.expires) {
+		writeError(w, http.StatusUnauthorized, errInvalidToken)
+		return
+	}
+
+	var req struct {
+		Format                    string `json:"format"`
+		CredentialConfigurationID string `json:"credential_configuration_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
+		(req.Format != FormatJWTVC && req.CredentialConfigurationID != ConfigurationID) {
+		writeError(w, http.StatusBadRequest, errInvalidRequest)
+		return
+	}
+
+	cred, err := jwtvc.Encode(t.cred, i.acc)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errInvalidRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"credential": cred,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
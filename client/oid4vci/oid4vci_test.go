@@ -0,0 +1,122 @@
+package oid4vci
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/jwtvc"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+func newAccount(t *testing.T) app.Account {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(t, err)
+	return acc.(*simple.Account)
+}
+
+// post posts `body` to `path` of `srv` and decodes the JSON response into
+// `resp`. It returns the status code.
+func post(t *testing.T, srv *httptest.Server, path, contentType, auth, body string, resp interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	if auth != "" {
+		req.Header.Set("Authorization", "Bearer "+auth)
+	}
+	r, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer r.Body.Close()
+	require.NoError(t, json.NewDecoder(r.Body).Decode(resp))
+	return r.StatusCode
+}
+
+// requestToken requests an access token for pre-authorized code `code`.
+func requestToken(t *testing.T, srv *httptest.Server, code string) (int, map[string]interface{}) {
+	t.Helper()
+	form := url.Values{"grant_type": {GrantPreAuthorizedCode}, "pre-authorized_code": {code}}
+	var resp map[string]interface{}
+	status := post(t, srv, "/token", "application/x-www-form-urlencoded", "", form.Encode(), &resp)
+	return status, resp
+}
+
+func TestOfferURI(t *testing.T) {
+	i := NewIssuer("https://issuer.example/", newAccount(t))
+	offer := i.NewOffer(common.Address{1}, app.ComputeDocumentHash([]byte("doc")))
+	require.Equal(t, "https://issuer.example", offer.CredentialIssuer)
+	require.Equal(t, []string{ConfigurationID}, offer.CredentialConfigurationIDs)
+
+	u, err := url.Parse(offer.URI())
+	require.NoError(t, err)
+	require.Equal(t, "openid-credential-offer", u.Scheme)
+	var dec Offer
+	require.NoError(t, json.Unmarshal([]byte(u.Query().Get("credential_offer")), &dec))
+	require.Equal(t, *offer, dec)
+
+	_, err = offer.QRCode()
+	require.NoError(t, err)
+}
+
+func TestIssuer(t *testing.T) {
+	ctx := context.Background()
+	acc := newAccount(t)
+	srv := httptest.NewServer(nil)
+	t.Cleanup(srv.Close)
+	i := NewIssuer(srv.URL, acc)
+	srv.Config.Handler = i.Handler()
+
+	var meta map[string]interface{}
+	r, err := srv.Client().Get(srv.URL + "/.well-known/openid-credential-issuer")
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&meta))
+	r.Body.Close()
+	require.Equal(t, srv.URL+"/credential", meta["credential_endpoint"])
+
+	holder := common.Address{1}
+	doc := []byte(`{"degree":"MSc"}`)
+	offer := i.NewOffer(holder, app.ComputeDocumentHash(doc))
+	code := offer.Grants[GrantPreAuthorizedCode].Code
+
+	// The code is authorized once the credential is issued.
+	status, resp := requestToken(t, srv, code)
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, errAuthorizationPending, resp["error"])
+	i.mu.Lock()
+	i.grants[code].cred = &app.Credential{Document: doc, Metadata: &app.Metadata{Holder: did.Ethr(holder)}}
+	i.mu.Unlock()
+
+	status, resp = requestToken(t, srv, code)
+	require.Equal(t, http.StatusOK, status)
+	access, _ := resp["access_token"].(string)
+	require.NotEmpty(t, access)
+	status, resp = requestToken(t, srv, code)
+	require.Equal(t, http.StatusBadRequest, status, "code reused")
+	require.Equal(t, errInvalidGrant, resp["error"])
+
+	var credResp map[string]string
+	status = post(t, srv, "/credential", "application/json", access, `{"format":"`+FormatJWTVC+`"}`, &credResp)
+	require.Equal(t, http.StatusOK, status)
+	claims, err := jwtvc.Verify(ctx, credResp["credential"], did.EthrResolver{})
+	require.NoError(t, err)
+	require.Equal(t, did.Ethr(app.AccountAddress(acc)), claims.Issuer)
+	require.Equal(t, did.Ethr(holder), claims.Subject)
+	require.JSONEq(t, string(doc), string(claims.VC.CredentialSubject))
+
+	status = post(t, srv, "/credential", "application/json", access, `{"format":"`+FormatJWTVC+`"}`, &credResp)
+	require.Equal(t, http.StatusUnauthorized, status, "token reused")
+	require.Equal(t, errInvalidToken, credResp["error"])
+}
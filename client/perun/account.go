@@ -0,0 +1,79 @@
+package perun
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	wtest "perun.network/go-perun/backend/ethereum/wallet/simple"
+	pwallet "perun.network/go-perun/wallet"
+)
+
+// clientAccount returns the account configured by `cfg`.
+func clientAccount(cfg ClientConfig) (app.Account, error) {
+	if cfg.Account != nil {
+		return cfg.Account, nil
+	} else if cfg.PrivateKey == nil {
+		return nil, fmt.Errorf("neither account nor private key configured")
+	}
+	w := wtest.NewWallet(cfg.PrivateKey)
+	acc, err := w.Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(cfg.PrivateKey.PublicKey)))
+	if err != nil {
+		return nil, fmt.Errorf("unlocking account: %w", err)
+	}
+	return acc.(*wtest.Account), nil
+}
+
+// accountWallet is the wallet of the client, which holds only the client's
+// account.
+type accountWallet struct {
+	acc app.Account
+}
+
+func (w accountWallet) Unlock(addr pwallet.Address) (pwallet.Account, error) {
+	if !addr.Equals(w.acc.Address()) {
+		return nil, fmt.Errorf("unknown account: %v", addr)
+	}
+	return w.acc, nil
+}
+
+func (accountWallet) LockAll() {}
+
+func (accountWallet) IncrementUsage(pwallet.Address) {}
+
+func (accountWallet) DecrementUsage(pwallet.Address) {}
+
+// accountTransactor signs the transactions of the client's account.
+type accountTransactor struct {
+	acc    app.Account
+	signer types.Signer
+}
+
+func (t accountTransactor) NewTransactor(account accounts.Account) (*bind.TransactOpts, error) {
+	if account.Address != app.AccountAddress(t.acc) {
+		return nil, fmt.Errorf("unknown account: %v", account.Address)
+	}
+	return &bind.TransactOpts{
+		From: account.Address,
+		Signer: func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if addr != account.Address {
+				return nil, fmt.Errorf("not authorized to sign for %v", addr)
+			}
+			return t.sign(tx)
+		},
+	}, nil
+}
+
+// sign signs `tx` with the account.
+func (t accountTransactor) sign(tx *types.Transaction) (*types.Transaction, error) {
+	sig, err := t.acc.SignHash(t.signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(t.signer, sig)
+}
@@ -0,0 +1,56 @@
+package perun
+
+import (
+	"context"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/retry"
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/channel"
+)
+
+// adjudicator registers and progresses disputes with the embedded
+// adjudicator, and concludes and withdraws with `withdrawals`, so that both
+// wait for their own transaction finality. Each operation waits at most
+// `timeout` for its confirmation, if set. Withdrawals are retried according
+// to `retry`, as go-perun skips the steps that were already confirmed.
+type adjudicator struct {
+	*ethchannel.Adjudicator
+	withdrawals *ethchannel.Adjudicator
+	timeout     time.Duration
+	retry       retry.Policy
+}
+
+// Register registers the channel state of `req`.
+func (a *adjudicator) Register(ctx context.Context, req channel.AdjudicatorReq, subChannels []channel.SignedState) error {
+	ctx, cancel := a.confirmationContext(ctx)
+	defer cancel()
+	return a.Adjudicator.Register(ctx, req, subChannels)
+}
+
+// Progress progresses the registered state to the state of `req`.
+func (a *adjudicator) Progress(ctx context.Context, req channel.ProgressReq) error {
+	ctx, cancel := a.confirmationContext(ctx)
+	defer cancel()
+	return a.Adjudicator.Progress(ctx, req)
+}
+
+// Withdraw concludes the channel and withdraws the funds of the own
+// participant.
+func (a *adjudicator) Withdraw(ctx context.Context, req channel.AdjudicatorReq, subStates channel.StateMap) error {
+	return a.retry.Do(ctx, clock.System(), func(ctx context.Context) error {
+		ctx, cancel := a.confirmationContext(ctx)
+		defer cancel()
+		return a.withdrawals.Withdraw(ctx, req, subStates)
+	})
+}
+
+// confirmationContext returns `ctx`, bounded by the confirmation timeout if
+// it is set.
+func (a *adjudicator) confirmationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, a.timeout)
+}
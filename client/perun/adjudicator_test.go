@@ -0,0 +1,29 @@
+package perun
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrDefault(t *testing.T) {
+	require.Equal(t, uint64(12), orDefault(12, 1))
+	require.Equal(t, uint64(1), orDefault(0, 1), "defaults to the transaction finality")
+}
+
+func TestConfirmationContext(t *testing.T) {
+	a := &adjudicator{}
+	ctx, cancel := a.confirmationContext(context.Background())
+	defer cancel()
+	_, ok := ctx.Deadline()
+	require.False(t, ok, "unbounded")
+
+	a.timeout = time.Minute
+	ctx, cancel = a.confirmationContext(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
@@ -7,16 +7,21 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/message"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/retry"
 	"github.com/pkg/errors"
 	"perun.network/go-perun/backend/ethereum/channel"
 	"perun.network/go-perun/backend/ethereum/wallet"
-	wtest "perun.network/go-perun/backend/ethereum/wallet/simple"
 	"perun.network/go-perun/client"
+	pwallet "perun.network/go-perun/wallet"
 	"perun.network/go-perun/watcher/local"
 	"perun.network/go-perun/wire"
 	"perun.network/go-perun/wire/net"
@@ -29,87 +34,171 @@ type Peer struct {
 }
 
 type ClientConfig struct {
-	PrivateKey    *ecdsa.PrivateKey
-	Host          string
-	ETHNodeURL    string
-	Adjudicator   common.Address
-	AssetHolder   common.Address
-	DialerTimeout time.Duration
-	Peers         []Peer
-	TxFinality    uint64
-	ChainID       *big.Int
+	PrivateKey          *ecdsa.PrivateKey
+	Host                string
+	ETHNodeURL          string
+	Adjudicator         common.Address
+	AssetHolder         common.Address
+	DialerTimeout       time.Duration
+	Peers               []Peer
+	TxFinality          uint64
+	DepositFinality     uint64 // Optional. The finality of deposits. Defaults to TxFinality.
+	DisputeFinality     uint64 // Optional. The finality of registering and progressing disputes. Defaults to TxFinality.
+	WithdrawalFinality  uint64 // Optional. The finality of concluding channels and withdrawing. Defaults to TxFinality.
+	ChainID             *big.Int
+	Gas                 GasConfig                 // Optional. Defaults to the fees suggested by the node.
+	TxManager           TxManagerConfig           // Optional. Configures the replacement of stuck transactions.
+	OnReceipt           func(*types.Receipt)      // Optional. Called once for every mined transaction.
+	Backend             ChainBackend              // Optional. Used instead of dialing ETHNodeURL, e.g., a simulated backend.
+	Session             *app.Session              // Optional. Authorizes PrivateKey as session key of a funding account, to which withdrawn funds are sent.
+	Account             app.Account               // Optional. Used instead of PrivateKey, e.g., a key held in a hardware security module. Documents sent to the client are then rejected, as it cannot decrypt them.
+	Compression         message.CompressionConfig // Optional. Compresses large messages to peers that support a common codec.
+	ConfirmationTimeout time.Duration             // Optional. Bounds waiting for disputes and withdrawals to be confirmed on-chain.
+	WithdrawalRetry     retry.Policy              // Optional. Retries concluding and withdrawing channels, e.g., after their confirmation timed out.
+	MessageRetry        retry.Policy              // Optional. Retries sending app messages to peers that cannot be reached, e.g., documents.
+	Clock               clock.Clock               // Optional. Defaults to the system clock. Times the client's waits, except those on the chain.
+}
+
+// ChainBackend is the connection to the chain. It is implemented by
+// ethclient.Client and by simulated backends.
+type ChainBackend interface {
+	channel.ContractInterface
+	ethereum.ChainStateReader
 }
 
 type Client struct {
-	EthClient       *ethclient.Client
+	EthClient       ChainBackend
 	PerunClient     *client.Client
 	Bus             *net.Bus
 	Listener        net.Listener
-	ContractBackend channel.ContractInterface
-	Wallet          *wtest.Wallet
-	Account         *wtest.Account
+	Dialer          *simple.Dialer
+	ContractBackend channel.ContractBackend
+	Wallet          pwallet.Wallet
+	Account         app.Account
+	Messenger       *message.Messenger
+	stopTxManager   context.CancelFunc
 }
 
 func SetupClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
 	// Create wallet and account
-	w := wtest.NewWallet(cfg.PrivateKey)
-	addr := wallet.AsWalletAddr(crypto.PubkeyToAddress(cfg.PrivateKey.PublicKey))
-	pAccount, err := w.Unlock(addr)
+	account, err := clientAccount(cfg)
 	if err != nil {
-		panic("failed to create account")
+		return nil, err
+	}
+	w := accountWallet{account}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.System()
+	}
+	ethAccount := accounts.Account{Address: app.AccountAddress(account)}
+
+	// Withdrawn funds are sent to the funding account of a session key.
+	receiver := ethAccount.Address
+	if s := cfg.Session; s != nil {
+		if s.Key != receiver {
+			return nil, fmt.Errorf("session authorizes %v, not %v", s.Key, receiver)
+		} else if err := s.Verify(clk.Now()); err != nil {
+			return nil, fmt.Errorf("verifying session: %w", err)
+		}
+		receiver = s.Account
 	}
-	account := pAccount.(*wtest.Account)
 
-	// Create Ethereum client and contract backend
-	ethClient, cb, err := createContractBackend(cfg.ETHNodeURL, w, cfg.ChainID, cfg.TxFinality)
+	// Create Ethereum client and contract backends. Each operation class
+	// waits for its own finality. The London signer also signs EIP-1559
+	// transactions.
+	accTr := accountTransactor{account, types.NewLondonSigner(cfg.ChainID)}
+	ethClient, ci, tr, err := createContractInterface(cfg.ETHNodeURL, cfg.Backend, accTr, cfg.ChainID, cfg.Gas, cfg.OnReceipt)
 	if err != nil {
 		return nil, errors.WithMessage(err, "creating contract backend")
 	}
+	txs := newTxManager(ci, ethAccount.Address, accTr.sign, cfg.Gas.FeeLimit, cfg.TxManager, clk)
+	ci = txs
+	cb := channel.NewContractBackend(ci, tr, cfg.TxFinality)
+	depositCB := channel.NewContractBackend(ci, tr, orDefault(cfg.DepositFinality, cfg.TxFinality))
+	disputeCB := channel.NewContractBackend(ci, tr, orDefault(cfg.DisputeFinality, cfg.TxFinality))
+	withdrawalCB := channel.NewContractBackend(ci, tr, orDefault(cfg.WithdrawalFinality, cfg.TxFinality))
 
 	// Setup adjudicator.
 	if err := channel.ValidateAdjudicator(ctx, cb, cfg.Adjudicator); err != nil {
 		return nil, fmt.Errorf("validating adjudicator: %w", err)
 	}
-	adjudicator := channel.NewAdjudicator(cb, cfg.Adjudicator, account.Account.Address, account.Account)
+	adj := &adjudicator{
+		Adjudicator: channel.NewAdjudicator(disputeCB, cfg.Adjudicator, receiver, ethAccount),
+		withdrawals: channel.NewAdjudicator(withdrawalCB, cfg.Adjudicator, receiver, ethAccount),
+		timeout:     cfg.ConfirmationTimeout,
+		retry:       cfg.WithdrawalRetry,
+	}
 
 	// Setup asset holder.
-	funder := createFunder(cb, account.Account, cfg.AssetHolder)
+	funder := createFunder(depositCB, ethAccount, cfg.AssetHolder)
 
 	// Setup network.
-	listener, bus, err := setupNetwork(account, cfg.Host, cfg.Peers, cfg.DialerTimeout)
+	listener, dialer, bus, err := setupNetwork(account, cfg.Host, cfg.Peers, cfg.DialerTimeout)
 	if err != nil {
 		return nil, errors.WithMessage(err, "setting up network")
 	}
 
 	// Setup watcher.
-	watcher, err := local.NewWatcher(adjudicator)
+	watcher, err := local.NewWatcher(adj)
 	if err != nil {
 		return nil, fmt.Errorf("initializing watcher: %w", err)
 	}
 
+	// Setup app messaging. Messages to peers are compressed below the app
+	// messages and channel updates.
+	cbus := message.NewCompressingBus(bus, cfg.Compression)
+	messenger := message.NewMessenger(cbus, account.Address())
+	messenger.SetRetry(cfg.MessageRetry)
+	cbus.Negotiate(messenger)
+
 	// Initialize Perun client.
-	c, err := client.New(account.Address(), bus, funder, adjudicator, w, watcher)
+	c, err := client.New(account.Address(), message.NewBus(cbus, messenger), funder, adj, w, watcher)
 	if err != nil {
 		return nil, errors.WithMessage(err, "initializing client")
 	}
 
-	return &Client{ethClient, c, bus, listener, cb, w, account}, nil
+	txCtx, stopTxManager := context.WithCancel(context.Background())
+	go txs.run(txCtx)
+	return &Client{ethClient, c, bus, listener, dialer, cb, w, account, messenger, stopTxManager}, nil
 }
 
-func createContractBackend(nodeURL string, wallet *wtest.Wallet, chainID *big.Int, txFinality uint64) (*ethclient.Client, channel.ContractBackend, error) {
-	client, err := ethclient.Dial(nodeURL)
-	if err != nil {
-		return nil, channel.ContractBackend{}, nil
+// Close stops replacing stuck transactions.
+func (c *Client) Close() {
+	c.stopTxManager()
+}
+
+// createContractInterface dials the node at `nodeURL`, unless `backend` is
+// given. Without a node, fees are only set if configured statically.
+func createContractInterface(nodeURL string, backend ChainBackend, accTr channel.Transactor, chainID *big.Int, gas GasConfig, onReceipt func(*types.Receipt)) (ChainBackend, channel.ContractInterface, channel.Transactor, error) {
+	var rpcClient *rpc.Client
+	if backend == nil {
+		var err error
+		if rpcClient, err = rpc.Dial(nodeURL); err != nil {
+			return nil, nil, nil, err
+		}
+		backend = ethclient.NewClient(rpcClient)
 	}
 
-	signer := types.NewEIP155Signer(chainID)
-	tr := wtest.NewTransactor(wallet, signer)
+	tr := &feeTransactor{Transactor: accTr, rpc: rpcClient, chainID: chainID, cfg: gas}
+
+	var ci channel.ContractInterface = backend
+	if onReceipt != nil {
+		ci = newReceiptObserver(backend, onReceipt)
+	}
 
-	return client, channel.NewContractBackend(client, tr, txFinality), nil
+	return backend, ci, tr, nil
+}
+
+// orDefault returns `finality`, or `def` if it is zero.
+func orDefault(finality, def uint64) uint64 {
+	if finality == 0 {
+		return def
+	}
+	return finality
 }
 
-func setupNetwork(account wire.Account, host string, peerAddresses []Peer, dialerTimeout time.Duration) (listener net.Listener, bus *net.Bus, err error) {
-	dialer := simple.NewTCPDialer(dialerTimeout)
+func setupNetwork(account wire.Account, host string, peerAddresses []Peer, dialerTimeout time.Duration) (listener net.Listener, dialer *simple.Dialer, bus *net.Bus, err error) {
+	dialer = simple.NewTCPDialer(dialerTimeout)
 
 	for _, pa := range peerAddresses {
 		dialer.Register(pa.Peer, pa.Address)
@@ -122,7 +211,7 @@ func setupNetwork(account wire.Account, host string, peerAddresses []Peer, diale
 	}
 
 	bus = net.NewBus(account, dialer)
-	return listener, bus, nil
+	return listener, dialer, bus, nil
 }
 
 func createFunder(cb channel.ContractBackend, account accounts.Account, assetHolder common.Address) *channel.Funder {
@@ -0,0 +1,148 @@
+package perun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"perun.network/go-perun/backend/ethereum/channel"
+)
+
+const (
+	// feeHistoryBlocks is the number of blocks whose priority fees are
+	// considered for the dynamic tip.
+	feeHistoryBlocks = 10
+	// feeQueryTimeout bounds querying the fee history.
+	feeQueryTimeout = 10 * time.Second
+)
+
+// ErrFeeLimitExceeded is returned if a transaction would pay more per gas
+// than the configured fee limit.
+var ErrFeeLimitExceeded = errors.New("fee limit exceeded")
+
+// GasConfig configures the fees of the transactions sent by the client.
+type GasConfig struct {
+	// MaxFeePerGas is the fee cap of EIP-1559 transactions. Optional. If nil,
+	// it is twice the base fee of the next block plus the priority fee.
+	MaxFeePerGas *big.Int
+	// MaxPriorityFeePerGas is the priority fee of EIP-1559 transactions.
+	// Optional. If nil, it is the median priority fee paid in recent blocks.
+	MaxPriorityFeePerGas *big.Int
+	// FeeLimit is the highest fee per gas the client pays. Optional.
+	// Transactions that may pay more, e.g., during gas spikes, are aborted
+	// with ErrFeeLimitExceeded instead.
+	FeeLimit *big.Int
+}
+
+// feeTransactor sets the fees configured by cfg on the transactions of the
+// wrapped transactor. As go-perun sets the gas price suggested by the node,
+// the transactions are turned into EIP-1559 transactions when they are
+// signed. On chains without base fee, legacy transactions are sent.
+type feeTransactor struct {
+	channel.Transactor
+	rpc     *rpc.Client
+	chainID *big.Int
+	cfg     GasConfig
+}
+
+func (t *feeTransactor) NewTransactor(acc accounts.Account) (*bind.TransactOpts, error) {
+	opts, err := t.Transactor.NewTransactor(acc)
+	if err != nil {
+		return nil, err
+	}
+
+	sign := opts.Signer
+	opts.Signer = func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		tx, err := t.withFees(tx)
+		if err != nil {
+			return nil, fmt.Errorf("determining fees: %w", err)
+		} else if t.cfg.FeeLimit != nil && tx.GasFeeCap().Cmp(t.cfg.FeeLimit) > 0 {
+			return nil, fmt.Errorf("%w: fee per gas %v above limit %v", ErrFeeLimitExceeded, tx.GasFeeCap(), t.cfg.FeeLimit)
+		}
+		return sign(addr, tx)
+	}
+	return opts, nil
+}
+
+// withFees returns legacy transaction `tx` as EIP-1559 transaction with the
+// configured fees. Other transactions are returned unchanged.
+func (t *feeTransactor) withFees(tx *types.Transaction) (*types.Transaction, error) {
+	if tx.Type() != types.LegacyTxType {
+		return tx, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), feeQueryTimeout)
+	defer cancel()
+	feeCap, tip, err := t.fees(ctx)
+	if err != nil || feeCap == nil {
+		return tx, err
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   t.chainID,
+		Nonce:     tx.Nonce(),
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       tx.Gas(),
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Data:      tx.Data(),
+	}), nil
+}
+
+// fees returns the fee cap and priority fee of the next transaction, or nil
+// if the chain has no base fee.
+func (t *feeTransactor) fees(ctx context.Context) (feeCap, tip *big.Int, err error) {
+	if t.cfg.MaxFeePerGas != nil && t.cfg.MaxPriorityFeePerGas != nil {
+		return t.cfg.MaxFeePerGas, t.cfg.MaxPriorityFeePerGas, nil
+	} else if t.rpc == nil {
+		// The backend is not a node, e.g., a simulated backend.
+		return nil, nil, nil
+	}
+
+	var history struct {
+		BaseFee []*hexutil.Big   `json:"baseFeePerGas"`
+		Reward  [][]*hexutil.Big `json:"reward"`
+	}
+	err = t.rpc.CallContext(ctx, &history, "eth_feeHistory", hexutil.Uint(feeHistoryBlocks), "latest", []float64{50})
+	if err != nil || len(history.BaseFee) == 0 || history.BaseFee[len(history.BaseFee)-1].ToInt().Sign() == 0 {
+		// The node does not support EIP-1559.
+		return nil, nil, nil
+	}
+
+	tip = t.cfg.MaxPriorityFeePerGas
+	if tip == nil {
+		tip = medianReward(history.Reward)
+	}
+	feeCap = t.cfg.MaxFeePerGas
+	if feeCap == nil {
+		// The last entry is the base fee of the next block.
+		feeCap = new(big.Int).Mul(history.BaseFee[len(history.BaseFee)-1].ToInt(), big.NewInt(2))
+		feeCap.Add(feeCap, tip)
+	}
+	return feeCap, tip, nil
+}
+
+// medianReward returns the median of the priority fees in `rewards`, which
+// hold one percentile per block.
+func medianReward(rewards [][]*hexutil.Big) *big.Int {
+	var fees []*big.Int
+	for _, r := range rewards {
+		if len(r) > 0 {
+			fees = append(fees, r[0].ToInt())
+		}
+	}
+	if len(fees) == 0 {
+		return new(big.Int)
+	}
+	sort.Slice(fees, func(i, j int) bool { return fees[i].Cmp(fees[j]) < 0 })
+	return new(big.Int).Set(fees[len(fees)/2])
+}
@@ -0,0 +1,132 @@
+package perun
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/stretchr/testify/require"
+	ethchanneltest "perun.network/go-perun/backend/ethereum/channel/test"
+)
+
+// simulatedChainID is the chain ID of the simulated backend.
+var simulatedChainID = big.NewInt(1337)
+
+// newSimulatedAccount returns a simulated backend and the transactor of an
+// account that is funded on it.
+func newSimulatedAccount(t *testing.T) (*ethchanneltest.SimulatedBackend, accountTransactor) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := clientAccount(ClientConfig{PrivateKey: key})
+	require.NoError(t, err)
+
+	sb := ethchanneltest.NewSimulatedBackend()
+	sb.FundAddress(context.Background(), app.AccountAddress(acc))
+	return sb, accountTransactor{acc, types.NewLondonSigner(simulatedChainID)}
+}
+
+// transfer returns an unsigned legacy transaction of the account of `tr` with
+// the next nonce.
+func transfer(t *testing.T, sb *ethchanneltest.SimulatedBackend, tr accountTransactor) *types.Transaction {
+	t.Helper()
+	nonce, err := sb.PendingNonceAt(context.Background(), app.AccountAddress(tr.acc))
+	require.NoError(t, err)
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: big.NewInt(ethchanneltest.GasPrice),
+		Gas:      21000,
+		To:       &common.Address{1},
+		Value:    big.NewInt(1),
+	})
+}
+
+func TestFeeTransactor(t *testing.T) {
+	ctx := context.Background()
+	sb, accTr := newSimulatedAccount(t)
+	from := accounts.Account{Address: app.AccountAddress(accTr.acc)}
+	feeCap, tip := big.NewInt(2*ethchanneltest.GasPrice), big.NewInt(1e9)
+
+	tests := []struct {
+		name    string
+		cfg     GasConfig
+		txType  uint8
+		feeCap  *big.Int
+		limited bool
+	}{
+		{"static fees", GasConfig{MaxFeePerGas: feeCap, MaxPriorityFeePerGas: tip}, types.DynamicFeeTxType, feeCap, false},
+		{"no node", GasConfig{MaxFeePerGas: feeCap}, types.LegacyTxType, big.NewInt(ethchanneltest.GasPrice), false},
+		{"fee limit", GasConfig{MaxFeePerGas: feeCap, MaxPriorityFeePerGas: tip, FeeLimit: big.NewInt(ethchanneltest.GasPrice)}, types.DynamicFeeTxType, feeCap, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &feeTransactor{Transactor: accTr, chainID: simulatedChainID, cfg: tt.cfg}
+			opts, err := tr.NewTransactor(from)
+			require.NoError(t, err)
+			tx, err := opts.Signer(from.Address, transfer(t, sb, accTr))
+			if tt.limited {
+				require.ErrorIs(t, err, ErrFeeLimitExceeded)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.txType, tx.Type())
+			require.Equal(t, tt.feeCap, tx.GasFeeCap())
+
+			// The node accepts the transaction and mines it.
+			require.NoError(t, sb.SendTransaction(ctx, tx))
+			r, err := bind.WaitMined(ctx, sb, tx)
+			require.NoError(t, err)
+			require.Equal(t, types.ReceiptStatusSuccessful, r.Status)
+		})
+	}
+}
+
+// feeHistory serves eth_feeHistory with fixed fees.
+type feeHistory struct {
+	baseFee []*hexutil.Big
+	reward  [][]*hexutil.Big
+}
+
+func (f *feeHistory) FeeHistory(blocks hexutil.Uint, newest string, percentiles []float64) (map[string]interface{}, error) {
+	return map[string]interface{}{"baseFeePerGas": f.baseFee, "reward": f.reward}, nil
+}
+
+func TestFees(t *testing.T) {
+	fee := func(x int64) *hexutil.Big { return (*hexutil.Big)(big.NewInt(x)) }
+	history := &feeHistory{
+		baseFee: []*hexutil.Big{fee(100), fee(200)},
+		reward:  [][]*hexutil.Big{{fee(3)}, {fee(1)}, {}, {fee(2)}},
+	}
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", history))
+	client := rpc.DialInProc(server)
+	defer client.Close()
+	tr := &feeTransactor{rpc: client, chainID: simulatedChainID}
+
+	// The fee cap is twice the next base fee plus the median tip.
+	feeCap, tip, err := tr.fees(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 2, tip.Int64())
+	require.EqualValues(t, 402, feeCap.Int64())
+
+	// Configured fees take precedence.
+	tr.cfg = GasConfig{MaxPriorityFeePerGas: big.NewInt(5)}
+	feeCap, tip, err = tr.fees(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 5, tip.Int64())
+	require.EqualValues(t, 405, feeCap.Int64())
+
+	// Without base fee, legacy transactions are sent.
+	history.baseFee = []*hexutil.Big{fee(0)}
+	feeCap, _, err = tr.fees(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, feeCap)
+}
@@ -0,0 +1,44 @@
+package perun
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"perun.network/go-perun/backend/ethereum/channel"
+)
+
+// receiptObserver wraps a contract interface and reports every transaction
+// receipt it sees exactly once.
+type receiptObserver struct {
+	channel.ContractInterface
+	mu       sync.Mutex
+	seen     map[common.Hash]struct{}
+	observer func(*types.Receipt)
+}
+
+func newReceiptObserver(ci channel.ContractInterface, observer func(*types.Receipt)) *receiptObserver {
+	return &receiptObserver{
+		ContractInterface: ci,
+		seen:              make(map[common.Hash]struct{}),
+		observer:          observer,
+	}
+}
+
+func (o *receiptObserver) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	r, err := o.ContractInterface.TransactionReceipt(ctx, txHash)
+	if err != nil || r == nil {
+		return r, err
+	}
+
+	o.mu.Lock()
+	_, ok := o.seen[txHash]
+	o.seen[txHash] = struct{}{}
+	o.mu.Unlock()
+
+	if !ok {
+		o.observer(r)
+	}
+	return r, nil
+}
@@ -0,0 +1,307 @@
+package perun
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/log"
+)
+
+const (
+	// DefaultStuckAfter is the default duration after which a pending
+	// transaction is considered stuck.
+	DefaultStuckAfter = 3 * time.Minute
+	// DefaultFeeBump is the default fee increase of replacement transactions
+	// in percent. Nodes require at least 10 percent.
+	DefaultFeeBump = 20
+	// DefaultMaxReplacements is the default number of times a transaction is
+	// replaced.
+	DefaultMaxReplacements = 5
+)
+
+// TxManagerConfig configures the replacement of stuck transactions.
+type TxManagerConfig struct {
+	StuckAfter      time.Duration // Optional. Defaults to DefaultStuckAfter.
+	FeeBump         uint64        // Optional. Defaults to DefaultFeeBump.
+	MaxReplacements int           // Optional. Defaults to DefaultMaxReplacements.
+}
+
+// pendingTx is a transaction sent by the client that was not mined yet.
+type pendingTx struct {
+	tx     *types.Transaction // The latest replacement.
+	hashes []common.Hash      // The original and all replacements.
+	sent   time.Time
+	done   time.Time // When the transaction stopped being tracked. Zero while pending.
+}
+
+// nonceReader reads the nonce of an account in the latest block. It is
+// implemented by the ethclient and the simulated backend.
+type nonceReader interface {
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// txManager wraps a contract interface and tracks the transactions sent
+// through it by nonce. Transactions that are not mined in time are resent
+// with bumped fees, e.g., if they were underpriced. Receipts requested for
+// the original transaction are those of the mined replacement, so that
+// waiting for a transaction is not affected by its replacement. Transactions
+// whose nonce was used are kept for another StuckAfter, so that waiting for
+// them completes, and are then evicted.
+type txManager struct {
+	channel.ContractInterface
+	from     common.Address
+	sign     func(*types.Transaction) (*types.Transaction, error)
+	feeLimit *big.Int
+	cfg      TxManagerConfig
+	clock    clock.Clock
+
+	mu        sync.Mutex
+	pending   map[uint64]*pendingTx
+	byHash    map[common.Hash]*pendingTx
+	done      []*pendingTx // Transactions that are no longer pending, oldest first.
+	nextNonce uint64
+}
+
+func newTxManager(ci channel.ContractInterface, from common.Address, sign func(*types.Transaction) (*types.Transaction, error), feeLimit *big.Int, cfg TxManagerConfig, clk clock.Clock) *txManager {
+	if cfg.StuckAfter == 0 {
+		cfg.StuckAfter = DefaultStuckAfter
+	}
+	if cfg.FeeBump == 0 {
+		cfg.FeeBump = DefaultFeeBump
+	}
+	if cfg.MaxReplacements == 0 {
+		cfg.MaxReplacements = DefaultMaxReplacements
+	}
+	return &txManager{
+		ContractInterface: ci,
+		from:              from,
+		sign:              sign,
+		feeLimit:          feeLimit,
+		cfg:               cfg,
+		clock:             clk,
+		pending:           make(map[uint64]*pendingTx),
+		byHash:            make(map[common.Hash]*pendingTx),
+	}
+}
+
+func (m *txManager) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := m.ContractInterface.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if prev, ok := m.pending[tx.Nonce()]; ok {
+		prev.done = now
+		m.done = append(m.done, prev)
+	}
+	p := &pendingTx{tx: tx, hashes: []common.Hash{tx.Hash()}, sent: now}
+	m.pending[tx.Nonce()] = p
+	m.byHash[tx.Hash()] = p
+	if tx.Nonce() >= m.nextNonce {
+		m.nextNonce = tx.Nonce() + 1
+	}
+	return nil
+}
+
+// PendingNonceAt returns the next nonce of `account`. For the own account,
+// it accounts for the transactions sent by the client that the node does
+// not know about, e.g., because they were dropped from its pool.
+func (m *txManager) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	nonce, err := m.ContractInterface.PendingNonceAt(ctx, account)
+	if err != nil || account != m.from {
+		return nonce, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.nextNonce > nonce {
+		return m.nextNonce, nil
+	}
+	return nonce, nil
+}
+
+// TransactionReceipt returns the receipt of the transaction with hash
+// `txHash`, or of the replacement that was mined instead.
+func (m *txManager) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	m.mu.Lock()
+	hashes := []common.Hash{txHash}
+	if p, ok := m.byHash[txHash]; ok {
+		hashes = append([]common.Hash(nil), p.hashes...)
+	}
+	m.mu.Unlock()
+
+	for _, h := range hashes {
+		r, err := m.ContractInterface.TransactionReceipt(ctx, h)
+		if err == nil && r != nil {
+			return r, nil
+		}
+	}
+	// Report the result for the requested hash, e.g., ethereum.NotFound.
+	return m.ContractInterface.TransactionReceipt(ctx, txHash)
+}
+
+// run replaces stuck transactions until `ctx` is done.
+func (m *txManager) run(ctx context.Context) {
+	ticker := m.clock.NewTicker(m.cfg.StuckAfter / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			m.replaceStuck(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// replaceStuck stops tracking the transactions whose nonce was used and
+// resends the ones that are pending for longer than StuckAfter with bumped
+// fees. A transaction is given up on if its nonce was used by another
+// transaction of the account or if it was replaced MaxReplacements times.
+func (m *txManager) replaceStuck(ctx context.Context) {
+	now := m.clock.Now()
+	confirmed, known := m.confirmedNonce(ctx)
+
+	m.mu.Lock()
+	m.evict(now)
+	var stuck []*pendingTx
+	for nonce, p := range m.pending {
+		if now.Sub(p.sent) > m.cfg.StuckAfter || (known && nonce < confirmed) {
+			stuck = append(stuck, p)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, p := range stuck {
+		switch {
+		case m.mined(ctx, p):
+			m.finish(p, now)
+		case known && p.tx.Nonce() < confirmed:
+			log.Errorf("Nonce %d of transaction %v was used by another transaction", p.tx.Nonce(), p.hashes[0])
+			m.finish(p, now)
+		case len(p.hashes) > m.cfg.MaxReplacements:
+			log.Errorf("Giving up on transaction %v, which is stuck after %d replacements", p.hashes[0], len(p.hashes)-1)
+			m.finish(p, now)
+		default:
+			if err := m.replace(ctx, p); err != nil {
+				log.Warnf("Replacing stuck transaction %v: %v", p.tx.Hash(), err)
+			}
+		}
+	}
+}
+
+// confirmedNonce returns the nonce of the account in the latest block, i.e.,
+// the number of its mined transactions. It returns false if the nonce is
+// unknown, e.g., because the backend does not provide it.
+func (m *txManager) confirmedNonce(ctx context.Context) (uint64, bool) {
+	nr, ok := m.ContractInterface.(nonceReader)
+	if !ok {
+		return 0, false
+	}
+	nonce, err := nr.NonceAt(ctx, m.from, nil)
+	if err != nil {
+		log.Warnf("Querying the confirmed nonce: %v", err)
+		return 0, false
+	}
+	return nonce, true
+}
+
+// mined returns whether `p` or one of its replacements was mined.
+func (m *txManager) mined(ctx context.Context, p *pendingTx) bool {
+	m.mu.Lock()
+	hashes := append([]common.Hash(nil), p.hashes...)
+	m.mu.Unlock()
+
+	for _, h := range hashes {
+		if r, err := m.ContractInterface.TransactionReceipt(ctx, h); err == nil && r != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// finish stops tracking `p` as pending at time `now`. Its hashes are kept
+// until it is evicted, so that receipts requested for the original are still
+// found.
+func (m *txManager) finish(p *pendingTx, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending[p.tx.Nonce()] == p {
+		delete(m.pending, p.tx.Nonce())
+	}
+	p.done = now
+	m.done = append(m.done, p)
+}
+
+// evict forgets the hashes of the transactions that were finished at least
+// StuckAfter before `now`. It must be called with the lock held.
+func (m *txManager) evict(now time.Time) {
+	n := 0
+	for ; n < len(m.done) && now.Sub(m.done[n].done) >= m.cfg.StuckAfter; n++ {
+		for _, h := range m.done[n].hashes {
+			delete(m.byHash, h)
+		}
+		m.done[n] = nil
+	}
+	m.done = m.done[n:]
+}
+
+// replace resends `p` with bumped fees.
+func (m *txManager) replace(ctx context.Context, p *pendingTx) error {
+	tx, err := m.sign(m.bumped(p.tx))
+	if err != nil {
+		return err
+	}
+	if m.feeLimit != nil && tx.GasFeeCap().Cmp(m.feeLimit) > 0 {
+		return ErrFeeLimitExceeded
+	}
+	if err := m.ContractInterface.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	log.Infof("Replaced stuck transaction %v with %v", p.tx.Hash(), tx.Hash())
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p.tx = tx
+	p.hashes = append(p.hashes, tx.Hash())
+	p.sent = m.clock.Now()
+	m.byHash[tx.Hash()] = p
+	return nil
+}
+
+// bumped returns an unsigned copy of `tx` with fees increased by FeeBump
+// percent.
+func (m *txManager) bumped(tx *types.Transaction) *types.Transaction {
+	bump := func(fee *big.Int) *big.Int {
+		b := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+m.cfg.FeeBump))
+		return b.Div(b, big.NewInt(100))
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   tx.ChainId(),
+			Nonce:     tx.Nonce(),
+			GasTipCap: bump(tx.GasTipCap()),
+			GasFeeCap: bump(tx.GasFeeCap()),
+			Gas:       tx.Gas(),
+			To:        tx.To(),
+			Value:     tx.Value(),
+			Data:      tx.Data(),
+		})
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    tx.Nonce(),
+		GasPrice: bump(tx.GasPrice()),
+		Gas:      tx.Gas(),
+		To:       tx.To(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+	})
+}
@@ -0,0 +1,176 @@
+package perun
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/channel"
+	ethchanneltest "perun.network/go-perun/backend/ethereum/channel/test"
+)
+
+// droppingBackend drops the sent transactions while `drop` is set, like a
+// node that evicts underpriced transactions from its pool.
+type droppingBackend struct {
+	channel.ContractInterface
+	mu   sync.Mutex
+	drop bool
+}
+
+func (b *droppingBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mu.Lock()
+	drop := b.drop
+	b.mu.Unlock()
+	if drop {
+		return nil
+	}
+	return b.ContractInterface.SendTransaction(ctx, tx)
+}
+
+func (b *droppingBackend) setDrop(drop bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop = drop
+}
+
+// stuckTx sends a transaction through `m` that is dropped by `b`, and waits
+// until it is stuck.
+func stuckTx(t *testing.T, m *txManager, b *droppingBackend, sb *ethchanneltest.SimulatedBackend, tr accountTransactor) *types.Transaction {
+	t.Helper()
+	b.setDrop(true)
+	tx, err := tr.sign(transfer(t, sb, tr))
+	require.NoError(t, err)
+	require.NoError(t, m.SendTransaction(context.Background(), tx))
+	time.Sleep(2 * m.cfg.StuckAfter)
+	return tx
+}
+
+func TestTxManagerReplace(t *testing.T) {
+	ctx := context.Background()
+	sb, tr := newSimulatedAccount(t)
+	from := app.AccountAddress(tr.acc)
+	b := &droppingBackend{ContractInterface: sb}
+	m := newTxManager(b, from, tr.sign, nil, TxManagerConfig{StuckAfter: time.Millisecond}, clock.System())
+
+	// The node does not know the dropped transaction, but the manager
+	// accounts for its nonce.
+	tx := stuckTx(t, m, b, sb, tr)
+	nonce, err := m.PendingNonceAt(ctx, from)
+	require.NoError(t, err)
+	require.Equal(t, tx.Nonce()+1, nonce)
+	r, err := m.TransactionReceipt(ctx, tx.Hash())
+	require.NoError(t, err)
+	require.Nil(t, r, "receipt of the dropped transaction")
+
+	// The stuck transaction is replaced with bumped fees and mined.
+	b.setDrop(false)
+	m.replaceStuck(ctx)
+	r, err = m.TransactionReceipt(ctx, tx.Hash())
+	require.NoError(t, err, "receipt of the replacement")
+	require.NotEqual(t, tx.Hash(), r.TxHash)
+	require.Equal(t, types.ReceiptStatusSuccessful, r.Status)
+	replacement, _, err := sb.TransactionByHash(ctx, r.TxHash)
+	require.NoError(t, err)
+	require.Equal(t, tx.Nonce(), replacement.Nonce())
+	require.EqualValues(t, ethchanneltest.GasPrice*(100+DefaultFeeBump)/100, replacement.GasPrice().Int64())
+
+	// Mined transactions are no longer tracked, but the receipt requested
+	// for the original is still found.
+	time.Sleep(2 * m.cfg.StuckAfter)
+	m.replaceStuck(ctx)
+	m.mu.Lock()
+	require.Empty(t, m.pending)
+	m.mu.Unlock()
+	r2, err := m.TransactionReceipt(ctx, tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, r.TxHash, r2.TxHash)
+
+	// The hashes of mined transactions are evicted after StuckAfter.
+	time.Sleep(2 * m.cfg.StuckAfter)
+	m.replaceStuck(ctx)
+	m.mu.Lock()
+	require.Empty(t, m.byHash)
+	require.Empty(t, m.done)
+	m.mu.Unlock()
+}
+
+func TestTxManagerLimits(t *testing.T) {
+	ctx := context.Background()
+	sb, tr := newSimulatedAccount(t)
+	from := app.AccountAddress(tr.acc)
+	b := &droppingBackend{ContractInterface: sb}
+
+	// Transactions are replaced at most MaxReplacements times and are then
+	// given up on.
+	m := newTxManager(b, from, tr.sign, nil, TxManagerConfig{StuckAfter: time.Millisecond, MaxReplacements: 2}, clock.System())
+	tx := stuckTx(t, m, b, sb, tr)
+	m.mu.Lock()
+	p := m.pending[tx.Nonce()]
+	m.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		m.replaceStuck(ctx)
+		time.Sleep(2 * m.cfg.StuckAfter)
+	}
+	m.mu.Lock()
+	require.Len(t, p.hashes, 3)
+	require.NotContains(t, m.pending, tx.Nonce())
+	m.mu.Unlock()
+
+	// Replacements above the fee limit are not sent.
+	limit := big.NewInt(ethchanneltest.GasPrice)
+	m = newTxManager(b, from, tr.sign, limit, TxManagerConfig{StuckAfter: time.Millisecond}, clock.System())
+	tx = stuckTx(t, m, b, sb, tr)
+	require.ErrorIs(t, m.replace(ctx, m.pending[tx.Nonce()]), ErrFeeLimitExceeded)
+	require.Len(t, m.pending[tx.Nonce()].hashes, 1)
+}
+
+func TestTxManagerNonceUsed(t *testing.T) {
+	ctx := context.Background()
+	sb, tr := newSimulatedAccount(t)
+	from := app.AccountAddress(tr.acc)
+	b := &droppingBackend{ContractInterface: sb}
+	m := newTxManager(b, from, tr.sign, nil, TxManagerConfig{StuckAfter: time.Millisecond}, clock.System())
+
+	// Another transaction of the account is mined with the nonce of the
+	// stuck transaction, which is then given up on instead of replaced.
+	tx := stuckTx(t, m, b, sb, tr)
+	other, err := tr.sign(types.NewTx(&types.LegacyTx{
+		Nonce:    tx.Nonce(),
+		GasPrice: big.NewInt(ethchanneltest.GasPrice),
+		Gas:      21000,
+		To:       &common.Address{2},
+		Value:    big.NewInt(1),
+	}))
+	require.NoError(t, err)
+	require.NoError(t, sb.SendTransaction(ctx, other))
+
+	b.setDrop(false)
+	m.replaceStuck(ctx)
+	m.mu.Lock()
+	require.Empty(t, m.pending)
+	m.mu.Unlock()
+	r, err := m.TransactionReceipt(ctx, tx.Hash())
+	require.NoError(t, err)
+	require.Nil(t, r, "receipt of the stuck transaction")
+}
+
+func TestBumped(t *testing.T) {
+	m := newTxManager(nil, common.Address{}, nil, nil, TxManagerConfig{FeeBump: 10}, clock.System())
+	legacy := types.NewTx(&types.LegacyTx{Nonce: 3, GasPrice: big.NewInt(1000), Gas: 21000})
+	bumped := m.bumped(legacy)
+	require.Equal(t, uint64(3), bumped.Nonce())
+	require.EqualValues(t, 1100, bumped.GasPrice().Int64())
+
+	dynamic := types.NewTx(&types.DynamicFeeTx{ChainID: simulatedChainID, Nonce: 3, GasTipCap: big.NewInt(100), GasFeeCap: big.NewInt(1000), Gas: 21000})
+	bumped = m.bumped(dynamic)
+	require.Equal(t, uint8(types.DynamicFeeTxType), bumped.Type())
+	require.EqualValues(t, 110, bumped.GasTipCap().Int64())
+	require.EqualValues(t, 1100, bumped.GasFeeCap().Int64())
+}
@@ -0,0 +1,146 @@
+// Package policy provides rules for automatically deciding on credential
+// requests.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"perun.network/go-perun/wallet"
+)
+
+var ErrDenied = errors.New("denied by policy")
+
+// DeniedError is returned when a rule denies a request.
+type DeniedError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%v: %s: %s", ErrDenied, e.Rule, e.Reason)
+}
+
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+func deny(rule, format string, v ...interface{}) error {
+	return &DeniedError{Rule: rule, Reason: fmt.Sprintf(format, v...)}
+}
+
+// Request describes a credential request to be decided on.
+type Request struct {
+	Peer     wallet.Address
+	Type     string // The credential type, empty if unknown.
+	Document []byte
+	DocHash  app.Hash
+	Price    *big.Int
+	Time     time.Time // The time of the request by the clock of the issuer.
+}
+
+// Policy decides on credential requests.
+type Policy interface {
+	// Evaluate returns nil if the request is accepted and an error describing
+	// the reason otherwise.
+	Evaluate(r *Request) error
+}
+
+// Func is a Policy implemented by a function.
+type Func func(r *Request) error
+
+func (f Func) Evaluate(r *Request) error {
+	return f(r)
+}
+
+// All accepts a request if all policies accept it. Policies are evaluated
+// in order and evaluation stops at the first denial.
+func All(policies ...Policy) Policy {
+	return Func(func(r *Request) error {
+		for _, p := range policies {
+			if err := p.Evaluate(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DocumentMatches accepts requests whose document matches `pattern`.
+func DocumentMatches(pattern *regexp.Regexp) Policy {
+	return Func(func(r *Request) error {
+		if !pattern.Match(r.Document) {
+			return deny("document", "does not match %v", pattern)
+		}
+		return nil
+	})
+}
+
+// MinPrice accepts requests whose price is at least the minimum price
+// configured for the credential type. Requests for types without a minimum
+// price are checked against `def`, if not nil, and denied otherwise.
+func MinPrice(prices map[string]*big.Int, def *big.Int) Policy {
+	return Func(func(r *Request) error {
+		min, ok := prices[r.Type]
+		if !ok {
+			min = def
+		}
+		if min == nil {
+			return deny("price", "no price for credential type %q", r.Type)
+		} else if r.Price.Cmp(min) < 0 {
+			return deny("price", "price %v below minimum %v", r.Price, min)
+		}
+		return nil
+	})
+}
+
+// PeerAllowlist accepts requests from the given peers only.
+func PeerAllowlist(peers ...wallet.Address) Policy {
+	return Func(func(r *Request) error {
+		for _, p := range peers {
+			if p.Equals(r.Peer) {
+				return nil
+			}
+		}
+		return deny("peer", "%v not allowed", r.Peer)
+	})
+}
+
+// DailyLimit accepts at most `limit` requests per peer and calendar day
+// (UTC). Only requests accepted by the limit are counted, so it should be the
+// last policy in a chain.
+func DailyLimit(limit int) Policy {
+	return &dailyLimit{
+		limit:  limit,
+		counts: make(map[string]int),
+	}
+}
+
+type dailyLimit struct {
+	mu     sync.Mutex
+	limit  int
+	day    string
+	counts map[string]int
+}
+
+func (l *dailyLimit) Evaluate(r *Request) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if day := r.Time.UTC().Format("2006-01-02"); day != l.day {
+		l.day = day
+		l.counts = make(map[string]int)
+	}
+
+	peer := r.Peer.String()
+	if l.counts[peer] >= l.limit {
+		return deny("limit", "daily limit of %d reached", l.limit)
+	}
+	l.counts[peer]++
+	return nil
+}
@@ -0,0 +1,51 @@
+package policy_test
+
+import (
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client/policy"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+var (
+	alice = ethwallet.AsWalletAddr(common.Address{1})
+	bob   = ethwallet.AsWalletAddr(common.Address{2})
+	day   = time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func TestAll(t *testing.T) {
+	p := policy.All(
+		policy.DocumentMatches(regexp.MustCompile("^ok")),
+		policy.PeerAllowlist(alice),
+	)
+	require.NoError(t, p.Evaluate(&policy.Request{Peer: alice, Document: []byte("ok")}))
+	require.ErrorIs(t, p.Evaluate(&policy.Request{Peer: alice, Document: []byte("no")}), policy.ErrDenied)
+	err := p.Evaluate(&policy.Request{Peer: bob, Document: []byte("ok")})
+	var denied *policy.DeniedError
+	require.ErrorAs(t, err, &denied)
+	require.Equal(t, "peer", denied.Rule)
+}
+
+func TestMinPrice(t *testing.T) {
+	p := policy.MinPrice(map[string]*big.Int{"diploma": big.NewInt(10)}, nil)
+	require.NoError(t, p.Evaluate(&policy.Request{Type: "diploma", Price: big.NewInt(10)}))
+	require.ErrorIs(t, p.Evaluate(&policy.Request{Type: "diploma", Price: big.NewInt(9)}), policy.ErrDenied)
+	require.ErrorIs(t, p.Evaluate(&policy.Request{Type: "other", Price: big.NewInt(100)}), policy.ErrDenied, "type without price")
+
+	withDefault := policy.MinPrice(map[string]*big.Int{"diploma": big.NewInt(10)}, big.NewInt(1))
+	require.NoError(t, withDefault.Evaluate(&policy.Request{Type: "other", Price: big.NewInt(1)}))
+}
+
+func TestDailyLimit(t *testing.T) {
+	p := policy.DailyLimit(1)
+	require.NoError(t, p.Evaluate(&policy.Request{Peer: alice, Time: day}))
+	require.ErrorIs(t, p.Evaluate(&policy.Request{Peer: alice, Time: day.Add(time.Hour)}), policy.ErrDenied)
+	require.NoError(t, p.Evaluate(&policy.Request{Peer: bob, Time: day.Add(time.Hour)}), "other peer")
+	// The limit is reset on the next day of the request time.
+	require.NoError(t, p.Evaluate(&policy.Request{Peer: alice, Time: day.Add(12 * time.Hour)}))
+}
@@ -0,0 +1,153 @@
+package policy
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// bpsDenominator is the denominator of rates given in basis points.
+const bpsDenominator = 10000
+
+// PricingStrategy determines the price that the issuer asks for a request.
+// The returned price may be modified by the caller.
+type PricingStrategy interface {
+	Price(r *Request) (*big.Int, error)
+}
+
+// PricingFunc is a PricingStrategy implemented by a function.
+type PricingFunc func(r *Request) (*big.Int, error)
+
+func (f PricingFunc) Price(r *Request) (*big.Int, error) {
+	return f(r)
+}
+
+// CounterError is returned when a request offers less than the asked price.
+// The issuer may counter the request with Price.
+type CounterError struct {
+	DeniedError
+	Price *big.Int
+}
+
+// Priced accepts requests that offer at least the price asked by strategy
+// `s`. Requests offering less are denied with a CounterError.
+func Priced(s PricingStrategy) Policy {
+	return Func(func(r *Request) error {
+		price, err := s.Price(r)
+		if err != nil {
+			return deny("price", "%v", err)
+		}
+		if r.Price.Cmp(price) < 0 {
+			return &CounterError{
+				DeniedError: DeniedError{Rule: "price", Reason: fmt.Sprintf("price %v below asked price %v", r.Price, price)},
+				Price:       price,
+			}
+		}
+		return nil
+	})
+}
+
+// FixedPrice asks the price configured for the credential type. Requests for
+// types without a price are asked `def`, if not nil, and fail otherwise.
+func FixedPrice(prices map[string]*big.Int, def *big.Int) PricingStrategy {
+	return PricingFunc(func(r *Request) (*big.Int, error) {
+		price, ok := prices[r.Type]
+		if !ok {
+			price = def
+		}
+		if price == nil {
+			return nil, fmt.Errorf("no price for credential type %q", r.Type)
+		}
+		return new(big.Int).Set(price), nil
+	})
+}
+
+// TimeDecay asks a price that decreases linearly from `start` at time `from`
+// to `floor` after duration `d`, as in a dutch auction. Before `from`, the
+// price is `start`, and after the decay, it stays at `floor`.
+func TimeDecay(start, floor *big.Int, from time.Time, d time.Duration) PricingStrategy {
+	return PricingFunc(func(r *Request) (*big.Int, error) {
+		elapsed := r.Time.Sub(from)
+		if elapsed <= 0 {
+			return new(big.Int).Set(start), nil
+		} else if elapsed >= d {
+			return new(big.Int).Set(floor), nil
+		}
+		// price = start - (start - floor) * elapsed / d
+		decay := new(big.Int).Sub(start, floor)
+		decay.Mul(decay, big.NewInt(int64(elapsed)))
+		decay.Div(decay, big.NewInt(int64(d)))
+		return decay.Sub(start, decay), nil
+	})
+}
+
+// Surge raises the price asked by `base` with demand. For every request
+// beyond `threshold` within the sliding `window`, the price increases by
+// `rate` basis points. Every priced request counts as demand.
+func Surge(base PricingStrategy, window time.Duration, threshold int, rate uint16) PricingStrategy {
+	return &surge{
+		base:      base,
+		window:    window,
+		threshold: threshold,
+		rate:      rate,
+	}
+}
+
+type surge struct {
+	mu        sync.Mutex
+	base      PricingStrategy
+	window    time.Duration
+	threshold int
+	rate      uint16
+	requests  []time.Time
+}
+
+func (s *surge) Price(r *Request) (*big.Int, error) {
+	price, err := s.base.Price(r)
+	if err != nil {
+		return nil, err
+	}
+	excess := s.record(r.Time) - s.threshold
+	if excess <= 0 {
+		return price, nil
+	}
+
+	factor := big.NewInt(int64(bpsDenominator) + int64(excess)*int64(s.rate))
+	price.Mul(price, factor)
+	return price.Div(price, big.NewInt(bpsDenominator)), nil
+}
+
+// record records a request at `now` and returns the number of requests
+// within the window.
+func (s *surge) record(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := now.Add(-s.window)
+	i := 0
+	for i < len(s.requests) && !s.requests[i].After(cutoff) {
+		i++
+	}
+	s.requests = append(s.requests[i:], now)
+	return len(s.requests)
+}
+
+// HolderDiscount grants the holders in `discounts`, keyed by their address,
+// a discount in basis points on the price asked by `base`.
+func HolderDiscount(base PricingStrategy, discounts map[string]uint16) PricingStrategy {
+	return PricingFunc(func(r *Request) (*big.Int, error) {
+		price, err := base.Price(r)
+		if err != nil {
+			return nil, err
+		}
+		discount, ok := discounts[r.Peer.String()]
+		if !ok {
+			return price, nil
+		} else if discount > bpsDenominator {
+			discount = bpsDenominator
+		}
+		price.Mul(price, big.NewInt(int64(bpsDenominator-discount)))
+		return price.Div(price, big.NewInt(bpsDenominator)), nil
+	})
+}
@@ -0,0 +1,57 @@
+package policy_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/client/policy"
+	"github.com/stretchr/testify/require"
+)
+
+// requirePrice checks that `s` asks `price` for request `r`.
+func requirePrice(t *testing.T, s policy.PricingStrategy, r *policy.Request, price int64) {
+	t.Helper()
+	p, err := s.Price(r)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(price), p)
+}
+
+func TestFixedPrice(t *testing.T) {
+	s := policy.FixedPrice(map[string]*big.Int{"diploma": big.NewInt(10)}, nil)
+	requirePrice(t, s, &policy.Request{Type: "diploma"}, 10)
+	_, err := s.Price(&policy.Request{Type: "other"})
+	require.Error(t, err)
+}
+
+func TestPriced(t *testing.T) {
+	p := policy.Priced(policy.FixedPrice(map[string]*big.Int{"diploma": big.NewInt(10)}, nil))
+	require.NoError(t, p.Evaluate(&policy.Request{Type: "diploma", Price: big.NewInt(10)}))
+
+	var counter *policy.CounterError
+	require.ErrorAs(t, p.Evaluate(&policy.Request{Type: "diploma", Price: big.NewInt(5)}), &counter)
+	require.Equal(t, big.NewInt(10), counter.Price)
+}
+
+func TestTimeDecay(t *testing.T) {
+	s := policy.TimeDecay(big.NewInt(100), big.NewInt(20), day, 10*time.Second)
+	requirePrice(t, s, &policy.Request{Time: day.Add(-time.Second)}, 100)
+	requirePrice(t, s, &policy.Request{Time: day.Add(5 * time.Second)}, 60)
+	requirePrice(t, s, &policy.Request{Time: day.Add(time.Minute)}, 20)
+}
+
+func TestSurge(t *testing.T) {
+	base := policy.FixedPrice(nil, big.NewInt(100))
+	s := policy.Surge(base, time.Minute, 1, 1000)
+	requirePrice(t, s, &policy.Request{Time: day}, 100)
+	requirePrice(t, s, &policy.Request{Time: day.Add(time.Second)}, 110)
+	requirePrice(t, s, &policy.Request{Time: day.Add(2 * time.Second)}, 120)
+	// Requests outside of the window no longer count.
+	requirePrice(t, s, &policy.Request{Time: day.Add(2 * time.Minute)}, 100)
+}
+
+func TestHolderDiscount(t *testing.T) {
+	s := policy.HolderDiscount(policy.FixedPrice(nil, big.NewInt(100)), map[string]uint16{alice.String(): 2500})
+	requirePrice(t, s, &policy.Request{Peer: alice}, 75)
+	requirePrice(t, s, &policy.Request{Peer: bob}, 100)
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"perun.network/go-perun/backend/ethereum/bindings/adjudicator"
+	"perun.network/go-perun/channel"
+)
+
+// phaseConcluded is the dispute phase of concluded channels in the
+// adjudicator contract.
+const phaseConcluded = 2
+
+// adjudicatorDisputes reads the disputes recorded by the adjudicator
+// contract.
+type adjudicatorDisputes struct {
+	caller *adjudicator.AdjudicatorCaller
+}
+
+func (a *adjudicatorDisputes) Dispute(ctx context.Context, id channel.ID) (connection.OnChainDispute, error) {
+	d, err := a.caller.Disputes(&bind.CallOpts{Context: ctx}, id)
+	if err != nil {
+		return connection.OnChainDispute{}, err
+	}
+	return connection.OnChainDispute{
+		Registered: d.Timeout != 0,
+		Version:    d.Version,
+		Concluded:  d.Phase == phaseConcluded,
+	}, nil
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	pkgapp "github.com/perun-network/perun-credential-payment/app"
+)
+
+const revokeGasLimit = 100000
+
+var ErrNoRevocationRegistry = errors.New("no revocation registry configured")
+
+// Revoke revokes credential `id` issued by the client. The ID of a credential
+// is given by app.Credential.ID.
+func (c *Client) Revoke(ctx context.Context, id pkgapp.Hash) error {
+	if c.revocations == nil {
+		return ErrNoRevocationRegistry
+	}
+
+	cb := &c.perunClient.ContractBackend
+	acc := c.txAccount()
+	opts, err := cb.NewTransactor(ctx, revokeGasLimit, acc)
+	if err != nil {
+		return fmt.Errorf("creating transactor: %w", err)
+	}
+
+	tx, err := c.revocations.Revoke(opts, id)
+	if err != nil {
+		return fmt.Errorf("sending revocation: %w", err)
+	}
+	if _, err := cb.ConfirmTransaction(ctx, tx, acc); err != nil {
+		return fmt.Errorf("confirming revocation: %w", err)
+	}
+	return nil
+}
+
+// CheckRevocation returns whether credential `id` was revoked by `issuer`.
+func (c *Client) CheckRevocation(ctx context.Context, issuer common.Address, id pkgapp.Hash) (revoked bool, err error) {
+	if c.revocations == nil {
+		return false, ErrNoRevocationRegistry
+	}
+
+	t, err := c.revocations.RevokedAt(&bind.CallOpts{Context: ctx}, issuer, id)
+	if err != nil {
+		return false, fmt.Errorf("querying revocation registry: %w", err)
+	}
+	return t.Sign() != 0, nil
+}
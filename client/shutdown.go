@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"perun.network/go-perun/channel"
+)
+
+// ShutdownError lists the channels that GracefulShutdown left open, with the reason
+// why they could not be closed.
+type ShutdownError struct {
+	Channels map[channel.ID]error
+}
+
+func (e *ShutdownError) Error() string {
+	msgs := make([]string, 0, len(e.Channels))
+	for id, err := range e.Channels {
+		msgs = append(msgs, fmt.Sprintf("channel %x: %v", id, err))
+	}
+	sort.Strings(msgs)
+	return fmt.Sprintf("%d channels left open: %s", len(e.Channels), strings.Join(msgs, "; "))
+}
+
+// GracefulShutdown shuts the client down gracefully. New channel proposals
+// and credential requests are rejected with the code shutting_down. It then waits until the requests in progress are answered and closes the
+// channels, which settles them on-chain. Channels whose requests are still in
+// progress when `ctx` is done are left open, and their latest states remain
+// in the Store, if configured. Finally, the client is shut down as by Shutdown.
+// If channels are left open, a *ShutdownError is returned.
+func (c *Client) GracefulShutdown(ctx context.Context) error {
+	c.shuttingDown.SetValue(true)
+	defer c.Shutdown()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		open = make(map[channel.ID]error)
+	)
+	for _, conn := range c.connections.All() {
+		if conn.Inspect().Concluded {
+			continue
+		}
+		wg.Add(1)
+		go func(conn *connection.Connection) {
+			defer wg.Done()
+			if err := c.drain(ctx, conn); err != nil {
+				c.log.WithField("channel", channelSubject(conn.ID())).Warnf("Leaving channel open: %v", err)
+				mu.Lock()
+				open[conn.ID()] = err
+				mu.Unlock()
+			}
+		}(conn)
+	}
+	wg.Wait()
+
+	if len(open) > 0 {
+		return &ShutdownError{Channels: open}
+	}
+	return nil
+}
+
+// drain waits until the requests in progress in `conn` are answered and
+// closes it.
+func (c *Client) drain(ctx context.Context, conn *connection.Connection) error {
+	if err := conn.Drain(ctx); err != nil {
+		return err
+	}
+	return conn.Close(ctx)
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// Keys and prefixes of an encrypted database. The salt and the check value
+// are stored in plain, the data in encrypted form.
+const (
+	saltKey    = "crypt/salt"
+	checkKey   = "crypt/check"
+	dataPrefix = "data/"
+)
+
+// Parameters of the key derivation.
+const (
+	scryptN   = 1 << 15
+	scryptR   = 8
+	scryptP   = 1
+	saltLen   = 32
+	keyLength = 32
+)
+
+// checkValue is encrypted under the key to detect wrong passphrases.
+var checkValue = []byte("perun-credential-payment store")
+
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// NewEncrypted returns a store on `db` whose values are encrypted with a key
+// derived from `passphrase`. See Encrypt.
+func NewEncrypted(db sortedkv.Database, passphrase []byte) (*Store, error) {
+	edb, err := Encrypt(db, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return New(edb), nil
+}
+
+// Encrypt returns a view of `db` that encrypts its values with AES-GCM under
+// a key derived from `passphrase` with scrypt. The salt of the key is created
+// when the database is encrypted first. Afterwards, the database can only be
+// opened with the same passphrase. Keys are not encrypted; the keys of a
+// store only contain channel IDs and versions. Closing the view closes `db`.
+func Encrypt(db sortedkv.Database, passphrase []byte) (sortedkv.Database, error) {
+	salt, err := loadSalt(db)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	e := &encryptedDB{Database: sortedkv.NewTable(db, dataPrefix), aead: aead, root: db}
+	return e, e.check(db)
+}
+
+// loadSalt returns the salt stored in `db`, or stores a new one.
+func loadSalt(db sortedkv.Database) ([]byte, error) {
+	if ok, err := db.Has(saltKey); err != nil {
+		return nil, err
+	} else if ok {
+		return db.GetBytes(saltKey)
+	}
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	return salt, db.PutBytes(saltKey, salt)
+}
+
+// encryptedDB encrypts the values of the embedded database. The embedded
+// database is a table of `root`, which also holds the salt and check value.
+type encryptedDB struct {
+	sortedkv.Database
+	aead cipher.AEAD
+	root sortedkv.Database
+}
+
+// check checks the check value stored in `db`, or stores it.
+func (e *encryptedDB) check(db sortedkv.Database) error {
+	if ok, err := db.Has(checkKey); err != nil {
+		return err
+	} else if !ok {
+		return db.PutBytes(checkKey, e.seal(checkKey, checkValue))
+	}
+	b, err := db.GetBytes(checkKey)
+	if err != nil {
+		return err
+	}
+	v, err := e.open(checkKey, b)
+	if err != nil || subtle.ConstantTimeCompare(v, checkValue) != 1 {
+		return ErrWrongPassphrase
+	}
+	return nil
+}
+
+// seal encrypts `value` of `key`. The key is authenticated, so that values
+// cannot be swapped between keys.
+func (e *encryptedDB) seal(key string, value []byte) []byte {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(value)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("generating nonce: %v", err))
+	}
+	return e.aead.Seal(nonce, nonce, value, []byte(key))
+}
+
+func (e *encryptedDB) open(key string, b []byte) ([]byte, error) {
+	n := e.aead.NonceSize()
+	if len(b) < n {
+		return nil, fmt.Errorf("decrypting %s: ciphertext too short", key)
+	}
+	v, err := e.aead.Open(nil, b[:n], b[n:], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", key, err)
+	}
+	return v, nil
+}
+
+func (e *encryptedDB) Get(key string) (string, error) {
+	v, err := e.GetBytes(key)
+	return string(v), err
+}
+
+func (e *encryptedDB) GetBytes(key string) ([]byte, error) {
+	b, err := e.Database.GetBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	return e.open(key, b)
+}
+
+func (e *encryptedDB) Put(key, value string) error {
+	return e.PutBytes(key, []byte(value))
+}
+
+func (e *encryptedDB) PutBytes(key string, value []byte) error {
+	return e.Database.PutBytes(key, e.seal(key, value))
+}
+
+func (e *encryptedDB) NewBatch() sortedkv.Batch {
+	return &encryptedBatch{Batch: e.Database.NewBatch(), db: e}
+}
+
+func (e *encryptedDB) NewIterator() sortedkv.Iterator {
+	return &encryptedIterator{Iterator: e.Database.NewIterator(), db: e}
+}
+
+func (e *encryptedDB) NewIteratorWithRange(start, end string) sortedkv.Iterator {
+	return &encryptedIterator{Iterator: e.Database.NewIteratorWithRange(start, end), db: e}
+}
+
+func (e *encryptedDB) NewIteratorWithPrefix(prefix string) sortedkv.Iterator {
+	return &encryptedIterator{Iterator: e.Database.NewIteratorWithPrefix(prefix), db: e}
+}
+
+func (e *encryptedDB) Close() error {
+	return e.root.Close()
+}
+
+type encryptedBatch struct {
+	sortedkv.Batch
+	db *encryptedDB
+}
+
+func (b *encryptedBatch) Put(key, value string) error {
+	return b.PutBytes(key, []byte(value))
+}
+
+func (b *encryptedBatch) PutBytes(key string, value []byte) error {
+	return b.Batch.PutBytes(key, b.db.seal(key, value))
+}
+
+// encryptedIterator decrypts the values of the embedded iterator. It stops at
+// the first value that cannot be decrypted and returns the error on Close.
+type encryptedIterator struct {
+	sortedkv.Iterator
+	db    *encryptedDB
+	value []byte
+	err   error
+}
+
+func (it *encryptedIterator) Next() bool {
+	it.value = nil
+	if it.err != nil || !it.Iterator.Next() {
+		return false
+	}
+	it.value, it.err = it.db.open(it.Iterator.Key(), it.Iterator.ValueBytes())
+	return it.err == nil
+}
+
+func (it *encryptedIterator) Value() string {
+	return string(it.value)
+}
+
+func (it *encryptedIterator) ValueBytes() []byte {
+	return it.value
+}
+
+func (it *encryptedIterator) Close() error {
+	if err := it.Iterator.Close(); err != nil {
+		return err
+	}
+	return it.err
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	db := memorydb.NewDatabase()
+	edb, err := Encrypt(db, []byte("passphrase"))
+	require.NoError(t, err)
+	require.NoError(t, edb.Put("a", "secret credential"))
+	b := edb.NewBatch()
+	require.NoError(t, b.PutBytes("b", []byte("batched")))
+	require.NoError(t, b.Apply())
+
+	// The values are stored encrypted.
+	raw, err := db.Get(dataPrefix + "a")
+	require.NoError(t, err)
+	require.NotContains(t, raw, "secret")
+
+	// Reopening with the same passphrase decrypts them.
+	edb, err = Encrypt(db, []byte("passphrase"))
+	require.NoError(t, err)
+	v, err := edb.Get("a")
+	require.NoError(t, err)
+	require.Equal(t, "secret credential", v)
+
+	it := edb.NewIterator()
+	var values []string
+	for it.Next() {
+		values = append(values, it.Key()+"="+it.Value())
+	}
+	require.NoError(t, it.Close())
+	require.Equal(t, []string{"a=secret credential", "b=batched"}, values)
+}
+
+func TestEncryptWrongPassphrase(t *testing.T) {
+	db := memorydb.NewDatabase()
+	_, err := Encrypt(db, []byte("passphrase"))
+	require.NoError(t, err)
+	_, err = Encrypt(db, []byte("wrong"))
+	require.ErrorIs(t, err, ErrWrongPassphrase)
+}
+
+func TestEncryptTampered(t *testing.T) {
+	db := memorydb.NewDatabase()
+	edb, err := Encrypt(db, []byte("passphrase"))
+	require.NoError(t, err)
+	require.NoError(t, edb.Put("a", "value a"))
+	require.NoError(t, edb.Put("b", "value b"))
+
+	// Flipping a bit of the ciphertext is detected.
+	raw, err := db.GetBytes(dataPrefix + "a")
+	require.NoError(t, err)
+	raw[len(raw)-1] ^= 1
+	require.NoError(t, db.PutBytes(dataPrefix+"a", raw))
+	_, err = edb.Get("a")
+	require.Error(t, err)
+
+	it := edb.NewIterator()
+	require.False(t, it.Next(), "iterator stops at tampered value")
+	require.Error(t, it.Close())
+
+	// Values cannot be moved between keys.
+	rawB, err := db.GetBytes(dataPrefix + "b")
+	require.NoError(t, err)
+	require.NoError(t, db.PutBytes(dataPrefix+"a", rawB))
+	_, err = edb.Get("a")
+	require.Error(t, err)
+
+	// Truncated ciphertexts are rejected.
+	require.NoError(t, db.PutBytes(dataPrefix+"a", rawB[:4]))
+	_, err = edb.Get("a")
+	require.Error(t, err)
+}
+
+func TestNewEncryptedStore(t *testing.T) {
+	db := memorydb.NewDatabase()
+	s, err := NewEncrypted(db, []byte("passphrase"))
+	require.NoError(t, err)
+	update(t, s, [32]byte{1}, 1, offer)
+
+	s, err = NewEncrypted(db, []byte("passphrase"))
+	require.NoError(t, err)
+	req, err := s.Request([32]byte{1})
+	require.NoError(t, err)
+	require.True(t, offer.Equal(req.Offer))
+
+	_, err = NewEncrypted(db, []byte("wrong"))
+	require.ErrorIs(t, err, ErrWrongPassphrase)
+}
@@ -0,0 +1,35 @@
+//go:build leveldb
+// +build leveldb
+
+package store
+
+import (
+	"fmt"
+
+	"perun.network/go-perun/pkg/sortedkv/leveldb"
+)
+
+// OpenLevelDB opens the store in the LevelDB database at `path`, which is
+// created if it does not exist.
+func OpenLevelDB(path string) (*Store, error) {
+	db, err := leveldb.LoadDatabase(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading database: %w", err)
+	}
+	return New(db), nil
+}
+
+// OpenEncryptedLevelDB opens the store in the LevelDB database at `path`,
+// encrypted with a key derived from `passphrase`. See Encrypt.
+func OpenEncryptedLevelDB(path string, passphrase []byte) (*Store, error) {
+	db, err := leveldb.LoadDatabase(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading database: %w", err)
+	}
+	s, err := NewEncrypted(db, passphrase)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
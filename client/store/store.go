@@ -0,0 +1,367 @@
+// Package store persists the state of a client in a sorted key-value
+// database: the channel states, the pending credential requests, and the
+// credentials issued in the channels. It is the persister of the Perun
+// client, so that channels can be restored after a restart, and keeps the
+// issued credentials for audits.
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	"perun.network/go-perun/wire"
+)
+
+// Prefixes of the tables in the database.
+const (
+	channelsPrefix    = "channels/"
+	requestsPrefix    = "requests/"
+	credentialsPrefix = "credentials/"
+)
+
+var ErrUnexpectedData = errors.New("unexpected data")
+
+// Request is a pending credential request, that is, an offer, batch offer, or
+// counter-offer in a channel that has not been answered yet. Exactly one of
+// Offer, BatchOffer, and CounterOffer is set.
+type Request struct {
+	Channel      channel.ID
+	Version      uint64
+	Offer        *data.Offer
+	BatchOffer   *data.BatchOffer
+	CounterOffer *data.CounterOffer
+}
+
+// Credential is a credential issued in a channel, or the credentials issued
+// for a batch offer. Either Offer and Cert, or BatchOffer and BatchCert are
+// set.
+type Credential struct {
+	Channel    channel.ID
+	Version    uint64 // The version of the state containing the certificate.
+	Offer      *data.Offer
+	Cert       *data.Cert
+	BatchOffer *data.BatchOffer
+	BatchCert  *data.BatchCert
+}
+
+// Store is a persistence.PersistRestorer that also keeps the pending
+// requests and issued credentials of the channels.
+type Store struct {
+	*keyvalue.PersistRestorer
+	db          sortedkv.Database
+	requests    sortedkv.Database
+	credentials sortedkv.Database
+}
+
+var _ persistence.PersistRestorer = (*Store)(nil)
+
+// New returns a store on `db`. The store takes ownership of `db`, which is
+// closed by Close.
+func New(db sortedkv.Database) *Store {
+	return &Store{
+		PersistRestorer: keyvalue.NewPersistRestorer(sortedkv.NewTable(db, channelsPrefix)),
+		db:              db,
+		requests:        sortedkv.NewTable(db, requestsPrefix),
+		credentials:     sortedkv.NewTable(db, credentialsPrefix),
+	}
+}
+
+// NewMemory returns a store that keeps its data in memory. It does not
+// survive restarts and is meant for testing.
+func NewMemory() *Store {
+	return New(memorydb.NewDatabase())
+}
+
+// ChannelCreated persists a new channel.
+func (s *Store) ChannelCreated(ctx context.Context, src channel.Source, peers []wire.Address, parent *channel.ID) error {
+	if err := s.PersistRestorer.ChannelCreated(ctx, src, peers, parent); err != nil {
+		return err
+	}
+	return s.track(src)
+}
+
+// Enabled persists a new fully signed state of a channel. An offer, batch
+// offer, or counter-offer in the state is recorded as pending request. A
+// certificate answering the pending request is recorded as issued
+// credential.
+func (s *Store) Enabled(ctx context.Context, src channel.Source) error {
+	if err := s.PersistRestorer.Enabled(ctx, src); err != nil {
+		return err
+	}
+	return s.track(src)
+}
+
+// ChannelRemoved removes a settled channel and its pending request. The
+// issued credentials are kept.
+func (s *Store) ChannelRemoved(ctx context.Context, id channel.ID) error {
+	if err := s.PersistRestorer.ChannelRemoved(ctx, id); err != nil {
+		return err
+	}
+	return s.deleteRequest(id)
+}
+
+// track updates the pending request and the issued credentials with the
+// current state of `src`.
+func (s *Store) track(src channel.Source) error {
+	st := src.CurrentTX().State
+	if st == nil {
+		return nil
+	}
+	switch d := st.Data.(type) {
+	case *data.Offer:
+		return s.putRequest(&Request{Channel: st.ID, Version: st.Version, Offer: d})
+	case *data.BatchOffer:
+		return s.putRequest(&Request{Channel: st.ID, Version: st.Version, BatchOffer: d})
+	case *data.CounterOffer:
+		return s.putRequest(&Request{Channel: st.ID, Version: st.Version, CounterOffer: d})
+	case *data.Cert, *data.BatchCert:
+		req, err := s.Request(st.ID)
+		if err != nil {
+			return err
+		} else if req != nil {
+			cred, err := req.credential(st.Version, d)
+			if err != nil {
+				return err
+			} else if err := s.putCredential(cred); err != nil {
+				return err
+			}
+		}
+	}
+	return s.deleteRequest(st.ID)
+}
+
+func (s *Store) putRequest(r *Request) error {
+	b, err := encodeRecord(r.Version, r.data())
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+	return s.requests.PutBytes(channelKey(r.Channel), b)
+}
+
+func (s *Store) deleteRequest(id channel.ID) error {
+	key := channelKey(id)
+	if ok, err := s.requests.Has(key); err != nil || !ok {
+		return err
+	}
+	return s.requests.Delete(key)
+}
+
+// Request returns the pending request of channel `id`, or nil if there is
+// none.
+func (s *Store) Request(id channel.ID) (*Request, error) {
+	key := channelKey(id)
+	if ok, err := s.requests.Has(key); err != nil || !ok {
+		return nil, err
+	}
+	b, err := s.requests.GetBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRequest(id, b)
+}
+
+// Requests returns the pending requests of all channels.
+func (s *Store) Requests() ([]*Request, error) {
+	var reqs []*Request
+	it := s.requests.NewIterator()
+	for it.Next() {
+		id, err := parseChannelKey(it.Key())
+		if err != nil {
+			return nil, err
+		}
+		r, err := decodeRequest(id, []byte(it.Value()))
+		if err != nil {
+			return nil, fmt.Errorf("decoding request of channel %x: %w", id, err)
+		}
+		reqs = append(reqs, r)
+	}
+	return reqs, it.Close()
+}
+
+func (s *Store) putCredential(c *Credential) error {
+	var b []byte
+	var err error
+	if c.BatchOffer != nil {
+		b, err = encodeRecord(c.Version, c.BatchOffer, c.BatchCert)
+	} else {
+		b, err = encodeRecord(c.Version, c.Offer, c.Cert)
+	}
+	if err != nil {
+		return fmt.Errorf("encoding credential: %w", err)
+	}
+	return s.credentials.PutBytes(credentialKey(c.Channel, c.Version), b)
+}
+
+// Credentials returns the credentials issued in channel `id`, ordered by
+// version.
+func (s *Store) Credentials(id channel.ID) ([]*Credential, error) {
+	return s.iterateCredentials(channelKey(id) + ":")
+}
+
+// AllCredentials returns the credentials issued in all channels.
+func (s *Store) AllCredentials() ([]*Credential, error) {
+	return s.iterateCredentials("")
+}
+
+func (s *Store) iterateCredentials(prefix string) ([]*Credential, error) {
+	var creds []*Credential
+	it := s.credentials.NewIteratorWithPrefix(prefix)
+	for it.Next() {
+		c, err := decodeCredential(it.Key(), []byte(it.Value()))
+		if err != nil {
+			return nil, fmt.Errorf("decoding credential %s: %w", it.Key(), err)
+		}
+		creds = append(creds, c)
+	}
+	return creds, it.Close()
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func channelKey(id channel.ID) string {
+	return hexutil.Encode(id[:])
+}
+
+func parseChannelKey(key string) (id channel.ID, err error) {
+	b, err := hexutil.Decode(key)
+	if err != nil {
+		return id, fmt.Errorf("parsing channel ID: %w", err)
+	} else if len(b) != len(id) {
+		return id, fmt.Errorf("parsing channel ID: wrong length %d", len(b))
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// credentialKey returns the key of the credential issued in version
+// `version` of channel `id`. The version is padded, so that the credentials
+// of a channel are sorted by version.
+func credentialKey(id channel.ID, version uint64) string {
+	return fmt.Sprintf("%s:%020d", channelKey(id), version)
+}
+
+// encodeRecord encodes `version` followed by the encoding of `ds`.
+func encodeRecord(version uint64, ds ...channel.Data) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.BigEndian, version); err != nil {
+		return nil, err
+	}
+	for _, d := range ds {
+		if err := d.Encode(&buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeRecord decodes a record encoded by encodeRecord with `n` data
+// values.
+func decodeRecord(b []byte, n int) (uint64, []channel.Data, error) {
+	r := bytes.NewReader(b)
+	var version uint64
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return 0, nil, err
+	}
+	ds := make([]channel.Data, n)
+	for i := range ds {
+		d, err := data.Decode(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		ds[i] = d
+	}
+	return version, ds, nil
+}
+
+// data returns the offer of the request.
+func (r *Request) data() channel.Data {
+	switch {
+	case r.BatchOffer != nil:
+		return r.BatchOffer
+	case r.CounterOffer != nil:
+		return r.CounterOffer
+	default:
+		return r.Offer
+	}
+}
+
+// credential returns the credential issued with certificate `cert` in
+// version `version` in response to the request.
+func (r *Request) credential(version uint64, cert channel.Data) (*Credential, error) {
+	c := &Credential{Channel: r.Channel, Version: version}
+	switch cert := cert.(type) {
+	case *data.Cert:
+		c.Offer, c.Cert = r.Offer, cert
+	case *data.BatchCert:
+		c.BatchOffer, c.BatchCert = r.BatchOffer, cert
+	}
+	if (c.Offer == nil) == (c.BatchOffer == nil) {
+		return nil, fmt.Errorf("%w: %T answering %T", ErrUnexpectedData, cert, r.data())
+	}
+	return c, nil
+}
+
+func decodeRequest(id channel.ID, b []byte) (*Request, error) {
+	version, ds, err := decodeRecord(b, 1)
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{Channel: id, Version: version}
+	switch d := ds[0].(type) {
+	case *data.Offer:
+		r.Offer = d
+	case *data.BatchOffer:
+		r.BatchOffer = d
+	case *data.CounterOffer:
+		r.CounterOffer = d
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnexpectedData, ds[0])
+	}
+	return r, nil
+}
+
+func decodeCredential(key string, b []byte) (*Credential, error) {
+	i := strings.IndexByte(key, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid key")
+	}
+	id, err := parseChannelKey(key[:i])
+	if err != nil {
+		return nil, err
+	}
+	version, ds, err := decodeRecord(b, 2)
+	if err != nil {
+		return nil, err
+	}
+	c := &Credential{Channel: id, Version: version}
+	var ok bool
+	switch offer := ds[0].(type) {
+	case *data.Offer:
+		c.Offer = offer
+		if c.Cert, ok = ds[1].(*data.Cert); !ok {
+			return nil, fmt.Errorf("%w: %T", ErrUnexpectedData, ds[1])
+		}
+	case *data.BatchOffer:
+		c.BatchOffer = offer
+		if c.BatchCert, ok = ds[1].(*data.BatchCert); !ok {
+			return nil, fmt.Errorf("%w: %T", ErrUnexpectedData, ds[1])
+		}
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnexpectedData, ds[0])
+	}
+	return c, nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+)
+
+// source is a channel source that only has a current state.
+type source struct {
+	channel.Source
+	state *channel.State
+}
+
+func (s *source) CurrentTX() channel.Transaction {
+	return channel.Transaction{State: s.state}
+}
+
+// update tracks the state of channel `id` with version `version` and data
+// `d`.
+func update(t *testing.T, s *Store, id channel.ID, version uint64, d channel.Data) {
+	t.Helper()
+	require.NoError(t, s.track(&source{state: &channel.State{ID: id, Version: version, Data: d}}))
+}
+
+var (
+	issuer = common.HexToAddress("0x5eb3bc0a489c5a8288765d2336659ebca68fcd00")
+	offer  = &data.Offer{Issuer: issuer, DataHash: [32]byte{1}, Price: big.NewInt(10), Nonce: 1}
+	batch  = &data.BatchOffer{Issuer: issuer, DataHashes: [][32]byte{{2}, {3}}, Price: big.NewInt(20), Nonce: 3}
+)
+
+func TestCredential(t *testing.T) {
+	s := NewMemory()
+	id := channel.ID{1}
+	update(t, s, id, 1, offer)
+	req, err := s.Request(id)
+	require.NoError(t, err)
+	require.True(t, offer.Equal(req.Offer))
+
+	cert := &data.Cert{Signature: [data.SigLen]byte{4}}
+	update(t, s, id, 2, cert)
+	req, err = s.Request(id)
+	require.NoError(t, err)
+	require.Nil(t, req, "request answered")
+
+	creds, err := s.Credentials(id)
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	require.Equal(t, uint64(2), creds[0].Version)
+	require.True(t, offer.Equal(creds[0].Offer))
+	require.Equal(t, cert.Signature, creds[0].Cert.Signature)
+	require.Nil(t, creds[0].BatchOffer)
+}
+
+func TestBatchCredentials(t *testing.T) {
+	s := NewMemory()
+	id := channel.ID{2}
+	update(t, s, id, 3, batch)
+	reqs, err := s.Requests()
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	require.True(t, batch.Equal(reqs[0].BatchOffer))
+	require.Nil(t, reqs[0].Offer)
+
+	cert := &data.BatchCert{Signatures: [][data.SigLen]byte{{5}, {6}}}
+	update(t, s, id, 4, cert)
+	creds, err := s.AllCredentials()
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	require.True(t, batch.Equal(creds[0].BatchOffer))
+	require.Equal(t, cert.Signatures, creds[0].BatchCert.Signatures)
+	require.Nil(t, creds[0].Offer)
+}
+
+func TestCounterOffer(t *testing.T) {
+	s := NewMemory()
+	id := channel.ID{3}
+	update(t, s, id, 1, offer)
+	counter := &data.CounterOffer{Offer: *offer}
+	counter.Price = big.NewInt(15)
+	update(t, s, id, 2, counter)
+
+	req, err := s.Request(id)
+	require.NoError(t, err)
+	require.Nil(t, req.Offer)
+	require.True(t, counter.Offer.Equal(&req.CounterOffer.Offer))
+
+	// Abandoning the counter-offer removes the request.
+	update(t, s, id, 3, &data.DefaultData{})
+	req, err = s.Request(id)
+	require.NoError(t, err)
+	require.Nil(t, req)
+}
+
+func TestMismatchedCert(t *testing.T) {
+	s := NewMemory()
+	id := channel.ID{4}
+	update(t, s, id, 3, batch)
+	err := s.track(&source{state: &channel.State{ID: id, Version: 4, Data: &data.Cert{}}})
+	require.ErrorIs(t, err, ErrUnexpectedData)
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/client/policy"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire"
+)
+
+// TenantConfig configures a logical issuer hosted by the client. The
+// channels of all tenants are opened by the client's account, but each tenant
+// signs its credentials with its own key.
+type TenantConfig struct {
+	ID      string        // Identifies the tenant in channel proposals.
+	Account app.Account   // Signs the credentials issued by the tenant.
+	Policy  policy.Policy // Optional. Decides on the credential requests to the tenant.
+}
+
+// Tenant is a logical issuer hosted by the client. Channel proposals naming
+// the tenant are routed to Tenant.NextConnectionRequest instead of
+// Client.NextConnectionRequest.
+type Tenant struct {
+	cfg       TenantConfig
+	client    *Client
+	proposals chan *connection.ChannelProposal
+}
+
+func newTenants(cfgs []TenantConfig) (map[string]*Tenant, error) {
+	ts := make(map[string]*Tenant, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.ID == "" {
+			return nil, fmt.Errorf("tenant without ID")
+		} else if cfg.Account == nil {
+			return nil, fmt.Errorf("tenant %s without account", cfg.ID)
+		} else if _, ok := ts[cfg.ID]; ok {
+			return nil, fmt.Errorf("duplicate tenant %s", cfg.ID)
+		}
+		ts[cfg.ID] = &Tenant{cfg: cfg, proposals: make(chan *connection.ChannelProposal)}
+	}
+	return ts, nil
+}
+
+// Tenant returns the tenant with ID `id`, if it is hosted by the client.
+func (c *Client) Tenant(id string) (*Tenant, bool) {
+	t, ok := c.tenants[id]
+	return t, ok
+}
+
+// ConnectTenant opens a channel with tenant `tenant` hosted by `peer`.
+// Credentials are then requested with the tenant's address as issuer.
+func (c *Client) ConnectTenant(ctx context.Context, peer wire.Address, tenant string, balance channel.Bal) (*connection.Connection, error) {
+	return c.connect(ctx, c.appAddress, peer, tenant, balance, big.NewInt(0), c.challengeDuration)
+}
+
+// ID returns the ID of the tenant.
+func (t *Tenant) ID() string {
+	return t.cfg.ID
+}
+
+// Address returns the address with which the tenant signs credentials.
+func (t *Tenant) Address() common.Address {
+	return app.AccountAddress(t.cfg.Account)
+}
+
+// NextConnectionRequest returns the next channel proposal for the tenant.
+func (t *Tenant) NextConnectionRequest(ctx context.Context) (*connection.ConnectionRequest, error) {
+	select {
+	case p := <-t.proposals:
+		atomic.AddInt32(&t.client.pendingProposals, -1)
+		return connection.NewConnectionRequest(p, t.client.PerunAddress(), t.client.connections, t.client.connCfg), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Issue checks credential request `r` for document `doc` against the
+// tenant's policy, if any, and issues the credential with the tenant's key.
+// It fails if the credential is requested from another issuer.
+func (t *Tenant) Issue(ctx context.Context, r *connection.CredentialRequest, doc []byte) error {
+	if r.Offer().Issuer != t.Address() {
+		return fmt.Errorf("credential requested from %v, not from tenant %s", r.Offer().Issuer, t.cfg.ID)
+	}
+	if t.cfg.Policy != nil {
+		if err := r.Evaluate(t.cfg.Policy, doc); err != nil {
+			return err
+		}
+	} else if err := r.CheckDoc(doc); err != nil {
+		return err
+	}
+	return r.IssueCredential(ctx, t.cfg.Account)
+}
+
+// ExportChannels writes the records of the tenant's channels to `w` in
+// format `f`. It fails with ErrNoAccounting if ClientConfig.Accounting is not
+// set.
+func (t *Tenant) ExportChannels(w io.Writer, f connection.ExportFormat) error {
+	if t.client.ledger == nil {
+		return ErrNoAccounting
+	}
+	return t.client.ledger.ExportTenantChannels(w, t.cfg.ID, f)
+}
+
+// ExportPayments writes the records of the payments in the tenant's channels
+// to `w` in format `f`. It fails with ErrNoAccounting if
+// ClientConfig.Accounting is not set.
+func (t *Tenant) ExportPayments(w io.Writer, f connection.ExportFormat) error {
+	if t.client.ledger == nil {
+		return ErrNoAccounting
+	}
+	return t.client.ledger.ExportTenantPayments(w, t.cfg.ID, f)
+}
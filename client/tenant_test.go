@@ -0,0 +1,31 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+func TestNewTenants(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(t, err)
+	a := acc.(*simple.Account)
+
+	ts, err := newTenants([]TenantConfig{{ID: "a", Account: a}, {ID: "b", Account: a}})
+	require.NoError(t, err)
+	require.Len(t, ts, 2)
+	require.Equal(t, "a", ts["a"].ID())
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), ts["b"].Address())
+
+	_, err = newTenants([]TenantConfig{{Account: a}})
+	require.Error(t, err, "no ID")
+	_, err = newTenants([]TenantConfig{{ID: "a"}})
+	require.Error(t, err, "no account")
+	_, err = newTenants([]TenantConfig{{ID: "a", Account: a}, {ID: "a", Account: a}})
+	require.Error(t, err, "duplicate ID")
+}
@@ -2,11 +2,11 @@ package client
 
 import (
 	"context"
-	"fmt"
-	"log"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	pkgapp "github.com/perun-network/perun-credential-payment/app"
 	"perun.network/go-perun/wallet"
 )
 
@@ -15,15 +15,16 @@ func (c *Client) PerunAddress() wallet.Address {
 }
 
 func (c *Client) Address() common.Address {
-	return c.perunClient.Account.Account.Address
+	return pkgapp.AccountAddress(c.perunClient.Account)
 }
 
-func (c *Client) challengeDurationInSeconds() uint64 {
-	return uint64(c.challengeDuration.Seconds())
+// txAccount returns the account that sends the client's transactions.
+func (c *Client) txAccount() accounts.Account {
+	return accounts.Account{Address: c.Address()}
 }
 
 func (c *Client) Logf(format string, v ...interface{}) {
-	log.Printf("Client %v: %v", c.Address(), fmt.Sprintf(format, v...))
+	c.log.Infof(format, v...)
 }
 
 func (c *Client) OnChainBalance() (b *big.Int, err error) {
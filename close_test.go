@@ -0,0 +1,80 @@
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+)
+
+// TestCloseWith checks that the issuer can refund the holder when closing
+// the channel, but that the holder cannot take funds of the issuer.
+func TestCloseWith(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	price := env.Amount(2)
+
+	// The issuer refunds half of the price when closing.
+	refunded := make(chan struct{})
+	issuerErr := make(chan error, 1)
+	go func() {
+		issuerErr <- func() error {
+			req, err := issuer.NextConnectionRequest(ctx)
+			if err != nil {
+				return fmt.Errorf("awaiting next connection request: %w", err)
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return fmt.Errorf("accepting connection request: %w", err)
+			}
+			credReq, err := conn.NextCredentialRequest(ctx)
+			if err != nil {
+				return fmt.Errorf("awaiting next credential request: %w", err)
+			}
+			if err := credReq.IssueCredential(ctx, issuer.Account()); err != nil {
+				return fmt.Errorf("issuing credential: %w", err)
+			}
+			select {
+			case <-refunded:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			alloc := make([]channel.Bal, 2)
+			alloc[conn.Idx()] = env.Amount(1)
+			alloc[1-conn.Idx()] = env.Amount(4)
+			return conn.CloseWith(ctx, alloc)
+		}()
+	}()
+
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, price, issuer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+
+	alloc := make([]channel.Bal, 2)
+	alloc[conn.Idx()] = env.Amount(5)
+	alloc[1-conn.Idx()] = env.Amount(0)
+	require.Error(conn.CloseWith(ctx, alloc), "taking funds of issuer")
+	require.False(conn.State().IsFinal)
+	close(refunded)
+
+	require.NoError(conn.WaitConcludadable(ctx), "awaiting final state")
+	require.NoError(conn.Close(ctx), "settling")
+	require.NoError(<-issuerErr, "running issuer")
+	bals := conn.State().Balances[app.AssetIdx]
+	require.Zero(env.Amount(4).Cmp(bals[conn.Idx()]), "holder balance")
+	require.Zero(env.Amount(1).Cmp(bals[1-conn.Idx()]), "issuer balance")
+}
@@ -0,0 +1,142 @@
+package deploy
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// Create2Factory is the deterministic deployment proxy, which deploys the
+// init code following a 32 byte salt in its calldata with CREATE2. It exists
+// at the same address on every chain that it was deployed to with
+// create2FactoryTx.
+var Create2Factory = common.HexToAddress("0x4e59b44847b379578588920cA78FbF26c0B4956C")
+
+var (
+	// create2FactoryTx deploys the factory. It is a pre-signed legacy
+	// transaction without chain ID, sent by create2FactorySigner, whose key
+	// is unknown.
+	create2FactoryTx     = common.FromHex("0xf8a58085174876e800830186a08080b853604580600e600039806000f350fe7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe03601600081602082378035828234f58015156039578182fd5b8082525050506014600cf31ba02222222222222222222222222222222222222222222222222222222222222222a02222222222222222222222222222222222222222222222222222222222222222")
+	create2FactorySigner = common.HexToAddress("0x3fab184622dc19b6109349b94811493bf2a45362")
+	// create2FactoryCost is the gas of create2FactoryTx times its gas price.
+	create2FactoryCost = new(big.Int).Mul(big.NewInt(100000), big.NewInt(100e9))
+)
+
+// Create2Address returns the address at which the factory deploys
+// `initCode` with `salt`.
+func Create2Address(salt [32]byte, initCode []byte) common.Address {
+	return crypto.CreateAddress2(Create2Factory, salt, crypto.Keccak256(initCode))
+}
+
+// Create2Addresses returns the addresses at which DeployCreate2 deploys the
+// contracts with `salt`. They do not depend on the chain or the deployer.
+func Create2Addresses(salt [32]byte) (ContractAddresses, error) {
+	code, err := create2InitCodes(salt)
+	if err != nil {
+		return ContractAddresses{}, err
+	}
+	return ContractAddresses{
+		Adjudicator: Create2Address(salt, code.adjudicator),
+		AssetHolder: Create2Address(salt, code.assetHolder),
+		App:         Create2Address(salt, code.app),
+	}, nil
+}
+
+// create2InitCodes returns the init code of the contracts. The asset holder
+// is constructed with the adjudicator deployed with `salt`.
+func create2InitCodes(salt [32]byte) (initCode, error) {
+	code := initCodes()
+	args, err := assetHolderArgs(Create2Address(salt, code.adjudicator))
+	if err != nil {
+		return initCode{}, err
+	}
+	code.assetHolder = append(code.assetHolder, args...)
+	return code, nil
+}
+
+// DeployCreate2 deploys the contracts with CREATE2, so that they land at
+// Create2Addresses(salt) on every chain. Contracts that already exist are
+// reused after validating them. The factory is deployed first if the chain
+// does not have it yet.
+func (d *Deployer) DeployCreate2(ctx context.Context, salt [32]byte) (ContractAddresses, error) {
+	if err := d.ensureCreate2Factory(ctx); err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "setting up CREATE2 factory")
+	}
+
+	code, err := create2InitCodes(salt)
+	if err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "assembling init code")
+	}
+	adj, txAdj, err := d.deployCreate2(ctx, salt, code.adjudicator)
+	if err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "deploying adjudicator")
+	}
+	appAddr, txApp, err := d.deployCreate2(ctx, salt, code.app)
+	if err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "deploying CollateralApp")
+	}
+	assetHolderAddr, txAss, err := d.deployCreate2(ctx, salt, code.assetHolder)
+	if err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "deploying CollateralAssetHolderETH")
+	}
+
+	addrs := ContractAddresses{Adjudicator: adj, AssetHolder: assetHolderAddr, App: appAddr}
+	if err := d.waitDeployment(ctx, addrs, txAdj, txApp, txAss); err != nil {
+		return ContractAddresses{}, err
+	}
+	return addrs, nil
+}
+
+// ensureCreate2Factory deploys the factory if the chain does not have it
+// yet. The signer of the factory deployment is funded by the deployer.
+func (d *Deployer) ensureCreate2Factory(ctx context.Context) error {
+	code, err := d.CodeAt(ctx, Create2Factory, nil)
+	if err != nil {
+		return errors.WithMessage(err, "reading factory code")
+	} else if len(code) > 0 {
+		return nil
+	}
+
+	funding, err := d.sendValue(ctx, create2FactorySigner, create2FactoryCost)
+	if err != nil {
+		return errors.WithMessage(err, "funding factory signer")
+	}
+	if err := d.waitSuccess(ctx, funding); err != nil {
+		return errors.WithMessage(err, "funding factory signer")
+	}
+
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(create2FactoryTx); err != nil {
+		return err
+	}
+	if err := d.SendTransaction(ctx, &tx); err != nil {
+		return errors.WithMessage(err, "sending factory deployment")
+	}
+	return errors.WithMessage(d.waitSuccess(ctx, &tx), "deploying factory")
+}
+
+// deployCreate2 deploys `initCode` with `salt` through the factory. If the
+// contract already exists, it returns its address and no transaction.
+func (d *Deployer) deployCreate2(ctx context.Context, salt [32]byte, initCode []byte) (common.Address, *types.Transaction, error) {
+	addr := Create2Address(salt, initCode)
+	code, err := d.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return common.Address{}, nil, errors.WithMessage(err, "reading contract code")
+	} else if len(code) > 0 {
+		return addr, nil, nil
+	}
+
+	tr, err := d.newTransactor(ctx)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	tx, err := d.transact(tr, Create2Factory, append(salt[:], initCode...))
+	if err != nil {
+		return common.Address{}, nil, errors.WithMessage(err, "sending deployment transaction")
+	}
+	return addr, tx, nil
+}
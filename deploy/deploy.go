@@ -0,0 +1,268 @@
+// Package deploy deploys the contracts of the credential payment protocol and
+// validates existing deployments.
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/pkg/errors"
+	"perun.network/go-perun/backend/ethereum/bindings/adjudicator"
+	"perun.network/go-perun/backend/ethereum/bindings/assetholdereth"
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+)
+
+type ContractAddresses struct {
+	Adjudicator, AssetHolder, App common.Address
+}
+
+// Backend is the chain a Deployer deploys to. It is implemented by
+// ethclient.Client and by simulated backends.
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	ethereum.ChainStateReader
+}
+
+// Deployer deploys contracts from the account of its key.
+type Deployer struct {
+	Backend
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+	nonce   uint64
+	close   func() // Nil if the backend was not dialed by the Deployer.
+}
+
+func NewDeployer(ctx context.Context, nodeURL string, key *ecdsa.PrivateKey, chainID *big.Int) (*Deployer, error) {
+	client, err := ethclient.DialContext(ctx, nodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("dialing: %w", err)
+	}
+
+	d, err := NewDeployerWithBackend(ctx, client, key, chainID)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	d.close = client.Close
+	return d, nil
+}
+
+// NewDeployerWithBackend returns a Deployer that deploys to `backend`, e.g.,
+// a simulated backend.
+func NewDeployerWithBackend(ctx context.Context, backend Backend, key *ecdsa.PrivateKey, chainID *big.Int) (*Deployer, error) {
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	nonce, err := backend.NonceAt(ctx, addr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting nonce: %w", err)
+	}
+
+	return &Deployer{
+		Backend: backend,
+		key:     key,
+		chainID: chainID,
+		nonce:   nonce,
+	}, nil
+}
+
+// Close closes the connection to the node if it was dialed by the Deployer.
+func (d *Deployer) Close() {
+	if d.close != nil {
+		d.close()
+	}
+}
+
+// Deploy deploys fresh instances of the contracts and validates them.
+func (d *Deployer) Deploy(ctx context.Context) (ContractAddresses, error) {
+	code := initCodes()
+	adj, txAdj, err := d.deployContract(ctx, code.adjudicator)
+	if err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "deploying adjudicator")
+	}
+	appAddr, txApp, err := d.deployContract(ctx, code.app)
+	if err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "deploying CollateralApp")
+	}
+	// The asset holder is constructed with the adjudicator address, which
+	// is only known now.
+	args, err := assetHolderArgs(adj)
+	if err != nil {
+		return ContractAddresses{}, err
+	}
+	assetHolderAddr, txAss, err := d.deployContract(ctx, append(code.assetHolder, args...))
+	if err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "deploying CollateralAssetHolderETH")
+	}
+
+	addrs := ContractAddresses{Adjudicator: adj, AssetHolder: assetHolderAddr, App: appAddr}
+	if err := d.waitDeployment(ctx, addrs, txAdj, txApp, txAss); err != nil {
+		return ContractAddresses{}, err
+	}
+	return addrs, nil
+}
+
+// Validate checks that the contracts at `addrs` run the expected code and
+// that the asset holder belongs to the adjudicator. Tampered contracts fail
+// with an error that satisfies ethchannel.IsErrInvalidContractCode.
+func Validate(ctx context.Context, backend bind.ContractBackend, addrs ContractAddresses) error {
+	if err := ethchannel.ValidateAssetHolderETH(ctx, backend, addrs.AssetHolder, addrs.Adjudicator); err != nil {
+		return err
+	}
+	return ValidateApp(ctx, backend, addrs.App)
+}
+
+// ValidateApp checks that the app contract at `addr` runs the expected code.
+func ValidateApp(ctx context.Context, backend bind.ContractCaller, addr common.Address) error {
+	code, err := backend.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return errors.WithMessage(err, "fetching app code")
+	}
+	want, err := runtimeCode(common.FromHex(app.CredentialSwapBin))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(code, want) {
+		return errors.Wrap(ethchannel.ErrInvalidContractCode, "incorrect app code")
+	}
+	return nil
+}
+
+// runtimeCode returns the code that a contract runs after deployment with
+// `initCode`. The solc constructor copies it out of the init code with
+//
+//	PUSH2 size DUP1 PUSH2 offset PUSH1 0 CODECOPY
+//
+// and returns it, so it is located by this sequence.
+func runtimeCode(initCode []byte) ([]byte, error) {
+	for i := 0; i+10 <= len(initCode); i++ {
+		c := initCode[i : i+10]
+		if c[0] != 0x61 || c[3] != 0x80 || c[4] != 0x61 || c[7] != 0x60 || c[8] != 0x00 || c[9] != 0x39 {
+			continue
+		}
+		size := int(c[1])<<8 | int(c[2])
+		offset := int(c[5])<<8 | int(c[6])
+		if offset+size <= len(initCode) {
+			return initCode[offset : offset+size], nil
+		}
+	}
+	return nil, errors.New("runtime code not found in init code")
+}
+
+type initCode struct {
+	adjudicator, assetHolder, app []byte
+}
+
+// initCodes returns the init code of the contracts. The asset holder code
+// lacks its constructor arguments.
+func initCodes() initCode {
+	return initCode{
+		adjudicator: common.FromHex(adjudicator.AdjudicatorBin),
+		assetHolder: common.FromHex(assetholdereth.AssetHolderETHBin),
+		app:         common.FromHex(app.CredentialSwapBin),
+	}
+}
+
+// assetHolderArgs returns the encoded constructor arguments of the asset
+// holder.
+func assetHolderArgs(adj common.Address) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(assetholdereth.AssetHolderETHABI))
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Pack("", adj)
+}
+
+func (d *Deployer) deployContract(ctx context.Context, code []byte) (common.Address, *types.Transaction, error) {
+	tr, err := d.newTransactor(ctx)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	addr, tx, _, err := bind.DeployContract(tr, abi.ABI{}, code, d.Backend)
+	if err != nil {
+		return common.Address{}, nil, errors.WithMessage(err, "sending deployment transaction")
+	}
+	d.nonce++
+	return addr, tx, nil
+}
+
+// transact sends a transaction with `data` to contract `to`.
+func (d *Deployer) transact(tr *bind.TransactOpts, to common.Address, data []byte) (*types.Transaction, error) {
+	tx, err := bind.NewBoundContract(to, abi.ABI{}, d.Backend, d.Backend, d.Backend).RawTransact(tr, data)
+	if err != nil {
+		return nil, err
+	}
+	d.nonce++
+	return tx, nil
+}
+
+// sendValue transfers `value` to `to`. Unlike transact, it does not estimate
+// the gas, which fails for accounts without code.
+func (d *Deployer) sendValue(ctx context.Context, to common.Address, value *big.Int) (*types.Transaction, error) {
+	gasPrice, err := d.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "suggesting gas price")
+	}
+	tx, err := types.SignNewTx(d.key, types.LatestSignerForChainID(d.chainID), &types.LegacyTx{
+		Nonce:    d.nonce,
+		To:       &to,
+		Value:    value,
+		Gas:      params.TxGas,
+		GasPrice: gasPrice,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "signing transaction")
+	}
+	if err := d.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	d.nonce++
+	return tx, nil
+}
+
+// waitDeployment waits for the deployment transactions and validates the
+// deployed contracts. Nil transactions are skipped.
+func (d *Deployer) waitDeployment(ctx context.Context, addrs ContractAddresses, txs ...*types.Transaction) error {
+	for _, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		if err := d.waitSuccess(ctx, tx); err != nil {
+			return errors.WithMessagef(err, "waiting for deployment: %v", tx.Hash())
+		}
+	}
+	return errors.WithMessage(Validate(ctx, d.Backend, addrs), "validating deployment")
+}
+
+func (d *Deployer) waitSuccess(ctx context.Context, tx *types.Transaction) error {
+	r, err := bind.WaitMined(ctx, d.Backend, tx)
+	if err != nil {
+		return err
+	} else if r.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("transaction %v failed", tx.Hash())
+	}
+	return nil
+}
+
+func (d *Deployer) newTransactor(ctx context.Context) (*bind.TransactOpts, error) {
+	tr, err := bind.NewKeyedTransactorWithChainID(d.key, d.chainID)
+	if err != nil {
+		return nil, err
+	}
+	tr.Context = ctx
+	// The nonce is only advanced once the transaction was sent.
+	tr.Nonce = new(big.Int).SetUint64(d.nonce)
+	return tr, nil
+}
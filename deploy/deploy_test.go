@@ -0,0 +1,83 @@
+package deploy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	ethchanneltest "perun.network/go-perun/backend/ethereum/channel/test"
+)
+
+// newDeployer returns a deployer on a new simulated backend, which mines a
+// block every 100ms.
+func newDeployer(t *testing.T) *Deployer {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sb := ethchanneltest.NewSimulatedBackend()
+	sb.FundAddress(context.Background(), crypto.PubkeyToAddress(key.PublicKey))
+	sb.StartMining(100 * time.Millisecond)
+	t.Cleanup(sb.StopMining)
+
+	d, err := NewDeployerWithBackend(context.Background(), sb, key, big.NewInt(1337))
+	require.NoError(t, err)
+	return d
+}
+
+func TestRuntimeCode(t *testing.T) {
+	// PUSH2 3 DUP1 PUSH2 12 PUSH1 0 CODECOPY, followed by the runtime code
+	// at offset 12.
+	initCode := []byte{0x61, 0x00, 0x03, 0x80, 0x61, 0x00, 0x0c, 0x60, 0x00, 0x39, 0xf3, 0x00, 0xaa, 0xbb, 0xcc}
+	code, err := runtimeCode(initCode)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xaa, 0xbb, 0xcc}, code)
+
+	_, err = runtimeCode(initCode[:14])
+	require.Error(t, err, "truncated")
+	_, err = runtimeCode([]byte{0x60, 0x00})
+	require.Error(t, err, "no CODECOPY")
+}
+
+func TestDeploy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	d := newDeployer(t)
+
+	addrs, err := d.Deploy(ctx)
+	require.NoError(t, err)
+	require.NoError(t, Validate(ctx, d, addrs))
+
+	err = ValidateApp(ctx, d, addrs.Adjudicator)
+	require.True(t, ethchannel.IsErrInvalidContractCode(err), "other contract: %v", err)
+	swapped := addrs
+	swapped.Adjudicator = addrs.App
+	require.Error(t, Validate(ctx, d, swapped), "asset holder of other adjudicator")
+}
+
+func TestDeployCreate2(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	d := newDeployer(t)
+	salt := [32]byte{1}
+
+	want, err := Create2Addresses(salt)
+	require.NoError(t, err)
+	addrs, err := d.DeployCreate2(ctx, salt)
+	require.NoError(t, err)
+	require.Equal(t, want, addrs)
+
+	// Existing contracts are reused.
+	nonce := d.nonce
+	addrs, err = d.DeployCreate2(ctx, salt)
+	require.NoError(t, err)
+	require.Equal(t, want, addrs)
+	require.Equal(t, nonce, d.nonce, "transactions sent")
+
+	other, err := Create2Addresses([32]byte{2})
+	require.NoError(t, err)
+	require.NotEqual(t, want, other, "other salt")
+}
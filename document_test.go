@@ -0,0 +1,132 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOutOfBandDocument checks that the requested document reaches the
+// issuer out-of-band and that the channel state only holds its hash.
+func TestOutOfBandDocument(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	balance, price := env.Amount(5), env.Amount(1)
+
+	type received struct {
+		req *connection.CredentialRequest
+		err error
+	}
+	issuerRecv := make(chan received, 1)
+	go func() {
+		req, err := issuer.NextConnectionRequest(ctx)
+		if err != nil {
+			issuerRecv <- received{err: err}
+			return
+		}
+		conn, err := req.Accept(ctx)
+		if err != nil {
+			issuerRecv <- received{err: err}
+			return
+		}
+		credReq, err := conn.NextCredentialRequest(ctx)
+		issuerRecv <- received{req: credReq, err: err}
+	}()
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), balance)
+	require.NoError(err, "proposing connection")
+	_, err = conn.RequestCredential(ctx, doc, price, issuer.Address())
+	require.NoError(err, "requesting credential")
+	recv := <-issuerRecv
+	require.NoError(recv.err, "receiving credential request")
+
+	got, err := recv.req.Document()
+	require.NoError(err, "resolving document")
+	require.Equal(doc, got)
+	require.NoError(recv.req.CheckDoc(doc))
+	require.Error(recv.req.CheckDoc([]byte("other")), "other document")
+
+	// The offer only commits to the hash of the document.
+	offer, ok := conn.State().Data.(*data.Offer)
+	require.True(ok, "state data: %T", conn.State().Data)
+	require.Equal(app.ComputeDocumentHash(doc), offer.DataHash)
+	var buf bytes.Buffer
+	require.NoError(offer.Encode(&buf))
+	require.False(bytes.Contains(buf.Bytes(), doc), "document in app data")
+}
+
+// contentStore is an in-memory connection.ContentStore.
+type contentStore struct {
+	mu      sync.Mutex
+	content map[string][]byte
+}
+
+func (s *contentStore) Put(_ context.Context, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := fmt.Sprint(len(s.content))
+	s.content[ref] = data
+	return ref, nil
+}
+
+func (s *contentStore) Get(_ context.Context, ref string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.content[ref]
+	if !ok {
+		return nil, errors.New("unknown reference")
+	}
+	return data, nil
+}
+
+// TestContentStore checks that large documents are transferred via the
+// content store and that only their ciphertext is stored.
+func TestContentStore(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	store := &contentStore{content: make(map[string][]byte)}
+	env := testutil.Setup(t, func(holder, issuer *client.ClientConfig) {
+		holder.ContentStore = store
+		issuer.ContentStore = store
+	})
+	holder, issuer := env.Holder, env.Issuer
+	small := []byte("Perun/Bosch: SSI Credential Payment")
+	large := bytes.Repeat(small, connection.ContentStoreThreshold/len(small)+1)
+
+	issuerConn := make(chan *connection.Connection, 1)
+	go func() {
+		req, err := issuer.NextConnectionRequest(ctx)
+		if err != nil {
+			close(issuerConn)
+			return
+		}
+		conn, _ := req.Accept(ctx)
+		issuerConn <- conn
+	}()
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	require.NotNil(<-issuerConn, "accepting connection")
+
+	require.NoError(conn.SendDocument(ctx, small))
+	require.Empty(store.content, "small document stored")
+	require.NoError(conn.SendDocument(ctx, large))
+	require.Len(store.content, 1)
+	require.False(bytes.Contains(store.content["0"], small), "plaintext stored")
+}
@@ -0,0 +1,56 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDisputeEvidence checks that the evidence exported by a holder contains
+// every fully signed state of the channel.
+func TestDisputeEvidence(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t, func(holder, _ *client.ClientConfig) {
+		holder.RecordEvidence = true
+	})
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+
+	issuerErr := runIssuer(ctx, issuer, 1, func(req *connection.CredentialRequest) error {
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, env.Amount(1), issuer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+
+	ev, err := conn.ExportDisputeEvidence()
+	require.NoError(err, "exporting evidence")
+	require.Equal(common.Hash(conn.ID()), ev.ChannelID)
+	require.Equal([]common.Address{holder.Address(), issuer.Address()}, ev.Params.Participants)
+	require.NotEqual(common.Address{}, ev.Params.App, "app channel")
+
+	// The initial state, the request, and the certificate.
+	require.Len(ev.States, 3)
+	require.Equal("*data.Offer", ev.States[1].DataType)
+	require.Equal("*data.Cert", ev.States[2].DataType)
+	for i, s := range ev.States {
+		require.Equal(uint64(i), s.Version, "version of state %d", i)
+		require.NotEmpty(s.Encoded, "encoding of state %d", i)
+		require.Len(s.Signatures, 2, "signatures of state %d", i)
+	}
+	require.Equal(conn.State().Version, ev.States[2].Version, "latest state")
+}
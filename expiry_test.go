@@ -0,0 +1,50 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExpiringCredential checks that the expiry of a credential is signed
+// by the issuer.
+func TestExpiringCredential(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	now := time.Now()
+	if env.Clock != nil {
+		now = env.Clock.Now()
+	}
+	expiry := now.Add(time.Hour).Truncate(time.Second)
+
+	issuerErr := runIssuer(ctx, issuer, 1, func(req *connection.CredentialRequest) error {
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+
+	_, err = conn.RequestExpiringCredential(ctx, doc, env.Amount(1), issuer.Address(), now.Add(-time.Second))
+	require.ErrorIs(err, app.ErrCredentialExpired, "requesting expired credential")
+
+	asyncCred, err := conn.RequestExpiringCredential(ctx, doc, env.Amount(1), issuer.Address(), expiry)
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.Equal(uint64(expiry.Unix()), resp.Expiry())
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+
+	cred := &app.Credential{Document: doc, Signature: resp.Signature, Expiry: resp.Expiry(), Domain: resp.Domain()}
+	require.NoError(app.VerifyCredential(cred, issuer.Address(), now))
+	require.ErrorIs(app.VerifyCredential(cred, issuer.Address(), expiry), app.ErrCredentialExpired)
+}
@@ -0,0 +1,100 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestForceClose checks that the holder settles the latest state of a
+// channel on its own, and that the channel takes no requests afterwards.
+func TestForceClose(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	env.SkipDisputes(ctx)
+	holder, issuer := env.Holder, env.Issuer
+	events := holder.Events(ctx)
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+
+	issuerErr := runIssuer(ctx, issuer, 1, func(req *connection.CredentialRequest) error {
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, env.Amount(1), issuer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+
+	require.NoError(conn.ForceClose(ctx), "force closing")
+	require.True(conn.Disputed())
+	s := conn.State()
+	require.False(s.IsFinal, "registered state")
+	require.IsType(&data.Cert{}, s.Data, "registered state")
+	require.Zero(env.Amount(4).Cmp(s.Balances[app.AssetIdx][conn.Idx()]), "holder balance")
+
+	_, err = conn.RequestCredential(ctx, doc, env.Amount(1), issuer.Address())
+	require.ErrorIs(err, connection.ErrDisputeRegistered)
+	for {
+		select {
+		case ev := <-events:
+			if closed, ok := ev.(*connection.ChannelClosed); ok {
+				require.Equal(conn.ID(), closed.Channel)
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("channel not closed:", ctx.Err())
+		}
+	}
+}
+
+// TestAdjudicatorEvents checks that the peer of a force-closed channel is
+// notified of the registration and the conclusion of the channel.
+func TestAdjudicatorEvents(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	env.SkipDisputes(ctx)
+	holder, issuer := env.Holder, env.Issuer
+	events := issuer.Events(ctx)
+
+	issuerErr := runIssuer(ctx, issuer, 0, nil)
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	require.NoError(<-issuerErr, "running issuer")
+	require.NoError(conn.ForceClose(ctx), "force closing")
+
+	var registered *connection.DisputeRegistered
+	for {
+		select {
+		case ev := <-events:
+			switch ev := ev.(type) {
+			case *connection.DisputeRegistered:
+				require.Equal(conn.ID(), ev.Channel)
+				require.Equal(conn.State().Version, ev.Version)
+				require.False(ev.Timeout.IsZero(), "timeout")
+				registered = ev
+			case *connection.ChannelConcluded:
+				require.NotNil(registered, "concluded before registered")
+				require.Equal(conn.ID(), ev.Channel)
+				require.Equal(registered.Version, ev.Version)
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("channel not concluded:", ctx.Err())
+		}
+	}
+}
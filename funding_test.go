@@ -0,0 +1,56 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFundingTimeout checks that the holder reclaims its deposit if the
+// issuer does not fund the channel in time.
+func TestFundingTimeout(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t, func(holder, _ *client.ClientConfig) {
+		holder.FundingTimeout = 5 * time.Second
+	})
+	env.SkipDisputes(ctx)
+	holder, issuer := env.Holder, env.Issuer
+	before, err := holder.OnChainBalance()
+	require.NoError(err)
+
+	// The issuer cannot afford its deposit.
+	issuerBalance, err := issuer.OnChainBalance()
+	require.NoError(err)
+	peerBalance := new(big.Int).Add(issuerBalance, env.Amount(1))
+	issuerErr := make(chan error, 1)
+	go func() {
+		req, err := issuer.NextConnectionRequest(ctx)
+		if err != nil {
+			issuerErr <- err
+			return
+		}
+		_, err = req.Accept(ctx)
+		issuerErr <- err
+	}()
+
+	_, err = holder.ConnectWithPeerBalance(ctx, issuer.PerunAddress(), env.Amount(5), peerBalance)
+	var timeout *connection.FundingTimeoutError
+	require.True(errors.As(err, &timeout), "funding timeout expected: %v", err)
+	require.True(timeout.Reclaimed, "deposit reclaimed")
+	require.Error(<-issuerErr, "funding without funds")
+
+	// Only gas was spent.
+	after, err := holder.OnChainBalance()
+	require.NoError(err)
+	require.Positive(after.Cmp(new(big.Int).Sub(before, env.Amount(1))), "holder balance")
+}
@@ -0,0 +1,76 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHolderBinding checks that an issuer requiring holder binding rejects
+// unbound requests, and that the holder of a bound credential can prove
+// possession of it.
+func TestHolderBinding(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t, func(_, issuer *client.ClientConfig) {
+		issuer.RequireHolderBinding = true
+	})
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	now := time.Now()
+	if env.Clock != nil {
+		now = env.Clock.Now()
+	}
+	meta := &app.Metadata{Type: "Diploma", IssuedAt: uint64(now.Unix())}
+
+	issuerErr := runIssuer(ctx, issuer, 2, func(req *connection.CredentialRequest) error {
+		if err := req.CheckDoc(doc); errors.Is(err, app.ErrNotBound) {
+			return req.Reject(ctx, err.Error())
+		} else if err != nil {
+			return err
+		}
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+
+	_, err = conn.RequestCredentialWithOptions(ctx, doc, env.Amount(1), issuer.Address(), connection.CredentialOptions{Metadata: meta})
+	require.Error(err, "requesting unbound credential")
+
+	asyncCred, err := conn.RequestCredentialWithOptions(ctx, doc, env.Amount(1), issuer.Address(), connection.CredentialOptions{Metadata: meta, BindHolder: true})
+	require.NoError(err, "requesting bound credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+	require.Empty(meta.Holder, "metadata of caller modified")
+
+	// The issuer signed the metadata bound to the holder's did:ethr.
+	bound := *meta
+	bound.Holder = did.Ethr(holder.Address())
+	cred := &app.Credential{Document: doc, Signature: resp.Signature, Metadata: &bound, Domain: resp.Domain()}
+	require.NoError(app.VerifyCredential(cred, issuer.Address(), now))
+
+	resolver := did.EthrResolver{}
+	require.NoError(cred.VerifyHolder(ctx, resolver, holder.Address()))
+	require.ErrorIs(cred.VerifyHolder(ctx, resolver, issuer.Address()), app.ErrWrongHolder)
+
+	challenge := []byte("challenge")
+	proof, err := app.ProveHolder(holder.Account(), cred, challenge)
+	require.NoError(err)
+	require.NoError(app.VerifyHolderProof(ctx, resolver, cred, challenge, proof))
+	require.Error(app.VerifyHolderProof(ctx, resolver, cred, []byte("other challenge"), proof), "other challenge")
+	proof, err = app.ProveHolder(issuer.Account(), cred, challenge)
+	require.NoError(err)
+	require.ErrorIs(app.VerifyHolderProof(ctx, resolver, cred, challenge, proof), app.ErrWrongHolder, "proof of issuer")
+}
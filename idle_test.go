@@ -0,0 +1,98 @@
+package main_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// idleTimeout is the idle timeout of the issuer in TestIdleClose.
+const idleTimeout = 3 * time.Second
+
+// TestIdleClose checks that the issuer closes a channel cooperatively once it
+// was not updated for the idle timeout, and that the holder gets its change.
+func TestIdleClose(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t, func(_, issuer *client.ClientConfig) {
+		issuer.IdleTimeout = idleTimeout
+	})
+	holder, issuer := env.Holder, env.Issuer
+	events := issuer.Events(ctx)
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	balance, price := env.Amount(5), env.Amount(1)
+
+	// Buy a credential, which restarts the idle timer.
+	issuerErr := make(chan error, 1)
+	go func() {
+		issuerErr <- func() error {
+			req, err := issuer.NextConnectionRequest(ctx)
+			if err != nil {
+				return err
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return err
+			}
+			credReq, err := conn.NextCredentialRequest(ctx)
+			if err != nil {
+				return err
+			}
+			if err := credReq.CheckDoc(doc); err != nil {
+				return err
+			}
+			return credReq.IssueCredential(ctx, issuer.Account())
+		}()
+	}()
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), balance)
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, price, issuer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+
+	// The issuer closes the channel once it is idle. The fake clock of chains
+	// with controllable time is advanced until then.
+	awaitClosed(ctx, t, env, events)
+	require.NoError(conn.WaitConcludadable(ctx), "awaiting final state")
+	s := conn.State()
+	require.True(s.IsFinal, "final state")
+	require.IsType(&data.DefaultData{}, s.Data, "final state")
+	require.Zero(new(big.Int).Sub(balance, price).Cmp(s.Balances[app.AssetIdx][conn.Idx()]), "holder balance")
+	require.NoError(conn.Close(ctx), "closing")
+
+	env.LogAccountBalances()
+}
+
+// awaitClosed waits for the event that a channel was closed in `events`.
+func awaitClosed(ctx context.Context, t *testing.T, env *testutil.Environment, events <-chan connection.Event) {
+	t.Helper()
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case ev := <-events:
+			if _, ok := ev.(*connection.ChannelClosed); ok {
+				return
+			}
+		case <-tick.C:
+			if env.Clock != nil {
+				env.Clock.Advance(idleTimeout)
+			}
+		case <-ctx.Done():
+			t.Fatal("channel not closed:", ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInspect checks the phases and balances of a channel during a swap.
+func TestInspect(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	price := env.Amount(1)
+
+	// The issuer waits until the request was inspected.
+	inspected := make(chan struct{})
+	issuerErr := runIssuer(ctx, issuer, 1, func(req *connection.CredentialRequest) error {
+		select {
+		case <-inspected:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+
+	s := conn.Inspect()
+	require.Equal(conn.ID(), s.ID)
+	require.Equal(connection.PhaseIdle, s.Phase)
+	require.Nil(s.Pending)
+	require.Len(s.Balances, 2)
+	require.Equal(holder.Address(), s.Balances[s.Idx].Address)
+	require.Zero(env.Amount(5).Cmp(s.Balances[s.Idx].Balance), "holder balance")
+	require.Equal(issuer.Address(), s.Balances[1-s.Idx].Address)
+	require.Zero(s.Balances[1-s.Idx].Balance.Sign(), "issuer balance")
+
+	asyncCred, err := conn.RequestCredential(ctx, doc, price, issuer.Address())
+	require.NoError(err, "requesting credential")
+	s = conn.Inspect()
+	close(inspected)
+	require.Equal(connection.PhaseRequested, s.Phase)
+	require.Equal(uint64(1), s.Version)
+	require.NotNil(s.Pending)
+	require.Equal(issuer.Address(), s.Pending.Issuer)
+	require.Equal(s.Idx, s.Pending.Buyer)
+	require.Equal([]app.Hash{app.ComputeDocumentHash(doc)}, s.Pending.DocHashes)
+	require.Zero(price.Cmp(s.Pending.Price))
+
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+	s = conn.Inspect()
+	require.Equal(connection.PhaseIssued, s.Phase)
+	require.Equal(uint64(2), s.Version)
+	require.Nil(s.Pending)
+	require.Zero(env.Amount(4).Cmp(s.Balances[s.Idx].Balance), "holder balance")
+	require.Zero(price.Cmp(s.Balances[1-s.Idx].Balance), "issuer balance")
+	require.False(s.Disputed)
+	require.False(s.Concluded)
+}
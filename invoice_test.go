@@ -0,0 +1,49 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPayInvoice checks that a holder buys the credential of an invoice from
+// its payment request link alone.
+func TestPayInvoice(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	inv, err := issuer.CreateInvoice("Diploma", env.Amount(2), time.Hour)
+	require.NoError(err, "creating invoice")
+
+	issuerErr := runIssuer(ctx, issuer, 1, func(req *connection.CredentialRequest) error {
+		if err := req.CheckInvoice(inv, issuer.Address()); err != nil {
+			return err
+		}
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	_, _, err = holder.PayInvoice(ctx, inv, doc, env.Amount(1))
+	require.Error(err, "balance below price")
+
+	conn, asyncCred, err := holder.PayInvoiceURI(ctx, inv.URI(), doc, env.Amount(5))
+	require.NoError(err, "paying invoice")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+	require.Zero(env.Amount(3).Cmp(conn.State().Balances[app.AssetIdx][conn.Idx()]), "holder balance")
+
+	expired := *inv
+	expired.Expiry = uint64(now(env).Add(-time.Second).Unix())
+	require.NoError(expired.Sign(issuer.Account()))
+	_, _, err = holder.PayInvoice(ctx, &expired, doc, env.Amount(5))
+	require.ErrorIs(err, app.ErrInvoiceExpired)
+}
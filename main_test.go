@@ -4,16 +4,29 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/app/data"
 	"github.com/perun-network/perun-credential-payment/client"
 	"github.com/perun-network/perun-credential-payment/client/connection"
-	"github.com/perun-network/perun-credential-payment/test"
+	"github.com/perun-network/perun-credential-payment/testutil"
 	"github.com/stretchr/testify/require"
 )
 
+// issuanceTimeout is the time after which the holder gives up waiting for
+// a credential from a malicious issuer.
+const issuanceTimeout = 10 * time.Second
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	testutil.Teardown()
+	os.Exit(code)
+}
+
 func TestCredentialSwap(t *testing.T) {
 	t.Run("Honest holder", func(t *testing.T) {
 		runCredentialSwapTest(t, true)
@@ -23,21 +36,98 @@ func TestCredentialSwap(t *testing.T) {
 	})
 }
 
+func TestMaliciousIssuer(t *testing.T) {
+	for _, m := range []connection.Misbehavior{
+		connection.SignWrongDocument,
+		connection.DemandPayment,
+		connection.Stall,
+	} {
+		m := m
+		t.Run(m.String(), func(t *testing.T) {
+			runMaliciousIssuerTest(t, m)
+		})
+	}
+}
+
+func runMaliciousIssuerTest(t *testing.T, m connection.Misbehavior) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// Setup test environment.
+	env := testutil.Setup(t)
+	env.SkipDisputes(ctx)
+	holder, issuer := env.Holder, env.Issuer
+
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	balance := env.Amount(5)
+	price := env.Amount(1)
+
+	// Run malicious issuer. It does not close the channel, so that the
+	// holder must recover its funds on its own.
+	issuerErr := make(chan error, 1)
+	go func() {
+		issuerErr <- func() error {
+			req, err := issuer.NextConnectionRequest(ctx)
+			if err != nil {
+				return fmt.Errorf("awaiting next connection request: %w", err)
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return fmt.Errorf("accepting connection request: %w", err)
+			}
+			credReq, err := conn.NextCredentialRequest(ctx)
+			if err != nil {
+				return fmt.Errorf("awaiting next credential request: %w", err)
+			}
+			return credReq.Misbehave(ctx, issuer.Account(), m)
+		}()
+	}()
+
+	// Run holder.
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), balance)
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, price, issuer.Address())
+	require.NoError(err, "requesting credential")
+
+	// The credential does not arrive.
+	awaitCtx, awaitCancel := context.WithTimeout(ctx, issuanceTimeout)
+	defer awaitCancel()
+	_, err = asyncCred.Await(awaitCtx)
+	require.ErrorIs(err, context.DeadlineExceeded, "awaiting credential")
+
+	// Misbehaving updates are refused by go-perun before they reach us.
+	if err := <-issuerErr; m == connection.Stall {
+		require.NoError(err, "running issuer")
+	} else {
+		require.Error(err, "running issuer")
+	}
+
+	// The holder recovers its funds via dispute.
+	require.NoError(conn.ForceClose(ctx), "force closing")
+	s := conn.State()
+	require.IsType(&data.Offer{}, s.Data, "settled state")
+	require.Zero(balance.Cmp(s.Balances[app.AssetIdx][conn.Idx()]), "holder balance")
+
+	env.LogAccountBalances()
+}
+
 func runCredentialSwapTest(t *testing.T, honestHolder bool) {
 	require := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
 	t.Cleanup(cancel)
 
 	// Setup test environment.
-	env := test.Setup(t)
+	env := testutil.Setup(t)
+	env.SkipDisputes(ctx)
 	env.LogAccountBalances()
 	wg, errs := sync.WaitGroup{}, make(chan error)
 	wg.Add(2)
 	holder, issuer := env.Holder, env.Issuer
 
 	doc := []byte("Perun/Bosch: SSI Credential Payment")
-	balance := test.EthToWei(big.NewFloat(5))
-	price := test.EthToWei(big.NewFloat(1))
+	balance := env.Amount(5)
+	price := env.Amount(1)
 
 	// Run credential holder.
 	go func() {
@@ -234,3 +324,38 @@ func runCredentialIssuer(
 
 	return nil
 }
+
+// runIssuer runs an issuer that accepts the next connection request and
+// handles the next `n` credential requests on it with `handle`. The returned
+// channel yields the result.
+func runIssuer(
+	ctx context.Context,
+	issuer *client.Client,
+	n int,
+	handle func(*connection.CredentialRequest) error,
+) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- func() error {
+			req, err := issuer.NextConnectionRequest(ctx)
+			if err != nil {
+				return fmt.Errorf("awaiting next connection request: %w", err)
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return fmt.Errorf("accepting connection request: %w", err)
+			}
+			for i := 0; i < n; i++ {
+				credReq, err := conn.NextCredentialRequest(ctx)
+				if err != nil {
+					return fmt.Errorf("awaiting next credential request: %w", err)
+				}
+				if err := handle(credReq); err != nil {
+					return fmt.Errorf("handling credential request %d: %w", i, err)
+				}
+			}
+			return nil
+		}()
+	}()
+	return errs
+}
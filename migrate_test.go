@@ -0,0 +1,90 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+// TestMigrate checks that a channel is reopened with the same balances when
+// it is migrated, and that credentials can be requested in the successor.
+func TestMigrate(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	env.SkipDisputes(ctx)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	events := issuer.Events(ctx)
+
+	issuerErr := runIssuer(ctx, issuer, 1, func(req *connection.CredentialRequest) error {
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, env.Amount(1), issuer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+	balance, peerBalance := conn.Balances()
+
+	// The issuer refuses apps it is not configured with.
+	_, err = holder.Migrate(ctx, conn, common.Address{1})
+	require.Error(err, "unsupported app")
+	require.False(conn.Migrating())
+
+	appAddr := ethwallet.AsEthAddr(conn.Params().App.Def())
+	next, err := holder.Migrate(ctx, conn, appAddr)
+	require.NoError(err, "migrating")
+	require.NotEqual(conn.ID(), next.ID())
+	nextBalance, nextPeerBalance := next.Balances()
+	require.Zero(balance.Cmp(nextBalance), "holder balance")
+	require.Zero(peerBalance.Cmp(nextPeerBalance), "issuer balance")
+	_, err = conn.RequestCredential(ctx, doc, env.Amount(1), issuer.Address())
+	require.ErrorIs(err, connection.ErrMigrating, "migrated channel")
+
+	// The issuer continues in the successor.
+	var issuerConn *connection.Connection
+	for issuerConn == nil {
+		select {
+		case ev := <-events:
+			if migrated, ok := ev.(*connection.ChannelMigrated); ok {
+				require.Equal(conn.ID(), migrated.Channel)
+				require.Equal(next.ID(), migrated.Successor)
+				require.Equal(appAddr, migrated.App)
+				var found bool
+				issuerConn, found = issuer.Connection(migrated.Successor)
+				require.True(found, "successor of issuer")
+			}
+		case <-ctx.Done():
+			t.Fatal("channel not migrated:", ctx.Err())
+		}
+	}
+	issued := make(chan error, 1)
+	go func() {
+		req, err := issuerConn.NextCredentialRequest(ctx)
+		if err != nil {
+			issued <- err
+			return
+		}
+		issued <- req.IssueCredential(ctx, issuer.Account())
+	}()
+	asyncCred, err = next.RequestCredential(ctx, doc, env.Amount(1), issuer.Address())
+	require.NoError(err, "requesting credential in successor")
+	resp, err = asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issued, "issuing in successor")
+	require.NoError(app.VerifyCredential(&app.Credential{Document: doc, Signature: resp.Signature, Domain: resp.Domain()}, issuer.Address(), now(env)))
+}
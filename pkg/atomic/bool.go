@@ -25,3 +25,11 @@ func (a *Bool) Value() bool {
 	defer a.mux.RUnlock()
 	return a.value
 }
+
+// Swap sets the value to `v` and returns the previous value.
+func (a *Bool) Swap(v bool) (old bool) {
+	a.mux.Lock()
+	old, a.value = a.value, v
+	a.mux.Unlock()
+	return
+}
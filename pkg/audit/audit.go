@@ -0,0 +1,191 @@
+// Package audit implements an append-only, hash-chained audit log. Every
+// entry commits to the hash of its predecessor, so that modifying or removing
+// an entry breaks the chain of all later entries. Truncating the log is only
+// detected against a head hash that was recorded elsewhere, see Log.Head.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxEntrySize is the maximum size of an encoded entry.
+const maxEntrySize = 1 << 24
+
+var (
+	ErrBrokenChain  = errors.New("broken hash chain")
+	ErrHeadMismatch = errors.New("head mismatch")
+)
+
+// Entry is an entry of the audit log. Its hash commits to all other fields,
+// including the hash of the previous entry.
+type Entry struct {
+	Seq     uint64          `json:"seq"`
+	Time    time.Time       `json:"time"`
+	Kind    string          `json:"kind"`
+	Subject string          `json:"subject,omitempty"` // E.g., the channel ID.
+	Details json.RawMessage `json:"details,omitempty"`
+	Prev    common.Hash     `json:"prev"`
+	Hash    common.Hash     `json:"hash"`
+}
+
+// computeHash returns the hash of `e`, which is the hash of its JSON encoding
+// without the hash.
+func (e *Entry) computeHash() (common.Hash, error) {
+	body := *e
+	body.Hash = common.Hash{}
+	b, err := json.Marshal(&body)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(b), nil
+}
+
+// Log is an audit log in a file with one JSON encoded entry per line.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	seq  uint64
+	head common.Hash
+}
+
+// Open opens the audit log at `path`, which is created if it does not exist.
+// The entries of an existing log are verified, and new entries continue its
+// chain.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening log: %w", err)
+	}
+	n, head, err := Verify(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("verifying log: %w", err)
+	}
+	return &Log{path: path, f: f, seq: n, head: head}, nil
+}
+
+// Append appends an entry of kind `kind` about `subject` to the log. The
+// details are stored in their JSON encoding.
+func (l *Log) Append(kind, subject string, details interface{}) error {
+	var raw json.RawMessage
+	if details != nil {
+		b, err := json.Marshal(details)
+		if err != nil {
+			return fmt.Errorf("encoding details: %w", err)
+		}
+		raw = b
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := &Entry{
+		Seq:     l.seq,
+		Time:    time.Now().UTC(),
+		Kind:    kind,
+		Subject: subject,
+		Details: raw,
+		Prev:    l.head,
+	}
+	h, err := e.computeHash()
+	if err != nil {
+		return err
+	}
+	e.Hash = h
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing entry: %w", err)
+	}
+	l.seq++
+	l.head = h
+	return nil
+}
+
+// Head returns the number of entries and the hash of the last entry. Recording
+// the head elsewhere, e.g., on-chain, makes truncations of the log evident.
+func (l *Log) Head() (uint64, common.Hash) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq, l.head
+}
+
+// Export writes the log to `w` in the format read by Verify.
+func (l *Log) Export(w io.Writer) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	return l.f.Close()
+}
+
+// Verify verifies the hash chain of the log read from `r` and returns the
+// number of entries and the hash of the last entry.
+func Verify(r io.Reader) (n uint64, head common.Hash, err error) {
+	return scan(r, nil)
+}
+
+// VerifyHead verifies the log read from `r` and checks that it contains the
+// head `n`, `head` recorded by Log.Head.
+func VerifyHead(r io.Reader, n uint64, head common.Hash) error {
+	total, _, err := scan(r, func(e *Entry) error {
+		if e.Seq+1 == n && e.Hash != head {
+			return fmt.Errorf("%w at entry %d", ErrHeadMismatch, e.Seq)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	} else if total < n {
+		return fmt.Errorf("%w: log has %d of %d entries", ErrHeadMismatch, total, n)
+	}
+	return nil
+}
+
+// scan verifies the hash chain of the log read from `r` and calls `fn`, if
+// non-nil, with every verified entry.
+func scan(r io.Reader, fn func(*Entry) error) (n uint64, head common.Hash, err error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxEntrySize)
+	for s.Scan() {
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return 0, common.Hash{}, fmt.Errorf("decoding entry %d: %w", n, err)
+		}
+		h, err := e.computeHash()
+		if err != nil {
+			return 0, common.Hash{}, err
+		}
+		if e.Seq != n || e.Prev != head || e.Hash != h {
+			return 0, common.Hash{}, fmt.Errorf("%w at entry %d", ErrBrokenChain, n)
+		}
+		if fn != nil {
+			if err := fn(&e); err != nil {
+				return 0, common.Hash{}, err
+			}
+		}
+		n, head = n+1, h
+	}
+	return n, head, s.Err()
+}
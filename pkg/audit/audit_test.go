@@ -0,0 +1,100 @@
+package audit_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/pkg/audit"
+	"github.com/stretchr/testify/require"
+)
+
+// newLog returns a log with three entries.
+func newLog(t *testing.T) (*audit.Log, string) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := audit.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	require.NoError(t, l.Append("channel_opened", "0x01", map[string]int{"balance": 10}))
+	require.NoError(t, l.Append("credential_issued", "0x01", nil))
+	require.NoError(t, l.Append("channel_closed", "0x01", nil))
+	return l, path
+}
+
+func export(t *testing.T, l *audit.Log) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, l.Export(&buf))
+	return buf.Bytes()
+}
+
+func TestAppendVerify(t *testing.T) {
+	l, path := newLog(t)
+	n, head := l.Head()
+	require.Equal(t, uint64(3), n)
+
+	gotN, gotHead, err := audit.Verify(bytes.NewReader(export(t, l)))
+	require.NoError(t, err)
+	require.Equal(t, n, gotN)
+	require.Equal(t, head, gotHead)
+	require.NoError(t, audit.VerifyHead(bytes.NewReader(export(t, l)), n, head))
+
+	// Reopening continues the chain.
+	require.NoError(t, l.Close())
+	l, err = audit.Open(path)
+	require.NoError(t, err)
+	defer l.Close()
+	gotN, gotHead = l.Head()
+	require.Equal(t, n, gotN)
+	require.Equal(t, head, gotHead)
+	require.NoError(t, l.Append("channel_opened", "0x02", nil))
+	n, _, err = audit.Verify(bytes.NewReader(export(t, l)))
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), n)
+}
+
+func TestTampering(t *testing.T) {
+	l, _ := newLog(t)
+	log := string(export(t, l))
+	lines := strings.SplitAfter(log, "\n")
+
+	tests := map[string]string{
+		"modified entry":  strings.Replace(log, `"balance":10`, `"balance":99`, 1),
+		"removed entry":   lines[0] + lines[2],
+		"swapped entries": lines[1] + lines[0] + lines[2],
+	}
+	for name, tampered := range tests {
+		_, _, err := audit.Verify(strings.NewReader(tampered))
+		require.ErrorIs(t, err, audit.ErrBrokenChain, name)
+	}
+
+	_, _, err := audit.Verify(strings.NewReader("not json\n"))
+	require.Error(t, err)
+}
+
+func TestTruncation(t *testing.T) {
+	l, _ := newLog(t)
+	n, head := l.Head()
+	lines := strings.SplitAfter(string(export(t, l)), "\n")
+
+	// A truncated log has a valid chain, but not the recorded head.
+	truncated := lines[0] + lines[1]
+	_, _, err := audit.Verify(strings.NewReader(truncated))
+	require.NoError(t, err)
+	require.ErrorIs(t, audit.VerifyHead(strings.NewReader(truncated), n, head), audit.ErrHeadMismatch)
+	require.ErrorIs(t, audit.VerifyHead(bytes.NewReader(export(t, l)), n, common.Hash{1}), audit.ErrHeadMismatch)
+}
+
+func TestOpenBroken(t *testing.T) {
+	l, path := newLog(t)
+	require.NoError(t, l.Close())
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(b), "\n")
+	require.NoError(t, os.WriteFile(path, []byte(lines[1]+lines[2]), 0o600))
+
+	_, err = audit.Open(path)
+	require.ErrorIs(t, err, audit.ErrBrokenChain)
+}
@@ -0,0 +1,150 @@
+// Package clock abstracts the passing of time, so that tests can control it
+// with a Fake clock instead of sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Clock is a source of time.
+	Clock interface {
+		Now() time.Time
+		// After returns a channel on which the time is sent after `d`.
+		After(d time.Duration) <-chan time.Time
+		NewTicker(d time.Duration) Ticker
+	}
+
+	// Ticker sends the time on C in intervals until it is stopped.
+	Ticker interface {
+		C() <-chan time.Time
+		Stop()
+	}
+)
+
+// System returns the clock of the system.
+func System() Clock {
+	return systemClock{}
+}
+
+type (
+	systemClock  struct{}
+	systemTicker struct{ *time.Ticker }
+)
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// Fake is a clock that only passes time when it is advanced.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter is a pending After or Ticker of a fake clock. Tickers have a
+// non-zero period.
+type waiter struct {
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+type fakeTicker struct {
+	clock *Fake
+	w     *waiter
+}
+
+// NewFake returns a fake clock at time `now`.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.add(d, 0).c
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return &fakeTicker{clock: f, w: f.add(d, d)}
+}
+
+func (f *Fake) add(d, period time.Duration) *waiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{at: f.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- f.now
+		return w
+	}
+	f.waiters = append(f.waiters, w)
+	return w
+}
+
+// Advance advances the clock by `d` and fires the waiters that are due. Like
+// the tickers of package time, a ticker drops ticks for slow receivers.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.at.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		if w.period > 0 {
+			for !w.at.After(f.now) {
+				w.at = w.at.Add(w.period)
+			}
+			pending = append(pending, w)
+		}
+	}
+	f.waiters = pending
+}
+
+func (f *Fake) remove(w *waiter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, o := range f.waiters {
+		if o == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.w.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.remove(t.w)
+}
@@ -0,0 +1,40 @@
+package devchain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Anvil runs Anvil of Foundry.
+type Anvil struct{}
+
+func (Anvil) Cmd() string {
+	return "anvil"
+}
+
+func (Anvil) Args(cfg Config, _ []Account) []string {
+	args := []string{"--host", cfg.Host, "--port", fmt.Sprint(cfg.Port), "--chain-id", cfg.ChainID.String()}
+	// Without a block time, Anvil mines a block per transaction.
+	if s := int(cfg.BlockTime.Seconds()); s > 0 {
+		args = append(args, "--block-time", fmt.Sprint(s))
+	}
+	return args
+}
+
+func (Anvil) Ready(line string) bool {
+	return strings.HasPrefix(line, "Listening on")
+}
+
+// Setup sets the balances of the accounts, as Anvil only prefunds the
+// accounts of its mnemonic.
+func (Anvil) Setup(ctx context.Context, cfg Config, accounts []Account) error {
+	client, err := rpc.DialContext(ctx, cfg.NodeURL())
+	if err != nil {
+		return fmt.Errorf("dialing: %w", err)
+	}
+	defer client.Close()
+	return setBalances(ctx, client, "anvil_setBalance", accounts)
+}
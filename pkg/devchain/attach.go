@@ -0,0 +1,109 @@
+package devchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// transferGasLimit is the gas limit of transfers from the faucet.
+const transferGasLimit = 21000
+
+// Node is an externally managed node, e.g., of a long-lived devnet.
+type Node struct {
+	URL      string
+	ChainID  *big.Int
+	Accounts []Account
+}
+
+// Attach attaches to the node at `url`. If `faucet` is not nil, the accounts
+// of `funding` whose balance is below their funding are topped up from the
+// account of `faucet`. Otherwise, they must be funded already.
+func Attach(ctx context.Context, url string, faucet *ecdsa.PrivateKey, funding []KeyWithBalance) (*Node, error) {
+	accounts, err := parseAccounts(funding)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("dialing: %w", err)
+	}
+	defer client.Close()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting chain ID: %w", err)
+	}
+
+	if faucet != nil {
+		if err := Fund(ctx, client, chainID, faucet, accounts); err != nil {
+			return nil, err
+		}
+	}
+	return &Node{URL: url, ChainID: chainID, Accounts: accounts}, nil
+}
+
+// FundingBackend is the chain access needed for funding accounts.
+type FundingBackend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// Fund tops up the balances of the accounts to their amounts from the
+// account of `faucet`.
+func Fund(ctx context.Context, client FundingBackend, chainID *big.Int, faucet *ecdsa.PrivateKey, accounts []Account) error {
+	from := crypto.PubkeyToAddress(faucet.PublicKey)
+	nonce, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return fmt.Errorf("getting faucet nonce: %w", err)
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return fmt.Errorf("getting gas price: %w", err)
+	}
+	signer := types.LatestSignerForChainID(chainID)
+
+	var txs []*types.Transaction
+	for _, a := range accounts {
+		to := a.Address()
+		balance, err := client.BalanceAt(ctx, to, nil)
+		if err != nil {
+			return fmt.Errorf("getting balance of %v: %w", to, err)
+		} else if balance.Cmp(a.Amount) >= 0 {
+			continue
+		}
+
+		tx, err := types.SignNewTx(faucet, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: gasPrice,
+			Gas:      transferGasLimit,
+			To:       &to,
+			Value:    new(big.Int).Sub(a.Amount, balance),
+		})
+		if err != nil {
+			return fmt.Errorf("signing transfer: %w", err)
+		} else if err := client.SendTransaction(ctx, tx); err != nil {
+			return fmt.Errorf("funding %v: %w", to, err)
+		}
+		txs = append(txs, tx)
+		nonce++
+	}
+
+	for _, tx := range txs {
+		r, err := bind.WaitMined(ctx, client, tx)
+		if err != nil {
+			return fmt.Errorf("waiting for transfer %v: %w", tx.Hash(), err)
+		} else if r.Status != types.ReceiptStatusSuccessful {
+			return fmt.Errorf("transfer %v failed", tx.Hash())
+		}
+	}
+	return nil
+}
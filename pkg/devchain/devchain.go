@@ -0,0 +1,325 @@
+// Package devchain runs local development chains, such as ganache-cli,
+// Anvil, and Hardhat Network, with prefunded accounts.
+package devchain
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+// healthCheckInterval is the interval at which a starting chain is checked
+// for answering requests.
+const healthCheckInterval = 100 * time.Millisecond
+
+type Config struct {
+	Driver        Driver
+	Cmd           string // Optional. Defaults to the command of the driver.
+	Host          string
+	Port          uint
+	BlockTime     time.Duration
+	Funding       []KeyWithBalance
+	StartupTime   time.Duration // The maximum time until the chain accepts connections.
+	ChainID       *big.Int
+	PrintToStdOut bool
+	Docker        *Docker // Optional. Runs the chain in a Docker container.
+}
+
+// Docker configures running a chain in a Docker container. The container
+// publishes the port of the chain on the host and is removed on shutdown.
+type Docker struct {
+	Image string
+	// Cmd is run in the container instead of the entrypoint of the image.
+	// Optional. Only the arguments are passed to the entrypoint otherwise.
+	Cmd string
+}
+
+// Driver adapts Start to the command line and the output of a chain
+// implementation.
+type Driver interface {
+	// Cmd returns the default command that runs the chain.
+	Cmd() string
+	// Args returns the command line arguments that start the chain.
+	Args(cfg Config, accounts []Account) []string
+	// Ready reports whether output line `line` signals that the chain
+	// accepts connections.
+	Ready(line string) bool
+	// Setup prepares the chain once it accepts connections, e.g., funds the
+	// accounts if they were not funded by the command line arguments.
+	Setup(ctx context.Context, cfg Config, accounts []Account) error
+}
+
+type Chain struct {
+	Accounts  []Account
+	Cmd       *exec.Cmd
+	nodeURL   string
+	container string // Empty if the chain does not run in a container.
+}
+
+type Account struct {
+	PrivateKey *ecdsa.PrivateKey
+	Amount     *big.Int
+}
+
+type KeyWithBalance struct {
+	PrivateKey string
+	BalanceEth uint
+}
+
+// Start starts the chain of `cfg.Driver` and funds the accounts of
+// `cfg.Funding`.
+func Start(cfg Config) (chain *Chain, err error) {
+	// Create accounts
+	accounts, err := parseAccounts(cfg.Funding)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start command
+	var cmd *exec.Cmd
+	var container string
+	if cfg.Docker != nil {
+		cmd, container = dockerCmd(cfg, accounts)
+	} else {
+		cmdLine := cfg.Cmd
+		if cmdLine == "" {
+			cmdLine = cfg.Driver.Cmd()
+		}
+		cmdTokens := strings.Split(cmdLine, " ")
+		cmdName := cmdTokens[0]
+		var cmdArgs []string
+		cmdArgs = append(cmdArgs, cmdTokens[1:]...)
+		cmdArgs = append(cmdArgs, cfg.Driver.Args(cfg, accounts)...)
+		cmd = exec.Command(cmdName, cmdArgs...)
+	}
+
+	// This is needed for correctly shutting down the chain and its child
+	// processes.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.WithMessage(err, "creating output pipe")
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.WithMessage(err, "starting chain")
+	}
+	chain = &Chain{accounts, cmd, cfg.NodeURL(), container}
+
+	// Wait until the chain signals that it accepts connections.
+	ready := make(chan struct{})
+	go func() {
+		rd := bufio.NewReader(stdout)
+		signaled := false
+		for {
+			str, err := rd.ReadString('\n')
+			if err != nil {
+				if cfg.PrintToStdOut {
+					log.Print("Failed to read chain output:", err)
+				}
+				return
+			}
+			if cfg.PrintToStdOut {
+				log.Print(str)
+			}
+			if !signaled && cfg.Driver.Ready(str) {
+				close(ready)
+				signaled = true
+			}
+		}
+	}()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- cmd.Wait()
+	}()
+	select {
+	case err = <-errChan:
+		if err == nil {
+			err = errors.New("chain exited")
+		}
+		return nil, err
+	case <-time.After(cfg.StartupTime):
+		chain.Shutdown()
+		return nil, fmt.Errorf("chain not ready after %v", cfg.StartupTime)
+	case <-ready:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.StartupTime)
+	defer cancel()
+	if err := waitHealthy(ctx, cfg.NodeURL()); err != nil {
+		chain.Shutdown()
+		return nil, errors.WithMessage(err, "checking health")
+	}
+	if err := cfg.Driver.Setup(ctx, cfg, accounts); err != nil {
+		chain.Shutdown()
+		return nil, errors.WithMessage(err, "setting up chain")
+	}
+	return chain, nil
+}
+
+// Snapshot takes a snapshot of the state of the chain and returns its ID.
+func (c *Chain) Snapshot(ctx context.Context) (string, error) {
+	client, err := rpc.DialContext(ctx, c.nodeURL)
+	if err != nil {
+		return "", fmt.Errorf("dialing: %w", err)
+	}
+	defer client.Close()
+
+	var id string
+	if err := client.CallContext(ctx, &id, "evm_snapshot"); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Revert reverts the chain to snapshot `id`. The snapshot and all snapshots
+// taken after it are deleted.
+func (c *Chain) Revert(ctx context.Context, id string) error {
+	client, err := rpc.DialContext(ctx, c.nodeURL)
+	if err != nil {
+		return fmt.Errorf("dialing: %w", err)
+	}
+	defer client.Close()
+
+	var reverted bool
+	if err := client.CallContext(ctx, &reverted, "evm_revert", id); err != nil {
+		return err
+	} else if !reverted {
+		return fmt.Errorf("snapshot %s not found", id)
+	}
+	return nil
+}
+
+// IncreaseTime increases the time of the chain by `d` and mines a block with
+// the new time, so that timeouts on the chain pass without waiting.
+func (c *Chain) IncreaseTime(ctx context.Context, d time.Duration) error {
+	client, err := rpc.DialContext(ctx, c.nodeURL)
+	if err != nil {
+		return fmt.Errorf("dialing: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.CallContext(ctx, nil, "evm_increaseTime", int64(d.Seconds())); err != nil {
+		return fmt.Errorf("increasing time: %w", err)
+	}
+	if err := client.CallContext(ctx, nil, "evm_mine"); err != nil {
+		return fmt.Errorf("mining block: %w", err)
+	}
+	return nil
+}
+
+// dockerCmd returns the command that runs the chain in a new container, and
+// the name of the container. The chain listens on all interfaces of the
+// container.
+func dockerCmd(cfg Config, accounts []Account) (*exec.Cmd, string) {
+	container := fmt.Sprintf("devchain-%d-%d", os.Getpid(), cfg.Port)
+	port := fmt.Sprint(cfg.Port)
+	args := []string{"run", "--rm", "--name", container, "-p", cfg.Host + ":" + port + ":" + port}
+	var cmdTokens []string
+	if cfg.Docker.Cmd != "" {
+		cmdTokens = strings.Split(cfg.Docker.Cmd, " ")
+		args = append(args, "--entrypoint", cmdTokens[0])
+	}
+	args = append(args, cfg.Docker.Image)
+	if len(cmdTokens) > 0 {
+		args = append(args, cmdTokens[1:]...)
+	}
+	inner := cfg
+	inner.Host = "0.0.0.0"
+	args = append(args, cfg.Driver.Args(inner, accounts)...)
+	return exec.Command("docker", args...), container
+}
+
+// waitHealthy waits until the node at `nodeURL` answers requests.
+func waitHealthy(ctx context.Context, nodeURL string) error {
+	for {
+		err := checkHealth(ctx, nodeURL)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(healthCheckInterval):
+		}
+	}
+}
+
+func checkHealth(ctx context.Context, nodeURL string) error {
+	client, err := rpc.DialContext(ctx, nodeURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var chainID hexutil.Big
+	return client.CallContext(ctx, &chainID, "eth_chainId")
+}
+
+func (c *Chain) Shutdown() error {
+	// Running Process.Kill() does not kill child processes.
+	// The below kills the process group referenced by the negative process ID
+	// and therefore correctly shuts down the chain.
+	// May only work on unix-like systems.
+	err := syscall.Kill(-c.Cmd.Process.Pid, syscall.SIGKILL)
+
+	// Killing the Docker client does not stop the container.
+	if c.container != "" {
+		if out, rmErr := exec.Command("docker", "rm", "-f", c.container).CombinedOutput(); rmErr != nil && err == nil {
+			err = fmt.Errorf("removing container: %v: %s", rmErr, out)
+		}
+	}
+	return err
+}
+
+// parseAccounts returns the accounts of `funding`.
+func parseAccounts(funding []KeyWithBalance) ([]Account, error) {
+	accounts := make([]Account, len(funding))
+	for i, f := range funding {
+		accountKey, err := crypto.HexToECDSA(f.PrivateKey[2:])
+		if err != nil {
+			return nil, errors.WithMessage(err, "parsing private key")
+		}
+		accounts[i] = Account{PrivateKey: accountKey, Amount: ethToWei(big.NewFloat(float64(f.BalanceEth)))}
+	}
+	return accounts, nil
+}
+
+func ethToWei(eth *big.Float) (wei *big.Int) {
+	var weiPerEth = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	var weiPerEthFloat = new(big.Float).SetInt(weiPerEth)
+	wei, _ = new(big.Float).Mul(eth, weiPerEthFloat).Int(nil)
+	return
+}
+
+func (a *Account) Address() common.Address {
+	return crypto.PubkeyToAddress(a.PrivateKey.PublicKey)
+}
+
+// setBalances sets the balances of the accounts with RPC method `method`.
+func setBalances(ctx context.Context, client *rpc.Client, method string, accounts []Account) error {
+	for _, a := range accounts {
+		err := client.CallContext(ctx, nil, method, a.Address(), (*hexutil.Big)(a.Amount))
+		if err != nil {
+			return fmt.Errorf("setting balance of %v: %w", a.Address(), err)
+		}
+	}
+	return nil
+}
+
+func (cfg Config) NodeURL() string {
+	return fmt.Sprintf("ws://%s:%d", cfg.Host, cfg.Port)
+}
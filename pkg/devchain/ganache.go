@@ -0,0 +1,38 @@
+package devchain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Ganache runs ganache-cli.
+type Ganache struct{}
+
+func (Ganache) Cmd() string {
+	return "ganache-cli"
+}
+
+func (Ganache) Args(cfg Config, accounts []Account) []string {
+	var args []string
+	args = append(args, "ganache-cli", "--host", cfg.Host, "--port", fmt.Sprint(cfg.Port))
+	for _, a := range accounts {
+		key := hexutil.Encode(crypto.FromECDSA(a.PrivateKey))
+		args = append(args, "--account", fmt.Sprintf("%v,%v", key, a.Amount))
+	}
+	args = append(args, fmt.Sprintf("--blockTime=%v", int(cfg.BlockTime.Seconds())))
+	args = append(args, fmt.Sprintf("--chainId=%d", cfg.ChainID.Uint64()))
+	return args
+}
+
+func (Ganache) Ready(line string) bool {
+	return strings.Contains(line, "Listening on")
+}
+
+// Setup does nothing, as ganache-cli is set up by its arguments.
+func (Ganache) Setup(context.Context, Config, []Account) error {
+	return nil
+}
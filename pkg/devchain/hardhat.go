@@ -0,0 +1,57 @@
+package devchain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Hardhat runs Hardhat Network. Its chain ID is set in the Hardhat
+// configuration and must equal the configured chain ID.
+type Hardhat struct{}
+
+func (Hardhat) Cmd() string {
+	return "npx hardhat node"
+}
+
+func (Hardhat) Args(cfg Config, _ []Account) []string {
+	return []string{"--hostname", cfg.Host, "--port", fmt.Sprint(cfg.Port)}
+}
+
+func (Hardhat) Ready(line string) bool {
+	return strings.HasPrefix(line, "Started HTTP and WebSocket JSON-RPC server")
+}
+
+// Setup checks the chain ID, sets the balances of the accounts, and switches
+// from automining to interval mining if a block time is configured.
+func (Hardhat) Setup(ctx context.Context, cfg Config, accounts []Account) error {
+	client, err := rpc.DialContext(ctx, cfg.NodeURL())
+	if err != nil {
+		return fmt.Errorf("dialing: %w", err)
+	}
+	defer client.Close()
+
+	var chainID hexutil.Big
+	if err := client.CallContext(ctx, &chainID, "eth_chainId"); err != nil {
+		return fmt.Errorf("getting chain ID: %w", err)
+	} else if chainID.ToInt().Cmp(cfg.ChainID) != 0 {
+		return fmt.Errorf("chain ID %v, expected %v", chainID.ToInt(), cfg.ChainID)
+	}
+
+	if err := setBalances(ctx, client, "hardhat_setBalance", accounts); err != nil {
+		return err
+	}
+
+	if cfg.BlockTime > 0 {
+		if err := client.CallContext(ctx, nil, "evm_setAutomine", false); err != nil {
+			return fmt.Errorf("disabling automining: %w", err)
+		}
+		if err := client.CallContext(ctx, nil, "evm_setIntervalMining", cfg.BlockTime.Milliseconds()); err != nil {
+			return fmt.Errorf("enabling interval mining: %w", err)
+		}
+	}
+	return nil
+}
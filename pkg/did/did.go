@@ -0,0 +1,195 @@
+// Package did parses and resolves decentralized identifiers of the methods
+// did:ethr, did:key, and did:web.
+package did
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+	"github.com/perun-network/perun-credential-payment/pkg/multibase"
+)
+
+const (
+	prefix = "did:"
+
+	// Verification method types.
+	TypeSecp256k1Recovery = "EcdsaSecp256k1RecoveryMethod2020"
+	TypeSecp256k1         = "EcdsaSecp256k1VerificationKey2019"
+	TypeMultikey          = "Multikey"
+	TypeJSONWebKey        = "JsonWebKey2020"
+)
+
+// Multicodec prefixes of public keys in did:key DIDs.
+var (
+	codecEd25519   = []byte{0xed, 0x01}
+	codecSecp256k1 = []byte{0xe7, 0x01}
+)
+
+var (
+	ErrInvalidDID = errors.New("invalid DID")
+	ErrNoKey      = errors.New("no matching verification key")
+)
+
+// DID is a parsed decentralized identifier.
+type DID struct {
+	Method string
+	ID     string // Method-specific identifier.
+}
+
+// Parse parses DID `s`. Fragments and paths are not supported.
+func Parse(s string) (DID, error) {
+	parts := strings.SplitN(strings.TrimPrefix(s, prefix), ":", 2)
+	if !strings.HasPrefix(s, prefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.ContainsAny(s, "#/?") {
+		return DID{}, fmt.Errorf("%w: %s", ErrInvalidDID, s)
+	}
+	return DID{Method: parts[0], ID: parts[1]}, nil
+}
+
+func (d DID) String() string {
+	return prefix + d.Method + ":" + d.ID
+}
+
+// Ethr returns the did:ethr DID of address `addr`.
+func Ethr(addr common.Address) string {
+	return DID{Method: "ethr", ID: addr.Hex()}.String()
+}
+
+// KeyEd25519 returns the did:key DID of Ed25519 public key `pub`.
+func KeyEd25519(pub ed25519.PublicKey) string {
+	return DID{Method: "key", ID: multibase.Encode(append(append([]byte(nil), codecEd25519...), pub...))}.String()
+}
+
+// Document is a DID document.
+type Document struct {
+	Context            interface{}          `json:"@context,omitempty"`
+	ID                 string               `json:"id"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod,omitempty"`
+	// AssertionMethod references the verification methods that may issue
+	// credentials. Embedded verification methods are not supported.
+	AssertionMethod []string `json:"assertionMethod,omitempty"`
+	Authentication  []string `json:"authentication,omitempty"`
+}
+
+// VerificationMethod is a verification method of a DID document.
+type VerificationMethod struct {
+	ID                  string `json:"id"`
+	Type                string `json:"type"`
+	Controller          string `json:"controller"`
+	BlockchainAccountID string `json:"blockchainAccountId,omitempty"`
+	PublicKeyMultibase  string `json:"publicKeyMultibase,omitempty"`
+	PublicKeyJwk        *JWK   `json:"publicKeyJwk,omitempty"`
+}
+
+// JWK is an elliptic-curve JSON Web Key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+}
+
+// EthereumAddress returns the Ethereum address of the verification method.
+func (m *VerificationMethod) EthereumAddress() (common.Address, error) {
+	switch {
+	case m.BlockchainAccountID != "":
+		// CAIP-10 account ID, e.g., eip155:1:0xab16a96D359eC26a11e2C2b3d8f8B8942d5Bfcdb.
+		parts := strings.Split(m.BlockchainAccountID, ":")
+		if len(parts) != 3 || parts[0] != "eip155" || !common.IsHexAddress(parts[2]) {
+			return common.Address{}, fmt.Errorf("unsupported account ID: %s", m.BlockchainAccountID)
+		}
+		return common.HexToAddress(parts[2]), nil
+
+	case m.PublicKeyMultibase != "":
+		key, err := multibase.Decode(m.PublicKeyMultibase)
+		if err != nil {
+			return common.Address{}, err
+		}
+		return secp256k1Address(key)
+
+	case m.PublicKeyJwk != nil && m.PublicKeyJwk.Kty == "EC" && m.PublicKeyJwk.Crv == "secp256k1":
+		x, err := jws.Decode(m.PublicKeyJwk.X)
+		if err != nil {
+			return common.Address{}, err
+		}
+		y, err := jws.Decode(m.PublicKeyJwk.Y)
+		if err != nil {
+			return common.Address{}, err
+		}
+		pub, err := crypto.UnmarshalPubkey(append([]byte{4}, append(pad32(x), pad32(y)...)...))
+		if err != nil {
+			return common.Address{}, err
+		}
+		return crypto.PubkeyToAddress(*pub), nil
+	}
+	return common.Address{}, ErrNoKey
+}
+
+// Ed25519Key returns the Ed25519 public key of the verification method.
+func (m *VerificationMethod) Ed25519Key() (ed25519.PublicKey, error) {
+	switch {
+	case m.PublicKeyMultibase != "":
+		key, err := multibase.Decode(m.PublicKeyMultibase)
+		if err != nil {
+			return nil, err
+		} else if len(key) != len(codecEd25519)+ed25519.PublicKeySize || string(key[:2]) != string(codecEd25519) {
+			return nil, ErrNoKey
+		}
+		return ed25519.PublicKey(key[2:]), nil
+
+	case m.PublicKeyJwk != nil && m.PublicKeyJwk.Kty == "OKP" && m.PublicKeyJwk.Crv == "Ed25519":
+		key, err := jws.Decode(m.PublicKeyJwk.X)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, ErrNoKey
+		}
+		return ed25519.PublicKey(key), nil
+	}
+	return nil, ErrNoKey
+}
+
+// Method returns the verification method with ID `id`. Relative IDs are
+// resolved against the document ID.
+func (d *Document) Method(id string) (*VerificationMethod, error) {
+	if strings.HasPrefix(id, "#") {
+		id = d.ID + id
+	}
+	for i := range d.VerificationMethod {
+		m := &d.VerificationMethod[i]
+		if m.ID == id || (strings.HasPrefix(m.ID, "#") && d.ID+m.ID == id) {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown verification method: %s", id)
+}
+
+// Controls returns whether any verification method of the document has
+// Ethereum address `addr`.
+func (d *Document) Controls(addr common.Address) bool {
+	for i := range d.VerificationMethod {
+		if a, err := d.VerificationMethod[i].EthereumAddress(); err == nil && a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// secp256k1Address returns the address of a multicodec secp256k1 public key.
+func secp256k1Address(key []byte) (common.Address, error) {
+	if len(key) != len(codecSecp256k1)+33 || string(key[:2]) != string(codecSecp256k1) {
+		return common.Address{}, ErrNoKey
+	}
+	pub, err := crypto.DecompressPubkey(key[2:])
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+func pad32(b []byte) []byte {
+	return new(big.Int).SetBytes(b).FillBytes(make([]byte, 32))
+}
@@ -0,0 +1,168 @@
+package did_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	d, err := did.Parse("did:web:example.com:user:alice")
+	require.NoError(t, err)
+	require.Equal(t, did.DID{Method: "web", ID: "example.com:user:alice"}, d)
+	require.Equal(t, "did:web:example.com:user:alice", d.String())
+
+	for _, s := range []string{"", "did:", "did:web", "did::x", "did:web:", "web:example.com", "did:web:example.com#key-1", "did:web:example.com/path"} {
+		_, err := did.Parse(s)
+		require.ErrorIs(t, err, did.ErrInvalidDID, s)
+	}
+}
+
+// TestEthr checks the examples of the did:ethr method specification.
+func TestEthr(t *testing.T) {
+	addr := common.HexToAddress("0xb9c5714089478a327f09197987f16f9e5d936e8a")
+	tests := []struct {
+		did     string
+		chainID string
+	}{
+		{"did:ethr:0xb9c5714089478a327f09197987f16f9e5d936e8a", "1"},
+		{"did:ethr:mainnet:0xb9c5714089478a327f09197987f16f9e5d936e8a", "1"},
+		{"did:ethr:goerli:0xb9c5714089478a327f09197987f16f9e5d936e8a", "5"},
+		{"did:ethr:0x5:0xb9c5714089478a327f09197987f16f9e5d936e8a", "5"},
+	}
+	for _, tt := range tests {
+		doc, err := did.EthrResolver{}.Resolve(context.Background(), tt.did)
+		require.NoError(t, err, tt.did)
+		require.Equal(t, tt.did, doc.ID)
+		require.Len(t, doc.VerificationMethod, 1)
+		vm := doc.VerificationMethod[0]
+		require.Equal(t, tt.did+"#controller", vm.ID)
+		require.Equal(t, did.TypeSecp256k1Recovery, vm.Type)
+		require.Equal(t, "eip155:"+tt.chainID+":"+addr.Hex(), vm.BlockchainAccountID)
+		require.Equal(t, []string{vm.ID}, doc.AssertionMethod)
+		require.True(t, doc.Controls(addr))
+	}
+
+	require.Equal(t, "did:ethr:"+addr.Hex(), did.Ethr(addr))
+
+	_, err := did.EthrResolver{}.Resolve(context.Background(), "did:ethr:unknown:"+addr.Hex())
+	require.Error(t, err)
+	_, err = did.EthrResolver{}.Resolve(context.Background(), "did:ethr:0x1234")
+	require.ErrorIs(t, err, did.ErrInvalidDID)
+	_, err = did.EthrResolver{}.Resolve(context.Background(), "did:key:"+addr.Hex())
+	require.ErrorIs(t, err, did.ErrInvalidDID)
+}
+
+func TestEthrPublicKey(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	id := "did:ethr:" + hexutil.Encode(crypto.CompressPubkey(&key.PublicKey))
+	addr, err := did.ResolveAddress(context.Background(), did.NewResolver(nil), id)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), addr)
+}
+
+// TestKey checks the examples of the did:key method specification.
+func TestKey(t *testing.T) {
+	const id = "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"
+	doc, err := did.KeyResolver{}.Resolve(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, id, doc.ID)
+	vm, err := doc.Method("#z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK")
+	require.NoError(t, err)
+	require.Equal(t, id+"#z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK", vm.ID)
+	pub, err := vm.Ed25519Key()
+	require.NoError(t, err)
+	require.Equal(t, id, did.KeyEd25519(pub))
+
+	_, err = did.KeyResolver{}.Resolve(context.Background(), "did:key:z6Mk")
+	require.Error(t, err)
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	id := did.KeyEd25519(pub)
+	require.True(t, strings.HasPrefix(id, "did:key:z6Mk"), "Ed25519 did:key DIDs start with z6Mk")
+
+	doc, err := did.NewResolver(nil).Resolve(context.Background(), id)
+	require.NoError(t, err)
+	got, err := doc.VerificationMethod[0].Ed25519Key()
+	require.NoError(t, err)
+	require.Equal(t, pub, got)
+	_, err = doc.VerificationMethod[0].EthereumAddress()
+	require.ErrorIs(t, err, did.ErrNoKey)
+}
+
+// TestWebURL checks the examples of the did:web method specification.
+func TestWebURL(t *testing.T) {
+	tests := []struct {
+		did, url string
+	}{
+		{"did:web:w3c-ccg.github.io", "https://w3c-ccg.github.io/.well-known/did.json"},
+		{"did:web:w3c-ccg.github.io:user:alice", "https://w3c-ccg.github.io/user/alice/did.json"},
+		{"did:web:example.com%3A3000:user:alice", "https://example.com:3000/user/alice/did.json"},
+	}
+	for _, tt := range tests {
+		u, err := did.WebURL(tt.did)
+		require.NoError(t, err, tt.did)
+		require.Equal(t, tt.url, u)
+	}
+
+	_, err := did.WebURL("did:ethr:0xb9c5714089478a327f09197987f16f9e5d936e8a")
+	require.ErrorIs(t, err, did.ErrInvalidDID)
+}
+
+func TestWebResolver(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	var id string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/issuer/did.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(did.Document{
+			ID: id,
+			VerificationMethod: []did.VerificationMethod{{
+				ID:                  id + "#owner",
+				Type:                did.TypeSecp256k1Recovery,
+				Controller:          id,
+				BlockchainAccountID: fmt.Sprintf("eip155:1:%s", addr.Hex()),
+			}},
+		})
+	}))
+	defer srv.Close()
+	// The port is percent-encoded in did:web DIDs.
+	host := strings.ReplaceAll(strings.TrimPrefix(srv.URL, "https://"), ":", "%3A")
+	id = "did:web:" + host + ":issuer"
+	r := &did.WebResolver{Client: srv.Client()}
+
+	doc, err := r.Resolve(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, doc.Controls(addr))
+	vm, err := doc.Method("#owner")
+	require.NoError(t, err)
+	require.Equal(t, id+"#owner", vm.ID)
+
+	_, err = r.Resolve(context.Background(), "did:web:"+host+":other")
+	require.Error(t, err, "missing document")
+
+	// The document must have the resolved DID as ID.
+	id = "did:web:example.com"
+	_, err = r.Resolve(context.Background(), "did:web:"+host+":issuer")
+	require.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/pkg/multibase"
+)
+
+// maxDocumentSize is the maximum size of a fetched did:web document.
+const maxDocumentSize = 1 << 20
+
+// Resolver resolves DIDs to DID documents.
+type Resolver interface {
+	Resolve(ctx context.Context, did string) (*Document, error)
+}
+
+// MethodResolver resolves DIDs with the resolver registered for their
+// method.
+type MethodResolver map[string]Resolver
+
+// NewResolver returns a resolver for did:ethr, did:key, and did:web. The
+// documents of did:web DIDs are fetched with `client`. If `client` is nil,
+// http.DefaultClient is used.
+func NewResolver(client *http.Client) MethodResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return MethodResolver{
+		"ethr": EthrResolver{},
+		"key":  KeyResolver{},
+		"web":  &WebResolver{Client: client},
+	}
+}
+
+func (r MethodResolver) Resolve(ctx context.Context, did string) (*Document, error) {
+	d, err := Parse(did)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := r[d.Method]
+	if !ok {
+		return nil, fmt.Errorf("unsupported DID method: %s", d.Method)
+	}
+	return m.Resolve(ctx, did)
+}
+
+// ResolveAddress resolves `did` with `r` and returns the Ethereum address of
+// its first verification method that has one.
+func ResolveAddress(ctx context.Context, r Resolver, did string) (common.Address, error) {
+	doc, err := r.Resolve(ctx, did)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("resolving %s: %w", did, err)
+	}
+	for i := range doc.VerificationMethod {
+		if addr, err := doc.VerificationMethod[i].EthereumAddress(); err == nil {
+			return addr, nil
+		}
+	}
+	return common.Address{}, fmt.Errorf("resolving %s: %w", did, ErrNoKey)
+}
+
+// EthrResolver resolves did:ethr DIDs to their default document, which has
+// the identifier itself as controller. Changes of the controller or added
+// delegates in the ERC-1056 registry are not taken into account.
+type EthrResolver struct{}
+
+// ethrNetworks maps network names to chain IDs.
+var ethrNetworks = map[string]*big.Int{
+	"":        big.NewInt(1),
+	"mainnet": big.NewInt(1),
+	"goerli":  big.NewInt(5),
+	"sepolia": big.NewInt(11155111),
+}
+
+func (EthrResolver) Resolve(_ context.Context, did string) (*Document, error) {
+	d, err := Parse(did)
+	if err != nil {
+		return nil, err
+	} else if d.Method != "ethr" {
+		return nil, fmt.Errorf("%w: not did:ethr", ErrInvalidDID)
+	}
+
+	network, id := "", d.ID
+	if i := strings.LastIndexByte(id, ':'); i >= 0 {
+		network, id = id[:i], id[i+1:]
+	}
+	chainID, ok := ethrNetworks[network]
+	if !ok {
+		if chainID, err = hexutil.DecodeBig(network); err != nil {
+			return nil, fmt.Errorf("unknown network: %s", network)
+		}
+	}
+
+	var addr common.Address
+	switch {
+	case common.IsHexAddress(id):
+		addr = common.HexToAddress(id)
+	case len(id) == 2+2*33:
+		// Compressed public key.
+		key, err := hexutil.Decode(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidDID, did)
+		}
+		pub, err := crypto.DecompressPubkey(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidDID, did)
+		}
+		addr = crypto.PubkeyToAddress(*pub)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDID, did)
+	}
+
+	vm := did + "#controller"
+	return &Document{
+		ID: did,
+		VerificationMethod: []VerificationMethod{{
+			ID:                  vm,
+			Type:                TypeSecp256k1Recovery,
+			Controller:          did,
+			BlockchainAccountID: fmt.Sprintf("eip155:%s:%s", chainID, addr.Hex()),
+		}},
+		AssertionMethod: []string{vm},
+		Authentication:  []string{vm},
+	}, nil
+}
+
+// KeyResolver resolves did:key DIDs of Ed25519 and secp256k1 keys.
+type KeyResolver struct{}
+
+func (KeyResolver) Resolve(_ context.Context, did string) (*Document, error) {
+	d, err := Parse(did)
+	if err != nil {
+		return nil, err
+	} else if d.Method != "key" {
+		return nil, fmt.Errorf("%w: not did:key", ErrInvalidDID)
+	}
+	key, err := multibase.Decode(d.ID)
+	if err != nil || len(key) < 2 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDID, did)
+	}
+
+	vm := VerificationMethod{
+		ID:                 did + "#" + d.ID,
+		Type:               TypeMultikey,
+		Controller:         did,
+		PublicKeyMultibase: d.ID,
+	}
+	if _, err := vm.Ed25519Key(); err != nil {
+		if _, err := secp256k1Address(key); err != nil {
+			return nil, fmt.Errorf("unsupported key type: %s", did)
+		}
+	}
+	return &Document{
+		ID:                 did,
+		VerificationMethod: []VerificationMethod{vm},
+		AssertionMethod:    []string{vm.ID},
+		Authentication:     []string{vm.ID},
+	}, nil
+}
+
+// WebResolver resolves did:web DIDs by fetching their document via HTTPS.
+type WebResolver struct {
+	Client *http.Client
+}
+
+func (r *WebResolver) Resolve(ctx context.Context, did string) (*Document, error) {
+	u, err := WebURL(did)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching document: %s", resp.Status)
+	}
+
+	var doc Document
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding document: %w", err)
+	} else if doc.ID != did {
+		return nil, fmt.Errorf("document ID %s does not match %s", doc.ID, did)
+	}
+	return &doc, nil
+}
+
+// WebURL returns the URL of the document of did:web DID `did`.
+func WebURL(did string) (string, error) {
+	d, err := Parse(did)
+	if err != nil {
+		return "", err
+	} else if d.Method != "web" {
+		return "", fmt.Errorf("%w: not did:web", ErrInvalidDID)
+	}
+
+	parts := strings.Split(d.ID, ":")
+	host, err := url.PathUnescape(parts[0])
+	if err != nil || host == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDID, did)
+	}
+	path := "/.well-known"
+	if len(parts) > 1 {
+		path = "/" + strings.Join(parts[1:], "/")
+	}
+	return "https://" + host + path + "/did.json", nil
+}
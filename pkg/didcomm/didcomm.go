@@ -0,0 +1,156 @@
+// Package didcomm packs and unpacks DIDComm v2 messages. Messages are signed
+// with ES256K in the JWS JSON serialization; encryption is not supported.
+package didcomm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+)
+
+const (
+	MediaTypePlain  = "application/didcomm-plain+json"
+	MediaTypeSigned = "application/didcomm-signed+json"
+
+	// TypeProblemReport is the type of problem reports.
+	TypeProblemReport = "https://didcomm.org/report-problem/2.0/problem-report"
+)
+
+var ErrInvalidSignature = errors.New("invalid DIDComm signature")
+
+// Message is a plaintext DIDComm message.
+type Message struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	From        string          `json:"from,omitempty"`
+	To          []string        `json:"to,omitempty"`
+	ThID        string          `json:"thid,omitempty"`
+	CreatedTime int64           `json:"created_time,omitempty"`
+	Body        json.RawMessage `json:"body"`
+}
+
+// NewID returns a random message ID.
+func NewID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// ProblemReport is the body of a problem report.
+type ProblemReport struct {
+	Code    string `json:"code"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type (
+	signedMessage struct {
+		Payload    string      `json:"payload"`
+		Signatures []signature `json:"signatures"`
+	}
+
+	signature struct {
+		Protected string          `json:"protected"`
+		Header    signatureHeader `json:"header"`
+		Signature string          `json:"signature"`
+	}
+
+	signatureHeader struct {
+		Kid string `json:"kid"`
+	}
+
+	protectedHeader struct {
+		Typ string `json:"typ"`
+		Alg string `json:"alg"`
+	}
+)
+
+// Sign signs message `m` with signer `s`, whose key is identified by DID URL
+// `kid`. The DID of the key must be the sender of the message.
+func Sign(m *Message, s jws.Signer, kid string) ([]byte, error) {
+	if !strings.HasPrefix(kid, m.From+"#") {
+		return nil, fmt.Errorf("key %s does not belong to sender %s", kid, m.From)
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := json.Marshal(protectedHeader{Typ: MediaTypeSigned, Alg: s.Algorithm()})
+	if err != nil {
+		return nil, err
+	}
+
+	sm := signedMessage{Payload: jws.Encode(payload)}
+	sig := signature{Protected: jws.Encode(protected), Header: signatureHeader{Kid: kid}}
+	b, err := s.Sign([]byte(sig.Protected + "." + sm.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	sig.Signature = jws.Encode(b)
+	sm.Signatures = []signature{sig}
+	return json.Marshal(sm)
+}
+
+// Verify verifies signed message `packed` and returns the plaintext message
+// together with the address of the signing key. The signing key is resolved
+// with `r` and must belong to the sender.
+func Verify(ctx context.Context, packed []byte, r did.Resolver) (*Message, common.Address, error) {
+	var sm signedMessage
+	if err := json.Unmarshal(packed, &sm); err != nil {
+		return nil, common.Address{}, fmt.Errorf("decoding signed message: %w", err)
+	} else if len(sm.Signatures) != 1 {
+		return nil, common.Address{}, fmt.Errorf("expected one signature, got %d", len(sm.Signatures))
+	}
+	payload, err := jws.Decode(sm.Payload)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("decoding payload: %w", err)
+	}
+	var m Message
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil, common.Address{}, fmt.Errorf("decoding message: %w", err)
+	}
+
+	sig := sm.Signatures[0]
+	enc, err := jws.Decode(sig.Protected)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("decoding protected header: %w", err)
+	}
+	var h protectedHeader
+	if err := json.Unmarshal(enc, &h); err != nil {
+		return nil, common.Address{}, fmt.Errorf("decoding protected header: %w", err)
+	} else if h.Alg != jws.AlgES256K {
+		return nil, common.Address{}, fmt.Errorf("unsupported algorithm: %s", h.Alg)
+	}
+	if m.From == "" || !strings.HasPrefix(sig.Header.Kid, m.From+"#") {
+		return nil, common.Address{}, fmt.Errorf("key %s does not belong to sender %s", sig.Header.Kid, m.From)
+	}
+	doc, err := r.Resolve(ctx, m.From)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("resolving sender: %w", err)
+	}
+	vm, err := doc.Method(sig.Header.Kid)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	addr, err := vm.EthereumAddress()
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	b, err := jws.Decode(sig.Signature)
+	if err != nil {
+		return nil, common.Address{}, ErrInvalidSignature
+	}
+	if err := (jws.ES256KVerifier{Address: addr}).Verify([]byte(sig.Protected+"."+sm.Payload), b); err != nil {
+		return nil, common.Address{}, ErrInvalidSignature
+	}
+	return &m, addr, nil
+}
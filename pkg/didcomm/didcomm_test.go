@@ -0,0 +1,51 @@
+package didcomm_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/pkg/did"
+	"github.com/perun-network/perun-credential-payment/pkg/didcomm"
+	"github.com/perun-network/perun-credential-payment/pkg/jws"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+// newSigner returns a signer and its did:ethr DID.
+func newSigner(t *testing.T) (jws.Signer, string) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(addr))
+	require.NoError(t, err)
+	return jws.ES256K{Key: acc.(*simple.Account)}, did.Ethr(addr)
+}
+
+func TestSignVerify(t *testing.T) {
+	ctx := context.Background()
+	s, alice := newSigner(t)
+	_, bob := newSigner(t)
+	m := &didcomm.Message{ID: didcomm.NewID(), Type: "ping", From: alice, To: []string{bob}, Body: json.RawMessage(`{"n":1}`)}
+
+	packed, err := didcomm.Sign(m, s, alice+"#controller")
+	require.NoError(t, err)
+	got, addr, err := didcomm.Verify(ctx, packed, did.EthrResolver{})
+	require.NoError(t, err)
+	require.Equal(t, m, got)
+	require.Equal(t, alice, did.Ethr(addr))
+
+	_, err = didcomm.Sign(m, s, bob+"#controller")
+	require.Error(t, err, "key of other DID")
+
+	// The sender is replaced by another DID, whose key did not sign.
+	forged := *m
+	forged.From = bob
+	packedForged, err := didcomm.Sign(&forged, s, bob+"#controller")
+	require.NoError(t, err)
+	_, _, err = didcomm.Verify(ctx, packedForged, did.EthrResolver{})
+	require.ErrorIs(t, err, didcomm.ErrInvalidSignature, "other sender")
+}
@@ -0,0 +1,96 @@
+// Package hsm signs with secp256k1 keys held in a hardware security module,
+// which never reveals them. A Key is an app.Account, so that it can sign the
+// channel states, transactions, and credentials of a client.
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wallet"
+)
+
+const (
+	rawSigLen    = 64
+	sigVIndex    = 64
+	sigVMagicNum = 27
+)
+
+// ErrKeyMismatch is returned if a signature of the device does not recover
+// to the public key of the device.
+var ErrKeyMismatch = errors.New("signature does not match key")
+
+// halfN is half of the order of secp256k1.
+var halfN = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+
+// Device is a secp256k1 key in a hardware security module.
+type Device interface {
+	// PublicKey returns the public key of the key.
+	PublicKey() (*ecdsa.PublicKey, error)
+	// Sign returns the ECDSA signature r || s of `digest`, with 32 bytes per
+	// value.
+	Sign(digest []byte) ([]byte, error)
+}
+
+// Key is an Ethereum account whose key is held by a device.
+type Key struct {
+	dev  Device
+	addr common.Address
+}
+
+// NewKey returns the account of the key of `dev`.
+func NewKey(dev Device) (*Key, error) {
+	pub, err := dev.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("reading public key: %w", err)
+	} else if pub.Curve != crypto.S256() {
+		return nil, fmt.Errorf("key is not on secp256k1")
+	}
+	return &Key{dev: dev, addr: crypto.PubkeyToAddress(*pub)}, nil
+}
+
+// Address returns the Ethereum address of the key.
+func (k *Key) Address() wallet.Address {
+	return ethwallet.AsWalletAddr(k.addr)
+}
+
+// SignData signs the prefixed hash of `data`, like *simple.Account.
+func (k *Key) SignData(data []byte) ([]byte, error) {
+	sig, err := k.SignHash(ethwallet.PrefixedHash(data))
+	if err != nil {
+		return nil, err
+	}
+	sig[sigVIndex] += sigVMagicNum
+	return sig, nil
+}
+
+// SignHash signs `hash` in the device. The signature is normalized to a low
+// s value, as required by Ethereum, and has a recovery id of 0 or 1.
+func (k *Key) SignHash(hash []byte) ([]byte, error) {
+	raw, err := k.dev.Sign(hash)
+	if err != nil {
+		return nil, fmt.Errorf("signing in device: %w", err)
+	} else if len(raw) != rawSigLen {
+		return nil, fmt.Errorf("invalid signature length: %d", len(raw))
+	}
+
+	sig := make([]byte, rawSigLen+1)
+	copy(sig, raw)
+	if s := new(big.Int).SetBytes(raw[32:]); s.Cmp(halfN) > 0 {
+		s.Sub(crypto.S256().Params().N, s).FillBytes(sig[32:rawSigLen])
+	}
+
+	// Devices do not return the recovery id, so it is found by recovery.
+	for v := byte(0); v < 2; v++ {
+		sig[sigVIndex] = v
+		if pub, err := crypto.SigToPub(hash, sig); err == nil && crypto.PubkeyToAddress(*pub) == k.addr {
+			return sig, nil
+		}
+	}
+	return nil, ErrKeyMismatch
+}
@@ -0,0 +1,111 @@
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+// softDevice is a Device that holds its key in memory. Like many modules, it
+// does not normalize s.
+type softDevice struct {
+	key   *ecdsa.PrivateKey
+	pub   *ecdsa.PublicKey // Optional. Reported instead of the public key of key.
+	highS bool             // Returns the high s value of every signature.
+}
+
+func newSoftDevice(t *testing.T) *softDevice {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return &softDevice{key: key}
+}
+
+func (d *softDevice) PublicKey() (*ecdsa.PublicKey, error) {
+	if d.pub != nil {
+		return d.pub, nil
+	}
+	return &d.key.PublicKey, nil
+}
+
+func (d *softDevice) Sign(digest []byte) ([]byte, error) {
+	sig, err := crypto.Sign(digest, d.key)
+	if err != nil {
+		return nil, err
+	}
+	sig = sig[:rawSigLen]
+	if d.highS {
+		s := new(big.Int).SetBytes(sig[32:])
+		s.Sub(crypto.S256().Params().N, s).FillBytes(sig[32:])
+	}
+	return sig, nil
+}
+
+func TestNewKey(t *testing.T) {
+	dev := newSoftDevice(t)
+	k, err := NewKey(dev)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(dev.key.PublicKey), ethwallet.AsEthAddr(k.Address()))
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = NewKey(&softDevice{key: dev.key, pub: &p256.PublicKey})
+	require.Error(t, err, "key not on secp256k1")
+}
+
+func TestSignHash(t *testing.T) {
+	for _, highS := range []bool{false, true} {
+		t.Run(fmt.Sprintf("highS=%t", highS), func(t *testing.T) {
+			dev := newSoftDevice(t)
+			dev.highS = highS
+			k, err := NewKey(dev)
+			require.NoError(t, err)
+
+			// Both recovery ids occur with overwhelming probability.
+			var ids [2]bool
+			for i := 0; i < 32; i++ {
+				hash := crypto.Keccak256([]byte{byte(i)})
+				sig, err := k.SignHash(hash)
+				require.NoError(t, err)
+
+				want, err := crypto.Sign(hash, dev.key)
+				require.NoError(t, err)
+				require.Equal(t, want, sig, "signature of software key")
+				require.True(t, new(big.Int).SetBytes(sig[32:rawSigLen]).Cmp(halfN) <= 0, "low s")
+				ids[sig[sigVIndex]] = true
+			}
+			require.Equal(t, [2]bool{true, true}, ids, "recovery ids")
+		})
+	}
+}
+
+func TestSignData(t *testing.T) {
+	dev := newSoftDevice(t)
+	dev.highS = true
+	k, err := NewKey(dev)
+	require.NoError(t, err)
+
+	data := []byte("Perun/Bosch: SSI Credential Payment")
+	sig, err := k.SignData(data)
+	require.NoError(t, err)
+	require.Contains(t, []byte{27, 28}, sig[sigVIndex])
+	ok, err := ethwallet.VerifySignature(data, sig, k.Address())
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestKeyMismatch(t *testing.T) {
+	dev, other := newSoftDevice(t), newSoftDevice(t)
+	dev.pub = &other.key.PublicKey
+	k, err := NewKey(dev)
+	require.NoError(t, err)
+
+	_, err = k.SignHash(crypto.Keccak256([]byte("hash")))
+	require.ErrorIs(t, err, ErrKeyMismatch)
+}
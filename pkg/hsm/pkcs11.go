@@ -0,0 +1,149 @@
+//go:build pkcs11
+// +build pkcs11
+
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/miekg/pkcs11"
+)
+
+// PKCS11Config selects a key pair in a PKCS#11 token.
+type PKCS11Config struct {
+	Module string // Path of the PKCS#11 library of the HSM.
+	Token  string // Label of the token.
+	PIN    string // User PIN of the token.
+	Label  string // Label of the secp256k1 key pair.
+}
+
+// PKCS11Device is a Device accessed via PKCS#11.
+type PKCS11Device struct {
+	ctx       *pkcs11.Ctx
+	mu        sync.Mutex // Guards session, which must not be used concurrently.
+	session   pkcs11.SessionHandle
+	priv, pub pkcs11.ObjectHandle
+}
+
+// OpenPKCS11 logs into the token and looks up the key pair selected by
+// `cfg`. The device must be closed after use.
+func OpenPKCS11(cfg PKCS11Config) (*PKCS11Device, error) {
+	ctx := pkcs11.New(cfg.Module)
+	if ctx == nil {
+		return nil, fmt.Errorf("loading PKCS#11 module %s", cfg.Module)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("initializing PKCS#11 module: %w", err)
+	}
+	d := &PKCS11Device{ctx: ctx}
+	if err := d.open(cfg); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *PKCS11Device) open(cfg PKCS11Config) error {
+	slot, err := d.findToken(cfg.Token)
+	if err != nil {
+		return err
+	}
+	if d.session, err = d.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION); err != nil {
+		return fmt.Errorf("opening session: %w", err)
+	}
+	if err := d.ctx.Login(d.session, pkcs11.CKU_USER, cfg.PIN); err != nil {
+		return fmt.Errorf("logging in: %w", err)
+	}
+	if d.priv, err = d.findKey(pkcs11.CKO_PRIVATE_KEY, cfg.Label); err != nil {
+		return err
+	}
+	d.pub, err = d.findKey(pkcs11.CKO_PUBLIC_KEY, cfg.Label)
+	return err
+}
+
+// findToken returns the slot of the token with label `label`.
+func (d *PKCS11Device) findToken(label string) (uint, error) {
+	slots, err := d.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("listing slots: %w", err)
+	}
+	for _, s := range slots {
+		info, err := d.ctx.GetTokenInfo(s)
+		if err != nil {
+			return 0, fmt.Errorf("reading token info: %w", err)
+		}
+		// Labels are padded with spaces.
+		if strings.TrimRight(info.Label, " ") == label {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("token %q not found", label)
+}
+
+// findKey returns the object of class `class` with label `label`.
+func (d *PKCS11Device) findKey(class uint, label string) (pkcs11.ObjectHandle, error) {
+	tmpl := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := d.ctx.FindObjectsInit(d.session, tmpl); err != nil {
+		return 0, fmt.Errorf("finding key: %w", err)
+	}
+	objs, _, err := d.ctx.FindObjects(d.session, 1)
+	if err := d.ctx.FindObjectsFinal(d.session); err != nil {
+		return 0, fmt.Errorf("finding key: %w", err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("finding key: %w", err)
+	} else if len(objs) == 0 {
+		return 0, fmt.Errorf("key %q not found", label)
+	}
+	return objs[0], nil
+}
+
+// PublicKey reads the public key from the token.
+func (d *PKCS11Device) PublicKey() (*ecdsa.PublicKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	attrs, err := d.ctx.GetAttributeValue(d.session, d.pub, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading EC point: %w", err)
+	}
+	// The point is DER-encoded as octet string, but some tokens return it
+	// unwrapped.
+	point := attrs[0].Value
+	var wrapped []byte
+	if rest, err := asn1.Unmarshal(point, &wrapped); err == nil && len(rest) == 0 {
+		point = wrapped
+	}
+	return crypto.UnmarshalPubkey(point)
+}
+
+// Sign signs `digest` with CKM_ECDSA, which returns r || s.
+func (d *PKCS11Device) Sign(digest []byte) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}
+	if err := d.ctx.SignInit(d.session, mech, d.priv); err != nil {
+		return nil, fmt.Errorf("initializing signing: %w", err)
+	}
+	return d.ctx.Sign(d.session, digest)
+}
+
+// Close logs out and unloads the module.
+func (d *PKCS11Device) Close() {
+	if d.session != 0 {
+		d.ctx.Logout(d.session)
+		d.ctx.CloseSession(d.session)
+	}
+	d.ctx.Finalize()
+	d.ctx.Destroy()
+}
@@ -0,0 +1,88 @@
+// Package ipfs stores and retrieves content using the HTTP RPC API of an IPFS
+// node.
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client talks to the RPC API of an IPFS node, e.g., http://127.0.0.1:5001.
+type Client struct {
+	api    string
+	client *http.Client
+}
+
+func NewClient(api string) *Client {
+	return &Client{
+		api:    strings.TrimSuffix(api, "/"),
+		client: &http.Client{},
+	}
+}
+
+// Put adds and pins `data` and returns its CID.
+func (c *Client) Put(ctx context.Context, data []byte) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "data")
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	resp, err := c.call(ctx, "add", url.Values{"pin": {"true"}}, w.FormDataContentType(), &body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Close()
+
+	var added struct{ Hash string }
+	if err := json.NewDecoder(resp).Decode(&added); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	return added.Hash, nil
+}
+
+// Get returns the content with CID `cid`.
+func (c *Client) Get(ctx context.Context, cid string) ([]byte, error) {
+	resp, err := c.call(ctx, "cat", url.Values{"arg": {cid}}, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	return io.ReadAll(resp)
+}
+
+func (c *Client) call(ctx context.Context, cmd string, args url.Values, contentType string, body io.Reader) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/api/v0/%s?%s", c.api, cmd, args.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling %s: %w", cmd, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("calling %s: %s: %s", cmd, resp.Status, msg)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,83 @@
+package ipfs_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/pkg/ipfs"
+	"github.com/stretchr/testify/require"
+)
+
+// node is a fake IPFS node that implements the add and cat commands of the
+// RPC API. Its CIDs are hex-encoded SHA-256 hashes.
+type node struct {
+	mu      sync.Mutex
+	content map[string][]byte
+	pinned  map[string]bool
+}
+
+func newNode(t *testing.T) (*node, string) {
+	n := &node{content: make(map[string][]byte), pinned: make(map[string]bool)}
+	srv := httptest.NewServer(n)
+	t.Cleanup(srv.Close)
+	return n, srv.URL
+}
+
+func (n *node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch r.URL.Path {
+	case "/api/v0/add":
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		h := sha256.Sum256(data)
+		cid := hex.EncodeToString(h[:])
+		n.content[cid] = data
+		n.pinned[cid] = r.URL.Query().Get("pin") == "true"
+		json.NewEncoder(w).Encode(struct{ Hash string }{cid})
+	case "/api/v0/cat":
+		data, ok := n.content[r.URL.Query().Get("arg")]
+		if !ok {
+			http.Error(w, "not found", http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	n, url := newNode(t)
+	c := ipfs.NewClient(url + "/")
+
+	data := []byte("Perun/Bosch: SSI Credential Payment")
+	cid, err := c.Put(ctx, data)
+	require.NoError(t, err)
+	n.mu.Lock()
+	require.True(t, n.pinned[cid], "pinned")
+	n.mu.Unlock()
+
+	got, err := c.Get(ctx, cid)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	_, err = c.Get(ctx, "unknown")
+	require.Error(t, err, "unknown CID")
+}
@@ -0,0 +1,273 @@
+// Package jsonschema validates JSON documents against a subset of JSON
+// Schema.
+//
+// The supported keywords are type, enum, const, properties, required,
+// additionalProperties, items, contains, minItems, maxItems, minLength, maxLength,
+// pattern, minimum and maximum.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON schema.
+type Schema struct {
+	Type                 typeList           `json:"type"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                interface{}        `json:"const"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Contains             *Schema            `json:"contains"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	pattern *regexp.Regexp
+}
+
+// Error is a validation error at a location in the document.
+type Error struct {
+	Path    string `json:"path"` // JSON pointer.
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Compile parses schema `b`.
+func Compile(b []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Schema) compile() (err error) {
+	if s.Pattern != "" {
+		if s.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("compiling pattern: %w", err)
+		}
+	}
+	for _, p := range s.Properties {
+		if err := p.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Contains != nil {
+		return s.Contains.compile()
+	}
+	return nil
+}
+
+// Validate validates document `doc` and returns all validation errors.
+func (s *Schema) Validate(doc []byte) []Error {
+	d := json.NewDecoder(bytes.NewReader(doc))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return []Error{{Path: "", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	var errs []Error
+	s.validate("", v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]Error) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, Error{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.Type.matches(v) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), typeOf(v))
+		return
+	}
+	if s.Enum != nil && !contains(s.Enum, v) {
+		fail("value not in enum")
+	}
+	if s.Const != nil && !equal(s.Const, v) {
+		fail("value does not equal const")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, r := range s.Required {
+			if _, ok := v[r]; !ok {
+				fail("missing required property %q", r)
+			}
+		}
+		for k, pv := range v {
+			p, ok := s.Properties[k]
+			if ok {
+				p.validate(path+"/"+escape(k), pv, errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				fail("additional property %q not allowed", k)
+			}
+		}
+
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("expected at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("expected at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, iv := range v {
+				s.Items.validate(path+"/"+strconv.Itoa(i), iv, errs)
+			}
+		}
+		if s.Contains != nil && !s.Contains.containedIn(path, v) {
+			fail("no item matches contains")
+		}
+
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("expected at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("expected at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("does not match pattern %q", s.Pattern)
+		}
+
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("expected minimum %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("expected maximum %v", *s.Maximum)
+		}
+	}
+}
+
+// containedIn reports whether any of `items` is valid against the schema.
+func (s *Schema) containedIn(path string, items []interface{}) bool {
+	for i, iv := range items {
+		var errs []Error
+		s.validate(path+"/"+strconv.Itoa(i), iv, &errs)
+		if len(errs) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// typeList is a list of JSON types. In a schema, it is given as a single
+// string or a list of strings.
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = typeList{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(t))
+}
+
+func (t typeList) matches(v interface{}) bool {
+	actual := typeOf(v)
+	for _, typ := range t {
+		if typ == actual || (typ == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		// Numbers with a zero fractional part, like 1.0, are integers.
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func contains(vals []interface{}, v interface{}) bool {
+	for _, e := range vals {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares a value from the schema with a value from the document.
+// Numbers are compared by value, also within arrays and objects.
+func equal(schemaVal, docVal interface{}) bool {
+	return reflect.DeepEqual(schemaVal, floats(docVal))
+}
+
+// floats converts the numbers in document value `v` to float64, as they are
+// decoded in schemas.
+func floats(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v
+		}
+		return f
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = floats(e)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = floats(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// escape escapes a JSON pointer reference token.
+func escape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
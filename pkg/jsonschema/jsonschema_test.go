@@ -0,0 +1,137 @@
+package jsonschema_test
+
+import (
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/pkg/jsonschema"
+	"github.com/stretchr/testify/require"
+)
+
+// The cases are taken from the JSON Schema Test Suite, draft 2020-12.
+var suite = []struct {
+	name   string
+	schema string
+	tests  []testCase
+}{
+	{"integer type", `{"type": "integer"}`, cases(
+		`1`, true, `1.0`, true, `1.1`, false, `"foo"`, false, `"1"`, false, `{}`, false, `[]`, false, `true`, false, `null`, false,
+	)},
+	{"number type", `{"type": "number"}`, cases(
+		`1`, true, `1.0`, true, `1.1`, true, `"foo"`, false, `"1"`, false, `null`, false,
+	)},
+	{"string type", `{"type": "string"}`, cases(
+		`1`, false, `"foo"`, true, `""`, true, `"1"`, true, `{}`, false, `null`, false,
+	)},
+	{"multiple types", `{"type": ["integer", "string"]}`, cases(
+		`1`, true, `"foo"`, true, `1.1`, false, `{}`, false, `null`, false,
+	)},
+	{"enum", `{"enum": [1, 2, 3]}`, cases(
+		`1`, true, `1.0`, true, `4`, false,
+	)},
+	{"heterogeneous enum", `{"enum": [6, "foo", [], true, {"foo": 12}]}`, cases(
+		`[]`, true, `null`, false, `{"foo": 12}`, true, `{"foo": false}`, false, `{"foo": 12, "boo": 42}`, false,
+	)},
+	{"const object", `{"const": {"foo": "bar", "baz": "bax"}}`, cases(
+		`{"foo": "bar", "baz": "bax"}`, true, `{"baz": "bax", "foo": "bar"}`, true, `{"foo": "bar"}`, false, `[1, 2]`, false,
+	)},
+	{"const array", `{"const": [{"foo": "bar"}]}`, cases(
+		`[{"foo": "bar"}]`, true, `[2]`, false, `{"foo": "bar"}`, false,
+	)},
+	{"const number", `{"const": 1}`, cases(
+		`1`, true, `1.0`, true, `true`, false,
+	)},
+	{"properties", `{"properties": {"foo": {"type": "integer"}, "bar": {"type": "string"}}}`, cases(
+		`{"foo": 1, "bar": "baz"}`, true, `{"foo": 1, "bar": {}}`, false, `{"foo": [], "bar": {}}`, false, `{"quux": []}`, true, `[]`, true, `12`, true,
+	)},
+	{"required", `{"properties": {"foo": {}, "bar": {}}, "required": ["foo"]}`, cases(
+		`{"foo": 1}`, true, `{"bar": 1}`, false, `[]`, true, `""`, true, `12`, true,
+	)},
+	{"additionalProperties false", `{"properties": {"foo": {}, "bar": {}}, "additionalProperties": false}`, cases(
+		`{"foo": 1}`, true, `{"foo": 1, "bar": 2, "quux": "boom"}`, false, `[1, 2, 3]`, true,
+	)},
+	{"items", `{"items": {"type": "integer"}}`, cases(
+		`[1, 2, 3]`, true, `[1, "x"]`, false, `{"foo": "bar"}`, true,
+	)},
+	{"contains", `{"contains": {"minimum": 5}}`, cases(
+		`[3, 4, 5]`, true, `[5, 6, 7, 8]`, true, `[2, 3, 4]`, false, `[]`, false, `{}`, true,
+	)},
+	{"minItems", `{"minItems": 1}`, cases(
+		`[1, 2]`, true, `[1]`, true, `[]`, false, `""`, true,
+	)},
+	{"maxItems", `{"maxItems": 2}`, cases(
+		`[1]`, true, `[1, 2]`, true, `[1, 2, 3]`, false, `"foobar"`, true,
+	)},
+	{"minLength", `{"minLength": 2}`, cases(
+		`"foo"`, true, `"fo"`, true, `"f"`, false, `1`, true, `"💩"`, false,
+	)},
+	{"maxLength", `{"maxLength": 2}`, cases(
+		`"f"`, true, `"fo"`, true, `"foo"`, false, `100`, true, `"💩💩"`, true,
+	)},
+	{"pattern", `{"pattern": "^a*$"}`, cases(
+		`"aaa"`, true, `"abc"`, false, `true`, true, `123`, true,
+	)},
+	{"pattern is not anchored", `{"pattern": "a+"}`, cases(
+		`"xxaayy"`, true,
+	)},
+	{"minimum", `{"minimum": 1.1}`, cases(
+		`2.6`, true, `1.1`, true, `0.6`, false, `"x"`, true,
+	)},
+	{"maximum", `{"maximum": 3.0}`, cases(
+		`2.6`, true, `3.0`, true, `3.5`, false, `"x"`, true,
+	)},
+}
+
+type testCase struct {
+	doc   string
+	valid bool
+}
+
+// cases pairs documents with whether they are valid.
+func cases(args ...interface{}) []testCase {
+	out := make([]testCase, len(args)/2)
+	for i := range out {
+		out[i].doc, out[i].valid = args[2*i].(string), args[2*i+1].(bool)
+	}
+	return out
+}
+
+func TestSuite(t *testing.T) {
+	for _, group := range suite {
+		s, err := jsonschema.Compile([]byte(group.schema))
+		require.NoError(t, err, group.name)
+		for _, tt := range group.tests {
+			errs := s.Validate([]byte(tt.doc))
+			require.Equal(t, tt.valid, len(errs) == 0, "%s: %s: %v", group.name, tt.doc, errs)
+		}
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	s, err := jsonschema.Compile([]byte(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"a/b": {"type": "string"},
+			"tags": {"items": {"type": "string"}}
+		}
+	}`))
+	require.NoError(t, err)
+
+	errs := s.Validate([]byte(`{"a/b": 1, "tags": ["x", 2]}`))
+	require.ElementsMatch(t, []jsonschema.Error{
+		{Path: "", Message: `missing required property "name"`},
+		{Path: "/a~1b", Message: "expected string, got integer"},
+		{Path: "/tags/1", Message: "expected string, got integer"},
+	}, errs)
+
+	require.Len(t, s.Validate([]byte(`{`)), 1, "invalid JSON")
+}
+
+func TestCompileInvalid(t *testing.T) {
+	_, err := jsonschema.Compile([]byte(`{"pattern": "("}`))
+	require.Error(t, err)
+	_, err = jsonschema.Compile([]byte(`{"properties": {"a": {"pattern": "("}}}`))
+	require.Error(t, err)
+	_, err = jsonschema.Compile([]byte(`[]`))
+	require.Error(t, err)
+}
@@ -0,0 +1,145 @@
+// Package jws creates and verifies JSON Web Signatures in compact
+// serialization.
+package jws
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// AlgES256K is ECDSA on secp256k1 with SHA-256.
+const AlgES256K = "ES256K"
+
+var ErrInvalidSignature = errors.New("invalid JWS signature")
+
+// Header is the protected header of a JWS.
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Signer signs the signing input of a JWS.
+type Signer interface {
+	Algorithm() string
+	Sign(signingInput []byte) ([]byte, error)
+}
+
+// Verifier verifies the signature of a JWS.
+type Verifier interface {
+	Algorithm() string
+	Verify(signingInput, sig []byte) error
+}
+
+// Sign signs `payload` with signer `s` and returns the compact serialization.
+// The algorithm of the header is set by the signer.
+func Sign(s Signer, h Header, payload []byte) (string, error) {
+	h.Alg = s.Algorithm()
+	enc, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	input := Encode(enc) + "." + Encode(payload)
+	sig, err := s.Sign([]byte(input))
+	if err != nil {
+		return "", fmt.Errorf("signing: %w", err)
+	}
+	return input + "." + Encode(sig), nil
+}
+
+// Verify verifies the compact serialization `token` with verifier `v` and
+// returns its header and payload.
+func Verify(v Verifier, token string) (*Header, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("malformed JWS")
+	}
+	enc, err := Decode(parts[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding header: %w", err)
+	}
+	var h Header
+	if err := json.Unmarshal(enc, &h); err != nil {
+		return nil, nil, fmt.Errorf("decoding header: %w", err)
+	} else if h.Alg != v.Algorithm() {
+		return nil, nil, fmt.Errorf("unexpected algorithm: %s", h.Alg)
+	}
+	payload, err := Decode(parts[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding payload: %w", err)
+	}
+	sig, err := Decode(parts[2])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding signature: %w", err)
+	}
+	if err := v.Verify([]byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, nil, err
+	}
+	return &h, payload, nil
+}
+
+// Encode encodes `b` in unpadded base64url.
+func Encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode decodes unpadded base64url.
+func Decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// HashSigner signs 32-byte hashes with a secp256k1 key and returns the
+// signature in [R || S || V] format. It is implemented by the accounts of the
+// go-perun simple wallet.
+type HashSigner interface {
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// ES256K signs with a secp256k1 key.
+type ES256K struct {
+	Key HashSigner
+}
+
+func (ES256K) Algorithm() string { return AlgES256K }
+
+func (s ES256K) Sign(signingInput []byte) ([]byte, error) {
+	h := sha256.Sum256(signingInput)
+	sig, err := s.Key.SignHash(h[:])
+	if err != nil {
+		return nil, err
+	}
+	return sig[:64], nil
+}
+
+// ES256KVerifier verifies ES256K signatures of the key with address Address.
+type ES256KVerifier struct {
+	Address common.Address
+}
+
+func (ES256KVerifier) Algorithm() string { return AlgES256K }
+
+func (v ES256KVerifier) Verify(signingInput, sig []byte) error {
+	if len(sig) != 64 {
+		return ErrInvalidSignature
+	}
+	h := sha256.Sum256(signingInput)
+	// JWS signatures carry no recovery ID, so both candidates are tried.
+	for id := byte(0); id < 2; id++ {
+		pub, err := crypto.SigToPub(h[:], append(append([]byte(nil), sig...), id))
+		if err != nil {
+			continue
+		}
+		if addr := crypto.PubkeyToAddress(*pub); bytes.Equal(addr[:], v.Address[:]) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"fmt"
+	stdlog "log"
+	"strings"
+)
+
+// Logger is the logging interface used by the client. Integrators can route
+// the output into their logging framework of choice by implementing it.
+type Logger interface {
+	Infof(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	WithField(key string, value interface{}) Logger
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// StdLogger is a Logger writing to a standard library logger. Fields are
+// prepended to each message as key=value pairs.
+type StdLogger struct {
+	l      *stdlog.Logger
+	fields []field
+}
+
+// NewStdLogger returns a Logger writing to `l`. If `l` is nil, the standard
+// logger of the log package is used.
+func NewStdLogger(l *stdlog.Logger) *StdLogger {
+	if l == nil {
+		l = stdlog.Default()
+	}
+	return &StdLogger{l: l}
+}
+
+func (l *StdLogger) Infof(format string, v ...interface{}) {
+	l.printf("INFO", format, v...)
+}
+
+func (l *StdLogger) Warnf(format string, v ...interface{}) {
+	l.printf("WARN", format, v...)
+}
+
+// WithField returns a copy of the logger with the field added.
+func (l *StdLogger) WithField(key string, value interface{}) Logger {
+	fields := make([]field, len(l.fields), len(l.fields)+1)
+	copy(fields, l.fields)
+	return &StdLogger{
+		l:      l.l,
+		fields: append(fields, field{key, value}),
+	}
+}
+
+func (l *StdLogger) printf(level string, format string, v ...interface{}) {
+	var b strings.Builder
+	b.WriteString(level)
+	for _, f := range l.fields {
+		fmt.Fprintf(&b, " %s=%v", f.key, f.value)
+	}
+	l.l.Printf("%s: %s", b.String(), fmt.Sprintf(format, v...))
+}
+
+// None is a Logger that discards all output.
+type None struct{}
+
+func (None) Infof(string, ...interface{})           {}
+func (None) Warnf(string, ...interface{})           {}
+func (n None) WithField(string, interface{}) Logger { return n }
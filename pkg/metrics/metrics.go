@@ -0,0 +1,159 @@
+// Package metrics provides a registry of the client metrics backed by the
+// Prometheus client library, so that they can be exposed in the Prometheus
+// text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry holds a set of named metrics.
+type Registry struct {
+	reg     *prometheus.Registry
+	mu      sync.Mutex
+	metrics map[string]interface{} // *Counter or *Histogram
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		reg:     prometheus.NewRegistry(),
+		metrics: make(map[string]interface{}),
+	}
+}
+
+// Prometheus returns the underlying Prometheus registry, e.g., to register
+// further collectors or to gather the metrics into another registry.
+func (r *Registry) Prometheus() *prometheus.Registry {
+	return r.reg
+}
+
+// Counter returns the counter registered under `name`, creating it if it
+// does not exist yet. It panics if `name` is registered as another type of
+// metric.
+func (r *Registry) Counter(name, help string) *Counter {
+	m := r.getOrAdd(name, func() (prometheus.Collector, interface{}) {
+		c := prometheus.NewCounter(prometheus.CounterOpts{Name: name, Help: help})
+		return c, &Counter{c: c}
+	})
+	c, ok := m.(*Counter)
+	if !ok {
+		panic(fmt.Sprintf("metric %s registered as %T, not as counter", name, m))
+	}
+	return c
+}
+
+// Histogram returns the histogram registered under `name`, creating it with
+// the given buckets if it does not exist yet. It panics if `name` is
+// registered as another type of metric.
+func (r *Registry) Histogram(name, help string, buckets []float64) *Histogram {
+	m := r.getOrAdd(name, func() (prometheus.Collector, interface{}) {
+		h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets})
+		return h, &Histogram{h: h}
+	})
+	h, ok := m.(*Histogram)
+	if !ok {
+		panic(fmt.Sprintf("metric %s registered as %T, not as histogram", name, m))
+	}
+	return h
+}
+
+// getOrAdd returns the metric registered under `name`, or registers the one
+// returned by `create`.
+func (r *Registry) getOrAdd(name string, create func() (prometheus.Collector, interface{})) interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		return m
+	}
+	c, m := create()
+	r.reg.MustRegister(c)
+	r.metrics[name] = m
+	return m
+}
+
+// Write writes all metrics to `w` in the Prometheus text format.
+func (r *Registry) Write(w io.Writer) error {
+	families, err := r.reg.Gather()
+	if err != nil {
+		return err
+	}
+	for _, f := range families {
+		if _, err := expfmt.MetricFamilyToText(w, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns an http.Handler serving the metrics of the registry.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{})
+}
+
+// Counter is a monotonically increasing value. A nil Counter discards all
+// observations.
+type Counter struct {
+	c prometheus.Counter
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds `v` to the counter. It panics if `v` is negative, as a counter
+// cannot decrease.
+func (c *Counter) Add(v float64) {
+	if c == nil {
+		return
+	}
+	c.c.Add(v)
+}
+
+func (c *Counter) Value() float64 {
+	if c == nil {
+		return 0
+	}
+	var m dto.Metric
+	if err := c.c.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
+
+// Histogram counts observations in buckets. A nil Histogram discards all
+// observations.
+type Histogram struct {
+	h prometheus.Histogram
+}
+
+func (h *Histogram) Observe(v float64) {
+	if h == nil {
+		return
+	}
+	h.h.Observe(v)
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	if h == nil {
+		return 0
+	}
+	var m dto.Metric
+	if err := h.h.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,69 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/pkg/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounter(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := r.Counter("test_total", "A test counter.")
+	c.Inc()
+	c.Add(2.5)
+	require.Equal(t, 3.5, c.Value())
+	require.Same(t, c, r.Counter("test_total", "A test counter."), "existing counter")
+
+	// Counters cannot decrease.
+	require.Panics(t, func() { c.Add(-1) })
+	require.Equal(t, 3.5, c.Value())
+
+	// Nil counters discard observations.
+	var nilCounter *metrics.Counter
+	nilCounter.Inc()
+	require.Zero(t, nilCounter.Value())
+}
+
+func TestHistogram(t *testing.T) {
+	r := metrics.NewRegistry()
+	h := r.Histogram("test_seconds", "A test histogram.", []float64{1, 2})
+	h.Observe(0.5)
+	h.Observe(1.5)
+	h.Observe(3)
+	require.EqualValues(t, 3, h.Count())
+
+	var b bytes.Buffer
+	require.NoError(t, r.Write(&b))
+	for _, line := range []string{
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{le="1"} 1`,
+		`test_seconds_bucket{le="2"} 2`,
+		`test_seconds_bucket{le="+Inf"} 3`,
+		"test_seconds_sum 5",
+		"test_seconds_count 3",
+	} {
+		require.Contains(t, b.String(), line+"\n")
+	}
+}
+
+func TestTypeConflict(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Counter("test", "A test counter.")
+	require.Panics(t, func() { r.Histogram("test", "A test histogram.", metrics.DefBuckets) })
+
+	r.Histogram("test_seconds", "A test histogram.", metrics.DefBuckets)
+	require.Panics(t, func() { r.Counter("test_seconds", "A test counter.") })
+}
+
+func TestHandler(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Counter("test_total", "A test counter.").Add(2)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+	require.Contains(t, rec.Body.String(), "# HELP test_total A test counter.\n# TYPE test_total counter\ntest_total 2\n")
+}
@@ -0,0 +1,65 @@
+// Package multibase encodes and decodes base58btc multibase strings.
+package multibase
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const (
+	// Base58BTC is the multibase prefix of base58btc.
+	Base58BTC = 'z'
+
+	alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+var ErrInvalidEncoding = errors.New("invalid multibase encoding")
+
+// Encode encodes `b` in base58btc with multibase prefix.
+func Encode(b []byte) string {
+	return string(Base58BTC) + base58Encode(b)
+}
+
+// Decode decodes a base58btc multibase string.
+func Decode(s string) ([]byte, error) {
+	if len(s) == 0 || s[0] != Base58BTC {
+		return nil, ErrInvalidEncoding
+	}
+	return base58Decode(s[1:])
+}
+
+func base58Encode(b []byte) string {
+	x := new(big.Int).SetBytes(b)
+	base, mod := big.NewInt(58), new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, base, mod)
+		out = append(out, alphabet[mod.Int64()])
+	}
+	// Leading zero bytes are encoded as leading ones.
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	x, base := new(big.Int), big.NewInt(58)
+	for _, c := range s {
+		d := strings.IndexRune(alphabet, c)
+		if d < 0 {
+			return nil, ErrInvalidEncoding
+		}
+		x.Mul(x, base)
+		x.Add(x, big.NewInt(int64(d)))
+	}
+	zeros := len(s) - len(strings.TrimLeft(s, alphabet[:1]))
+	return append(make([]byte, zeros), x.Bytes()...), nil
+}
@@ -0,0 +1,195 @@
+// Package pex implements input descriptors of DIF Presentation Exchange v2.
+//
+// Field paths support the JSONPath subset of a root `$` followed by member
+// names (`.name` or `['name']`) and array indices (`[0]`). Filters support
+// the JSON Schema subset of package jsonschema.
+package pex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/perun-network/perun-credential-payment/pkg/jsonschema"
+)
+
+var (
+	ErrInvalidPath = errors.New("invalid JSONPath")
+	ErrNoMatch     = errors.New("input descriptor not satisfied")
+)
+
+type (
+	// InputDescriptor describes a credential that a holder needs.
+	InputDescriptor struct {
+		ID          string      `json:"id"`
+		Name        string      `json:"name,omitempty"`
+		Purpose     string      `json:"purpose,omitempty"`
+		Constraints Constraints `json:"constraints"`
+	}
+
+	// Constraints are the constraints of an input descriptor.
+	Constraints struct {
+		Fields []Field `json:"fields,omitempty"`
+	}
+
+	// Field constrains a value of the credential. The value is found at the
+	// first of the paths that exists and must match the filter, if given.
+	Field struct {
+		ID       string          `json:"id,omitempty"`
+		Path     []string        `json:"path"`
+		Filter   json.RawMessage `json:"filter,omitempty"`
+		Optional bool            `json:"optional,omitempty"`
+	}
+)
+
+// Match checks that credential `doc` satisfies the descriptor.
+func (d *InputDescriptor) Match(doc []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("decoding credential: %w", err)
+	}
+
+	for i, f := range d.Constraints.Fields {
+		if err := f.match(v); err != nil {
+			if f.Optional {
+				continue
+			}
+			return fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (f *Field) match(doc interface{}) error {
+	var filter *jsonschema.Schema
+	if len(f.Filter) != 0 {
+		var err error
+		if filter, err = jsonschema.Compile(f.Filter); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range f.Path {
+		v, ok, err := Select(doc, p)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if filter == nil {
+			return nil
+		}
+		enc, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if errs := filter.Validate(enc); len(errs) != 0 {
+			return fmt.Errorf("%w: %s: %v", ErrNoMatch, p, errs[0])
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: no value at %s", ErrNoMatch, strings.Join(f.Path, ", "))
+}
+
+// Values returns the constant values that the filters of the fields with
+// path `path` require, e.g., the credential types required at `$.type`. It
+// considers the keywords const, enum and contains.
+func (d *InputDescriptor) Values(path string) []interface{} {
+	var vals []interface{}
+	for _, f := range d.Constraints.Fields {
+		if !hasPath(f.Path, path) || len(f.Filter) == 0 {
+			continue
+		}
+		var filter struct {
+			Const    interface{}   `json:"const"`
+			Enum     []interface{} `json:"enum"`
+			Contains *struct {
+				Const interface{} `json:"const"`
+			} `json:"contains"`
+		}
+		if err := json.Unmarshal(f.Filter, &filter); err != nil {
+			continue
+		}
+		if filter.Const != nil {
+			vals = append(vals, filter.Const)
+		}
+		vals = append(vals, filter.Enum...)
+		if filter.Contains != nil && filter.Contains.Const != nil {
+			vals = append(vals, filter.Contains.Const)
+		}
+	}
+	return vals
+}
+
+func hasPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the value at JSONPath `path` in decoded JSON value `v`. It
+// returns false if there is no value at the path.
+func Select(v interface{}, path string) (interface{}, bool, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, false, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+	}
+	rest := path[1:]
+	for rest != "" {
+		var key string
+		index := -1
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key, rest = rest[1:1+end], rest[1+end:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, false, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+			}
+			key, rest = rest[2:end], rest[end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, false, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+			}
+			index, rest = i, rest[end+1:]
+		default:
+			return nil, false, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+		}
+
+		if index >= 0 {
+			arr, ok := v.([]interface{})
+			if !ok || index >= len(arr) {
+				return nil, false, nil
+			}
+			v = arr[index]
+			continue
+		}
+		if key == "" {
+			return nil, false, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+		}
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false, nil
+		}
+		if v, ok = obj[key]; !ok {
+			return nil, false, nil
+		}
+	}
+	return v, true, nil
+}
@@ -0,0 +1,68 @@
+package pex_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/pkg/pex"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelect(t *testing.T) {
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"type":["VerifiableCredential","Diploma"],"subject":{"first name":"Alice","grades":[1,2]}}`), &doc))
+
+	for path, want := range map[string]interface{}{
+		"$":                          doc,
+		"$.type[1]":                  "Diploma",
+		"$['subject']['first name']": "Alice",
+		"$.subject.grades[0]":        1.0,
+	} {
+		v, ok, err := pex.Select(doc, path)
+		require.NoError(t, err, path)
+		require.True(t, ok, path)
+		require.Equal(t, want, v, path)
+	}
+
+	for _, path := range []string{"$.missing", "$.type[2]", "$.type.name", "$.subject[0]"} {
+		_, ok, err := pex.Select(doc, path)
+		require.NoError(t, err, path)
+		require.False(t, ok, path)
+	}
+
+	for _, path := range []string{"type", "$.", "$[x]", "$[-1]", "$['type'", "$type"} {
+		_, _, err := pex.Select(doc, path)
+		require.ErrorIs(t, err, pex.ErrInvalidPath, path)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	d := &pex.InputDescriptor{
+		ID: "diploma",
+		Constraints: pex.Constraints{Fields: []pex.Field{
+			{Path: []string{"$.type"}, Filter: json.RawMessage(`{"type":"array","contains":{"const":"Diploma"}}`)},
+			{Path: []string{"$.credentialSubject.grade", "$.grade"}, Filter: json.RawMessage(`{"type":"number","maximum":2}`)},
+			{Path: []string{"$.credentialSubject.name"}},
+			{Path: []string{"$.nickname"}, Optional: true},
+		}},
+	}
+
+	require.NoError(t, d.Match([]byte(`{"type":["Diploma"],"credentialSubject":{"grade":1.3,"name":"Alice"}}`)))
+	require.NoError(t, d.Match([]byte(`{"type":["Diploma"],"grade":2,"credentialSubject":{"name":"Alice"}}`)), "second path")
+	require.ErrorIs(t, d.Match([]byte(`{"type":["Certificate"],"grade":1,"credentialSubject":{"name":"Alice"}}`)), pex.ErrNoMatch, "other type")
+	require.ErrorIs(t, d.Match([]byte(`{"type":["Diploma"],"grade":3,"credentialSubject":{"name":"Alice"}}`)), pex.ErrNoMatch, "grade")
+	require.ErrorIs(t, d.Match([]byte(`{"type":["Diploma"],"grade":1}`)), pex.ErrNoMatch, "missing name")
+	require.Error(t, d.Match([]byte(`not JSON`)))
+}
+
+func TestValues(t *testing.T) {
+	d := &pex.InputDescriptor{Constraints: pex.Constraints{Fields: []pex.Field{
+		{Path: []string{"$.type"}, Filter: json.RawMessage(`{"contains":{"const":"Diploma"}}`)},
+		{Path: []string{"$.vc.type", "$.type"}, Filter: json.RawMessage(`{"enum":["Certificate","License"]}`)},
+		{Path: []string{"$.type"}, Filter: json.RawMessage(`{"const":"Badge"}`)},
+		{Path: []string{"$.type"}},
+		{Path: []string{"$.name"}, Filter: json.RawMessage(`{"const":"Alice"}`)},
+	}}}
+	require.Equal(t, []interface{}{"Diploma", "Certificate", "License", "Badge"}, d.Values("$.type"))
+	require.Empty(t, d.Values("$.missing"))
+}
@@ -0,0 +1,550 @@
+// Package qr encodes data as QR codes (ISO/IEC 18004) in byte mode.
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// Level is an error correction level.
+type Level int
+
+const (
+	L Level = iota // Recovers 7% of the codewords.
+	M              // Recovers 15% of the codewords.
+	Q              // Recovers 25% of the codewords.
+	H              // Recovers 30% of the codewords.
+)
+
+// quietZone is the width of the border around a code, in modules.
+const quietZone = 4
+
+var ErrTooLong = errors.New("data too long for a QR code")
+
+var (
+	// formatBits are the format information bits of the levels.
+	formatBits = [4]int{1, 0, 3, 2}
+
+	// eccPerBlock is the number of error correction codewords per block, by
+	// level and version.
+	eccPerBlock = [4][40]int{
+		{7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+		{13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	}
+
+	// numBlocks is the number of error correction blocks, by level and
+	// version.
+	numBlocks = [4][40]int{
+		{1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		{1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+		{1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+		{1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+	}
+)
+
+// Code is a QR code.
+type Code struct {
+	Size    int // Width and height in modules.
+	modules []bool
+	isFunc  []bool
+}
+
+// Encode encodes `data` in the smallest QR code version that fits it at
+// error correction level `level`.
+func Encode(data []byte, level Level) (*Code, error) {
+	ver := 1
+	for ; ver <= 40; ver++ {
+		countBits := 8
+		if ver >= 10 {
+			countBits = 16
+		}
+		if len(data) < 1<<countBits && 4+countBits+8*len(data) <= 8*dataCodewords(ver, level) {
+			break
+		}
+	}
+	if ver > 40 {
+		return nil, ErrTooLong
+	}
+
+	codewords := encodeData(data, ver, level)
+	c := newCode(ver)
+	c.drawFunctionPatterns(ver)
+	c.drawCodewords(addECC(codewords, ver, level))
+
+	// Choose the mask with the lowest penalty.
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(level, mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // Masking is an involution.
+	}
+	c.applyMask(best)
+	c.drawFormat(level, best)
+	c.isFunc = nil
+	return c, nil
+}
+
+// Black returns whether the module at column `x` and row `y` is dark.
+// Coordinates outside the code are light.
+func (c *Code) Black(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y*c.Size+x]
+}
+
+// Image returns the code with a quiet zone as image, with `scale` pixels per
+// module.
+func (c *Code) Image(scale int) image.Image {
+	n := (c.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, n, n))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			v := color.Gray{Y: 0xff}
+			if c.Black(x/scale-quietZone, y/scale-quietZone) {
+				v.Y = 0
+			}
+			img.SetGray(x, y, v)
+		}
+	}
+	return img
+}
+
+// PNG returns the code as PNG image, with `scale` pixels per module.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// String renders the code for terminals, two rows per line.
+func (c *Code) String() string {
+	var b strings.Builder
+	for y := -quietZone; y < c.Size+quietZone; y += 2 {
+		for x := -quietZone; x < c.Size+quietZone; x++ {
+			top, bottom := c.Black(x, y), c.Black(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteRune(' ')
+			case top:
+				b.WriteRune('▄')
+			case bottom:
+				b.WriteRune('▀')
+			default:
+				b.WriteRune('█')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func newCode(ver int) *Code {
+	size := 4*ver + 17
+	return &Code{
+		Size:    size,
+		modules: make([]bool, size*size),
+		isFunc:  make([]bool, size*size),
+	}
+}
+
+func (c *Code) set(x, y int, black bool) {
+	c.modules[y*c.Size+x] = black
+	c.isFunc[y*c.Size+x] = true
+}
+
+func (c *Code) drawFunctionPatterns(ver int) {
+	// Timing patterns.
+	for i := 0; i < c.Size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with separators.
+	for _, p := range [][2]int{{3, 3}, {c.Size - 4, 3}, {3, c.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := p[0]+dx, p[1]+dy
+				if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+					continue
+				}
+				d := max(abs(dx), abs(dy))
+				c.set(x, y, d != 2 && d != 4)
+			}
+		}
+	}
+
+	// Alignment patterns, except where they overlap the finder patterns.
+	pos := alignmentPositions(ver)
+	for i, y := range pos {
+		for j, x := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == len(pos)-1) || (i == len(pos)-1 && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas and the dark module.
+	c.drawFormat(L, 0)
+	c.set(8, c.Size-8, true)
+
+	// Version information.
+	if ver >= 7 {
+		bits := ver<<12 | bch(ver, 0x1f25, 12)
+		for i := 0; i < 18; i++ {
+			black := bits>>i&1 != 0
+			a, b := c.Size-11+i%3, i/3
+			c.set(a, b, black)
+			c.set(b, a, black)
+		}
+	}
+}
+
+// drawFormat draws the format information of `level` and `mask`.
+func (c *Code) drawFormat(level Level, mask int) {
+	data := formatBits[level]<<3 | mask
+	bits := (data<<10 | bch(data, 0x537, 10)) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	// Around the top-left finder pattern.
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	// Next to the other finder patterns.
+	for i := 0; i < 8; i++ {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true)
+}
+
+// drawCodewords places the codewords in the zigzag pattern.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // Skip the vertical timing pattern.
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert // Upwards.
+				}
+				if c.isFunc[y*c.Size+x] {
+					continue
+				}
+				// Remainder bits stay light.
+				if i < len(codewords)*8 {
+					c.modules[y*c.Size+x] = codewords[i/8]>>(7-i%8)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !c.isFunc[y*c.Size+x] {
+				c.modules[y*c.Size+x] = !c.modules[y*c.Size+x]
+			}
+		}
+	}
+}
+
+// penalty computes the mask penalty score of the code.
+func (c *Code) penalty() int {
+	p := 0
+	at := func(x, y int, transposed bool) bool {
+		if transposed {
+			x, y = y, x
+		}
+		return c.modules[y*c.Size+x]
+	}
+
+	// Runs of five or more modules of the same color, and finder-like
+	// patterns, in rows and columns.
+	for _, transposed := range []bool{false, true} {
+		for y := 0; y < c.Size; y++ {
+			run := 0
+			for x := 0; x < c.Size; x++ {
+				if x > 0 && at(x, y, transposed) == at(x-1, y, transposed) {
+					run++
+				} else {
+					run = 1
+				}
+				if run == 5 {
+					p += 3
+				} else if run > 5 {
+					p++
+				}
+			}
+			for x := 0; x+11 <= c.Size; x++ {
+				var word int
+				for k := 0; k < 11; k++ {
+					word <<= 1
+					if at(x+k, y, transposed) {
+						word |= 1
+					}
+				}
+				if word == 0x5d0 || word == 0x05d {
+					p += 40
+				}
+			}
+		}
+	}
+
+	// 2x2 blocks of the same color.
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			v := c.modules[y*c.Size+x]
+			if v {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size &&
+				v == c.modules[y*c.Size+x+1] &&
+				v == c.modules[(y+1)*c.Size+x] &&
+				v == c.modules[(y+1)*c.Size+x+1] {
+				p += 3
+			}
+		}
+	}
+
+	// Imbalance of dark and light modules.
+	total := c.Size * c.Size
+	p += abs(dark*20-total*10) / total * 10
+	return p
+}
+
+// alignmentPositions returns the center coordinates of the alignment
+// patterns of version `ver`.
+func alignmentPositions(ver int) []int {
+	if ver == 1 {
+		return nil
+	}
+	n := ver/7 + 2
+	step := (ver*8 + n*3 + 5) / (n*4 - 4) * 2
+	pos := make([]int, n)
+	pos[0] = 6
+	for i, p := n-1, 4*ver+10; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return pos
+}
+
+// rawModules returns the number of data modules of version `ver`, including
+// remainder bits.
+func rawModules(ver int) int {
+	n := (16*ver+128)*ver + 64
+	if ver >= 2 {
+		a := ver/7 + 2
+		n -= (25*a-10)*a - 55
+		if ver >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+func dataCodewords(ver int, level Level) int {
+	return rawModules(ver)/8 - eccPerBlock[level][ver-1]*numBlocks[level][ver-1]
+}
+
+// encodeData encodes `data` in byte mode and pads it to the capacity of the
+// version.
+func encodeData(data []byte, ver int, level Level) []byte {
+	var bits []bool
+	appendBits := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, v>>i&1 != 0)
+		}
+	}
+	countBits := 8
+	if ver >= 10 {
+		countBits = 16
+	}
+
+	capacity := 8 * dataCodewords(ver, level)
+	appendBits(0x4, 4) // Byte mode.
+	appendBits(len(data), countBits)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	appendBits(0, min(4, capacity-len(bits))) // Terminator.
+	appendBits(0, (8-len(bits)%8)%8)
+	for pad := 0xec; len(bits) < capacity; pad ^= 0xec ^ 0x11 {
+		appendBits(pad, 8)
+	}
+
+	out := make([]byte, len(bits)/8)
+	for i, b := range bits {
+		if b {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+// addECC splits the data codewords into blocks, appends the error
+// correction codewords of each block and interleaves the blocks.
+func addECC(data []byte, ver int, level Level) []byte {
+	nBlocks := numBlocks[level][ver-1]
+	eccLen := eccPerBlock[level][ver-1]
+	raw := rawModules(ver) / 8
+	nShort := nBlocks - raw%nBlocks
+	shortLen := raw / nBlocks
+
+	gen := generator(eccLen)
+	blocks := make([][]byte, nBlocks)
+	for i, k := 0, 0; i < nBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= nShort {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := remainder(block, gen)
+		if i < nShort {
+			block = append(block, 0) // Placeholder, skipped when interleaving.
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	out := make([]byte, 0, raw)
+	for i := 0; i <= shortLen; i++ {
+		for j, b := range blocks {
+			if i != shortLen-eccLen || j >= nShort {
+				out = append(out, b[i])
+			}
+		}
+	}
+	return out
+}
+
+// generator returns the Reed-Solomon generator polynomial of degree
+// `degree`, without the leading coefficient.
+func generator(degree int) []byte {
+	g := make([]byte, degree)
+	g[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range g {
+			g[j] = gfMul(g[j], root)
+			if j+1 < len(g) {
+				g[j] ^= g[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return g
+}
+
+// remainder returns the remainder of `data` divided by generator `gen`.
+func remainder(data, gen []byte) []byte {
+	r := make([]byte, len(gen))
+	for _, b := range data {
+		f := b ^ r[0]
+		copy(r, r[1:])
+		r[len(r)-1] = 0
+		for i := range r {
+			r[i] ^= gfMul(gen[i], f)
+		}
+	}
+	return r
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11d
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// bch returns the BCH error correction bits of `data` for generator `gen`
+// of degree `n`.
+func bch(data, gen, n int) int {
+	r := data << n
+	for i := bitLen(r) - 1; i >= n; i-- {
+		if r>>i&1 != 0 {
+			r ^= gen << (i - n)
+		}
+	}
+	return r
+}
+
+func bitLen(v int) int {
+	n := 0
+	for ; v != 0; v >>= 1 {
+		n++
+	}
+	return n
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,239 @@
+package qr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatBits(t *testing.T) {
+	// Format information with mask 0, from ISO/IEC 18004 table C.1.
+	for level, want := range map[Level]int{L: 0x77c4, M: 0x5412, Q: 0x355f, H: 0x1689} {
+		c := newCode(1)
+		c.drawFormat(level, 0)
+		require.Equal(t, want, readFormat(c), "level %d", level)
+	}
+}
+
+func TestVersionBits(t *testing.T) {
+	// Version information of version 7, from ISO/IEC 18004 table D.1.
+	require.Equal(t, 0x07c94, 7<<12|bch(7, 0x1f25, 12))
+}
+
+func TestECC(t *testing.T) {
+	// The data codewords of "HELLO WORLD" at version 1-M and their error
+	// correction codewords.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	ecc := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	require.Equal(t, ecc, remainder(data, generator(len(ecc))))
+}
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		ver   int
+		level Level
+		words int
+	}{
+		{1, L, 19}, {1, M, 16}, {1, Q, 13}, {1, H, 9},
+		{7, M, 124}, {10, H, 122}, {40, L, 2956}, {40, H, 1276},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.words, dataCodewords(tt.ver, tt.level), "version %d, level %d", tt.ver, tt.level)
+	}
+}
+
+func TestVersionSelection(t *testing.T) {
+	// Byte-mode capacities, from ISO/IEC 18004 table 7.
+	tests := []struct {
+		level Level
+		n     int
+		ver   int
+	}{
+		{L, 17, 1}, {L, 18, 2}, {H, 7, 1}, {H, 8, 2},
+		{M, 180, 9}, {M, 181, 10}, {L, 2953, 40},
+	}
+	for _, tt := range tests {
+		c, err := Encode(make([]byte, tt.n), tt.level)
+		require.NoError(t, err)
+		require.Equal(t, 4*tt.ver+17, c.Size, "%d bytes at level %d", tt.n, tt.level)
+	}
+
+	_, err := Encode(make([]byte, 2954), L)
+	require.ErrorIs(t, err, ErrTooLong)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, level := range []Level{L, M, Q, H} {
+		for _, n := range []int{0, 1, 17, 100, 300} {
+			data := bytes.Repeat([]byte("perun"), n/5+1)[:n]
+			c, err := Encode(data, level)
+			require.NoError(t, err)
+
+			gotLevel, mask := decodeFormat(t, c)
+			require.Equal(t, level, gotLevel)
+			require.Equal(t, data, decode(t, c, level, mask), "%d bytes at level %d", n, level)
+		}
+	}
+}
+
+func TestMaskSelection(t *testing.T) {
+	data := []byte("did:ethr:0x5eb3bc0a489c5a8288765d2336659ebca68fcd00")
+	c, err := Encode(data, M)
+	require.NoError(t, err)
+	_, chosen := decodeFormat(t, c)
+
+	ver := (c.Size - 17) / 4
+	for mask := 0; mask < 8; mask++ {
+		m := newCode(ver)
+		m.drawFunctionPatterns(ver)
+		m.drawCodewords(addECC(encodeData(data, ver, M), ver, M))
+		m.applyMask(mask)
+		m.drawFormat(M, mask)
+		if mask == chosen {
+			require.Equal(t, c.modules, m.modules)
+		}
+		require.GreaterOrEqual(t, m.penalty(), c.penalty(), "mask %d", mask)
+	}
+}
+
+// readFormat reads the format information around the top-left finder
+// pattern.
+func readFormat(c *Code) int {
+	var bits int
+	set := func(i, x, y int) {
+		if c.Black(x, y) {
+			bits |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		set(i, 8, i)
+	}
+	set(6, 8, 7)
+	set(7, 8, 8)
+	set(8, 7, 8)
+	for i := 9; i < 15; i++ {
+		set(i, 14-i, 8)
+	}
+	return bits
+}
+
+// decodeFormat returns the level and mask of `c`, after checking that both
+// copies of the format information agree.
+func decodeFormat(t *testing.T, c *Code) (Level, int) {
+	bits := readFormat(c)
+	var copy2 int
+	for i := 0; i < 8; i++ {
+		if c.Black(c.Size-1-i, 8) {
+			copy2 |= 1 << i
+		}
+	}
+	for i := 8; i < 15; i++ {
+		if c.Black(8, c.Size-15+i) {
+			copy2 |= 1 << i
+		}
+	}
+	require.Equal(t, bits, copy2, "format copies differ")
+
+	data := (bits ^ 0x5412) >> 10
+	require.Equal(t, bits^0x5412, data<<10|bch(data, 0x537, 10), "invalid BCH code")
+	for level, b := range formatBits {
+		if b == data>>3 {
+			return Level(level), data & 7
+		}
+	}
+	t.Fatalf("invalid format bits: %x", bits)
+	return 0, 0
+}
+
+// decode reads the data encoded in `c` back.
+func decode(t *testing.T, c *Code, level Level, mask int) []byte {
+	ver := (c.Size - 17) / 4
+	layout := newCode(ver)
+	layout.drawFunctionPatterns(ver)
+	unmasked := &Code{Size: c.Size, modules: append([]bool(nil), c.modules...), isFunc: layout.isFunc}
+	unmasked.applyMask(mask)
+
+	// Read the codewords in the zigzag pattern.
+	var codewords []byte
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if layout.isFunc[y*c.Size+x] || i >= rawModules(ver)/8*8 {
+					continue
+				}
+				if i%8 == 0 {
+					codewords = append(codewords, 0)
+				}
+				if unmasked.modules[y*c.Size+x] {
+					codewords[i/8] |= 1 << (7 - i%8)
+				}
+				i++
+			}
+		}
+	}
+
+	// Deinterleave the data codewords of the blocks.
+	nBlocks := numBlocks[level][ver-1]
+	eccLen := eccPerBlock[level][ver-1]
+	raw := rawModules(ver) / 8
+	nShort := nBlocks - raw%nBlocks
+	shortData := raw/nBlocks - eccLen
+	blocks := make([][]byte, nBlocks)
+	k := 0
+	for i := 0; i < shortData; i++ {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], codewords[k])
+			k++
+		}
+	}
+	for j := nShort; j < nBlocks; j++ {
+		blocks[j] = append(blocks[j], codewords[k])
+		k++
+	}
+	for j, b := range blocks {
+		n := shortData
+		if j >= nShort {
+			n++
+		}
+		ecc := codewords[k:]
+		var got []byte
+		for e := 0; e < eccLen; e++ {
+			got = append(got, ecc[e*nBlocks+j])
+		}
+		require.Equal(t, remainder(b[:n], generator(eccLen)), got, "ECC of block %d", j)
+	}
+	var stream []byte
+	for _, b := range blocks {
+		stream = append(stream, b...)
+	}
+
+	// Parse the byte mode segment.
+	bit := func(i int) int { return int(stream[i/8]>>(7-i%8)) & 1 }
+	read := func(pos, n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | bit(pos+i)
+		}
+		return v
+	}
+	require.Equal(t, 0x4, read(0, 4), "mode")
+	countBits := 8
+	if ver >= 10 {
+		countBits = 16
+	}
+	n := read(4, countBits)
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(read(4+countBits+8*i, 8))
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+// Package ratelimit limits the rate of events per key and in total with
+// token buckets, e.g., the rate of requests per peer and of all requests.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+)
+
+// maxKeys is the number of keys from which on buckets that are full again
+// are dropped.
+const maxKeys = 10000
+
+// ErrRateLimited is wrapped by the errors of events that exceed a limit.
+var ErrRateLimited = errors.New("rate limited")
+
+// Scopes of limits.
+const (
+	ScopeKey    = "per-peer"
+	ScopeGlobal = "global"
+)
+
+type (
+	// Rate allows Events events per Interval, which may occur in a burst. The
+	// zero rate is unlimited.
+	Rate struct {
+		Events   int
+		Interval time.Duration
+	}
+
+	// Limits are the rates allowed per key and in total.
+	Limits struct {
+		PerKey Rate
+		Global Rate
+	}
+
+	// Error is returned for events exceeding the limit of Scope. The next
+	// event is allowed after RetryAfter.
+	Error struct {
+		Scope      string
+		RetryAfter time.Duration
+	}
+)
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s limit exceeded, retry after %v", ErrRateLimited, e.Scope, e.RetryAfter)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// unlimited reports whether the rate does not limit events.
+func (r Rate) unlimited() bool {
+	return r.Events <= 0 || r.Interval <= 0
+}
+
+// IsZero reports whether no limit is set.
+func (l Limits) IsZero() bool {
+	return l.PerKey.unlimited() && l.Global.unlimited()
+}
+
+// bucket is a token bucket, which holds up to Rate.Events tokens and is
+// refilled at the rate.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill refills the bucket at time `now` and returns whether it is full.
+func (b *bucket) refill(r Rate, now time.Time) bool {
+	b.tokens += float64(r.Events) * float64(now.Sub(b.last)) / float64(r.Interval)
+	b.last = now
+	if full := float64(r.Events); b.tokens >= full {
+		b.tokens = full
+		return true
+	}
+	return false
+}
+
+// wait returns how long until the bucket holds a token.
+func (b *bucket) wait(r Rate) time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(r.Interval) / float64(r.Events))
+}
+
+// Limiter enforces Limits.
+type Limiter struct {
+	limits Limits
+	clock  clock.Clock
+
+	mu     sync.Mutex
+	global bucket
+	keys   map[string]*bucket
+}
+
+// New returns a limiter enforcing `l`, which takes the time from `clk`.
+func New(l Limits, clk clock.Clock) *Limiter {
+	now := clk.Now()
+	return &Limiter{
+		limits: l,
+		clock:  clk,
+		global: bucket{tokens: float64(l.Global.Events), last: now},
+		keys:   make(map[string]*bucket),
+	}
+}
+
+// Allow records an event of `key` if it is within the limits. Otherwise, it
+// returns an *Error, and the event is not counted.
+func (l *Limiter) Allow(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+
+	var kb *bucket
+	if r := l.limits.PerKey; !r.unlimited() {
+		kb = l.keyBucket(key, now)
+		kb.refill(r, now)
+		if d := kb.wait(r); d > 0 {
+			return &Error{Scope: ScopeKey, RetryAfter: d}
+		}
+	}
+	if r := l.limits.Global; !r.unlimited() {
+		l.global.refill(r, now)
+		if d := l.global.wait(r); d > 0 {
+			return &Error{Scope: ScopeGlobal, RetryAfter: d}
+		}
+		l.global.tokens--
+	}
+	if kb != nil {
+		kb.tokens--
+	}
+	return nil
+}
+
+// keyBucket returns the bucket of `key`, creating a full one if there is
+// none.
+func (l *Limiter) keyBucket(key string, now time.Time) *bucket {
+	if b, ok := l.keys[key]; ok {
+		return b
+	}
+	if len(l.keys) >= maxKeys {
+		// Full buckets are equivalent to new ones.
+		for k, b := range l.keys {
+			if b.refill(l.limits.PerKey, now) {
+				delete(l.keys, k)
+			}
+		}
+	}
+	b := &bucket{tokens: float64(l.limits.PerKey.Events), last: now}
+	l.keys[key] = b
+	return b
+}
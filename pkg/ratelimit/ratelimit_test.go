@@ -0,0 +1,76 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/ratelimit"
+	"github.com/stretchr/testify/require"
+)
+
+func requireLimited(t *testing.T, err error, scope string, retryAfter time.Duration) {
+	t.Helper()
+	require.ErrorIs(t, err, ratelimit.ErrRateLimited)
+	var rerr *ratelimit.Error
+	require.True(t, errors.As(err, &rerr))
+	require.Equal(t, scope, rerr.Scope)
+	require.Equal(t, retryAfter, rerr.RetryAfter)
+}
+
+func TestPerKey(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	l := ratelimit.New(ratelimit.Limits{PerKey: ratelimit.Rate{Events: 3, Interval: 3 * time.Second}}, clk)
+
+	// The bucket allows a burst of three events.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.Allow("alice"))
+	}
+	requireLimited(t, l.Allow("alice"), ratelimit.ScopeKey, time.Second)
+	require.NoError(t, l.Allow("bob"), "other keys are not limited")
+
+	// It refills one token per second.
+	clk.Advance(500 * time.Millisecond)
+	requireLimited(t, l.Allow("alice"), ratelimit.ScopeKey, 500*time.Millisecond)
+	clk.Advance(500 * time.Millisecond)
+	require.NoError(t, l.Allow("alice"))
+	requireLimited(t, l.Allow("alice"), ratelimit.ScopeKey, time.Second)
+
+	// It holds at most three tokens.
+	clk.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.Allow("alice"))
+	}
+	require.Error(t, l.Allow("alice"))
+}
+
+func TestGlobal(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	l := ratelimit.New(ratelimit.Limits{
+		PerKey: ratelimit.Rate{Events: 2, Interval: time.Minute},
+		Global: ratelimit.Rate{Events: 3, Interval: time.Minute},
+	}, clk)
+
+	require.NoError(t, l.Allow("alice"))
+	require.NoError(t, l.Allow("alice"))
+	require.NoError(t, l.Allow("bob"))
+	requireLimited(t, l.Allow("carol"), ratelimit.ScopeGlobal, 20*time.Second)
+	requireLimited(t, l.Allow("alice"), ratelimit.ScopeKey, 30*time.Second)
+
+	// Rejected events are not counted.
+	clk.Advance(20 * time.Second)
+	require.NoError(t, l.Allow("carol"))
+	requireLimited(t, l.Allow("bob"), ratelimit.ScopeGlobal, 20*time.Second)
+}
+
+func TestUnlimited(t *testing.T) {
+	require.True(t, ratelimit.Limits{}.IsZero())
+	require.True(t, ratelimit.Limits{PerKey: ratelimit.Rate{Events: 1}}.IsZero())
+	require.False(t, ratelimit.Limits{Global: ratelimit.Rate{Events: 1, Interval: time.Second}}.IsZero())
+
+	l := ratelimit.New(ratelimit.Limits{}, clock.NewFake(time.Unix(0, 0)))
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, l.Allow("alice"))
+	}
+}
@@ -0,0 +1,68 @@
+// Package retry retries failed operations with exponential backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+)
+
+// Policy decides how often a failed operation is retried and how long to
+// wait in between. The zero policy runs an operation once.
+type Policy struct {
+	Attempts   int           // Attempts including the first one. Values below two disable retries.
+	Backoff    time.Duration // Wait before the first retry, which doubles after every retry.
+	MaxBackoff time.Duration // Optional. Caps the wait between retries.
+}
+
+// permanentError is an error that is not retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks `err` as permanent, so that Do returns it without
+// retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// Do runs `op` until it succeeds, fails permanently, or the attempts are
+// used up, and returns the last error. The waits are timed by `clk` and end
+// early with the error of `op` if `ctx` is done.
+func (p Policy) Do(ctx context.Context, clk clock.Clock, op func(context.Context) error) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		var perm *permanentError
+		if err == nil {
+			return nil
+		} else if errors.As(err, &perm) {
+			return err
+		} else if attempt >= p.Attempts || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-clk.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
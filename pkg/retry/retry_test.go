@@ -0,0 +1,90 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/retry"
+	"github.com/stretchr/testify/require"
+)
+
+var errOp = errors.New("operation failed")
+
+// waits is a clock that records the waits and ends them immediately.
+type waits struct {
+	clock.Clock
+	ds []time.Duration
+}
+
+func (w *waits) After(d time.Duration) <-chan time.Time {
+	w.ds = append(w.ds, d)
+	c := make(chan time.Time, 1)
+	c <- time.Time{}
+	return c
+}
+
+// failing returns an operation that counts its runs in `n` and fails with
+// `err` for the first `failures` runs.
+func failing(n *int, failures int, err error) func(context.Context) error {
+	return func(context.Context) error {
+		*n++
+		if *n <= failures {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	p := retry.Policy{Attempts: 5, Backoff: time.Second, MaxBackoff: 3 * time.Second}
+
+	var n int
+	clk := &waits{}
+	require.NoError(t, p.Do(ctx, clk, failing(&n, 3, errOp)))
+	require.Equal(t, 4, n)
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, clk.ds, "capped backoff")
+
+	n, clk = 0, &waits{}
+	require.ErrorIs(t, p.Do(ctx, clk, failing(&n, 10, errOp)), errOp)
+	require.Equal(t, p.Attempts, n, "attempts used up")
+
+	n, clk = 0, &waits{}
+	require.ErrorIs(t, retry.Policy{}.Do(ctx, clk, failing(&n, 1, errOp)), errOp)
+	require.Equal(t, 1, n, "zero policy")
+	require.Empty(t, clk.ds)
+}
+
+func TestPermanent(t *testing.T) {
+	require.NoError(t, retry.Permanent(nil))
+
+	var n int
+	p := retry.Policy{Attempts: 5, Backoff: time.Second}
+	err := p.Do(context.Background(), &waits{}, failing(&n, 10, retry.Permanent(errOp)))
+	require.ErrorIs(t, err, errOp)
+	require.Equal(t, errOp.Error(), err.Error())
+	require.Equal(t, 1, n, "permanent error retried")
+}
+
+func TestDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := retry.Policy{Attempts: 5, Backoff: time.Second}
+	clk := clock.NewFake(time.Unix(0, 0))
+
+	// The wait ends with the error of the operation once the context is
+	// done.
+	var n int
+	errc := make(chan error, 1)
+	go func() { errc <- p.Do(ctx, clk, failing(&n, 10, errOp)) }()
+	cancel()
+	select {
+	case err := <-errc:
+		require.ErrorIs(t, err, errOp)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do not canceled")
+	}
+	require.Equal(t, 1, n)
+}
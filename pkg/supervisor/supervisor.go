@@ -0,0 +1,216 @@
+// Package supervisor runs long-lived goroutines, recovers their panics,
+// restarts them according to a policy, and collects their failures, so that
+// a single failing goroutine does not silently wedge the process.
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+)
+
+const (
+	// MaxRestarts is the number of times a task is restarted before it is
+	// given up.
+	MaxRestarts = 5
+	// restartBackoff is the wait before the first restart of a task. It
+	// doubles with every further restart.
+	restartBackoff = 100 * time.Millisecond
+	// maxFailures is the number of failures kept for reporting. Older
+	// failures are dropped.
+	maxFailures = 64
+)
+
+// ErrPanic is wrapped by the failures of tasks that panicked.
+var ErrPanic = errors.New("panic")
+
+// Policy determines when a task is restarted.
+type Policy int
+
+const (
+	// Never does not restart the task.
+	Never Policy = iota
+	// OnPanic restarts the task if it panics.
+	OnPanic
+	// OnFailure restarts the task if it panics or returns an error.
+	OnFailure
+)
+
+// Failure is the failure of a task.
+type Failure struct {
+	Task string
+	Time time.Time
+	Err  error
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("%s: %v", f.Task, f.Err)
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// Failures is a list of failures. It is returned by Supervisor.Err.
+type Failures []*Failure
+
+func (fs Failures) Error() string {
+	msgs := make([]string, len(fs))
+	for i, f := range fs {
+		msgs[i] = f.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Supervisor runs and restarts tasks until it is stopped.
+type Supervisor struct {
+	log   log.Logger
+	clock clock.Clock
+
+	mu       sync.Mutex
+	failures Failures
+	failed   map[string]bool // Tasks that were given up.
+	done     chan struct{}
+	once     sync.Once
+}
+
+// New returns a supervisor that logs failures to `logger` and times restarts
+// with `clk`.
+func New(logger log.Logger, clk clock.Clock) *Supervisor {
+	return &Supervisor{
+		log:    logger,
+		clock:  clk,
+		failed: make(map[string]bool),
+		done:   make(chan struct{}),
+	}
+}
+
+// Go runs task `run` named `name` in a goroutine and restarts it according to
+// policy `p`, at most MaxRestarts times. Returns of the task after the
+// supervisor was stopped are neither restarted nor reported.
+func (s *Supervisor) Go(name string, p Policy, run func() error) {
+	go func() {
+		backoff := restartBackoff
+		for restarts := 0; ; restarts++ {
+			err, panicked := s.run(run)
+			if s.stopped() {
+				return
+			}
+			if err != nil {
+				s.report(name, err)
+			}
+			if !restart(p, err, panicked) {
+				return
+			}
+			if restarts == MaxRestarts {
+				s.giveUp(name)
+				return
+			}
+
+			s.log.Infof("Restarting %s in %v", name, backoff)
+			select {
+			case <-s.clock.After(backoff):
+			case <-s.done:
+				return
+			}
+			backoff *= 2
+		}
+	}()
+}
+
+func restart(p Policy, err error, panicked bool) bool {
+	switch p {
+	case OnPanic:
+		return panicked
+	case OnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// run runs `task` and returns its error, or the recovered panic.
+func (s *Supervisor) run(task func() error) (err error, panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			err, panicked = panicError(r), true
+		}
+	}()
+	return task(), false
+}
+
+// Recover recovers a panic of the calling goroutine and reports it as failure
+// of task `name`. It must be deferred. It is meant for handlers that are
+// called in goroutines the supervisor does not own.
+func (s *Supervisor) Recover(name string) {
+	if r := recover(); r != nil {
+		s.report(name, panicError(r))
+	}
+}
+
+func panicError(r interface{}) error {
+	return fmt.Errorf("%w: %v\n%s", ErrPanic, r, debug.Stack())
+}
+
+func (s *Supervisor) report(name string, err error) {
+	f := &Failure{Task: name, Time: s.clock.Now(), Err: err}
+	s.log.Warnf("Task failed: %v", f)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.failures) == maxFailures {
+		s.failures = append(s.failures[:0], s.failures[1:]...)
+	}
+	s.failures = append(s.failures, f)
+}
+
+func (s *Supervisor) giveUp(name string) {
+	s.log.Warnf("Giving up %s after %d restarts", name, MaxRestarts)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed[name] = true
+}
+
+// Err returns the failures of all tasks as Failures, or nil if no task
+// failed.
+func (s *Supervisor) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.failures) == 0 {
+		return nil
+	}
+	return append(Failures(nil), s.failures...)
+}
+
+// Failed returns the names of the tasks that were given up.
+func (s *Supervisor) Failed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.failed))
+	for name := range s.failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stop stops restarting tasks. It does not wait for running tasks.
+func (s *Supervisor) Stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+func (s *Supervisor) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
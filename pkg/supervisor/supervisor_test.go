@@ -0,0 +1,135 @@
+package supervisor_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"github.com/perun-network/perun-credential-payment/pkg/supervisor"
+	"github.com/stretchr/testify/require"
+)
+
+const timeout = 5 * time.Second
+
+var errTask = errors.New("task failed")
+
+// runs returns a task that counts its runs in `n` and fails with `fail` for
+// the first `failures` runs.
+func runs(n *int32, failures int32, fail func() error) func() error {
+	return func() error {
+		if atomic.AddInt32(n, 1) <= failures {
+			return fail()
+		}
+		return nil
+	}
+}
+
+// eventually advances `clk` past the restart backoffs until `cond` holds.
+func eventually(t *testing.T, clk *clock.Fake, cond func() bool) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		clk.Advance(time.Minute)
+		return cond()
+	}, timeout, time.Millisecond)
+}
+
+// failures returns the failures reported to `s`.
+func failures(t *testing.T, s *supervisor.Supervisor) supervisor.Failures {
+	t.Helper()
+	var fs supervisor.Failures
+	if err := s.Err(); err != nil {
+		require.True(t, errors.As(err, &fs))
+	}
+	return fs
+}
+
+func newSupervisor() (*supervisor.Supervisor, *clock.Fake) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	return supervisor.New(log.None{}, clk), clk
+}
+
+func TestOnFailure(t *testing.T) {
+	s, clk := newSupervisor()
+	var n int32
+	s.Go("task", supervisor.OnFailure, runs(&n, 2, func() error { return errTask }))
+
+	eventually(t, clk, func() bool { return atomic.LoadInt32(&n) == 3 })
+	fs := failures(t, s)
+	require.Len(t, fs, 2)
+	require.Equal(t, "task", fs[0].Task)
+	require.ErrorIs(t, fs[0], errTask)
+	require.Empty(t, s.Failed())
+}
+
+func TestOnPanic(t *testing.T) {
+	s, clk := newSupervisor()
+	var n int32
+	s.Go("panicking", supervisor.OnPanic, runs(&n, 1, func() error { panic("boom") }))
+	eventually(t, clk, func() bool { return atomic.LoadInt32(&n) == 2 })
+	require.ErrorIs(t, failures(t, s)[0], supervisor.ErrPanic)
+
+	// Errors are reported, but do not restart the task.
+	var m int32
+	s.Go("failing", supervisor.OnPanic, runs(&m, 1, func() error { return errTask }))
+	require.Eventually(t, func() bool { return len(failures(t, s)) == 2 }, timeout, time.Millisecond)
+	require.ErrorIs(t, failures(t, s)[1], errTask)
+	clk.Advance(time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&m))
+}
+
+func TestNever(t *testing.T) {
+	s, clk := newSupervisor()
+	var n int32
+	s.Go("task", supervisor.Never, runs(&n, 1, func() error { panic("boom") }))
+	require.Eventually(t, func() bool { return s.Err() != nil }, timeout, time.Millisecond)
+	clk.Advance(time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&n))
+}
+
+func TestGiveUp(t *testing.T) {
+	s, clk := newSupervisor()
+	var n int32
+	s.Go("task", supervisor.OnFailure, runs(&n, 100, func() error { return errTask }))
+
+	eventually(t, clk, func() bool { return len(s.Failed()) == 1 })
+	require.Equal(t, []string{"task"}, s.Failed())
+	require.Equal(t, int32(supervisor.MaxRestarts+1), atomic.LoadInt32(&n))
+}
+
+func TestStop(t *testing.T) {
+	s, clk := newSupervisor()
+	started, release := make(chan struct{}), make(chan struct{})
+	var n int32
+	s.Go("task", supervisor.OnFailure, func() error {
+		atomic.AddInt32(&n, 1)
+		close(started)
+		<-release
+		return errTask
+	})
+	<-started
+	s.Stop()
+	s.Stop() // Idempotent.
+	close(release)
+
+	clk.Advance(time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, s.Err(), "failures after Stop are not reported")
+	require.Equal(t, int32(1), atomic.LoadInt32(&n))
+}
+
+func TestRecover(t *testing.T) {
+	s, _ := newSupervisor()
+	func() {
+		defer s.Recover("handler")
+		panic("boom")
+	}()
+	fs := failures(t, s)
+	require.Len(t, fs, 1)
+	require.Equal(t, "handler", fs[0].Task)
+	require.ErrorIs(t, fs[0], supervisor.ErrPanic)
+}
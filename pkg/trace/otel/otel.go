@@ -0,0 +1,90 @@
+// Package otel adapts the tracing interface of the client to OpenTelemetry.
+package otel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/perun-network/perun-credential-payment/pkg/trace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Tracer is a trace.Tracer that starts OpenTelemetry spans.
+//
+// If the context of a span carries an OpenTelemetry span, e.g., one started
+// by the application, the span becomes its child. Otherwise, the span is part
+// of the trace identified by the trace.TraceID of the context, if any, which
+// the holder and the issuer derive alike.
+type Tracer struct {
+	oteltrace.Tracer
+}
+
+// NewTracer returns a Tracer that starts spans with `t`, e.g., the tracer of
+// an OpenTelemetry TracerProvider.
+func NewTracer(t oteltrace.Tracer) *Tracer {
+	return &Tracer{Tracer: t}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	if id, ok := trace.TraceIDFromContext(ctx); ok && !oteltrace.SpanContextFromContext(ctx).IsValid() {
+		// The parent only carries the derived trace ID. Its span ID is
+		// random, as the peer derives the same trace ID.
+		parent := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    oteltrace.TraceID(id),
+			SpanID:     oteltrace.SpanID(trace.NewSpanID()),
+			TraceFlags: oteltrace.FlagsSampled,
+			Remote:     true,
+		})
+		ctx = oteltrace.ContextWithRemoteSpanContext(ctx, parent)
+	}
+	ctx, span := t.Tracer.Start(ctx, name)
+	return ctx, Span{span}
+}
+
+// Span is a trace.Span backed by an OpenTelemetry span.
+type Span struct {
+	oteltrace.Span
+}
+
+func (s Span) SetAttribute(key string, value interface{}) {
+	s.Span.SetAttributes(attributeOf(key, value))
+}
+
+func (s Span) RecordError(err error) {
+	s.Span.RecordError(err)
+	s.Span.SetStatus(codes.Error, err.Error())
+}
+
+func (s Span) End() {
+	s.Span.End()
+}
+
+// attributeOf returns the attribute `key` with `value`. Values of types
+// without an attribute type are formatted as strings.
+func attributeOf(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return attribute.String(key, strconv.FormatUint(v, 10))
+		}
+		return attribute.Int64(key, int64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case fmt.Stringer:
+		return attribute.Stringer(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,107 @@
+package otel_test
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/pkg/trace"
+	"github.com/perun-network/perun-credential-payment/pkg/trace/otel"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// recorder is an OpenTelemetry tracer that records the started spans.
+type recorder struct {
+	spans []*span
+}
+
+type span struct {
+	oteltrace.Span // Noop.
+	sc             oteltrace.SpanContext
+	parent         oteltrace.SpanContext
+	attrs          []attribute.KeyValue
+	status         codes.Code
+	ended          bool
+}
+
+func (r *recorder) Start(ctx context.Context, name string, _ ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	parent := oteltrace.SpanContextFromContext(ctx)
+	traceID := parent.TraceID()
+	if !parent.IsValid() {
+		rand.Read(traceID[:])
+	}
+	var spanID oteltrace.SpanID
+	rand.Read(spanID[:])
+	s := &span{
+		Span:   oteltrace.SpanFromContext(context.Background()),
+		sc:     oteltrace.NewSpanContext(oteltrace.SpanContextConfig{TraceID: traceID, SpanID: spanID}),
+		parent: parent,
+	}
+	r.spans = append(r.spans, s)
+	return oteltrace.ContextWithSpan(ctx, s), s
+}
+
+func (s *span) SpanContext() oteltrace.SpanContext          { return s.sc }
+func (s *span) SetAttributes(kv ...attribute.KeyValue)      { s.attrs = append(s.attrs, kv...) }
+func (s *span) SetStatus(code codes.Code, _ string)         { s.status = code }
+func (s *span) RecordError(error, ...oteltrace.EventOption) {}
+func (s *span) End(...oteltrace.SpanEndOption)              { s.ended = true }
+
+func TestDerivedTrace(t *testing.T) {
+	id := trace.DeriveTraceID([]byte("channel"), []byte("document"))
+	var holder, issuer recorder
+
+	// Holder and issuer end up in the derived trace, with distinct spans.
+	_, hs := otel.NewTracer(&holder).Start(trace.WithTraceID(context.Background(), id), "RequestCredential")
+	_, is := otel.NewTracer(&issuer).Start(trace.WithTraceID(context.Background(), id), "IssueCredential")
+	hs.End()
+	is.End()
+	h, i := holder.spans[0], issuer.spans[0]
+	require.Equal(t, oteltrace.TraceID(id), h.sc.TraceID())
+	require.Equal(t, oteltrace.TraceID(id), i.sc.TraceID())
+	require.True(t, h.parent.IsRemote())
+	require.NotEqual(t, h.parent.SpanID(), i.parent.SpanID(), "parent span IDs")
+	require.True(t, h.ended)
+}
+
+func TestCallerSpan(t *testing.T) {
+	var r recorder
+	tracer := otel.NewTracer(&r)
+
+	// The span of the caller is kept as parent, even if a trace ID is
+	// derived afterwards.
+	ctx, caller := r.Start(context.Background(), "caller")
+	ctx = trace.WithTraceID(ctx, trace.DeriveTraceID([]byte("derived")))
+	ctx, s := tracer.Start(ctx, "child")
+	require.Equal(t, caller.SpanContext(), r.spans[1].parent)
+
+	// Nested spans are children of the span in the context.
+	_, nested := tracer.Start(ctx, "nested")
+	require.Equal(t, r.spans[1].sc, r.spans[2].parent)
+	nested.End()
+	s.End()
+}
+
+func TestSpan(t *testing.T) {
+	var r recorder
+	_, s := otel.NewTracer(&r).Start(context.Background(), "span")
+	s.SetAttribute("channel", "0x01")
+	s.SetAttribute("disputed", true)
+	s.SetAttribute("version", uint64(3))
+	s.SetAttribute("hash", [2]byte{1, 2})
+	trace.EndWithError(s, errors.New("failed"))
+
+	rs := r.spans[0]
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("channel", "0x01"),
+		attribute.Bool("disputed", true),
+		attribute.Int64("version", 3),
+		attribute.String("hash", "[1 2]"),
+	}, rs.attrs)
+	require.Equal(t, codes.Error, rs.status)
+	require.True(t, rs.ended)
+}
@@ -0,0 +1,89 @@
+// Package trace defines the tracing interface used by the client. It is
+// small enough to be adapted to any tracing library. Package otel adapts it to
+// OpenTelemetry.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TraceID identifies a trace across holder and issuer.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// NewSpanID returns a random SpanID. Unlike trace IDs, span IDs are not
+// derived, so that the spans of holder and issuer stay distinguishable.
+func NewSpanID() SpanID {
+	var id SpanID
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a span with the given name. If `ctx` carries a TraceID, the
+	// span is part of the corresponding trace.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents a single traced operation.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type traceIDKey struct{}
+
+// WithTraceID returns a context carrying `id`. If `ctx` already carries a
+// TraceID, e.g., one set by the application, it is returned unchanged, so
+// that the operation stays part of the caller's trace.
+func WithTraceID(ctx context.Context, id TraceID) context.Context {
+	if _, ok := TraceIDFromContext(ctx); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the TraceID carried by `ctx`, if any.
+func TraceIDFromContext(ctx context.Context) (TraceID, bool) {
+	id, ok := ctx.Value(traceIDKey{}).(TraceID)
+	return id, ok
+}
+
+// DeriveTraceID derives a TraceID from data that is known to both peers, such
+// as a proposal ID or a channel ID and the nonce of a request. This way, holder and
+// issuer end up in the same trace without exchanging additional messages.
+func DeriveTraceID(data ...[]byte) TraceID {
+	var id TraceID
+	copy(id[:], crypto.Keccak256(data...))
+	return id
+}
+
+// Noop is a Tracer that does not record anything.
+type Noop struct{}
+
+func (Noop) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) RecordError(error)                {}
+func (noopSpan) End()                             {}
+
+// EndWithError records `err`, if it is not nil, and ends the span.
+func EndWithError(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
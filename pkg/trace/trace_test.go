@@ -0,0 +1,40 @@
+package trace_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/perun-network/perun-credential-payment/pkg/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTraceID(t *testing.T) {
+	ctx := context.Background()
+	_, ok := trace.TraceIDFromContext(ctx)
+	require.False(t, ok)
+
+	callerID := trace.DeriveTraceID([]byte("caller"))
+	ctx = trace.WithTraceID(ctx, callerID)
+	id, ok := trace.TraceIDFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, callerID, id)
+
+	// The trace ID of the caller is kept.
+	ctx = trace.WithTraceID(ctx, trace.DeriveTraceID([]byte("derived")))
+	id, _ = trace.TraceIDFromContext(ctx)
+	require.Equal(t, callerID, id)
+}
+
+func TestDeriveTraceID(t *testing.T) {
+	require.Equal(t, trace.DeriveTraceID([]byte("a"), []byte("b")), trace.DeriveTraceID([]byte("a"), []byte("b")))
+	require.NotEqual(t, trace.DeriveTraceID([]byte("a"), []byte("b")), trace.DeriveTraceID([]byte("a"), []byte("c")))
+}
+
+func TestNewSpanID(t *testing.T) {
+	seen := make(map[trace.SpanID]bool)
+	for i := 0; i < 100; i++ {
+		id := trace.NewSpanID()
+		require.False(t, seen[id], "duplicate span ID")
+		seen[id] = true
+	}
+}
@@ -0,0 +1,224 @@
+// Package webhook delivers JSON notifications to HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"github.com/perun-network/perun-credential-payment/pkg/retry"
+)
+
+const (
+	// DefaultTimeout is the default timeout for delivering a notification.
+	DefaultTimeout = 10 * time.Second
+	// DefaultQueueSize is the default number of queued deliveries.
+	DefaultQueueSize = 256
+	// DefaultWorkers is the default number of concurrent deliveries.
+	DefaultWorkers = 4
+
+	// SignatureHeader holds the hex-encoded HMAC-SHA256 of the timestamp
+	// and the body of a request, see Sign.
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader holds the time of a request in Unix seconds.
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+// DefaultRetry is the default retry policy of failed deliveries.
+var DefaultRetry = retry.Policy{Attempts: 5, Backoff: time.Second, MaxBackoff: time.Minute}
+
+// ErrInvalidSignature is returned by Verify for requests that are not signed
+// with the secret.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Config configures a Notifier.
+type Config struct {
+	URLs      []string
+	Secret    []byte        // Optional. Signs the requests in SignatureHeader. Unsigned if empty.
+	Timeout   time.Duration // Optional. Bounds every delivery attempt. Defaults to DefaultTimeout.
+	QueueSize int           // Optional. Notifications are dropped while this many deliveries are queued. Defaults to DefaultQueueSize.
+	Workers   int           // Optional. Defaults to DefaultWorkers.
+	Retry     retry.Policy  // Optional. Retries failed deliveries. Defaults to DefaultRetry.
+	Clock     clock.Clock   // Optional. Times the retries and the requests. Defaults to the system clock.
+}
+
+// Notifier posts notifications to a set of webhook URLs. The deliveries are
+// queued and sent by a fixed number of workers until the notifier is closed.
+type Notifier struct {
+	cfg    Config
+	client *http.Client
+	log    log.Logger
+	queue  chan delivery
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// delivery is a queued notification for one URL.
+type delivery struct {
+	url  string
+	body []byte
+}
+
+// Payload is the body of a webhook request.
+type Payload struct {
+	Type  string      `json:"type"`
+	Event interface{} `json:"event"`
+}
+
+// NewNotifier returns a notifier for `cfg` and starts its workers. It must
+// be closed to stop them.
+func NewNotifier(cfg Config, logger log.Logger) *Notifier {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = DefaultRetry
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.System()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Notifier{
+		cfg:    cfg,
+		client: &http.Client{},
+		log:    logger,
+		queue:  make(chan delivery, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	n.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go n.work()
+	}
+	return n
+}
+
+// Notify queues the event for delivery to all URLs. Notifications that do
+// not fit into the queue and delivery failures are logged.
+func (n *Notifier) Notify(typ string, event interface{}) {
+	body, err := json.Marshal(Payload{Type: typ, Event: event})
+	if err != nil {
+		n.log.Warnf("Encoding webhook payload: %v", err)
+		return
+	}
+
+	for _, url := range n.cfg.URLs {
+		select {
+		case <-n.ctx.Done():
+			return
+		default:
+		}
+		select {
+		case n.queue <- delivery{url: url, body: body}:
+		default:
+			n.log.Warnf("Dropped %s webhook to %s: queue full", typ, url)
+		}
+	}
+}
+
+// Close stops the workers and waits for them to return. Queued and retried
+// deliveries are abandoned.
+func (n *Notifier) Close() {
+	n.cancel()
+	n.wg.Wait()
+}
+
+func (n *Notifier) work() {
+	defer n.wg.Done()
+	for {
+		select {
+		case d := <-n.queue:
+			err := n.cfg.Retry.Do(n.ctx, n.cfg.Clock, func(ctx context.Context) error {
+				return n.post(ctx, d.url, d.body)
+			})
+			if err != nil && n.ctx.Err() == nil {
+				n.log.Warnf("Delivering webhook to %s: %v", d.url, err)
+			}
+		case <-n.ctx.Done():
+			return
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return retry.Permanent(fmt.Errorf("creating request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.cfg.Secret) > 0 {
+		ts := n.cfg.Clock.Now().Unix()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(SignatureHeader, Sign(n.cfg.Secret, ts, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("unexpected status: %s", resp.Status)
+	if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return retry.Permanent(err) // The receiver rejected the request.
+	}
+	return err
+}
+
+// Sign returns the signature of a request with body `body` sent at Unix time
+// `timestamp`, which is the hex-encoded HMAC-SHA256 of the decimal timestamp,
+// a dot, and the body under key `secret`. Signing the timestamp lets
+// receivers reject replayed requests.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that a request with headers `h` and body `body` is signed
+// with `secret` and was sent at most `maxAge` before `now`.
+func Verify(secret []byte, h http.Header, body []byte, now time.Time, maxAge time.Duration) error {
+	ts, err := strconv.ParseInt(h.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > maxAge || age < -maxAge {
+		return fmt.Errorf("%w: timestamp outside of %v", ErrInvalidSignature, maxAge)
+	}
+	sig, err := hex.DecodeString(h.Get(SignatureHeader))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	want, _ := hex.DecodeString(Sign(secret, ts, body))
+	if !hmac.Equal(sig, want) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/pkg/log"
+	"github.com/perun-network/perun-credential-payment/pkg/retry"
+	"github.com/perun-network/perun-credential-payment/pkg/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("secret")
+
+// receiver is a webhook endpoint that answers with the queued statuses, and
+// with 200 OK once they are used up.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, string) {
+	r := &receiver{statuses: statuses, received: make(chan struct{}, 16)}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return r, srv.URL
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+	w.WriteHeader(status)
+	r.received <- struct{}{}
+}
+
+func (r *receiver) await(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d requests", i, n)
+		}
+	}
+}
+
+func TestSignedDelivery(t *testing.T) {
+	r, url := newReceiver(t)
+	n := webhook.NewNotifier(webhook.Config{URLs: []string{url}, Secret: secret}, log.None{})
+	defer n.Close()
+
+	n.Notify("CredentialIssued", map[string]int{"price": 1})
+	r.await(t, 1)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var p webhook.Payload
+	require.NoError(t, json.Unmarshal(r.bodies[0], &p))
+	require.Equal(t, "CredentialIssued", p.Type)
+
+	h := r.requests[0].Header
+	require.NoError(t, webhook.Verify(secret, h, r.bodies[0], time.Now(), time.Minute))
+	require.ErrorIs(t, webhook.Verify([]byte("other"), h, r.bodies[0], time.Now(), time.Minute), webhook.ErrInvalidSignature)
+	require.ErrorIs(t, webhook.Verify(secret, h, []byte("{}"), time.Now(), time.Minute), webhook.ErrInvalidSignature)
+	require.ErrorIs(t, webhook.Verify(secret, h, r.bodies[0], time.Now().Add(time.Hour), time.Minute), webhook.ErrInvalidSignature, "replayed")
+}
+
+func TestUnsignedDelivery(t *testing.T) {
+	r, url := newReceiver(t)
+	n := webhook.NewNotifier(webhook.Config{URLs: []string{url}}, log.None{})
+	defer n.Close()
+
+	n.Notify("ChannelOpened", nil)
+	r.await(t, 1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.Empty(t, r.requests[0].Header.Get(webhook.SignatureHeader))
+}
+
+func TestRetry(t *testing.T) {
+	policy := retry.Policy{Attempts: 3, Backoff: time.Millisecond}
+
+	// Server errors are retried.
+	r, url := newReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	n := webhook.NewNotifier(webhook.Config{URLs: []string{url}, Retry: policy}, log.None{})
+	defer n.Close()
+	n.Notify("ChannelOpened", nil)
+	r.await(t, 3)
+
+	// Rejected requests are not retried.
+	r, url = newReceiver(t, http.StatusBadRequest)
+	n2 := webhook.NewNotifier(webhook.Config{URLs: []string{url}, Retry: policy}, log.None{})
+	defer n2.Close()
+	n2.Notify("ChannelOpened", nil)
+	r.await(t, 1)
+	select {
+	case <-r.received:
+		t.Fatal("rejected request retried")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	arrived, block := make(chan struct{}, 16), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		arrived <- struct{}{}
+		<-block
+	}))
+	defer srv.Close()
+	n := webhook.NewNotifier(webhook.Config{URLs: []string{srv.URL}, Workers: 1, QueueSize: 1}, log.None{})
+	defer n.Close()
+
+	// While one delivery is in flight, one more is queued and the others
+	// are dropped.
+	n.Notify("ChannelOpened", nil)
+	<-arrived
+	for i := 0; i < 5; i++ {
+		n.Notify("ChannelOpened", nil)
+	}
+	close(block)
+	<-arrived
+	select {
+	case <-arrived:
+		t.Fatal("dropped notification delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,58 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProofOfPossession checks that an issuer requiring proof of possession
+// issues bound credentials once the requester answered the challenge, and
+// refuses to issue unbound ones.
+func TestProofOfPossession(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t, func(_, issuer *client.ClientConfig) {
+		issuer.ProofOfPossession = true
+	})
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	now := time.Now()
+	if env.Clock != nil {
+		now = env.Clock.Now()
+	}
+	meta := &app.Metadata{Type: "Diploma", IssuedAt: uint64(now.Unix())}
+
+	notBound := make(chan bool, 2)
+	issuerErr := runIssuer(ctx, issuer, 2, func(req *connection.CredentialRequest) error {
+		err := req.IssueCredential(ctx, issuer.Account())
+		notBound <- errors.Is(err, app.ErrNotBound)
+		if errors.Is(err, app.ErrNotBound) {
+			return req.Reject(ctx, err.Error())
+		}
+		return err
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+
+	_, err = conn.RequestCredentialWithOptions(ctx, doc, env.Amount(1), issuer.Address(), connection.CredentialOptions{Metadata: meta})
+	require.Error(err, "requesting unbound credential")
+	require.True(<-notBound, "unbound credential issued")
+
+	asyncCred, err := conn.RequestCredentialWithOptions(ctx, doc, env.Amount(1), issuer.Address(), connection.CredentialOptions{Metadata: meta, BindHolder: true})
+	require.NoError(err, "requesting bound credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.False(<-notBound)
+	require.NoError(<-issuerErr, "running issuer")
+}
@@ -0,0 +1,70 @@
+package main_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/client/policy"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestServePricing checks that an issuer serving requests with a pricing
+// strategy counters requests below the asked price.
+func TestServePricing(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	price := env.Amount(2)
+
+	serveCtx, stopServing := context.WithCancel(ctx)
+	defer stopServing()
+	issuerErr := make(chan error, 1)
+	go func() {
+		req, err := issuer.NextConnectionRequest(ctx)
+		if err != nil {
+			issuerErr <- err
+			return
+		}
+		conn, err := req.Accept(ctx)
+		if err != nil {
+			issuerErr <- err
+			return
+		}
+		docs := func(h app.Hash) ([]byte, bool) {
+			return doc, h == app.ComputeDocumentHash(doc)
+		}
+		issuerErr <- conn.ServeCredentialRequests(serveCtx, policy.Priced(policy.FixedPrice(nil, price)), docs, issuer.Account())
+	}()
+
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	balance := conn.State().Balances[app.AssetIdx][conn.Idx()]
+
+	asyncCred, err := conn.RequestCredential(ctx, doc, env.Amount(1), issuer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.AwaitResponse(ctx)
+	require.NoError(err, "awaiting response")
+	counter, ok := resp.(*connection.CounterOfferProposal)
+	require.True(ok, "counter-offer expected, got %T", resp)
+	require.Zero(price.Cmp(counter.Price()), "asked price")
+
+	asyncCred, err = counter.Accept(ctx)
+	require.NoError(err, "accepting counter-offer")
+	cred, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(cred.Accept(ctx), "accepting transaction")
+	require.NoError(app.VerifyCredential(&app.Credential{Document: doc, Signature: cred.Signature, Domain: cred.Domain()}, issuer.Address(), time.Now()))
+	require.Zero(new(big.Int).Sub(balance, price).Cmp(conn.State().Balances[app.AssetIdx][conn.Idx()]), "holder balance")
+
+	stopServing()
+	require.ErrorIs(<-issuerErr, context.Canceled, "serving stopped")
+}
@@ -0,0 +1,50 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReceipts checks that the holder obtains a receipt signed by both
+// parties for every credential issued in a channel.
+func TestReceipts(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	docs := [][]byte{[]byte("Perun/Bosch: SSI Credential Payment"), []byte("Perun/Bosch: Receipts")}
+
+	issuerErr := runIssuer(ctx, issuer, len(docs), func(req *connection.CredentialRequest) error {
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	for i, doc := range docs {
+		asyncCred, err := conn.RequestCredential(ctx, doc, env.Amount(int64(i+1)), issuer.Address())
+		require.NoError(err, "requesting credential")
+		resp, err := asyncCred.Await(ctx)
+		require.NoError(err, "awaiting credential")
+		require.NoError(resp.Accept(ctx), "accepting transaction")
+	}
+	require.NoError(<-issuerErr, "running issuer")
+
+	// The receipts are countersigned before the issuer finishes issuing.
+	receipts := conn.Receipts()
+	require.Len(receipts, len(docs))
+	for i, rc := range receipts {
+		require.NoError(rc.Verify(), "receipt %d", i)
+		require.Equal(app.Hash(conn.ID()), rc.ChannelID)
+		require.Equal(issuer.Address(), rc.Issuer)
+		require.Equal(holder.Address(), rc.Holder)
+		require.Zero(env.Amount(int64(i+1)).Cmp(rc.Price), "price of receipt %d", i)
+		require.LessOrEqual(rc.RequestedAt, rc.IssuedAt)
+	}
+}
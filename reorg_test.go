@@ -0,0 +1,95 @@
+//go:build !devchain
+// +build !devchain
+
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReorg checks that the holder re-evaluates its channel when a reorg drops
+// the registration of a dispute.
+func TestReorg(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// The reorg window is checked with the fake clock of the holder.
+	clk := clock.NewFake(time.Now())
+	env := testutil.Setup(t, func(holder, _ *client.ClientConfig) {
+		holder.Clock = clk
+		holder.ReorgWindow = time.Minute
+	})
+	holder, issuer := env.Holder, env.Issuer
+	events := holder.Events(ctx)
+
+	go func() {
+		if req, err := issuer.NextConnectionRequest(ctx); err == nil {
+			_, _ = req.Accept(ctx)
+		}
+	}()
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+
+	// Register a dispute, but do not wait for its timeout.
+	start, err := env.BlockNumber(ctx)
+	require.NoError(err, "reading block number")
+	closeCtx, cancelClose := context.WithCancel(ctx)
+	closed := make(chan error, 1)
+	go func() { closed <- conn.ForceClose(closeCtx) }()
+	awaitEvent(ctx, t, events, func(ev connection.Event) bool {
+		_, ok := ev.(*connection.DisputeRegistered)
+		return ok
+	})
+	cancelClose()
+	require.Error(<-closed, "force-closing")
+	require.True(conn.Disputed(), "disputed")
+
+	// Drop all transactions since the registration.
+	end, err := env.BlockNumber(ctx)
+	require.NoError(err, "reading block number")
+	depth := end - start
+	err = env.Reorg(ctx, depth, func([]types.Transactions) []types.Transactions {
+		return make([]types.Transactions, depth+1)
+	})
+	require.NoError(err, "reorg")
+
+	reverted := awaitEvent(ctx, t, events, func(ev connection.Event) bool {
+		_, ok := ev.(*connection.DisputeReverted)
+		if !ok {
+			clk.Advance(time.Second)
+		}
+		return ok
+	}).(*connection.DisputeReverted)
+	require.False(reverted.Registered, "registered after reorg")
+	require.False(conn.Disputed(), "disputed after reorg")
+}
+
+// awaitEvent returns the first event in `events` that satisfies `match`.
+// `match` is also called with nil at regular intervals.
+func awaitEvent(ctx context.Context, t *testing.T, events <-chan connection.Event, match func(connection.Event) bool) connection.Event {
+	t.Helper()
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case ev := <-events:
+			if match(ev) {
+				return ev
+			}
+		case <-tick.C:
+			match(nil)
+		case <-ctx.Done():
+			t.Fatal("awaiting event:", ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+// TestRotateIssuerKey checks that a request addressed to the issuer key is
+// still signed with it if the key is rotated while the request is in flight,
+// and that later requests are signed with the new key.
+func TestRotateIssuerKey(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	key, err := crypto.GenerateKey()
+	require.NoError(err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(err)
+	next := connection.AccountSigner(acc.(*simple.Account))
+	prev := issuer.IssuerKey()
+	require.Equal(issuer.Address(), prev, "default issuer key")
+
+	// The issuer rotates its key before issuing the first credential.
+	rotated := false
+	issuerErr := runIssuer(ctx, issuer, 2, func(req *connection.CredentialRequest) error {
+		if !rotated {
+			if err := issuer.RotateIssuerKey(ctx, next, time.Minute); err != nil {
+				return err
+			}
+			rotated = true
+		}
+		return issuer.IssueCredential(ctx, req)
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+
+	for _, key := range []common.Address{prev, next.Address()} {
+		asyncCred, err := conn.RequestCredential(ctx, doc, env.Amount(1), key)
+		require.NoError(err, "requesting credential")
+		resp, err := asyncCred.Await(ctx)
+		require.NoError(err, "awaiting credential")
+		require.NoError(resp.Accept(ctx), "accepting transaction")
+		cred := &app.Credential{Document: doc, Signature: resp.Signature, Domain: resp.Domain()}
+		require.NoError(app.VerifyCredential(cred, key, now(env)), "signed by %v", key)
+	}
+	require.NoError(<-issuerErr, "running issuer")
+	require.Equal(next.Address(), issuer.IssuerKey())
+}
+
+// TestIssuerKey checks that credentials are signed by the configured issuer
+// key instead of the channel account of the issuer.
+func TestIssuerKey(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(err)
+	signer := connection.AccountSigner(acc.(*simple.Account))
+	env := testutil.Setup(t, func(_, issuer *client.ClientConfig) {
+		issuer.IssuerKey = signer
+	})
+	holder, issuer := env.Holder, env.Issuer
+	require.Equal(signer.Address(), issuer.IssuerKey())
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+
+	issuerErr := runIssuer(ctx, issuer, 1, func(req *connection.CredentialRequest) error {
+		return issuer.IssueCredential(ctx, req)
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, env.Amount(1), signer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+
+	cred := &app.Credential{Document: doc, Signature: resp.Signature, Domain: resp.Domain()}
+	require.NoError(app.VerifyCredential(cred, signer.Address(), now(env)), "signed by issuer key")
+	require.Error(app.VerifyCredential(cred, issuer.Address(), now(env)), "signed by channel account")
+}
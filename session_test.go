@@ -0,0 +1,92 @@
+package main_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+// TestSessionKey checks that a holder can open channels with a session key,
+// and that the issuer learns the funding account that authorized the key.
+func TestSessionKey(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	c := testutil.SetupChain(t)
+	amount := c.Profile().Amount
+	const holderHost, issuerHost = "127.0.0.1:8548", "127.0.0.1:8549"
+	sessionKey, err := c.NewAccount(ctx, amount(10))
+	require.NoError(err, "funding session key")
+	issuerKey, err := c.NewAccount(ctx, amount(10))
+	require.NoError(err, "funding issuer")
+
+	// The funding account stays offline and needs no funds.
+	fundingKey, err := crypto.GenerateKey()
+	require.NoError(err)
+	acc, err := simple.NewWallet(fundingKey).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(fundingKey.PublicKey)))
+	require.NoError(err)
+	funding := acc.(*simple.Account)
+	session := &app.Session{
+		Account: app.AccountAddress(funding),
+		Key:     crypto.PubkeyToAddress(sessionKey.PublicKey),
+		Expiry:  uint64(time.Now().Add(time.Hour).Unix()),
+	}
+	require.NoError(session.Sign(funding), "signing session")
+
+	holderCfg := c.ClientConfig(sessionKey, holderHost, testutil.Peer(issuerKey, issuerHost))
+	issuerCfg := c.ClientConfig(issuerKey, issuerHost, testutil.Peer(sessionKey, holderHost))
+
+	// Sessions of other keys and expired sessions are refused.
+	other := *session
+	other.Key = crypto.PubkeyToAddress(issuerKey.PublicKey)
+	require.NoError(other.Sign(funding))
+	holderCfg.Session = &other
+	_, err = client.StartClient(ctx, holderCfg)
+	require.Error(err, "session of other key")
+	expired := *session
+	expired.Expiry = uint64(time.Now().Add(-time.Hour).Unix())
+	require.NoError(expired.Sign(funding))
+	holderCfg.Session = &expired
+	_, err = client.StartClient(ctx, holderCfg)
+	require.Error(err, "expired session")
+
+	holderCfg.Session = session
+	holder, err := client.StartClient(ctx, holderCfg)
+	require.NoError(err, "holder setup")
+	t.Cleanup(holder.Shutdown)
+	issuer, err := client.StartClient(ctx, issuerCfg)
+	require.NoError(err, "issuer setup")
+	t.Cleanup(issuer.Shutdown)
+
+	addr, err := issuer.RequestAccount(ctx, holder.PerunAddress())
+	require.NoError(err, "requesting account of holder")
+	require.Equal(session.Account, addr)
+	addr, err = holder.RequestAccount(ctx, issuer.PerunAddress())
+	require.NoError(err, "requesting account of issuer")
+	require.Equal(issuer.Address(), addr, "issuer without session")
+
+	// The session key opens and settles channels.
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	issuerErr := runIssuer(ctx, issuer, 1, func(req *connection.CredentialRequest) error {
+		return req.IssueCredential(ctx, issuer.Account())
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), amount(5))
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, amount(1), issuer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+	require.NoError(conn.Close(ctx), "settling")
+}
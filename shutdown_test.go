@@ -0,0 +1,63 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGracefulShutdownTimeout checks that a graceful shutdown leaves a
+// channel open whose request is still unanswered when the context is done.
+func TestGracefulShutdownTimeout(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	balance, price := env.Amount(5), env.Amount(1)
+
+	// The issuer receives the credential request but does not answer it.
+	type received struct {
+		conn *connection.Connection
+		err  error
+	}
+	issuerRecv := make(chan received, 1)
+	go func() {
+		req, err := issuer.NextConnectionRequest(ctx)
+		if err != nil {
+			issuerRecv <- received{err: err}
+			return
+		}
+		conn, err := req.Accept(ctx)
+		if err != nil {
+			issuerRecv <- received{err: err}
+			return
+		}
+		_, err = conn.NextCredentialRequest(ctx)
+		issuerRecv <- received{conn: conn, err: err}
+	}()
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), balance)
+	require.NoError(err, "proposing connection")
+	reqCtx, cancelReq := context.WithCancel(ctx)
+	defer cancelReq()
+	_, err = conn.RequestCredential(reqCtx, doc, price, issuer.Address())
+	require.NoError(err, "requesting credential")
+	recv := <-issuerRecv
+	require.NoError(recv.err, "receiving credential request")
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(ctx, 2*time.Second)
+	defer cancelShutdown()
+	err = issuer.GracefulShutdown(shutdownCtx)
+	var shutdownErr *client.ShutdownError
+	require.True(errors.As(err, &shutdownErr), "shutdown error: %v", err)
+	require.Len(shutdownErr.Channels, 1, "channels left open")
+	require.ErrorIs(shutdownErr.Channels[recv.conn.ID()], context.DeadlineExceeded, "reason")
+}
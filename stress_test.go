@@ -0,0 +1,175 @@
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// stressRequests is the number of credentials that every holder buys in
+	// stress mode.
+	stressRequests = 3
+	// stressMaxDelay is the maximum random delay before a request.
+	stressMaxDelay = 2 * time.Second
+	// stressTimeout bounds the stress test. Exceeding it indicates a
+	// deadlock.
+	stressTimeout = 10 * time.Minute
+)
+
+// TestStress connects many holders to a single issuer, which serve all of
+// them concurrently. The holders request credentials with random delays. It
+// checks that all requests are served in time and that the balances are
+// correct. The test runs in stress mode only, see testutil.StressHolders.
+func TestStress(t *testing.T) {
+	n, err := testutil.StressHolders()
+	require.NoError(t, err)
+	if n == 0 {
+		t.Skip("Stress mode disabled. Set STRESS_HOLDERS to enable it.")
+	}
+
+	env := testutil.SetupStress(t, n)
+	ctx, cancel := context.WithTimeout(context.Background(), stressTimeout)
+	defer cancel()
+	balance, price := env.Profile.Amount(5), env.Profile.Amount(1)
+	seed := time.Now().UnixNano()
+	t.Logf("Random seed: %d", seed)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	var issued int32
+
+	// Run issuer.
+	wg.Add(n)
+	go func() {
+		for i := 0; i < n; i++ {
+			req, err := env.Issuer.NextConnectionRequest(ctx)
+			if err != nil {
+				errs <- fmt.Errorf("awaiting next connection request: %w", err)
+				return
+			}
+			go func() {
+				defer wg.Done()
+				if err := serveStressHolder(ctx, env.Issuer, req, price, &issued); err != nil {
+					errs <- fmt.Errorf("serving holder %v: %w", req.Peer(), err)
+				}
+			}()
+		}
+	}()
+
+	// Run holders.
+	wg.Add(n)
+	for i, holder := range env.Holders {
+		i, holder := i, holder
+		rng := rand.New(rand.NewSource(seed + int64(i)))
+		go func() {
+			defer wg.Done()
+			if err := runStressHolder(ctx, holder, env.Issuer, i, rng, balance, price); err != nil {
+				errs <- fmt.Errorf("running holder %d: %w", i, err)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("Stress test timed out, possible deadlock")
+	}
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	require.EqualValues(t, n*stressRequests, atomic.LoadInt32(&issued), "issued credentials")
+}
+
+// runStressHolder buys stressRequests credentials from the issuer, each
+// after a random delay, and closes the connection.
+func runStressHolder(
+	ctx context.Context,
+	holder, issuer *client.Client,
+	idx int,
+	rng *rand.Rand,
+	balance, price *big.Int,
+) error {
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), balance)
+	if err != nil {
+		return fmt.Errorf("proposing connection: %w", err)
+	}
+
+	for i := 0; i < stressRequests; i++ {
+		time.Sleep(time.Duration(rng.Int63n(int64(stressMaxDelay))))
+
+		doc := []byte(fmt.Sprintf("Stress credential %d/%d", idx, i))
+		asyncCred, err := conn.RequestCredential(ctx, doc, price, issuer.Address())
+		if err != nil {
+			return fmt.Errorf("requesting credential %d: %w", i, err)
+		}
+		resp, err := asyncCred.Await(ctx)
+		if err != nil {
+			return fmt.Errorf("awaiting credential %d: %w", i, err)
+		}
+		if err := resp.Accept(ctx); err != nil {
+			return fmt.Errorf("accepting credential %d: %w", i, err)
+		}
+	}
+
+	paid := new(big.Int).Mul(price, big.NewInt(stressRequests))
+	expected := new(big.Int).Sub(balance, paid)
+	if bal := conn.State().Balances[app.AssetIdx][conn.Idx()]; bal.Cmp(expected) != 0 {
+		return fmt.Errorf("wrong balance: expected %v, got %v", expected, bal)
+	}
+	return conn.Close(ctx)
+}
+
+// serveStressHolder accepts connection request `req` and issues
+// stressRequests credentials in the connection, counting them in `issued`.
+func serveStressHolder(
+	ctx context.Context,
+	issuer *client.Client,
+	req *connection.ConnectionRequest,
+	price *big.Int,
+	issued *int32,
+) error {
+	conn, err := req.Accept(ctx)
+	if err != nil {
+		return fmt.Errorf("accepting connection request: %w", err)
+	}
+
+	for i := 0; i < stressRequests; i++ {
+		credReq, err := conn.NextCredentialRequest(ctx)
+		if err != nil {
+			return fmt.Errorf("awaiting credential request %d: %w", i, err)
+		} else if err := credReq.CheckPrice(price); err != nil {
+			return fmt.Errorf("checking price %d: %w", i, err)
+		}
+		if err := credReq.IssueCredential(ctx, issuer.Account()); err != nil {
+			return fmt.Errorf("issuing credential %d: %w", i, err)
+		}
+		atomic.AddInt32(issued, 1)
+	}
+
+	if err := conn.WaitConcludadable(ctx); err != nil {
+		return fmt.Errorf("waiting for channel finalization: %w", err)
+	}
+	expected := new(big.Int).Mul(price, big.NewInt(stressRequests))
+	if bal := conn.State().Balances[app.AssetIdx][conn.Idx()]; bal.Cmp(expected) != 0 {
+		return fmt.Errorf("wrong balance: expected %v, got %v", expected, bal)
+	}
+	return conn.Close(ctx)
+}
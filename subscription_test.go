@@ -0,0 +1,99 @@
+package main_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubscription checks that a subscription is renewed every period and
+// that the issuer notices when it lapses.
+func TestSubscription(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env := testutil.Setup(t)
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	terms := connection.SubscriptionTerms{Price: env.Amount(1), Period: 2 * time.Second, Grace: 3 * time.Second}
+	events := issuer.Events(ctx)
+
+	issuerErr := runIssuer(ctx, issuer, 2, func(req *connection.CredentialRequest) error {
+		return req.IssueSubscription(ctx, issuer.Account(), terms)
+	})
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	balance := conn.State().Balances[app.AssetIdx][conn.Idx()]
+
+	sub, err := conn.Subscribe(ctx, doc, issuer.Address(), terms)
+	require.NoError(err, "subscribing")
+	first := <-sub.Credentials()
+	require.NoError(app.VerifyCredential(first, issuer.Address(), now(env)))
+	require.ErrorIs(app.VerifyCredential(first, issuer.Address(), now(env).Add(terms.Period+terms.Grace+time.Second)), app.ErrCredentialExpired)
+
+	// The subscription is renewed after one period. The fake clock of chains
+	// with controllable time is advanced in small steps until then, so that
+	// the renewal does not expire while it is issued.
+	second := awaitCredential(ctx, t, env, sub.Credentials(), terms.Period/10)
+	require.NoError(app.VerifyCredential(second, issuer.Address(), now(env)))
+	require.Greater(second.Expiry, first.Expiry)
+	sub.Cancel()
+	require.NoError(sub.Err())
+	require.NoError(<-issuerErr, "running issuer")
+	require.Zero(new(big.Int).Sub(balance, env.Amount(2)).Cmp(conn.State().Balances[app.AssetIdx][conn.Idx()]), "holder balance")
+
+	// Without renewal, the subscription lapses after the grace time.
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case ev := <-events:
+			if lapsed, ok := ev.(*connection.SubscriptionLapsed); ok {
+				require.Equal(app.ComputeDocumentHash(doc), lapsed.DocHash)
+				return
+			}
+		case <-tick.C:
+			if env.Clock != nil {
+				env.Clock.Advance(terms.Grace)
+			}
+		case <-ctx.Done():
+			t.Fatal("subscription not lapsed:", ctx.Err())
+		}
+	}
+}
+
+// now returns the time of the clients of `env`.
+func now(env *testutil.Environment) time.Time {
+	if env.Clock != nil {
+		return env.Clock.Now()
+	}
+	return time.Now()
+}
+
+// awaitCredential waits for the next credential in `creds`, advancing the
+// fake clock of `env`, if any, by `d` until then.
+func awaitCredential(ctx context.Context, t *testing.T, env *testutil.Environment, creds <-chan *app.Credential, d time.Duration) *app.Credential {
+	t.Helper()
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case cred, ok := <-creds:
+			require.True(t, ok, "subscription ended")
+			return cred
+		case <-tick.C:
+			if env.Clock != nil {
+				env.Clock.Advance(d)
+			}
+		case <-ctx.Done():
+			t.Fatal("no credential:", ctx.Err())
+		}
+	}
+}
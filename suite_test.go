@@ -0,0 +1,74 @@
+package main_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSuiteSignature checks that the holder obtains the additional Ed25519
+// signature of an issued credential.
+func TestSuiteSignature(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(err)
+	signer := app.Ed25519Signer{Key: key}
+	env := testutil.Setup(t, func(_, issuer *client.ClientConfig) {
+		issuer.CredentialSigner = signer
+	})
+	holder, issuer := env.Holder, env.Issuer
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+	balance, price := env.Amount(5), env.Amount(1)
+
+	issuerErr := make(chan error, 1)
+	go func() {
+		issuerErr <- func() error {
+			req, err := issuer.NextConnectionRequest(ctx)
+			if err != nil {
+				return err
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return err
+			}
+			credReq, err := conn.NextCredentialRequest(ctx)
+			if err != nil {
+				return err
+			}
+			if err := credReq.CheckDoc(doc); err != nil {
+				return err
+			}
+			return credReq.IssueCredential(ctx, issuer.Account())
+		}()
+	}()
+	conn, err := holder.Connect(ctx, issuer.PerunAddress(), balance)
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, price, issuer.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running issuer")
+
+	cred := &app.Credential{Document: doc, Signature: resp.Signature, Domain: resp.Domain()}
+	sig, err := conn.RequestSuiteSignature(ctx, cred)
+	require.NoError(err, "requesting suite signature")
+	require.Equal(signer.Suite().ID(), sig.Suite)
+	require.Equal(signer.PublicKey(), sig.PublicKey)
+	cred.SuiteSignature = sig
+	require.NoError(app.VerifyCredential(cred, issuer.Address(), time.Now()), "verifying credential")
+
+	// Only credentials issued to the holder have suite signatures.
+	other := &app.Credential{Document: []byte("other"), Domain: resp.Domain()}
+	_, err = conn.RequestSuiteSignature(ctx, other)
+	require.Error(err, "other credential")
+}
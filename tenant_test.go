@@ -0,0 +1,75 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/testutil"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+// TestTenant checks that channels naming a tenant are routed to the tenant,
+// which issues credentials with its own key.
+func TestTenant(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(err)
+	acc, err := simple.NewWallet(key).Unlock(wallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	require.NoError(err)
+	env := testutil.Setup(t, func(_, issuer *client.ClientConfig) {
+		issuer.Tenants = []client.TenantConfig{{ID: "diplomas", Account: acc.(*simple.Account)}}
+	})
+	holder, issuer := env.Holder, env.Issuer
+	tenant, ok := issuer.Tenant("diplomas")
+	require.True(ok, "tenant")
+	doc := []byte("Perun/Bosch: SSI Credential Payment")
+
+	// Proposals for unknown tenants are rejected.
+	_, err = holder.ConnectTenant(ctx, issuer.PerunAddress(), "unknown", env.Amount(5))
+	var rejected *connection.PeerRejectedError
+	require.True(errors.As(err, &rejected), "unknown tenant: %v", err)
+
+	issuerErr := make(chan error, 1)
+	go func() {
+		issuerErr <- func() error {
+			req, err := tenant.NextConnectionRequest(ctx)
+			if err != nil {
+				return err
+			} else if req.Tenant() != tenant.ID() {
+				return errors.New("wrong tenant")
+			}
+			conn, err := req.Accept(ctx)
+			if err != nil {
+				return err
+			}
+			credReq, err := conn.NextCredentialRequest(ctx)
+			if err != nil {
+				return err
+			}
+			return tenant.Issue(ctx, credReq, doc)
+		}()
+	}()
+	conn, err := holder.ConnectTenant(ctx, issuer.PerunAddress(), tenant.ID(), env.Amount(5))
+	require.NoError(err, "proposing connection")
+	asyncCred, err := conn.RequestCredential(ctx, doc, env.Amount(1), tenant.Address())
+	require.NoError(err, "requesting credential")
+	resp, err := asyncCred.Await(ctx)
+	require.NoError(err, "awaiting credential")
+	require.NoError(resp.Accept(ctx), "accepting transaction")
+	require.NoError(<-issuerErr, "running tenant")
+
+	cred := &app.Credential{Document: doc, Signature: resp.Signature, Domain: resp.Domain()}
+	require.NoError(app.VerifyCredential(cred, tenant.Address(), now(env)))
+	require.Error(app.VerifyCredential(cred, issuer.Address(), now(env)), "signed by tenant")
+}
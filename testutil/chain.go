@@ -0,0 +1,92 @@
+package testutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/perun"
+	"github.com/perun-network/perun-credential-payment/pkg/devchain"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+// Chain is a throwaway blockchain with deployed contracts. Applications
+// embedding the client start their own clients on it with ClientConfig.
+type Chain struct {
+	chain     *chain
+	contracts ContractAddresses
+}
+
+// SetupChain returns the chain of the test environment, on which the
+// contracts are deployed and the app is registered. The chain is reverted
+// for every test, see Setup.
+func SetupChain(t testing.TB) *Chain {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	chain, contracts := setupChain(ctx, t)
+	return &Chain{chain: chain, contracts: contracts}
+}
+
+// Contracts returns the addresses of the deployed contracts.
+func (c *Chain) Contracts() ContractAddresses {
+	return c.contracts
+}
+
+// Profile returns the profile of the chain.
+func (c *Chain) Profile() Profile {
+	return c.chain.profile
+}
+
+// ChainID returns the chain ID.
+func (c *Chain) ChainID() *big.Int {
+	return new(big.Int).Set(c.chain.chainID)
+}
+
+// NewAccount returns a new account, which is funded with `balance` from the
+// account of the contract deployer.
+func (c *Chain) NewAccount(ctx context.Context, balance *big.Int) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+	deployer := c.chain.accounts[0]
+	if deployer == nil {
+		return nil, fmt.Errorf("no deployer account")
+	}
+
+	b := devchain.FundingBackend(c.chain.backend)
+	if b == nil {
+		client, err := ethclient.DialContext(ctx, c.chain.nodeURL)
+		if err != nil {
+			return nil, fmt.Errorf("dialing: %w", err)
+		}
+		defer client.Close()
+		b = client
+	}
+	err = devchain.Fund(ctx, b, c.chain.chainID, deployer, []devchain.Account{{PrivateKey: key, Amount: balance}})
+	if err != nil {
+		return nil, fmt.Errorf("funding account: %w", err)
+	}
+	return key, nil
+}
+
+// ClientConfig returns the configuration of a client on the chain with key
+// `key`, which listens on `host` and knows `peers`.
+func (c *Chain) ClientConfig(key *ecdsa.PrivateKey, host string, peers ...perun.Peer) client.ClientConfig {
+	return newClientConfig(c.chain, c.contracts, key, host, peers...)
+}
+
+// Peer returns the peer with key `key` listening on `host`.
+func Peer(key *ecdsa.PrivateKey, host string) perun.Peer {
+	return perun.Peer{
+		Peer:    ethwallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)),
+		Address: host,
+	}
+}
@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/perun-network/perun-credential-payment/client/connection"
+	"github.com/perun-network/perun-credential-payment/pkg/clock"
+)
+
+// disputeMargin is added to the dispute duration when skipping a dispute, so
+// that the block with the new time is after the timeout.
+const disputeMargin = time.Second
+
+// Clock is the fake clock of the clients of a test environment. Skipping time
+// also increases the time of the chain, so that disputes time out without
+// waiting.
+type Clock struct {
+	*clock.Fake
+	chain *chain
+}
+
+// newClock returns a fake clock for the clients on `c`, or nil if the time of
+// `c` cannot be controlled.
+func newClock(c *chain) *Clock {
+	if c.increaseTime == nil {
+		return nil
+	}
+	return &Clock{Fake: clock.NewFake(time.Now()), chain: c}
+}
+
+// Skip increases the time of the chain by `d` and advances the clock.
+func (c *Clock) Skip(ctx context.Context, d time.Duration) error {
+	if err := c.chain.increaseTime(ctx, d); err != nil {
+		return err
+	}
+	c.Advance(d)
+	return nil
+}
+
+// SkipDisputes skips the dispute duration whenever a dispute is registered
+// or progressed in a channel of the holder, until `ctx` is done. A registered
+// dispute of an app channel can only be concluded after the dispute and the
+// force-execution phase, so both are skipped. It does nothing on chains whose
+// time cannot be controlled, on which disputes take their full duration.
+func (e *Environment) SkipDisputes(ctx context.Context) {
+	if e.Clock == nil {
+		return
+	}
+	events := e.Holder.Events(ctx)
+	go func() {
+		for ev := range events {
+			var d time.Duration
+			switch ev.(type) {
+			case *connection.DisputeRegistered:
+				d = 2 * e.Profile.DisputeDuration
+			case *connection.DisputeProgressed:
+				d = e.Profile.DisputeDuration
+			default:
+				continue
+			}
+			if err := e.Clock.Skip(ctx, d+disputeMargin); err != nil && ctx.Err() == nil {
+				log.Printf("Skipping dispute: %v", err)
+			}
+		}
+	}()
+}
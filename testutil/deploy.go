@@ -0,0 +1,54 @@
+package testutil
+
+import (
+	"context"
+
+	"github.com/perun-network/perun-credential-payment/app"
+	"github.com/perun-network/perun-credential-payment/deploy"
+	"github.com/pkg/errors"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+)
+
+type ContractAddresses = deploy.ContractAddresses
+
+// deployContracts deploys the contracts with CREATE2 and `salt` from the
+// first account of `chain`, so that they land at deploy.Create2Addresses(salt),
+// and registers the app.
+func deployContracts(ctx context.Context, chain *chain, salt [32]byte) (ContractAddresses, error) {
+	d, err := chain.newDeployer(ctx)
+	if err != nil {
+		return ContractAddresses{}, errors.WithMessage(err, "creating deployer")
+	}
+	defer d.Close()
+
+	contracts, err := d.DeployCreate2(ctx, salt)
+	if err != nil {
+		return ContractAddresses{}, err
+	}
+
+	registerApp(contracts)
+	return contracts, nil
+}
+
+// deployedContracts returns the contracts deployed with CREATE2 and `salt`
+// by an earlier run, and registers the app.
+func deployedContracts(salt [32]byte) (ContractAddresses, error) {
+	contracts, err := deploy.Create2Addresses(salt)
+	if err != nil {
+		return ContractAddresses{}, err
+	}
+	registerApp(contracts)
+	return contracts, nil
+}
+
+func registerApp(contracts ContractAddresses) {
+	app.Register(ethwallet.AsWalletAddr(contracts.App), nil)
+}
+
+// newDeployer returns a deployer that deploys from the first account.
+func (c *chain) newDeployer(ctx context.Context) (*deploy.Deployer, error) {
+	if c.backend != nil {
+		return deploy.NewDeployerWithBackend(ctx, c.backend, c.accounts[0], c.chainID)
+	}
+	return deploy.NewDeployer(ctx, c.nodeURL, c.accounts[0], c.chainID)
+}
@@ -0,0 +1,8 @@
+//go:build devchain
+// +build devchain
+
+package testutil
+
+func init() {
+	startChain = startDevChain
+}
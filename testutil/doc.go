@@ -0,0 +1,11 @@
+// Package testutil bootstraps throwaway test environments for the credential
+// payment client: it starts a development chain with funded accounts, deploys
+// the contracts, and configures clients on it.
+//
+// Applications embedding the client use it for their integration tests. They
+// either use the holder and issuer of Setup, or start their own clients with
+// the configurations of SetupChain and fund their accounts with
+// Chain.NewAccount. The chain is selected by environment variables, see the
+// README. Setup, SetupChain, Chain, Environment, Profile, Peer, EthToWei and
+// WeiToEth are the stable API of the package.
+package testutil
@@ -0,0 +1,58 @@
+package testutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+type EthClient struct {
+	*ethclient.Client
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+	nonce   uint64
+}
+
+func NewEthClient(ctx context.Context, nodeURL string, key *ecdsa.PrivateKey, chainID *big.Int) (*EthClient, error) {
+	client, err := ethclient.DialContext(ctx, nodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("dialing: %w", err)
+	}
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	nonce, err := client.NonceAt(ctx, addr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting nonce: %w", err)
+	}
+
+	return &EthClient{
+		Client:  client,
+		key:     key,
+		chainID: chainID,
+		nonce:   nonce,
+	}, nil
+}
+
+func (c *EthClient) AccountBalance(a common.Address) (b *big.Int, err error) {
+	return c.BalanceAt(context.Background(), a, nil)
+}
+
+func WeiToEth(weiAmount *big.Int) (ethAmount *big.Float) {
+	weiPerEth := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	weiPerEthFloat := new(big.Float).SetInt(weiPerEth)
+	weiAmountFloat := new(big.Float).SetInt(weiAmount)
+	return new(big.Float).Quo(weiAmountFloat, weiPerEthFloat)
+}
+
+func EthToWei(ethAmount *big.Float) (weiAmount *big.Int) {
+	weiPerEth := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	weiPerEthFloat := new(big.Float).SetInt(weiPerEth)
+	weiAmountFloat := new(big.Float).Mul(ethAmount, weiPerEthFloat)
+	weiAmount, _ = weiAmountFloat.Int(nil)
+	return weiAmount
+}
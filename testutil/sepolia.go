@@ -0,0 +1,80 @@
+package testutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// sepoliaChainID is the chain ID of the Sepolia testnet.
+const sepoliaChainID = 11155111
+
+// sepoliaProfile is the profile of Sepolia. Funds are scarce, so a unit is a
+// milliether. The dispute duration leaves time for registering and
+// progressing a dispute with blocks of twelve seconds.
+var sepoliaProfile = Profile{
+	TxFinality:      3,
+	DisputeDuration: 5 * time.Minute,
+	Unit:            EthToWei(big.NewFloat(0.001)),
+}
+
+// attachSepolia attaches to the Sepolia node at SEPOLIA_URL with the funded
+// keys SEPOLIA_HOLDER_KEY and SEPOLIA_ISSUER_KEY. The contracts must have
+// been deployed at deploy.Create2Addresses(deploymentSalt), e.g., with
+// Deployer.DeployCreate2.
+func attachSepolia() (*chain, error) {
+	url := os.Getenv("SEPOLIA_URL")
+	if len(url) == 0 {
+		return nil, fmt.Errorf("SEPOLIA_URL not set")
+	}
+	holder, err := keyFromEnv("SEPOLIA_HOLDER_KEY")
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := keyFromEnv("SEPOLIA_ISSUER_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), attachTimeout)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("dialing: %w", err)
+	}
+	defer client.Close()
+	if chainID, err := client.ChainID(ctx); err != nil {
+		return nil, fmt.Errorf("getting chain ID: %w", err)
+	} else if chainID.Cmp(big.NewInt(sepoliaChainID)) != 0 {
+		return nil, fmt.Errorf("chain ID %v is not Sepolia", chainID)
+	}
+
+	return &chain{
+		nodeURL:  url,
+		chainID:  big.NewInt(sepoliaChainID),
+		accounts: []*ecdsa.PrivateKey{nil, holder, issuer}, // No deployer.
+		deployed: true,
+		profile:  sepoliaProfile,
+		shutdown: func() {},
+	}, nil
+}
+
+// keyFromEnv parses the private key in environment variable `name`.
+func keyFromEnv(name string) (*ecdsa.PrivateKey, error) {
+	key := os.Getenv(name)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%s not set", name)
+	}
+	k, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return k, nil
+}
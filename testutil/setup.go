@@ -0,0 +1,350 @@
+package testutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/perun"
+	"github.com/perun-network/perun-credential-payment/pkg/devchain"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	chainHost        = "127.0.0.1"
+	chainPort        = 8545
+	chainStartupTime = 10 * time.Second
+	attachTimeout    = 1 * time.Minute
+	chainPrintOutput = false
+	chainID          = 1337
+	blockTime        = 1 * time.Second
+
+	// Client hosts.
+	holderHost = "127.0.0.1:8546"
+	issuerHost = "127.0.0.1:8547"
+)
+
+// deploymentSalt is the CREATE2 salt of the contracts, which pins their
+// addresses, see deploy.Create2Addresses.
+var deploymentSalt = crypto.Keccak256Hash([]byte("perun-credential-payment"))
+
+// Accounts and initial funding.
+var accountFunding = []devchain.KeyWithBalance{
+	{PrivateKey: "0x50b4713b4ba55b6fbcb826ae04e66c03a12fc62886a90ca57ab541959337e897", BalanceEth: 100}, // Contract Deployer, funds Chain.NewAccount
+	{PrivateKey: "0x1af2e950272dd403de7a5760d41c6e44d92b6d02797e51810795ff03cc2cda4f", BalanceEth: 10},  // Holder
+	{PrivateKey: "0xf63d7d8e930bccd74e93cf5662fde2c28fd8be95edb70c73f1bdd863d07f412e", BalanceEth: 10},  // Issuer
+}
+
+type Environment struct {
+	Holder, Issuer *client.Client
+	Chain          *devchain.Chain // Nil on the simulated backend and on attached nodes.
+	Profile        Profile
+	Clock          *Clock // The clock of the clients. Nil if they use the system clock.
+	chain          *chain
+}
+
+// Profile holds the parameters of a test environment that depend on its
+// chain.
+type Profile struct {
+	TxFinality      uint64
+	DisputeDuration time.Duration
+	Unit            *big.Int // The value in wei of one unit, see Profile.Amount.
+}
+
+// localProfile is the profile of local chains.
+var localProfile = Profile{
+	TxFinality:      1,
+	DisputeDuration: 3 * time.Second,
+	Unit:            EthToWei(big.NewFloat(1)),
+}
+
+// Amount returns the value of `units` units in wei. Tests express balances
+// and prices in units, so that they can run on chains with scarce funds.
+func (p Profile) Amount(units int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(units), p.Unit)
+}
+
+// Amount returns the value of `units` units in wei, see Profile.Amount.
+func (e *Environment) Amount(units int64) *big.Int {
+	return e.Profile.Amount(units)
+}
+
+// chain is the blockchain of a test environment.
+type chain struct {
+	nodeURL  string             // Empty for in-process backends.
+	backend  perun.ChainBackend // Nil if the chain is reached at nodeURL.
+	chainID  *big.Int
+	accounts []*ecdsa.PrivateKey // Deployer, holder, issuer, and stress holders.
+	devChain *devchain.Chain     // Nil for in-process backends and attached nodes.
+	deployed bool                // Whether the contracts are deployed at deploy.Create2Addresses.
+	profile  Profile
+	shutdown func()
+	// increaseTime increases the time of the chain. Nil if the time of the
+	// chain cannot be controlled.
+	increaseTime func(ctx context.Context, d time.Duration) error
+}
+
+// shared holds the development chain shared by the tests of the package and
+// the snapshot taken after the deployment of the contracts.
+var shared struct {
+	sync.Mutex
+	chain     *chain
+	contracts ContractAddresses
+	snapshot  string
+}
+
+// startChain starts the blockchain of the test environment, which is an
+// in-process simulated backend. If the tests are built with tag `devchain`,
+// they run on a development chain or node instead, see startDevChain.
+var startChain = startSimulated
+
+func (e *Environment) LogAccountBalances() {
+	LogAccountBalance(e.Holder, e.Issuer)
+}
+
+// Option modifies the configurations of the holder and the issuer of a test
+// environment.
+type Option func(holder, issuer *client.ClientConfig)
+
+// Setup sets up a holder and an issuer, which are connected as peers, on the
+// chain of SetupChain. The options are applied to their configurations.
+func Setup(t testing.TB, opts ...Option) *Environment {
+	t.Helper()
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// Start blockchain with prefunded accounts and deployed contracts
+	c := SetupChain(t)
+	holderKey, issuerKey := c.chain.accounts[1], c.chain.accounts[2]
+	clk := newClock(c.chain)
+
+	log.Print("Setting up clients...")
+	holderConfig := c.ClientConfig(holderKey, holderHost, Peer(issuerKey, issuerHost))
+	issuerConfig := c.ClientConfig(issuerKey, issuerHost, Peer(holderKey, holderHost))
+	if clk != nil {
+		holderConfig.Clock = clk
+		issuerConfig.Clock = clk
+	}
+	for _, opt := range opts {
+		opt(&holderConfig, &issuerConfig)
+	}
+
+	// Setup holder.
+	holder, err := client.StartClient(ctx, holderConfig)
+	require.NoError(err, "Holder setup")
+	t.Cleanup(holder.Shutdown)
+
+	// Setup issuer.
+	issuer, err := client.StartClient(ctx, issuerConfig)
+	require.NoError(err, "Issuer setup")
+	t.Cleanup(issuer.Shutdown)
+
+	log.Print("Setup done.")
+	return &Environment{Holder: holder, Issuer: issuer, Chain: c.chain.devChain, Profile: c.Profile(), Clock: clk, chain: c.chain}
+}
+
+// setupChain returns a chain with prefunded accounts and deployed contracts.
+// A development chain is shared by the tests of the package: instead of being
+// restarted, it is reverted to the snapshot taken after the deployment. Tests
+// using the chain must therefore not run in parallel. In-process chains are
+// started and attached nodes are attached to for every test.
+func setupChain(ctx context.Context, t testing.TB) (*chain, ContractAddresses) {
+	t.Helper()
+	require := require.New(t)
+	shared.Lock()
+	defer shared.Unlock()
+
+	if shared.chain != nil {
+		log.Print("Reverting local blockchain...")
+		require.NoError(shared.chain.devChain.Revert(ctx, shared.snapshot), "reverting chain")
+		// Reverting deletes the snapshot.
+		snapshot, err := shared.chain.devChain.Snapshot(ctx)
+		require.NoError(err, "taking snapshot")
+		shared.snapshot = snapshot
+		return shared.chain, shared.contracts
+	}
+
+	log.Print("Starting local blockchain...")
+	chain, err := startChain()
+	require.NoError(err, "starting chain")
+	if chain.devChain == nil {
+		t.Cleanup(chain.shutdown)
+	} else {
+		// Shut down the chain if it cannot be shared.
+		defer func() {
+			if shared.chain != chain {
+				chain.shutdown()
+			}
+		}()
+	}
+
+	var contracts ContractAddresses
+	if chain.deployed {
+		contracts, err = deployedContracts(deploymentSalt)
+	} else {
+		log.Print("Deploying contracts...")
+		contracts, err = deployContracts(ctx, chain, deploymentSalt)
+	}
+	require.NoError(err, "deploying contracts")
+	if chain.devChain != nil {
+		snapshot, err := chain.devChain.Snapshot(ctx)
+		require.NoError(err, "taking snapshot")
+		shared.chain, shared.contracts, shared.snapshot = chain, contracts, snapshot
+	}
+	return chain, contracts
+}
+
+// Teardown shuts down the chain shared by the tests of the package. It must be
+// called by TestMain after running the tests.
+func Teardown() {
+	shared.Lock()
+	defer shared.Unlock()
+	if shared.chain != nil {
+		shared.chain.shutdown()
+		shared.chain = nil
+	}
+}
+
+// startDevChain attaches to the node at DEVCHAIN_URL, if set, and otherwise
+// starts the development chain selected by DEVCHAIN, which is
+// "ganache" (default), "anvil", or "hardhat", with the accounts of
+// accountFunding. DEVCHAIN "sepolia" attaches to Sepolia instead, see
+// attachSepolia.
+// The command can be overridden by DEVCHAIN_CMD, or GANACHE_CMD for ganache.
+// If DEVCHAIN_DOCKER_IMAGE is set, the chain runs in a container of that
+// image, which is started with the command DEVCHAIN_DOCKER_CMD, if set.
+func startDevChain() (*chain, error) {
+	if url := os.Getenv("DEVCHAIN_URL"); len(url) != 0 {
+		return attachNode(url)
+	} else if os.Getenv("DEVCHAIN") == "sepolia" {
+		return attachSepolia()
+	}
+
+	cfg, err := makeDevChainConfig(accountFunding)
+	if err != nil {
+		return nil, fmt.Errorf("configuring chain: %w", err)
+	}
+	devChain, err := devchain.Start(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*ecdsa.PrivateKey, len(devChain.Accounts))
+	for i, a := range devChain.Accounts {
+		accounts[i] = a.PrivateKey
+	}
+	return &chain{
+		nodeURL:  cfg.NodeURL(),
+		chainID:  cfg.ChainID,
+		accounts: accounts,
+		devChain: devChain,
+		profile:  localProfile,
+		shutdown: func() {
+			err := devChain.Shutdown()
+			if err != nil {
+				log.Print("shutting down chain:", err)
+			}
+		},
+		increaseTime: devChain.IncreaseTime,
+	}, nil
+}
+
+// attachNode attaches to the externally managed node at `url`. If
+// DEVCHAIN_FAUCET_KEY is set, the accounts are funded from its account. The
+// node is not reverted between tests.
+func attachNode(url string) (*chain, error) {
+	var faucet *ecdsa.PrivateKey
+	if len(os.Getenv("DEVCHAIN_FAUCET_KEY")) != 0 {
+		var err error
+		if faucet, err = keyFromEnv("DEVCHAIN_FAUCET_KEY"); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), attachTimeout)
+	defer cancel()
+	node, err := devchain.Attach(ctx, url, faucet, accountFunding)
+	if err != nil {
+		return nil, fmt.Errorf("attaching to node: %w", err)
+	}
+
+	accounts := make([]*ecdsa.PrivateKey, len(node.Accounts))
+	for i, a := range node.Accounts {
+		accounts[i] = a.PrivateKey
+	}
+	return &chain{
+		nodeURL:  node.URL,
+		chainID:  node.ChainID,
+		accounts: accounts,
+		profile:  localProfile,
+		shutdown: func() {},
+	}, nil
+}
+
+func makeDevChainConfig(funding []devchain.KeyWithBalance) (devchain.Config, error) {
+	var driver devchain.Driver
+	cmd := os.Getenv("DEVCHAIN_CMD")
+	switch name := os.Getenv("DEVCHAIN"); name {
+	case "", "ganache":
+		driver = devchain.Ganache{}
+		if len(cmd) == 0 {
+			cmd = os.Getenv("GANACHE_CMD")
+		}
+	case "anvil":
+		driver = devchain.Anvil{}
+	case "hardhat":
+		driver = devchain.Hardhat{}
+	default:
+		return devchain.Config{}, fmt.Errorf("unknown chain: %s", name)
+	}
+	var docker *devchain.Docker
+	if image := os.Getenv("DEVCHAIN_DOCKER_IMAGE"); len(image) != 0 {
+		docker = &devchain.Docker{Image: image, Cmd: os.Getenv("DEVCHAIN_DOCKER_CMD")}
+	}
+	return devchain.Config{
+		Driver:        driver,
+		Cmd:           cmd,
+		Host:          chainHost,
+		Port:          chainPort,
+		BlockTime:     blockTime,
+		Funding:       funding,
+		StartupTime:   chainStartupTime,
+		ChainID:       big.NewInt(chainID),
+		PrintToStdOut: chainPrintOutput,
+		Docker:        docker,
+	}, nil
+}
+
+func newClientConfig(
+	chain *chain,
+	contracts ContractAddresses,
+	privateKey *ecdsa.PrivateKey,
+	host string,
+	peers ...perun.Peer,
+) client.ClientConfig {
+	return client.ClientConfig{
+		ClientConfig: perun.ClientConfig{
+			PrivateKey:    privateKey,
+			Host:          host,
+			ETHNodeURL:    chain.nodeURL,
+			Backend:       chain.backend,
+			Adjudicator:   contracts.Adjudicator,
+			AssetHolder:   contracts.AssetHolder,
+			DialerTimeout: 1 * time.Second,
+			Peers:         peers,
+			TxFinality:    chain.profile.TxFinality,
+			ChainID:       chain.chainID,
+		},
+		ChallengeDuration: chain.profile.DisputeDuration,
+		AppAddress:        contracts.App,
+	}
+}
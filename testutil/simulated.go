@@ -0,0 +1,81 @@
+package testutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	ethchanneltest "perun.network/go-perun/backend/ethereum/channel/test"
+)
+
+// simulatedChainID is the chain ID of the simulated backend.
+const simulatedChainID = 1337
+
+// simulatedProfile is the profile of the simulated backend. Every block
+// increases the time of the chain by 10 seconds, including the blocks of the
+// funding transactions, so the dispute duration spans several blocks.
+var simulatedProfile = Profile{
+	TxFinality:      1,
+	DisputeDuration: 60 * time.Second,
+	Unit:            localProfile.Unit,
+}
+
+// startSimulated starts an in-process simulated backend, which mines a block
+// every blockTime, and funds the accounts of accountFunding.
+func startSimulated() (*chain, error) {
+	sb := ethchanneltest.NewSimulatedBackend()
+
+	accounts := make([]*ecdsa.PrivateKey, len(accountFunding))
+	for i, funding := range accountFunding {
+		key, err := crypto.HexToECDSA(funding.PrivateKey[2:])
+		if err != nil {
+			return nil, fmt.Errorf("parsing account key: %w", err)
+		}
+		sb.FundAddress(context.Background(), crypto.PubkeyToAddress(key.PublicKey))
+		accounts[i] = key
+	}
+
+	sb.StartMining(blockTime)
+	return &chain{
+		backend:      sb,
+		chainID:      big.NewInt(simulatedChainID),
+		accounts:     accounts,
+		profile:      simulatedProfile,
+		shutdown:     sb.StopMining,
+		increaseTime: simulatedTimeIncreaser(sb),
+	}, nil
+}
+
+// simulatedTimeIncreaser returns a function that increases the time of `sb`
+// by mining a block that is later by the given duration.
+func simulatedTimeIncreaser(sb *ethchanneltest.SimulatedBackend) func(context.Context, time.Duration) error {
+	return func(_ context.Context, d time.Duration) error {
+		if err := sb.AdjustTime(d); err != nil {
+			return fmt.Errorf("adjusting time: %w", err)
+		}
+		sb.Commit()
+		return nil
+	}
+}
+
+// BlockNumber returns the number of the latest block.
+func (e *Environment) BlockNumber(ctx context.Context) (uint64, error) {
+	h, err := e.chain.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return h.Number.Uint64(), nil
+}
+
+// Reorg replaces the latest `depth` blocks of the simulated chain with the
+// blocks returned by `reorder`, which must be more.
+func (e *Environment) Reorg(ctx context.Context, depth uint64, reorder ethchanneltest.Reorder) error {
+	sb, ok := e.chain.backend.(*ethchanneltest.SimulatedBackend)
+	if !ok {
+		return fmt.Errorf("chain does not support reorgs")
+	}
+	return sb.Reorg(ctx, depth, reorder)
+}
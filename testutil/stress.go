@@ -0,0 +1,103 @@
+package testutil
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/perun-network/perun-credential-payment/client"
+	"github.com/perun-network/perun-credential-payment/client/perun"
+	"github.com/perun-network/perun-credential-payment/pkg/devchain"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// stressHolderPort is the port of the first stress holder. The others
+	// listen on the following ports.
+	stressHolderPort = 9000
+	// stressHolderBalanceEth is the initial funding of a stress holder.
+	stressHolderBalanceEth = 10
+	// numAccounts is the number of accounts used outside of stress mode.
+	numAccounts = 3
+)
+
+// StressEnvironment is a test environment in which many holders are
+// connected to a single issuer.
+type StressEnvironment struct {
+	Holders []*client.Client
+	Issuer  *client.Client
+	Profile Profile
+}
+
+// The stress holders are funded when the chain is started, so that the
+// shared chain does not need to be restarted.
+func init() {
+	n, err := StressHolders()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		key := stressHolderKey(i)
+		accountFunding = append(accountFunding, devchain.KeyWithBalance{
+			PrivateKey: "0x" + hex.EncodeToString(key),
+			BalanceEth: stressHolderBalanceEth,
+		})
+	}
+}
+
+// StressHolders returns the number of holders in stress mode, which is set
+// by STRESS_HOLDERS. It is zero if stress mode is disabled.
+func StressHolders() (int, error) {
+	env := os.Getenv("STRESS_HOLDERS")
+	if len(env) == 0 {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(env)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid STRESS_HOLDERS: %s", env)
+	}
+	return n, nil
+}
+
+// stressHolderKey returns the private key of stress holder `i`.
+func stressHolderKey(i int) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("perun-credential-payment/stress-holder/%d", i)))
+}
+
+// SetupStress sets up an environment with `n` holders and an issuer, which
+// knows all holders as peers. The holders must have been funded at the start
+// of the chain, see StressHolders.
+func SetupStress(t testing.TB, n int) *StressEnvironment {
+	t.Helper()
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c := SetupChain(t)
+	require.LessOrEqual(numAccounts+n, len(c.chain.accounts), "stress holders not funded, see STRESS_HOLDERS")
+
+	log.Printf("Setting up %d holders...", n)
+	issuerKey := c.chain.accounts[2]
+	holders := make([]*client.Client, n)
+	peers := make([]perun.Peer, n)
+	for i := range holders {
+		key := c.chain.accounts[numAccounts+i]
+		host := fmt.Sprintf("127.0.0.1:%d", stressHolderPort+i)
+		holder, err := client.StartClient(ctx, c.ClientConfig(key, host, Peer(issuerKey, issuerHost)))
+		require.NoErrorf(err, "Holder %d setup", i)
+		t.Cleanup(holder.Shutdown)
+		holders[i], peers[i] = holder, Peer(key, host)
+	}
+
+	issuer, err := client.StartClient(ctx, c.ClientConfig(issuerKey, issuerHost, peers...))
+	require.NoError(err, "Issuer setup")
+	t.Cleanup(issuer.Shutdown)
+
+	log.Print("Setup done.")
+	return &StressEnvironment{Holders: holders, Issuer: issuer, Profile: c.Profile()}
+}